@@ -0,0 +1,97 @@
+package cellaut
+
+/*
+AdaptivePacer decides which ticks of a long run are worth showing, so that a slow-developing
+pattern can be watched without sitting through the boring stretches. While little is changing, it
+lets more and more ticks go by between renders, up to MaxBatch. As soon as activity picks up, or
+anything changes in the Watch region, it drops back to rendering every tick, so the interesting
+part plays out in real time.
+
+It's told about every tick with Observe, and leaves the timing to the caller: render when Observe
+says so, and wait one frame after each render.
+*/
+type AdaptivePacer struct {
+	// MaxBatch is the most ticks that go by between renders when things are quiet
+	MaxBatch int
+	// Threshold is the fraction of cells that have to change in a tick for it to count as busy.
+	// Defaults to 0.01.
+	Threshold float64
+	// Watch, if it's set, is a region where any change at all counts as busy
+	Watch *Rect
+	// batch is how many ticks go by between renders right now, and pending is how many have gone
+	// by since the last one
+	batch   int
+	pending int
+	prev    *StateGrid
+}
+
+/*
+NewAdaptivePacer returns an *AdaptivePacer that lets up to maxBatch ticks go by between renders.
+*/
+func NewAdaptivePacer(maxBatch int) *AdaptivePacer {
+	return &AdaptivePacer{MaxBatch: maxBatch, batch: 1}
+}
+
+/*
+Observe takes the grid as of the latest tick, and returns whether it should be rendered.
+*/
+func (pacer *AdaptivePacer) Observe(grid *StateGrid) bool {
+	busy := pacer.busy(grid)
+	pacer.prev = grid
+	pacer.pending++
+	if busy {
+		// Show the change that woke us up straight away, rather than at the end of the batch
+		pacer.batch = 1
+		pacer.pending = 0
+		return true
+	}
+	if pacer.pending < pacer.batch {
+		return false
+	}
+	pacer.pending = 0
+	// Speed up gradually, so that a pattern that's only paused briefly doesn't get skipped
+	pacer.batch *= 2
+	if pacer.batch > pacer.MaxBatch {
+		pacer.batch = pacer.MaxBatch
+	}
+	if pacer.batch < 1 {
+		pacer.batch = 1
+	}
+	return true
+}
+
+/*
+Batch returns how many ticks go by between renders at the moment.
+*/
+func (pacer *AdaptivePacer) Batch() int {
+	return pacer.batch
+}
+
+/*
+busy returns whether grid has changed enough since the last tick to slow down for.
+*/
+func (pacer *AdaptivePacer) busy(grid *StateGrid) bool {
+	prev := pacer.prev
+	if prev == nil {
+		return true
+	}
+	if prev.Width != grid.Width || prev.Height != grid.Height {
+		// The grid grew, which is worth seeing
+		return true
+	}
+	if pacer.Watch != nil {
+		watch := pacer.Watch.Intersect(Rect{Width: grid.Width, Height: grid.Height})
+		for y := watch.Y; y < watch.Y+watch.Height; y++ {
+			for x := watch.X; x < watch.X+watch.Width; x++ {
+				if prev.At(x, y) != grid.At(x, y) {
+					return true
+				}
+			}
+		}
+	}
+	threshold := pacer.Threshold
+	if threshold == 0 {
+		threshold = 0.01
+	}
+	return float64(hammingDistance(prev, grid)) > threshold*float64(grid.Width*grid.Height)
+}
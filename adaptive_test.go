@@ -0,0 +1,74 @@
+package cellaut
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptivePacer(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	grid := NewStateGrid(10, 10, NewStateTable("-"))
+	pacer := NewAdaptivePacer(4)
+	pacer.Watch = &Rect{X: 8, Y: 8, Width: 2, Height: 2}
+	var rendered []bool
+	observe := func(grid *StateGrid) {
+		rendered = append(rendered, pacer.Observe(grid))
+	}
+
+	// While nothing changes, renders get further apart, up to MaxBatch
+	for tick := 0; tick < 12; tick++ {
+		observe(grid)
+	}
+	assert.Equal([]bool{true, true, false, true, false, false, false, true, false, false, false, true}, rendered)
+	assert.Equal(4, pacer.Batch())
+
+	// A lot of change renders straight away, and goes back to every tick
+	rendered = nil
+	busy := cloneStateGrid(grid)
+	for x := 0; x < 10; x++ {
+		busy.Set(x, 0, "X")
+	}
+	observe(busy)
+	observe(busy)
+	assert.Equal([]bool{true, true}, rendered)
+	assert.Equal(2, pacer.Batch())
+
+	// So does one cell changing, if it's in the watched region, but not if it isn't
+	rendered = nil
+	observe(busy)
+	quiet := cloneStateGrid(busy)
+	quiet.Set(0, 5, "X")
+	observe(quiet)
+	watched := cloneStateGrid(quiet)
+	watched.Set(9, 9, "X")
+	observe(watched)
+	assert.Equal([]bool{false, true, true}, rendered)
+	assert.Equal(1, pacer.Batch())
+}
+
+func TestWriteDemo_Adaptive(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// With a threshold nothing reaches, the frames get further and further apart, but the last
+	// one is always shown
+	preset, _ := findPreset("glider-gun")
+	preset.pacer = NewAdaptivePacer(8)
+	preset.pacer.Threshold = 1
+	var b bytes.Buffer
+	assert.Nil(writeDemo(&b, preset, 30, 1, 0, "", 4))
+	var ticks []string
+	for _, match := range regexp.MustCompile(`tick (\d+)\n`).FindAllStringSubmatch(b.String(), -1) {
+		ticks = append(ticks, match[1])
+	}
+	assert.Equal([]string{"0", "1", "3", "7", "15", "23", "30"}, ticks)
+
+	assert.NotNil(demoCommand([]string{"rule110", "--adaptive", "4"}))
+	assert.NotNil(demoCommand([]string{"glider-gun", "--watch", "1,1,2,2"}))
+	assert.NotNil(demoCommand([]string{"glider-gun", "--adaptive", "4", "--watch", "1,1"}))
+}
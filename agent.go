@@ -0,0 +1,284 @@
+package cellaut
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+/*
+World is a rectangular arrangement of CellAuts that can be addressed by coordinates.
+
+x grows to the right (NeighborRt) and y grows upward (NeighborUp).
+*/
+type World interface {
+	// Size returns the width and height of the World.
+	Size() (width, height int)
+	// At returns the CellAut at the given coordinates.
+	At(x, y int) CellAut
+}
+
+/*
+Agent is something that walks around on top of a World, as opposed to being one of its cells.
+
+Agents have a position, a heading, and a state of their own. What an agent does is determined by
+the AgentBehavior registered for its Kind.
+*/
+type Agent struct {
+	ID      int           `json:"id"`
+	Kind    string        `json:"kind"`
+	X       int           `json:"x"`
+	Y       int           `json:"y"`
+	Heading NeighborIndex `json:"heading"`
+	State   State         `json:"state"`
+}
+
+/*
+AgentBehavior is an agent's step function.
+
+It gets called once per step with the agent and the cell the agent is standing on. It may read and
+set the cell's state, change the agent's heading and state, and returns whether the agent wants to
+move one cell in the direction of its (new) heading.
+
+States set on the cell follow the usual CellAut rules: they become visible at the next tick.
+*/
+type AgentBehavior func(agent *Agent, cell CellAut) (move bool)
+
+/*
+CollisionPolicy says what happens when an agent tries to move onto a cell that another agent is
+already occupying.
+*/
+type CollisionPolicy int
+
+const (
+	// CollisionAllow lets any number of agents share a cell.
+	CollisionAllow CollisionPolicy = iota
+	// CollisionBlock keeps an agent where it is if the cell it wants is occupied.
+	CollisionBlock
+	// CollisionRemove removes the agent that moved onto an occupied cell from the layer.
+	CollisionRemove
+)
+
+/*
+AgentLayer holds the agents that live on top of a World and steps them.
+
+The agents are stepped in the order they were added, so the outcome of collisions is
+deterministic. Step should be called between ticks, when no CellAut is in the middle of updating
+its state.
+*/
+type AgentLayer struct {
+	World     World
+	Collision CollisionPolicy
+	// behaviors maps each Agent.Kind to its step function
+	behaviors map[string]AgentBehavior
+	agents    []*Agent
+	nextID    int
+}
+
+/*
+NewAgentLayer returns an empty *AgentLayer on top of world.
+
+behaviors maps agent kinds to the step functions that drive them.
+*/
+func NewAgentLayer(world World, behaviors map[string]AgentBehavior, collision CollisionPolicy) *AgentLayer {
+	return &AgentLayer{
+		World:     world,
+		Collision: collision,
+		behaviors: behaviors,
+	}
+}
+
+/*
+Add puts an agent of the given kind on the layer and returns it.
+
+It's an error to add an agent of a kind with no registered behavior, or outside the World.
+*/
+func (layer *AgentLayer) Add(kind string, x, y int, heading NeighborIndex) (*Agent, error) {
+	if _, ok := layer.behaviors[kind]; !ok {
+		return nil, fmt.Errorf("no behavior registered for agent kind '%s'", kind)
+	}
+	if !layer.inBounds(x, y) {
+		return nil, fmt.Errorf("agent position (%d, %d) is outside the world", x, y)
+	}
+	agent := &Agent{ID: layer.nextID, Kind: kind, X: x, Y: y, Heading: heading}
+	layer.nextID++
+	layer.agents = append(layer.agents, agent)
+	return agent, nil
+}
+
+/*
+Agents returns the agents currently on the layer, in stepping order.
+*/
+func (layer *AgentLayer) Agents() []*Agent {
+	return layer.agents
+}
+
+/*
+AgentsAt returns the agents standing on the cell at (x, y).
+*/
+func (layer *AgentLayer) AgentsAt(x, y int) []*Agent {
+	var rslt []*Agent
+	for _, agent := range layer.agents {
+		if agent.X == x && agent.Y == y {
+			rslt = append(rslt, agent)
+		}
+	}
+	return rslt
+}
+
+/*
+Step runs every agent's behavior once and moves the agents that asked to move.
+
+An agent that would walk off the edge of the World stays where it is.
+*/
+func (layer *AgentLayer) Step() {
+	var removed map[int]bool
+	for _, agent := range layer.agents {
+		if removed[agent.ID] {
+			continue
+		}
+		behavior := layer.behaviors[agent.Kind]
+		if !behavior(agent, layer.World.At(agent.X, agent.Y)) {
+			continue
+		}
+		dx, dy := neighborOffset(agent.Heading)
+		x, y := agent.X+dx, agent.Y+dy
+		if !layer.inBounds(x, y) {
+			continue
+		}
+		if layer.Collision != CollisionAllow && layer.occupied(x, y, removed) {
+			if layer.Collision == CollisionRemove {
+				if removed == nil {
+					removed = make(map[int]bool)
+				}
+				removed[agent.ID] = true
+			}
+			continue
+		}
+		agent.X, agent.Y = x, y
+	}
+	if removed == nil {
+		return
+	}
+	kept := layer.agents[:0]
+	for _, agent := range layer.agents {
+		if !removed[agent.ID] {
+			kept = append(kept, agent)
+		}
+	}
+	layer.agents = kept
+}
+
+func (layer *AgentLayer) inBounds(x, y int) bool {
+	width, height := layer.World.Size()
+	return x >= 0 && x < width && y >= 0 && y < height
+}
+
+func (layer *AgentLayer) occupied(x, y int, removed map[int]bool) bool {
+	for _, other := range layer.agents {
+		if other.X == x && other.Y == y && !removed[other.ID] {
+			return true
+		}
+	}
+	return false
+}
+
+// agentLayerJSON is what an AgentLayer looks like when it's serialized, e.g. in a checkpoint.
+type agentLayerJSON struct {
+	NextID int      `json:"next_id"`
+	Agents []*Agent `json:"agents"`
+}
+
+/*
+MarshalJSON serializes the agents on the layer.
+
+The World and the behaviors are not serialized; they belong to whoever owns the layer.
+*/
+func (layer *AgentLayer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(agentLayerJSON{NextID: layer.nextID, Agents: layer.agents})
+}
+
+/*
+UnmarshalJSON replaces the agents on the layer with the serialized ones.
+
+Every agent's kind must have a behavior registered on the layer.
+*/
+func (layer *AgentLayer) UnmarshalJSON(data []byte) error {
+	var decoded agentLayerJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	for _, agent := range decoded.Agents {
+		if _, ok := layer.behaviors[agent.Kind]; !ok {
+			return fmt.Errorf("no behavior registered for agent kind '%s'", agent.Kind)
+		}
+	}
+	layer.nextID = decoded.NextID
+	layer.agents = decoded.Agents
+	return nil
+}
+
+/*
+neighborOffset returns the change in coordinates that moving toward the given neighbor entails.
+*/
+func neighborOffset(i NeighborIndex) (dx, dy int) {
+	switch i {
+	case NeighborUp:
+		return 0, 1
+	case NeighborRt:
+		return 1, 0
+	case NeighborDn:
+		return 0, -1
+	case NeighborLf:
+		return -1, 0
+	case NeighborUpRt:
+		return 1, 1
+	case NeighborUpLf:
+		return -1, 1
+	case NeighborDnRt:
+		return 1, -1
+	case NeighborDnLf:
+		return -1, -1
+	}
+	return 0, 0
+}
+
+/*
+turnRight returns the direction 90 degrees clockwise from i.
+*/
+func turnRight(i NeighborIndex) NeighborIndex {
+	switch i {
+	case NeighborUp:
+		return NeighborRt
+	case NeighborRt:
+		return NeighborDn
+	case NeighborDn:
+		return NeighborLf
+	}
+	return NeighborUp
+}
+
+/*
+turnLeft returns the direction 90 degrees counterclockwise from i.
+*/
+func turnLeft(i NeighborIndex) NeighborIndex {
+	return turnRight(i).Recip()
+}
+
+/*
+NewLangtonsAnt returns the AgentBehavior of Langton's Ant.
+
+On an `off` cell the ant turns right, on any other cell it turns left. Either way it flips the cell
+between `on` and `off` and moves forward.
+*/
+func NewLangtonsAnt(on, off State) AgentBehavior {
+	return func(agent *Agent, cell CellAut) bool {
+		if cell.GetState() == off {
+			agent.Heading = turnRight(agent.Heading)
+			cell.SetState(on)
+		} else {
+			agent.Heading = turnLeft(agent.Heading)
+			cell.SetState(off)
+		}
+		return true
+	}
+}
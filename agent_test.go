@@ -0,0 +1,136 @@
+package cellaut
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+sliceWorld is a World whose cells aren't wired to each other.
+*/
+type sliceWorld struct {
+	width, height int
+	cells         []CellAut
+}
+
+func (world *sliceWorld) Size() (int, int) {
+	return world.width, world.height
+}
+
+func (world *sliceWorld) At(x, y int) CellAut {
+	return world.cells[y*world.width+x]
+}
+
+/*
+Returns a sliceWorld of GooCellAuts in the given state, plus a running Ticker that drives them.
+*/
+func newSliceWorld(width, height int, state State) (*sliceWorld, *Ticker) {
+	world := &sliceWorld{width: width, height: height, cells: make([]CellAut, width*height)}
+	ticker := &Ticker{}
+	for i := range world.cells {
+		world.cells[i] = NewGooCellAut(i)
+		world.cells[i].SetState(state)
+		ticker.Start(world.cells[i], nil)
+	}
+	ticker.Tick()
+	return world, ticker
+}
+
+func TestAgentLayer_LangtonsAnt(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	world, ticker := newSliceWorld(5, 5, "-")
+	defer ticker.Stop(time.Second)
+	layer := NewAgentLayer(world, map[string]AgentBehavior{"ant": NewLangtonsAnt("X", "-")}, CollisionAllow)
+	ant, err := layer.Add("ant", 2, 2, NeighborUp)
+	assert.Nil(err)
+
+	// In its first 4 steps, the ant walks clockwise around a 2x2 square, turning it all black.
+	for i := 0; i < 4; i++ {
+		layer.Step()
+		ticker.Tick()
+	}
+	assert.Equal(2, ant.X)
+	assert.Equal(2, ant.Y)
+	assert.Equal(NeighborUp, ant.Heading)
+	for _, xy := range [][2]int{{2, 2}, {3, 2}, {3, 1}, {2, 1}} {
+		assert.Equal(State("X"), world.At(xy[0], xy[1]).GetState())
+	}
+	assert.Equal(State("-"), world.At(1, 2).GetState())
+
+	// Now it's on a black cell, so it turns left.
+	layer.Step()
+	ticker.Tick()
+	assert.Equal(1, ant.X)
+	assert.Equal(NeighborLf, ant.Heading)
+	assert.Equal(State("-"), world.At(2, 2).GetState())
+}
+
+func TestAgentLayer_Collision(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	world, ticker := newSliceWorld(3, 1, "-")
+	defer ticker.Stop(time.Second)
+	walker := func(agent *Agent, cell CellAut) bool { return true }
+	behaviors := map[string]AgentBehavior{"walker": walker}
+
+	// Two agents walking toward each other.
+	for _, tc := range []struct {
+		policy   CollisionPolicy
+		expected [][2]int
+	}{
+		{CollisionAllow, [][2]int{{1, 0}, {1, 0}}},
+		{CollisionBlock, [][2]int{{1, 0}, {2, 0}}},
+		{CollisionRemove, [][2]int{{1, 0}}},
+	} {
+		layer := NewAgentLayer(world, behaviors, tc.policy)
+		layer.Add("walker", 0, 0, NeighborRt)
+		layer.Add("walker", 2, 0, NeighborLf)
+		layer.Step()
+		var positions [][2]int
+		for _, agent := range layer.Agents() {
+			positions = append(positions, [2]int{agent.X, agent.Y})
+		}
+		assert.Equal(tc.expected, positions)
+	}
+
+	// Agents don't walk off the edge of the world.
+	layer := NewAgentLayer(world, behaviors, CollisionAllow)
+	agent, _ := layer.Add("walker", 0, 0, NeighborDn)
+	layer.Step()
+	assert.Equal(0, agent.Y)
+
+	_, err := layer.Add("nobody", 0, 0, NeighborUp)
+	assert.NotNil(err)
+	_, err = layer.Add("walker", 3, 0, NeighborUp)
+	assert.NotNil(err)
+}
+
+func TestAgentLayer_JSON(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	world, ticker := newSliceWorld(4, 4, "-")
+	defer ticker.Stop(time.Second)
+	behaviors := map[string]AgentBehavior{"ant": NewLangtonsAnt("X", "-")}
+	layer := NewAgentLayer(world, behaviors, CollisionBlock)
+	layer.Add("ant", 1, 2, NeighborRt)
+	layer.Add("ant", 3, 0, NeighborDn)
+	layer.Agents()[1].State = "hungry"
+
+	data, err := json.Marshal(layer)
+	assert.Nil(err)
+	restored := NewAgentLayer(world, behaviors, CollisionBlock)
+	assert.Nil(json.Unmarshal(data, restored))
+	assert.Equal(layer.Agents(), restored.Agents())
+	// IDs keep counting from where the original layer left off
+	agent, _ := restored.Add("ant", 0, 0, NeighborUp)
+	assert.Equal(2, agent.ID)
+
+	assert.NotNil(json.Unmarshal(data, NewAgentLayer(world, nil, CollisionBlock)))
+}
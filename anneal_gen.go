@@ -0,0 +1,31 @@
+// Code generated by rulegen -rule B4678/S35678 -name Anneal; DO NOT EDIT.
+
+package cellaut
+
+// annealTable[self][n] is the next state of a cell in state self (0 for "-", 1 for "X")
+// with n live neighbors.
+var annealTable = [2][9]State{
+	{"-", "-", "-", "-", "X", "-", "X", "X", "X"},
+	{"-", "-", "-", "X", "-", "X", "X", "X", "X"},
+}
+
+/*
+Anneal is the Life-like rule B4678/S35678.
+
+Any state other than "X" counts as dead.
+*/
+func Anneal(self State, neighbors map[NeighborIndex]State) State {
+	n := 0
+	for _, neighbor := range neighbors {
+		if neighbor == "X" {
+			n++
+		}
+	}
+	if n >= len(annealTable[0]) {
+		return "-"
+	}
+	if self == "X" {
+		return annealTable[1][n]
+	}
+	return annealTable[0][n]
+}
@@ -0,0 +1,445 @@
+package cellaut
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+Experiment is everything it takes to reproduce a run: the rule, where it started, and where it had
+got to. It's what goes in a .caz archive.
+*/
+type Experiment struct {
+	// Rule is a Life-like rulestring or a registered rule's name and options, like
+	// "forest-fire growth=0.05 seed=3". It's ignored if RuleTable is set.
+	Rule string `json:"rule,omitempty"`
+	// Neighborhood is "moore", "von-neumann" or "line". Defaults to "moore". Rule tables say their
+	// own.
+	Neighborhood string `json:"neighborhood,omitempty"`
+	// Stop, if it's set, says how long the experiment is meant to run for
+	Stop *StopSpec `json:"stop,omitempty"`
+	// Tick is how many ticks Checkpoint is after Initial
+	Tick int64 `json:"tick"`
+	// Frozen are the names of Initial's Groups whose cells never change
+	Frozen []string `json:"frozen,omitempty"`
+	// RuleTable is the text of a Golly .rule file, for rules that are defined by one
+	RuleTable string `json:"-"`
+	// Initial is the grid the run started from
+	Initial *StateGrid `json:"-"`
+	// Checkpoint is the grid Tick ticks later. It may be nil if the run hasn't started.
+	Checkpoint *StateGrid `json:"-"`
+	// Run, if it's set, says which run took the Checkpoint
+	Run *RunMetadata `json:"run,omitempty"`
+}
+
+// The files in a .caz archive
+const (
+	archiveManifest   = "manifest.json"
+	archiveConfig     = "config.json"
+	archiveRuleTable  = "rule.rule"
+	archiveInitial    = "initial.json"
+	archiveCheckpoint = "checkpoint.json"
+)
+
+// archiveFormat and archiveVersion identify a .caz manifest
+const (
+	archiveFormat  = "cellaut-archive"
+	archiveVersion = 1
+)
+
+/*
+archiveManifestJSON is manifest.json: what the archive is, and a checksum of every other file in it, so
+that a damaged or edited archive is caught before anything is run from it.
+*/
+type archiveManifestJSON struct {
+	Format  string            `json:"format"`
+	Version int               `json:"version"`
+	Created time.Time         `json:"created"`
+	Files   map[string]string `json:"files"`
+}
+
+/*
+Stepper returns a function that advances a grid one tick under the Experiment's rule, keeping the
+Frozen groups as they are.
+*/
+func (exp *Experiment) Stepper() (func(*StateGrid) *StateGrid, error) {
+	step, err := exp.ruleStepper()
+	if err != nil || len(exp.Frozen) == 0 {
+		return step, err
+	}
+	var groups CellGroups
+	if exp.Initial != nil {
+		groups = exp.Initial.Groups
+	}
+	if err := groups.Check(exp.Frozen...); err != nil {
+		return nil, err
+	}
+	return groups.Freeze(step, exp.Frozen...), nil
+}
+
+/*
+ruleStepper returns a function that advances a grid one tick under the Experiment's rule.
+*/
+func (exp *Experiment) ruleStepper() (func(*StateGrid) *StateGrid, error) {
+	if exp.RuleTable != "" {
+		table, err := ParseRuleTable(strings.NewReader(exp.RuleTable))
+		if err != nil {
+			return nil, fmt.Errorf("rule table: %s", err)
+		}
+		return table.Step, nil
+	}
+	rule, err := lookupRuleSpec(exp.Rule)
+	if err != nil {
+		return nil, err
+	}
+	neighborhood, ok := map[string]map[NeighborIndex][2]int{
+		"":            mooreNeighborhood,
+		"moore":       mooreNeighborhood,
+		"von-neumann": vonNeumannNeighborhood,
+		"line":        lineNeighborhood,
+	}[strings.ToLower(exp.Neighborhood)]
+	if !ok {
+		return nil, fmt.Errorf("unknown neighborhood '%s'", exp.Neighborhood)
+	}
+	return func(grid *StateGrid) *StateGrid { return stepGrid(grid, rule, neighborhood) }, nil
+}
+
+/*
+Verify runs the Experiment from Initial for Tick ticks, and checks that it ends up at Checkpoint.
+*/
+func (exp *Experiment) Verify() error {
+	if exp.Checkpoint == nil {
+		return nil
+	}
+	step, err := exp.Stepper()
+	if err != nil {
+		return err
+	}
+	grid := exp.Initial
+	for tick := int64(0); tick < exp.Tick; tick++ {
+		grid = step(grid)
+	}
+	if !grid.Equal(exp.Checkpoint) {
+		return fmt.Errorf("running the initial pattern for %d ticks doesn't reproduce the checkpoint", exp.Tick)
+	}
+	return nil
+}
+
+/*
+WriteArchive writes exp to w as a .caz archive: a zip file holding manifest.json, config.json,
+initial.json, and checkpoint.json and rule.rule if the Experiment has them. Grids are stored as
+their JSON, which carries their States along with them.
+*/
+func WriteArchive(w io.Writer, exp *Experiment) error {
+	if exp.Initial == nil {
+		return fmt.Errorf("an experiment needs an initial grid")
+	}
+	files := make(map[string][]byte)
+	var err error
+	if files[archiveConfig], err = json.MarshalIndent(exp, "", "  "); err != nil {
+		return err
+	}
+	if files[archiveInitial], err = json.Marshal(exp.Initial); err != nil {
+		return err
+	}
+	if exp.Checkpoint != nil {
+		if files[archiveCheckpoint], err = json.Marshal(exp.Checkpoint); err != nil {
+			return err
+		}
+	}
+	if exp.RuleTable != "" {
+		files[archiveRuleTable] = []byte(exp.RuleTable)
+	}
+	manifest := archiveManifestJSON{Format: archiveFormat, Version: archiveVersion, Created: time.Now().UTC(), Files: make(map[string]string)}
+	for name, data := range files {
+		sum := sha256.Sum256(data)
+		manifest.Files[name] = hex.EncodeToString(sum[:])
+	}
+	if files[archiveManifest], err = json.MarshalIndent(manifest, "", "  "); err != nil {
+		return err
+	}
+
+	// The manifest goes first, so that it's the first thing anyone listing the archive sees
+	names := []string{archiveManifest}
+	for name := range files {
+		if name != archiveManifest {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: manifest.Created})
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+/*
+ReadArchive reads a .caz archive written by WriteArchive, checking every file against the
+manifest.
+*/
+func ReadArchive(r io.ReaderAt, size int64) (*Experiment, error) {
+	files, err := readArchiveFiles(r, size)
+	if err != nil {
+		return nil, err
+	}
+	exp := &Experiment{RuleTable: string(files[archiveRuleTable])}
+	if err := json.Unmarshal(files[archiveConfig], exp); err != nil {
+		return nil, fmt.Errorf("%s: %s", archiveConfig, err)
+	}
+	if files[archiveInitial] == nil {
+		return nil, fmt.Errorf("archive has no %s", archiveInitial)
+	}
+	exp.Initial = &StateGrid{}
+	if err := json.Unmarshal(files[archiveInitial], exp.Initial); err != nil {
+		return nil, fmt.Errorf("%s: %s", archiveInitial, err)
+	}
+	if files[archiveCheckpoint] != nil {
+		exp.Checkpoint = &StateGrid{}
+		if err := json.Unmarshal(files[archiveCheckpoint], exp.Checkpoint); err != nil {
+			return nil, fmt.Errorf("%s: %s", archiveCheckpoint, err)
+		}
+	}
+	return exp, nil
+}
+
+/*
+readArchiveFiles returns the contents of every file in a .caz archive, after checking the manifest
+and making sure every file matches its checksum and every file the manifest lists is there.
+*/
+func readArchiveFiles(r io.ReaderAt, size int64) (map[string][]byte, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		// Nothing in an archive should be anywhere near 256 MiB
+		data, err := ioutil.ReadAll(io.LimitReader(rc, 256<<20))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", f.Name, err)
+		}
+		files[f.Name] = data
+	}
+	var manifest archiveManifestJSON
+	if err := json.Unmarshal(files[archiveManifest], &manifest); err != nil {
+		return nil, fmt.Errorf("%s: %s", archiveManifest, err)
+	}
+	if manifest.Format != archiveFormat {
+		return nil, fmt.Errorf("not a cellaut archive")
+	}
+	if manifest.Version > archiveVersion {
+		return nil, fmt.Errorf("archive is version %d, but this cellaut only reads up to version %d", manifest.Version, archiveVersion)
+	}
+	for name, data := range files {
+		if name == archiveManifest {
+			continue
+		}
+		want, ok := manifest.Files[name]
+		if !ok {
+			return nil, fmt.Errorf("%s isn't in the manifest", name)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != want {
+			return nil, fmt.Errorf("%s doesn't match its checksum", name)
+		}
+	}
+	for name := range manifest.Files {
+		if _, ok := files[name]; !ok {
+			return nil, fmt.Errorf("%s is in the manifest but not the archive", name)
+		}
+	}
+	return files, nil
+}
+
+/*
+loadPatternFile reads a pattern from a file: Golly RLE if its name ends in .rle, the plaintext
+format if it ends in .cells, and otherwise rows of one-character States as for ParsePattern, with
+"-" as the empty State. It's placed in a grid with pad empty cells on every side.
+*/
+func loadPatternFile(path string, pad int) (*StateGrid, error) {
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var pattern *Pattern
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".rle":
+		pattern, _, err = parseRLE(string(text))
+	case ".cells":
+		pattern, err = LoadCells(bytes.NewReader(text))
+	default:
+		pattern, err = ParsePattern(string(text), NewStateTable("-"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	grid := NewStateGrid(pattern.Width+2*pad, pattern.Height+2*pad, NewStateTable("-"))
+	for y := 0; y < pattern.Height; y++ {
+		for x := 0; x < pattern.Width; x++ {
+			grid.Set(x+pad, y+pad, pattern.At(x, y))
+		}
+	}
+	return grid, nil
+}
+
+/*
+packCommand implements `cellaut pack <pattern> -o out.caz [--rule B3/S23] [--rule-file x.rule]
+[--neighborhood moore] [--ticks N] [--pad N]`, which runs a pattern for some ticks and packs the
+whole experiment into one archive.
+*/
+func packCommand(args []string) error {
+	fs := flag.NewFlagSet("pack", flag.ContinueOnError)
+	out := fs.String("o", "", "archive to write, which may be an s3://bucket/key URL")
+	ruleSpec := fs.String("rule", "B3/S23", "rulestring, or registered rule name and options, like 'life-like rule=B36/S23'")
+	ruleFile := fs.String("rule-file", "", "Golly .rule file to use instead of --rule")
+	neighborhood := fs.String("neighborhood", "moore", "moore, von-neumann or line")
+	ticks := fs.Int64("ticks", 0, "how many ticks to run before taking the checkpoint")
+	pad := fs.Int("pad", 5, "how much empty space to leave around the pattern")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: cellaut pack <pattern-file> -o <archive.caz>")
+	}
+	path := fs.Arg(0)
+	// Flags may come after the pattern too
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *out == "" || *ticks < 0 || *pad < 0 {
+		return fmt.Errorf("usage: cellaut pack <pattern-file> -o <archive.caz> [--ticks N] [--pad N]")
+	}
+	initial, err := loadPatternFile(path, *pad)
+	if err != nil {
+		return err
+	}
+	exp := &Experiment{Rule: *ruleSpec, Neighborhood: *neighborhood, Tick: *ticks, Initial: initial}
+	if *ruleFile != "" {
+		text, err := ioutil.ReadFile(*ruleFile)
+		if err != nil {
+			return err
+		}
+		exp.Rule, exp.Neighborhood, exp.RuleTable = "", "", string(text)
+	}
+	step, err := exp.Stepper()
+	if err != nil {
+		return err
+	}
+	exp.Checkpoint = initial
+	for tick := int64(0); tick < *ticks; tick++ {
+		exp.Checkpoint = step(exp.Checkpoint)
+	}
+	rule := exp.Rule
+	if *ruleFile != "" {
+		rule = filepath.Base(*ruleFile)
+	}
+	config := map[string]interface{}{"experiment": exp, "initial": initial, "rule-table": exp.RuleTable}
+	if exp.Run, err = NewRunMetadata(rule, 0, config); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := WriteArchive(&buf, exp); err != nil {
+		return err
+	}
+	return writeOutput(*out, buf.Bytes())
+}
+
+/*
+unpackCommand implements `cellaut unpack <archive.caz> [--dir DIR]`. It checks the archive, reruns
+the experiment to make sure it reproduces the checkpoint, and writes the files out into DIR, which
+defaults to the archive's name without the .caz.
+*/
+func unpackCommand(args []string) error {
+	fs := flag.NewFlagSet("unpack", flag.ContinueOnError)
+	dir := fs.String("dir", "", "directory to unpack into")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: cellaut unpack <archive.caz> [--dir DIR]")
+	}
+	path := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: cellaut unpack <archive.caz> [--dir DIR]")
+	}
+	if *dir == "" {
+		*dir = strings.TrimSuffix(path, filepath.Ext(path))
+	}
+	return unpack(os.Stdout, path, *dir)
+}
+
+/*
+unpack does the work of unpackCommand, reporting on the archive to w.
+*/
+func unpack(w io.Writer, path, dir string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	exp, err := ReadArchive(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	if err := exp.Verify(); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	files, err := readArchiveFiles(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// Names were checked against the manifest, but don't trust them with the filesystem
+		if filepath.Base(name) != name {
+			return fmt.Errorf("%s: bad file name '%s'", path, name)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), files[name], 0644); err != nil {
+			return err
+		}
+	}
+	rule := exp.Rule
+	if exp.RuleTable != "" {
+		rule = "from " + archiveRuleTable
+	}
+	if _, err := fmt.Fprintf(w, "%s: rule %s, %dx%d, tick %d reproduced; unpacked into %s\n",
+		path, rule, exp.Initial.Width, exp.Initial.Height, exp.Tick, dir); err != nil || exp.Run == nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "written by %s\n", exp.Run)
+	return err
+}
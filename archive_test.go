@@ -0,0 +1,120 @@
+package cellaut
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackUnpack(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	patternPath := filepath.Join(dir, "glider.rle")
+	assert.Nil(ioutil.WriteFile(patternPath, []byte("x = 3, y = 3, rule = B3/S23\nbo$2bo$3o!\n"), 0644))
+	archivePath := filepath.Join(dir, "glider.caz")
+	assert.Nil(packCommand([]string{patternPath, "-o", archivePath, "--ticks", "4", "--pad", "2"}))
+
+	data, err := ioutil.ReadFile(archivePath)
+	assert.Nil(err)
+	exp, err := ReadArchive(bytes.NewReader(data), int64(len(data)))
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Equal("B3/S23", exp.Rule)
+	assert.Equal(int64(4), exp.Tick)
+	assert.Equal(7, exp.Initial.Width)
+	assert.Nil(exp.Verify())
+	// After 4 ticks, the glider is back in shape one cell down and to the right
+	assert.Equal(
+		"-------\n"+
+			"-------\n"+
+			"-------\n"+
+			"----X--\n"+
+			"-----X-\n"+
+			"---XXX-\n"+
+			"-------\n",
+		(&Pattern{StateGrid: exp.Checkpoint}).String(),
+	)
+
+	var out bytes.Buffer
+	unpacked := filepath.Join(dir, "unpacked")
+	assert.Nil(unpack(&out, archivePath, unpacked))
+	assert.Contains(out.String(), "tick 4 reproduced")
+	for _, name := range []string{archiveManifest, archiveConfig, archiveInitial, archiveCheckpoint} {
+		_, err := ioutil.ReadFile(filepath.Join(unpacked, name))
+		assert.Nil(err, name)
+	}
+}
+
+func TestArchive_RuleTable(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	initial := NewStateGrid(5, 5, NewStateTable("-"))
+	initial.Set(2, 2, TableState(1))
+	exp := &Experiment{
+		RuleTable: "@RULE Spread\n@TABLE\nn_states:2\nneighborhood:vonNeumann\nsymmetries:rotate4\n0,1,0,0,0,1\n",
+		Initial:   initial,
+		Tick:      1,
+	}
+	step, err := exp.Stepper()
+	if !assert.Nil(err) {
+		return
+	}
+	exp.Checkpoint = step(initial)
+
+	var buf bytes.Buffer
+	assert.Nil(WriteArchive(&buf, exp))
+	read, err := ReadArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if !assert.Nil(err) {
+		return
+	}
+	assert.Equal(exp.RuleTable, read.RuleTable)
+	assert.Nil(read.Verify())
+	assert.Equal(int64(5), read.Checkpoint.Population(TableState(1)))
+
+	// A checkpoint that the rule doesn't lead to is caught
+	read.Checkpoint = initial
+	assert.NotNil(read.Verify())
+}
+
+func TestReadArchive_Tampered(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	initial := NewStateGrid(3, 3, NewStateTable("-"))
+	var buf bytes.Buffer
+	assert.Nil(WriteArchive(&buf, &Experiment{Rule: "B3/S23", Initial: initial}))
+
+	// Copy the archive, swapping in a different config.json
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(err)
+	var tampered bytes.Buffer
+	zw := zip.NewWriter(&tampered)
+	for _, f := range zr.File {
+		w, err := zw.Create(f.Name)
+		assert.Nil(err)
+		if f.Name == archiveConfig {
+			w.Write([]byte(`{"rule": "B36/S23", "tick": 0}`))
+			continue
+		}
+		rc, err := f.Open()
+		assert.Nil(err)
+		data, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		w.Write(data)
+	}
+	assert.Nil(zw.Close())
+
+	_, err = ReadArchive(bytes.NewReader(tampered.Bytes()), int64(tampered.Len()))
+	assert.EqualError(err, "config.json doesn't match its checksum")
+
+	_, err = ReadArchive(bytes.NewReader([]byte("not a zip")), 9)
+	assert.NotNil(err)
+}
@@ -0,0 +1,124 @@
+package cellaut
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitGrid(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	grid := NewBitGrid(70, 3, Plane)
+	grid.Set(0, 0, true)
+	grid.Set(64, 2, true)
+	grid.Set(69, 1, true)
+	grid.Set(70, 1, true)
+	grid.Set(-1, 0, true)
+	assert.True(grid.Get(64, 2))
+	assert.False(grid.Get(63, 2))
+	assert.False(grid.Get(70, 1))
+	assert.Equal(int64(3), grid.Population())
+	grid.Set(0, 0, false)
+	assert.Equal(int64(2), grid.Population())
+
+	unpacked := grid.Unpack()
+	assert.Equal(LifeAlive, unpacked.At(69, 1))
+	assert.Equal(LifeDead, unpacked.At(0, 0))
+	assert.True(PackStateGrid(unpacked, Plane).Unpack().Equal(unpacked))
+
+	// A blinker turns over, and a glider goes off the edge of a Plane and dies, but comes back
+	// around on a Torus
+	life, _ := ParseRulestring("B3/S23")
+	blinker := NewBitGrid(5, 5, Plane)
+	for x := 1; x <= 3; x++ {
+		blinker.Set(x, 2, true)
+	}
+	blinker.Step(life)
+	assert.Equal(".....\n..X..\n..X..\n..X..\n.....\n", blinkerString(blinker))
+	for _, topology := range []Topology{Plane, Torus} {
+		glider := NewBitGrid(8, 8, topology)
+		for _, cell := range gliderCells {
+			glider.Set(cell[0], cell[1]+5, true)
+		}
+		start := glider.Unpack()
+		// A glider moves a cell diagonally every 4 generations
+		for i := 0; i < 32; i++ {
+			glider.Step(life)
+		}
+		if topology == Torus {
+			assert.True(start.Equal(glider.Unpack()))
+		} else {
+			assert.Equal(int64(4), glider.Population())
+		}
+	}
+}
+
+/*
+blinkerString draws grid with "X" for live cells and "." for dead ones, top row first.
+*/
+func blinkerString(grid *BitGrid) string {
+	var s string
+	for y := grid.Height - 1; y >= 0; y-- {
+		for x := 0; x < grid.Width; x++ {
+			if grid.Get(x, y) {
+				s += "X"
+			} else {
+				s += "."
+			}
+		}
+		s += "\n"
+	}
+	return s
+}
+
+func TestBitGrid_MatchesEngine(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A BitGrid has to go through exactly the same generations as a Grid of LifeCellAuts, rows
+	// that span words and wrapping included
+	rng := rand.New(rand.NewSource(7))
+	rules := []string{"B3/S23", "B36/S23", "B2/S", "B3678/S34678"}
+	sizes := [][2]int{{70, 9}, {64, 5}, {2, 2}, {1, 6}, {130, 3}}
+	for _, rulestring := range rules {
+		rule, err := ParseRulestring(rulestring)
+		assert.Nil(err)
+		for _, size := range sizes {
+			for _, topology := range []Topology{Plane, Torus, Cylinder} {
+				start := randomStateGrid(size[0], size[1], []State{LifeDead, LifeAlive}, 0.4, rng)
+				world := NewLifeLikeGrid(size[0], size[1], rule, WithTopology(topology))
+				for y := 0; y < size[1]; y++ {
+					for x := 0; x < size[0]; x++ {
+						world.At(x, y).SetState(start.At(x, y))
+					}
+				}
+				packed := PackStateGrid(start, topology)
+				sim := NewSimulation()
+				assert.Nil(sim.Configure(SimulationConfig{World: world, Engine: SynchronousEngine, MaxTicks: 8}))
+				sim.Subscribe(func(event TickEvent) {
+					// Tick 0 only commits the starting states
+					if event.TickID > 0 {
+						packed.Step(rule)
+					}
+					want := TakeSnapshot(event.World, NewStateTable(LifeDead))
+					assert.True(want.Equal(packed.Unpack()), "%s, %dx%d, topology %d, tick %d", rulestring, size[0], size[1], topology, event.TickID)
+				})
+				assert.Nil(sim.Start())
+				assert.Nil(sim.Wait())
+			}
+		}
+	}
+}
+
+func BenchmarkBitGrid_Step(b *testing.B) {
+	life, _ := ParseRulestring("B3/S23")
+	start := randomStateGrid(1000, 1000, []State{LifeDead, LifeAlive}, 0.3, rand.New(rand.NewSource(1)))
+	grid := PackStateGrid(start, Torus)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		grid.Step(life)
+	}
+}
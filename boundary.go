@@ -0,0 +1,193 @@
+package cellaut
+
+import (
+	"fmt"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+/*
+BoundaryEdge says which side of a grid a BoundaryWarning is about.
+*/
+type BoundaryEdge string
+
+const (
+	EdgeLeft   BoundaryEdge = "left"
+	EdgeRight  BoundaryEdge = "right"
+	EdgeBottom BoundaryEdge = "bottom"
+	EdgeTop    BoundaryEdge = "top"
+	// EdgeWrapX and EdgeWrapY are the seams where a wrapping grid's opposite edges meet
+	EdgeWrapX BoundaryEdge = "wrap-x"
+	EdgeWrapY BoundaryEdge = "wrap-y"
+)
+
+/*
+BoundaryWarning says that what's going on in a grid may be an artifact of its edges rather than of
+the rule.
+
+On an edge that doesn't wrap, it means some cell that isn't empty got within the margin of the edge,
+where it's missing neighbors that an infinite universe would have given it. On a wrapping axis, it
+means the activity has spread far enough around that its two ends are within the margin of each
+other, and can start interacting with themselves.
+*/
+type BoundaryWarning struct {
+	Tick int64
+	Edge BoundaryEdge
+	// Distance is how many empty cells there are between the activity and the edge, or between
+	// the activity's two ends around a wrapping axis
+	Distance int
+}
+
+func (warning BoundaryWarning) String() string {
+	if warning.Edge == EdgeWrapX || warning.Edge == EdgeWrapY {
+		return fmt.Sprintf("tick %d: activity wraps around to within %d cells of itself (%s)", warning.Tick, warning.Distance, warning.Edge)
+	}
+	return fmt.Sprintf("tick %d: activity within %d cells of the %s edge", warning.Tick, warning.Distance, warning.Edge)
+}
+
+/*
+CheckBoundary returns a BoundaryWarning for each edge of grid that the cells that aren't empty have
+got within margin cells of, given how the grid's edges are joined up.
+*/
+func CheckBoundary(tick int64, grid *StateGrid, margin int, topology Topology) []BoundaryWarning {
+	extent, ok := grid.Extent()
+	if !ok {
+		return nil
+	}
+	var warnings []BoundaryWarning
+	warn := func(edge BoundaryEdge, distance int) {
+		if distance < margin {
+			warnings = append(warnings, BoundaryWarning{Tick: tick, Edge: edge, Distance: distance})
+		}
+	}
+	if topology == Torus || topology == Cylinder {
+		columns := make([]bool, grid.Width)
+		for y := extent.Y; y < extent.Y+extent.Height; y++ {
+			for x := extent.X; x < extent.X+extent.Width; x++ {
+				columns[x] = columns[x] || grid.IDAt(x, y) != 0
+			}
+		}
+		warn(EdgeWrapX, widestGap(columns))
+	} else {
+		warn(EdgeLeft, extent.X)
+		warn(EdgeRight, grid.Width-extent.X-extent.Width)
+	}
+	if topology == Torus {
+		rows := make([]bool, grid.Height)
+		for y := extent.Y; y < extent.Y+extent.Height; y++ {
+			for x := extent.X; x < extent.X+extent.Width; x++ {
+				rows[y] = rows[y] || grid.IDAt(x, y) != 0
+			}
+		}
+		warn(EdgeWrapY, widestGap(rows))
+	} else {
+		warn(EdgeBottom, extent.Y)
+		warn(EdgeTop, grid.Height-extent.Y-extent.Height)
+	}
+	return warnings
+}
+
+/*
+widestGap returns the longest run of false in occupied, going round from the end back to the start.
+That's the room the activity has left before it meets itself.
+*/
+func widestGap(occupied []bool) int {
+	// Start counting just after an occupied line, so no run gets split at the end of the slice
+	start := -1
+	for i, o := range occupied {
+		if o {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return len(occupied)
+	}
+	widest, run := 0, 0
+	for i := 1; i <= len(occupied); i++ {
+		if occupied[(start+i)%len(occupied)] {
+			run = 0
+			continue
+		}
+		run++
+		if run > widest {
+			widest = run
+		}
+	}
+	return widest
+}
+
+/*
+BoundaryChecker runs CheckBoundary on every tick of a Simulation and remembers the first warning
+about each edge, so that a run can be flagged once rather than on every tick it goes on touching
+the edge.
+
+It's safe for concurrent use.
+*/
+type BoundaryChecker struct {
+	Margin   int
+	Topology Topology
+	// Empty is the State that Sink doesn't count as activity
+	Empty State
+	mu    sync.Mutex
+	// first is the first warning about each edge, in the order they happened
+	first []BoundaryWarning
+}
+
+/*
+NewBoundaryChecker returns a BoundaryChecker that warns about activity within margin cells of an
+edge of a grid with the given topology.
+*/
+func NewBoundaryChecker(margin int, topology Topology) *BoundaryChecker {
+	return &BoundaryChecker{Margin: margin, Topology: topology}
+}
+
+/*
+Check checks grid, and returns the warnings about edges that haven't been warned about before.
+*/
+func (checker *BoundaryChecker) Check(tick int64, grid *StateGrid) []BoundaryWarning {
+	checker.mu.Lock()
+	defer checker.mu.Unlock()
+	warnings := CheckBoundary(tick, grid, checker.Margin, checker.Topology)
+	var fresh []BoundaryWarning
+	for _, warning := range warnings {
+		seen := false
+		for _, first := range checker.first {
+			seen = seen || first.Edge == warning.Edge
+		}
+		if !seen {
+			checker.first = append(checker.first, warning)
+			fresh = append(fresh, warning)
+		}
+	}
+	return fresh
+}
+
+/*
+Warnings returns the first warning about each edge so far.
+*/
+func (checker *BoundaryChecker) Warnings() []BoundaryWarning {
+	checker.mu.Lock()
+	defer checker.mu.Unlock()
+	return append([]BoundaryWarning(nil), checker.first...)
+}
+
+/*
+Sink returns a Sink that checks every tick of a Simulation, and logs a warning the first time
+activity gets near each edge. If the World is a *Grid, its own Topology is used instead of the
+BoundaryChecker's.
+*/
+func (checker *BoundaryChecker) Sink() Sink {
+	return func(event TickEvent) {
+		if world, ok := event.World.(*Grid); ok {
+			checker.mu.Lock()
+			checker.Topology = world.Topology()
+			checker.mu.Unlock()
+		}
+		grid := TakeSnapshot(event.World, NewStateTable(checker.Empty))
+		for _, warning := range checker.Check(event.TickID, grid) {
+			log.WithFields(log.Fields{"tick": warning.Tick, "edge": warning.Edge}).Warn(warning.String())
+		}
+	}
+}
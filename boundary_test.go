@@ -0,0 +1,85 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckBoundary(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	grid := NewStateGrid(10, 8, NewStateTable("-"))
+	assert.Empty(CheckBoundary(0, grid, 3, Plane))
+
+	// A blob in the middle is fine, until it gets near the left and top
+	grid.Set(4, 3, "X")
+	grid.Set(5, 4, "X")
+	assert.Empty(CheckBoundary(0, grid, 3, Plane))
+	grid.Set(1, 6, "X")
+	assert.Equal([]BoundaryWarning{
+		{Tick: 7, Edge: EdgeLeft, Distance: 1},
+		{Tick: 7, Edge: EdgeTop, Distance: 1},
+	}, CheckBoundary(7, grid, 3, Plane))
+
+	// On a torus there's no edge to be near, but there's room all the way round yet
+	assert.Empty(CheckBoundary(7, grid, 3, Torus))
+
+	// Until something on the far side closes the gap
+	grid.Set(8, 0, "X")
+	assert.Equal([]BoundaryWarning{
+		{Tick: 7, Edge: EdgeWrapX, Distance: 2},
+		{Tick: 7, Edge: EdgeWrapY, Distance: 2},
+	}, CheckBoundary(7, grid, 3, Torus))
+	assert.Equal([]BoundaryWarning{
+		{Tick: 7, Edge: EdgeWrapX, Distance: 2},
+		{Tick: 7, Edge: EdgeBottom, Distance: 0},
+		{Tick: 7, Edge: EdgeTop, Distance: 1},
+	}, CheckBoundary(7, grid, 3, Cylinder))
+}
+
+func TestWidestGap(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal(4, widestGap([]bool{false, false, false, false}))
+	assert.Equal(3, widestGap([]bool{true, false, false, false, true, false}))
+	// The gap at the ends is one gap
+	assert.Equal(4, widestGap([]bool{false, false, true, true, false, false}))
+	assert.Equal(0, widestGap([]bool{true, true}))
+}
+
+func TestBoundaryChecker(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	checker := NewBoundaryChecker(2, Plane)
+	grid := NewStateGrid(5, 5, NewStateTable("-"))
+	grid.Set(0, 2, "X")
+	assert.Equal([]BoundaryWarning{{Tick: 1, Edge: EdgeLeft, Distance: 0}}, checker.Check(1, grid))
+	// Still touching the left edge, but that's been said
+	assert.Empty(checker.Check(2, grid))
+	grid.Set(0, 4, "X")
+	assert.Equal([]BoundaryWarning{{Tick: 3, Edge: EdgeTop, Distance: 0}}, checker.Check(3, grid))
+	assert.Equal([]BoundaryWarning{
+		{Tick: 1, Edge: EdgeLeft, Distance: 0},
+		{Tick: 3, Edge: EdgeTop, Distance: 0},
+	}, checker.Warnings())
+	assert.Equal("tick 3: activity within 0 cells of the top edge", checker.Warnings()[1].String())
+
+	// As a Sink, it uses the Grid's own topology. Goo all over a torus has nowhere left to go.
+	checker = NewBoundaryChecker(1, Plane)
+	world := NewGrid(3, 3, func(x, y int) CellAut { return NewGooCellAut(y*3 + x) }, WithTopology(Torus))
+	world.At(1, 1).SetState("X")
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: world, MaxTicks: 4}))
+	sim.Subscribe(checker.Sink())
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	edges := []BoundaryEdge{}
+	for _, warning := range checker.Warnings() {
+		edges = append(edges, warning.Edge)
+	}
+	assert.Equal([]BoundaryEdge{EdgeWrapX, EdgeWrapY}, edges)
+}
@@ -0,0 +1,151 @@
+package cellaut
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+/*
+BufferResult is how a goo grid performed with one ChannelBuffer size.
+*/
+type BufferResult struct {
+	ChannelBuffer int
+	Ticks         int
+	Elapsed       time.Duration
+	// MeanTick and MaxTick are how long Tick took to return
+	MeanTick time.Duration
+	MaxTick  time.Duration
+	// Latency is how long the states the cells sent each other took to arrive
+	Latency *LatencyHistogram
+	// Memory is the estimated memory used by the cells and the Ticker
+	Memory int64
+}
+
+/*
+TicksPerSecond is the throughput of the run.
+*/
+func (result BufferResult) TicksPerSecond() float64 {
+	return float64(result.Ticks) / result.Elapsed.Seconds()
+}
+
+/*
+BufferExperiment runs a width×height grid of GooCellAuts for the given number of ticks once for
+each channel buffer size, and reports how long the ticks took.
+
+To keep the grid busy, the goo in the bottom left cell is switched on and off every tick, which
+sends waves of goo and non-goo across the grid.
+*/
+func BufferExperiment(sizes []int, width, height, ticks int) ([]BufferResult, error) {
+	if width < 1 || height < 1 || ticks < 1 {
+		return nil, fmt.Errorf("width, height and ticks must be at least 1")
+	}
+	var results []BufferResult
+	for _, size := range sizes {
+		if size < 1 {
+			return nil, fmt.Errorf("channel buffer size must be at least 1; got %d", size)
+		}
+		result, err := runBufferExperiment(size, width, height, ticks)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+/*
+newGooGrid returns a width×height grid of GooCellAuts, in row order, wired to their neighbors with
+channels of the given buffer size.
+*/
+func newGooGrid(width, height, channelBuffer int) []*GooCellAut {
+	cells := make([]*GooCellAut, width*height)
+	NewGrid(width, height, func(x, y int) CellAut {
+		i := y*width + x
+		cells[i] = NewGooCellAut(i)
+		cells[i].ChannelBuffer = channelBuffer
+		return cells[i]
+	})
+	return cells
+}
+
+func runBufferExperiment(size, width, height, ticks int) (BufferResult, error) {
+	cells := newGooGrid(width, height, size)
+	ticker := &Ticker{Latency: NewLatencyHistogram()}
+	for _, aut := range cells {
+		ticker.Start(aut, nil)
+	}
+
+	result := BufferResult{ChannelBuffer: size, Ticks: ticks, Latency: ticker.Latency}
+	seed := [2]State{"X", "-"}
+	start := time.Now()
+	for i := 0; i < ticks; i++ {
+		cells[0].SetState(seed[i%2])
+		tickStart := time.Now()
+		ticker.Tick()
+		if elapsed := time.Since(tickStart); elapsed > result.MaxTick {
+			result.MaxTick = elapsed
+		}
+	}
+	result.Elapsed = time.Since(start)
+	result.MeanTick = result.Elapsed / time.Duration(ticks)
+	result.Memory = ticker.MemoryUsage()
+	for _, aut := range cells {
+		result.Memory += aut.MemoryUsage()
+	}
+	stopErr := ticker.Stop(DefaultStopTimeout)
+	if err := ticker.Err(); err != nil {
+		return result, err
+	}
+	return result, stopErr
+}
+
+/*
+WriteBufferResults writes results as a table.
+*/
+func WriteBufferResults(w io.Writer, results []BufferResult) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "BUFFER\tTICKS/S\tMEAN TICK\tMAX TICK\tP50 LATENCY\tP99 LATENCY\tMEMORY")
+	for _, result := range results {
+		var p50, p99 time.Duration
+		if result.Latency != nil {
+			p50, p99 = result.Latency.Quantile(0.5), result.Latency.Quantile(0.99)
+		}
+		fmt.Fprintf(tw, "%d\t%.1f\t%s\t%s\t%s\t%s\t%s\n", result.ChannelBuffer, result.TicksPerSecond(), result.MeanTick,
+			result.MaxTick, p50, p99, formatBytes(result.Memory))
+	}
+	return tw.Flush()
+}
+
+/*
+buffersCommand implements `cellaut buffers --sizes 1,2,4 --width 100 --height 100 --ticks 100`,
+which runs BufferExperiment and prints the results.
+*/
+func buffersCommand(args []string) error {
+	fs := flag.NewFlagSet("buffers", flag.ContinueOnError)
+	sizesFlag := fs.String("sizes", "1,2,4,8", "comma-separated channel buffer sizes to try")
+	width := fs.Int("width", 100, "width of the grid")
+	height := fs.Int("height", 100, "height of the grid")
+	ticks := fs.Int("ticks", 100, "how many ticks to run for each size")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var sizes []int
+	for _, field := range strings.Split(*sizesFlag, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return fmt.Errorf("bad buffer size '%s'", field)
+		}
+		sizes = append(sizes, size)
+	}
+	results, err := BufferExperiment(sizes, *width, *height, *ticks)
+	if err != nil {
+		return err
+	}
+	return WriteBufferResults(os.Stdout, results)
+}
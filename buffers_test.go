@@ -0,0 +1,40 @@
+package cellaut
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferExperiment(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	results, err := BufferExperiment([]int{1, 3}, 4, 3, 10)
+	assert.Nil(err)
+	if !assert.Len(results, 2) {
+		return
+	}
+	for i, size := range []int{1, 3} {
+		assert.Equal(size, results[i].ChannelBuffer)
+		assert.Equal(10, results[i].Ticks)
+		assert.True(results[i].MaxTick >= results[i].MeanTick)
+		assert.True(results[i].TicksPerSecond() > 0)
+		// The goo goes back and forth across the grid, so states are always on the move
+		assert.True(results[i].Latency.Count() > 0)
+		assert.True(results[i].Latency.Quantile(0.99) <= results[i].Latency.Max())
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(WriteBufferResults(&buf, results))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(lines, 3)
+	assert.True(strings.HasPrefix(lines[2], "3 "))
+
+	_, err = BufferExperiment([]int{0}, 4, 3, 10)
+	assert.NotNil(err)
+	_, err = BufferExperiment([]int{1}, 0, 3, 10)
+	assert.NotNil(err)
+}
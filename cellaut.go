@@ -0,0 +1,1135 @@
+package cellaut
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	NeighborUp NeighborIndex = 0
+	NeighborRt NeighborIndex = 1
+	// NeighborDn = ^ NeighborUp = NeighborUp.Recip()
+	NeighborDn NeighborIndex = 255
+	// NeighborLf = ^ NeighborRt = NeighborRt.Recip()
+	NeighborLf NeighborIndex = 254
+
+	// The diagonal neighbors, for the Moore neighborhood. Each is paired with its opposite by ^,
+	// like the others, so that Recip works for them too.
+	NeighborUpRt NeighborIndex = 2
+	NeighborDnLf NeighborIndex = 253
+	NeighborUpLf NeighborIndex = 3
+	NeighborDnRt NeighborIndex = 252
+
+	// The neighbors in the slices in front of and behind a cell, in a Grid3D. The other 16 cells
+	// around a cell in 3D are given by Neighbor3D.
+	NeighborFwd NeighborIndex = 4
+	NeighborBk  NeighborIndex = 251
+)
+
+type State string
+
+type NeighborIndex uint8
+
+/*
+Recip returns the reciprocal of the NeighborIndex (i.e. the opposite direction)
+
+For example, NeighborUp.Recip() = NeighborDn. NeighborDn.Recip() = NeighborUp. Etc.
+*/
+func (i NeighborIndex) Recip() NeighborIndex {
+	return ^i
+}
+
+type Ticker struct {
+	// tickID is only ever modified by Tick. Other goroutines must read it atomically.
+	tickID int64
+	// ticking is 1 while Tick is running
+	ticking int32
+	// mu guards destinations, cellCallbacks, the pending registrations and stopped, since cells
+	// can be registered from any goroutine, even while Tick is running.
+	mu           sync.Mutex
+	destinations []chan int64
+	// cellCallbacks[i] is the callbacks of the CellAut that destinations[i] belongs to, if it was
+	// started with Start
+	cellCallbacks []*CellAutCallbacks
+	// pendingDestinations and pendingCallbacks are registered, but don't get ticks until the next
+	// tick starts
+	pendingDestinations []chan int64
+	pendingCallbacks    []*CellAutCallbacks
+	// pendingRemovals are the callbacks of the CellAuts to drop at the start of the next tick
+	pendingRemovals []*CellAutCallbacks
+	// byCell finds the callbacks of a CellAut started with Start
+	byCell map[CellAut]*CellAutCallbacks
+	// awake is scratch space for Tick
+	awake     []bool
+	waitGroup sync.WaitGroup
+	// commitGroup is the barrier that every destination passes through after committing its new
+	// state and before telling its neighbors about it.
+	commitGroup sync.WaitGroup
+	// sends counts the states sent to neighbors during the current tick, and lastSends is how many
+	// were sent during the last one. Cells only send their state when it changes, so no sends means
+	// nothing changed.
+	sends     int64
+	lastSends int64
+	// done is closed by Stop to tell the CellAuts to exit
+	done     chan struct{}
+	doneOnce sync.Once
+	stopped  bool
+	// running counts the CellAut goroutines started with Start that haven't exited yet
+	running      sync.WaitGroup
+	runningCount int64
+	// Supervisor handles the errors that CellAuts report. If it's nil when the Ticker is first
+	// used, a Supervisor with the ErrorHalt policy is created.
+	Supervisor     *Supervisor
+	supervisorOnce sync.Once
+	// Tracer, if it's set, gets a span for every tick and its phases
+	Tracer Tracer
+	// Latency, if it's set, counts how long every state sent between neighbors takes to be
+	// received. It has to be set before any cells are started.
+	Latency *LatencyHistogram
+	// tickMu is held for the whole of every tick, so that cancellation can wait for the current
+	// one to finish
+	tickMu sync.Mutex
+	// cancelErr is the error of the first context passed to StartCtx to be done. It's guarded by
+	// mu, as is watched, the Done channels of the contexts being watched.
+	cancelErr error
+	watched   map[<-chan struct{}]bool
+}
+
+func (ticker *Ticker) supervisor() *Supervisor {
+	ticker.supervisorOnce.Do(func() {
+		if ticker.Supervisor == nil {
+			ticker.Supervisor = NewSupervisor(ErrorHalt)
+		}
+	})
+	return ticker.Supervisor
+}
+
+/*
+Err returns the error that halted the Ticker, if a CellAut reported one under the ErrorHalt policy.
+
+If a context passed to StartCtx is done, Err returns its error.
+
+Once Err is non-nil, Tick does nothing.
+*/
+func (ticker *Ticker) Err() error {
+	if err := ticker.supervisor().Err(); err != nil {
+		return err
+	}
+	ticker.mu.Lock()
+	defer ticker.mu.Unlock()
+	return ticker.cancelErr
+}
+
+/*
+Done returns the channel that Stop closes. It's the `done` channel to pass to CellAut.Start.
+*/
+func (ticker *Ticker) Done() chan struct{} {
+	ticker.doneOnce.Do(func() { ticker.done = make(chan struct{}) })
+	return ticker.done
+}
+
+/*
+Start registers aut with the Ticker and runs it in a new goroutine, which Stop will wait for. It's
+safe to call while Tick is running, in which case aut gets its first tick from the next call to
+Tick. It does nothing after Stop.
+
+If aut panics, the panic is reported to the Supervisor and whatever aut still owed the current
+tick is settled so the other CellAuts don't hang, including the state it was in the middle of
+receiving or sending. Under the ErrorRestart policy, aut is then
+started again from its last committed state. Under any other policy it stays dead and the Ticker
+halts, since a dead CellAut can't keep up its end of the tick protocol. States sent to it after
+that are counted as received and thrown away.
+*/
+func (ticker *Ticker) Start(aut CellAut, stateLedger chan StateRecord) {
+	callbacks := ticker.Callbacks()
+	callbacks.cell = aut
+	callbacks.lastAckedTick = -1
+	// The tick channel is buffered so that, if aut panics, we can tell whether it had already
+	// taken the tick.
+	callbacks.tick = make(chan int64, 1)
+	// Each CellAut gets its own done channel, so that Remove can stop it on its own
+	callbacks.done = make(chan struct{})
+	done := callbacks.done
+	ticker.mu.Lock()
+	defer ticker.mu.Unlock()
+	if ticker.stopped {
+		return
+	}
+	ticker.register(callbacks.tick, callbacks)
+	if ticker.byCell == nil {
+		ticker.byCell = make(map[CellAut]*CellAutCallbacks)
+	}
+	ticker.byCell[aut] = callbacks
+	ticker.running.Add(1)
+	atomic.AddInt64(&ticker.runningCount, 1)
+	go func() {
+		defer ticker.running.Done()
+		defer atomic.AddInt64(&ticker.runningCount, -1)
+		for runRecovered(aut, done, stateLedger, callbacks) {
+			// Forget whatever the CellAut was in the middle of
+			aut.SetState(aut.GetState())
+			callbacks.mu.Lock()
+			callbacks.restarts++
+			callbacks.mu.Unlock()
+		}
+		callbacks.mu.Lock()
+		callbacks.exited = true
+		dead := callbacks.dead
+		callbacks.mu.Unlock()
+		if dead {
+			callbacks.drain(aut, done)
+		}
+	}()
+}
+
+/*
+StartCtx is like Start, except that once ctx is done, the whole Ticker shuts down, the way an
+errgroup does: Tick stops ticking, Err returns ctx's error, and every CellAut is told to exit.
+
+If a tick is underway when ctx is done, it's allowed to finish first, so that every state sent
+during it is received and no CellAut is left blocked sending to a neighbor that's gone. Shutting
+down happens in the background; Done is closed when it starts.
+*/
+func (ticker *Ticker) StartCtx(ctx context.Context, aut CellAut, stateLedger chan StateRecord) {
+	ticker.Start(aut, stateLedger)
+	ticker.watch(ctx)
+}
+
+/*
+watch shuts the Ticker down once ctx is done. Each context is only watched once, however many
+CellAuts it's given to.
+*/
+func (ticker *Ticker) watch(ctx context.Context) {
+	ctxDone := ctx.Done()
+	if ctxDone == nil {
+		// It can never be done
+		return
+	}
+	ticker.mu.Lock()
+	defer ticker.mu.Unlock()
+	if ticker.stopped || ticker.watched[ctxDone] {
+		return
+	}
+	if ticker.watched == nil {
+		ticker.watched = make(map[<-chan struct{}]bool)
+	}
+	ticker.watched[ctxDone] = true
+	tickerDone := ticker.Done()
+	go func() {
+		select {
+		case <-ctxDone:
+			ticker.cancel(ctx.Err())
+		case <-tickerDone:
+		}
+	}()
+}
+
+/*
+cancel halts the Ticker with err, waits for the current tick, if there is one, to finish, and
+stops it.
+*/
+func (ticker *Ticker) cancel(err error) {
+	ticker.mu.Lock()
+	if ticker.cancelErr == nil {
+		ticker.cancelErr = err
+	}
+	ticker.mu.Unlock()
+	ticker.tickMu.Lock()
+	ticker.tickMu.Unlock()
+	ticker.Stop(DefaultStopTimeout)
+}
+
+/*
+Stop tells every CellAut to exit by closing the Done channel, then waits up to timeout for the
+goroutines launched by Start to return.
+
+Once they have, the tick channels are closed. If they haven't by the timeout, Stop returns an error
+and leaves the tick channels open, since a CellAut still selecting on its tick channel would
+otherwise see a never-ending stream of ticks.
+
+Tick does nothing once Stop has been called.
+*/
+func (ticker *Ticker) Stop(timeout time.Duration) error {
+	ticker.mu.Lock()
+	if ticker.stopped {
+		ticker.mu.Unlock()
+		return nil
+	}
+	ticker.stopped = true
+	for _, callbacks := range ticker.startedCallbacks() {
+		callbacks.closeDone()
+	}
+	ticker.mu.Unlock()
+	close(ticker.Done())
+
+	exited := make(chan struct{})
+	go func() {
+		ticker.running.Wait()
+		close(exited)
+	}()
+	select {
+	case <-exited:
+	case <-time.After(timeout):
+		return fmt.Errorf("%d CellAuts still running %s after Stop", atomic.LoadInt64(&ticker.runningCount), timeout)
+	}
+	ticker.mu.Lock()
+	defer ticker.mu.Unlock()
+	ticker.applyPending()
+	for _, dest := range ticker.destinations {
+		close(dest)
+	}
+	return nil
+}
+
+/*
+TickChan registers a new destination for ticks and returns it. Like Start, it's safe to call while
+Tick is running, and the new channel gets its first tick from the next call to Tick.
+*/
+func (ticker *Ticker) TickChan() chan int64 {
+	newChan := make(chan int64)
+	ticker.mu.Lock()
+	defer ticker.mu.Unlock()
+	ticker.register(newChan, nil)
+	return newChan
+}
+
+/*
+register adds a destination, to be applied at the start of the next tick. The caller must hold
+ticker.mu.
+*/
+func (ticker *Ticker) register(dest chan int64, callbacks *CellAutCallbacks) {
+	ticker.pendingDestinations = append(ticker.pendingDestinations, dest)
+	ticker.pendingCallbacks = append(ticker.pendingCallbacks, callbacks)
+}
+
+/*
+Remove tells aut, which must have been started with Start, to exit, and stops sending it ticks. Like
+Start, it's safe to call while Tick is running, and takes effect at the start of the next tick.
+
+aut's tick channel is never closed; it only sees its done channel close.
+*/
+func (ticker *Ticker) Remove(aut CellAut) error {
+	ticker.mu.Lock()
+	defer ticker.mu.Unlock()
+	callbacks, ok := ticker.byCell[aut]
+	if !ok {
+		return fmt.Errorf("cell %s isn't running on this Ticker", cellName(aut))
+	}
+	ticker.pendingRemovals = append(ticker.pendingRemovals, callbacks)
+	return nil
+}
+
+/*
+SetAsleep puts aut, which must have been started with Start, to sleep or wakes it up. A sleeping
+CellAut gets no ticks, so its state can't change, but it still receives its neighbors' states. It's
+up to the caller to wake it in time to commit whatever it receives.
+
+Like Start, it's safe to call while Tick is running, and takes effect at the start of the next tick.
+*/
+func (ticker *Ticker) SetAsleep(aut CellAut, asleep bool) error {
+	ticker.mu.Lock()
+	callbacks, ok := ticker.byCell[aut]
+	ticker.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("cell %s isn't running on this Ticker", cellName(aut))
+	}
+	callbacks.mu.Lock()
+	defer callbacks.mu.Unlock()
+	callbacks.asleep = asleep
+	return nil
+}
+
+/*
+startedCallbacks returns the callbacks of every CellAut started with Start and not yet removed,
+including the pending ones. The caller must hold ticker.mu.
+*/
+func (ticker *Ticker) startedCallbacks() []*CellAutCallbacks {
+	var started []*CellAutCallbacks
+	for _, list := range [][]*CellAutCallbacks{ticker.cellCallbacks, ticker.pendingCallbacks} {
+		for _, callbacks := range list {
+			if callbacks != nil {
+				started = append(started, callbacks)
+			}
+		}
+	}
+	return started
+}
+
+/*
+applyPending moves the pending registrations into the destinations, and drops the pending removals.
+The caller must hold ticker.mu.
+*/
+func (ticker *Ticker) applyPending() {
+	ticker.destinations = append(ticker.destinations, ticker.pendingDestinations...)
+	ticker.cellCallbacks = append(ticker.cellCallbacks, ticker.pendingCallbacks...)
+	ticker.pendingDestinations = nil
+	ticker.pendingCallbacks = nil
+	if len(ticker.pendingRemovals) == 0 {
+		return
+	}
+
+	removed := make(map[*CellAutCallbacks]bool)
+	for _, callbacks := range ticker.pendingRemovals {
+		removed[callbacks] = true
+		delete(ticker.byCell, callbacks.cell)
+		callbacks.closeDone()
+	}
+	ticker.pendingRemovals = nil
+	var destinations []chan int64
+	var cellCallbacks []*CellAutCallbacks
+	for i, callbacks := range ticker.cellCallbacks {
+		if callbacks != nil && removed[callbacks] {
+			continue
+		}
+		destinations = append(destinations, ticker.destinations[i])
+		cellCallbacks = append(cellCallbacks, callbacks)
+	}
+	ticker.destinations, ticker.cellCallbacks = destinations, cellCallbacks
+}
+
+func (ticker *Ticker) add(aut CellAut, stateLedger chan StateRecord) error {
+	ticker.Start(aut, stateLedger)
+	return nil
+}
+
+func (ticker *Ticker) currentTick() int64 {
+	return atomic.LoadInt64(&ticker.tickID)
+}
+
+func (ticker *Ticker) Tick() {
+	ctx, span := startSpan(context.Background(), ticker.Tracer, "tick")
+	span.SetAttribute("tick.id", atomic.LoadInt64(&ticker.tickID))
+	ticker.TickContext(ctx)
+	span.End()
+}
+
+/*
+TickContext is like Tick, but the spans for the tick's phases are children of the span in ctx, if
+there's a Tracer.
+*/
+func (ticker *Ticker) TickContext(ctx context.Context) {
+	ticker.tickMu.Lock()
+	defer ticker.tickMu.Unlock()
+	if ticker.Err() != nil {
+		return
+	}
+	ticker.mu.Lock()
+	if ticker.stopped {
+		ticker.mu.Unlock()
+		return
+	}
+	// This is the tick boundary, where cells registered since the last tick join in. Whatever
+	// registers during the tick waits for the next one.
+	ticker.applyPending()
+	destinations, cellCallbacks := ticker.destinations, ticker.cellCallbacks
+	ticker.mu.Unlock()
+
+	atomic.StoreInt32(&ticker.ticking, 1)
+	atomic.StoreInt64(&ticker.sends, 0)
+	_, span := startSpan(ctx, ticker.Tracer, "compute")
+	// awake is reused from tick to tick, since Tick is never called concurrently
+	if cap(ticker.awake) < len(destinations) {
+		ticker.awake = make([]bool, len(destinations))
+	}
+	awake := ticker.awake[:len(destinations)]
+	nAwake := 0
+	for i := range destinations {
+		awake[i] = cellCallbacks[i] == nil || !cellCallbacks[i].isAsleep()
+		if awake[i] {
+			nAwake++
+		}
+	}
+	// Wait at least until all destinations have called their `tickProcessed()`
+	// callbacks.
+	ticker.waitGroup.Add(nAwake)
+	ticker.commitGroup.Add(nAwake)
+	for i, dest := range destinations {
+		if !awake[i] {
+			continue
+		}
+		if callbacks := cellCallbacks[i]; callbacks != nil {
+			callbacks.deliver(ticker.tickID)
+			continue
+		}
+		dest <- ticker.tickID
+	}
+	ticker.commitGroup.Wait()
+	span.End()
+	_, span = startSpan(ctx, ticker.Tracer, "exchange")
+	ticker.waitGroup.Wait()
+	span.End()
+	ticker.lastSends = atomic.LoadInt64(&ticker.sends)
+	atomic.AddInt64(&ticker.tickID, 1)
+	atomic.StoreInt32(&ticker.ticking, 0)
+}
+
+func (ticker *Ticker) Callbacks() *CellAutCallbacks {
+	return &CellAutCallbacks{
+		WaitGroup:   &ticker.waitGroup,
+		CommitGroup: &ticker.commitGroup,
+		Supervisor:  ticker.supervisor(),
+		sends:       &ticker.sends,
+		latency:     ticker.Latency,
+	}
+}
+
+type CellAutCallbacks struct {
+	WaitGroup   *sync.WaitGroup
+	CommitGroup *sync.WaitGroup
+	Supervisor  *Supervisor
+	// sends is the Ticker's count of states sent this tick
+	sends *int64
+	// latency is the Ticker's LatencyHistogram, if it has one
+	latency *LatencyHistogram
+
+	// tick is non-nil when these callbacks belong to a single CellAut started by Ticker.Start. In
+	// that case, the rest of the fields keep track of what the CellAut still owes the current tick,
+	// so that it can be settled if the CellAut panics.
+	tick      chan int64
+	mu        sync.Mutex
+	tickID    int64
+	pending   bool
+	committed bool
+	sending   bool
+	allSent   bool
+	dead      bool
+	// inFlight and unsent are only touched from the CellAut's own goroutine, so they don't need mu.
+	// inFlight is set while it handles a NeighborMessage, and unsent while it sends one.
+	inFlight bool
+	unsent   bool
+	// asleep is set by Ticker.SetAsleep
+	asleep bool
+	// done is closed when the CellAut is removed or the Ticker is stopped
+	done     chan struct{}
+	doneOnce sync.Once
+	// These are only kept for Health
+	cell          CellAut
+	exited        bool
+	restarts      int
+	lastAckedTick int64
+}
+
+func (callbacks *CellAutCallbacks) isAsleep() bool {
+	callbacks.mu.Lock()
+	defer callbacks.mu.Unlock()
+	return callbacks.asleep
+}
+
+/*
+cellName names the CellAut that the callbacks belong to, for its StateRecords. That's aut, unless
+aut is embedded in a CellAut that was started in its place, like the RuleCellAut in a LifeCellAut.
+*/
+func (callbacks *CellAutCallbacks) cellName(aut CellAut) string {
+	if callbacks.cell != nil {
+		return cellName(callbacks.cell)
+	}
+	return cellName(aut)
+}
+
+func (callbacks *CellAutCallbacks) closeDone() {
+	callbacks.doneOnce.Do(func() { close(callbacks.done) })
+}
+
+/*
+ReportError tells the Supervisor that aut ran into a problem, and returns the ErrorPolicy that aut
+must now apply to itself.
+
+A CellAut that reports an error must still finish taking part in the current tick, or the whole
+simulation will hang. Under ErrorSkipCell that goes for every tick after it, too.
+*/
+func (callbacks *CellAutCallbacks) ReportError(aut CellAut, tickID int64, err error) ErrorPolicy {
+	return callbacks.Supervisor.Report(&CellError{Cell: aut, TickID: tickID, Err: err})
+}
+
+/*
+StateCommitted must be called by every CellAut once per tick, after it has committed its new state
+and before it sends that state to any neighbor.
+
+It blocks until every CellAut has committed. Otherwise a CellAut could hear about a neighbor's new
+state before it has even seen the tick, and it would commit that state a tick early.
+*/
+func (callbacks *CellAutCallbacks) StateCommitted() {
+	if callbacks.tick != nil {
+		callbacks.mu.Lock()
+		callbacks.committed = true
+		callbacks.mu.Unlock()
+	}
+	callbacks.CommitGroup.Done()
+	callbacks.CommitGroup.Wait()
+}
+
+func (callbacks *CellAutCallbacks) StateSent() {
+	if callbacks.sends != nil {
+		atomic.AddInt64(callbacks.sends, 1)
+	}
+	if callbacks.tick != nil {
+		callbacks.mu.Lock()
+		callbacks.sending = true
+		callbacks.mu.Unlock()
+	}
+	callbacks.WaitGroup.Add(1)
+}
+
+func (callbacks *CellAutCallbacks) StateReceived() {
+	callbacks.WaitGroup.Done()
+}
+
+/*
+SendTime is what a CellAut should stamp each NeighborMessage with as it sends it: the time, if the
+Ticker is measuring latency, or else the zero time, since looking at the clock isn't free.
+*/
+func (callbacks *CellAutCallbacks) SendTime() time.Time {
+	if callbacks.latency == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+/*
+MessageReceived is StateReceived for a CellAut that has the whole NeighborMessage, so that how long
+it took to arrive can be counted.
+*/
+func (callbacks *CellAutCallbacks) MessageReceived(msg NeighborMessage) {
+	if callbacks.latency != nil && !msg.Sent.IsZero() {
+		callbacks.latency.Observe(time.Since(msg.Sent))
+	}
+	callbacks.inFlight = false
+	callbacks.StateReceived()
+}
+
+/*
+handling is called as a CellAut takes a NeighborMessage out of its inbox, before it does anything
+with it. If it panics before calling MessageReceived, the message still gets counted as received.
+*/
+func (callbacks *CellAutCallbacks) handling() {
+	callbacks.inFlight = callbacks.tick != nil
+}
+
+/*
+sendState calls StateSent and sends state to one neighbor. If the CellAut panics in between, the
+StateSent still gets balanced.
+*/
+func (callbacks *CellAutCallbacks) sendState(to recipient, state State) {
+	callbacks.StateSent()
+	callbacks.unsent = callbacks.tick != nil
+	to.send(state, callbacks.SendTime())
+	callbacks.unsent = false
+}
+
+func (callbacks *CellAutCallbacks) AllStatesSent() {
+	if callbacks.tick != nil {
+		callbacks.mu.Lock()
+		callbacks.allSent = true
+		callbacks.sending = false
+		callbacks.pending = false
+		callbacks.lastAckedTick = callbacks.tickID
+		callbacks.mu.Unlock()
+	}
+	callbacks.WaitGroup.Done()
+}
+
+/*
+StateRecord is what a CellAut writes to the state ledger when its state changes: which cell, which
+tick, and the state it changed to. Every cell writes one on its first tick too, so the records from
+tick 0 on are enough to rebuild the whole history of a simulation.
+*/
+type StateRecord struct {
+	TickID int64  `json:"tick"`
+	CellID string `json:"cell"`
+	State  State  `json:"state"`
+}
+
+/*
+recordState writes a StateRecord to stateLedger, unless stateLedger is nil. It gives up if done is
+closed first, so that a cell that's told to exit doesn't wait on a ledger nobody's reading any more.
+*/
+func recordState(stateLedger chan StateRecord, done chan struct{}, cellID string, tickID int64, state State) {
+	if stateLedger == nil {
+		return
+	}
+	select {
+	case stateLedger <- StateRecord{TickID: tickID, CellID: cellID, State: state}:
+	case <-done:
+	}
+}
+
+/*
+CellAut is the interface that cellular automata implement.
+*/
+type CellAut interface {
+	// AddNeighbor introduces the CellAut to its neighbor.
+	//
+	// This causes the CellAut on which AddNeighbor was called to send its states to aut's Inbox,
+	// labelled with i.Recip(), which is the direction it is from aut.
+	//
+	// It only affects the callee, so for two cells to hear each other, both have to be told.
+	//
+	// index should be one of the `Neighbor*` constants.
+	AddNeighbor(i NeighborIndex, aut CellAut)
+
+	// RemoveNeighbor makes the CellAut forget its neighbor at index i, if it has one.
+	//
+	// Like AddNeighbor, it only affects the callee, so both neighbors have to be told.
+	RemoveNeighbor(i NeighborIndex)
+
+	// Inbox returns the channel on which the CellAut's neighbors send it their states, all of them
+	// on the same one. It has to be looked up every time, since the CellAut may swap it for a
+	// bigger one when it gets a new neighbor.
+	Inbox() chan NeighborMessage
+
+	// Start brings the CellAut to life. It should be called as a goroutine.
+	//
+	// The `tick` channel receives a random int64 value at every tick of the clock. The `tick`
+	// channel is closed by Ticker.Stop, but only after `done` has been closed and Start has returned.
+	// `done` is closed when the CellAut should exit, either because the Ticker is stopping or
+	// because the CellAut has been removed from it.
+	//
+	// Every time its state changes, the CellAut should write a StateRecord to `stateLedger`, if it
+	// isn't nil, before it calls AllStatesSent. Whoever passes a ledger has to keep reading it.
+	Start(tick chan int64, done chan struct{}, stateLedger chan StateRecord, callbacks *CellAutCallbacks)
+
+	// Returns the current state of the CellAut.
+	//
+	// This state may not yet have been transmitted to neighbors, depending on where we are in the
+	// tick cycle.
+	GetState() State
+
+	// Sets the state of CellAut.
+	//
+	// This state will be transmitted to neighbors at the next tick.
+	//
+	// SetState is the only way a CellAut's state should ever get set.
+	SetState(State)
+}
+
+/*
+NeighborMessage is a State sent to a CellAut by one of its neighbors. From is the direction the
+neighbor is in, from the recipient's point of view, so that a cell can tell its neighbors apart with
+only the one inbox. Sent is when it was sent, if the engine is measuring latency, and the zero time
+otherwise.
+*/
+type NeighborMessage struct {
+	From  NeighborIndex
+	State State
+	Sent  time.Time
+}
+
+// deliveryOrder is the order in which a cell with ordered delivery applies its neighbors' states:
+// the four sides clockwise from Up, then the corners clockwise from UpRt, then Fwd and Bk. Any other
+// direction comes after those, lowest NeighborIndex first.
+var deliveryOrder = map[NeighborIndex]int{
+	NeighborUp: 0, NeighborRt: 1, NeighborDn: 2, NeighborLf: 3,
+	NeighborUpRt: 4, NeighborDnRt: 5, NeighborDnLf: 6, NeighborUpLf: 7,
+	NeighborFwd: 8, NeighborBk: 9,
+}
+
+/*
+deliveryRank returns where messages from direction i go in deliveryOrder.
+*/
+func deliveryRank(i NeighborIndex) int {
+	if rank, ok := deliveryOrder[i]; ok {
+		return rank
+	}
+	return len(deliveryOrder) + int(i)
+}
+
+/*
+sortDeliveries puts msgs in deliveryOrder. Messages from the same direction keep their order.
+*/
+func sortDeliveries(msgs []NeighborMessage) {
+	sort.SliceStable(msgs, func(a, b int) bool {
+		return deliveryRank(msgs[a].From) < deliveryRank(msgs[b].From)
+	})
+}
+
+/*
+OrderedCellAut is a CellAut that can apply the states its neighbors send it in deliveryOrder, so
+that it comes out the same however the engine schedules them. GooCellAut is one. Cells that only
+remember their neighbors' states by direction, like RuleCellAut, don't need to be: the order they
+hear about them in can't change what they do.
+*/
+type OrderedCellAut interface {
+	CellAut
+	SetOrderedDelivery(ordered bool)
+}
+
+/*
+mailbox is the neighbor plumbing that GooCellAut and RuleCellAut share: who our neighbors are, and
+the inbox they all send us their states on. Its neighbors can only change between ticks, but it's
+safe to use from the cell's goroutine and its neighbors' at the same time.
+*/
+type mailbox struct {
+	// mu guards everything else, since neighbors can be added and removed while we're running
+	mu        sync.Mutex
+	neighbors map[NeighborIndex]CellAut
+	// recipients holds the neighbors along with the direction we are from each of them, so we
+	// don't build a new slice every time we send. It's nil when it needs rebuilding.
+	recipients []recipient
+	inbox      chan NeighborMessage
+}
+
+/*
+recipient is a neighbor we send our state to, and the direction we are from it.
+*/
+type recipient struct {
+	aut  CellAut
+	from NeighborIndex
+}
+
+/*
+send puts state in the recipient's inbox, stamped with sent.
+*/
+func (to recipient) send(state State, sent time.Time) {
+	to.aut.Inbox() <- NeighborMessage{From: to.from, State: state, Sent: sent}
+}
+
+/*
+addNeighbor remembers neighbor as the one in direction i, and makes sure the inbox has room for
+buffer states from every neighbor.
+*/
+func (box *mailbox) addNeighbor(i NeighborIndex, neighbor CellAut, buffer int) {
+	box.mu.Lock()
+	defer box.mu.Unlock()
+	if box.neighbors == nil {
+		box.neighbors = make(map[NeighborIndex]CellAut)
+	}
+	box.neighbors[i] = neighbor
+	box.recipients = nil
+	box.growInbox(buffer)
+}
+
+/*
+removeNeighbor forgets the neighbor in direction i. The inbox keeps its size.
+*/
+func (box *mailbox) removeNeighbor(i NeighborIndex) {
+	box.mu.Lock()
+	defer box.mu.Unlock()
+	delete(box.neighbors, i)
+	box.recipients = nil
+}
+
+/*
+inboxFor returns the inbox, making it first if it hasn't been made yet.
+*/
+func (box *mailbox) inboxFor(buffer int) chan NeighborMessage {
+	box.mu.Lock()
+	defer box.mu.Unlock()
+	box.growInbox(buffer)
+	return box.inbox
+}
+
+/*
+currentInbox returns the inbox as it is, which is nil if nobody has sent us anything yet, and a nil
+channel is never ready, which is what we want.
+*/
+func (box *mailbox) currentInbox() chan NeighborMessage {
+	box.mu.Lock()
+	defer box.mu.Unlock()
+	return box.inbox
+}
+
+/*
+growInbox swaps the inbox for a bigger one if it can't hold buffer states from every neighbor, and
+moves over anything that's waiting in the old one. Neighbors only change between ticks, when nobody
+is sending, so nothing can go astray. The caller must hold mu.
+*/
+func (box *mailbox) growInbox(buffer int) {
+	size := buffer * maxInt(len(box.neighbors), 1)
+	if box.inbox != nil && cap(box.inbox) >= size {
+		return
+	}
+	inbox := make(chan NeighborMessage, size)
+	for box.inbox != nil {
+		select {
+		case msg := <-box.inbox:
+			inbox <- msg
+		default:
+			box.inbox = nil
+		}
+	}
+	box.inbox = inbox
+}
+
+/*
+mailingList returns the neighbors to send our state to. The slice is shared, so it mustn't be
+modified.
+*/
+func (box *mailbox) mailingList() []recipient {
+	box.mu.Lock()
+	defer box.mu.Unlock()
+	if box.recipients == nil {
+		box.recipients = make([]recipient, 0, len(box.neighbors))
+		for i, neighbor := range box.neighbors {
+			box.recipients = append(box.recipients, recipient{aut: neighbor, from: i.Recip()})
+		}
+	}
+	return box.recipients
+}
+
+/*
+GooCellAut is a CellAut implementation that spreads one tick at a time to every adjacent neighbor.
+
+It has two states, "X" and "-". "X" means "covered in goo", "-" means "not (yet) covered in goo".
+*/
+type GooCellAut struct {
+	//@DEBUG
+	ID int
+	// ChannelBuffer is how many states the inbox can hold from each neighbor. It has to be set
+	// before the GooCellAut is wired to its neighbors, and it has to be at least 1, or two
+	// neighbors sending to each other at once would deadlock.
+	ChannelBuffer int
+	// The ID of the last tick we saw
+	tickID int64
+	// Whether we've been frozen by the ErrorSkipCell policy
+	skipped bool
+	// The next state the GooCellAut will have (after the next tick)
+	newState State
+	// The current state of the GooCellAut
+	state State
+	// Whether we apply our neighbors' states in deliveryOrder, rather than as they come
+	ordered bool
+	// With ordered delivery, the states we've been sent since the last tick, and whether
+	// SetState has been called since the first of them came, so it has the last word
+	held       []NeighborMessage
+	overridden bool
+	// Our neighbors, and the inbox on which they send us their states
+	mailbox
+}
+
+/*
+AddNeighbor tells us "your neighbor to this direction is `neighbor`".
+
+From then on we send our states to its Inbox. It has to be told about us separately, if we're to
+hear from it, and our inbox grows to make room for it.
+*/
+func (aut *GooCellAut) AddNeighbor(i NeighborIndex, neighbor CellAut) {
+	aut.addNeighbor(i, neighbor, aut.ChannelBuffer)
+}
+
+/*
+RemoveNeighbor forgets our neighbor in direction i.
+*/
+func (aut *GooCellAut) RemoveNeighbor(i NeighborIndex) {
+	aut.removeNeighbor(i)
+}
+
+/*
+Inbox returns the channel on which all our neighbors send us their states.
+*/
+func (aut *GooCellAut) Inbox() chan NeighborMessage {
+	return aut.inboxFor(aut.ChannelBuffer)
+}
+
+/*
+SetState sets the *GooCellAut's state.
+
+This is the only way state should ever be set on a *GooCellAut.
+
+SetState can be called multiple times per tick. If it is, the last state will win.
+*/
+func (aut *GooCellAut) SetState(newState State) {
+	aut.newState = newState
+	// With ordered delivery, the states we've been sent aren't applied until the next tick, but
+	// they came before this one, so this one still wins
+	aut.overridden = len(aut.held) > 0
+}
+
+/*
+SetOrderedDelivery makes us apply the states our neighbors send us in a fixed order of directions,
+rather than the order they happen to arrive in, which the scheduler picks. Since the last state
+we're sent wins, that's the difference between a goo front that's the same every run and one that
+isn't. See deliveryOrder for the order.
+
+Under the ChannelEngine, we can't know we've heard from every neighbor until the next tick comes, so
+the states are held until then. It has to be set before we're started.
+*/
+func (aut *GooCellAut) SetOrderedDelivery(ordered bool) {
+	aut.ordered = ordered
+}
+
+/*
+GetState returns the *GooCellAut's state.
+
+Depending where we are in the simulation, this state might be new, and not yet transmitted to the
+neighbors.
+*/
+func (aut *GooCellAut) GetState() State {
+	return aut.state
+}
+
+func (aut *GooCellAut) Start(tick chan int64, done chan struct{}, stateLedger chan StateRecord, callbacks *CellAutCallbacks) {
+	// Our neighbors don't need to hear about our first state unless it changed, but the ledger does
+	recorded := false
+	for {
+		// The inbox can be swapped for a bigger one between ticks, so it's looked up every time
+		inbox := aut.currentInbox()
+		select {
+		case tickID, ok := <-tick:
+			if !ok {
+				callbacks.ReportError(aut, aut.tickID, fmt.Errorf("tick channel closed unexpectedly"))
+				return
+			}
+			aut.deliverHeld(callbacks)
+			changed := aut.Commit(tickID)
+			callbacks.StateCommitted()
+			if changed {
+				for _, to := range aut.mailingList() {
+					callbacks.sendState(to, aut.state)
+				}
+			}
+			if changed || !recorded {
+				recordState(stateLedger, done, callbacks.cellName(aut), tickID, aut.state)
+				recorded = true
+			}
+			callbacks.AllStatesSent()
+		case <-done:
+			return
+		case msg := <-inbox:
+			if aut.ordered {
+				aut.held = append(aut.held, msg)
+				callbacks.MessageReceived(msg)
+				continue
+			}
+			aut.receive(msg, callbacks)
+		}
+	}
+}
+
+/*
+deliverHeld applies the states held for ordered delivery, in deliveryOrder. If SetState was called
+after they came, its state is put back afterward.
+*/
+func (aut *GooCellAut) deliverHeld(callbacks *CellAutCallbacks) {
+	if len(aut.held) == 0 {
+		return
+	}
+	held, overridden, newState := aut.held, aut.overridden, aut.newState
+	aut.held, aut.overridden = nil, false
+	sortDeliveries(held)
+	for _, msg := range held {
+		if err := aut.apply(msg.State); err != nil {
+			aut.applyPolicy(callbacks.ReportError(aut, aut.tickID, err))
+		}
+	}
+	if overridden {
+		aut.newState = newState
+	}
+	aut.held = held[:0]
+}
+
+/*
+receive handles a state sent to us by a neighbor.
+
+Only "X" and "-" are valid GooCellAut states. Anything else gets reported to the Supervisor.
+*/
+func (aut *GooCellAut) receive(msg NeighborMessage, callbacks *CellAutCallbacks) {
+	defer callbacks.MessageReceived(msg)
+	if err := aut.apply(msg.State); err != nil {
+		aut.applyPolicy(callbacks.ReportError(aut, aut.tickID, err))
+	}
+}
+
+/*
+apply takes on neighborState as our next state, or returns an error if it's invalid.
+*/
+func (aut *GooCellAut) apply(neighborState State) error {
+	if neighborState != "X" && neighborState != "-" {
+		return fmt.Errorf("invalid neighbor state '%s'", neighborState)
+	}
+	if !aut.skipped {
+		aut.SetState(neighborState)
+	}
+	return nil
+}
+
+/*
+applyPolicy does what the Supervisor told us to do about an error.
+*/
+func (aut *GooCellAut) applyPolicy(policy ErrorPolicy) {
+	switch policy {
+	case ErrorSkipCell:
+		aut.skipped = true
+	case ErrorRestart:
+		aut.SetState(aut.state)
+	}
+}
+
+/*
+Commit makes the state set by SetState our current state, and returns whether it changed.
+*/
+func (aut *GooCellAut) Commit(tickID int64) bool {
+	aut.tickID = tickID
+	if aut.skipped {
+		aut.newState = aut.state
+	}
+	changed := aut.newState != aut.state
+	aut.state = aut.newState
+	return changed
+}
+
+/*
+Broadcast sends our state to all our neighbors. It doesn't block, as long as each neighbor only
+sends one state per tick, and the inbox is drained by Receive before the next.
+*/
+func (aut *GooCellAut) Broadcast() {
+	for _, to := range aut.mailingList() {
+		to.send(aut.state, time.Time{})
+	}
+}
+
+/*
+Receive takes whatever states our neighbors have sent us, without blocking. With ordered delivery,
+they're all taken before any is applied, and then applied in deliveryOrder.
+*/
+func (aut *GooCellAut) Receive(supervisor *Supervisor) {
+	inbox := aut.currentInbox()
+	received := aut.held[:0]
+	for {
+		select {
+		case msg := <-inbox:
+			if aut.ordered {
+				received = append(received, msg)
+				continue
+			}
+			if err := aut.apply(msg.State); err != nil {
+				aut.applyPolicy(supervisor.Report(&CellError{Cell: aut, TickID: aut.tickID, Err: err}))
+			}
+		default:
+			sortDeliveries(received)
+			for _, msg := range received {
+				if err := aut.apply(msg.State); err != nil {
+					aut.applyPolicy(supervisor.Report(&CellError{Cell: aut, TickID: aut.tickID, Err: err}))
+				}
+			}
+			// The slice is kept for next time, but nothing's held
+			aut.held = received[:0]
+			return
+		}
+	}
+}
+
+/*
+String identifies the GooCellAut in logs and errors.
+*/
+func (aut *GooCellAut) String() string {
+	return fmt.Sprintf("goo#%d", aut.ID)
+}
+
+// DefaultChannelBuffer is the ChannelBuffer that NewGooCellAut gives its GooCellAuts.
+const DefaultChannelBuffer = 1
+
+/*
+NewGooCellAut returns a *GooCellAut that has been initialized.
+
+"Initialized" means it's okay to call Inbox and AddNeighbor on it.
+*/
+func NewGooCellAut(i int) *GooCellAut {
+	//@DEBUG v^
+	aut := &GooCellAut{ID: i, ChannelBuffer: DefaultChannelBuffer}
+	aut.neighbors = make(map[NeighborIndex]CellAut)
+	return aut
+}
@@ -0,0 +1,349 @@
+package cellaut
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+Returns a list consisting of the concatStates of each of the auts in the input.
+*/
+func concatStates(auts []CellAut) string {
+	var rslt string
+	for _, aut := range auts {
+		state := string(aut.GetState())
+		if state == "" {
+			state = "-"
+		}
+		rslt = rslt + state
+	}
+	return rslt
+}
+
+func TestNeighborIndex_Recip(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	opposites := map[NeighborIndex]NeighborIndex{
+		NeighborUp:   NeighborDn,
+		NeighborRt:   NeighborLf,
+		NeighborUpRt: NeighborDnLf,
+		NeighborUpLf: NeighborDnRt,
+	}
+	for i, opposite := range opposites {
+		assert.Equal(opposite, i.Recip())
+		assert.Equal(i, opposite.Recip())
+		// And they point opposite ways
+		dx, dy := neighborOffset(i)
+		odx, ody := neighborOffset(opposite)
+		assert.Equal([2]int{-dx, -dy}, [2]int{odx, ody})
+	}
+}
+
+/*
+Tests the functionality of GooCellAut, which itself is used only for testing.
+*/
+func TestGooCellAut(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Put five GooCellAuts in a row. Set the middle one to gooed. After the next tick, there should
+	// be 3 gooed. After the next tick, all 5 should be gooed.
+	//
+	// After tick 0: --X--
+	// After tick 1: -XXX-
+	// After tick 2: XXXXX
+	auts := NewGrid(5, 1, func(x, y int) CellAut { return NewGooCellAut(x) }).Cells()
+	auts[2].SetState("X")
+	ticker := &Ticker{}
+	stateLedger := make(chan StateRecord)
+	for _, aut := range auts {
+		ticker.Start(aut, stateLedger)
+	}
+	go func() {
+		// Discard everything sent to state ledger
+		for {
+			select {
+			case <-stateLedger:
+			case <-ticker.Done():
+				return
+			}
+		}
+	}()
+	ticker.Tick()
+	assert.Equal("--X--", concatStates(auts))
+	ticker.Tick()
+	assert.Equal("-XXX-", concatStates(auts))
+	ticker.Tick()
+	assert.Equal("XXXXX", concatStates(auts))
+	// After they're gooed, cells should stay gooed
+	for i := 0; i < 10; i++ {
+		ticker.Tick()
+	}
+	assert.Equal("XXXXX", concatStates(auts))
+	assert.Nil(ticker.Stop(time.Second))
+}
+
+func TestGooCellAut_Inbox(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// One cell with more neighbors than there are directions on a grid, all sending to the same
+	// inbox, which grows to fit them
+	hub := NewGooCellAut(0)
+	hub.ChannelBuffer = 2
+	var leaves []CellAut
+	for i := NeighborIndex(0); i < 6; i++ {
+		for _, direction := range []NeighborIndex{i, i.Recip()} {
+			leaf := NewGooCellAut(len(leaves) + 1)
+			hub.AddNeighbor(direction, leaf)
+			leaf.AddNeighbor(direction.Recip(), hub)
+			leaves = append(leaves, leaf)
+		}
+	}
+	assert.Equal(24, cap(hub.Inbox()))
+
+	// Each state says who it's from, and anything waiting when the inbox grows is kept
+	leaf := NewGooCellAut(99)
+	leaf.AddNeighbor(NeighborUp, hub)
+	leaf.SetState("X")
+	leaf.Commit(0)
+	leaf.Broadcast()
+	hub.AddNeighbor(Neighbor3D(1, 1, 1), leaf)
+	assert.Equal(26, cap(hub.Inbox()))
+	assert.Equal(NeighborMessage{From: NeighborDn, State: "X"}, <-hub.Inbox())
+	hub.RemoveNeighbor(Neighbor3D(1, 1, 1))
+
+	// Goo gets from one leaf to the rest through the hub
+	leaves[7].SetState("X")
+	ticker := &Ticker{}
+	ticker.Start(hub, nil)
+	for _, leaf := range leaves {
+		ticker.Start(leaf, nil)
+	}
+	ticker.Tick()
+	assert.Equal("-------X----", concatStates(leaves))
+	ticker.Tick()
+	assert.Equal("X", string(hub.GetState()))
+	ticker.Tick()
+	assert.Equal("XXXXXXXXXXXX", concatStates(leaves))
+	assert.Nil(ticker.Stop(time.Second))
+}
+
+func TestGooCellAut_OrderedDelivery(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A hub hears "-" from three sides and "X" from its left, which comes last in deliveryOrder,
+	// so it ends up "X" however the states arrive
+	directions := []NeighborIndex{NeighborUp, NeighborRt, NeighborDn, NeighborLf}
+	newHub := func() (*GooCellAut, []*GooCellAut) {
+		hub := NewGooCellAut(0)
+		hub.SetOrderedDelivery(true)
+		var leaves []*GooCellAut
+		for i, direction := range directions {
+			leaf := NewGooCellAut(i + 1)
+			hub.AddNeighbor(direction, leaf)
+			leaf.AddNeighbor(direction.Recip(), hub)
+			leaf.SetState("-")
+			leaves = append(leaves, leaf)
+		}
+		leaves[3].SetState("X")
+		return hub, leaves
+	}
+	for i := 0; i < 20; i++ {
+		hub, leaves := newHub()
+		ticker := &Ticker{}
+		ticker.Start(hub, nil)
+		for _, leaf := range leaves {
+			ticker.Start(leaf, nil)
+		}
+		ticker.Tick()
+		ticker.Tick()
+		assert.Equal("X", string(hub.GetState()))
+		assert.Nil(ticker.Stop(time.Second))
+	}
+
+	// Same when they're all taken at once, even if the left one's state comes first
+	hub, leaves := newHub()
+	for j := len(leaves) - 1; j >= 0; j-- {
+		leaves[j].Commit(0)
+		leaves[j].Broadcast()
+	}
+	hub.Receive(nil)
+	hub.Commit(1)
+	assert.Equal("X", string(hub.GetState()))
+
+	// A state set between ticks still wins over the ones held for the next
+	hub, leaves = newHub()
+	ticker := &Ticker{}
+	ticker.Start(hub, nil)
+	for _, leaf := range leaves {
+		ticker.Start(leaf, nil)
+	}
+	ticker.Tick()
+	hub.SetState("-")
+	ticker.Tick()
+	assert.Equal("-", string(hub.GetState()))
+	assert.Nil(ticker.Stop(time.Second))
+}
+
+/*
+A CellAut that ignores its done channel until it's told to quit.
+*/
+type stubbornCellAut struct {
+	*GooCellAut
+	quit chan struct{}
+}
+
+func (aut *stubbornCellAut) Start(tick chan int64, done chan struct{}, stateLedger chan StateRecord, callbacks *CellAutCallbacks) {
+	<-aut.quit
+}
+
+func TestTicker_Stop(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	ticker := &Ticker{}
+	tickChans := []chan int64{ticker.TickChan()}
+	for i := 0; i < 3; i++ {
+		ticker.Start(NewGooCellAut(i), nil)
+		tickChans = append(tickChans, ticker.pendingDestinations[len(ticker.pendingDestinations)-1])
+	}
+	// The manually registered destination has to play along too
+	go func() {
+		callbacks := ticker.Callbacks()
+		for range tickChans[0] {
+			callbacks.StateCommitted()
+			callbacks.AllStatesSent()
+		}
+	}()
+	ticker.Tick()
+	assert.Nil(ticker.Stop(time.Second))
+	for _, ch := range tickChans {
+		_, ok := <-ch
+		assert.False(ok)
+	}
+	// Ticking and stopping again are harmless
+	ticker.Tick()
+	assert.Nil(ticker.Stop(time.Second))
+}
+
+func TestTicker_StopTimeout(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	ticker := &Ticker{}
+	stubborn := &stubbornCellAut{GooCellAut: NewGooCellAut(0), quit: make(chan struct{})}
+	ticker.Start(stubborn, nil)
+	ticker.Start(NewGooCellAut(1), nil)
+	err := ticker.Stop(10 * time.Millisecond)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "1 CellAuts still running")
+	close(stubborn.quit)
+}
+
+func TestTicker_StartCtx(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A soup keeps the cells busy sending to each other, so the cancellation lands mid-tick
+	ctx, cancel := context.WithCancel(context.Background())
+	grid := NewLifeGrid(12, 12, WithTopology(Torus))
+	for i, aut := range grid.Cells() {
+		if i%3 != 0 {
+			aut.SetState(LifeAlive)
+		}
+	}
+	ticker := &Ticker{}
+	for _, aut := range grid.Cells() {
+		ticker.StartCtx(ctx, aut, nil)
+	}
+	ran := make(chan error)
+	go func() { ran <- ticker.Run(context.Background()) }()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	assert.Equal(context.Canceled, <-ran)
+	assert.Equal(context.Canceled, ticker.Err())
+	<-ticker.Done()
+	// Shutting down finishes in the background
+	for deadline := time.Now().Add(time.Second); atomic.LoadInt64(&ticker.runningCount) > 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(int64(0), atomic.LoadInt64(&ticker.runningCount))
+	tickID := ticker.currentTick()
+	ticker.Tick()
+	assert.Equal(tickID, ticker.currentTick())
+}
+
+func TestTicker_RegisterDuringTick(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	ticker := &Ticker{}
+	ticker.Start(NewGooCellAut(0), nil)
+	// A destination that registers another one in the middle of each tick
+	registered := make(chan chan int64, 1)
+	go func(tick chan int64) {
+		callbacks := ticker.Callbacks()
+		for range tick {
+			select {
+			case registered <- ticker.TickChan():
+			default:
+			}
+			callbacks.StateCommitted()
+			callbacks.AllStatesSent()
+		}
+	}(ticker.TickChan())
+
+	ticker.Tick()
+	late := <-registered
+	assert.Len(ticker.Health().Cells, 1)
+	received := make(chan int64)
+	go func() {
+		callbacks := ticker.Callbacks()
+		for tickID := range late {
+			received <- tickID
+			callbacks.StateCommitted()
+			callbacks.AllStatesSent()
+		}
+		close(received)
+	}()
+
+	// The late destination missed tick 0, but gets tick 1
+	ticked := make(chan struct{})
+	go func() {
+		ticker.Tick()
+		close(ticked)
+	}()
+	assert.Equal(int64(1), <-received)
+	<-ticked
+	assert.Nil(ticker.Stop(time.Second))
+	_, ok := <-received
+	assert.False(ok)
+}
+
+func BenchmarkTicker_Tick(b *testing.B) {
+	world := newGooRow(100)
+	ticker := &Ticker{}
+	for _, aut := range world.cells {
+		ticker.Start(aut, nil)
+	}
+	defer ticker.Stop(time.Second)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Keep the goo sloshing back and forth so that every tick sends states
+		if i%2 == 0 {
+			world.cells[0].SetState("X")
+		} else {
+			world.cells[0].SetState("-")
+		}
+		ticker.Tick()
+	}
+}
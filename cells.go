@@ -0,0 +1,104 @@
+package cellaut
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+/*
+LoadCells reads a pattern in the plaintext .cells format used by LifeWiki, which is the easiest to
+write by hand:
+
+	!Name: Glider
+	!A comment
+	.O.
+	..O
+	OOO
+
+"." is a dead cell and "O" a live one, and lines starting with "!" are comments. Rows may stop
+short, since trailing dead cells can be left off, and a blank line is a row of dead cells. As with
+LoadRLE, dead cells are "-", which is the Pattern's empty State, and live ones are "X".
+*/
+func LoadCells(r io.Reader) (*Pattern, error) {
+	var rows []string
+	width := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+		for _, c := range line {
+			if c != '.' && c != 'O' {
+				return nil, fmt.Errorf("row %d: unexpected '%c' in .cells pattern", len(rows)+1, c)
+			}
+		}
+		rows = append(rows, line)
+		width = maxInt(width, len(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	// Blank lines at the end are just the end of the file
+	for len(rows) > 0 && rows[len(rows)-1] == "" {
+		rows = rows[:len(rows)-1]
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no rows in .cells pattern")
+	}
+
+	pattern := NewPattern(width, len(rows), NewStateTable("-"))
+	for i, row := range rows {
+		for x, c := range row {
+			if c == 'O' {
+				pattern.Set(x, len(rows)-1-i, LifeAlive)
+			}
+		}
+	}
+	return pattern, nil
+}
+
+/*
+ExportCells writes the live cells of a Life-like world in the .cells format, cropped to their
+bounding box, with name in a "!Name:" comment if it isn't empty.
+
+Like TakeSnapshot, it must be called between ticks. Only "-" and "X" cells can be written.
+*/
+func ExportCells(w io.Writer, world World, name string) error {
+	return writeCells(w, TakeSnapshot(world, NewStateTable("-")), name)
+}
+
+/*
+writeCells writes the live cells of a two-state grid in the .cells format, leaving off each row's
+trailing dead cells.
+*/
+func writeCells(w io.Writer, grid *StateGrid, name string) error {
+	var b strings.Builder
+	if name != "" {
+		fmt.Fprintf(&b, "!Name: %s\n", name)
+	}
+	rect, ok := grid.boundingBox(func(id StateID) bool { return id != 0 })
+	if !ok {
+		// An empty pattern still needs a row, for LoadCells to read it back
+		b.WriteString(".\n")
+	}
+	for y := rect.Y + rect.Height - 1; ok && y >= rect.Y; y-- {
+		var row strings.Builder
+		for x := rect.X; x < rect.X+rect.Width; x++ {
+			switch state := grid.At(x, y); state {
+			case LifeAlive:
+				row.WriteByte('O')
+			case LifeDead:
+				row.WriteByte('.')
+			default:
+				return fmt.Errorf(".cells can only hold the states '-' and 'X', not '%s'", state)
+			}
+		}
+		b.WriteString(strings.TrimRight(row.String(), "."))
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
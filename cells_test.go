@@ -0,0 +1,60 @@
+package cellaut
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadCells(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Rows that stop short, and a blank row in the middle
+	pattern, err := LoadCells(strings.NewReader("!Name: Two gliders\n!\n.O\n..O\nOOO\n\n...O\n\n"))
+	assert.Nil(err)
+	assert.Equal("-X--\n--X-\nXXX-\n----\n---X\n", pattern.String())
+
+	for _, bad := range []string{"!Just a comment\n", ".O.\n.*.\n"} {
+		_, err := LoadCells(strings.NewReader(bad))
+		assert.NotNil(err, bad)
+	}
+
+	// Pattern files can be .cells too
+	path := filepath.Join(t.TempDir(), "blinker.cells")
+	assert.Nil(ioutil.WriteFile(path, []byte("!Name: Blinker\nOOO\n"), 0644))
+	grid, err := loadPatternFile(path, 1)
+	assert.Nil(err)
+	assert.Equal("-----\n-XXX-\n-----\n", (&Pattern{StateGrid: grid}).String())
+}
+
+func TestExportCells(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	glider, err := LoadCells(strings.NewReader(".O.\n..O\nOOO\n"))
+	assert.Nil(err)
+	grid := NewLifeGrid(8, 8)
+	assert.Nil(grid.Place(glider, 2, 3))
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 1}))
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+
+	var b strings.Builder
+	assert.Nil(ExportCells(&b, grid, "Glider"))
+	assert.Equal("!Name: Glider\n.O\n..O\nOOO\n", b.String())
+	exported, err := LoadCells(strings.NewReader(b.String()))
+	assert.Nil(err)
+	assert.True(exported.StateGrid.Equal(glider.StateGrid))
+
+	b.Reset()
+	assert.Nil(ExportCells(&b, NewLifeGrid(3, 3), ""))
+	assert.Equal(".\n", b.String())
+	state := NewStateGrid(1, 1, NewStateTable("-"))
+	state.Set(0, 0, "?")
+	assert.NotNil(writeCells(&b, state, ""))
+}
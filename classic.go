@@ -0,0 +1,164 @@
+package cellaut
+
+import (
+	"image/color"
+	"math/rand"
+)
+
+// LifePalette draws the states used by Life and HighLife
+var LifePalette = Palette{
+	"-": color.White,
+	"X": color.Black,
+}
+
+// States used by WireWorld
+const (
+	WireEmpty     State = "-"
+	WireHead      State = "H"
+	WireTail      State = "T"
+	WireConductor State = "C"
+)
+
+// WireWorldPalette draws the states used by WireWorld
+var WireWorldPalette = Palette{
+	WireEmpty:     color.Black,
+	WireHead:      color.RGBA{0x40, 0x80, 0xff, 0xff},
+	WireTail:      color.RGBA{0xff, 0x40, 0x20, 0xff},
+	WireConductor: color.RGBA{0xff, 0xc0, 0x00, 0xff},
+}
+
+/*
+WireWorld is Silverman's WireWorld: electron heads become tails, tails become conductor, and
+conductor becomes a head if exactly one or two of its neighbors are heads. Empty cells stay empty.
+*/
+func WireWorld(self State, neighbors map[NeighborIndex]State) State {
+	switch self {
+	case WireHead:
+		return WireTail
+	case WireTail:
+		return WireConductor
+	case WireConductor:
+		heads := 0
+		for _, neighbor := range neighbors {
+			if neighbor == WireHead {
+				heads++
+			}
+		}
+		if heads == 1 || heads == 2 {
+			return WireHead
+		}
+		return WireConductor
+	}
+	return WireEmpty
+}
+
+// States used by BriansBrain
+const (
+	BrainOff   State = "-"
+	BrainOn    State = "X"
+	BrainDying State = "D"
+)
+
+// BriansBrainPalette draws the states used by BriansBrain
+var BriansBrainPalette = Palette{
+	BrainOff:   color.Black,
+	BrainOn:    color.White,
+	BrainDying: color.RGBA{0x30, 0x60, 0xc0, 0xff},
+}
+
+/*
+BriansBrain is Silverman's Brian's Brain: an off cell turns on if exactly two of its neighbors are
+on, an on cell starts dying, and a dying cell turns off.
+*/
+func BriansBrain(self State, neighbors map[NeighborIndex]State) State {
+	switch self {
+	case BrainOn:
+		return BrainDying
+	case BrainDying:
+		return BrainOff
+	}
+	on := 0
+	for _, neighbor := range neighbors {
+		if neighbor == BrainOn {
+			on++
+		}
+	}
+	if on == 2 {
+		return BrainOn
+	}
+	return BrainOff
+}
+
+// States used by the forest fire model
+const (
+	ForestEmpty   State = "-"
+	ForestTree    State = "T"
+	ForestBurning State = "F"
+)
+
+// ForestFirePalette draws the states used by NewForestFire
+var ForestFirePalette = Palette{
+	ForestEmpty:   color.RGBA{0x30, 0x20, 0x10, 0xff},
+	ForestTree:    color.RGBA{0x20, 0xa0, 0x30, 0xff},
+	ForestBurning: color.RGBA{0xff, 0x60, 0x00, 0xff},
+}
+
+/*
+NewForestFire returns the Drossel-Schwabl forest fire model: a burning cell burns out, a tree
+catches fire if any neighbor is burning (or with probability lightning if none is), and an empty
+cell grows a tree with probability growth.
+
+The returned Rule draws from rng, which isn't safe for concurrent use, so it has to be run by
+something that calls it from one goroutine at a time.
+*/
+func NewForestFire(growth, lightning float64, rng *rand.Rand) Rule {
+	return func(self State, neighbors map[NeighborIndex]State) State {
+		switch self {
+		case ForestBurning:
+			return ForestEmpty
+		case ForestTree:
+			for _, neighbor := range neighbors {
+				if neighbor == ForestBurning {
+					return ForestBurning
+				}
+			}
+			if rng.Float64() < lightning {
+				return ForestBurning
+			}
+			return ForestTree
+		}
+		if rng.Float64() < growth {
+			return ForestTree
+		}
+		return ForestEmpty
+	}
+}
+
+/*
+Elementary returns Wolfram's elementary cellular automaton with the given rule number, over the
+states "-" and "X". Only the NeighborLf and NeighborRt neighbors are looked at; a missing neighbor
+counts as "-".
+*/
+func Elementary(number uint8) Rule {
+	return func(self State, neighbors map[NeighborIndex]State) State {
+		bit := uint(0)
+		for _, state := range []State{neighbors[NeighborLf], self, neighbors[NeighborRt]} {
+			bit <<= 1
+			if state == "X" {
+				bit |= 1
+			}
+		}
+		if number&(1<<bit) != 0 {
+			return "X"
+		}
+		return "-"
+	}
+}
+
+/*
+Rule1D returns elementary rule n, for running on a one-dimensional Grid from NewLineGrid. It's the
+same as Elementary.
+*/
+func Rule1D(n uint8) Rule {
+	return Elementary(n)
+}
@@ -0,0 +1,68 @@
+package cellaut
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWireWorld(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal(WireTail, WireWorld(WireHead, neighborMap(WireHead)))
+	assert.Equal(WireConductor, WireWorld(WireTail, neighborMap(WireHead)))
+	assert.Equal(WireHead, WireWorld(WireConductor, neighborMap(WireHead, WireTail, WireConductor)))
+	assert.Equal(WireHead, WireWorld(WireConductor, neighborMap(WireHead, WireHead)))
+	assert.Equal(WireConductor, WireWorld(WireConductor, neighborMap(WireHead, WireHead, WireHead)))
+	assert.Equal(WireConductor, WireWorld(WireConductor, neighborMap(WireTail)))
+	assert.Equal(WireEmpty, WireWorld(WireEmpty, neighborMap(WireHead)))
+}
+
+func TestBriansBrain(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal(BrainOn, BriansBrain(BrainOff, neighborMap(BrainOn, BrainOn, BrainDying)))
+	assert.Equal(BrainOff, BriansBrain(BrainOff, neighborMap(BrainOn, BrainOn, BrainOn)))
+	assert.Equal(BrainOff, BriansBrain(BrainOff, neighborMap(BrainOn, BrainDying)))
+	assert.Equal(BrainDying, BriansBrain(BrainOn, neighborMap()))
+	assert.Equal(BrainOff, BriansBrain(BrainDying, neighborMap(BrainOn, BrainOn)))
+}
+
+func TestForestFire(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	never := NewForestFire(0, 0, rand.New(rand.NewSource(1)))
+	assert.Equal(ForestEmpty, never(ForestBurning, neighborMap()))
+	assert.Equal(ForestBurning, never(ForestTree, neighborMap(ForestEmpty, ForestBurning)))
+	assert.Equal(ForestTree, never(ForestTree, neighborMap(ForestTree)))
+	assert.Equal(ForestEmpty, never(ForestEmpty, neighborMap(ForestTree)))
+
+	always := NewForestFire(1, 1, rand.New(rand.NewSource(1)))
+	assert.Equal(ForestBurning, always(ForestTree, neighborMap(ForestTree)))
+	assert.Equal(ForestTree, always(ForestEmpty, neighborMap()))
+}
+
+func TestElementary(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	rule110 := Elementary(110)
+	// 110 is 01101110: 111→0, 110→1, 101→1, 100→0, 011→1, 010→1, 001→1, 000→0
+	expected := []State{"-", "X", "X", "X", "-", "X", "X", "-"}
+	for bits := 0; bits < 8; bits++ {
+		cell := func(bit uint) State {
+			if bits&(1<<bit) != 0 {
+				return "X"
+			}
+			return "-"
+		}
+		neighbors := map[NeighborIndex]State{NeighborLf: cell(2), NeighborRt: cell(0)}
+		assert.Equal(expected[bits], rule110(cell(1), neighbors), "neighborhood %03b", bits)
+	}
+	// Missing neighbors are dead
+	assert.Equal(State("X"), rule110("-", map[NeighborIndex]State{NeighborRt: "X"}))
+}
@@ -0,0 +1,74 @@
+package cellaut
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+/*
+Clipboard is somewhere patterns can be copied to and pasted from, as text.
+*/
+type Clipboard interface {
+	ReadText() (string, error)
+	WriteText(text string) error
+}
+
+// clipboardTools are the programs systemClipboard tries, in order, for reading and for writing
+var clipboardTools = []struct {
+	read, write []string
+}{
+	{[]string{"pbpaste"}, []string{"pbcopy"}},
+	{[]string{"wl-paste", "--no-newline"}, []string{"wl-copy"}},
+	{[]string{"xclip", "-selection", "clipboard", "-o"}, []string{"xclip", "-selection", "clipboard"}},
+	{[]string{"xsel", "--clipboard", "--output"}, []string{"xsel", "--clipboard", "--input"}},
+	{[]string{"powershell", "-NoProfile", "-Command", "Get-Clipboard"}, []string{"clip"}},
+}
+
+/*
+systemClipboard is the desktop's clipboard, which Golly and web browsers use too. There's no
+portable way to get at it from Go, so it runs whichever of the usual command line tools is
+installed.
+*/
+type systemClipboard struct{}
+
+/*
+tool returns the first command line in clipboardTools, picked by pick, whose program is installed.
+*/
+func (systemClipboard) tool(pick func(read, write []string) []string) ([]string, error) {
+	var names []string
+	for _, tool := range clipboardTools {
+		args := pick(tool.read, tool.write)
+		if _, err := exec.LookPath(args[0]); err == nil {
+			return args, nil
+		}
+		names = append(names, args[0])
+	}
+	return nil, fmt.Errorf("no clipboard tool found; install one of %s", strings.Join(names, ", "))
+}
+
+func (clipboard systemClipboard) ReadText() (string, error) {
+	args, err := clipboard.tool(func(read, write []string) []string { return read })
+	if err != nil {
+		return "", err
+	}
+	out, err := exec.Command(args[0], args[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("reading clipboard with %s: %s", args[0], err)
+	}
+	return string(out), nil
+}
+
+func (clipboard systemClipboard) WriteText(text string) error {
+	args, err := clipboard.tool(func(read, write []string) []string { return write })
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewBufferString(text)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("writing clipboard with %s: %s", args[0], err)
+	}
+	return nil
+}
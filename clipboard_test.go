@@ -0,0 +1,33 @@
+package cellaut
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemClipboard(t *testing.T) {
+	assert := assert.New(t)
+
+	// A stand-in for xclip, which keeps the clipboard in a file. It's the only tool on the PATH, so
+	// the ones ahead of it in clipboardTools are skipped.
+	dir := t.TempDir()
+	store := filepath.Join(dir, "clipboard")
+	script := "#!/bin/sh\nif [ \"$3\" = -o ]; then /bin/cat " + store + "; else /bin/cat > " + store + "; fi\n"
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, "xclip"), []byte(script), 0755))
+	t.Setenv("PATH", dir)
+
+	var clipboard Clipboard = systemClipboard{}
+	assert.Nil(clipboard.WriteText("x = 1, y = 1\no!\n"))
+	text, err := clipboard.ReadText()
+	assert.Nil(err)
+	assert.Equal("x = 1, y = 1\no!\n", text)
+
+	assert.Nil(os.Remove(filepath.Join(dir, "xclip")))
+	_, err = clipboard.ReadText()
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "pbpaste, wl-paste, xclip")
+}
@@ -0,0 +1,39 @@
+/*
+cellaut is the command-line front end to the cellaut package.
+
+Usage:
+
+	cellaut <command> [arguments]
+
+Run it with no command for a list. Go plugins listed in $CELLAUT_PLUGINS are loaded first, so the
+rules they register can be used by name.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/danslimmon/cellaut"
+)
+
+func main() {
+	if err := cellaut.LoadPlugins(); err != nil {
+		fmt.Fprintln(os.Stderr, "cellaut:", err)
+		os.Exit(1)
+	}
+	if len(os.Args) < 2 {
+		fmt.Fprintf(os.Stderr, "usage: cellaut <command> [arguments]\ncommands: %s\n", strings.Join(cellaut.CommandNames(), ", "))
+		os.Exit(2)
+	}
+	command, ok := cellaut.Command(os.Args[1])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "cellaut: unknown command '%s'; commands are %s\n", os.Args[1], strings.Join(cellaut.CommandNames(), ", "))
+		os.Exit(2)
+	}
+	if err := command(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "cellaut:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+rulegen compiles a Life-like rulestring into a Go file containing a lookup table and a Rule that
+uses it.
+
+Usage:
+
+	rulegen -rule B36/S23 -name HighLife -o highlife_gen.go
+
+It's meant to be run from a `//go:generate` directive, so that hot rules don't have to interpret
+their rulestring on every cell of every tick.
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// maxNeighbors is the largest neighbor count the generated table covers (a Moore neighborhood).
+const maxNeighbors = 8
+
+/*
+ruleSpec is everything rulegen needs to know to generate a rule.
+*/
+type ruleSpec struct {
+	Rulestring string
+	Name       string
+	Package    string
+	Alive      string
+	Dead       string
+	// Birth[n] is true if a dead cell with n live neighbors becomes alive
+	Birth [maxNeighbors + 1]bool
+	// Survival[n] is true if a live cell with n live neighbors stays alive
+	Survival [maxNeighbors + 1]bool
+}
+
+/*
+Q is what cellaut's names have to be qualified with in the generated file: nothing, if it's going
+in package cellaut, and "cellaut." anywhere else.
+*/
+func (spec *ruleSpec) Q() string {
+	if spec.Package == "cellaut" {
+		return ""
+	}
+	return "cellaut."
+}
+
+/*
+parseRulestring fills in spec.Birth and spec.Survival from a "B<digits>/S<digits>" rulestring.
+*/
+func parseRulestring(spec *ruleSpec, rulestring string) error {
+	parts := strings.Split(strings.ToUpper(rulestring), "/")
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "B") || !strings.HasPrefix(parts[1], "S") {
+		return fmt.Errorf("rulestring '%s' is not of the form B<digits>/S<digits>", rulestring)
+	}
+	for i, counts := range []*[maxNeighbors + 1]bool{&spec.Birth, &spec.Survival} {
+		for _, c := range parts[i][1:] {
+			if c < '0' || c > '0'+maxNeighbors {
+				return fmt.Errorf("invalid neighbor count '%c' in rulestring '%s'", c, rulestring)
+			}
+			counts[c-'0'] = true
+		}
+	}
+	spec.Rulestring = rulestring
+	return nil
+}
+
+var ruleTemplate = template.Must(template.New("rule").Parse(`// Code generated by rulegen -rule {{.Rulestring}} -name {{.Name}}; DO NOT EDIT.
+
+package {{.Package}}
+{{if .Q}}
+import "github.com/danslimmon/cellaut"
+{{end}}
+// {{.Table}}[self][n] is the next state of a cell in state self (0 for {{printf "%q" .Dead}}, 1 for {{printf "%q" .Alive}})
+// with n live neighbors.
+var {{.Table}} = [2][{{.Size}}]{{.Q}}State{
+	{ {{- range .Birth}}{{if .}}{{printf "%q" $.Alive}}{{else}}{{printf "%q" $.Dead}}{{end}}, {{end -}} },
+	{ {{- range .Survival}}{{if .}}{{printf "%q" $.Alive}}{{else}}{{printf "%q" $.Dead}}{{end}}, {{end -}} },
+}
+
+/*
+{{.Name}} is the Life-like rule {{.Rulestring}}.
+
+Any state other than {{printf "%q" .Alive}} counts as dead.
+*/
+func {{.Name}}(self {{.Q}}State, neighbors map[{{.Q}}NeighborIndex]{{.Q}}State) {{.Q}}State {
+	n := 0
+	for _, neighbor := range neighbors {
+		if neighbor == {{printf "%q" .Alive}} {
+			n++
+		}
+	}
+	if n >= len({{.Table}}[0]) {
+		return {{printf "%q" .Dead}}
+	}
+	if self == {{printf "%q" .Alive}} {
+		return {{.Table}}[1][n]
+	}
+	return {{.Table}}[0][n]
+}
+`))
+
+/*
+generate returns the formatted Go source for spec.
+*/
+func generate(spec *ruleSpec) ([]byte, error) {
+	var buf bytes.Buffer
+	err := ruleTemplate.Execute(&buf, struct {
+		*ruleSpec
+		Table string
+		Size  int
+	}{spec, strings.ToLower(spec.Name[:1]) + spec.Name[1:] + "Table", maxNeighbors + 1})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+func main() {
+	spec := &ruleSpec{}
+	rulestring := flag.String("rule", "", "Life-like rulestring, e.g. B3/S23")
+	flag.StringVar(&spec.Name, "name", "", "name of the generated Rule function")
+	flag.StringVar(&spec.Package, "package", os.Getenv("GOPACKAGE"), "package of the generated file")
+	flag.StringVar(&spec.Alive, "alive", "X", "state of live cells")
+	flag.StringVar(&spec.Dead, "dead", "-", "state of dead cells")
+	output := flag.String("o", "", "file to write; stdout if empty")
+	flag.Parse()
+
+	if spec.Name == "" || spec.Package == "" {
+		fmt.Fprintln(os.Stderr, "rulegen: -name and -package (or $GOPACKAGE) are required")
+		os.Exit(2)
+	}
+	if err := parseRulestring(spec, *rulestring); err != nil {
+		fmt.Fprintln(os.Stderr, "rulegen:", err)
+		os.Exit(2)
+	}
+	src, err := generate(spec)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rulegen:", err)
+		os.Exit(1)
+	}
+	if *output == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := ioutil.WriteFile(*output, src, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "rulegen:", err)
+		os.Exit(1)
+	}
+}
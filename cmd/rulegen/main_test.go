@@ -0,0 +1,64 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRulestring(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	spec := &ruleSpec{}
+	assert.Nil(parseRulestring(spec, "B3/S23"))
+	assert.Equal([9]bool{3: true}, spec.Birth)
+	assert.Equal([9]bool{2: true, 3: true}, spec.Survival)
+
+	spec = &ruleSpec{}
+	assert.Nil(parseRulestring(spec, "b2/s"))
+	assert.Equal([9]bool{2: true}, spec.Birth)
+	assert.Equal([9]bool{}, spec.Survival)
+
+	for _, bad := range []string{"", "B3", "S23/B3", "B39/S23", "B3/S2x", "B3/S23/G4"} {
+		assert.NotNil(parseRulestring(&ruleSpec{}, bad), bad)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	spec := &ruleSpec{Name: "Seeds", Package: "rules", Alive: "O", Dead: "."}
+	assert.Nil(parseRulestring(spec, "B2/S"))
+	src, err := generate(spec)
+	assert.Nil(err)
+	assert.True(strings.HasPrefix(string(src), "// Code generated by rulegen -rule B2/S -name Seeds; DO NOT EDIT."))
+	assert.Contains(string(src), "package rules\n")
+	assert.Contains(string(src), "var seedsTable = [2][9]cellaut.State{\n"+
+		"\t{\".\", \".\", \"O\", \".\", \".\", \".\", \".\", \".\", \".\"},\n"+
+		"\t{\".\", \".\", \".\", \".\", \".\", \".\", \".\", \".\", \".\"},\n}")
+
+	// Outside package cellaut, the rule has to compile against cellaut's exported names alone
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "seeds_gen.go", src, 0)
+	if !assert.Nil(err) {
+		return
+	}
+	config := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = config.Check("rules", fset, []*ast.File{f}, nil)
+	assert.Nil(err)
+
+	// In package cellaut, nothing's qualified
+	spec.Package = "cellaut"
+	src, err = generate(spec)
+	assert.Nil(err)
+	assert.NotContains(string(src), "import")
+	assert.Contains(string(src), "func Seeds(self State, neighbors map[NeighborIndex]State) State {")
+}
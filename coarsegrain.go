@@ -0,0 +1,166 @@
+package cellaut
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+)
+
+/*
+ScaleStats is what CoarseGrain measured of a run at one scale. Each is averaged over the run's
+frames.
+*/
+type ScaleStats struct {
+	// Block is how many cells across each cell at this scale stands for
+	Block         int
+	Width, Height int
+	// Density is the fraction of cells that aren't in the background State
+	Density float64
+	// Activity is the fraction of cells that change from one frame to the next. It's 0 if there's
+	// only one frame.
+	Activity float64
+	// Entropy is the Shannon entropy, in bits, of the mix of States the cells are in
+	Entropy float64
+}
+
+/*
+CoarseGrain renormalizes a run: it shrinks every frame by factor with Downscale, over and over, up
+to levels times or until the frames are down to a single cell, and measures the frames at each
+scale, starting with the frames as they are. Blocks that are tied go to background. How the numbers change from scale to scale
+characterizes the dynamics: noise washes out to the background as the blocks get bigger, while
+structure that's there at every scale, like the domains of a voting rule or the triangles of Rule
+90, keeps its density and activity.
+
+The frames must all be the same size, as a History's Frames are.
+*/
+func CoarseGrain(frames []*StateGrid, background State, factor, levels int) ([]ScaleStats, error) {
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames to coarse-grain")
+	}
+	if factor < 2 {
+		return nil, fmt.Errorf("can't coarse-grain by a factor of %d; it has to be at least 2", factor)
+	}
+	for _, frame := range frames {
+		if frame.Width != frames[0].Width || frame.Height != frames[0].Height {
+			return nil, fmt.Errorf("frames are different sizes: %dx%d and %dx%d", frames[0].Width, frames[0].Height, frame.Width, frame.Height)
+		}
+	}
+	// Downscale breaks ties in favor of the first State in the table, so the frames are copied into
+	// one that starts with the background
+	table := NewStateTable(background)
+	copies := make([]*StateGrid, len(frames))
+	for i, frame := range frames {
+		copies[i] = NewStateGrid(frame.Width, frame.Height, table)
+		for j, id := range frame.cells {
+			copies[i].cells[j] = table.Intern(frame.Table.State(id))
+		}
+	}
+	frames = copies
+
+	var scales []ScaleStats
+	block := 1
+	for level := 0; ; level++ {
+		scales = append(scales, measureScale(frames, block))
+		if level == levels || (frames[0].Width == 1 && frames[0].Height == 1) {
+			return scales, nil
+		}
+		coarse := make([]*StateGrid, len(frames))
+		for i, frame := range frames {
+			coarse[i] = frame.Downscale(factor)
+		}
+		frames = coarse
+		block *= factor
+	}
+}
+
+/*
+measureScale works out the ScaleStats of frames, whose cells each stand for a block×block block,
+and whose background State is StateID 0.
+*/
+func measureScale(frames []*StateGrid, block int) ScaleStats {
+	stats := ScaleStats{Block: block, Width: frames[0].Width, Height: frames[0].Height}
+	cells := float64(stats.Width * stats.Height)
+	for i, frame := range frames {
+		counts := make(map[StateID]int)
+		for _, id := range frame.cells {
+			counts[id]++
+		}
+		stats.Density += 1 - float64(counts[0])/cells
+		for _, count := range counts {
+			p := float64(count) / cells
+			stats.Entropy -= p * math.Log2(p)
+		}
+		if i > 0 {
+			stats.Activity += float64(hammingDistance(frames[i-1], frame)) / cells
+		}
+	}
+	stats.Density /= float64(len(frames))
+	stats.Entropy /= float64(len(frames))
+	if len(frames) > 1 {
+		stats.Activity /= float64(len(frames) - 1)
+	}
+	return stats
+}
+
+/*
+coarseGrainCommand implements `cellaut coarsegrain [--rule B3/S23] [--width N] [--height N]
+[--ticks N] [--density P] [--factor N] [--levels N] [--seed N]`. It runs the rule from a random
+start and reports CoarseGrain of the run. The rule is given as for divergence.
+*/
+func coarseGrainCommand(args []string) error {
+	fs := flag.NewFlagSet("coarsegrain", flag.ContinueOnError)
+	ruleSpec := fs.String("rule", "B3/S23", "rulestring, or registered rule name and options, like 'life-like rule=B36/S23'")
+	width := fs.Int("width", 128, "width of the grid")
+	height := fs.Int("height", 128, "height of the grid")
+	ticks := fs.Int("ticks", 50, "how many ticks to run")
+	density := fs.Float64("density", 0.3, "fraction of cells alive at the start")
+	factor := fs.Int("factor", 2, "how many cells across each block is at each step")
+	levels := fs.Int("levels", 4, "how many times to coarse-grain")
+	seed := fs.Int64("seed", 0, "random seed; defaults to the time")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	if *width < 1 || *height < 1 || *ticks < 1 {
+		return fmt.Errorf("width, height and ticks must all be at least 1")
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	rule, err := lookupRuleSpec(*ruleSpec)
+	if err != nil {
+		return err
+	}
+	grid := randomStateGrid(*width, *height, []State{"-", "X"}, *density, rand.New(rand.NewSource(*seed)))
+	frames := []*StateGrid{grid}
+	for tick := 1; tick <= *ticks; tick++ {
+		grid = stepGrid(grid, rule, mooreNeighborhood)
+		frames = append(frames, grid)
+	}
+	scales, err := CoarseGrain(frames, "-", *factor, *levels)
+	if err != nil {
+		return err
+	}
+	return writeCoarseGrain(os.Stdout, *ruleSpec, *seed, scales)
+}
+
+/*
+writeCoarseGrain reports what CoarseGrain found, one scale to a line.
+*/
+func writeCoarseGrain(w io.Writer, ruleSpec string, seed int64, scales []ScaleStats) error {
+	fmt.Fprintf(w, "rule %s, seed %d\n", ruleSpec, seed)
+	for _, scale := range scales {
+		size := fmt.Sprintf("%dx%d", scale.Width, scale.Height)
+		_, err := fmt.Fprintf(w, "block %4d  %9s  density %.3f  activity %.3f  entropy %.3f bits\n", scale.Block, size, scale.Density, scale.Activity, scale.Entropy)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
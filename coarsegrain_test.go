@@ -0,0 +1,78 @@
+package cellaut
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoarseGrain(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Two domains, half and half, look the same at every scale; a checkerboard is all fine
+	// detail, and washes out to the background at the first step
+	table := NewStateTable("-", "X")
+	domains, checkers := NewStateGrid(8, 8, table), NewStateGrid(8, 8, table)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if x < 4 {
+				domains.Set(x, y, "X")
+			}
+			if (x+y)%2 == 0 {
+				checkers.Set(x, y, "X")
+			}
+		}
+	}
+	scales, err := CoarseGrain([]*StateGrid{domains, domains}, "-", 2, 10)
+	assert.Nil(err)
+	assert.Len(scales, 4)
+	assert.Equal([3]int{8, 1, 1}, [3]int{scales[3].Block, scales[3].Width, scales[3].Height})
+	for _, scale := range scales[:3] {
+		assert.InDelta(0.5, scale.Density, 1e-9, "block %d", scale.Block)
+		assert.InDelta(1, scale.Entropy, 1e-9)
+		assert.Equal(0.0, scale.Activity)
+	}
+
+	// The checkerboard flips every tick, which only shows at the finest scale. Ties go to the
+	// background, whatever order the frames' table is in.
+	flipped := NewStateGrid(8, 8, NewStateTable("X", "-"))
+	copy(flipped.cells, checkers.cells)
+	scales, err = CoarseGrain([]*StateGrid{checkers, flipped, checkers}, "-", 2, 1)
+	assert.Nil(err)
+	assert.Len(scales, 2)
+	assert.Equal([3]float64{0.5, 1, 1}, [3]float64{scales[0].Density, scales[0].Activity, scales[0].Entropy})
+	assert.Equal([3]float64{0, 0, 0}, [3]float64{scales[1].Density, scales[1].Activity, scales[1].Entropy})
+
+	var b bytes.Buffer
+	assert.Nil(writeCoarseGrain(&b, "B3/S23", 1, scales))
+	assert.Contains(b.String(), "block    2        4x4  density 0.000  activity 0.000  entropy 0.000 bits\n")
+
+	_, err = CoarseGrain(nil, "-", 2, 1)
+	assert.NotNil(err)
+	_, err = CoarseGrain([]*StateGrid{checkers}, "-", 1, 1)
+	assert.NotNil(err)
+	_, err = CoarseGrain([]*StateGrid{checkers, NewStateGrid(4, 4, table)}, "-", 2, 1)
+	assert.NotNil(err)
+}
+
+func TestHistory_Frames(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	grid := startLifeGrid(t)
+	history := NewHistory(grid, 0)
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 5, History: history}))
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	frames := history.Frames()
+	assert.Len(frames, 5)
+	assert.Equal(history.At(3), frames[3])
+	scales, err := CoarseGrain(frames, LifeDead, 2, 1)
+	assert.Nil(err)
+	// A glider is 5 of the 30 cells
+	assert.InDelta(5.0/30, scales[0].Density, 1e-9)
+	assert.True(scales[0].Activity > 0)
+}
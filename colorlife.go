@@ -0,0 +1,88 @@
+package cellaut
+
+import (
+	"image/color"
+)
+
+// States used by the multi-color Life variants
+const (
+	LifeDead   State = "-"
+	LifeRed    State = "R"
+	LifeBlue   State = "B"
+	LifeGreen  State = "G"
+	LifeYellow State = "Y"
+)
+
+// ImmigrationPalette draws the states used by Immigration
+var ImmigrationPalette = Palette{
+	LifeDead: color.White,
+	LifeRed:  color.RGBA{0xd0, 0x20, 0x20, 0xff},
+	LifeBlue: color.RGBA{0x20, 0x40, 0xd0, 0xff},
+}
+
+// QuadLifePalette draws the states used by QuadLife
+var QuadLifePalette = Palette{
+	LifeDead:   color.White,
+	LifeRed:    color.RGBA{0xd0, 0x20, 0x20, 0xff},
+	LifeBlue:   color.RGBA{0x20, 0x40, 0xd0, 0xff},
+	LifeGreen:  color.RGBA{0x20, 0xa0, 0x30, 0xff},
+	LifeYellow: color.RGBA{0xe0, 0xc0, 0x10, 0xff},
+}
+
+/*
+Immigration is Conway's Life (B3/S23) with two colors of live cell.
+
+Surviving cells keep their color. A newborn cell takes the color held by the majority of its three
+parents.
+*/
+func Immigration(self State, neighbors map[NeighborIndex]State) State {
+	return colorLife(self, neighbors, []State{LifeRed, LifeBlue})
+}
+
+/*
+QuadLife is Conway's Life (B3/S23) with four colors of live cell.
+
+Surviving cells keep their color. A newborn cell takes the color held by the majority of its three
+parents or, if all three parents have different colors, the one color none of them has.
+*/
+func QuadLife(self State, neighbors map[NeighborIndex]State) State {
+	return colorLife(self, neighbors, []State{LifeRed, LifeBlue, LifeGreen, LifeYellow})
+}
+
+/*
+colorLife applies B3/S23, treating any of `colors` as alive, and picks the color of newborn cells.
+*/
+func colorLife(self State, neighbors map[NeighborIndex]State, colors []State) State {
+	counts := make(map[State]int)
+	alive := 0
+	for _, neighbor := range neighbors {
+		for _, c := range colors {
+			if neighbor == c {
+				counts[c]++
+				alive++
+			}
+		}
+	}
+	if self != LifeDead && self != "" {
+		if alive == 2 || alive == 3 {
+			return self
+		}
+		return LifeDead
+	}
+	if alive != 3 {
+		return LifeDead
+	}
+	for _, c := range colors {
+		if counts[c] >= 2 {
+			return c
+		}
+	}
+	// Three parents, all different colors. With two colors that can't happen, and with four there's
+	// exactly one color missing.
+	for _, c := range colors {
+		if counts[c] == 0 {
+			return c
+		}
+	}
+	return LifeDead
+}
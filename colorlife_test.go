@@ -0,0 +1,49 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+Returns a neighbor map containing the given states.
+
+The NeighborIndex keys are arbitrary; they just need to be distinct.
+*/
+func neighborMap(states ...State) map[NeighborIndex]State {
+	neighbors := make(map[NeighborIndex]State)
+	for i, state := range states {
+		neighbors[NeighborIndex(i)] = state
+	}
+	return neighbors
+}
+
+func TestImmigration(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Survival keeps the cell's own color regardless of its neighbors'
+	assert.Equal(LifeRed, Immigration(LifeRed, neighborMap(LifeBlue, LifeBlue, LifeDead)))
+	assert.Equal(LifeBlue, Immigration(LifeBlue, neighborMap(LifeRed, LifeRed, LifeRed)))
+	// Under- and overpopulation
+	assert.Equal(LifeDead, Immigration(LifeRed, neighborMap(LifeRed, LifeDead)))
+	assert.Equal(LifeDead, Immigration(LifeBlue, neighborMap(LifeRed, LifeRed, LifeBlue, LifeBlue)))
+	// Births take the majority color
+	assert.Equal(LifeRed, Immigration(LifeDead, neighborMap(LifeRed, LifeBlue, LifeRed, LifeDead)))
+	assert.Equal(LifeBlue, Immigration(LifeDead, neighborMap(LifeBlue, LifeBlue, LifeBlue)))
+	assert.Equal(LifeDead, Immigration(LifeDead, neighborMap(LifeBlue, LifeBlue)))
+	// The empty state counts as dead
+	assert.Equal(LifeRed, Immigration("", neighborMap(LifeRed, LifeRed, LifeBlue)))
+}
+
+func TestQuadLife(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal(LifeGreen, QuadLife(LifeDead, neighborMap(LifeGreen, LifeGreen, LifeYellow)))
+	assert.Equal(LifeYellow, QuadLife(LifeDead, neighborMap(LifeRed, LifeBlue, LifeGreen)))
+	assert.Equal(LifeRed, QuadLife(LifeDead, neighborMap(LifeBlue, LifeGreen, LifeYellow, LifeDead)))
+	assert.Equal(LifeYellow, QuadLife(LifeYellow, neighborMap(LifeRed, LifeBlue, LifeGreen)))
+	assert.Equal(LifeDead, QuadLife(LifeYellow, neighborMap(LifeRed)))
+}
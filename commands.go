@@ -0,0 +1,43 @@
+package cellaut
+
+import "sort"
+
+// commands are the subcommands of the cellaut binary, keyed by name.
+var commands = map[string]func(args []string) error{
+	"new-rule":    newRuleCommand,
+	"health":      healthCommand,
+	"buffers":     buffersCommand,
+	"demo":        demoCommand,
+	"repl":        replCommand,
+	"percolation": percolationCommand,
+	"explore":     exploreCommand,
+	"fetch":       fetchCommand,
+	"playground":  playgroundCommand,
+	"divergence":  divergenceCommand,
+	"coarsegrain": coarseGrainCommand,
+	"lightcone":   lightConeCommand,
+	"pack":        packCommand,
+	"unpack":      unpackCommand,
+}
+
+/*
+Command returns the subcommand of the cellaut binary with the given name, which takes the
+arguments that come after the name. They live here rather than in cmd/cellaut so that they can get
+at the package's insides.
+*/
+func Command(name string) (func(args []string) error, bool) {
+	command, ok := commands[name]
+	return command, ok
+}
+
+/*
+CommandNames returns the names of the subcommands, sorted.
+*/
+func CommandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,162 @@
+package cellaut
+
+import "sync"
+
+/*
+Exchange is called by a Coupler between ticks, once both of its Simulations have finished tickID,
+to pass information from one World to the other. It may read both Worlds and SetState on their
+cells; the states it sets take effect on the next tick, as they do for hooks.
+*/
+type Exchange func(tickID int64, a, b World)
+
+/*
+CopyRow is an Exchange that drives b with a: after every tick, row from of a is copied into row to
+of b, cell for cell, as far as the narrower World goes. Copying a boundary row like this is the
+usual way of coupling two automata edge to edge.
+*/
+func CopyRow(from, to int) Exchange {
+	return func(tickID int64, a, b World) {
+		aWidth, _ := a.Size()
+		bWidth, _ := b.Size()
+		for x := 0; x < minInt(aWidth, bWidth); x++ {
+			source, target := a.At(x, from), b.At(x, to)
+			if source != nil && target != nil {
+				target.SetState(source.GetState())
+			}
+		}
+	}
+}
+
+/*
+Coupler runs two Simulations in lockstep, for studying coupled and driven systems: neither starts a
+tick until the other has finished the one before, and in between, Exchange gets to move information
+from one to the other.
+
+Both Simulations must be configured, and not yet started. Each can have its own World, engine and
+subscribers, which are called before Exchange. The run ends when either one's MaxTicks is up, with
+both having run the same number of ticks. If one is stopped some other way, the Coupler stops the
+other too, at most a tick later.
+*/
+type Coupler struct {
+	A, B     *Simulation
+	Exchange Exchange
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// arrived is how many ticks each Simulation has finished, and exchanged how many ticks
+	// Exchange has been called for
+	arrived   [2]int64
+	exchanged int64
+	// limit is the fewest MaxTicks of the two, if either has one
+	limit int64
+	// done is set once either Simulation has stopped, so the other isn't kept waiting for it
+	done bool
+}
+
+/*
+NewCoupler returns a *Coupler that runs a and b in lockstep, calling exchange after every tick.
+exchange may be nil, to just keep the two in step.
+*/
+func NewCoupler(a, b *Simulation, exchange Exchange) *Coupler {
+	coupler := &Coupler{A: a, B: b, Exchange: exchange}
+	coupler.cond = sync.NewCond(&coupler.mu)
+	return coupler
+}
+
+/*
+Start starts both Simulations. It returns immediately.
+*/
+func (coupler *Coupler) Start() error {
+	sims := [2]*Simulation{coupler.A, coupler.B}
+	for side, sim := range sims {
+		side, sim := side, sim
+		if max := sim.config.MaxTicks; max > 0 && (coupler.limit == 0 || max < coupler.limit) {
+			coupler.limit = max
+		}
+		sim.Subscribe(func(event TickEvent) { coupler.arrive(side, event) })
+	}
+	for side, sim := range sims {
+		if err := sim.Start(); err != nil {
+			if side == 1 {
+				coupler.release()
+				coupler.A.Stop()
+			}
+			return err
+		}
+	}
+	for side, sim := range sims {
+		other := sims[1-side]
+		go func(sim *Simulation) {
+			sim.Wait()
+			coupler.release()
+			other.Stop()
+		}(sim)
+	}
+	return nil
+}
+
+/*
+arrive is called by each Simulation after every tick. It waits for the other to finish the same
+tick, and whichever gets there last calls Exchange, while the other waits for it to be done. Once
+the run is over, the Simulation is told to stop before its next tick.
+*/
+func (coupler *Coupler) arrive(side int, event TickEvent) {
+	ticks := event.TickID + 1
+	coupler.mu.Lock()
+	defer coupler.mu.Unlock()
+	coupler.arrived[side] = ticks
+	if coupler.arrived[1-side] >= ticks {
+		if coupler.Exchange != nil && !coupler.done {
+			coupler.mu.Unlock()
+			coupler.Exchange(event.TickID, coupler.A.config.World, coupler.B.config.World)
+			coupler.mu.Lock()
+		}
+		coupler.exchanged = ticks
+		coupler.cond.Broadcast()
+	}
+	for coupler.exchanged < ticks && !coupler.done {
+		coupler.cond.Wait()
+	}
+	if coupler.done || ticks == coupler.limit {
+		coupler.sim(side).halt()
+	}
+}
+
+func (coupler *Coupler) sim(side int) *Simulation {
+	if side == 0 {
+		return coupler.A
+	}
+	return coupler.B
+}
+
+/*
+release stops the Simulations waiting for each other.
+*/
+func (coupler *Coupler) release() {
+	coupler.mu.Lock()
+	coupler.done = true
+	coupler.cond.Broadcast()
+	coupler.mu.Unlock()
+}
+
+/*
+Stop stops both Simulations, and returns what Wait does.
+*/
+func (coupler *Coupler) Stop() error {
+	coupler.release()
+	coupler.A.Stop()
+	coupler.B.Stop()
+	return coupler.Wait()
+}
+
+/*
+Wait blocks until both Simulations have stopped, and returns the error that stopped A, if any, or
+else the one that stopped B.
+*/
+func (coupler *Coupler) Wait() error {
+	errA, errB := coupler.A.Wait(), coupler.B.Wait()
+	if errA != nil {
+		return errA
+	}
+	return errB
+}
@@ -0,0 +1,62 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoupler(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A runs Rule 90, and B just holds whatever it's given, so B shows A's row a tick late
+	a := NewSimulation()
+	lineA := NewLineGrid(9, Rule1D(90), "-", WithTopology(Cylinder))
+	lineA.At(4, 0).SetState("X")
+	assert.Nil(a.Configure(SimulationConfig{World: lineA, MaxTicks: 6}))
+	b := NewSimulation()
+	hold := func(self State, neighbors map[NeighborIndex]State) State { return self }
+	assert.Nil(b.Configure(SimulationConfig{World: NewLineGrid(9, hold, "-"), Engine: SynchronousEngine}))
+	var rowsA, rowsB []*StateGrid
+	a.Subscribe(func(event TickEvent) { rowsA = append(rowsA, TakeSnapshot(event.World, nil)) })
+	b.Subscribe(func(event TickEvent) { rowsB = append(rowsB, TakeSnapshot(event.World, nil)) })
+
+	coupler := NewCoupler(a, b, CopyRow(0, 0))
+	assert.Nil(coupler.Start())
+	// B has no MaxTicks of its own, but stops with A
+	assert.Nil(coupler.Wait())
+	assert.Len(rowsA, 6)
+	assert.Len(rowsB, 6)
+	for i := 1; i < len(rowsB); i++ {
+		assert.True(rowsA[i-1].Equal(rowsB[i]), "tick %d", i)
+	}
+}
+
+func TestCoupler_Stop(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	a, b := NewSimulation(), NewSimulation()
+	assert.Nil(a.Configure(SimulationConfig{World: startLifeGrid(t)}))
+	assert.Nil(b.Configure(SimulationConfig{World: startLifeGrid(t)}))
+	ticks := 0
+	coupler := NewCoupler(a, b, func(tickID int64, a, b World) {
+		ticks++
+		assert.Equal(int64(ticks-1), tickID)
+	})
+	assert.Nil(coupler.Start())
+	for {
+		coupler.mu.Lock()
+		exchanged := coupler.exchanged
+		coupler.mu.Unlock()
+		if exchanged >= 3 {
+			break
+		}
+	}
+	assert.Nil(coupler.Stop())
+	assert.True(ticks >= 3)
+
+	// A Coupler can't start Simulations that aren't configured
+	assert.NotNil(NewCoupler(NewSimulation(), NewSimulation(), nil).Start())
+}
@@ -0,0 +1,554 @@
+package cellaut
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mooreNeighborhood is where each of a cell's 8 neighbors is, relative to the cell
+var mooreNeighborhood = map[NeighborIndex][2]int{
+	NeighborUp:   {0, 1},
+	NeighborRt:   {1, 0},
+	NeighborDn:   {0, -1},
+	NeighborLf:   {-1, 0},
+	NeighborUpRt: {1, 1},
+	NeighborUpLf: {-1, 1},
+	NeighborDnRt: {1, -1},
+	NeighborDnLf: {-1, -1},
+}
+
+// vonNeumannNeighborhood is where each of a cell's 4 orthogonal neighbors is
+var vonNeumannNeighborhood = map[NeighborIndex][2]int{
+	NeighborUp: {0, 1},
+	NeighborRt: {1, 0},
+	NeighborDn: {0, -1},
+	NeighborLf: {-1, 0},
+}
+
+// lineNeighborhood is where a one-dimensional cell's neighbors are
+var lineNeighborhood = map[NeighborIndex][2]int{
+	NeighborRt: {1, 0},
+	NeighborLf: {-1, 0},
+}
+
+/*
+stepGrid applies rule to every cell of grid at once, and returns the next generation in a new
+StateGrid. Neighbors that would be off the edge of the grid are left out of the neighbor map.
+*/
+func stepGrid(grid *StateGrid, rule Rule, neighborhood map[NeighborIndex][2]int) *StateGrid {
+	next := NewStateGrid(grid.Width, grid.Height, grid.Table)
+	next.Groups = grid.Groups
+	neighbors := make(map[NeighborIndex]State, len(neighborhood))
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			for index, offset := range neighborhood {
+				nx, ny := x+offset[0], y+offset[1]
+				if nx < 0 || nx >= grid.Width || ny < 0 || ny >= grid.Height {
+					delete(neighbors, index)
+					continue
+				}
+				neighbors[index] = grid.At(nx, ny)
+			}
+			next.Set(x, y, rule(grid.At(x, y), neighbors))
+		}
+	}
+	return next
+}
+
+/*
+demoPreset is everything `cellaut demo` needs to show off a rule. Every preset's empty state is
+"-".
+*/
+type demoPreset struct {
+	name        string
+	description string
+	width       int
+	height      int
+	ticks       int
+	// rule is given the demo's random number generator, for rules that need one
+	rule         func(rng *rand.Rand) Rule
+	neighborhood map[NeighborIndex][2]int
+	// step, if it's set, is used to step the grid instead of rule, for models that don't fit in a
+	// single Rule
+	step    func(grid *StateGrid, tick int) *StateGrid
+	setup   func(grid *StateGrid, rng *rand.Rand) error
+	palette Palette
+	// spacetime presets are one row wide, and are drawn one row per tick, oldest first, rather than
+	// one frame per tick
+	spacetime bool
+	// expand isn't part of any preset, but set by --expand: the grid grows by this much whenever
+	// anything gets this close to its edge
+	expand int
+	// pacer isn't part of any preset either, but set by --adaptive: it picks which frames to show
+	pacer *AdaptivePacer
+	// input is set by --input: it has cells to change before each generation is shown
+	input *InputReader
+	// gif is set by --gif: it gets every generation that's shown
+	gif *GIFRecorder
+	// meta, if it's set, is stamped into the PNG and GIF
+	meta *RunMetadata
+}
+
+var demoPresets = []demoPreset{
+	{
+		name:         "glider-gun",
+		description:  "Gosper's glider gun in Conway's Life",
+		width:        60,
+		height:       36,
+		ticks:        120,
+		rule:         func(*rand.Rand) Rule { return Life },
+		neighborhood: mooreNeighborhood,
+		setup: placePattern(`
+			------------------------X-----------
+			----------------------X-X-----------
+			------------XX------XX------------XX
+			-----------X---X----XX------------XX
+			XX--------X-----X---XX--------------
+			XX--------X---X-XX----X-X-----------
+			----------X-----X-------X-----------
+			-----------X---X--------------------
+			------------XX----------------------
+		`, 2, 25),
+		palette: LifePalette,
+	},
+	{
+		name:         "wireworld-clock",
+		description:  "an electron going round a loop of WireWorld wire, sending a pulse down the line every 8 ticks",
+		width:        30,
+		height:       5,
+		ticks:        40,
+		rule:         func(*rand.Rand) Rule { return WireWorld },
+		neighborhood: mooreNeighborhood,
+		setup: placePattern(`
+			-CTH--------------------------
+			C---CCCCCCCCCCCCCCCCCCCCCCCCC-
+			-CCC--------------------------
+		`, 0, 1),
+		palette: WireWorldPalette,
+	},
+	{
+		name:        "forest-fire",
+		description: "the Drossel-Schwabl forest fire model",
+		width:       60,
+		height:      30,
+		ticks:       200,
+		rule: func(rng *rand.Rand) Rule {
+			return NewForestFire(0.03, 0.0002, rng)
+		},
+		neighborhood: mooreNeighborhood,
+		setup:        randomFill(0.5, ForestTree),
+		palette:      ForestFirePalette,
+	},
+	{
+		name:         "brians-brain",
+		description:  "Brian's Brain, starting from random noise",
+		width:        60,
+		height:       30,
+		ticks:        100,
+		rule:         func(*rand.Rand) Rule { return BriansBrain },
+		neighborhood: mooreNeighborhood,
+		setup:        randomFill(0.2, BrainOn),
+		palette:      BriansBrainPalette,
+	},
+	{
+		name:         "anneal",
+		description:  "Vichniac's twisted majority vote, coarsening random noise into smooth domains",
+		width:        80,
+		height:       40,
+		ticks:        100,
+		rule:         func(*rand.Rand) Rule { return Anneal },
+		neighborhood: mooreNeighborhood,
+		setup:        randomFill(0.5, "X"),
+		palette:      LifePalette,
+	},
+	{
+		name:        "traffic",
+		description: "Biham-Middleton-Levine traffic, just dense enough to jam",
+		width:       64,
+		height:      32,
+		ticks:       400,
+		step: func(grid *StateGrid, tick int) *StateGrid {
+			next, _ := TrafficStep(grid, tick%2 == 1)
+			return next
+		},
+		setup: func(grid *StateGrid, rng *rand.Rand) error {
+			*grid = *RandomTraffic(grid.Width, grid.Height, 0.38, rng)
+			return nil
+		},
+		palette: TrafficPalette,
+	},
+	{
+		name:         "langtons-loops",
+		description:  "Langton's self-reproducing loop, budding off daughter loops every 151 ticks",
+		width:        60,
+		height:       60,
+		ticks:        300,
+		rule:         func(*rand.Rand) Rule { return LangtonsLoops().Rule() },
+		neighborhood: vonNeumannNeighborhood,
+		setup:        placePattern(LangtonsLoopSeed, 30, 10),
+		palette:      LangtonsLoops().Palette,
+	},
+	{
+		name:        "von-neumann",
+		description: "a pulser in von Neumann's 29-state automaton, building out a construction arm",
+		width:       60,
+		height:      5,
+		ticks:       500,
+		step: func() func(*StateGrid, int) *StateGrid {
+			table := VonNeumann()
+			return func(grid *StateGrid, tick int) *StateGrid { return table.Step(grid) }
+		}(),
+		setup:   vonNeumannPulser(1, 1),
+		palette: VonNeumannPalette,
+	},
+	{
+		name:         "turing",
+		description:  "the 3-state busy beaver Turing machine, compiled into a one-dimensional automaton",
+		width:        16,
+		height:       1,
+		ticks:        16,
+		rule:         func(*rand.Rand) Rule { return mustParseTuringMachine(BusyBeaver3).Rule() },
+		neighborhood: lineNeighborhood,
+		setup: func(grid *StateGrid, rng *rand.Rand) error {
+			tape, err := mustParseTuringMachine(BusyBeaver3).Tape("", grid.Width, grid.Width/2)
+			if err != nil {
+				return err
+			}
+			*grid = *tape
+			return nil
+		},
+	},
+	{
+		name:         "rule110",
+		description:  "elementary rule 110 growing from a single cell",
+		width:        80,
+		height:       1,
+		ticks:        60,
+		rule:         func(*rand.Rand) Rule { return Elementary(110) },
+		neighborhood: lineNeighborhood,
+		setup: func(grid *StateGrid, rng *rand.Rand) error {
+			grid.Set(grid.Width-1, 0, "X")
+			return nil
+		},
+		palette:   LifePalette,
+		spacetime: true,
+	},
+}
+
+/*
+placePattern returns a preset setup that puts the given pattern, written as for ParsePattern, with
+its bottom left corner at (x, y).
+*/
+func placePattern(text string, x, y int) func(*StateGrid, *rand.Rand) error {
+	return func(grid *StateGrid, rng *rand.Rand) error {
+		pattern, err := ParsePattern(text, grid.Table)
+		if err != nil {
+			return err
+		}
+		if x+pattern.Width > grid.Width || y+pattern.Height > grid.Height {
+			return fmt.Errorf("pattern doesn't fit in a %dx%d grid", grid.Width, grid.Height)
+		}
+		for py := 0; py < pattern.Height; py++ {
+			for px := 0; px < pattern.Width; px++ {
+				grid.SetID(x+px, y+py, pattern.IDAt(px, py))
+			}
+		}
+		return nil
+	}
+}
+
+/*
+randomFill returns a preset setup that puts each cell in one of states, picked at random, with
+probability density.
+*/
+func randomFill(density float64, states ...State) func(*StateGrid, *rand.Rand) error {
+	return func(grid *StateGrid, rng *rand.Rand) error {
+		for y := 0; y < grid.Height; y++ {
+			for x := 0; x < grid.Width; x++ {
+				if rng.Float64() < density {
+					grid.Set(x, y, states[rng.Intn(len(states))])
+				}
+			}
+		}
+		return nil
+	}
+}
+
+/*
+findPreset returns the preset with the given name.
+*/
+func findPreset(name string) (demoPreset, bool) {
+	for _, preset := range demoPresets {
+		if preset.name == name {
+			return preset, true
+		}
+	}
+	return demoPreset{}, false
+}
+
+/*
+run sets up the preset and runs it for the given number of ticks, calling fn with every generation,
+starting with the initial one.
+*/
+func (preset demoPreset) run(ticks int, seed int64, fn func(tick int, grid *StateGrid)) error {
+	rng := rand.New(rand.NewSource(seed))
+	grid := NewStateGrid(preset.width, preset.height, NewStateTable("-"))
+	if err := preset.setup(grid, rng); err != nil {
+		return fmt.Errorf("setting up demo '%s': %s", preset.name, err)
+	}
+	step := preset.step
+	if step == nil {
+		rule := preset.rule(rng)
+		step = func(grid *StateGrid, tick int) *StateGrid { return stepGrid(grid, rule, preset.neighborhood) }
+	}
+	// originX and originY are where the starting grid's (0, 0) has got to, for the input's events
+	originX, originY := 0, 0
+	for tick := 0; tick <= ticks; tick++ {
+		if tick > 0 {
+			if preset.expand > 0 {
+				var dx, dy int
+				grid, dx, dy = Expand(grid, preset.expand)
+				originX, originY = originX+dx, originY+dy
+			}
+			grid = step(grid, tick)
+		}
+		if err := preset.applyInput(grid, tick, originX, originY); err != nil {
+			return err
+		}
+		fn(tick, grid)
+	}
+	return nil
+}
+
+/*
+applyInput sets the cells of grid that the preset's input has events for at tick. The events'
+coordinates are relative to the starting grid, whose (0, 0) is now at (originX, originY).
+*/
+func (preset demoPreset) applyInput(grid *StateGrid, tick, originX, originY int) error {
+	if preset.input == nil {
+		return nil
+	}
+	events, err := preset.input.Due(int64(tick))
+	if err != nil {
+		return err
+	}
+	bounds := Rect{Width: grid.Width, Height: grid.Height}
+	for _, event := range events {
+		x, y := event.X+originX, event.Y+originY
+		if !bounds.Contains(x, y) {
+			return fmt.Errorf("input for tick %d: (%d, %d) is outside the grid", event.Tick, event.X, event.Y)
+		}
+		grid.Set(x, y, event.State)
+	}
+	return nil
+}
+
+/*
+writeDemo runs the preset, writing each generation to w as text. If pngPath isn't empty, the last
+generation (or for spacetime presets, the whole history) is also drawn there with the preset's
+palette, cellSize pixels to a cell.
+
+If the preset has a pacer, only the generations it picks are written, along with the last one, and
+delay is only waited out before those. If it has a GIF recorder, that gets the same generations.
+*/
+func writeDemo(w io.Writer, preset demoPreset, ticks int, seed int64, delay time.Duration, pngPath string, cellSize int) error {
+	spacetime := NewSpaceTimeRecorder(preset.palette, cellSize)
+	var last *StateGrid
+	err := preset.run(ticks, seed, func(tick int, grid *StateGrid) {
+		last = grid
+		if preset.pacer != nil && !preset.pacer.Observe(grid) && tick < ticks {
+			return
+		}
+		if preset.gif != nil {
+			preset.gif.AddGrid(grid)
+		}
+		if tick > 0 && delay > 0 {
+			time.Sleep(delay)
+		}
+		if preset.spacetime {
+			fmt.Fprint(w, &Pattern{StateGrid: grid})
+			spacetime.Add(grid)
+			return
+		}
+		fmt.Fprintf(w, "tick %d\n%s\n", tick, &Pattern{StateGrid: grid})
+	})
+	if err != nil || pngPath == "" {
+		return err
+	}
+	if preset.spacetime {
+		last = spacetime.Diagram()
+	}
+	img, err := RenderGrid(last, preset.palette, cellSize)
+	if err != nil {
+		return err
+	}
+	f, err := createOutput(pngPath)
+	if err != nil {
+		return err
+	}
+	if err := EncodePNG(f, img, preset.meta); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+/*
+demoCommand implements `cellaut demo <name> [--ticks N] [--seed N] [--delay 100ms] [--png out.png]
+[--expand N] [--adaptive N] [--watch X,Y,W,H] [--input events.csv] [--gif out.gif]
+[--cell-size N] [--trail N] [--cycle S1,S2,...]`. Without a name, it lists the presets.
+*/
+func demoCommand(args []string) error {
+	fs := flag.NewFlagSet("demo", flag.ContinueOnError)
+	ticks := fs.Int("ticks", 0, "how many ticks to run; defaults to the preset's own")
+	seed := fs.Int64("seed", 1, "seed for presets that use randomness")
+	delay := fs.Duration("delay", 0, "how long to wait between frames")
+	pngPath := fs.String("png", "", "also draw the result to this PNG file, which may be an s3://bucket/key URL")
+	expand := fs.Int("expand", 0, "grow the grid by this many cells whenever anything gets that close to an edge")
+	adaptive := fs.Int("adaptive", 0, "skip up to this many ticks between frames while little is changing")
+	watch := fs.String("watch", "", "with --adaptive, show every tick while anything changes in this X,Y,W,H region")
+	gifPath := fs.String("gif", "", "also animate the generations shown in this GIF file or s3:// URL, a frame each, --delay apart")
+	cellSize := fs.Int("cell-size", 4, "how many pixels across each cell is in the PNG and GIF")
+	trail := fs.Int("trail", 0, "in the GIF, leave trails behind cells that fade over this many generations")
+	cycle := fs.String("cycle", "", "in the GIF, cycle the colors of these comma-separated states from frame to frame")
+	inputPath := fs.String("input", "", "read tick,x,y,state events from this CSV file, or - for stdin, and apply them as the demo runs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return listPresets(os.Stdout)
+	}
+	name := fs.Arg(0)
+	// Flags may come after the name too
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	preset, ok := findPreset(name)
+	if !ok {
+		return fmt.Errorf("no demo named '%s'; run `cellaut demo` for a list", name)
+	}
+	if *ticks == 0 {
+		*ticks = preset.ticks
+	}
+	if *ticks < 0 {
+		return fmt.Errorf("--ticks must not be negative")
+	}
+	if *expand < 0 {
+		return fmt.Errorf("--expand must not be negative")
+	}
+	if *expand > 0 && preset.spacetime {
+		return fmt.Errorf("demo '%s' is drawn as a spacetime diagram, which can't expand", preset.name)
+	}
+	preset.expand = *expand
+	if *adaptive < 0 {
+		return fmt.Errorf("--adaptive must not be negative")
+	}
+	if *adaptive > 0 && preset.spacetime {
+		return fmt.Errorf("demo '%s' is drawn as a spacetime diagram, which needs every tick", preset.name)
+	}
+	if *adaptive > 0 {
+		preset.pacer = NewAdaptivePacer(*adaptive)
+	}
+	if *watch != "" {
+		if preset.pacer == nil {
+			return fmt.Errorf("--watch only makes sense with --adaptive")
+		}
+		var rect Rect
+		if _, err := fmt.Sscanf(*watch, "%d,%d,%d,%d", &rect.X, &rect.Y, &rect.Width, &rect.Height); err != nil {
+			return fmt.Errorf("--watch should be X,Y,W,H: %s", err)
+		}
+		preset.pacer.Watch = &rect
+	}
+	switch *inputPath {
+	case "":
+	case "-":
+		preset.input = NewInputReader(os.Stdin)
+	default:
+		f, err := os.Open(*inputPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		preset.input = NewInputReader(f)
+	}
+	if *cellSize < 1 {
+		return fmt.Errorf("--cell-size must be at least 1")
+	}
+	if *gifPath != "" {
+		if preset.spacetime {
+			return fmt.Errorf("demo '%s' is drawn as a spacetime diagram, which is already in the PNG", preset.name)
+		}
+		preset.gif = NewGIFRecorder(preset.palette, *cellSize)
+		preset.gif.Delay = *delay
+	}
+	if *trail < 0 {
+		return fmt.Errorf("--trail must not be negative")
+	}
+	if (*trail > 0 || *cycle != "") && preset.gif == nil {
+		return fmt.Errorf("--trail and --cycle only make sense with --gif")
+	}
+	if preset.gif != nil {
+		preset.gif.Trail = *trail
+		if *cycle != "" {
+			for _, state := range strings.Split(*cycle, ",") {
+				preset.gif.Cycle = append(preset.gif.Cycle, State(state))
+			}
+		}
+	}
+	// Every flag goes into the config hash, so that runs with the same ones hash the same
+	config := map[string]string{"name": preset.name}
+	fs.VisitAll(func(f *flag.Flag) { config[f.Name] = f.Value.String() })
+	config["ticks"] = strconv.Itoa(*ticks)
+	meta, err := NewRunMetadata(preset.name, *seed, config)
+	if err != nil {
+		return err
+	}
+	preset.meta = meta
+	if preset.gif != nil {
+		preset.gif.Metadata = meta
+	}
+	if err := writeDemo(os.Stdout, preset, *ticks, *seed, *delay, *pngPath, *cellSize); err != nil {
+		return err
+	}
+	if preset.gif == nil {
+		return nil
+	}
+	f, err := createOutput(*gifPath)
+	if err != nil {
+		return err
+	}
+	if err := preset.gif.WriteGIF(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+/*
+listPresets writes the name and description of every preset to w.
+*/
+func listPresets(w io.Writer) error {
+	names := make([]string, len(demoPresets))
+	descriptions := make(map[string]string)
+	for i, preset := range demoPresets {
+		names[i] = preset.name
+		descriptions[preset.name] = preset.description
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := fmt.Fprintf(w, "%-16s %s\n", name, descriptions[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
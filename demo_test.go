@@ -0,0 +1,90 @@
+package cellaut
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDemoPresets(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Every preset sets up and runs, and doesn't die out right away
+	for _, preset := range demoPresets {
+		var last *StateGrid
+		err := preset.run(10, 1, func(tick int, grid *StateGrid) { last = grid })
+		assert.Nil(err, preset.name)
+		_, ok := last.Extent()
+		assert.True(ok, preset.name)
+	}
+
+	// The gun is back where it started after 30 ticks, with a glider on its way out
+	preset, _ := findPreset("glider-gun")
+	var grids []*StateGrid
+	preset.run(30, 1, func(tick int, grid *StateGrid) { grids = append(grids, grid) })
+	gun := Rect{X: 2, Y: 25, Width: 36, Height: 9}
+	assert.True(grids[0].Crop(gun).Equal(grids[30].Crop(gun)))
+	assert.Equal(grids[0].Population("X")+5, grids[30].Population("X"))
+
+	// With --expand, the grid grows to keep up with the gliders
+	preset, _ = findPreset("glider-gun")
+	preset.expand = 4
+	var last *StateGrid
+	preset.run(120, 1, func(tick int, grid *StateGrid) { last = grid })
+	assert.True(last.Width > 60)
+	assert.True(last.Population("X") > grids[30].Population("X"))
+
+	// A pulse reaches the end of the WireWorld line every 8 ticks
+	preset, _ = findPreset("wireworld-clock")
+	var arrivals []int
+	preset.run(60, 1, func(tick int, grid *StateGrid) {
+		if grid.At(28, 2) == WireHead {
+			arrivals = append(arrivals, tick)
+		}
+	})
+	assert.True(len(arrivals) >= 3)
+	for i := 1; i < len(arrivals); i++ {
+		assert.Equal(8, arrivals[i]-arrivals[i-1])
+	}
+}
+
+func TestWriteDemo(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	preset, ok := findPreset("rule110")
+	assert.True(ok)
+	var b bytes.Buffer
+	pngPath := filepath.Join(t.TempDir(), "rule110.png")
+	assert.Nil(writeDemo(&b, preset, 3, 1, 0, pngPath, 4))
+	rows := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Equal(4, len(rows))
+	ends := make([]string, len(rows))
+	for i, row := range rows {
+		ends[i] = row[len(row)-5:]
+	}
+	assert.Equal([]string{"----X", "---XX", "--XXX", "-XX-X"}, ends)
+	info, err := os.Stat(pngPath)
+	assert.Nil(err)
+	assert.True(info.Size() > 0)
+
+	b.Reset()
+	preset, _ = findPreset("wireworld-clock")
+	assert.Nil(writeDemo(&b, preset, 1, 1, 0, "", 4))
+	assert.True(strings.HasPrefix(b.String(), "tick 0\n"))
+	assert.Contains(b.String(), "tick 1\n")
+
+	b.Reset()
+	assert.Nil(listPresets(&b))
+	assert.Equal(len(demoPresets), strings.Count(b.String(), "\n"))
+	_, ok = findPreset("nope")
+	assert.False(ok)
+	assert.NotNil(demoCommand([]string{"nope"}))
+	assert.NotNil(demoCommand([]string{"rule110", "extra"}))
+	assert.NotNil(demoCommand([]string{"rule110", "--expand", "4"}))
+}
@@ -0,0 +1,151 @@
+package cellaut
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+/*
+LineRule is a one-dimensional, two-state rule of any radius, stored as a lookup table. It's the
+representation used in the density classification literature, where rules are searched for by
+flipping table entries.
+
+Table is indexed by the 2*Radius+1 cells centered on a cell, leftmost cell as the highest bit.
+*/
+type LineRule struct {
+	Radius int
+	Table  []bool
+}
+
+/*
+NewLineRule builds the table for a LineRule from a function of the window of cells around each
+cell, leftmost first.
+*/
+func NewLineRule(radius int, fn func(window []bool) bool) LineRule {
+	size := 2*radius + 1
+	rule := LineRule{Radius: radius, Table: make([]bool, 1<<uint(size))}
+	window := make([]bool, size)
+	for index := range rule.Table {
+		for i := range window {
+			window[i] = index&(1<<uint(size-1-i)) != 0
+		}
+		rule.Table[index] = fn(window)
+	}
+	return rule
+}
+
+/*
+Step applies the rule to every cell of a ring of cells at once, writing the result to next, which
+must be the same length as cells.
+*/
+func (rule LineRule) Step(cells, next []bool) {
+	n := len(cells)
+	size := 2*rule.Radius + 1
+	mask := 1<<uint(size) - 1
+	// Slide a window of bits along the ring, starting with the one centered on cell 0
+	index := 0
+	for i := -rule.Radius; i <= rule.Radius; i++ {
+		index <<= 1
+		if cells[((i%n)+n)%n] {
+			index |= 1
+		}
+	}
+	for i := 0; i < n; i++ {
+		next[i] = rule.Table[index]
+		index = (index << 1) & mask
+		if cells[(i+rule.Radius+1)%n] {
+			index |= 1
+		}
+	}
+}
+
+/*
+GKL returns the Gacs-Kurdyumov-Levin rule, the classic radius-3 density classifier: a 0 takes the
+majority of itself and its neighbors 1 and 3 cells to the left, and a 1 the majority of itself and
+its neighbors 1 and 3 cells to the right.
+*/
+func GKL() LineRule {
+	return NewLineRule(3, func(window []bool) bool {
+		self := window[3]
+		var votes []bool
+		if self {
+			votes = []bool{self, window[4], window[6]}
+		} else {
+			votes = []bool{self, window[2], window[0]}
+		}
+		n := 0
+		for _, vote := range votes {
+			if vote {
+				n++
+			}
+		}
+		return n >= 2
+	})
+}
+
+/*
+DensityTask is the density classification task: given a ring of cells, each 0 or 1, the rule has to
+turn every cell into whichever value the majority started out as, within Steps steps.
+*/
+type DensityTask struct {
+	// Width is how many cells are in the ring. It should be odd so that there's always a majority.
+	Width int
+	// Steps is how long the rule gets. The usual choice is 2*Width.
+	Steps int
+	// Trials is how many random starting rings to try.
+	Trials int
+	// UniformDensity picks each ring's density uniformly at random, instead of setting each cell
+	// to 0 or 1 with even odds. Without it, rings are all close to half full, which is the hardest
+	// case and the one accuracies are usually quoted for; with it, most rings are easy, which
+	// gives a rule search something to climb at the start.
+	UniformDensity bool
+}
+
+/*
+Accuracy returns the fraction of random starting rings that rule classifies correctly. Since it's a
+number between 0 and 1 that grows as a rule gets better, it works as a fitness function.
+*/
+func (task DensityTask) Accuracy(rule LineRule, rng *rand.Rand) (float64, error) {
+	if task.Width < 1 || task.Steps < 0 || task.Trials < 1 {
+		return 0, fmt.Errorf("density task needs a Width and Trials of at least 1")
+	}
+	if task.Width < 2*rule.Radius+1 {
+		return 0, fmt.Errorf("ring of %d cells is narrower than the rule's neighborhood", task.Width)
+	}
+	cells, next := make([]bool, task.Width), make([]bool, task.Width)
+	correct := 0
+	for trial := 0; trial < task.Trials; trial++ {
+		p := 0.5
+		if task.UniformDensity {
+			p = rng.Float64()
+		}
+		ones := 0
+		for i := range cells {
+			cells[i] = rng.Float64() < p
+			if cells[i] {
+				ones++
+			}
+		}
+		majority := 2*ones > task.Width
+		for step := 0; step < task.Steps && !uniformLine(cells); step++ {
+			rule.Step(cells, next)
+			cells, next = next, cells
+		}
+		if uniformLine(cells) && cells[0] == majority {
+			correct++
+		}
+	}
+	return float64(correct) / float64(task.Trials), nil
+}
+
+/*
+uniformLine returns whether every cell has the same value.
+*/
+func uniformLine(cells []bool) bool {
+	for _, cell := range cells {
+		if cell != cells[0] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package cellaut
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineRule(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// With radius 1, the table is the elementary rule number's bits
+	rule110 := NewLineRule(1, func(window []bool) bool {
+		return Elementary(110)(lineState(window[1]), map[NeighborIndex]State{
+			NeighborLf: lineState(window[0]),
+			NeighborRt: lineState(window[2]),
+		}) == "X"
+	})
+	for i, bit := range rule110.Table {
+		assert.Equal(110&(1<<uint(i)) != 0, bit)
+	}
+
+	// The ring wraps around: the lone cell at the start grows to the left, onto the end
+	cells := []bool{true, false, false, false, false}
+	next := make([]bool, len(cells))
+	rule110.Step(cells, next)
+	assert.Equal([]bool{true, false, false, false, true}, next)
+}
+
+func lineState(alive bool) State {
+	if alive {
+		return "X"
+	}
+	return "-"
+}
+
+func TestDensityTask(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	gkl := GKL()
+	task := DensityTask{Width: 149, Steps: 298, Trials: 200}
+	accuracy, err := task.Accuracy(gkl, rand.New(rand.NewSource(1)))
+	assert.Nil(err)
+	// GKL famously gets about 82% of these right
+	assert.True(accuracy > 0.75 && accuracy < 0.9, "GKL accuracy %g", accuracy)
+
+	// Local majority voting freezes into stripes, so it almost never finishes
+	majority := NewLineRule(3, func(window []bool) bool {
+		n := 0
+		for _, cell := range window {
+			if cell {
+				n++
+			}
+		}
+		return n > 3
+	})
+	accuracy, err = task.Accuracy(majority, rand.New(rand.NewSource(1)))
+	assert.Nil(err)
+	assert.True(accuracy < 0.1, "majority accuracy %g", accuracy)
+
+	// Rings far from half full are easier
+	task.UniformDensity = true
+	accuracy, err = task.Accuracy(gkl, rand.New(rand.NewSource(1)))
+	assert.Nil(err)
+	assert.True(accuracy > 0.9, "GKL accuracy %g", accuracy)
+
+	_, err = DensityTask{Width: 5, Steps: 10, Trials: 1}.Accuracy(gkl, rand.New(rand.NewSource(1)))
+	assert.NotNil(err)
+}
@@ -0,0 +1,158 @@
+package cellaut
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+determinismWorkload is a World to run, built fresh for every run, along with what it should end up
+as under stepGrid, if that's known.
+*/
+type determinismWorkload struct {
+	name  string
+	world func() *Grid
+	// want, if it's set, is what the World should be after the workload's ticks
+	want *StateGrid
+}
+
+/*
+determinismWorkloads returns the Worlds that TestDeterminism runs: random Life soups on a plane and
+a torus, and a rule that cares which direction each neighbor is in.
+*/
+func determinismWorkloads(ticks int64) []determinismWorkload {
+	rng := rand.New(rand.NewSource(258))
+	soup := randomStateGrid(16, 16, []State{LifeDead, LifeAlive}, 0.35, rng)
+	fill := func(grid *Grid, start *StateGrid) *Grid {
+		for y := 0; y < start.Height; y++ {
+			for x := 0; x < start.Width; x++ {
+				grid.At(x, y).SetState(start.At(x, y))
+			}
+		}
+		return grid
+	}
+	want := soup
+	for tick := int64(1); tick < ticks; tick++ {
+		want = stepGrid(want, Life, mooreNeighborhood)
+	}
+
+	// Each cell takes the State of its left neighbor, flipped if the one above it is alive. Sending
+	// neighbor states the wrong way round, or a tick late, shows up straight away.
+	skew := func(self State, neighbors map[NeighborIndex]State) State {
+		state := neighbors[NeighborLf]
+		if neighbors[NeighborUp] == LifeAlive {
+			if state == LifeAlive {
+				return LifeDead
+			}
+			return LifeAlive
+		}
+		return state
+	}
+	skewStart := randomStateGrid(13, 7, []State{LifeDead, LifeAlive}, 0.5, rng)
+
+	return []determinismWorkload{
+		{
+			name:  "life on a plane",
+			world: func() *Grid { return fill(NewLifeGrid(16, 16), soup) },
+			want:  want,
+		},
+		{
+			name:  "life on a torus",
+			world: func() *Grid { return fill(NewLifeGrid(16, 16, WithTopology(Torus)), soup) },
+		},
+		{
+			name: "skew on a cylinder",
+			world: func() *Grid {
+				return fill(NewRuleGrid(13, 7, skew, LifeDead, WithTopology(Cylinder)), skewStart)
+			},
+		},
+	}
+}
+
+/*
+runHistory runs world and returns its States after every tick.
+*/
+func runHistory(t *testing.T, world *Grid, engine EngineKind, workers, batchSize int, ticks int64) []*StateGrid {
+	var history []*StateGrid
+	sim := NewSimulation()
+	assert.Nil(t, sim.Configure(SimulationConfig{World: world, Engine: engine, Workers: workers, BatchSize: batchSize, MaxTicks: ticks}))
+	sim.Subscribe(func(event TickEvent) {
+		history = append(history, TakeSnapshot(event.World, NewStateTable(LifeDead)))
+	})
+	assert.Nil(t, sim.Start())
+	assert.Nil(t, sim.Wait())
+	return history
+}
+
+/*
+TestDeterminism runs the same Worlds under every engine, with different numbers of Workers and
+GOMAXPROCS, over and over, and checks that every run goes through exactly the same States.
+
+It changes GOMAXPROCS, so it mustn't run in parallel with anything. Run it with
+
+	go test -race -run Determinism -count 20
+
+to shake out scheduling bugs. -short cuts down the repeats.
+*/
+func TestDeterminism(t *testing.T) {
+	assert := assert.New(t)
+
+	const ticks = 12
+	repeats := 3
+	if testing.Short() {
+		repeats = 1
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	procs := []int{1, 2, 4}
+	if runtime.NumCPU() > 4 {
+		procs = append(procs, runtime.NumCPU())
+	}
+	engines := []struct {
+		engine    EngineKind
+		workers   int
+		batchSize int
+	}{
+		{ChannelEngine, 0, 0},
+		{MultiplexedEngine, 1, 0},
+		{MultiplexedEngine, 3, 0},
+		// The workloads are smaller than a batch, so it takes small ones to share them out
+		{MultiplexedEngine, 3, 1},
+		{MultiplexedEngine, 4, 7},
+		// Defaults to GOMAXPROCS
+		{MultiplexedEngine, 0, 0},
+		{SynchronousEngine, 0, 0},
+	}
+
+	for _, workload := range determinismWorkloads(ticks) {
+		var reference []*StateGrid
+		for _, n := range procs {
+			runtime.GOMAXPROCS(n)
+			for _, e := range engines {
+				for repeat := 0; repeat < repeats; repeat++ {
+					how := fmt.Sprintf("%s: %s engine, %d workers in batches of %d, GOMAXPROCS %d, run %d", workload.name, e.engine, e.workers, e.batchSize, n, repeat)
+					history := runHistory(t, workload.world(), e.engine, e.workers, e.batchSize, ticks)
+					if !assert.Len(history, ticks, how) {
+						return
+					}
+					if reference == nil {
+						reference = history
+						if workload.want != nil {
+							assert.True(workload.want.Equal(history[ticks-1]), "%s: doesn't match stepGrid", how)
+						}
+						continue
+					}
+					for tick := range history {
+						if !reference[tick].Equal(history[tick]) {
+							assert.Fail("history diverged", "%s: tick %d differs from the first run", how, tick)
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+}
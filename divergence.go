@@ -0,0 +1,244 @@
+package cellaut
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+)
+
+/*
+lockstep steps a reference grid and a perturbed copy of it the same way, tick for tick, and says
+how far apart they've got.
+*/
+type lockstep struct {
+	reference, perturbed *StateGrid
+	advance              func(*StateGrid) *StateGrid
+}
+
+/*
+step runs both grids one tick and returns the Hamming distance between them: how many cells
+differ.
+*/
+func (pair *lockstep) step() int {
+	pair.reference = pair.advance(pair.reference)
+	pair.perturbed = pair.advance(pair.perturbed)
+	return hammingDistance(pair.reference, pair.perturbed)
+}
+
+/*
+randomStateGrid returns a grid where each cell is, with probability density, one of states other
+than the first, and otherwise the first. The states are interned in order, so their StateIDs are
+their indexes.
+*/
+func randomStateGrid(width, height int, states []State, density float64, rng *rand.Rand) *StateGrid {
+	grid := NewStateGrid(width, height, NewStateTable(states...))
+	for i := range grid.cells {
+		if rng.Float64() < density {
+			grid.cells[i] = StateID(1 + rng.Intn(len(states)-1))
+		}
+	}
+	return grid
+}
+
+/*
+perturb changes the cell at (x, y) of a grid made by randomStateGrid to some other one of states.
+*/
+func perturb(grid *StateGrid, x, y int, states []State, rng *rand.Rand) {
+	was := int(grid.IDAt(x, y))
+	grid.SetID(x, y, StateID((was+1+rng.Intn(len(states)-1))%len(states)))
+}
+
+/*
+hammingDistance returns how many cells of two grids the same size are in different States.
+*/
+func hammingDistance(a, b *StateGrid) int {
+	distance := 0
+	for i := range a.cells {
+		if a.Table == b.Table {
+			if a.cells[i] != b.cells[i] {
+				distance++
+			}
+		} else if a.Table.State(a.cells[i]) != b.Table.State(b.cells[i]) {
+			distance++
+		}
+	}
+	return distance
+}
+
+/*
+DivergenceConfig says how EstimateDivergence should go about it.
+*/
+type DivergenceConfig struct {
+	Width, Height int
+	// Ticks is how long each run goes on for
+	Ticks int
+	// Trials is how many random starts to average over
+	Trials int
+	// States are what the random starts are made of. The first is the empty state. Defaults to
+	// "-" and "X".
+	States []State
+	// Density is the fraction of cells that start in a State other than the first
+	Density float64
+	// Neighborhood defaults to the Moore neighborhood
+	Neighborhood map[NeighborIndex][2]int
+	Seed         int64
+}
+
+/*
+DivergenceResult is what EstimateDivergence found.
+*/
+type DivergenceResult struct {
+	// Distances is the mean Hamming distance between the runs at each tick, starting with the 1
+	// cell that was changed at tick 0
+	Distances []float64
+	// Rate is the fitted exponential growth rate of the distance per tick, over the ticks before
+	// the damage either died out or filled half the grid. It's the cellular automaton's stand-in
+	// for the largest Lyapunov exponent.
+	Rate float64
+	// Damage is the fraction of cells that differ at the end
+	Damage float64
+}
+
+/*
+Chaotic is a rough verdict: the damage from one changed cell grew, and was still there at the end.
+Ordered rules heal it; chaotic ones spread it until it covers a good part of the grid.
+*/
+func (result DivergenceResult) Chaotic() bool {
+	return result.Rate > 0 && result.Damage > 0.05
+}
+
+/*
+EstimateDivergence measures how sensitive a rule is to its initial conditions. For each trial, it
+makes a random grid, changes one cell in the middle of a copy, runs the two in lockstep, and tracks
+how many cells differ. Rules that roll dice, like forest-fire, come out looking chaotic whatever
+they do, since the two runs don't roll the same numbers.
+*/
+func EstimateDivergence(rule Rule, config DivergenceConfig) (DivergenceResult, error) {
+	if config.Width < 1 || config.Height < 1 || config.Ticks < 1 || config.Trials < 1 {
+		return DivergenceResult{}, fmt.Errorf("width, height, ticks and trials must all be at least 1")
+	}
+	states := config.States
+	if len(states) == 0 {
+		states = []State{"-", "X"}
+	}
+	if len(states) < 2 {
+		return DivergenceResult{}, fmt.Errorf("need at least 2 states to perturb a cell")
+	}
+	neighborhood := config.Neighborhood
+	if neighborhood == nil {
+		neighborhood = mooreNeighborhood
+	}
+	advance := func(grid *StateGrid) *StateGrid { return stepGrid(grid, rule, neighborhood) }
+	rng := rand.New(rand.NewSource(config.Seed))
+	result := DivergenceResult{Distances: make([]float64, config.Ticks+1)}
+	cx, cy := config.Width/2, config.Height/2
+	for trial := 0; trial < config.Trials; trial++ {
+		reference := randomStateGrid(config.Width, config.Height, states, config.Density, rng)
+		perturbed := cloneStateGrid(reference)
+		perturb(perturbed, cx, cy, states, rng)
+
+		pair := &lockstep{reference: reference, perturbed: perturbed, advance: advance}
+		result.Distances[0]++
+		for tick := 1; tick <= config.Ticks; tick++ {
+			result.Distances[tick] += float64(pair.step())
+		}
+	}
+	for tick := range result.Distances {
+		result.Distances[tick] /= float64(config.Trials)
+	}
+	cells := float64(config.Width * config.Height)
+	result.Damage = result.Distances[config.Ticks] / cells
+	result.Rate = divergenceRate(result.Distances, cells/2)
+	return result, nil
+}
+
+/*
+divergenceRate fits a line to the log of the distances, by least squares, from the start up to the
+first tick where the distance hits 0 or saturation. The slope is the exponential growth rate. If
+the damage dies out straight away, the rate is -Inf.
+*/
+func divergenceRate(distances []float64, saturation float64) float64 {
+	var n, sumT, sumY, sumTT, sumTY float64
+	for tick, d := range distances {
+		if d <= 0 {
+			if n < 2 {
+				return math.Inf(-1)
+			}
+			break
+		}
+		t, y := float64(tick), math.Log(d)
+		n++
+		sumT += t
+		sumY += y
+		sumTT += t * t
+		sumTY += t * y
+		if d >= saturation {
+			break
+		}
+	}
+	if n < 2 {
+		return 0
+	}
+	return (n*sumTY - sumT*sumY) / (n*sumTT - sumT*sumT)
+}
+
+/*
+divergenceCommand implements `cellaut divergence [--rule B3/S23] [--width N] [--height N]
+[--ticks N] [--trials N] [--density P] [--seed N]`. The rule can be a Life-like rulestring or a
+registered rule's name, with its options after it in the same argument.
+*/
+func divergenceCommand(args []string) error {
+	fs := flag.NewFlagSet("divergence", flag.ContinueOnError)
+	ruleSpec := fs.String("rule", "B3/S23", "rulestring, or registered rule name and options, like 'life-like rule=B36/S23'")
+	width := fs.Int("width", 64, "width of the grid")
+	height := fs.Int("height", 64, "height of the grid")
+	ticks := fs.Int("ticks", 30, "how many ticks to run each trial")
+	trials := fs.Int("trials", 20, "how many random starts to average over")
+	density := fs.Float64("density", 0.3, "fraction of cells alive at the start")
+	seed := fs.Int64("seed", 0, "random seed; defaults to the time")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	rule, err := lookupRuleSpec(*ruleSpec)
+	if err != nil {
+		return err
+	}
+	result, err := EstimateDivergence(rule, DivergenceConfig{
+		Width:   *width,
+		Height:  *height,
+		Ticks:   *ticks,
+		Trials:  *trials,
+		Density: *density,
+		Seed:    *seed,
+	})
+	if err != nil {
+		return err
+	}
+	return writeDivergence(os.Stdout, *ruleSpec, *seed, result)
+}
+
+/*
+writeDivergence reports a DivergenceResult.
+*/
+func writeDivergence(w io.Writer, ruleSpec string, seed int64, result DivergenceResult) error {
+	fmt.Fprintf(w, "rule %s, seed %d\n", ruleSpec, seed)
+	for tick, d := range result.Distances {
+		fmt.Fprintf(w, "tick %3d  distance %.1f\n", tick, d)
+	}
+	verdict := "ordered"
+	if result.Chaotic() {
+		verdict = "chaotic"
+	}
+	_, err := fmt.Fprintf(w, "rate %.3f per tick, final damage %.1f%%: %s\n", result.Rate, 100*result.Damage, verdict)
+	return err
+}
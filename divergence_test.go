@@ -0,0 +1,88 @@
+package cellaut
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHammingDistance(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	table := NewStateTable("-", "X")
+	a, b := NewStateGrid(3, 3, table), NewStateGrid(3, 3, table)
+	assert.Equal(0, hammingDistance(a, b))
+	a.Set(0, 0, "X")
+	b.Set(2, 1, "X")
+	assert.Equal(2, hammingDistance(a, b))
+
+	// Different tables are compared by State
+	c := NewStateGrid(3, 3, NewStateTable("X", "-"))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			c.Set(x, y, "-")
+		}
+	}
+	c.Set(0, 0, "X")
+	assert.Equal(0, hammingDistance(a, c))
+}
+
+func TestDivergenceRate(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Doubling every tick until it saturates
+	assert.InDelta(math.Log(2), divergenceRate([]float64{1, 2, 4, 8, 16, 16, 16}, 10), 1e-9)
+	// Healing straight away
+	assert.True(math.IsInf(divergenceRate([]float64{1, 0, 0}, 10), -1))
+	// Shrinking before it dies out
+	assert.True(divergenceRate([]float64{1, 0.5, 0.25, 0}, 10) < 0)
+}
+
+func TestEstimateDivergence(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	config := DivergenceConfig{Width: 32, Height: 32, Ticks: 20, Trials: 5, Density: 0.4, Seed: 1}
+
+	// Seeds is famously explosive, and so is any difference between two runs of it
+	seeds, _ := lifeLikeRule("B2/S")
+	result, err := EstimateDivergence(seeds, config)
+	assert.NoError(err)
+	assert.Len(result.Distances, 21)
+	assert.Equal(1.0, result.Distances[0])
+	assert.True(result.Rate > 0)
+	assert.True(result.Chaotic())
+
+	// A rule that forgets everything heals at once
+	blank := func(self State, neighbors map[NeighborIndex]State) State { return "-" }
+	result, err = EstimateDivergence(blank, config)
+	assert.NoError(err)
+	assert.Equal(0.0, result.Damage)
+	assert.False(result.Chaotic())
+
+	// The same seed gives the same answer
+	life, _ := lifeLikeRule("B3/S23")
+	a, _ := EstimateDivergence(life, config)
+	b, _ := EstimateDivergence(life, config)
+	assert.Equal(a, b)
+
+	_, err = EstimateDivergence(life, DivergenceConfig{Width: 4, Height: 4, Ticks: 0, Trials: 1})
+	assert.Error(err)
+	_, err = EstimateDivergence(life, DivergenceConfig{Width: 4, Height: 4, Ticks: 1, Trials: 1, States: []State{"-"}})
+	assert.Error(err)
+}
+
+func TestWriteDivergence(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	assert.NoError(writeDivergence(&buf, "B3/S23", 7, DivergenceResult{Distances: []float64{1, 3}, Rate: 1.1, Damage: 0.5}))
+	assert.Contains(buf.String(), "rule B3/S23, seed 7\n")
+	assert.Contains(buf.String(), "tick   1  distance 3.0\n")
+	assert.Contains(buf.String(), "50.0%: chaotic\n")
+}
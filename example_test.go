@@ -0,0 +1,37 @@
+package cellaut_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/danslimmon/cellaut"
+)
+
+// Running a rule table from another program
+func Example() {
+	table, err := cellaut.ParseRuleTable(strings.NewReader(`@RULE Spread
+@TABLE
+n_states:2
+neighborhood:vonNeumann
+symmetries:permute
+0,1,0,0,0,1
+`))
+	if err != nil {
+		panic(err)
+	}
+	pattern, err := cellaut.ParsePattern("-----\n-----\n--1--\n-----\n-----\n", cellaut.NewStateTable("-"))
+	if err != nil {
+		panic(err)
+	}
+	grid := pattern.StateGrid
+	for i := 0; i < 2; i++ {
+		grid = table.Step(grid)
+	}
+	fmt.Print(&cellaut.Pattern{StateGrid: grid})
+	// Output:
+	// --1--
+	// --1--
+	// 11111
+	// --1--
+	// --1--
+}
@@ -0,0 +1,72 @@
+package cellaut
+
+import "fmt"
+
+/*
+Expand returns grid with room added on every side where cells that aren't empty have got within
+margin cells of the edge, margin cells at a time, so that a bounded run never finds out it's
+bounded. If nothing's that close, it returns grid itself. dx and dy are how far the old cells moved
+to make room on the left and bottom.
+*/
+func Expand(grid *StateGrid, margin int) (expanded *StateGrid, dx, dy int) {
+	var left, right, bottom, top int
+	for _, warning := range CheckBoundary(0, grid, margin, Plane) {
+		switch warning.Edge {
+		case EdgeLeft:
+			left = margin
+		case EdgeRight:
+			right = margin
+		case EdgeBottom:
+			bottom = margin
+		case EdgeTop:
+			top = margin
+		}
+	}
+	if left+right+bottom+top == 0 {
+		return grid, 0, 0
+	}
+	expanded = NewStateGrid(grid.Width+left+right, grid.Height+bottom+top, grid.Table)
+	expanded.Groups = grid.Groups.Translate(left, bottom)
+	for y := 0; y < grid.Height; y++ {
+		copy(expanded.cells[(y+bottom)*expanded.Width+left:], grid.cells[y*grid.Width:(y+1)*grid.Width])
+	}
+	return expanded, left, bottom
+}
+
+/*
+ExpandingRun steps a StateGrid that grows whenever its activity gets near an edge, for runs that
+should be big enough without anyone having to say how big that is. It keeps track of where the
+starting grid's cells have got to, so that coordinates from before the run can still be found.
+*/
+type ExpandingRun struct {
+	Grid *StateGrid
+	// Margin is how close to an edge activity gets before the grid grows, and how much it grows by
+	Margin int
+	// MaxCells, if it's set, is as big as the grid is allowed to get
+	MaxCells int
+	// OriginX and OriginY are where (0, 0) of the starting grid is in Grid now
+	OriginX, OriginY int
+	step             func(*StateGrid) *StateGrid
+}
+
+/*
+NewExpandingRun returns an ExpandingRun that starts from grid and advances it with step.
+*/
+func NewExpandingRun(grid *StateGrid, margin int, step func(*StateGrid) *StateGrid) *ExpandingRun {
+	return &ExpandingRun{Grid: grid, Margin: margin, step: step}
+}
+
+/*
+Step grows the grid if it needs room, then advances it one tick. It returns an error, and leaves
+the grid alone, if growing would take it past MaxCells.
+*/
+func (run *ExpandingRun) Step() error {
+	expanded, dx, dy := Expand(run.Grid, run.Margin)
+	if run.MaxCells > 0 && expanded.Width*expanded.Height > run.MaxCells {
+		return fmt.Errorf("grid would grow to %d×%d, past the limit of %d cells", expanded.Width, expanded.Height, run.MaxCells)
+	}
+	run.OriginX += dx
+	run.OriginY += dy
+	run.Grid = run.step(expanded)
+	return nil
+}
@@ -0,0 +1,60 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpand(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	grid := NewStateGrid(6, 6, NewStateTable("-"))
+	grid.Set(3, 3, "X")
+	same, dx, dy := Expand(grid, 2)
+	assert.True(same == grid)
+	assert.Equal(0, dx)
+	assert.Equal(0, dy)
+
+	// Too close to the left and the top
+	grid.Set(1, 5, "X")
+	expanded, dx, dy := Expand(grid, 2)
+	assert.Equal(8, expanded.Width)
+	assert.Equal(8, expanded.Height)
+	assert.Equal(2, dx)
+	assert.Equal(0, dy)
+	assert.Equal(State("X"), expanded.At(5, 3))
+	assert.Equal(State("X"), expanded.At(3, 5))
+	assert.Equal(int64(2), expanded.Population("X"))
+	assert.True(expanded.Table == grid.Table)
+
+	// An empty grid never needs room
+	empty := NewStateGrid(1, 1, nil)
+	same, _, _ = Expand(empty, 5)
+	assert.True(same == empty)
+}
+
+func TestExpandingRun(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A glider heading down and to the right, on a grid far too small for it
+	pattern, err := ParsePattern("-X-\n--X\nXXX", NewStateTable("-"))
+	assert.Nil(err)
+	step := func(grid *StateGrid) *StateGrid { return stepGrid(grid, Life, mooreNeighborhood) }
+	run := NewExpandingRun(pattern.StateGrid, 2, step)
+	for tick := 0; tick < 40; tick++ {
+		assert.Nil(run.Step())
+	}
+	// It's moved 10 cells right and 10 down, and it's still whole
+	assert.Equal(int64(5), run.Grid.Population("X"))
+	extent, _ := run.Grid.Extent()
+	assert.Equal(Rect{X: run.OriginX + 10, Y: run.OriginY - 10, Width: 3, Height: 3}, extent)
+	assert.True(run.Grid.Width < 20)
+
+	run = NewExpandingRun(pattern.StateGrid, 2, step)
+	run.MaxCells = 30
+	assert.Error(run.Step())
+	assert.True(run.Grid == pattern.StateGrid)
+}
@@ -0,0 +1,100 @@
+package cellaut
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"time"
+)
+
+/*
+exploreOptions are the settings for an `cellaut explore` run.
+*/
+type exploreOptions struct {
+	rule    string
+	mutate  int
+	width   int
+	height  int
+	ticks   int
+	density float64
+	seed    int64
+}
+
+/*
+explore runs a Life-like rule on a random soup and writes the rule, the last generation and how the
+population changed to w. If opts.rule is empty, a random rule is used. The seed is written too, so
+that anything interesting can be found again.
+*/
+func explore(w io.Writer, opts exploreOptions) error {
+	rng := rand.New(rand.NewSource(opts.seed))
+	rulestring := opts.rule
+	if rulestring == "" {
+		rulestring = RandomLifeLike(rng)
+	}
+	for i := 0; i < opts.mutate; i++ {
+		var err error
+		if rulestring, err = MutateLifeLike(rulestring, rng); err != nil {
+			return err
+		}
+	}
+	rule, err := lifeLikeRule(rulestring)
+	if err != nil {
+		return err
+	}
+
+	grid := NewStateGrid(opts.width, opts.height, NewStateTable("-", "X"))
+	if err := randomFill(opts.density, "X")(grid, rng); err != nil {
+		return err
+	}
+	start := grid.Population("X")
+	for tick := 0; tick < opts.ticks; tick++ {
+		grid = stepGrid(grid, rule, mooreNeighborhood)
+	}
+	fmt.Fprintf(w, "rule %s, seed %d\n", rulestring, opts.seed)
+	fmt.Fprintf(w, "tick %d\n%s", opts.ticks, &Pattern{StateGrid: grid})
+	_, err = fmt.Fprintf(w, "population %d -> %d\n", start, grid.Population("X"))
+	return err
+}
+
+/*
+exploreCommand implements `cellaut explore [--random-rule | --rule B3/S23] [--mutate N] [--width N]
+[--height N] [--ticks N] [--density P] [--seed N]`, for poking around rule space.
+*/
+func exploreCommand(args []string) error {
+	fs := flag.NewFlagSet("explore", flag.ContinueOnError)
+	random := fs.Bool("random-rule", false, "pick a Life-like rule at random")
+	rule := fs.String("rule", "B3/S23", "Life-like rule to run, if not --random-rule")
+	mutate := fs.Int("mutate", 0, "flip this many of the rule's birth and survival counts first")
+	width := fs.Int("width", 60, "width of the grid")
+	height := fs.Int("height", 30, "height of the grid")
+	ticks := fs.Int("ticks", 100, "how many ticks to run")
+	density := fs.Float64("density", 0.3, "fraction of cells alive at the start")
+	seed := fs.Int64("seed", 0, "random seed; defaults to the time")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	if *width < 1 || *height < 1 || *ticks < 0 || *mutate < 0 {
+		return fmt.Errorf("--width and --height must be positive, and --ticks and --mutate not negative")
+	}
+	opts := exploreOptions{
+		rule:    *rule,
+		mutate:  *mutate,
+		width:   *width,
+		height:  *height,
+		ticks:   *ticks,
+		density: *density,
+		seed:    *seed,
+	}
+	if *random {
+		opts.rule = ""
+	}
+	if opts.seed == 0 {
+		opts.seed = time.Now().UnixNano()
+	}
+	return explore(os.Stdout, opts)
+}
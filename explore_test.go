@@ -0,0 +1,39 @@
+package cellaut
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplore(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	opts := exploreOptions{rule: "B3/S23", width: 20, height: 10, ticks: 5, density: 0.3, seed: 1}
+	var b bytes.Buffer
+	assert.Nil(explore(&b, opts))
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Equal("rule B3/S23, seed 1", lines[0])
+	assert.Equal("tick 5", lines[1])
+	assert.Equal(13, len(lines))
+	assert.True(strings.HasPrefix(lines[12], "population "))
+
+	// The same seed picks the same random rule
+	opts.rule = ""
+	var first, second bytes.Buffer
+	assert.Nil(explore(&first, opts))
+	assert.Nil(explore(&second, opts))
+	assert.Equal(first.String(), second.String())
+	assert.NotContains(first.String(), "rule B3/S23,")
+
+	opts.rule, opts.mutate = "B3/S23", 1
+	b.Reset()
+	assert.Nil(explore(&b, opts))
+	assert.NotContains(b.String(), "rule B3/S23,")
+
+	opts.rule = "nonsense"
+	assert.NotNil(explore(&b, opts))
+}
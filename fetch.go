@@ -0,0 +1,185 @@
+package cellaut
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// patternSources are the URLs fetch tries, in order, with %s replaced by the pattern's name:
+// LifeWiki's pattern files, then Catagolue's objects in Life, by apgcode (like xp2_7)
+var patternSources = []string{
+	"https://conwaylife.com/patterns/%s.rle",
+	"https://catagolue.hatsya.com/rle/b3s23/%s",
+}
+
+// patternName is what a pattern name can look like. It keeps names from escaping the cache
+// directory.
+var patternName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+/*
+patternFetcher downloads RLE patterns by name and keeps copies of them in a cache directory, so
+each pattern only gets downloaded once.
+*/
+type patternFetcher struct {
+	client   *http.Client
+	sources  []string
+	cacheDir string
+}
+
+/*
+newPatternFetcher returns a patternFetcher that caches patterns under the user's cache directory.
+*/
+func newPatternFetcher() (*patternFetcher, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &patternFetcher{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		sources:  patternSources,
+		cacheDir: filepath.Join(dir, "cellaut", "patterns"),
+	}, nil
+}
+
+/*
+Fetch returns the RLE of the named pattern, from the cache if it's there and refresh is false, and
+otherwise from the first source that has it.
+*/
+func (fetcher *patternFetcher) Fetch(name string, refresh bool) (string, error) {
+	if !patternName.MatchString(name) {
+		return "", fmt.Errorf("'%s' isn't a pattern name; use letters, digits, '-' and '_'", name)
+	}
+	path := filepath.Join(fetcher.cacheDir, name+".rle")
+	if !refresh {
+		if text, err := ioutil.ReadFile(path); err == nil {
+			return string(text), nil
+		}
+	}
+	var errs []error
+	for _, source := range fetcher.sources {
+		text, err := fetcher.download(fmt.Sprintf(source, name))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// Check it's really a pattern before caching it; some sites answer with an error page
+		if _, _, err := parseRLE(text); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", fmt.Sprintf(source, name), err))
+			continue
+		}
+		if err := os.MkdirAll(fetcher.cacheDir, 0755); err != nil {
+			return "", err
+		}
+		return text, ioutil.WriteFile(path, []byte(text), 0644)
+	}
+	return "", fmt.Errorf("couldn't fetch pattern '%s': %v", name, errs)
+}
+
+/*
+download GETs url and returns the body.
+*/
+func (fetcher *patternFetcher) download(url string) (string, error) {
+	resp, err := fetcher.client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	// No pattern worth fetching this way is anywhere near 16 MiB
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+/*
+runFetched parses a fetched pattern and runs it for the given number of ticks, in a grid with pad
+empty cells on every side, writing the last generation to w. rulestring overrides the pattern's own
+rule if it isn't empty.
+*/
+func runFetched(w io.Writer, text, rulestring string, ticks, pad int) error {
+	pattern, rle, err := parseRLE(text)
+	if err != nil {
+		return err
+	}
+	if rulestring == "" {
+		rulestring = rle
+	}
+	if rulestring == "" {
+		rulestring = "B3/S23"
+	}
+	rule, err := lifeLikeRule(normalizeRulestring(rulestring))
+	if err != nil {
+		return fmt.Errorf("%s; pass --rule to run it with a Life-like rule", err)
+	}
+	grid := NewStateGrid(pattern.Width+2*pad, pattern.Height+2*pad, NewStateTable("-"))
+	for y := 0; y < pattern.Height; y++ {
+		for x := 0; x < pattern.Width; x++ {
+			grid.Set(x+pad, y+pad, pattern.At(x, y))
+		}
+	}
+	for tick := 0; tick < ticks; tick++ {
+		grid = stepGrid(grid, rule, mooreNeighborhood)
+	}
+	_, err = fmt.Fprintf(w, "rule %s, tick %d\n%s", rulestring, ticks, &Pattern{StateGrid: grid})
+	return err
+}
+
+// lowerRulestring matches rulestrings written the way Catagolue does, like b3s23
+var lowerRulestring = regexp.MustCompile(`^[bB]([0-8]*)[sS]([0-8]*)$`)
+
+/*
+normalizeRulestring rewrites a rulestring like "b3s23" as "B3/S23". Others are left alone.
+*/
+func normalizeRulestring(rulestring string) string {
+	if match := lowerRulestring.FindStringSubmatch(rulestring); match != nil {
+		return "B" + match[1] + "/S" + match[2]
+	}
+	return rulestring
+}
+
+/*
+fetchCommand implements `cellaut fetch <name> [--ticks N] [--pad N] [--rule B3/S23] [--refresh]`,
+which downloads a pattern from LifeWiki or Catagolue (or takes it from the cache), runs it for the
+given number of ticks, and prints it.
+*/
+func fetchCommand(args []string) error {
+	fs := flag.NewFlagSet("fetch", flag.ContinueOnError)
+	ticks := fs.Int("ticks", 0, "how many ticks to run the pattern for")
+	pad := fs.Int("pad", 5, "how much empty space to leave around the pattern")
+	rule := fs.String("rule", "", "rule to run the pattern with, instead of the one it names")
+	refresh := fs.Bool("refresh", false, "download the pattern even if it's in the cache")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: cellaut fetch <pattern-name>")
+	}
+	name := fs.Arg(0)
+	// Flags may come after the name too
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *ticks < 0 || *pad < 0 {
+		return fmt.Errorf("usage: cellaut fetch <pattern-name> [--ticks N] [--pad N]")
+	}
+	fetcher, err := newPatternFetcher()
+	if err != nil {
+		return err
+	}
+	text, err := fetcher.Fetch(name, *refresh)
+	if err != nil {
+		return err
+	}
+	return runFetched(os.Stdout, text, *rule, *ticks, *pad)
+}
@@ -0,0 +1,74 @@
+package cellaut
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatternFetcher(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/wiki/glider.rle":
+			fmt.Fprint(w, "#N Glider\nx = 3, y = 3, rule = B3/S23\nbo$2bo$3o!\n")
+		case "/catagolue/xp2_7":
+			fmt.Fprint(w, "x = 3, y = 1, rule = b3s23\n3o!\n")
+		case "/wiki/broken.rle":
+			fmt.Fprint(w, "<html>not a pattern</html>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	fetcher := &patternFetcher{
+		client:   server.Client(),
+		sources:  []string{server.URL + "/wiki/%s.rle", server.URL + "/catagolue/%s"},
+		cacheDir: t.TempDir(),
+	}
+
+	text, err := fetcher.Fetch("glider", false)
+	assert.Nil(err)
+	assert.Contains(text, "bo$2bo$3o!")
+	// The second time, it comes from the cache
+	_, err = fetcher.Fetch("glider", false)
+	assert.Nil(err)
+	assert.Equal(int32(1), atomic.LoadInt32(&requests))
+	_, err = fetcher.Fetch("glider", true)
+	assert.Nil(err)
+	assert.Equal(int32(2), atomic.LoadInt32(&requests))
+
+	// Not on the wiki, so it falls through to Catagolue
+	text, err = fetcher.Fetch("xp2_7", false)
+	assert.Nil(err)
+	assert.Contains(text, "3o!")
+
+	_, err = fetcher.Fetch("broken", false)
+	assert.NotNil(err)
+	_, err = fetcher.Fetch("missing", false)
+	assert.NotNil(err)
+	_, err = fetcher.Fetch("../etc/passwd", false)
+	assert.NotNil(err)
+}
+
+func TestRunFetched(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	var b bytes.Buffer
+	assert.Nil(runFetched(&b, "x = 3, y = 1, rule = b3s23\n3o!\n", "", 1, 1))
+	assert.Equal("rule b3s23, tick 1\n--X--\n--X--\n--X--\n", b.String())
+
+	b.Reset()
+	assert.NotNil(runFetched(&b, "x = 1, y = 1, rule = WireWorld\nA!\n", "", 1, 1))
+	assert.Nil(runFetched(&b, "x = 1, y = 1, rule = WireWorld\no!\n", "B3/S23", 1, 1))
+}
@@ -0,0 +1,168 @@
+package cellaut
+
+import (
+	"fmt"
+)
+
+/*
+Kernel is a square convolution kernel with an odd side length, used to diffuse a Field.
+
+Kernel[dy+r][dx+r] is the fraction of a cell's value that flows to the cell at offset (dx, dy), where
+r is the kernel's radius.
+*/
+type Kernel [][]float64
+
+/*
+NewDiffusionKernel returns a 3x3 Kernel that keeps 1-rate of each cell's value in place and spreads
+the rest evenly among its NeighborUp, NeighborRt, NeighborDn and NeighborLf.
+*/
+func NewDiffusionKernel(rate float64) Kernel {
+	return Kernel{
+		{0, rate / 4, 0},
+		{rate / 4, 1 - rate, rate / 4},
+		{0, rate / 4, 0},
+	}
+}
+
+/*
+NewMooreDiffusionKernel is like NewDiffusionKernel, except that the diffused value is spread evenly
+among all 8 surrounding cells.
+*/
+func NewMooreDiffusionKernel(rate float64) Kernel {
+	return Kernel{
+		{rate / 8, rate / 8, rate / 8},
+		{rate / 8, 1 - rate, rate / 8},
+		{rate / 8, rate / 8, rate / 8},
+	}
+}
+
+func (kernel Kernel) radius() int {
+	return len(kernel) / 2
+}
+
+func (kernel Kernel) validate() error {
+	if len(kernel)%2 != 1 {
+		return fmt.Errorf("kernel must have an odd number of rows; got %d", len(kernel))
+	}
+	for _, row := range kernel {
+		if len(row) != len(kernel) {
+			return fmt.Errorf("kernel must be square; got a row of length %d in a kernel of height %d", len(row), len(kernel))
+		}
+	}
+	return nil
+}
+
+/*
+Field is a continuous quantity (e.g. heat or pheromone) laid over the same coordinates as a World.
+
+Every Step, the Field's values are spread out according to its Kernel and then decayed. Whatever
+diffuses past the edge of the Field is lost.
+
+Cell rules and agents can read the Field with At and deposit into it with Add. Like AgentLayer.Step,
+Step should be called between ticks.
+*/
+type Field struct {
+	Width  int
+	Height int
+	Kernel Kernel
+	// Decay is the fraction of the Field's value that disappears each step
+	Decay float64
+	// values holds the current value of every cell, row by row
+	values []float64
+	// scratch is where Step computes the next values
+	scratch []float64
+}
+
+/*
+NewField returns a *Field of the given size that's zero everywhere. Neither width nor height may be
+negative.
+*/
+func NewField(width, height int, kernel Kernel, decay float64) (*Field, error) {
+	if width < 0 || height < 0 {
+		return nil, fmt.Errorf("field size must not be negative; got %dx%d", width, height)
+	}
+	if err := kernel.validate(); err != nil {
+		return nil, err
+	}
+	if decay < 0 || decay > 1 {
+		return nil, fmt.Errorf("decay must be between 0 and 1; got %f", decay)
+	}
+	return &Field{
+		Width:   width,
+		Height:  height,
+		Kernel:  kernel,
+		Decay:   decay,
+		values:  make([]float64, width*height),
+		scratch: make([]float64, width*height),
+	}, nil
+}
+
+/*
+At returns the Field's value at (x, y).
+
+Coordinates outside the Field have the value 0.
+*/
+func (field *Field) At(x, y int) float64 {
+	if x < 0 || x >= field.Width || y < 0 || y >= field.Height {
+		return 0
+	}
+	return field.values[y*field.Width+x]
+}
+
+/*
+Set sets the Field's value at (x, y).
+
+Coordinates outside the Field are ignored, the same as anything that diffuses past its edge.
+*/
+func (field *Field) Set(x, y int, value float64) {
+	if x < 0 || x >= field.Width || y < 0 || y >= field.Height {
+		return
+	}
+	field.values[y*field.Width+x] = value
+}
+
+/*
+Add adds amount to the Field's value at (x, y).
+
+Coordinates outside the Field are ignored, so an agent at the edge can deposit without checking.
+*/
+func (field *Field) Add(x, y int, amount float64) {
+	if x < 0 || x >= field.Width || y < 0 || y >= field.Height {
+		return
+	}
+	field.values[y*field.Width+x] += amount
+}
+
+/*
+Total returns the sum of the Field's values.
+*/
+func (field *Field) Total() float64 {
+	var total float64
+	for _, v := range field.values {
+		total += v
+	}
+	return total
+}
+
+/*
+Step diffuses the Field through its Kernel, then applies Decay.
+*/
+func (field *Field) Step() {
+	r := field.Kernel.radius()
+	for y := 0; y < field.Height; y++ {
+		for x := 0; x < field.Width; x++ {
+			// Gather from the cells whose outflow lands on (x, y). A flow at offset (dx, dy) from
+			// the source lands here, so the source is at (x-dx, y-dy).
+			var sum float64
+			for ky, row := range field.Kernel {
+				for kx, weight := range row {
+					if weight != 0 {
+						sum += weight * field.At(x-(kx-r), y-(ky-r))
+					}
+				}
+			}
+			field.scratch[y*field.Width+x] = sum * (1 - field.Decay)
+		}
+	}
+	field.values, field.scratch = field.scratch, field.values
+}
@@ -0,0 +1,88 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestField_Diffusion(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	field, err := NewField(5, 5, NewDiffusionKernel(0.4), 0)
+	assert.Nil(err)
+	field.Set(2, 2, 1)
+	field.Step()
+	assert.InDelta(0.6, field.At(2, 2), 1e-9)
+	assert.InDelta(0.1, field.At(2, 3), 1e-9)
+	assert.InDelta(0.1, field.At(1, 2), 1e-9)
+	assert.InDelta(0, field.At(1, 1), 1e-9)
+	// Nothing has reached the edge yet, so nothing has been lost
+	assert.InDelta(1, field.Total(), 1e-9)
+	field.Step()
+	assert.InDelta(1, field.Total(), 1e-9)
+	assert.InDelta(0.02, field.At(1, 1), 1e-9)
+
+	// Asymmetric kernels push the field in one direction
+	wind := Kernel{
+		{0, 0, 0},
+		{0, 0, 1},
+		{0, 0, 0},
+	}
+	field, _ = NewField(3, 1, wind, 0)
+	field.Set(0, 0, 1)
+	field.Step()
+	assert.Equal([]float64{0, 1, 0}, []float64{field.At(0, 0), field.At(1, 0), field.At(2, 0)})
+	field.Step()
+	field.Step()
+	assert.Equal(0.0, field.Total())
+}
+
+func TestField_Decay(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	field, err := NewField(3, 3, NewMooreDiffusionKernel(0.8), 0.5)
+	assert.Nil(err)
+	field.Add(1, 1, 2)
+	field.Add(1, 1, 2)
+	field.Step()
+	assert.InDelta(2, field.Total(), 1e-9)
+	assert.InDelta(0.4, field.At(1, 1), 1e-9)
+	assert.InDelta(0.2, field.At(0, 0), 1e-9)
+}
+
+func TestField_Invalid(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	_, err := NewField(3, 3, Kernel{{1, 0}, {0, 0}}, 0)
+	assert.NotNil(err)
+	_, err = NewField(3, 3, Kernel{{0, 1, 0}, {1}, {0, 1, 0}}, 0)
+	assert.NotNil(err)
+	_, err = NewField(3, 3, NewDiffusionKernel(0.5), 1.5)
+	assert.NotNil(err)
+	_, err = NewField(-1, 5, NewDiffusionKernel(0.5), 0)
+	assert.NotNil(err)
+	_, err = NewField(5, -1, NewDiffusionKernel(0.5), 0)
+	assert.NotNil(err)
+	// An empty Field is fine, if not much use
+	_, err = NewField(0, 0, NewDiffusionKernel(0.5), 0)
+	assert.Nil(err)
+}
+
+func TestField_OutOfBounds(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Writes outside the Field are dropped, rather than wrapping onto the next row
+	field, err := NewField(3, 3, NewDiffusionKernel(0.5), 0)
+	assert.Nil(err)
+	field.Set(3, 0, 1)
+	field.Add(-1, 1, 1)
+	field.Add(0, 3, 1)
+	field.Set(1, -1, 1)
+	assert.Equal(float64(0), field.Total())
+	assert.Equal(float64(0), field.At(3, 0))
+}
@@ -0,0 +1,271 @@
+package cellaut
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+/*
+GIFRecorder collects frames of a run and writes them out as an animated GIF, for sharing what a
+rule does. Each cell is a CellSize × CellSize square in its State's color from Palette, as for
+RenderGrid.
+
+Trail and Cycle make animations easier to follow: a trail shows where fast-moving things have
+been, and cycling colors show which way the waves of a cyclic automaton are going. They're worked
+out from the last few grids the recorder has been given.
+
+It's safe for concurrent use.
+*/
+type GIFRecorder struct {
+	Palette  Palette
+	CellSize int
+	// Delay is how long each frame is shown. GIFs count in hundredths of a second, so it's rounded
+	// to the nearest one. Defaults to a tenth of a second.
+	Delay time.Duration
+	// MaxFrames, if it's set, is how many frames to keep. Any after that are ignored, so that a Sink
+	// can record the first few ticks of a longer run.
+	MaxFrames int
+	// Metadata, if it's set, is written into the GIF as a comment
+	Metadata *RunMetadata
+	// Trail, if it's set, leaves a trail behind cells for this many generations after they go back
+	// to Background, in their old State's color fading into Background's. It's cut short if there
+	// isn't room for every shade in a GIF's 256 colors.
+	Trail int
+	// Background is the State that trails fade into. Defaults to "-".
+	Background State
+	// Cycle, if it's set, cycles the colors of these States: in frame n, each is drawn in the
+	// color of the State n places after it, wrapping around
+	Cycle []State
+
+	mu     sync.Mutex
+	frames []*image.Paletted
+	// dropped is how many frames couldn't be drawn
+	dropped int
+	// recent are the last Trail grids, oldest first
+	recent []*StateGrid
+}
+
+/*
+NewGIFRecorder returns a *GIFRecorder that draws with palette, cellSize pixels to a cell.
+*/
+func NewGIFRecorder(palette Palette, cellSize int) *GIFRecorder {
+	return &GIFRecorder{Palette: palette, CellSize: cellSize}
+}
+
+/*
+AddGrid adds grid as the next frame.
+*/
+func (recorder *GIFRecorder) AddGrid(grid *StateGrid) {
+	recorder.addGrid(func() *StateGrid { return grid })
+}
+
+/*
+Sink returns a Sink that adds a frame of a Simulation's World after every tick.
+*/
+func (recorder *GIFRecorder) Sink() Sink {
+	return func(event TickEvent) {
+		recorder.addGrid(func() *StateGrid { return TakeSnapshot(event.World, nil) })
+	}
+}
+
+/*
+addGrid adds a frame of the grid that snapshot returns, unless there are enough already. A frame
+that can't be drawn is counted by Dropped, and the first one is logged, since a Sink has nowhere to
+return the error to.
+*/
+func (recorder *GIFRecorder) addGrid(snapshot func() *StateGrid) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if recorder.MaxFrames > 0 && len(recorder.frames) >= recorder.MaxFrames {
+		return
+	}
+	grid := snapshot()
+	frame, err := recorder.draw(grid)
+	if err != nil {
+		recorder.dropped++
+		if recorder.dropped == 1 {
+			log.WithField("frame", len(recorder.frames)).WithError(err).Error("couldn't draw GIF frame; dropping it and any more that can't be drawn")
+		}
+		return
+	}
+	recorder.frames = append(recorder.frames, frame)
+	if recorder.Trail > 0 {
+		recorder.recent = append(recorder.recent, grid)
+		if len(recorder.recent) > recorder.Trail {
+			recorder.recent = recorder.recent[1:]
+		}
+	}
+}
+
+/*
+draw draws grid as the next frame, with whatever effects are turned on. The caller must hold
+recorder.mu.
+*/
+func (recorder *GIFRecorder) draw(grid *StateGrid) (*image.Paletted, error) {
+	if err := recorder.Palette.check(); err != nil {
+		return nil, err
+	}
+	if recorder.Trail <= 0 && len(recorder.Cycle) == 0 {
+		return RenderGrid(grid, recorder.Palette, recorder.CellSize)
+	}
+	colors, indices := recorder.Palette.colors()
+	background := recorder.Background
+	if background == "" {
+		background = "-"
+	}
+	// cycled is the State whose color state is drawn in
+	frame := len(recorder.frames)
+	cycle := make(map[State]int)
+	for i, state := range recorder.Cycle {
+		cycle[state] = i
+	}
+	cycled := func(state State) State {
+		if i, ok := cycle[state]; ok {
+			return recorder.Cycle[(i+frame)%len(recorder.Cycle)]
+		}
+		return state
+	}
+
+	// Every State in the palette but the background gets a shade for each generation of trail,
+	// from just left to nearly gone
+	trail := recorder.Trail
+	if len(indices) > 0 {
+		trail = minInt(trail, (256-len(colors))/len(indices))
+	}
+	shades := make(map[State][]uint8)
+	states := make([]State, len(indices))
+	for state, i := range indices {
+		states[i-1] = state
+	}
+	for _, state := range states {
+		if state == background {
+			continue
+		}
+		for k := 1; k <= trail; k++ {
+			shades[state] = append(shades[state], uint8(len(colors)))
+			colors = append(colors, blendColors(recorder.Palette.Color(state), recorder.Palette.Color(background), float64(k)/float64(trail+1)))
+		}
+	}
+
+	cellSize := recorder.CellSize
+	img := image.NewPaletted(image.Rect(0, 0, grid.Width*cellSize, grid.Height*cellSize), colors)
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			state := grid.At(x, y)
+			index := indices[cycled(state)]
+			for k := 1; state == background && k <= minInt(trail, len(recorder.recent)); k++ {
+				old := recorder.recent[len(recorder.recent)-k]
+				if !(Rect{Width: old.Width, Height: old.Height}).Contains(x, y) {
+					break
+				}
+				if was := old.At(x, y); was != background {
+					if shade, ok := shades[cycled(was)]; ok {
+						index = shade[k-1]
+					}
+					break
+				}
+			}
+			top := (grid.Height - 1 - y) * cellSize
+			fillRect(img, image.Rect(x*cellSize, top, (x+1)*cellSize, top+cellSize), index)
+		}
+	}
+	return img, nil
+}
+
+/*
+blendColors mixes a and b, with t of b.
+*/
+func blendColors(a, b color.Color, t float64) color.Color {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	mix := func(x, y uint32) uint16 { return uint16(float64(x)*(1-t) + float64(y)*t) }
+	return color.RGBA64{R: mix(ar, br), G: mix(ag, bg), B: mix(ab, bb), A: mix(aa, ba)}
+}
+
+/*
+add adds the frame that draw draws, unless there are enough already. If draw returns an error,
+nothing is added and the error is returned.
+*/
+func (recorder *GIFRecorder) add(draw func() (*image.Paletted, error)) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if recorder.MaxFrames > 0 && len(recorder.frames) >= recorder.MaxFrames {
+		return nil
+	}
+	frame, err := draw()
+	if err != nil {
+		return err
+	}
+	recorder.frames = append(recorder.frames, frame)
+	return nil
+}
+
+/*
+Frames returns how many frames have been recorded.
+*/
+func (recorder *GIFRecorder) Frames() int {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return len(recorder.frames)
+}
+
+/*
+Dropped returns how many frames couldn't be drawn, and were left out.
+*/
+func (recorder *GIFRecorder) Dropped() int {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return recorder.dropped
+}
+
+/*
+WriteGIF writes the frames recorded so far to w as a GIF that loops forever. If the grid grew along
+the way, the GIF is as big as the biggest frame, and the smaller ones are drawn in its top left
+corner.
+*/
+func (recorder *GIFRecorder) WriteGIF(w io.Writer) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	// If the palette's too big, every frame will have been dropped, and this says why
+	if err := recorder.Palette.check(); err != nil {
+		return err
+	}
+	if len(recorder.frames) == 0 {
+		return fmt.Errorf("no frames to write")
+	}
+	delay := recorder.Delay
+	if delay == 0 {
+		delay = 100 * time.Millisecond
+	}
+	anim := &gif.GIF{LoopCount: 0}
+	for _, frame := range recorder.frames {
+		bounds := frame.Bounds()
+		anim.Config.Width = maxInt(anim.Config.Width, bounds.Dx())
+		anim.Config.Height = maxInt(anim.Config.Height, bounds.Dy())
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, int((delay+5*time.Millisecond)/(10*time.Millisecond)))
+		// Each frame replaces the last completely, rather than being drawn over it
+		anim.Disposal = append(anim.Disposal, gif.DisposalBackground)
+	}
+	anim.Config.ColorModel = recorder.frames[0].Palette
+	if recorder.Metadata == nil {
+		return gif.EncodeAll(w, anim)
+	}
+	var b bytes.Buffer
+	if err := gif.EncodeAll(&b, anim); err != nil {
+		return err
+	}
+	// The comment goes just before the trailer, the 0x3b that ends every GIF
+	data := b.Bytes()
+	data = append(data[:len(data)-1], append(gifComment(recorder.Metadata), 0x3b)...)
+	_, err := w.Write(data)
+	return err
+}
@@ -0,0 +1,151 @@
+package cellaut
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGIFRecorder(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	palette := Palette{LifeAlive: color.White, LifeDead: color.Gray{Y: 64}}
+	recorder := NewGIFRecorder(palette, 3)
+	recorder.Delay = 50 * time.Millisecond
+	recorder.MaxFrames = 4
+	var b bytes.Buffer
+	assert.NotNil(recorder.WriteGIF(&b))
+
+	grid := startLifeGrid(t)
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 6}))
+	sim.Subscribe(recorder.Sink())
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	assert.Equal(4, recorder.Frames())
+
+	assert.Nil(recorder.WriteGIF(&b))
+	anim, err := gif.DecodeAll(&b)
+	assert.Nil(err)
+	assert.Len(anim.Image, 4)
+	assert.Equal([]int{5, 5, 5, 5}, anim.Delay)
+	assert.Equal(18, anim.Config.Width)
+	assert.Equal(15, anim.Config.Height)
+	// The first frame is the starting glider, whose top cell is (1, 4), the second along the top
+	first := anim.Image[0]
+	r, g, bl, _ := first.At(4, 1).RGBA()
+	assert.Equal([]uint32{0xffff, 0xffff, 0xffff}, []uint32{r, g, bl})
+	r, _, _, _ = first.At(1, 1).RGBA()
+	assert.Equal(uint32(64*0x101), r)
+
+	// Frames of different sizes make a GIF as big as the biggest
+	recorder = NewGIFRecorder(palette, 1)
+	recorder.AddGrid(NewStateGrid(2, 5, nil))
+	recorder.AddGrid(NewStateGrid(4, 3, nil))
+	b.Reset()
+	assert.Nil(recorder.WriteGIF(&b))
+	config, err := gif.DecodeConfig(&b)
+	assert.Nil(err)
+	assert.Equal([]int{4, 5}, []int{config.Width, config.Height})
+
+	// Frames that can't be drawn are counted, and WriteGIF says why there aren't any
+	big := Palette{}
+	for i := 0; i <= maxPaletteStates; i++ {
+		big[State(fmt.Sprintf("s%d", i))] = color.Gray{Y: uint8(i)}
+	}
+	recorder = NewGIFRecorder(big, 1)
+	recorder.AddGrid(NewStateGrid(2, 2, nil))
+	recorder.AddGrid(NewStateGrid(2, 2, nil))
+	assert.Equal(0, recorder.Frames())
+	assert.Equal(2, recorder.Dropped())
+	err = recorder.WriteGIF(&b)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "palette")
+}
+
+/*
+startLifeGrid returns a 6x5 Life grid with a glider in its top left corner.
+*/
+func startLifeGrid(t *testing.T) *Grid {
+	grid := NewLifeGrid(6, 5)
+	for cell := range shifted(gliderCells, 0, 2) {
+		grid.At(cell[0], cell[1]).SetState(LifeAlive)
+	}
+	return grid
+}
+
+func TestDemo_GIF(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	preset, _ := findPreset("glider-gun")
+	preset.gif = NewGIFRecorder(preset.palette, 2)
+	var b bytes.Buffer
+	assert.Nil(writeDemo(&b, preset, 3, 1, 0, "", 2))
+	b.Reset()
+	assert.Nil(preset.gif.WriteGIF(&b))
+	anim, err := gif.DecodeAll(&b)
+	assert.Nil(err)
+	assert.Len(anim.Image, 4)
+	assert.Equal(120, anim.Config.Width)
+
+	path := filepath.Join(t.TempDir(), "rule110.gif")
+	assert.NotNil(demoCommand([]string{"rule110", "--gif", path}))
+	assert.NotNil(demoCommand([]string{"glider-gun", "--cell-size", "0"}))
+}
+
+func TestGIFRecorder_Effects(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	red := func(img *image.Paletted) uint32 {
+		r, _, _, _ := img.At(0, 0).RGBA()
+		return r
+	}
+	cell := func(state State) *StateGrid {
+		grid := NewStateGrid(1, 1, nil)
+		grid.Set(0, 0, state)
+		return grid
+	}
+
+	// A live cell leaves a trail that fades over 2 generations
+	recorder := NewGIFRecorder(Palette{LifeAlive: color.White, LifeDead: color.Black}, 1)
+	recorder.Trail = 2
+	for _, state := range []State{LifeAlive, LifeDead, LifeDead, LifeDead} {
+		recorder.AddGrid(cell(state))
+	}
+	var reds []uint32
+	for _, frame := range recorder.frames {
+		reds = append(reds, red(frame))
+	}
+	assert.Equal([]uint32{0xffff, 0xaaaa, 0x5555, 0}, reds)
+
+	// Cycling colors move along the cycle a State per frame
+	recorder = NewGIFRecorder(Palette{
+		"a": color.RGBA{R: 255, A: 255},
+		"b": color.RGBA{G: 255, A: 255},
+		"c": color.RGBA{B: 255, A: 255},
+	}, 1)
+	recorder.Cycle = []State{"a", "b", "c"}
+	for i := 0; i < 4; i++ {
+		recorder.AddGrid(cell("a"))
+	}
+	var colors []color.Color
+	for _, frame := range recorder.frames {
+		colors = append(colors, frame.At(0, 0))
+	}
+	assert.Equal([]color.Color{recorder.Palette["a"], recorder.Palette["b"], recorder.Palette["c"], recorder.Palette["a"]}, colors)
+	var b bytes.Buffer
+	assert.Nil(recorder.WriteGIF(&b))
+
+	assert.NotNil(demoCommand([]string{"glider-gun", "--trail", "3"}))
+	assert.NotNil(demoCommand([]string{"glider-gun", "--gif", "x.gif", "--trail", "-1"}))
+}
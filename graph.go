@@ -0,0 +1,268 @@
+package cellaut
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+/*
+NodeID names a node of a GraphAutomaton.
+*/
+type NodeID int
+
+// maxGraphDegree is the most neighbors a node of a GraphAutomaton can have. Every edge needs a
+// NeighborIndex that's free at one end and whose Recip is free at the other, and with no more
+// than this many at each end, there's always one left.
+const maxGraphDegree = 127
+
+/*
+GraphAutomaton is a World of CellAuts that live on the nodes of a graph rather than a lattice, each
+wired to the nodes it shares an edge with, for small-world and random-graph experiments.
+
+A node's neighbors don't have directions as such, so each edge is given whichever NeighborIndex is
+free at both ends, and rules should treat their neighbors as a set, counting them rather than
+looking them up by direction. Direction says which NeighborIndex an edge got.
+
+As a World, it's one row of nodes in NodeID order, so that it can be run by a Simulation and
+snapshotted like any other. As for Grid3D, the synchronous engine can't run it.
+*/
+type GraphAutomaton struct {
+	// nodes is every NodeID, in order
+	nodes []NodeID
+	cells map[NodeID]CellAut
+	// directions is the NeighborIndex that each node knows each of its neighbors by
+	directions map[NodeID]map[NodeID]NeighborIndex
+}
+
+/*
+NewGraphAutomaton builds a GraphAutomaton out of adjacency, which lists each node's neighbors,
+calling factory for the CellAut at each node. Edges go both ways, so a neighbor only has to be
+listed on one side of the edge, and every node that appears anywhere in adjacency is part of the
+graph. Edges from a node to itself are ignored. factory is called for the nodes in order.
+
+No node can have more than 127 neighbors.
+*/
+func NewGraphAutomaton(adjacency map[NodeID][]NodeID, factory func(id NodeID) CellAut) (*GraphAutomaton, error) {
+	graph := &GraphAutomaton{cells: make(map[NodeID]CellAut), directions: make(map[NodeID]map[NodeID]NeighborIndex)}
+	edges := make(map[NodeID]map[NodeID]bool)
+	addNode := func(id NodeID) {
+		if edges[id] == nil {
+			edges[id] = make(map[NodeID]bool)
+			graph.nodes = append(graph.nodes, id)
+		}
+	}
+	for id, neighbors := range adjacency {
+		addNode(id)
+		for _, neighbor := range neighbors {
+			addNode(neighbor)
+			if neighbor != id {
+				edges[id][neighbor] = true
+				edges[neighbor][id] = true
+			}
+		}
+	}
+	sort.Slice(graph.nodes, func(i, j int) bool { return graph.nodes[i] < graph.nodes[j] })
+	for _, id := range graph.nodes {
+		if len(edges[id]) > maxGraphDegree {
+			return nil, fmt.Errorf("node %d has %d neighbors; the most a node can have is %d", id, len(edges[id]), maxGraphDegree)
+		}
+		graph.cells[id] = factory(id)
+		graph.directions[id] = make(map[NodeID]NeighborIndex)
+	}
+
+	// Each edge is wired once, from the end with the lower NodeID, in order, so that the same
+	// graph is always wired the same way
+	used := make(map[NodeID]map[NeighborIndex]bool)
+	for _, id := range graph.nodes {
+		used[id] = make(map[NeighborIndex]bool)
+	}
+	for _, id := range graph.nodes {
+		for _, neighbor := range sortedNodes(edges[id]) {
+			if neighbor < id {
+				continue
+			}
+			i := NeighborIndex(0)
+			for used[id][i] || used[neighbor][i.Recip()] {
+				i++
+			}
+			used[id][i], used[neighbor][i.Recip()] = true, true
+			graph.directions[id][neighbor], graph.directions[neighbor][id] = i, i.Recip()
+			graph.cells[id].AddNeighbor(i, graph.cells[neighbor])
+			graph.cells[neighbor].AddNeighbor(i.Recip(), graph.cells[id])
+		}
+	}
+	return graph, nil
+}
+
+/*
+NewRuleGraph builds a GraphAutomaton out of adjacency whose nodes are RuleCellAuts that follow rule,
+all starting in state.
+*/
+func NewRuleGraph(adjacency map[NodeID][]NodeID, rule Rule, state State) (*GraphAutomaton, error) {
+	// Cells are numbered by their place in the row, as they are in a Grid
+	next := 0
+	return NewGraphAutomaton(adjacency, func(id NodeID) CellAut {
+		next++
+		return NewRuleCellAut(next-1, rule, state)
+	})
+}
+
+func sortedNodes(set map[NodeID]bool) []NodeID {
+	nodes := make([]NodeID, 0, len(set))
+	for id := range set {
+		nodes = append(nodes, id)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+	return nodes
+}
+
+/*
+Nodes returns every node in the graph, in order.
+*/
+func (graph *GraphAutomaton) Nodes() []NodeID {
+	return append([]NodeID(nil), graph.nodes...)
+}
+
+/*
+Node returns the CellAut at node id, or nil if there isn't one.
+*/
+func (graph *GraphAutomaton) Node(id NodeID) CellAut {
+	return graph.cells[id]
+}
+
+/*
+Neighbors returns the nodes that share an edge with node id, in order.
+*/
+func (graph *GraphAutomaton) Neighbors(id NodeID) []NodeID {
+	set := make(map[NodeID]bool, len(graph.directions[id]))
+	for neighbor := range graph.directions[id] {
+		set[neighbor] = true
+	}
+	return sortedNodes(set)
+}
+
+/*
+Direction returns the NeighborIndex by which node from knows node to. ok is false if they don't
+share an edge.
+*/
+func (graph *GraphAutomaton) Direction(from, to NodeID) (i NeighborIndex, ok bool) {
+	i, ok = graph.directions[from][to]
+	return i, ok
+}
+
+/*
+Size returns the size of the graph as a World: one row of all its nodes.
+*/
+func (graph *GraphAutomaton) Size() (width, height int) {
+	return len(graph.nodes), 1
+}
+
+/*
+At returns the CellAut of the xth node in order, if y is 0, or nil if there isn't one.
+*/
+func (graph *GraphAutomaton) At(x, y int) CellAut {
+	if y != 0 || x < 0 || x >= len(graph.nodes) {
+		return nil
+	}
+	return graph.cells[graph.nodes[x]]
+}
+
+/*
+RingLattice returns the adjacency of n nodes in a ring, numbered 0 to n-1, each joined to the k
+nearest nodes on either side of it. It's where the Watts-Strogatz model starts from.
+*/
+func RingLattice(n, k int) map[NodeID][]NodeID {
+	adjacency := make(map[NodeID][]NodeID, n)
+	for i := 0; i < n; i++ {
+		adjacency[NodeID(i)] = nil
+		for j := 1; j <= k && j < n; j++ {
+			adjacency[NodeID(i)] = append(adjacency[NodeID(i)], NodeID((i+j)%n))
+		}
+	}
+	return adjacency
+}
+
+/*
+WattsStrogatz returns the adjacency of a small-world graph: a RingLattice(n, k) with each of its
+edges rewired, with probability p, to a node picked at random, avoiding edges that are already
+there. p = 0 leaves the lattice alone, and p = 1 makes a random graph.
+*/
+func WattsStrogatz(n, k int, p float64, rng *rand.Rand) map[NodeID][]NodeID {
+	edges := make(map[NodeID]map[NodeID]bool, n)
+	for i := 0; i < n; i++ {
+		edges[NodeID(i)] = make(map[NodeID]bool)
+	}
+	for i, neighbors := range RingLattice(n, k) {
+		for _, j := range neighbors {
+			edges[i][j], edges[j][i] = true, true
+		}
+	}
+	for j := 1; j <= k; j++ {
+		for i := 0; i < n; i++ {
+			a, b := NodeID(i), NodeID((i+j)%n)
+			// A node joined to everything already has nowhere to go
+			if !edges[a][b] || rng.Float64() >= p || len(edges[a]) >= n-1 {
+				continue
+			}
+			c := NodeID(rng.Intn(n))
+			for c == a || edges[a][c] {
+				c = NodeID(rng.Intn(n))
+			}
+			delete(edges[a], b)
+			delete(edges[b], a)
+			edges[a][c], edges[c][a] = true, true
+		}
+	}
+	return adjacencyOf(edges)
+}
+
+/*
+RandomGraph returns the adjacency of an Erdős–Rényi random graph on n nodes, numbered 0 to n-1, in
+which each pair of nodes is joined with probability p.
+*/
+func RandomGraph(n int, p float64, rng *rand.Rand) map[NodeID][]NodeID {
+	adjacency := make(map[NodeID][]NodeID, n)
+	for i := 0; i < n; i++ {
+		adjacency[NodeID(i)] = nil
+		for j := i + 1; j < n; j++ {
+			if rng.Float64() < p {
+				adjacency[NodeID(i)] = append(adjacency[NodeID(i)], NodeID(j))
+			}
+		}
+	}
+	return adjacency
+}
+
+func adjacencyOf(edges map[NodeID]map[NodeID]bool) map[NodeID][]NodeID {
+	adjacency := make(map[NodeID][]NodeID, len(edges))
+	for id, neighbors := range edges {
+		adjacency[id] = sortedNodes(neighbors)
+	}
+	return adjacency
+}
+
+/*
+Majority is a Rule for any number of neighbors, over any States: a cell takes on whichever State
+more of its neighbors are in than any other, and keeps its own on a tie. It's the usual first automaton to run on
+a graph, since it needs no sense of direction.
+*/
+func Majority(self State, neighbors map[NeighborIndex]State) State {
+	counts := make(map[State]int, len(neighbors))
+	best := 0
+	for _, state := range neighbors {
+		counts[state]++
+		best = maxInt(best, counts[state])
+	}
+	winner, winners := self, 0
+	for state, count := range counts {
+		if count == best {
+			winner = state
+			winners++
+		}
+	}
+	if winners != 1 {
+		return self
+	}
+	return winner
+}
@@ -0,0 +1,112 @@
+package cellaut
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGraphAutomaton(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A triangle with a tail, listed from one side only, with a loop that's ignored
+	adjacency := map[NodeID][]NodeID{1: {2, 3}, 2: {3}, 3: {3, 7}}
+	graph, err := NewGraphAutomaton(adjacency, func(id NodeID) CellAut {
+		return &wiringCellAut{GooCellAut: NewGooCellAut(int(id)), neighbors: make(map[NeighborIndex]CellAut)}
+	})
+	assert.Nil(err)
+	assert.Equal([]NodeID{1, 2, 3, 7}, graph.Nodes())
+	assert.Equal([]NodeID{1, 2, 7}, graph.Neighbors(3))
+	assert.Equal([]NodeID{3}, graph.Neighbors(7))
+	for _, id := range graph.Nodes() {
+		wired := graph.Node(id).(*wiringCellAut).neighbors
+		assert.Len(wired, len(graph.Neighbors(id)))
+		for _, neighbor := range graph.Neighbors(id) {
+			i, ok := graph.Direction(id, neighbor)
+			assert.True(ok)
+			assert.Equal(graph.Node(neighbor), wired[i])
+			back, _ := graph.Direction(neighbor, id)
+			assert.Equal(i.Recip(), back)
+		}
+	}
+	_, ok := graph.Direction(1, 7)
+	assert.False(ok)
+	width, height := graph.Size()
+	assert.Equal([2]int{4, 1}, [2]int{width, height})
+	assert.Equal(graph.Node(7), graph.At(3, 0))
+	assert.Nil(graph.At(4, 0))
+
+	// A hub can have at most 127 spokes
+	star := map[NodeID][]NodeID{0: nil}
+	for i := 1; i <= 127; i++ {
+		star[0] = append(star[0], NodeID(i))
+	}
+	_, err = NewRuleGraph(star, Majority, "-")
+	assert.Nil(err)
+	star[0] = append(star[0], 128)
+	_, err = NewRuleGraph(star, Majority, "-")
+	assert.NotNil(err)
+}
+
+func TestGraphAutomaton_Run(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// In a ring where each node sees 2 on either side, a run of 3 "X" holds out, and a lone one
+	// is voted down
+	for _, engine := range []EngineKind{ChannelEngine, MultiplexedEngine} {
+		graph, err := NewRuleGraph(RingLattice(12, 2), Majority, "-")
+		assert.Nil(err)
+		for _, id := range []NodeID{2, 3, 4, 9} {
+			graph.Node(id).SetState("X")
+		}
+		var last *StateGrid
+		sim := NewSimulation()
+		assert.Nil(sim.Configure(SimulationConfig{World: graph, Engine: engine, MaxTicks: 4}))
+		sim.Subscribe(func(event TickEvent) { last = TakeSnapshot(event.World, nil) })
+		assert.Nil(sim.Start())
+		assert.Nil(sim.Wait())
+		assert.Equal("--XXX-------\n", (&Pattern{StateGrid: last}).String(), engine)
+	}
+}
+
+func TestMajority(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal(State("X"), Majority("-", map[NeighborIndex]State{0: "X", 1: "X", 2: "-"}))
+	assert.Equal(State("-"), Majority("-", map[NeighborIndex]State{0: "X", 1: "-"}))
+	assert.Equal(State("-"), Majority("-", map[NeighborIndex]State{0: "X", 1: "O"}))
+	assert.Equal(State("O"), Majority("-", map[NeighborIndex]State{0: "X", 1: "O", 2: "O", 3: "-"}))
+	assert.Equal(State("-"), Majority("-", nil))
+}
+
+func TestGraphGenerators(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	edges := func(adjacency map[NodeID][]NodeID) int {
+		graph, err := NewGraphAutomaton(adjacency, func(id NodeID) CellAut { return NewGooCellAut(int(id)) })
+		assert.Nil(err)
+		n := 0
+		for _, id := range graph.Nodes() {
+			n += len(graph.Neighbors(id))
+		}
+		return n / 2
+	}
+	ring := RingLattice(20, 2)
+	assert.Equal(40, edges(ring))
+	assert.Equal([]NodeID{1, 2}, ring[0])
+	// WattsStrogatz lists both ends of every edge
+	lattice := WattsStrogatz(20, 2, 0, rand.New(rand.NewSource(1)))
+	assert.Equal([]NodeID{1, 2, 18, 19}, lattice[0])
+	assert.Equal(40, edges(lattice))
+	// Rewiring moves edges around, but doesn't add or remove any
+	rewired := WattsStrogatz(20, 2, 0.5, rand.New(rand.NewSource(1)))
+	assert.NotEqual(lattice, rewired)
+	assert.Equal(40, edges(rewired))
+	assert.Equal(20*19/2, edges(RandomGraph(20, 1, rand.New(rand.NewSource(1)))))
+	assert.Equal(0, edges(RandomGraph(20, 0, rand.New(rand.NewSource(1)))))
+}
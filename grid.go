@@ -0,0 +1,186 @@
+package cellaut
+
+import "fmt"
+
+/*
+Grid is a World of CellAuts laid out in a rectangle, each wired to the cells above, below, left and
+right of it. What happens at the edges depends on the Grid's Topology.
+*/
+type Grid struct {
+	width, height int
+	topology      Topology
+	diagonals     bool
+	hex           bool
+	// cells is in row order, bottom row first
+	cells []CellAut
+}
+
+/*
+Topology says which edges of a Grid wrap around to the opposite edge.
+*/
+type Topology int
+
+const (
+	// Plane doesn't wrap at all: cells on the edges just have fewer neighbors
+	Plane Topology = iota
+	// Torus wraps both ways, so a glider that goes off the left edge comes back on the right, and
+	// one that goes off the top comes back on the bottom
+	Torus
+	// Cylinder wraps the left and right edges to each other, but not the top and bottom
+	Cylinder
+)
+
+/*
+GridOption changes how NewGrid builds a Grid.
+*/
+type GridOption func(*Grid)
+
+/*
+WithTopology makes NewGrid wrap the Grid's edges according to topology. The default is Plane.
+*/
+func WithTopology(topology Topology) GridOption {
+	return func(grid *Grid) {
+		grid.topology = topology
+	}
+}
+
+/*
+WithDiagonals makes NewGrid wire each cell to its 4 diagonal neighbors too, for automata that need
+the whole Moore neighborhood, like Life.
+*/
+func WithDiagonals() GridOption {
+	return func(grid *Grid) {
+		grid.diagonals = true
+	}
+}
+
+/*
+NewGrid builds a width×height Grid, calling factory for the CellAut at each (x, y), and wires every
+pair of adjacent cells to each other.
+
+A Grid that wraps in a direction it's only 1 cell across doesn't wire cells to themselves.
+*/
+func NewGrid(width, height int, factory func(x, y int) CellAut, options ...GridOption) *Grid {
+	grid := &Grid{width: width, height: height, cells: make([]CellAut, width*height)}
+	for _, option := range options {
+		option(grid)
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			grid.cells[y*width+x] = factory(x, y)
+		}
+	}
+	// Each pair of neighbors gets wired once, from the one that's below or to the left
+	var directions []NeighborIndex
+	for _, i := range grid.directions() {
+		if i < i.Recip() {
+			directions = append(directions, i)
+		}
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			aut := grid.At(x, y)
+			for _, i := range directions {
+				dx, dy := neighborOffset(i)
+				neighbor, ok := grid.wrapped(x+dx, y+dy)
+				if !ok {
+					continue
+				}
+				aut.AddNeighbor(i, neighbor)
+				neighbor.AddNeighbor(i.Recip(), aut)
+			}
+		}
+	}
+	return grid
+}
+
+/*
+directions returns the directions each cell in the Grid has a neighbor in, away from the edges.
+*/
+func (grid *Grid) directions() []NeighborIndex {
+	if grid.hex {
+		return []NeighborIndex{NeighborHexNE, NeighborHexE, NeighborHexSE, NeighborHexSW, NeighborHexW, NeighborHexNW}
+	}
+	directions := []NeighborIndex{NeighborUp, NeighborRt, NeighborDn, NeighborLf}
+	if grid.diagonals {
+		directions = append(directions, NeighborUpRt, NeighborUpLf, NeighborDnRt, NeighborDnLf)
+	}
+	return directions
+}
+
+/*
+wrapped returns the CellAut at (x, y), after wrapping those coordinates around whichever edges the
+Grid's Topology joins up. ok is false if (x, y) is off an edge that doesn't wrap, or the Grid is too
+narrow for wrapping to lead anywhere new.
+*/
+func (grid *Grid) wrapped(x, y int) (aut CellAut, ok bool) {
+	if (grid.topology == Torus || grid.topology == Cylinder) && grid.width > 1 {
+		x = (x + grid.width) % grid.width
+	}
+	if grid.topology == Torus && grid.height > 1 {
+		y = (y + grid.height) % grid.height
+	}
+	aut = grid.At(x, y)
+	return aut, aut != nil
+}
+
+/*
+Topology returns the Grid's Topology.
+*/
+func (grid *Grid) Topology() Topology {
+	return grid.topology
+}
+
+/*
+Size returns the width and height of the Grid.
+*/
+func (grid *Grid) Size() (width, height int) {
+	return grid.width, grid.height
+}
+
+/*
+At returns the CellAut at (x, y), or nil if that's off the Grid.
+*/
+func (grid *Grid) At(x, y int) CellAut {
+	if !(Rect{Width: grid.width, Height: grid.height}).Contains(x, y) {
+		return nil
+	}
+	return grid.cells[y*grid.width+x]
+}
+
+/*
+Place stamps pattern onto the Grid with its bottom left cell at (x, y), setting the State of each
+cell under one of the pattern's non-empty cells. The rest are left alone, so a glider can be put
+down next to something without wiping it out.
+
+The pattern wraps around whichever edges the Grid's Topology joins up. If any of it would still be
+off the Grid, nothing is placed and an error is returned.
+*/
+func (grid *Grid) Place(pattern *Pattern, x, y int) error {
+	auts := make(map[CellAut]State)
+	for py := 0; py < pattern.Height; py++ {
+		for px := 0; px < pattern.Width; px++ {
+			if pattern.IDAt(px, py) == 0 {
+				continue
+			}
+			aut, ok := grid.wrapped(x+px, y+py)
+			if !ok {
+				return fmt.Errorf("a %dx%d pattern at (%d, %d) doesn't fit on the %dx%d grid",
+					pattern.Width, pattern.Height, x, y, grid.width, grid.height)
+			}
+			auts[aut] = pattern.At(px, py)
+		}
+	}
+	for aut, state := range auts {
+		aut.SetState(state)
+	}
+	return nil
+}
+
+/*
+Cells returns every CellAut in the Grid, in row order starting from the bottom, ready to be started
+on a Ticker.
+*/
+func (grid *Grid) Cells() []CellAut {
+	return append([]CellAut(nil), grid.cells...)
+}
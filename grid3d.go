@@ -0,0 +1,248 @@
+package cellaut
+
+import (
+	"fmt"
+	"image"
+	"sort"
+)
+
+// neighbors3D and offsets3D map between each of the 26 cells around a cell in 3D and its
+// NeighborIndex. The 8 in the cell's own slice are the usual ones. The 8 diagonal ones in the slice
+// in front get the indices after NeighborFwd, and the ones behind are their opposites, so that
+// Recip works for all of them.
+var neighbors3D, offsets3D = func() (map[[3]int]NeighborIndex, map[NeighborIndex][3]int) {
+	neighbors := make(map[[3]int]NeighborIndex)
+	for _, i := range []NeighborIndex{NeighborUp, NeighborRt, NeighborDn, NeighborLf, NeighborUpRt, NeighborUpLf, NeighborDnRt, NeighborDnLf} {
+		dx, dy := neighborOffset(i)
+		neighbors[[3]int{dx, dy, 0}] = i
+	}
+	neighbors[[3]int{0, 0, 1}] = NeighborFwd
+	neighbors[[3]int{0, 0, -1}] = NeighborBk
+	next := NeighborFwd + 1
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if dx == 0 && dy == 0 {
+				continue
+			}
+			neighbors[[3]int{dx, dy, 1}] = next
+			neighbors[[3]int{-dx, -dy, -1}] = next.Recip()
+			next++
+		}
+	}
+	offsets := make(map[NeighborIndex][3]int, len(neighbors))
+	for offset, i := range neighbors {
+		offsets[i] = offset
+	}
+	return neighbors, offsets
+}()
+
+/*
+Neighbor3D returns the NeighborIndex of the cell at (x+dx, y+dy, z+dz) from the one at (x, y, z) in
+a Grid3D. dx, dy and dz must each be -1, 0 or 1, and they can't all be 0. Neighbor3D(0, 1, 0) is
+NeighborUp, Neighbor3D(0, 0, 1) is NeighborFwd, and so on.
+*/
+func Neighbor3D(dx, dy, dz int) NeighborIndex {
+	return neighbors3D[[3]int{dx, dy, dz}]
+}
+
+/*
+neighborOffset3D is neighborOffset in 3D. It returns all zeros for indices that aren't neighbors in
+a Grid3D.
+*/
+func neighborOffset3D(i NeighborIndex) (dx, dy, dz int) {
+	offset := offsets3D[i]
+	return offset[0], offset[1], offset[2]
+}
+
+/*
+Grid3D is a World of CellAuts laid out in a box: a stack of depth slices, each width×height, with
+NeighborFwd pointing to the next slice and NeighborBk to the one before. Each cell is wired to the 6
+cells whose faces it shares, or to all 26 around it with WithDiagonals, as 3D Life needs.
+
+As a World, it's its slices stacked on top of each other, slice 0 at the bottom, so that it can be
+run by a Simulation and snapshotted and recorded like any other. The synchronous engine can't run
+it, though.
+*/
+type Grid3D struct {
+	width, height, depth int
+	topology             Topology
+	// cells is in slice order, then row order, as in Grid
+	cells []CellAut
+}
+
+/*
+NewGrid3D builds a width×height×depth Grid3D, calling factory for the CellAut at each (x, y, z), and
+wires every pair of adjacent cells to each other. WithTopology(Torus) wraps all three ways, and
+Cylinder only wraps left and right.
+*/
+func NewGrid3D(width, height, depth int, factory func(x, y, z int) CellAut, options ...GridOption) *Grid3D {
+	// The options are written for Grid, so they're applied to one and read back
+	var settings Grid
+	for _, option := range options {
+		option(&settings)
+	}
+	grid := &Grid3D{width: width, height: height, depth: depth, topology: settings.topology, cells: make([]CellAut, width*height*depth)}
+	for z := 0; z < depth; z++ {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				grid.cells[(z*height+y)*width+x] = factory(x, y, z)
+			}
+		}
+	}
+
+	var directions []NeighborIndex
+	for offset, i := range neighbors3D {
+		faces := offset[0]*offset[0] + offset[1]*offset[1] + offset[2]*offset[2]
+		if (settings.diagonals || faces == 1) && i < i.Recip() {
+			directions = append(directions, i)
+		}
+	}
+	sort.Slice(directions, func(a, b int) bool { return directions[a] < directions[b] })
+	for z := 0; z < depth; z++ {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				aut := grid.At3D(x, y, z)
+				for _, i := range directions {
+					dx, dy, dz := neighborOffset3D(i)
+					neighbor, ok := grid.wrapped(x+dx, y+dy, z+dz)
+					if !ok {
+						continue
+					}
+					aut.AddNeighbor(i, neighbor)
+					neighbor.AddNeighbor(i.Recip(), aut)
+				}
+			}
+		}
+	}
+	return grid
+}
+
+/*
+NewRuleGrid3D builds a width×height×depth Grid3D of RuleCellAuts that follow rule, all starting in
+state. Rules that need all 26 neighbors should be given WithDiagonals.
+*/
+func NewRuleGrid3D(width, height, depth int, rule Rule, state State, options ...GridOption) *Grid3D {
+	return NewGrid3D(width, height, depth, func(x, y, z int) CellAut {
+		return NewRuleCellAut((z*height+y)*width+x, rule, state)
+	}, options...)
+}
+
+/*
+wrapped returns the CellAut at (x, y, z), after wrapping the coordinates as Grid.wrapped does.
+*/
+func (grid *Grid3D) wrapped(x, y, z int) (aut CellAut, ok bool) {
+	if (grid.topology == Torus || grid.topology == Cylinder) && grid.width > 1 {
+		x = (x + grid.width) % grid.width
+	}
+	if grid.topology == Torus && grid.height > 1 {
+		y = (y + grid.height) % grid.height
+	}
+	if grid.topology == Torus && grid.depth > 1 {
+		z = (z + grid.depth) % grid.depth
+	}
+	aut = grid.At3D(x, y, z)
+	return aut, aut != nil
+}
+
+/*
+Dimensions returns the width, height and depth of the Grid3D.
+*/
+func (grid *Grid3D) Dimensions() (width, height, depth int) {
+	return grid.width, grid.height, grid.depth
+}
+
+/*
+Size returns the size of the Grid3D as a World, with its slices stacked up.
+*/
+func (grid *Grid3D) Size() (width, height int) {
+	return grid.width, grid.height * grid.depth
+}
+
+/*
+At returns the CellAut at (x, y) of the Grid3D as a World, which is (x, y % height) of slice
+y / height. It returns nil if that's outside the Grid3D.
+*/
+func (grid *Grid3D) At(x, y int) CellAut {
+	if y < 0 || grid.height == 0 {
+		return nil
+	}
+	return grid.At3D(x, y%grid.height, y/grid.height)
+}
+
+/*
+At3D returns the CellAut at (x, y, z), or nil if that's outside the Grid3D.
+*/
+func (grid *Grid3D) At3D(x, y, z int) CellAut {
+	if x < 0 || y < 0 || z < 0 || x >= grid.width || y >= grid.height || z >= grid.depth {
+		return nil
+	}
+	return grid.cells[(z*grid.height+y)*grid.width+x]
+}
+
+/*
+Slice returns slice z of the Grid3D as a World of its own, for taking a snapshot of or setting
+the states of.
+*/
+func (grid *Grid3D) Slice(z int) World {
+	return gridSlice{grid: grid, z: z}
+}
+
+type gridSlice struct {
+	grid *Grid3D
+	z    int
+}
+
+func (slice gridSlice) Size() (width, height int) {
+	return slice.grid.width, slice.grid.height
+}
+
+func (slice gridSlice) At(x, y int) CellAut {
+	return slice.grid.At3D(x, y, slice.z)
+}
+
+/*
+RenderSlices draws every slice of grid side by side, cellSize pixels to a cell, each labelled with
+its z, the way 3D automata are usually looked at. Like TakeSnapshot, it must be called between
+ticks. It returns an error if palette has too many States to draw.
+*/
+func RenderSlices(grid *Grid3D, palette Palette, cellSize int) (*image.Paletted, error) {
+	montage := NewMontage(palette, cellSize)
+	montage.LabelScale, montage.Columns = 1, grid.depth
+	for z := 0; z < grid.depth; z++ {
+		montage.Add(fmt.Sprintf("z=%d", z), TakeSnapshot(grid.Slice(z), nil))
+	}
+	return montage.Image()
+}
+
+/*
+NewLife3D returns a Life-like Rule for a Grid3D built WithDiagonals, over the states "-" and "X":
+a dead cell with a number of live neighbors in birth comes to life, and a live one with a number in
+survival lives on. Bays' Life 4555, for example, is NewLife3D([]int{5}, []int{4, 5}).
+*/
+func NewLife3D(birth, survival []int) Rule {
+	var table [2][27]State
+	for i := range table {
+		for n := range table[i] {
+			table[i][n] = LifeDead
+		}
+	}
+	for i, counts := range [][]int{birth, survival} {
+		for _, n := range counts {
+			if n >= 0 && n < len(table[i]) {
+				table[i][n] = LifeAlive
+			}
+		}
+	}
+	return func(self State, neighbors map[NeighborIndex]State) State {
+		n := 0
+		for _, state := range neighbors {
+			if state == LifeAlive {
+				n++
+			}
+		}
+		if self == LifeAlive {
+			return table[1][n]
+		}
+		return table[0][n]
+	}
+}
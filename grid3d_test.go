@@ -0,0 +1,103 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNeighbor3D(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	seen := make(map[NeighborIndex]bool)
+	for dz := -1; dz <= 1; dz++ {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if dx == 0 && dy == 0 && dz == 0 {
+					continue
+				}
+				i := Neighbor3D(dx, dy, dz)
+				assert.False(seen[i], "(%d, %d, %d)", dx, dy, dz)
+				seen[i] = true
+				assert.Equal(Neighbor3D(-dx, -dy, -dz), i.Recip())
+				x, y, z := neighborOffset3D(i)
+				assert.Equal([3]int{dx, dy, dz}, [3]int{x, y, z})
+			}
+		}
+	}
+	assert.Equal(NeighborUpLf, Neighbor3D(-1, 1, 0))
+	assert.Equal(NeighborFwd, Neighbor3D(0, 0, 1))
+	assert.Equal(NeighborBk, NeighborFwd.Recip())
+}
+
+func TestNewGrid3D(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	build := func(options ...GridOption) *Grid3D {
+		return NewGrid3D(3, 3, 3, func(x, y, z int) CellAut {
+			return &wiringCellAut{GooCellAut: NewGooCellAut((z*3+y)*3 + x), neighbors: make(map[NeighborIndex]CellAut)}
+		}, options...)
+	}
+	neighbors := func(grid *Grid3D, x, y, z int) map[NeighborIndex]CellAut {
+		return grid.At3D(x, y, z).(*wiringCellAut).neighbors
+	}
+
+	grid := build()
+	assert.Len(neighbors(grid, 1, 1, 1), 6)
+	assert.Equal(grid.At3D(1, 1, 2), neighbors(grid, 1, 1, 1)[NeighborFwd])
+	assert.Equal(grid.At3D(1, 1, 0), neighbors(grid, 1, 1, 1)[NeighborBk])
+	assert.Len(neighbors(grid, 0, 0, 0), 3)
+
+	moore := build(WithDiagonals())
+	assert.Len(neighbors(moore, 1, 1, 1), 26)
+	assert.Len(neighbors(moore, 0, 0, 0), 7)
+	assert.Equal(moore.At3D(0, 2, 0), neighbors(moore, 1, 1, 1)[Neighbor3D(-1, 1, -1)])
+
+	torus := build(WithDiagonals(), WithTopology(Torus))
+	assert.Len(neighbors(torus, 0, 0, 0), 26)
+	assert.Equal(torus.At3D(2, 2, 2), neighbors(torus, 0, 0, 0)[Neighbor3D(-1, -1, -1)])
+
+	// As a World, the slices are stacked up
+	width, height := grid.Size()
+	assert.Equal([2]int{3, 9}, [2]int{width, height})
+	assert.Equal(grid.At3D(2, 1, 2), grid.At(2, 7))
+	assert.Equal(grid.At3D(2, 1, 2), grid.Slice(2).At(2, 1))
+	assert.Nil(grid.At(0, 9))
+}
+
+func TestGrid3D_Run(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Anything next to a live cell comes to life, so after two ticks there's an octahedron of
+	// 1+6+18 cells, under either engine that can run a Grid3D
+	spread := NewLife3D([]int{1, 2, 3, 4, 5, 6}, []int{0, 1, 2, 3, 4, 5, 6})
+	for _, engine := range []EngineKind{ChannelEngine, MultiplexedEngine} {
+		grid := NewRuleGrid3D(5, 5, 5, spread, LifeDead)
+		grid.At3D(2, 2, 2).SetState(LifeAlive)
+		sim := NewSimulation()
+		assert.Nil(sim.Configure(SimulationConfig{World: grid, Engine: engine, MaxTicks: 3}))
+		assert.Nil(sim.Start())
+		assert.Nil(sim.Wait())
+		alive := 0
+		for z := 0; z < 5; z++ {
+			for y := 0; y < 5; y++ {
+				for x := 0; x < 5; x++ {
+					distance := (x-2)*(x-2) + (y-2)*(y-2) + (z-2)*(z-2)
+					assert.Equal(distance <= 2 || distance == 4, grid.At3D(x, y, z).GetState() == LifeAlive, "%s (%d, %d, %d)", engine, x, y, z)
+					if grid.At3D(x, y, z).GetState() == LifeAlive {
+						alive++
+					}
+				}
+			}
+		}
+		assert.Equal(25, alive, engine)
+	}
+
+	// Three 5x5 slices side by side
+	img, err := RenderSlices(NewRuleGrid3D(5, 5, 3, spread, LifeDead), LifePalette, 2)
+	assert.Nil(err)
+	assert.Equal(3*(10+4)+4, img.Bounds().Dx())
+}
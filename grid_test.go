@@ -0,0 +1,192 @@
+package cellaut
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+wiringCellAut is a GooCellAut that remembers who it was told its neighbors are.
+*/
+type wiringCellAut struct {
+	*GooCellAut
+	neighbors map[NeighborIndex]CellAut
+}
+
+func (aut *wiringCellAut) AddNeighbor(i NeighborIndex, neighbor CellAut) {
+	aut.neighbors[i] = neighbor
+	aut.GooCellAut.AddNeighbor(i, neighbor)
+}
+
+func TestNewGrid(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	grid := NewGrid(3, 2, func(x, y int) CellAut {
+		return &wiringCellAut{GooCellAut: NewGooCellAut(y*3 + x), neighbors: make(map[NeighborIndex]CellAut)}
+	})
+	width, height := grid.Size()
+	assert.Equal(3, width)
+	assert.Equal(2, height)
+	assert.Len(grid.Cells(), 6)
+	assert.Nil(grid.At(3, 0))
+	assert.Nil(grid.At(0, -1))
+
+	// Every cell knows about everything next to it, and nothing else
+	neighbors := func(x, y int) map[NeighborIndex]CellAut {
+		return grid.At(x, y).(*wiringCellAut).neighbors
+	}
+	assert.Equal(map[NeighborIndex]CellAut{NeighborRt: grid.At(1, 0), NeighborUp: grid.At(0, 1)}, neighbors(0, 0))
+	assert.Equal(map[NeighborIndex]CellAut{
+		NeighborLf: grid.At(0, 1),
+		NeighborRt: grid.At(2, 1),
+		NeighborDn: grid.At(1, 0),
+	}, neighbors(1, 1))
+
+	// And goo gets everywhere
+	grid.At(0, 0).SetState("X")
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 4}))
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	for _, aut := range grid.Cells() {
+		assert.Equal(State("X"), aut.GetState())
+	}
+}
+
+func TestNewGridTopology(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	build := func(width, height int, topology Topology) *Grid {
+		return NewGrid(width, height, func(x, y int) CellAut {
+			return &wiringCellAut{GooCellAut: NewGooCellAut(y*width + x), neighbors: make(map[NeighborIndex]CellAut)}
+		}, WithTopology(topology))
+	}
+	neighbors := func(grid *Grid, x, y int) map[NeighborIndex]CellAut {
+		return grid.At(x, y).(*wiringCellAut).neighbors
+	}
+
+	assert.Equal(Plane, NewGrid(1, 1, func(x, y int) CellAut { return NewGooCellAut(0) }).Topology())
+
+	torus := build(3, 3, Torus)
+	assert.Equal(Torus, torus.Topology())
+	assert.Equal(map[NeighborIndex]CellAut{
+		NeighborUp: torus.At(0, 1),
+		NeighborRt: torus.At(1, 0),
+		NeighborDn: torus.At(0, 2),
+		NeighborLf: torus.At(2, 0),
+	}, neighbors(torus, 0, 0))
+	assert.Equal(map[NeighborIndex]CellAut{
+		NeighborUp: torus.At(2, 0),
+		NeighborRt: torus.At(0, 2),
+		NeighborDn: torus.At(2, 1),
+		NeighborLf: torus.At(1, 2),
+	}, neighbors(torus, 2, 2))
+
+	cylinder := build(3, 3, Cylinder)
+	assert.Equal(map[NeighborIndex]CellAut{
+		NeighborUp: cylinder.At(0, 1),
+		NeighborRt: cylinder.At(1, 0),
+		NeighborLf: cylinder.At(2, 0),
+	}, neighbors(cylinder, 0, 0))
+
+	// Nothing is its own neighbor
+	thin := build(1, 3, Torus)
+	assert.Equal(map[NeighborIndex]CellAut{NeighborUp: thin.At(0, 1), NeighborDn: thin.At(0, 2)}, neighbors(thin, 0, 0))
+
+	// Goo that starts on the left edge of a cylinder gets to the right edge as soon as it gets one cell to the right
+	grid := build(5, 1, Cylinder)
+	grid.At(0, 0).SetState("X")
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 2}))
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	assert.Equal(State("X"), grid.At(4, 0).GetState())
+	assert.Equal(State("X"), grid.At(1, 0).GetState())
+	assert.NotEqual(State("X"), grid.At(2, 0).GetState())
+}
+
+func TestNewGridDiagonals(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	build := func(topology Topology) *Grid {
+		return NewGrid(3, 3, func(x, y int) CellAut {
+			return &wiringCellAut{GooCellAut: NewGooCellAut(y*3 + x), neighbors: make(map[NeighborIndex]CellAut)}
+		}, WithDiagonals(), WithTopology(topology))
+	}
+	neighbors := func(grid *Grid, x, y int) map[NeighborIndex]CellAut {
+		return grid.At(x, y).(*wiringCellAut).neighbors
+	}
+
+	grid := build(Plane)
+	assert.Len(neighbors(grid, 1, 1), 8)
+	for i, neighbor := range neighbors(grid, 1, 1) {
+		dx, dy := neighborOffset(i)
+		assert.Equal(grid.At(1+dx, 1+dy), neighbor)
+	}
+	assert.Equal(map[NeighborIndex]CellAut{
+		NeighborUp:   grid.At(0, 1),
+		NeighborRt:   grid.At(1, 0),
+		NeighborUpRt: grid.At(1, 1),
+	}, neighbors(grid, 0, 0))
+
+	torus := build(Torus)
+	assert.Len(neighbors(torus, 0, 0), 8)
+	assert.Equal(torus.At(2, 2), neighbors(torus, 0, 0)[NeighborDnLf])
+	assert.Equal(torus.At(1, 2), neighbors(torus, 0, 0)[NeighborDnRt])
+
+	// Goo spreads diagonally
+	grid.At(0, 0).SetState("X")
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 2}))
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	assert.Equal(State("X"), grid.At(1, 1).GetState())
+	assert.NotEqual(State("X"), grid.At(2, 2).GetState())
+}
+
+func TestGrid_Place(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	glider, err := LoadRLE(strings.NewReader("x = 3, y = 3\nbo$2bo$3o!"))
+	assert.Nil(err)
+	// A glider in the corner of a torus wraps around, and leaves what's under its dead cells alone
+	grid := NewLifeGrid(6, 6, WithTopology(Torus))
+	grid.At(5, 5).SetState(LifeAlive)
+	assert.Nil(grid.Place(glider, 4, 4))
+	// On a plane it doesn't fit, so none of it is placed
+	plane := NewLifeGrid(6, 6)
+	assert.NotNil(plane.Place(glider, 4, 4))
+	assert.Nil(plane.Place(glider, 0, 0))
+
+	var placed, unplaced *StateGrid
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 1}))
+	sim.Subscribe(func(event TickEvent) { placed = TakeSnapshot(event.World, nil) })
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	sim = NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: plane, MaxTicks: 1}))
+	sim.Subscribe(func(event TickEvent) { unplaced = TakeSnapshot(event.World, nil) })
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+
+	alive := func(grid *StateGrid) map[[2]int]bool {
+		cells := make(map[[2]int]bool)
+		for y := 0; y < grid.Height; y++ {
+			for x := 0; x < grid.Width; x++ {
+				if grid.At(x, y) == LifeAlive {
+					cells[[2]int{x, y}] = true
+				}
+			}
+		}
+		return cells
+	}
+	assert.Equal(map[[2]int]bool{{5, 0}: true, {0, 5}: true, {4, 4}: true, {5, 4}: true, {0, 4}: true, {5, 5}: true}, alive(placed))
+	assert.Equal(map[[2]int]bool{{1, 2}: true, {2, 1}: true, {0, 0}: true, {1, 0}: true, {2, 0}: true}, alive(unplaced))
+}
@@ -0,0 +1,144 @@
+package cellaut
+
+import (
+	"fmt"
+	"sort"
+)
+
+/*
+CellGroups gives names to sets of cells, by their (x, y), so that a scenario can say things like
+"the walls never change" or "the sources are all on" without listing cells every time. A cell can be
+in any number of groups.
+
+A StateGrid carries its CellGroups along in its JSON, so they're saved with snapshots and
+archives.
+*/
+type CellGroups map[string][][2]int
+
+/*
+Add puts cells into the named group, creating it if it doesn't exist.
+*/
+func (groups CellGroups) Add(name string, cells ...[2]int) {
+	groups[name] = append(groups[name], cells...)
+}
+
+/*
+AddRect puts every cell in rect into the named group.
+*/
+func (groups CellGroups) AddRect(name string, rect Rect) {
+	for y := rect.Y; y < rect.Y+rect.Height; y++ {
+		for x := rect.X; x < rect.X+rect.Width; x++ {
+			groups.Add(name, [2]int{x, y})
+		}
+	}
+}
+
+/*
+Names returns the names of the groups, sorted.
+*/
+func (groups CellGroups) Names() []string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*
+Check returns an error if any of names isn't a group.
+*/
+func (groups CellGroups) Check(names ...string) error {
+	for _, name := range names {
+		if _, ok := groups[name]; !ok {
+			return fmt.Errorf("no cell group named '%s'", name)
+		}
+	}
+	return nil
+}
+
+/*
+Translate returns a copy of the groups with every cell moved by (dx, dy), for when the grid they
+belong to has been moved or grown.
+*/
+func (groups CellGroups) Translate(dx, dy int) CellGroups {
+	if groups == nil {
+		return nil
+	}
+	moved := make(CellGroups, len(groups))
+	for name, cells := range groups {
+		moved[name] = make([][2]int, len(cells))
+		for i, cell := range cells {
+			moved[name][i] = [2]int{cell[0] + dx, cell[1] + dy}
+		}
+	}
+	return moved
+}
+
+/*
+each calls fn with every cell in the named groups that's inside a width×height grid.
+*/
+func (groups CellGroups) each(width, height int, names []string, fn func(x, y int)) {
+	bounds := Rect{Width: width, Height: height}
+	for _, name := range names {
+		for _, cell := range groups[name] {
+			if bounds.Contains(cell[0], cell[1]) {
+				fn(cell[0], cell[1])
+			}
+		}
+	}
+}
+
+/*
+freezable returns whether every cell of world in the named groups is a FreezableCellAut.
+*/
+func (groups CellGroups) freezable(world World, names []string) bool {
+	width, height := world.Size()
+	ok := true
+	groups.each(width, height, names, func(x, y int) {
+		if _, freezable := world.At(x, y).(FreezableCellAut); !freezable {
+			ok = false
+		}
+	})
+	return ok
+}
+
+/*
+Fill sets every cell in the named groups of grid to state.
+*/
+func (groups CellGroups) Fill(grid *StateGrid, state State, names ...string) {
+	groups.each(grid.Width, grid.Height, names, func(x, y int) { grid.Set(x, y, state) })
+}
+
+/*
+SetStates calls SetState(state) on every cell in the named groups of world, so they all change at
+the next tick.
+*/
+func (groups CellGroups) SetStates(world World, state State, names ...string) {
+	width, height := world.Size()
+	groups.each(width, height, names, func(x, y int) { world.At(x, y).SetState(state) })
+}
+
+/*
+Mask returns a copy of grid with the cells in the named groups emptied, so that statistics like
+Population and Clusters leave them out.
+*/
+func (groups CellGroups) Mask(grid *StateGrid, names ...string) *StateGrid {
+	masked := cloneStateGrid(grid)
+	groups.each(grid.Width, grid.Height, names, func(x, y int) { masked.SetID(x, y, 0) })
+	return masked
+}
+
+/*
+Freeze wraps step so that the cells in the named groups keep whatever State they had before each
+step, whatever the rule says. Their neighbors still see them, so a frozen group makes a fixed wall
+or a source that never runs dry.
+*/
+func (groups CellGroups) Freeze(step func(*StateGrid) *StateGrid, names ...string) func(*StateGrid) *StateGrid {
+	return func(grid *StateGrid) *StateGrid {
+		next := step(grid)
+		groups.each(grid.Width, grid.Height, names, func(x, y int) { next.Set(x, y, grid.At(x, y)) })
+		next.Groups = grid.Groups
+		return next
+	}
+}
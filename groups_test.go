@@ -0,0 +1,131 @@
+package cellaut
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCellGroups(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	groups := make(CellGroups)
+	groups.AddRect("wall", Rect{X: 0, Y: 0, Width: 3, Height: 1})
+	groups.Add("source", [2]int{4, 4}, [2]int{9, 9})
+	assert.Equal([]string{"source", "wall"}, groups.Names())
+	assert.Nil(groups.Check("wall", "source"))
+	assert.NotNil(groups.Check("wall", "nope"))
+	assert.Equal([][2]int{{5, 6}, {10, 11}}, groups.Translate(1, 2)["source"])
+
+	// Cells off the grid are left alone
+	grid := NewStateGrid(5, 5, NewStateTable("-"))
+	groups.Fill(grid, "X", "wall", "source")
+	assert.Equal(int64(4), grid.Population("X"))
+	assert.Equal(int64(1), groups.Mask(grid, "wall").Population("X"))
+	assert.Equal(int64(4), grid.Population("X"))
+
+	// Groups go along with the grid's JSON
+	grid.Groups = groups
+	data, err := json.Marshal(grid)
+	assert.Nil(err)
+	var decoded StateGrid
+	assert.Nil(json.Unmarshal(data, &decoded))
+	assert.Equal(groups, decoded.Groups)
+}
+
+func TestCellGroups_Freeze(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A blinker with its cells frozen can't turn, so it keeps giving birth above and below
+	groups := make(CellGroups)
+	groups.AddRect("wall", Rect{X: 1, Y: 3, Width: 3, Height: 1})
+	grid := NewStateGrid(5, 7, NewStateTable("-"))
+	grid.Groups = groups
+	groups.Fill(grid, LifeAlive, "wall")
+	step := groups.Freeze(func(grid *StateGrid) *StateGrid { return stepGrid(grid, Life, mooreNeighborhood) }, "wall")
+	grid = step(grid)
+	assert.Equal(
+		"-----\n"+
+			"-----\n"+
+			"--X--\n"+
+			"-XXX-\n"+
+			"--X--\n"+
+			"-----\n"+
+			"-----\n",
+		(&Pattern{StateGrid: grid}).String(),
+	)
+	assert.Equal(groups, grid.Groups)
+	grid = step(grid)
+	for x := 1; x <= 3; x++ {
+		assert.Equal(LifeAlive, grid.At(x, 3))
+	}
+}
+
+func TestSimulation_Frozen(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	groups := make(CellGroups)
+	groups.AddRect("wall", Rect{X: 2, Y: 4, Width: 3, Height: 1})
+	start := NewStateGrid(7, 9, NewStateTable(LifeDead))
+	groups.Fill(start, LifeAlive, "wall")
+	want := start
+	step := groups.Freeze(func(grid *StateGrid) *StateGrid { return stepGrid(grid, Life, mooreNeighborhood) }, "wall")
+	for tick := 0; tick < 4; tick++ {
+		want = step(want)
+	}
+
+	for _, engine := range []EngineKind{ChannelEngine, MultiplexedEngine, SynchronousEngine} {
+		grid := NewLifeGrid(7, 9)
+		groups.SetStates(grid, LifeAlive, "wall")
+		sim := NewSimulation()
+		assert.Nil(sim.Configure(SimulationConfig{World: grid, Engine: engine, MaxTicks: 5, Groups: groups, Frozen: []string{"wall"}}))
+		assert.Nil(sim.Start())
+		assert.Nil(sim.Wait())
+		got := TakeSnapshot(grid, NewStateTable(LifeDead))
+		assert.True(want.Equal(got), "engine %s:\n%s", engine, &Pattern{StateGrid: got})
+	}
+
+	sim := NewSimulation()
+	assert.NotNil(sim.Configure(SimulationConfig{World: NewLifeGrid(3, 3), Groups: groups, Frozen: []string{"nope"}}))
+	// Life cells freeze without sleeping, so waking their tiles up doesn't matter, but Goo cells
+	// can only sleep
+	roi := &RegionOfInterest{TileSize: 2, QuietTicks: 1}
+	assert.Nil(NewSimulation().Configure(SimulationConfig{World: NewLifeGrid(3, 3), Groups: groups, Frozen: []string{"wall"}, RegionOfInterest: roi}))
+	goo := make(CellGroups)
+	goo.Add("wall", [2]int{1, 0})
+	assert.NotNil(NewSimulation().Configure(SimulationConfig{World: newGooRow(3), Groups: goo, Frozen: []string{"wall"}, RegionOfInterest: roi}))
+}
+
+func TestExperiment_Frozen(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	initial := NewStateGrid(7, 7, NewStateTable("-"))
+	initial.Groups = make(CellGroups)
+	initial.Groups.AddRect("wall", Rect{X: 2, Y: 3, Width: 3, Height: 1})
+	initial.Groups.Fill(initial, LifeAlive, "wall")
+	exp := &Experiment{Rule: "B3/S23", Initial: initial, Tick: 6, Frozen: []string{"wall"}}
+	step, err := exp.Stepper()
+	assert.Nil(err)
+	exp.Checkpoint = initial
+	for tick := 0; tick < 6; tick++ {
+		exp.Checkpoint = step(exp.Checkpoint)
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(WriteArchive(&buf, exp))
+	read, err := ReadArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(err)
+	assert.Equal([]string{"wall"}, read.Frozen)
+	assert.Equal(initial.Groups, read.Initial.Groups)
+	assert.Nil(read.Verify())
+
+	// Without the freeze, the wall is just a blinker, which is back where it started after 6 ticks
+	read.Frozen = nil
+	assert.NotNil(read.Verify())
+}
@@ -0,0 +1,227 @@
+package cellaut
+
+import (
+	"hash/fnv"
+	"math/bits"
+)
+
+/*
+Snapshot hashes are polynomials mod the Mersenne prime 2^61-1: every non-empty cell at (x, y)
+contributes weight(state) * hashX^x * hashY^y, and the hash is the sum of them all.
+
+That makes the hash the same however the cells are stored, and lets a block's hash be worked out
+from its quadrants' hashes, so a QuadUniverse only has to hash each distinct node once.
+*/
+const (
+	hashPrime uint64 = 1<<61 - 1
+	hashX     uint64 = 0x1c3b5a7d9e2f4061
+	hashY     uint64 = 0x0a9e37b4c2d5f183
+)
+
+/*
+mulMod returns a*b mod hashPrime. a and b must be less than hashPrime.
+*/
+func mulMod(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	// 2^61 is 1 mod hashPrime, so fold the high bits back in
+	return reduceMod(hi<<3 | lo>>61 + lo&hashPrime)
+}
+
+func addMod(a, b uint64) uint64 {
+	return reduceMod(a + b)
+}
+
+func reduceMod(n uint64) uint64 {
+	n = n&hashPrime + n>>61
+	if n >= hashPrime {
+		n -= hashPrime
+	}
+	return n
+}
+
+/*
+powMod returns base^exp mod hashPrime. exp may be negative.
+*/
+func powMod(base uint64, exp int) uint64 {
+	// base^(hashPrime-1) is 1, so exponents only matter mod hashPrime-1
+	e := int64(exp) % int64(hashPrime-1)
+	if e < 0 {
+		e += int64(hashPrime - 1)
+	}
+	rslt := uint64(1)
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			rslt = mulMod(rslt, base)
+		}
+		base = mulMod(base, base)
+	}
+	return rslt
+}
+
+/*
+stateWeights returns the hash weight of every State in table, indexed by StateID. The empty state
+weighs nothing.
+*/
+func stateWeights(table *StateTable) []uint64 {
+	states := table.States()
+	weights := make([]uint64, len(states))
+	for id := 1; id < len(states); id++ {
+		h := fnv.New64a()
+		h.Write([]byte(states[id]))
+		weights[id] = reduceMod(h.Sum64())
+		if weights[id] == 0 {
+			weights[id] = 1
+		}
+	}
+	return weights
+}
+
+/*
+Hash returns a hash of the non-empty cells and where they are.
+*/
+func (grid *StateGrid) Hash() uint64 {
+	weights := stateWeights(grid.Table)
+	var sum uint64
+	rowPow := uint64(1)
+	for y := 0; y < grid.Height; y++ {
+		var row uint64
+		colPow := uint64(1)
+		for _, id := range grid.cells[y*grid.Width : (y+1)*grid.Width] {
+			if id != 0 {
+				row = addMod(row, mulMod(weights[id], colPow))
+			}
+			colPow = mulMod(colPow, hashX)
+		}
+		sum = addMod(sum, mulMod(row, rowPow))
+		rowPow = mulMod(rowPow, hashY)
+	}
+	return sum
+}
+
+/*
+Hash returns a hash of the non-empty cells and where they are, in the coordinates the Pattern is
+positioned at.
+*/
+func (pattern *Pattern) Hash() uint64 {
+	return mulMod(pattern.StateGrid.Hash(), mulMod(powMod(hashX, pattern.X), powMod(hashY, pattern.Y)))
+}
+
+/*
+Hash returns a hash of the non-empty cells and where they are. Node hashes are memoized, so
+hashing a universe after a few Sets only rehashes the nodes that changed.
+*/
+func (universe *QuadUniverse) Hash() uint64 {
+	min, _ := universe.Bounds()
+	weights := stateWeights(universe.Table)
+	return mulMod(universe.hash(universe.Root, weights), mulMod(powMod(hashX, min), powMod(hashY, min)))
+}
+
+/*
+hash returns the hash of node as if its bottom left cell were at (0, 0).
+*/
+func (universe *QuadUniverse) hash(node *QuadNode, weights []uint64) uint64 {
+	if node.Population == 0 {
+		return 0
+	}
+	if node.Level == 0 {
+		return weights[node.ID]
+	}
+	if h, ok := universe.hashes[node]; ok {
+		return h
+	}
+	half := 1 << (node.Level - 1)
+	right, up := powMod(hashX, half), powMod(hashY, half)
+	h := universe.hash(node.SW, weights)
+	h = addMod(h, mulMod(right, universe.hash(node.SE, weights)))
+	h = addMod(h, mulMod(up, universe.hash(node.NW, weights)))
+	h = addMod(h, mulMod(mulMod(right, up), universe.hash(node.NE, weights)))
+	if universe.hashes == nil {
+		universe.hashes = make(map[*QuadNode]uint64)
+	}
+	universe.hashes[node] = h
+	return h
+}
+
+/*
+Extent returns the smallest Rect holding every cell that isn't in the empty state.
+*/
+func (grid *StateGrid) Extent() (Rect, bool) {
+	return grid.boundingBox(func(id StateID) bool { return id != 0 })
+}
+
+/*
+Extent returns the smallest Rect holding every cell that isn't in the empty state, in the
+coordinates the Pattern is positioned at.
+*/
+func (pattern *Pattern) Extent() (Rect, bool) {
+	rect, ok := pattern.StateGrid.Extent()
+	if ok {
+		rect.X += pattern.X
+		rect.Y += pattern.Y
+	}
+	return rect, ok
+}
+
+/*
+Extent returns the smallest Rect holding every cell that isn't in the empty state.
+*/
+func (universe *QuadUniverse) Extent() (Rect, bool) {
+	return universe.boundingBox(func(node *QuadNode) int64 { return node.Population })
+}
+
+/*
+Equal returns whether other has the same States in the same places.
+*/
+func (grid *StateGrid) Equal(other Snapshot) bool {
+	return snapshotsEqual(grid, other)
+}
+
+/*
+Equal returns whether other has the same States in the same places.
+*/
+func (pattern *Pattern) Equal(other Snapshot) bool {
+	return snapshotsEqual(pattern, other)
+}
+
+/*
+Equal returns whether other has the same States in the same places.
+*/
+func (universe *QuadUniverse) Equal(other Snapshot) bool {
+	return snapshotsEqual(universe, other)
+}
+
+/*
+snapshotsEqual compares the hashes of a and b first, and only if they match, compares the cells
+within their Extents.
+*/
+func snapshotsEqual(a, b Snapshot) bool {
+	if a.Hash() != b.Hash() {
+		return false
+	}
+	rectA, okA := a.Extent()
+	rectB, okB := b.Extent()
+	if okA != okB || rectA != rectB {
+		return false
+	}
+	if !okA {
+		return true
+	}
+	cropA, cropB := a.Crop(rectA), b.Crop(rectB)
+	sameTable := cropA.Table == cropB.Table
+	for i, idA := range cropA.cells {
+		idB := cropB.cells[i]
+		switch {
+		case idA == 0 || idB == 0:
+			if idA != idB {
+				return false
+			}
+		case sameTable:
+			if idA != idB {
+				return false
+			}
+		case cropA.Table.State(idA) != cropB.Table.State(idB):
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMulMod(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal(uint64(0), mulMod(hashPrime-1, 0))
+	// (-1)(-1) = 1
+	assert.Equal(uint64(1), mulMod(hashPrime-1, hashPrime-1))
+	assert.Equal(uint64(1), mulMod(hashX, powMod(hashX, -1)))
+	assert.Equal(mulMod(hashY, mulMod(hashY, hashY)), powMod(hashY, 3))
+}
+
+func TestSnapshot_HashEqual(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	glider := mustParsePattern(t, `
+		.X.
+		..X
+		XXX
+	`)
+	glider.X, glider.Y = -2, 1
+
+	// The same glider in a bigger grid with a different StateTable
+	grid := NewStateGrid(6, 5, NewStateTable("-"))
+	for y := 0; y < glider.Height; y++ {
+		for x := 0; x < glider.Width; x++ {
+			if glider.IDAt(x, y) != 0 {
+				grid.Set(x+1, y+2, glider.At(x, y))
+			}
+		}
+	}
+	inGrid := &Pattern{X: -3, Y: -1, StateGrid: grid}
+
+	universe := NewQuadUniverse(glider.Table)
+	for y := 0; y < glider.Height; y++ {
+		for x := 0; x < glider.Width; x++ {
+			universe.SetID(glider.X+x, glider.Y+y, glider.IDAt(x, y))
+		}
+	}
+	// Growing the universe and emptying it again doesn't change anything
+	universe.Set(200, -300, "X")
+	universe.Set(200, -300, ".")
+
+	snapshots := []Snapshot{glider, inGrid, universe}
+	for _, a := range snapshots {
+		for _, b := range snapshots {
+			assert.Equal(a.Hash(), b.Hash())
+			assert.True(a.Equal(b))
+		}
+	}
+	rect, ok := universe.Extent()
+	assert.True(ok)
+	assert.Equal(Rect{X: -2, Y: 1, Width: 3, Height: 3}, rect)
+
+	moved := glider.Translate(1, 0)
+	assert.NotEqual(glider.Hash(), moved.Hash())
+	assert.False(moved.Equal(universe))
+	flipped := glider.FlipH()
+	assert.NotEqual(glider.Hash(), flipped.Hash())
+	assert.False(flipped.Equal(inGrid))
+	other := glider.Translate(0, 0)
+	other.Set(1, 2, "O")
+	assert.NotEqual(glider.Hash(), other.Hash())
+	assert.False(universe.Equal(other))
+
+	empty := NewQuadUniverse(nil)
+	assert.Equal(uint64(0), empty.Hash())
+	assert.True(empty.Equal(NewStateGrid(3, 3, nil)))
+	_, ok = empty.Extent()
+	assert.False(ok)
+}
+
+func BenchmarkQuadUniverse_Hash(b *testing.B) {
+	universe := NewQuadUniverse(NewStateTable("-"))
+	for i := 0; i < 1000; i++ {
+		universe.Set(i*7%256, i*13%256, "X")
+	}
+	universe.Hash()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Only the path down to the changed cell gets rehashed
+		universe.Set(i%256, 0, State([]string{"X", "-"}[i%2]))
+		universe.Hash()
+	}
+}
@@ -0,0 +1,199 @@
+package cellaut
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"text/tabwriter"
+)
+
+/*
+CellPhase is where a CellAut is in the current tick.
+*/
+type CellPhase string
+
+const (
+	// CellIdle means the CellAut has finished the last tick, and the next one hasn't started.
+	CellIdle CellPhase = "idle"
+	// CellWaitingForTick means the tick has been sent but the CellAut hasn't taken it yet.
+	CellWaitingForTick CellPhase = "waiting for tick"
+	// CellComputing means the CellAut has taken the tick and hasn't committed its state yet.
+	CellComputing CellPhase = "computing"
+	// CellAtBarrier means the CellAut has committed and is waiting for everyone else to.
+	CellAtBarrier CellPhase = "at commit barrier"
+	// CellSending means the CellAut is sending its new state to its neighbors. A CellAut that's
+	// stuck here is blocked on a send.
+	CellSending CellPhase = "sending"
+	// CellDead means the CellAut's goroutine has exited.
+	CellDead CellPhase = "dead"
+	// CellAsleep means the CellAut isn't getting ticks, because Ticker.SetAsleep put it to sleep.
+	CellAsleep CellPhase = "asleep"
+)
+
+/*
+CellHealth is a snapshot of what one CellAut is up to.
+*/
+type CellHealth struct {
+	Cell  string    `json:"cell"`
+	Alive bool      `json:"alive"`
+	Phase CellPhase `json:"phase"`
+	// LastAckedTick is the last tick the CellAut finished, or -1 if it hasn't finished any
+	LastAckedTick int64 `json:"last_acked_tick"`
+	// BlockedOnSend is true if the CellAut is in the middle of sending to its neighbors
+	BlockedOnSend bool `json:"blocked_on_send"`
+	Restarts      int  `json:"restarts"`
+}
+
+/*
+Health is a snapshot of what a Ticker and its CellAuts are up to.
+
+Only CellAuts launched with Ticker.Start are included.
+*/
+type Health struct {
+	// TickID is the ID of the current tick if Ticking is true, or of the next one otherwise
+	TickID  int64        `json:"tick_id"`
+	Ticking bool         `json:"ticking"`
+	Err     string       `json:"error,omitempty"`
+	Cells   []CellHealth `json:"cells"`
+}
+
+/*
+Health reports the state of the Ticker and of every CellAut launched with Start.
+
+It's safe to call from any goroutine, including while Tick is running, which is the point: if Tick
+never returns, Health shows which CellAuts haven't finished the tick and why.
+*/
+func (ticker *Ticker) Health() Health {
+	health := Health{
+		TickID:  atomic.LoadInt64(&ticker.tickID),
+		Ticking: atomic.LoadInt32(&ticker.ticking) == 1,
+	}
+	if err := ticker.Err(); err != nil {
+		health.Err = err.Error()
+	}
+	ticker.mu.Lock()
+	started := ticker.startedCallbacks()
+	ticker.mu.Unlock()
+	for i, callbacks := range started {
+		health.Cells = append(health.Cells, callbacks.health(i))
+	}
+	return health
+}
+
+func (callbacks *CellAutCallbacks) health(index int) CellHealth {
+	callbacks.mu.Lock()
+	defer callbacks.mu.Unlock()
+	cellHealth := CellHealth{
+		Cell:          fmt.Sprintf("%d:%s", index, cellName(callbacks.cell)),
+		Alive:         !callbacks.exited,
+		LastAckedTick: callbacks.lastAckedTick,
+		Restarts:      callbacks.restarts,
+	}
+	switch {
+	case callbacks.exited || callbacks.dead:
+		cellHealth.Phase = CellDead
+	case !callbacks.pending && callbacks.asleep:
+		cellHealth.Phase = CellAsleep
+	case !callbacks.pending:
+		cellHealth.Phase = CellIdle
+	case len(callbacks.tick) > 0:
+		cellHealth.Phase = CellWaitingForTick
+	case !callbacks.committed:
+		cellHealth.Phase = CellComputing
+	case callbacks.sending:
+		cellHealth.Phase = CellSending
+		cellHealth.BlockedOnSend = true
+	default:
+		cellHealth.Phase = CellAtBarrier
+	}
+	return cellHealth
+}
+
+/*
+Health reports the state of the Simulation's Ticker and cells. Before Start, it's empty.
+*/
+func (sim *Simulation) Health() Health {
+	sim.mu.Lock()
+	engine := sim.engine
+	sim.mu.Unlock()
+	if engine == nil {
+		return Health{}
+	}
+	return engine.Health()
+}
+
+/*
+WriteText writes the Health as a human-readable table.
+
+Cells that are idle or asleep are left out unless all is true, since when a simulation is stalled
+the interesting ones are the ones that aren't.
+*/
+func (health Health) WriteText(w io.Writer, all bool) error {
+	state := "idle"
+	if health.Ticking {
+		state = "ticking"
+	}
+	if health.Err != "" {
+		state = "halted: " + health.Err
+	}
+	if _, err := fmt.Fprintf(w, "tick %d (%s)\n", health.TickID, state); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CELL\tPHASE\tLAST ACKED\tRESTARTS")
+	for _, cell := range health.Cells {
+		if (cell.Phase == CellIdle || cell.Phase == CellAsleep) && !all {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", cell.Cell, cell.Phase, cell.LastAckedTick, cell.Restarts)
+	}
+	return tw.Flush()
+}
+
+/*
+HealthHandler serves the Simulation's Health over HTTP, as JSON, or as a text table if the request
+has `?format=text`. With `&all=1`, the text table includes idle and asleep cells.
+*/
+func HealthHandler(sim *Simulation) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		health := sim.Health()
+		if r.URL.Query().Get("format") == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			health.WriteText(w, r.URL.Query().Get("all") != "")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(health)
+	})
+}
+
+/*
+healthCommand implements `cellaut health <url>`, which fetches a Health from a HealthHandler and
+prints it as a table.
+*/
+func healthCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: cellaut health <url>")
+	}
+	resp, err := http.Get(args[0])
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, body)
+	}
+	var health Health
+	if err := json.Unmarshal(body, &health); err != nil {
+		return err
+	}
+	return health.WriteText(os.Stdout, true)
+}
@@ -0,0 +1,120 @@
+package cellaut
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+A CellAut with no neighbors that takes ticks but never finishes them after tick stallAt.
+*/
+type stallingCellAut struct {
+	*GooCellAut
+	stallAt int64
+	release chan struct{}
+}
+
+func (aut *stallingCellAut) Start(tick chan int64, done chan struct{}, stateLedger chan StateRecord, callbacks *CellAutCallbacks) {
+	for {
+		select {
+		case tickID := <-tick:
+			if tickID == aut.stallAt {
+				<-aut.release
+			}
+			callbacks.StateCommitted()
+			callbacks.AllStatesSent()
+		case <-done:
+			return
+		}
+	}
+}
+
+func TestTicker_Health(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	world := newGooRow(2)
+	ticker := &Ticker{}
+	for _, aut := range world.cells {
+		ticker.Start(aut, nil)
+	}
+	staller := &stallingCellAut{GooCellAut: NewGooCellAut(7), stallAt: 1, release: make(chan struct{})}
+	ticker.Start(staller, nil)
+
+	health := ticker.Health()
+	assert.Equal(int64(0), health.TickID)
+	assert.False(health.Ticking)
+	assert.Len(health.Cells, 3)
+	assert.Equal(CellHealth{Cell: "2:goo#7", Alive: true, Phase: CellIdle, LastAckedTick: -1}, health.Cells[2])
+
+	ticker.Tick()
+	assert.Equal(int64(0), ticker.Health().Cells[0].LastAckedTick)
+
+	ticked := make(chan struct{})
+	go func() {
+		ticker.Tick()
+		close(ticked)
+	}()
+	// Wait for the Goo cells to get stuck behind the staller at the commit barrier
+	var stalled Health
+	for i := 0; i < 1000; i++ {
+		stalled = ticker.Health()
+		if stalled.Cells[0].Phase == CellAtBarrier && stalled.Cells[1].Phase == CellAtBarrier {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.True(stalled.Ticking)
+	assert.Equal(int64(1), stalled.TickID)
+	assert.Equal(CellAtBarrier, stalled.Cells[0].Phase)
+	assert.Equal(CellComputing, stalled.Cells[2].Phase)
+	assert.Equal(int64(0), stalled.Cells[2].LastAckedTick)
+
+	var buf bytes.Buffer
+	assert.Nil(stalled.WriteText(&buf, false))
+	assert.Contains(buf.String(), "tick 1 (ticking)\n")
+	assert.Contains(buf.String(), "2:goo#7  computing")
+
+	close(staller.release)
+	<-ticked
+	assert.Nil(ticker.Stop(time.Second))
+	health = ticker.Health()
+	assert.Equal(int64(2), health.TickID)
+	for _, cell := range health.Cells {
+		assert.False(cell.Alive)
+		assert.Equal(CellDead, cell.Phase)
+		assert.Equal(int64(1), cell.LastAckedTick)
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	sim := NewSimulation()
+	assert.Equal(Health{}, sim.Health())
+	assert.Nil(sim.Configure(SimulationConfig{World: newGooRow(3), MaxTicks: 2}))
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+
+	rec := httptest.NewRecorder()
+	HealthHandler(sim).ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal("application/json", rec.Header().Get("Content-Type"))
+	var health Health
+	assert.Nil(json.Unmarshal(rec.Body.Bytes(), &health))
+	assert.Equal(int64(2), health.TickID)
+	assert.Len(health.Cells, 3)
+	assert.Equal("1:goo#1", health.Cells[1].Cell)
+
+	rec = httptest.NewRecorder()
+	HealthHandler(sim).ServeHTTP(rec, httptest.NewRequest("GET", "/health?format=text&all=1", nil))
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	assert.Equal("tick 2 (idle)", lines[0])
+	assert.Len(lines, 5)
+}
@@ -0,0 +1,61 @@
+package cellaut
+
+/*
+The directions to a cell's 6 neighbors on a hex Grid. Hexes are stored in the same rows and columns
+as squares, with each row shifted half a hex to the right of the row below it, so the neighbors
+above a cell are straight up and up-left of it in the rows and columns, and the ones below are
+straight down and down-right. That lets the hex directions reuse six of the square ones, which
+keeps them paired with their opposites by Recip: NeighborHexNE.Recip() is NeighborHexSW, and so on.
+*/
+const (
+	NeighborHexE  = NeighborRt
+	NeighborHexW  = NeighborLf
+	NeighborHexNE = NeighborUp
+	NeighborHexSW = NeighborDn
+	NeighborHexNW = NeighborUpLf
+	NeighborHexSE = NeighborDnRt
+)
+
+/*
+WithHex makes NewGrid lay the cells out on a hex lattice, each wired to the 6 neighbors around it,
+instead of on squares. It takes precedence over WithDiagonals.
+
+Because of the way rows are shifted, a width×height hex Grid is a rhombus leaning to the right, and
+renderers, which draw cells as squares, show it sheared back into a rectangle. WithTopology works as
+usual: a hex Torus joins the opposite sides of the rhombus.
+*/
+func WithHex() GridOption {
+	return func(grid *Grid) {
+		grid.hex = true
+	}
+}
+
+/*
+NewHexGrid builds a width×height hex Grid of RuleCellAuts that follow rule, all starting in state.
+The neighbors rule sees are keyed by the NeighborHex directions.
+*/
+func NewHexGrid(width, height int, rule Rule, state State, options ...GridOption) *Grid {
+	options = append([]GridOption{WithHex()}, options...)
+	return NewRuleGrid(width, height, rule, state, options...)
+}
+
+/*
+Snowflake is Packard's snowflake model, over the states "-" and "X", for running on a hex Grid:
+water ("-") freezes into ice ("X") when exactly one of its neighbors is ice, and ice stays ice.
+Grown from a single frozen cell, it makes a six-fold flake that branches out as it goes.
+*/
+func Snowflake(self State, neighbors map[NeighborIndex]State) State {
+	if self == "X" {
+		return "X"
+	}
+	frozen := 0
+	for _, state := range neighbors {
+		if state == "X" {
+			frozen++
+		}
+	}
+	if frozen == 1 {
+		return "X"
+	}
+	return "-"
+}
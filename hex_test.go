@@ -0,0 +1,65 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithHex(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	grid := NewGrid(3, 3, func(x, y int) CellAut {
+		return &wiringCellAut{GooCellAut: NewGooCellAut(y*3 + x), neighbors: make(map[NeighborIndex]CellAut)}
+	}, WithHex(), WithDiagonals())
+	neighbors := func(x, y int) map[NeighborIndex]CellAut {
+		return grid.At(x, y).(*wiringCellAut).neighbors
+	}
+	assert.Equal(map[NeighborIndex]CellAut{
+		NeighborHexE:  grid.At(2, 1),
+		NeighborHexW:  grid.At(0, 1),
+		NeighborHexNE: grid.At(1, 2),
+		NeighborHexNW: grid.At(0, 2),
+		NeighborHexSE: grid.At(2, 0),
+		NeighborHexSW: grid.At(1, 0),
+	}, neighbors(1, 1))
+	// Every neighbor knows the cell back, from the opposite direction
+	for i, neighbor := range neighbors(1, 1) {
+		assert.Equal(grid.At(1, 1), neighbor.(*wiringCellAut).neighbors[i.Recip()])
+	}
+	assert.Equal(NeighborHexSW, NeighborHexNE.Recip())
+	assert.Equal(NeighborHexSE, NeighborHexNW.Recip())
+	assert.Equal(NeighborHexW, NeighborHexE.Recip())
+	assert.Len(neighbors(0, 0), 2)
+	assert.Len(neighbors(2, 0), 3)
+}
+
+func TestSnowflake(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// After two ticks, the flake has the seed, its 6 neighbors, and a tip at the end of each arm,
+	// but nothing between the arms, where cells touch two bits of ice
+	want := map[[2]int]bool{{4, 4}: true}
+	for _, offset := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {-1, 1}, {0, -1}, {1, -1}} {
+		want[[2]int{4 + offset[0], 4 + offset[1]}] = true
+		want[[2]int{4 + 2*offset[0], 4 + 2*offset[1]}] = true
+	}
+	grid := NewHexGrid(9, 9, Snowflake, "-")
+	grid.At(4, 4).SetState("X")
+	assert.Equal(want, liveCellsAfter(t, grid, 2))
+
+	// The synchronous engine sees the same neighbors
+	grid = NewHexGrid(9, 9, Snowflake, "-")
+	grid.At(4, 4).SetState("X")
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 3, Engine: SynchronousEngine}))
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 9; x++ {
+			assert.Equal(want[[2]int{x, y}], grid.At(x, y).GetState() == "X", "(%d, %d)", x, y)
+		}
+	}
+}
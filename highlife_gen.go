@@ -0,0 +1,31 @@
+// Code generated by rulegen -rule B36/S23 -name HighLife; DO NOT EDIT.
+
+package cellaut
+
+// highLifeTable[self][n] is the next state of a cell in state self (0 for "-", 1 for "X")
+// with n live neighbors.
+var highLifeTable = [2][9]State{
+	{"-", "-", "-", "X", "-", "-", "X", "-", "-"},
+	{"-", "-", "X", "X", "-", "-", "-", "-", "-"},
+}
+
+/*
+HighLife is the Life-like rule B36/S23.
+
+Any state other than "X" counts as dead.
+*/
+func HighLife(self State, neighbors map[NeighborIndex]State) State {
+	n := 0
+	for _, neighbor := range neighbors {
+		if neighbor == "X" {
+			n++
+		}
+	}
+	if n >= len(highLifeTable[0]) {
+		return "-"
+	}
+	if self == "X" {
+		return highLifeTable[1][n]
+	}
+	return highLifeTable[0][n]
+}
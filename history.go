@@ -0,0 +1,164 @@
+package cellaut
+
+import (
+	"fmt"
+	"sync"
+)
+
+/*
+History keeps a snapshot of a World as of every tick, built from the StateRecords on the state
+ledger, so that a run can be looked back over or rewound with Simulation.RewindTo. It's given to a
+Simulation in SimulationConfig.History.
+
+A tick in which nothing changed shares its snapshot with the tick before, so a settled pattern
+costs next to nothing to keep. Cells added to the World by Simulation.Edit aren't kept track of.
+*/
+type History struct {
+	mu sync.Mutex
+	// limit is the most ticks kept, or 0 for no limit
+	limit int
+	// where is the (x, y) of each cell by its CellID
+	where   map[string][2]int
+	current *StateGrid
+	// dirty is whether current has changed since the last snapshot was taken
+	dirty bool
+	// grids[i] is the snapshot as of tick first+i
+	first int64
+	grids []*StateGrid
+}
+
+/*
+NewHistory returns a *History of world that keeps the last limit ticks, dropping older ones as it
+goes. If limit is 0, it keeps every tick.
+*/
+func NewHistory(world World, limit int) *History {
+	width, height := world.Size()
+	where := make(map[string][2]int, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			where[cellName(world.At(x, y))] = [2]int{x, y}
+		}
+	}
+	return &History{
+		limit:   limit,
+		where:   where,
+		current: TakeSnapshot(world, nil),
+		dirty:   true,
+	}
+}
+
+/*
+Record applies a StateRecord from the ledger. Records for cells that aren't in the World are
+ignored.
+*/
+func (history *History) Record(record StateRecord) {
+	history.mu.Lock()
+	defer history.mu.Unlock()
+	cell, ok := history.where[record.CellID]
+	if !ok {
+		return
+	}
+	history.current.Set(cell[0], cell[1], record.State)
+	history.dirty = true
+}
+
+/*
+endTick takes the snapshot for tickID, once all of its records are in.
+*/
+func (history *History) endTick(tickID int64) {
+	history.mu.Lock()
+	defer history.mu.Unlock()
+	if len(history.grids) == 0 {
+		history.first = tickID
+	}
+	var snapshot *StateGrid
+	if history.dirty {
+		snapshot = cloneStateGrid(history.current)
+		history.dirty = false
+	} else {
+		snapshot = history.grids[len(history.grids)-1]
+	}
+	history.grids = append(history.grids, snapshot)
+	if history.limit > 0 && len(history.grids) > history.limit {
+		history.grids[0] = nil
+		history.grids = history.grids[1:]
+		history.first++
+	}
+}
+
+/*
+At returns the World's states as of the given tick, or nil if that tick hasn't happened yet or has
+been dropped. The *StateGrid may be shared with other ticks, so it mustn't be modified.
+*/
+func (history *History) At(tick int64) *StateGrid {
+	history.mu.Lock()
+	defer history.mu.Unlock()
+	i := tick - history.first
+	if i < 0 || i >= int64(len(history.grids)) {
+		return nil
+	}
+	return history.grids[i]
+}
+
+/*
+Range returns the first and last ticks the History holds. ok is false if it doesn't hold any yet.
+*/
+func (history *History) Range() (first, last int64, ok bool) {
+	history.mu.Lock()
+	defer history.mu.Unlock()
+	if len(history.grids) == 0 {
+		return 0, 0, false
+	}
+	return history.first, history.first + int64(len(history.grids)) - 1, true
+}
+
+/*
+Frames returns the World's states as of every tick the History holds, in order, for analyses of the
+whole run like CoarseGrain. As for At, the *StateGrids mustn't be modified.
+*/
+func (history *History) Frames() []*StateGrid {
+	history.mu.Lock()
+	defer history.mu.Unlock()
+	return append([]*StateGrid(nil), history.grids...)
+}
+
+/*
+RewindTo puts every cell of the World back into the state it had as of the given tick, which has to
+be one the config's History holds. It happens at the start of the next tick, so that tick's
+TickEvent shows the World as it was then. Tick numbers carry on counting up from where they were.
+*/
+func (sim *Simulation) RewindTo(tick int64) error {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	history := sim.config.History
+	if history == nil {
+		return fmt.Errorf("can't rewind a simulation without a History")
+	}
+	grid := history.At(tick)
+	if grid == nil {
+		return fmt.Errorf("tick %d isn't in the history", tick)
+	}
+	sim.rewind = grid
+	return nil
+}
+
+/*
+applyRewind sets the World's cells to the states RewindTo asked for, if it's been called since the
+last tick.
+*/
+func (sim *Simulation) applyRewind() {
+	sim.mu.Lock()
+	grid := sim.rewind
+	sim.rewind = nil
+	sim.mu.Unlock()
+	if grid == nil {
+		return
+	}
+	world := sim.config.World
+	width, height := world.Size()
+	for y := 0; y < height && y < grid.Height; y++ {
+		for x := 0; x < width && x < grid.Width; x++ {
+			world.At(x, y).SetState(grid.At(x, y))
+		}
+	}
+}
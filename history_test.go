@@ -0,0 +1,72 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	for _, engine := range []EngineKind{ChannelEngine, MultiplexedEngine, SynchronousEngine} {
+		grid := NewLifeGrid(10, 10)
+		for cell := range shifted(gliderCells, 2, 5) {
+			grid.At(cell[0], cell[1]).SetState(LifeAlive)
+		}
+		history := NewHistory(grid, 0)
+		sim := NewSimulation()
+		assert.Nil(sim.Configure(SimulationConfig{World: grid, Engine: engine, MaxTicks: 8, History: history}))
+		var seen []*StateGrid
+		sim.Subscribe(func(ev TickEvent) {
+			// The History is already up to date
+			assert.True(TakeSnapshot(ev.World, nil).Equal(history.At(ev.TickID)), "engine %s, tick %d", engine, ev.TickID)
+			seen = append(seen, TakeSnapshot(ev.World, nil))
+			if ev.TickID == 4 {
+				assert.Nil(sim.RewindTo(1))
+			}
+		})
+		assert.Nil(sim.Start())
+		assert.Nil(sim.Wait())
+
+		// After the rewind, the glider goes over the same ground again
+		for tick := 5; tick < 8; tick++ {
+			assert.True(seen[tick].Equal(seen[tick-4]), "engine %s, tick %d", engine, tick)
+		}
+		first, last, ok := history.Range()
+		assert.True(ok)
+		assert.Equal([]int64{0, 7}, []int64{first, last})
+		assert.Nil(history.At(8))
+		assert.NotNil(sim.RewindTo(8))
+	}
+}
+
+func TestHistory_Limit(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A block never changes, so every tick after the first shares a snapshot
+	grid := NewLifeGrid(4, 4)
+	for _, cell := range [][2]int{{1, 1}, {1, 2}, {2, 1}, {2, 2}} {
+		grid.At(cell[0], cell[1]).SetState(LifeAlive)
+	}
+	history := NewHistory(grid, 3)
+	_, _, ok := history.Range()
+	assert.False(ok)
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 6, History: history}))
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	first, last, ok := history.Range()
+	assert.True(ok)
+	assert.Equal([]int64{3, 5}, []int64{first, last})
+	assert.Nil(history.At(2))
+	assert.True(history.At(3) == history.At(5))
+	assert.Equal(int64(4), history.At(4).Population(LifeAlive))
+	assert.NotNil(sim.RewindTo(2))
+
+	sim = NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: NewLifeGrid(4, 4)}))
+	assert.NotNil(sim.RewindTo(0))
+}
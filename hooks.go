@@ -0,0 +1,216 @@
+package cellaut
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+/*
+Hook is something to do at particular ticks of a Simulation, declared in config rather than
+written in Go. It either runs an external Command or a Script, but not both.
+
+A Script is made of lines like these, with the tick number substituted for {tick} in file names:
+
+	set 3 4 X                # put the cell at (3, 4) in state X
+	place 10 10 glider.txt   # stamp a pattern file (as for ParsePattern) with its bottom left at (10, 10)
+	dump out-{tick}.txt      # write every cell's state to a file, top row first
+
+A dump's file can be an s3:// URL, as for NewS3Output, to write it to object storage.
+
+Anything after a # is a comment. A place leaves the cells under the pattern's
+"." cells alone.
+*/
+type Hook struct {
+	// At is the first tick to run at
+	At int64 `json:"at,omitempty" yaml:"at,omitempty"`
+	// Every, if it's set, runs the hook again every so many ticks after At
+	Every int64 `json:"every,omitempty" yaml:"every,omitempty"`
+	// Before runs the hook before the tick rather than after, so that changes it makes are part of
+	// that tick
+	Before bool `json:"before,omitempty" yaml:"before,omitempty"`
+	// Command is run with `sh -c`. It gets the tick in $CELLAUT_TICK and the World's states on
+	// stdin, as for dump.
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
+	Script  string `json:"script,omitempty" yaml:"script,omitempty"`
+}
+
+/*
+due returns whether the hook should run at tickID.
+*/
+func (hook Hook) due(tickID int64) bool {
+	if tickID < hook.At {
+		return false
+	}
+	if hook.Every == 0 {
+		return tickID == hook.At
+	}
+	return (tickID-hook.At)%hook.Every == 0
+}
+
+/*
+hookStep is one compiled line of a Script.
+*/
+type hookStep func(world World, tickID int64) error
+
+/*
+compile checks the hook and turns it into steps to run.
+*/
+func (hook Hook) compile() ([]hookStep, error) {
+	if hook.At < 0 || hook.Every < 0 {
+		return nil, fmt.Errorf("hook 'at' and 'every' must not be negative")
+	}
+	if (hook.Command == "") == (hook.Script == "") {
+		return nil, fmt.Errorf("a hook must have exactly one of 'command' and 'script'")
+	}
+	if hook.Command != "" {
+		return []hookStep{func(world World, tickID int64) error { return runHookCommand(hook.Command, world, tickID) }}, nil
+	}
+
+	var steps []hookStep
+	for i, line := range strings.Split(hook.Script, "\n") {
+		if comment := strings.Index(line, "#"); comment >= 0 {
+			line = line[:comment]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		step, err := compileHookLine(fields)
+		if err != nil {
+			return nil, fmt.Errorf("hook script line %d: %s", i+1, err)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func compileHookLine(fields []string) (hookStep, error) {
+	xy := func() (int, int, error) {
+		x, errX := strconv.Atoi(fields[1])
+		y, errY := strconv.Atoi(fields[2])
+		if errX != nil || errY != nil {
+			return 0, 0, fmt.Errorf("bad coordinates (%s, %s)", fields[1], fields[2])
+		}
+		return x, y, nil
+	}
+	switch {
+	case fields[0] == "set" && len(fields) == 4:
+		x, y, err := xy()
+		if err != nil {
+			return nil, err
+		}
+		state := State(fields[3])
+		return func(world World, tickID int64) error {
+			return setHookCell(world, x, y, state)
+		}, nil
+	case fields[0] == "place" && len(fields) == 4:
+		x, y, err := xy()
+		if err != nil {
+			return nil, err
+		}
+		path := fields[3]
+		return func(world World, tickID int64) error {
+			text, err := ioutil.ReadFile(hookPath(path, tickID))
+			if err != nil {
+				return err
+			}
+			pattern, err := ParsePattern(string(text), nil)
+			if err != nil {
+				return fmt.Errorf("%s: %s", path, err)
+			}
+			for py := 0; py < pattern.Height; py++ {
+				for px := 0; px < pattern.Width; px++ {
+					if pattern.IDAt(px, py) == 0 {
+						continue
+					}
+					if err := setHookCell(world, x+px, y+py, pattern.At(px, py)); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}, nil
+	case fields[0] == "dump" && len(fields) == 2:
+		path := fields[1]
+		return func(world World, tickID int64) error {
+			return writeOutput(hookPath(path, tickID), []byte(worldText(world)))
+		}, nil
+	}
+	return nil, fmt.Errorf("expected 'set x y state', 'place x y file' or 'dump file'; got '%s'", strings.Join(fields, " "))
+}
+
+func setHookCell(world World, x, y int, state State) error {
+	width, height := world.Size()
+	if !(Rect{Width: width, Height: height}).Contains(x, y) {
+		return fmt.Errorf("(%d, %d) is outside the %dx%d world", x, y, width, height)
+	}
+	world.At(x, y).SetState(state)
+	return nil
+}
+
+func hookPath(path string, tickID int64) string {
+	return strings.Replace(path, "{tick}", strconv.FormatInt(tickID, 10), -1)
+}
+
+/*
+worldText returns the States of world's cells, top row first, one row per line.
+*/
+func worldText(world World) string {
+	return (&Pattern{StateGrid: TakeSnapshot(world, nil)}).String()
+}
+
+func runHookCommand(command string, world World, tickID int64) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("CELLAUT_TICK=%d", tickID))
+	cmd.Stdin = strings.NewReader(worldText(world))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("hook command '%s': %s", command, err)
+	}
+	return nil
+}
+
+/*
+compiledHook is a Hook that's ready to run.
+*/
+type compiledHook struct {
+	Hook
+	steps []hookStep
+}
+
+func compileHooks(hooks []Hook) ([]compiledHook, error) {
+	compiled := make([]compiledHook, len(hooks))
+	for i, hook := range hooks {
+		steps, err := hook.compile()
+		if err != nil {
+			return nil, fmt.Errorf("hook %d: %s", i, err)
+		}
+		compiled[i] = compiledHook{hook, steps}
+	}
+	return compiled, nil
+}
+
+/*
+runHooks runs the hooks that are due at tickID and are on the given side of the tick. A hook that
+fails is logged, and its remaining steps are skipped, but the Simulation carries on.
+*/
+func runHooks(hooks []compiledHook, world World, tickID int64, before bool) {
+	for i, hook := range hooks {
+		if hook.Before != before || !hook.due(tickID) {
+			continue
+		}
+		for _, step := range hook.steps {
+			if err := step(world, tickID); err != nil {
+				log.WithFields(log.Fields{"tick": tickID, "hook": i}).Error(err.Error())
+				break
+			}
+		}
+	}
+}
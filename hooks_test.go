@@ -0,0 +1,73 @@
+package cellaut
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulation_Hooks(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, "seed.txt"), []byte("X.X"), 0644))
+	world := newGooRow(7)
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{
+		World:    world,
+		MaxTicks: 4,
+		Hooks: []Hook{
+			// Seed the row before tick 1, so that tick 1 commits it
+			{At: 1, Before: true, Script: "place 0 0 " + filepath.Join(dir, "seed.txt") + "\nset 6 0 X"},
+			{At: 1, Every: 2, Script: "dump " + filepath.Join(dir, "tick-{tick}.txt") + " # after ticks 1 and 3"},
+			{At: 2, Command: "echo $CELLAUT_TICK > " + filepath.Join(dir, "command.txt") + "; cat >> " + filepath.Join(dir, "command.txt")},
+			// Fails, but doesn't stop the other hooks
+			{At: 3, Before: true, Script: "set 10 0 X"},
+		},
+	}))
+	var rows []string
+	sim.Subscribe(func(ev TickEvent) { rows = append(rows, concatStates(world.cells)) })
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	assert.Equal([]string{"-------", "X-X---X", "XXXX-XX", "XXXXXXX"}, rows)
+
+	dump, err := ioutil.ReadFile(filepath.Join(dir, "tick-1.txt"))
+	assert.Nil(err)
+	assert.Equal("XXX\n", string(dump))
+	dump, err = ioutil.ReadFile(filepath.Join(dir, "tick-3.txt"))
+	assert.Nil(err)
+	assert.Equal("XXXXXXX\n", string(dump))
+	out, err := ioutil.ReadFile(filepath.Join(dir, "command.txt"))
+	assert.Nil(err)
+	assert.Equal("2\nXXXXXX\n", string(out))
+}
+
+func TestHook_Compile(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	for _, bad := range []Hook{
+		{},
+		{Command: "true", Script: "dump x"},
+		{At: -1, Script: "dump x"},
+		{Script: "set 1 X"},
+		{Script: "set a b X"},
+		{Script: "explode"},
+	} {
+		_, err := bad.compile()
+		assert.NotNil(err, "%+v", bad)
+	}
+	assert.NotNil(NewSimulation().Configure(SimulationConfig{World: newGooRow(1), Hooks: []Hook{{}}}))
+
+	hook := Hook{At: 3, Every: 5}
+	var due []int64
+	for tick := int64(0); tick < 20; tick++ {
+		if hook.due(tick) {
+			due = append(due, tick)
+		}
+	}
+	assert.Equal([]int64{3, 8, 13, 18}, due)
+}
@@ -0,0 +1,132 @@
+package cellaut
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+InputEvent is a change to make to one cell from outside the rule: the cell at (X, Y) goes into
+State at tick Tick, so that the World as of that tick shows it.
+*/
+type InputEvent struct {
+	Tick  int64
+	X, Y  int
+	State State
+}
+
+/*
+InputReader reads InputEvents from CSV, one per line, like this:
+
+	# tick,x,y,state
+	0,3,4,X
+	10,3,4,-
+
+so that recorded data, or a script writing to a pipe, can drive a simulation. There can be a header
+line starting with "tick", and anything after a # is a comment. The events have to be in order of
+tick, but there can be any number for one tick.
+
+It only reads as far as it has to, so a slow writer holds the simulation back rather than the other
+way around.
+*/
+type InputReader struct {
+	scanner *bufio.Scanner
+	// next is the first event for a tick that hasn't come up yet
+	next *InputEvent
+	// lastTick is the tick of the last event read, which the next one mustn't be before
+	lastTick int64
+	line     int
+	err      error
+}
+
+/*
+NewInputReader returns an *InputReader that reads events from r.
+*/
+func NewInputReader(r io.Reader) *InputReader {
+	return &InputReader{scanner: bufio.NewScanner(r), lastTick: -1}
+}
+
+/*
+Due returns the events for tickID, reading up to the first event for a later tick. Events for
+ticks before tickID that haven't been returned yet are returned too, since it's too late to do any
+better with them.
+
+Once the input runs out, Due returns no events and no error. If a line can't be read, that error is
+returned, and from then on every call returns it.
+*/
+func (input *InputReader) Due(tickID int64) ([]InputEvent, error) {
+	var events []InputEvent
+	for {
+		if input.next == nil {
+			if input.err != nil {
+				if input.err == io.EOF {
+					return events, nil
+				}
+				return events, input.err
+			}
+			input.next, input.err = input.read()
+			continue
+		}
+		if input.next.Tick > tickID {
+			return events, nil
+		}
+		events = append(events, *input.next)
+		input.next = nil
+	}
+}
+
+/*
+read returns the next event, or io.EOF once there aren't any more.
+*/
+func (input *InputReader) read() (*InputEvent, error) {
+	for input.scanner.Scan() {
+		input.line++
+		line := input.scanner.Text()
+		if comment := strings.Index(line, "#"); comment >= 0 {
+			line = line[:comment]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if input.lastTick == -1 && strings.EqualFold(strings.TrimSpace(fields[0]), "tick") {
+			continue
+		}
+		event, err := parseInputEvent(fields)
+		if err != nil {
+			return nil, fmt.Errorf("input line %d: %s", input.line, err)
+		}
+		if event.Tick < input.lastTick {
+			return nil, fmt.Errorf("input line %d: tick %d comes after tick %d", input.line, event.Tick, input.lastTick)
+		}
+		input.lastTick = event.Tick
+		return event, nil
+	}
+	if err := input.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func parseInputEvent(fields []string) (*InputEvent, error) {
+	if len(fields) != 4 {
+		return nil, fmt.Errorf("expected tick,x,y,state; got %d fields", len(fields))
+	}
+	tick, err := strconv.ParseInt(strings.TrimSpace(fields[0]), 10, 64)
+	if err != nil || tick < 0 {
+		return nil, fmt.Errorf("bad tick '%s'", fields[0])
+	}
+	x, errX := strconv.Atoi(strings.TrimSpace(fields[1]))
+	y, errY := strconv.Atoi(strings.TrimSpace(fields[2]))
+	if errX != nil || errY != nil {
+		return nil, fmt.Errorf("bad coordinates (%s, %s)", fields[1], fields[2])
+	}
+	state := State(strings.TrimSpace(fields[3]))
+	if state == "" {
+		return nil, fmt.Errorf("missing state")
+	}
+	return &InputEvent{Tick: tick, X: x, Y: y, State: state}, nil
+}
@@ -0,0 +1,94 @@
+package cellaut
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInputReader(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	input := NewInputReader(strings.NewReader(`tick,x,y,state
+		# the header and comments are skipped
+		0, 1, 2, X
+		0,3,4,Y   # two at once
+		5,0,0,-
+	`))
+	events, err := input.Due(0)
+	assert.Nil(err)
+	assert.Equal([]InputEvent{{Tick: 0, X: 1, Y: 2, State: "X"}, {Tick: 0, X: 3, Y: 4, State: "Y"}}, events)
+	events, err = input.Due(4)
+	assert.Nil(err)
+	assert.Empty(events)
+	// It's too late for tick 5, but it still happens
+	events, err = input.Due(7)
+	assert.Nil(err)
+	assert.Equal([]InputEvent{{Tick: 5, X: 0, Y: 0, State: "-"}}, events)
+	events, err = input.Due(8)
+	assert.Nil(err)
+	assert.Empty(events)
+
+	for _, text := range []string{
+		"0,1,2",
+		"0,1,two,X",
+		"-1,1,2,X",
+		"0,1,2, ",
+		"3,1,2,X\n2,1,2,X",
+	} {
+		input := NewInputReader(strings.NewReader(text))
+		_, err := input.Due(10)
+		assert.NotNil(err, text)
+		// It sticks
+		_, err = input.Due(11)
+		assert.NotNil(err, text)
+	}
+}
+
+func TestSimulation_Input(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A glider dropped into an empty grid at tick 2 flies like any other
+	var text strings.Builder
+	text.WriteString("tick,x,y,state\n")
+	for _, cell := range gliderCells {
+		fmt.Fprintf(&text, "2,%d,%d,%s\n", cell[0]+1, cell[1]+1, LifeAlive)
+	}
+	// This one's off the grid, which is logged and skipped
+	text.WriteString("3,50,50,X\n")
+	grid := NewLifeGrid(10, 10)
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 7, Input: strings.NewReader(text.String())}))
+	var populations []int64
+	sim.Subscribe(func(ev TickEvent) {
+		populations = append(populations, TakeSnapshot(ev.World, nil).Population(LifeAlive))
+	})
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	assert.Equal([]int64{0, 0, 5, 5, 5, 5, 5}, populations)
+	want := NewStateGrid(10, 10, NewStateTable(LifeDead))
+	// Four generations later, it's one cell right and one cell down from where it was dropped
+	for cell := range shifted(gliderCells, 2, 0) {
+		want.Set(cell[0], cell[1], LifeAlive)
+	}
+	assert.True(want.Equal(TakeSnapshot(grid, NewStateTable(LifeDead))), "\n%s", &Pattern{StateGrid: TakeSnapshot(grid, nil)})
+}
+
+func TestDemo_Input(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A lone cell shows up in the generation it's for, and dies in the next
+	preset, _ := findPreset("glider-gun")
+	preset.input = NewInputReader(strings.NewReader("3,59,0,X\n"))
+	var corner []State
+	assert.Nil(preset.run(5, 1, func(tick int, grid *StateGrid) { corner = append(corner, grid.At(59, 0)) }))
+	assert.Equal([]State{"-", "-", "-", "X", "-", "-"}, corner)
+
+	preset.input = NewInputReader(strings.NewReader("1,60,0,X\n"))
+	assert.NotNil(preset.run(5, 1, func(tick int, grid *StateGrid) {}))
+}
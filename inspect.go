@@ -0,0 +1,135 @@
+package cellaut
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+/*
+CellInfo is everything an Inspector knows about one cell.
+*/
+type CellInfo struct {
+	X, Y  int
+	State State
+	// Age is how many ticks the cell has been in State. A cell that hasn't changed since the
+	// Inspector started watching is as old as the watching.
+	Age int64
+	// History is the cell's State at each of the last few ticks, oldest first, ending with State
+	History []State
+}
+
+func (info CellInfo) String() string {
+	history := make([]string, len(info.History))
+	for i, state := range info.History {
+		history[i] = string(state)
+	}
+	return fmt.Sprintf("(%d, %d): %s for %d ticks; history %s", info.X, info.Y, info.State, info.Age, strings.Join(history, " "))
+}
+
+/*
+Inspector watches a grid tick by tick, so that viewers can say what any cell is doing: how long
+it's been in its state, and what it was before.
+
+It's safe for concurrent use, so a Simulation can feed it from its Sink while a viewer asks about
+cells from another goroutine.
+*/
+type Inspector struct {
+	mu    sync.Mutex
+	depth int
+	tick  int64
+	// grids holds copies of the last depth grids recorded, oldest first
+	grids []*StateGrid
+	// changed[i] is the tick the cell at index i last changed
+	changed []int64
+}
+
+/*
+NewInspector returns an Inspector that remembers depth ticks of history.
+*/
+func NewInspector(depth int) *Inspector {
+	if depth < 1 {
+		depth = 1
+	}
+	return &Inspector{depth: depth}
+}
+
+/*
+Record tells the Inspector what grid looks like at the given tick. Recording the same tick again
+replaces it, for grids that get edited between ticks. If the grid's size has changed since the last
+one, or tick goes backwards, the Inspector starts over.
+*/
+func (inspector *Inspector) Record(tick int64, grid *StateGrid) {
+	inspector.mu.Lock()
+	defer inspector.mu.Unlock()
+	grid = cloneStateGrid(grid)
+	if n := len(inspector.grids); n > 0 && tick == inspector.tick {
+		inspector.grids = inspector.grids[:n-1]
+	}
+	var last *StateGrid
+	if n := len(inspector.grids); n > 0 {
+		last = inspector.grids[n-1]
+	}
+	if last == nil || last.Width != grid.Width || last.Height != grid.Height || tick < inspector.tick {
+		inspector.grids = nil
+		inspector.changed = make([]int64, len(grid.cells))
+		for i := range inspector.changed {
+			inspector.changed[i] = tick
+		}
+	} else {
+		for i := range grid.cells {
+			if last.Table.State(last.cells[i]) != grid.Table.State(grid.cells[i]) {
+				inspector.changed[i] = tick
+			}
+		}
+	}
+	inspector.tick = tick
+	inspector.grids = append(inspector.grids, grid)
+	if len(inspector.grids) > inspector.depth {
+		inspector.grids = inspector.grids[len(inspector.grids)-inspector.depth:]
+	}
+}
+
+/*
+Inspect returns what the Inspector knows about the cell at (x, y).
+*/
+func (inspector *Inspector) Inspect(x, y int) (CellInfo, error) {
+	inspector.mu.Lock()
+	defer inspector.mu.Unlock()
+	if len(inspector.grids) == 0 {
+		return CellInfo{}, fmt.Errorf("nothing recorded yet")
+	}
+	last := inspector.grids[len(inspector.grids)-1]
+	if !(Rect{Width: last.Width, Height: last.Height}).Contains(x, y) {
+		return CellInfo{}, fmt.Errorf("(%d, %d) is outside the %dx%d grid", x, y, last.Width, last.Height)
+	}
+	info := CellInfo{
+		X:     x,
+		Y:     y,
+		State: last.At(x, y),
+		Age:   inspector.tick - inspector.changed[y*last.Width+x],
+	}
+	for _, grid := range inspector.grids {
+		info.History = append(info.History, grid.At(x, y))
+	}
+	return info, nil
+}
+
+/*
+Sink returns a Sink that records every tick of a Simulation.
+*/
+func (inspector *Inspector) Sink() Sink {
+	return func(event TickEvent) {
+		inspector.Record(event.TickID, TakeSnapshot(event.World, nil))
+	}
+}
+
+/*
+cloneStateGrid copies a StateGrid. The copy shares the original's StateTable, which only ever grows.
+*/
+func cloneStateGrid(grid *StateGrid) *StateGrid {
+	clone := NewStateGrid(grid.Width, grid.Height, grid.Table)
+	copy(clone.cells, grid.cells)
+	clone.Groups = grid.Groups
+	return clone
+}
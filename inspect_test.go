@@ -0,0 +1,79 @@
+package cellaut
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspector(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	inspector := NewInspector(3)
+	_, err := inspector.Inspect(0, 0)
+	assert.NotNil(err)
+
+	// A blinker, whose middle cell never changes and whose ends flip every tick
+	pattern, err := ParsePattern("-----\n-XXX-\n-----\n", NewStateTable("-"))
+	assert.Nil(err)
+	grid := pattern.StateGrid
+	for tick := int64(0); tick <= 4; tick++ {
+		inspector.Record(tick, grid)
+		grid = stepGrid(grid, Life, mooreNeighborhood)
+	}
+
+	info, err := inspector.Inspect(2, 1)
+	assert.Nil(err)
+	assert.Equal(CellInfo{X: 2, Y: 1, State: "X", Age: 4, History: []State{"X", "X", "X"}}, info)
+	info, err = inspector.Inspect(1, 1)
+	assert.Nil(err)
+	assert.Equal(CellInfo{X: 1, Y: 1, State: "X", Age: 0, History: []State{"X", "-", "X"}}, info)
+	assert.Equal("(1, 1): X for 0 ticks; history X - X", info.String())
+	_, err = inspector.Inspect(5, 0)
+	assert.NotNil(err)
+
+	// Recording the same tick again replaces it
+	grid = cloneStateGrid(pattern.StateGrid)
+	grid.Set(0, 0, "X")
+	inspector.Record(4, grid)
+	info, err = inspector.Inspect(0, 0)
+	assert.Nil(err)
+	assert.Equal([]State{"-", "-", "X"}, info.History)
+	assert.Equal(int64(0), info.Age)
+
+	// Going back to the start is starting over
+	inspector.Record(0, pattern.StateGrid)
+	info, err = inspector.Inspect(2, 1)
+	assert.Nil(err)
+	assert.Equal([]State{"X"}, info.History)
+}
+
+func TestREPL_Inspect(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	script := strings.Join([]string{
+		"new 5 3",
+		"set 1 1 X",
+		"set 2 1 X",
+		"set 3 1 X",
+		"inspect 2 1",
+		"step 3",
+		"inspect 2 1",
+		"inspect 1 1",
+		"inspect 9 9",
+	}, "\n")
+	var out bytes.Buffer
+	session := newREPLSession(10, 10, &out)
+	assert.Nil(runREPL(session, strings.NewReader(script), false))
+	assert.Equal(strings.Join([]string{
+		"(2, 1): X for 0 ticks; history X",
+		"(2, 1): X for 3 ticks; history X X X X",
+		"(1, 1): - for 0 ticks; history X - X -",
+		"error: (9, 9) is outside the 5x3 grid",
+		"",
+	}, "\n"), out.String())
+}
@@ -0,0 +1,221 @@
+package cellaut
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+/*
+StateID is the small-integer stand-in for a State inside a StateTable.
+*/
+type StateID uint16
+
+/*
+StateTable interns States: it maps each State to a StateID and back.
+
+Storing StateIDs instead of States costs 2 bytes per cell instead of a string header plus its
+contents, and comparing them is a single integer comparison. A StateTable only ever grows, so a
+StateID stays valid for as long as the table is around. It's safe for concurrent use.
+*/
+type StateTable struct {
+	mu     sync.RWMutex
+	ids    map[State]StateID
+	states []State
+}
+
+/*
+NewStateTable returns a *StateTable with the given States already interned, in order.
+
+The first State gets StateID 0, which is also what a zeroed StateGrid is full of, so it should
+usually be the "empty" state of whatever automaton is being stored.
+*/
+func NewStateTable(states ...State) *StateTable {
+	table := &StateTable{ids: make(map[State]StateID)}
+	for _, state := range states {
+		table.Intern(state)
+	}
+	return table
+}
+
+/*
+Intern returns the StateID for state, assigning it a new one if necessary.
+
+Intern panics if the table is full.
+*/
+func (table *StateTable) Intern(state State) StateID {
+	table.mu.RLock()
+	id, ok := table.ids[state]
+	table.mu.RUnlock()
+	if ok {
+		return id
+	}
+
+	table.mu.Lock()
+	defer table.mu.Unlock()
+	if id, ok := table.ids[state]; ok {
+		return id
+	}
+	if len(table.states) > int(^StateID(0)) {
+		panic(fmt.Sprintf("StateTable is full; can't intern state '%s'", state))
+	}
+	id = StateID(len(table.states))
+	table.ids[state] = id
+	table.states = append(table.states, state)
+	return id
+}
+
+/*
+ID returns the StateID for state, if it has been interned.
+*/
+func (table *StateTable) ID(state State) (StateID, bool) {
+	table.mu.RLock()
+	defer table.mu.RUnlock()
+	id, ok := table.ids[state]
+	return id, ok
+}
+
+/*
+State returns the State with the given StateID.
+*/
+func (table *StateTable) State(id StateID) State {
+	table.mu.RLock()
+	defer table.mu.RUnlock()
+	return table.states[id]
+}
+
+/*
+States returns every interned State, indexed by StateID.
+*/
+func (table *StateTable) States() []State {
+	table.mu.RLock()
+	defer table.mu.RUnlock()
+	return append([]State(nil), table.states...)
+}
+
+/*
+Len returns the number of interned States.
+*/
+func (table *StateTable) Len() int {
+	table.mu.RLock()
+	defer table.mu.RUnlock()
+	return len(table.states)
+}
+
+/*
+StateGrid is a rectangular array of States, stored internally as StateIDs.
+
+It's the compact representation that engines and snapshots use for big grids. Its StateTable may be
+shared with other StateGrids, in which case their StateIDs can be compared directly.
+*/
+type StateGrid struct {
+	Width  int
+	Height int
+	Table  *StateTable
+	// Groups, if there are any, name sets of the grid's cells
+	Groups CellGroups
+	// cells holds the StateID of every cell, row by row
+	cells []StateID
+}
+
+/*
+NewStateGrid returns a *StateGrid in which every cell has StateID 0.
+
+If table is nil, a new StateTable containing just the empty State is used.
+*/
+func NewStateGrid(width, height int, table *StateTable) *StateGrid {
+	if table == nil {
+		table = NewStateTable("")
+	}
+	return &StateGrid{
+		Width:  width,
+		Height: height,
+		Table:  table,
+		cells:  make([]StateID, width*height),
+	}
+}
+
+/*
+At returns the State of the cell at (x, y).
+*/
+func (grid *StateGrid) At(x, y int) State {
+	return grid.Table.State(grid.cells[y*grid.Width+x])
+}
+
+/*
+Set sets the State of the cell at (x, y).
+*/
+func (grid *StateGrid) Set(x, y int, state State) {
+	grid.cells[y*grid.Width+x] = grid.Table.Intern(state)
+}
+
+/*
+IDAt returns the StateID of the cell at (x, y).
+*/
+func (grid *StateGrid) IDAt(x, y int) StateID {
+	return grid.cells[y*grid.Width+x]
+}
+
+/*
+SetID sets the StateID of the cell at (x, y). id must come from grid.Table.
+*/
+func (grid *StateGrid) SetID(x, y int, id StateID) {
+	grid.cells[y*grid.Width+x] = id
+}
+
+// stateGridJSON is what a StateGrid looks like when serialized: the table travels with the cells.
+type stateGridJSON struct {
+	Width  int        `json:"width"`
+	Height int        `json:"height"`
+	States []State    `json:"states"`
+	Cells  []StateID  `json:"cells"`
+	Groups CellGroups `json:"groups,omitempty"`
+}
+
+/*
+MarshalJSON serializes the StateGrid along with its StateTable.
+*/
+func (grid *StateGrid) MarshalJSON() ([]byte, error) {
+	return json.Marshal(stateGridJSON{
+		Width:  grid.Width,
+		Height: grid.Height,
+		States: grid.Table.States(),
+		Cells:  grid.cells,
+		Groups: grid.Groups,
+	})
+}
+
+/*
+UnmarshalJSON deserializes a StateGrid into a fresh StateTable.
+*/
+func (grid *StateGrid) UnmarshalJSON(data []byte) error {
+	var decoded stateGridJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if len(decoded.Cells) != decoded.Width*decoded.Height {
+		return fmt.Errorf("expected %d cells in a %dx%d grid; got %d", decoded.Width*decoded.Height, decoded.Width, decoded.Height, len(decoded.Cells))
+	}
+	// Every state has to get the ID it was written with, which it won't if one comes twice
+	seen := make(map[State]bool, len(decoded.States))
+	for _, state := range decoded.States {
+		if seen[state] {
+			return fmt.Errorf("state '%s' is in the state table twice", state)
+		}
+		seen[state] = true
+	}
+	if len(decoded.States) > int(^StateID(0))+1 {
+		return fmt.Errorf("%d states is too many for a state table", len(decoded.States))
+	}
+	for _, id := range decoded.Cells {
+		if int(id) >= len(decoded.States) {
+			return fmt.Errorf("state ID %d is not in the state table", id)
+		}
+	}
+	grid.Width = decoded.Width
+	grid.Height = decoded.Height
+	grid.Table = NewStateTable(decoded.States...)
+	grid.cells = decoded.Cells
+	grid.Groups = decoded.Groups
+	return nil
+}
@@ -0,0 +1,96 @@
+package cellaut
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateTable(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	table := NewStateTable("-", "X")
+	assert.Equal(StateID(0), table.Intern("-"))
+	assert.Equal(StateID(1), table.Intern("X"))
+	assert.Equal(StateID(2), table.Intern("O"))
+	assert.Equal(StateID(1), table.Intern("X"))
+	assert.Equal(State("O"), table.State(2))
+	assert.Equal([]State{"-", "X", "O"}, table.States())
+	assert.Equal(3, table.Len())
+	_, ok := table.ID("?")
+	assert.False(ok)
+	id, ok := table.ID("O")
+	assert.True(ok)
+	assert.Equal(StateID(2), id)
+}
+
+func TestStateTable_Concurrent(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	table := NewStateTable()
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		go func() {
+			for _, state := range []State{"a", "b", "c", "d", "e"} {
+				table.Intern(state)
+			}
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+	assert.Equal(5, table.Len())
+	for id, state := range table.States() {
+		assert.Equal(StateID(id), table.Intern(state))
+	}
+}
+
+func TestStateGrid(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	grid := NewStateGrid(3, 2, NewStateTable("-"))
+	assert.Equal(State("-"), grid.At(2, 1))
+	grid.Set(2, 1, "X")
+	grid.Set(0, 0, "O")
+	assert.Equal(State("X"), grid.At(2, 1))
+	assert.Equal(State("O"), grid.At(0, 0))
+	assert.Equal(StateID(1), grid.IDAt(2, 1))
+	grid.SetID(1, 0, grid.IDAt(2, 1))
+	assert.Equal(State("X"), grid.At(1, 0))
+
+	// The default table starts with the empty state
+	assert.Equal(State(""), NewStateGrid(1, 1, nil).At(0, 0))
+}
+
+func TestStateGrid_JSON(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	grid := NewStateGrid(2, 2, nil)
+	grid.Set(0, 1, "X")
+	grid.Set(1, 1, "O")
+	data, err := json.Marshal(grid)
+	assert.Nil(err)
+
+	restored := &StateGrid{}
+	assert.Nil(json.Unmarshal(data, restored))
+	assert.Equal(2, restored.Width)
+	assert.Equal(2, restored.Height)
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			assert.Equal(grid.At(x, y), restored.At(x, y))
+		}
+	}
+
+	assert.NotNil(json.Unmarshal([]byte(`{"width":2,"height":2,"states":[""],"cells":[0,0,0]}`), restored))
+	assert.NotNil(json.Unmarshal([]byte(`{"width":1,"height":1,"states":[""],"cells":[1]}`), restored))
+	// With "X" in twice, "O" would come back as ID 1, which is "X"
+	err = json.Unmarshal([]byte(`{"width":1,"height":1,"states":["","X","X","O"],"cells":[3]}`), restored)
+	assert.NotNil(err)
+	assert.Equal(State("X"), restored.At(0, 1))
+}
@@ -0,0 +1,186 @@
+package cellaut
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// latencyBuckets is how many buckets a LatencyHistogram has. Bucket i counts latencies of less
+// than 2^i nanoseconds that didn't fit in bucket i-1, so the last one goes up to about 9 minutes,
+// and anything longer goes in it too.
+const latencyBuckets = 40
+
+/*
+LatencyHistogram counts how long states sent between neighbors take to be received, in buckets that
+double in size, from a nanosecond on up. It's how we find out where a tick's time goes while the
+cells are talking to each other, which is what any change to how they synchronize has to improve
+on.
+
+It's given to a Ticker in its Latency field, or made by a Simulation with MeasureLatency. It's safe
+for concurrent use, and cheap enough to leave on, apart from the cells having to look at the clock.
+*/
+type LatencyHistogram struct {
+	buckets [latencyBuckets]int64
+	count   int64
+	total   int64
+	max     int64
+}
+
+/*
+LatencyBucket is one bucket of a LatencyHistogram: how many latencies were under UpperBound, and not
+under the bucket before's.
+*/
+type LatencyBucket struct {
+	UpperBound time.Duration
+	Count      int64
+}
+
+/*
+NewLatencyHistogram returns an empty *LatencyHistogram.
+*/
+func NewLatencyHistogram() *LatencyHistogram {
+	return &LatencyHistogram{}
+}
+
+/*
+Observe counts one latency. Negative ones, which a clock that's been set back can make, count as 0.
+*/
+func (histogram *LatencyHistogram) Observe(latency time.Duration) {
+	ns := int64(latency)
+	if ns < 0 {
+		ns = 0
+	}
+	bucket := minInt(bits.Len64(uint64(ns)), latencyBuckets-1)
+	atomic.AddInt64(&histogram.buckets[bucket], 1)
+	atomic.AddInt64(&histogram.count, 1)
+	atomic.AddInt64(&histogram.total, ns)
+	for {
+		max := atomic.LoadInt64(&histogram.max)
+		if ns <= max || atomic.CompareAndSwapInt64(&histogram.max, max, ns) {
+			return
+		}
+	}
+}
+
+/*
+Count returns how many latencies have been counted.
+*/
+func (histogram *LatencyHistogram) Count() int64 {
+	return atomic.LoadInt64(&histogram.count)
+}
+
+/*
+Mean returns the mean latency, or 0 if none have been counted.
+*/
+func (histogram *LatencyHistogram) Mean() time.Duration {
+	count := histogram.Count()
+	if count == 0 {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&histogram.total) / count)
+}
+
+/*
+Max returns the longest latency counted.
+*/
+func (histogram *LatencyHistogram) Max() time.Duration {
+	return time.Duration(atomic.LoadInt64(&histogram.max))
+}
+
+/*
+Quantile returns the upper bound of the bucket that the q quantile falls in, so Quantile(0.99) is a
+latency that at least 99% of them were under. It's never more than Max, and it's 0 if no latencies
+have been counted.
+*/
+func (histogram *LatencyHistogram) Quantile(q float64) time.Duration {
+	buckets := histogram.Buckets()
+	var count int64
+	for _, bucket := range buckets {
+		count += bucket.Count
+	}
+	if count == 0 {
+		return 0
+	}
+	target := int64(q*float64(count) + 0.5)
+	var seen int64
+	for _, bucket := range buckets {
+		seen += bucket.Count
+		if seen >= target && seen > 0 {
+			if max := histogram.Max(); bucket.UpperBound > max {
+				return max
+			}
+			return bucket.UpperBound
+		}
+	}
+	return histogram.Max()
+}
+
+/*
+Buckets returns every bucket of the histogram, in order, empty ones included.
+*/
+func (histogram *LatencyHistogram) Buckets() []LatencyBucket {
+	buckets := make([]LatencyBucket, latencyBuckets)
+	for i := range buckets {
+		buckets[i] = LatencyBucket{UpperBound: time.Duration(int64(1) << uint(i)), Count: atomic.LoadInt64(&histogram.buckets[i])}
+	}
+	return buckets
+}
+
+/*
+Reset empties the histogram. Latencies counted while it's being reset may or may not be kept.
+*/
+func (histogram *LatencyHistogram) Reset() {
+	for i := range histogram.buckets {
+		atomic.StoreInt64(&histogram.buckets[i], 0)
+	}
+	atomic.StoreInt64(&histogram.count, 0)
+	atomic.StoreInt64(&histogram.total, 0)
+	atomic.StoreInt64(&histogram.max, 0)
+}
+
+/*
+WriteText writes the histogram as a table, with a line for each bucket from the first to the last
+that isn't empty, and a bar to show how full it is.
+*/
+func (histogram *LatencyHistogram) WriteText(w io.Writer) error {
+	buckets := histogram.Buckets()
+	first, last := len(buckets), -1
+	var most int64
+	for i, bucket := range buckets {
+		if bucket.Count > 0 {
+			first, last = minInt(first, i), i
+		}
+		if bucket.Count > most {
+			most = bucket.Count
+		}
+	}
+	fmt.Fprintf(w, "%d states, mean %s, p50 %s, p99 %s, max %s\n", histogram.Count(), histogram.Mean(),
+		histogram.Quantile(0.5), histogram.Quantile(0.99), histogram.Max())
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for i := first; i <= last; i++ {
+		bar := make([]byte, int(buckets[i].Count*40/most))
+		for j := range bar {
+			bar[j] = '#'
+		}
+		fmt.Fprintf(tw, "< %s\t%d\t%s\n", buckets[i].UpperBound, buckets[i].Count, bar)
+	}
+	return tw.Flush()
+}
+
+/*
+Latency returns the Simulation's LatencyHistogram, or nil if it wasn't configured with
+MeasureLatency, or isn't using the ChannelEngine, which is the only one whose cells send each other
+their states as they go.
+*/
+func (sim *Simulation) Latency() *LatencyHistogram {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	if ticker, ok := sim.engine.(*Ticker); ok {
+		return ticker.Latency
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package cellaut
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	histogram := NewLatencyHistogram()
+	assert.Equal(time.Duration(0), histogram.Quantile(0.5))
+	for i := 0; i < 98; i++ {
+		histogram.Observe(3 * time.Microsecond)
+	}
+	histogram.Observe(-time.Second)
+	histogram.Observe(time.Millisecond)
+	assert.Equal(int64(100), histogram.Count())
+	assert.Equal(time.Millisecond, histogram.Max())
+	assert.Equal((98*3*time.Microsecond+time.Millisecond)/100, histogram.Mean())
+
+	// 3µs is between 2^11 and 2^12 ns, and the negative one counts as 0
+	buckets := histogram.Buckets()
+	assert.Equal(LatencyBucket{UpperBound: 4096 * time.Nanosecond, Count: 98}, buckets[12])
+	assert.Equal(int64(1), buckets[0].Count)
+	assert.Equal(4096*time.Nanosecond, histogram.Quantile(0.5))
+	assert.Equal(4096*time.Nanosecond, histogram.Quantile(0.99))
+	assert.Equal(time.Millisecond, histogram.Quantile(1))
+
+	var out bytes.Buffer
+	assert.Nil(histogram.WriteText(&out))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.True(strings.HasPrefix(lines[0], "100 states, mean "), lines[0])
+	// One line for each bucket from 0 to 2^20ns, which is where 1ms goes
+	assert.Len(lines, 1+21)
+	assert.True(strings.HasSuffix(lines[13], strings.Repeat("#", 40)), lines[13])
+
+	histogram.Reset()
+	assert.Equal(int64(0), histogram.Count())
+	assert.Equal(time.Duration(0), histogram.Max())
+}
+
+func TestSimulation_Latency(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	for _, engine := range []EngineKind{ChannelEngine, MultiplexedEngine} {
+		sim := NewSimulation()
+		grid := NewLifeGrid(3, 3)
+		for x := 0; x < 3; x++ {
+			grid.At(x, 1).SetState(LifeAlive)
+		}
+		assert.Nil(sim.Configure(SimulationConfig{World: grid, Engine: engine, MaxTicks: 5, MeasureLatency: true}))
+		assert.Nil(sim.Latency())
+		assert.Nil(sim.Start())
+		assert.Nil(sim.Wait())
+		if engine == MultiplexedEngine {
+			assert.Nil(sim.Latency())
+			continue
+		}
+		// Every cell tells its neighbors about its first state, which is 4 corners with 3 neighbors,
+		// 4 edges with 5, and the middle with 8. After that, the blinker changes the 4 edge cells
+		// every tick.
+		assert.Equal(int64(4*3+4*5+8+4*4*5), sim.Latency().Count())
+	}
+
+	// Without MeasureLatency, nobody looks at the clock
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: NewLifeGrid(2, 2), MaxTicks: 1}))
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	assert.Nil(sim.Latency())
+}
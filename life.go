@@ -0,0 +1,58 @@
+package cellaut
+
+import "fmt"
+
+// LifeAlive is the state of a live LifeCellAut. Dead ones are LifeDead, like in the multi-color
+// variants.
+const LifeAlive State = "X"
+
+/*
+LifeCellAut is a cell in Conway's Game of Life: it's born with exactly 3 live neighbors, survives
+with 2 or 3, and dies otherwise. Or, made with NewLifeLikeCellAut, in any other Life-like rule. It
+needs all 8 of its Moore neighbors, so a Grid of them should be built with WithDiagonals, as
+NewLifeGrid does.
+
+It's a RuleCellAut underneath, and runs under either engine the same way.
+*/
+type LifeCellAut struct {
+	*RuleCellAut
+}
+
+/*
+NewLifeCellAut returns a dead *LifeCellAut that's ready to be wired up.
+*/
+func NewLifeCellAut(i int) *LifeCellAut {
+	return &LifeCellAut{NewRuleCellAut(i, Life, LifeDead)}
+}
+
+/*
+NewLifeLikeCellAut returns a dead *LifeCellAut that follows rule instead of Conway's.
+*/
+func NewLifeLikeCellAut(i int, rule LifeLikeRule) *LifeCellAut {
+	return &LifeCellAut{NewRuleCellAut(i, rule.Rule(), LifeDead)}
+}
+
+/*
+NewLifeGrid builds a width×height Grid of dead LifeCellAuts, each wired to all 8 of its neighbors.
+Cells are brought to life with SetState(LifeAlive) before the Simulation starts. Other
+GridOptions, like WithTopology, are passed on to NewGrid.
+*/
+func NewLifeGrid(width, height int, options ...GridOption) *Grid {
+	options = append([]GridOption{WithDiagonals()}, options...)
+	return NewGrid(width, height, func(x, y int) CellAut { return NewLifeCellAut(y*width + x) }, options...)
+}
+
+/*
+NewLifeLikeGrid is NewLifeGrid for some other Life-like rule, like one from ParseRulestring.
+*/
+func NewLifeLikeGrid(width, height int, rule LifeLikeRule, options ...GridOption) *Grid {
+	options = append([]GridOption{WithDiagonals()}, options...)
+	return NewGrid(width, height, func(x, y int) CellAut { return NewLifeLikeCellAut(y*width+x, rule) }, options...)
+}
+
+/*
+String identifies the LifeCellAut in logs and errors.
+*/
+func (aut *LifeCellAut) String() string {
+	return fmt.Sprintf("life#%d", aut.ID)
+}
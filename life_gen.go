@@ -0,0 +1,31 @@
+// Code generated by rulegen -rule B3/S23 -name Life; DO NOT EDIT.
+
+package cellaut
+
+// lifeTable[self][n] is the next state of a cell in state self (0 for "-", 1 for "X")
+// with n live neighbors.
+var lifeTable = [2][9]State{
+	{"-", "-", "-", "X", "-", "-", "-", "-", "-"},
+	{"-", "-", "X", "X", "-", "-", "-", "-", "-"},
+}
+
+/*
+Life is the Life-like rule B3/S23.
+
+Any state other than "X" counts as dead.
+*/
+func Life(self State, neighbors map[NeighborIndex]State) State {
+	n := 0
+	for _, neighbor := range neighbors {
+		if neighbor == "X" {
+			n++
+		}
+	}
+	if n >= len(lifeTable[0]) {
+		return "-"
+	}
+	if self == "X" {
+		return lifeTable[1][n]
+	}
+	return lifeTable[0][n]
+}
@@ -0,0 +1,113 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gliderCells are the live cells of a glider heading down and to the right, with y up
+var gliderCells = [][2]int{{1, 2}, {2, 1}, {0, 0}, {1, 0}, {2, 0}}
+
+/*
+runLifeGrid brings the cells at (x+dx, y+dy) to life for each of cells, runs the grid for the
+given number of ticks, and returns what's alive at the end.
+*/
+func runLifeGrid(t *testing.T, grid *Grid, engine EngineKind, cells [][2]int, dx, dy int, ticks int64) map[[2]int]bool {
+	for _, cell := range cells {
+		grid.At(cell[0]+dx, cell[1]+dy).SetState(LifeAlive)
+	}
+	sim := NewSimulation()
+	assert.Nil(t, sim.Configure(SimulationConfig{World: grid, Engine: engine, MaxTicks: ticks}))
+	assert.Nil(t, sim.Start())
+	assert.Nil(t, sim.Wait())
+	alive := make(map[[2]int]bool)
+	width, height := grid.Size()
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if grid.At(x, y).GetState() == LifeAlive {
+				alive[[2]int{x, y}] = true
+			}
+		}
+	}
+	return alive
+}
+
+/*
+shifted returns cells moved by (dx, dy), as a set.
+*/
+func shifted(cells [][2]int, dx, dy int) map[[2]int]bool {
+	set := make(map[[2]int]bool)
+	for _, cell := range cells {
+		set[[2]int{cell[0] + dx, cell[1] + dy}] = true
+	}
+	return set
+}
+
+func TestLifeCellAut_Glider(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	for _, engine := range []EngineKind{ChannelEngine, MultiplexedEngine} {
+		// The first tick commits the starting pattern, and each one after that is a generation.
+		// A glider moves 1 cell diagonally every 4 generations.
+		alive := runLifeGrid(t, NewLifeGrid(12, 12), engine, gliderCells, 2, 8, 9)
+		assert.Equal(shifted(gliderCells, 4, 6), alive, "engine %s", engine)
+
+		// On a torus, it comes back round from the bottom right to the top left
+		alive = runLifeGrid(t, NewLifeGrid(6, 6, WithTopology(Torus)), engine, gliderCells, 0, 0, 25)
+		assert.Equal(shifted(gliderCells, 0, 0), alive, "engine %s", engine)
+	}
+}
+
+func TestLifeCellAut_Blinker(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	horizontal := [][2]int{{1, 2}, {2, 2}, {3, 2}}
+	vertical := [][2]int{{2, 1}, {2, 2}, {2, 3}}
+	assert.Equal(shifted(vertical, 0, 0), runLifeGrid(t, NewLifeGrid(5, 5), ChannelEngine, horizontal, 0, 0, 2))
+	assert.Equal(shifted(horizontal, 0, 0), runLifeGrid(t, NewLifeGrid(5, 5), ChannelEngine, horizontal, 0, 0, 3))
+
+	// It matches the rule as stepGrid runs it
+	grid := NewStateGrid(5, 5, NewStateTable(LifeDead))
+	for _, cell := range horizontal {
+		grid.Set(cell[0], cell[1], LifeAlive)
+	}
+	grid = stepGrid(grid, Life, mooreNeighborhood)
+	for _, cell := range vertical {
+		assert.Equal(LifeAlive, grid.At(cell[0], cell[1]))
+	}
+	assert.Equal("life#7", NewLifeCellAut(7).String())
+}
+
+func TestLifeLikeCellAut(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// HighLife's replicator copies itself: after 12 generations there are two of it
+	highLife, err := ParseRulestring("B36/S23")
+	assert.Nil(err)
+	replicator := [][2]int{{2, 4}, {3, 4}, {4, 4}, {1, 3}, {4, 3}, {0, 2}, {4, 2}, {0, 1}, {3, 1}, {0, 0}, {1, 0}, {2, 0}}
+	grid := NewStateGrid(24, 24, NewStateTable(LifeDead))
+	for _, cell := range replicator {
+		grid.Set(cell[0]+10, cell[1]+10, LifeAlive)
+	}
+	for tick := 0; tick < 12; tick++ {
+		grid = stepGrid(grid, highLife.Rule(), mooreNeighborhood)
+	}
+	want := make(map[[2]int]bool)
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			if grid.At(x, y) == LifeAlive {
+				want[[2]int{x, y}] = true
+			}
+		}
+	}
+	alive := runLifeGrid(t, NewLifeLikeGrid(24, 24, highLife), ChannelEngine, replicator, 10, 10, 13)
+	assert.Equal(want, alive)
+	assert.Len(want, 2*len(replicator))
+
+	// Under Life, the same start does something else
+	assert.NotEqual(want, runLifeGrid(t, NewLifeGrid(24, 24), ChannelEngine, replicator, 10, 10, 13))
+}
@@ -0,0 +1,183 @@
+package cellaut
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+)
+
+/*
+LightConeConfig says how CheckLightCone should go about it.
+*/
+type LightConeConfig struct {
+	Width, Height int
+	// Ticks is how long each run goes on for
+	Ticks int
+	// Trials is how many random starts to try. Each one changes a different random cell.
+	Trials int
+	// States are what the random starts are made of. The first is the empty state. Defaults to
+	// "-" and "X".
+	States []State
+	// Density is the fraction of cells that start in a State other than the first
+	Density float64
+	// Radius is how far a cell can see: the most any neighbor is away from it in x or y. Defaults
+	// to 1.
+	Radius int
+	// Wrap says to measure distances around the edges of the grid, for step functions that wrap
+	Wrap bool
+	Seed int64
+}
+
+/*
+LightConeViolation is a cell that found out about a change sooner than it could have.
+*/
+type LightConeViolation struct {
+	Tick int
+	// X and Y are where the cell that differed was, and FromX and FromY where the change was made
+	X, Y, FromX, FromY int
+	// Distance is how far apart they are, in the same terms as LightConeConfig.Radius
+	Distance int
+}
+
+func (violation *LightConeViolation) Error() string {
+	return fmt.Sprintf(
+		"tick %d: (%d, %d) changed because of (%d, %d), %d cells away",
+		violation.Tick, violation.X, violation.Y, violation.FromX, violation.FromY, violation.Distance,
+	)
+}
+
+/*
+CheckLightCone makes sure that advance, which steps a whole grid on by one tick, never lets
+information get further than Radius cells per tick. It's a sanity check for engines and schedulers,
+which could otherwise let a cell see its neighbors' next states too early without anything looking
+obviously wrong.
+
+For each trial, it makes a random grid, changes one random cell in a copy, and runs the two in
+lockstep. After t ticks, the only cells that may differ are the ones within t×Radius of the change.
+The first one that's further away comes back as a *LightConeViolation.
+*/
+func CheckLightCone(advance func(*StateGrid) *StateGrid, config LightConeConfig) error {
+	if config.Width < 1 || config.Height < 1 || config.Ticks < 1 || config.Trials < 1 {
+		return fmt.Errorf("width, height, ticks and trials must all be at least 1")
+	}
+	states := config.States
+	if len(states) == 0 {
+		states = []State{"-", "X"}
+	}
+	if len(states) < 2 {
+		return fmt.Errorf("need at least 2 states to perturb a cell")
+	}
+	radius := config.Radius
+	if radius == 0 {
+		radius = 1
+	}
+	rng := rand.New(rand.NewSource(config.Seed))
+	for trial := 0; trial < config.Trials; trial++ {
+		reference := randomStateGrid(config.Width, config.Height, states, config.Density, rng)
+		perturbed := cloneStateGrid(reference)
+		fromX, fromY := rng.Intn(config.Width), rng.Intn(config.Height)
+		perturb(perturbed, fromX, fromY, states, rng)
+
+		pair := &lockstep{reference: reference, perturbed: perturbed, advance: advance}
+		for tick := 1; tick <= config.Ticks; tick++ {
+			pair.step()
+			for y := 0; y < config.Height; y++ {
+				for x := 0; x < config.Width; x++ {
+					if pair.reference.At(x, y) == pair.perturbed.At(x, y) {
+						continue
+					}
+					distance := chebyshevDistance(x-fromX, y-fromY, config.Width, config.Height, config.Wrap)
+					if distance > tick*radius {
+						return &LightConeViolation{Tick: tick, X: x, Y: y, FromX: fromX, FromY: fromY, Distance: distance}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+/*
+chebyshevDistance returns how far apart two cells dx and dy apart are, counting diagonal steps as
+1, which is how far a Moore neighborhood reaches. If wrap is set, it's the shorter way round the
+grid.
+*/
+func chebyshevDistance(dx, dy, width, height int, wrap bool) int {
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	if wrap {
+		if width-dx < dx {
+			dx = width - dx
+		}
+		if height-dy < dy {
+			dy = height - dy
+		}
+	}
+	if dx > dy {
+		return dx
+	}
+	return dy
+}
+
+/*
+neighborhoodRadius returns the furthest any of a neighborhood's offsets reaches in x or y.
+*/
+func neighborhoodRadius(neighborhood map[NeighborIndex][2]int) int {
+	radius := 0
+	for _, offset := range neighborhood {
+		if distance := chebyshevDistance(offset[0], offset[1], 0, 0, false); distance > radius {
+			radius = distance
+		}
+	}
+	return radius
+}
+
+/*
+lightConeCommand implements `cellaut lightcone [--rule B3/S23] [--width N] [--height N]
+[--ticks N] [--trials N] [--density P] [--seed N]`, which checks the grid stepper with a rule.
+*/
+func lightConeCommand(args []string) error {
+	fs := flag.NewFlagSet("lightcone", flag.ContinueOnError)
+	ruleSpec := fs.String("rule", "B3/S23", "rulestring, or registered rule name and options, like 'life-like rule=B36/S23'")
+	width := fs.Int("width", 32, "width of the grid")
+	height := fs.Int("height", 32, "height of the grid")
+	ticks := fs.Int("ticks", 10, "how many ticks to run each trial")
+	trials := fs.Int("trials", 20, "how many random starts to try")
+	density := fs.Float64("density", 0.3, "fraction of cells alive at the start")
+	seed := fs.Int64("seed", 0, "random seed; defaults to the time")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	rule, err := lookupRuleSpec(*ruleSpec)
+	if err != nil {
+		return err
+	}
+	err = CheckLightCone(func(grid *StateGrid) *StateGrid {
+		return stepGrid(grid, rule, mooreNeighborhood)
+	}, LightConeConfig{
+		Width:   *width,
+		Height:  *height,
+		Ticks:   *ticks,
+		Trials:  *trials,
+		Density: *density,
+		Radius:  neighborhoodRadius(mooreNeighborhood),
+		Seed:    *seed,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "rule %s, seed %d: nothing outran the light cone\n", *ruleSpec, *seed)
+	return nil
+}
@@ -0,0 +1,67 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckLightCone(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	config := LightConeConfig{Width: 16, Height: 16, Ticks: 6, Trials: 10, Density: 0.4, Seed: 1}
+	life, _ := lifeLikeRule("B3/S23")
+	assert.NoError(CheckLightCone(func(grid *StateGrid) *StateGrid {
+		return stepGrid(grid, life, mooreNeighborhood)
+	}, config))
+
+	// A stepper that lets each row see the row below it after it's already been updated, like a
+	// scheduler that doesn't wait for everyone to commit, leaks a change all the way up in 1 tick.
+	// Each cell here is the XOR of itself and the cell below, so any change gets through.
+	leaky := func(grid *StateGrid) *StateGrid {
+		next := cloneStateGrid(grid)
+		for y := 1; y < grid.Height; y++ {
+			for x := 0; x < grid.Width; x++ {
+				next.SetID(x, y, grid.IDAt(x, y)^next.IDAt(x, y-1))
+			}
+		}
+		return next
+	}
+	err := CheckLightCone(leaky, config)
+	violation, ok := err.(*LightConeViolation)
+	if assert.True(ok, "%v", err) {
+		assert.Equal(1, violation.Tick)
+		assert.Equal(violation.X, violation.FromX)
+		assert.True(violation.Distance > 1)
+		assert.Contains(violation.Error(), "tick 1: ")
+	}
+
+	// A radius-2 rule is fine with Radius set, and not without
+	wide := func(grid *StateGrid) *StateGrid {
+		next := cloneStateGrid(grid)
+		for y := 0; y < grid.Height; y++ {
+			for x := 0; x < grid.Width; x++ {
+				next.SetID(x, y, grid.IDAt((x+2)%grid.Width, y))
+			}
+		}
+		return next
+	}
+	config.Wrap = true
+	assert.Error(CheckLightCone(wide, config))
+	config.Radius = 2
+	assert.NoError(CheckLightCone(wide, config))
+
+	assert.Error(CheckLightCone(wide, LightConeConfig{Width: 4, Height: 4, Ticks: 1}))
+}
+
+func TestChebyshevDistance(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal(3, chebyshevDistance(-3, 2, 10, 10, false))
+	assert.Equal(9, chebyshevDistance(9, 0, 10, 10, false))
+	assert.Equal(1, chebyshevDistance(9, 0, 10, 10, true))
+	assert.Equal(1, neighborhoodRadius(mooreNeighborhood))
+	assert.Equal(1, neighborhoodRadius(lineNeighborhood))
+}
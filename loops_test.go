@@ -0,0 +1,71 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLangtonsLoops(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	table := LangtonsLoops()
+	assert.Equal("Langtons-Loops", table.Name)
+	assert.Equal(8, table.States)
+	assert.Equal(219, len(table.transitions))
+
+	grid := NewStateGrid(60, 60, NewStateTable("-"))
+	assert.Nil(placePattern(LangtonsLoopSeed, 30, 20)(grid, nil))
+	rule := table.Rule()
+	loops := make(map[int]int)
+	for tick := 1; tick <= 151; tick++ {
+		grid = stepGrid(grid, rule, vonNeumannNeighborhood)
+		loops[tick] = countLoops(grid)
+	}
+	// The daughter pinches off at tick 128, and both loops are still going at 151
+	assert.Equal(1, loops[127])
+	assert.Equal(2, loops[128])
+	assert.Equal(2, loops[151])
+	for _, size := range occupiedClusters(grid).Sizes {
+		assert.True(size > 80, "loop of %d cells", size)
+	}
+}
+
+func TestBuiltinRuleTable(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Every built-in table parses, under the name in its @RULE line
+	for _, name := range BuiltinRuleTableNames() {
+		table, ok := BuiltinRuleTable(name)
+		assert.True(ok, name)
+		assert.Equal(name, table.Name)
+	}
+	assert.Contains(BuiltinRuleTableNames(), "Langtons-Loops")
+	_, ok := BuiltinRuleTable("Nonexistent")
+	assert.False(ok)
+}
+
+/*
+occupiedClusters labels the clusters of cells that aren't empty.
+*/
+func occupiedClusters(grid *StateGrid) *Clusters {
+	occupied := NewStateGrid(grid.Width, grid.Height, NewStateTable("-", "X"))
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			if grid.At(x, y) != "-" {
+				occupied.Set(x, y, "X")
+			}
+		}
+	}
+	return LabelClusters(occupied, "X")
+}
+
+/*
+countLoops returns how many separate loops there are, counting each cluster of non-empty cells as a
+loop.
+*/
+func countLoops(grid *StateGrid) int {
+	return len(occupiedClusters(grid).Sizes)
+}
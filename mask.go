@@ -0,0 +1,69 @@
+package cellaut
+
+/*
+Mask picks out a region of a grid: it returns whether (x, y) is inside.
+*/
+type Mask func(x, y int) bool
+
+/*
+CircleMask is the disc of cells no further than radius from (cx, cy).
+*/
+func CircleMask(cx, cy, radius float64) Mask {
+	return func(x, y int) bool {
+		dx, dy := float64(x)-cx, float64(y)-cy
+		return dx*dx+dy*dy <= radius*radius
+	}
+}
+
+/*
+RectMask is the cells inside rect.
+*/
+func RectMask(rect Rect) Mask {
+	return rect.Contains
+}
+
+/*
+PatternMask is the cells under pattern's non-empty cells, with the pattern's bottom left cell at
+(pattern.X, pattern.Y), for regions that are easier to draw than to describe.
+*/
+func PatternMask(pattern *Pattern) Mask {
+	return func(x, y int) bool {
+		px, py := x-pattern.X, y-pattern.Y
+		return (Rect{Width: pattern.Width, Height: pattern.Height}).Contains(px, py) && pattern.IDAt(px, py) != 0
+	}
+}
+
+/*
+Not is everywhere the Mask isn't.
+*/
+func (mask Mask) Not() Mask {
+	return func(x, y int) bool { return !mask(x, y) }
+}
+
+/*
+And is where both Masks are.
+*/
+func (mask Mask) And(other Mask) Mask {
+	return func(x, y int) bool { return mask(x, y) && other(x, y) }
+}
+
+/*
+NewMaskedGrid builds a width×height Grid of RuleCellAuts that follow inside where mask is, and
+outside everywhere else, all starting in state, for composite worlds like Life inside a circle and
+Seeds outside it. Cells on either side of the edge see each other as neighbors, as usual.
+
+If outside is nil, cells outside the mask are inert: they stay in whatever state they start in,
+like NewConstantCellAut, but can still be given a state before the first tick.
+*/
+func NewMaskedGrid(width, height int, mask Mask, inside, outside Rule, state State, options ...GridOption) *Grid {
+	return NewGrid(width, height, func(x, y int) CellAut {
+		switch {
+		case mask(x, y):
+			return NewRuleCellAut(y*width+x, inside, state)
+		case outside == nil:
+			return NewConstantCellAut(y*width+x, state)
+		default:
+			return NewRuleCellAut(y*width+x, outside, state)
+		}
+	}, options...)
+}
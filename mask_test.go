@@ -0,0 +1,76 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMask(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	circle := CircleMask(5, 5, 2)
+	assert.True(circle(5, 7))
+	assert.True(circle(6, 6))
+	assert.False(circle(5, 8))
+	assert.False(circle(7, 7))
+	ring := CircleMask(5, 5, 2).And(CircleMask(5, 5, 1).Not())
+	assert.True(ring(5, 7))
+	assert.False(ring(5, 5))
+
+	pattern, err := ParsePattern("X.\nXX", nil)
+	assert.Nil(err)
+	pattern.X, pattern.Y = 3, 3
+	mask := PatternMask(pattern)
+	assert.True(mask(3, 4))
+	assert.False(mask(4, 4))
+	assert.False(mask(2, 3))
+	assert.True(RectMask(Rect{X: 1, Y: 1, Width: 2, Height: 2})(2, 2))
+}
+
+/*
+liveCellsAfter runs world for ticks ticks, and returns where its live cells are at the end.
+*/
+func liveCellsAfter(t *testing.T, world World, ticks int64) map[[2]int]bool {
+	var last *StateGrid
+	sim := NewSimulation()
+	assert.Nil(t, sim.Configure(SimulationConfig{World: world, MaxTicks: ticks + 1}))
+	sim.Subscribe(func(event TickEvent) { last = TakeSnapshot(event.World, nil) })
+	assert.Nil(t, sim.Start())
+	assert.Nil(t, sim.Wait())
+	cells := make(map[[2]int]bool)
+	for y := 0; y < last.Height; y++ {
+		for x := 0; x < last.Width; x++ {
+			if last.At(x, y) == LifeAlive {
+				cells[[2]int{x, y}] = true
+			}
+		}
+	}
+	return cells
+}
+
+func TestNewMaskedGrid(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Life on the left, and inert cells on the right, where a lone cell lives on
+	left := RectMask(Rect{Width: 6, Height: 6})
+	grid := NewMaskedGrid(12, 6, left, Life, nil, LifeDead, WithDiagonals())
+	for _, cell := range [][2]int{{1, 2}, {2, 2}, {3, 2}, {9, 2}} {
+		grid.At(cell[0], cell[1]).SetState(LifeAlive)
+	}
+	assert.Equal(map[[2]int]bool{{2, 1}: true, {2, 2}: true, {2, 3}: true, {9, 2}: true}, liveCellsAfter(t, grid, 1))
+
+	// Seeds on the right, where the same cell dies and a pair of cells makes four more
+	seeds, err := ParseRulestring("B2/S")
+	assert.Nil(err)
+	grid = NewMaskedGrid(12, 6, left, Life, seeds.Rule(), LifeDead, WithDiagonals())
+	for _, cell := range [][2]int{{1, 2}, {2, 2}, {3, 2}, {8, 2}, {9, 2}} {
+		grid.At(cell[0], cell[1]).SetState(LifeAlive)
+	}
+	assert.Equal(map[[2]int]bool{
+		{2, 1}: true, {2, 2}: true, {2, 3}: true,
+		{8, 1}: true, {9, 1}: true, {8, 3}: true, {9, 3}: true,
+	}, liveCellsAfter(t, grid, 1))
+}
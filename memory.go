@@ -0,0 +1,228 @@
+package cellaut
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"unsafe"
+)
+
+/*
+MemoryUser is anything that can say roughly how much memory it's using, in bytes.
+
+The numbers are estimates from the sizes of the things it holds on to, not measurements, so they're
+good for comparing settings and spotting what's growing, but they won't add up to what the Go
+runtime reports.
+*/
+type MemoryUser interface {
+	MemoryUsage() int64
+}
+
+/*
+SubsystemMemory is the estimated memory use of one part of a Simulation.
+*/
+type SubsystemMemory struct {
+	Subsystem string `json:"subsystem"`
+	Bytes     int64  `json:"bytes"`
+}
+
+const (
+	// goroutineStackBytes is a typical stack size for a goroutine that's been running for a while.
+	// Goroutines start smaller and grow as needed.
+	goroutineStackBytes = 8 << 10
+	// chanHeaderBytes is about how big a channel is, not counting its buffer
+	chanHeaderBytes = 96
+	// mapEntryBytes is about how much a map spends per entry on top of the key and value
+	mapEntryBytes     = 8
+	stringHeaderBytes = int64(unsafe.Sizeof(""))
+	pointerBytes      = int64(unsafe.Sizeof(uintptr(0)))
+)
+
+/*
+chanBytes estimates the size of a channel with the given buffer and element size.
+*/
+func chanBytes(buffer int, elemBytes int64) int64 {
+	return chanHeaderBytes + int64(buffer)*elemBytes
+}
+
+/*
+Memory estimates how much memory each part of the Simulation is using: the cells, the engine that
+runs them (including the cells' goroutines, for the ChannelEngine), the layers, and the buffered
+errors. Only cells and layers that are MemoryUsers are counted.
+
+Memory reads the World and the layers, so like anything else that reads them, it must be called
+from a subscriber, or while the Simulation isn't running. Before Configure, it returns nil.
+*/
+func (sim *Simulation) Memory() []SubsystemMemory {
+	sim.mu.Lock()
+	engine, configured := sim.engine, sim.configured
+	sim.mu.Unlock()
+	if !configured {
+		return nil
+	}
+
+	var cells int64
+	width, height := sim.config.World.Size()
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if user, ok := sim.config.World.At(x, y).(MemoryUser); ok {
+				cells += user.MemoryUsage()
+			}
+		}
+	}
+	var engineBytes int64
+	if user, ok := engine.(MemoryUser); ok {
+		engineBytes = user.MemoryUsage()
+	}
+	var layers int64
+	for _, layer := range sim.config.Layers {
+		if user, ok := layer.(MemoryUser); ok {
+			layers += user.MemoryUsage()
+		}
+	}
+	return []SubsystemMemory{
+		{"cells", cells},
+		{"engine", engineBytes},
+		{"layers", layers},
+		{"errors", sim.supervisor.MemoryUsage()},
+	}
+}
+
+/*
+WriteMemory writes usages as a table, with a total at the end.
+*/
+func WriteMemory(w io.Writer, usages []SubsystemMemory) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	var total int64
+	for _, usage := range usages {
+		fmt.Fprintf(tw, "%s\t%s\n", usage.Subsystem, formatBytes(usage.Bytes))
+		total += usage.Bytes
+	}
+	fmt.Fprintf(tw, "total\t%s\n", formatBytes(total))
+	return tw.Flush()
+}
+
+/*
+formatBytes formats a byte count like 1.5 MiB.
+*/
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+/*
+MemoryUsage estimates the memory used by the GooCellAut and the inbox its neighbors send it their
+states on.
+*/
+func (aut *GooCellAut) MemoryUsage() int64 {
+	aut.mu.Lock()
+	defer aut.mu.Unlock()
+	bytes := int64(unsafe.Sizeof(*aut))
+	// A CellAut in a map or a slice is an interface, which is two pointers
+	entryBytes := int64(unsafe.Sizeof(NeighborIndex(0))) + 2*pointerBytes + mapEntryBytes
+	bytes += int64(len(aut.neighbors)) * entryBytes
+	bytes += int64(cap(aut.recipients)) * int64(unsafe.Sizeof(recipient{}))
+	if aut.inbox != nil {
+		bytes += chanBytes(cap(aut.inbox), int64(unsafe.Sizeof(NeighborMessage{})))
+	}
+	return bytes
+}
+
+/*
+MemoryUsage estimates the memory used by the Ticker and the goroutines of the CellAuts it started,
+not counting the CellAuts themselves.
+*/
+func (ticker *Ticker) MemoryUsage() int64 {
+	ticker.mu.Lock()
+	defer ticker.mu.Unlock()
+	bytes := int64(unsafe.Sizeof(*ticker))
+	bytes += int64(cap(ticker.destinations)+cap(ticker.cellCallbacks)+cap(ticker.awake)) * pointerBytes
+	perCell := goroutineStackBytes + int64(unsafe.Sizeof(CellAutCallbacks{})) +
+		chanBytes(1, 8) + chanBytes(0, 0) + 2*pointerBytes + mapEntryBytes
+	return bytes + int64(len(ticker.startedCallbacks()))*perCell
+}
+
+/*
+MemoryUsage estimates the memory used by the Multiplexer and its workers, not counting the cells
+themselves.
+*/
+func (mux *Multiplexer) MemoryUsage() int64 {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	bytes := int64(unsafe.Sizeof(*mux))
+	bytes += int64(len(mux.work)) * (goroutineStackBytes + chanBytes(0, 0))
+	perCell := int64(unsafe.Sizeof(muxCell{})) + 2*pointerBytes + mapEntryBytes
+	return bytes + int64(len(mux.cells)+len(mux.pending))*perCell
+}
+
+/*
+MemoryUsage estimates the memory used by the Supervisor's buffer of errors.
+*/
+func (sup *Supervisor) MemoryUsage() int64 {
+	return chanBytes(cap(sup.errors), pointerBytes) + int64(len(sup.errors))*int64(unsafe.Sizeof(CellError{}))
+}
+
+/*
+MemoryUsage estimates the memory used by the StateGrid, including its StateTable.
+*/
+func (grid *StateGrid) MemoryUsage() int64 {
+	return int64(unsafe.Sizeof(*grid)) + int64(cap(grid.cells))*int64(unsafe.Sizeof(StateID(0))) +
+		grid.Table.MemoryUsage()
+}
+
+/*
+MemoryUsage estimates the memory used by the StateTable.
+*/
+func (table *StateTable) MemoryUsage() int64 {
+	table.mu.RLock()
+	defer table.mu.RUnlock()
+	bytes := int64(unsafe.Sizeof(*table))
+	for _, state := range table.states {
+		// Each State is in both the slice and the map, sharing its bytes
+		bytes += 2*stringHeaderBytes + int64(len(state)) + int64(unsafe.Sizeof(StateID(0))) + mapEntryBytes
+	}
+	return bytes
+}
+
+/*
+MemoryUsage estimates the memory used by the Field.
+*/
+func (field *Field) MemoryUsage() int64 {
+	bytes := int64(unsafe.Sizeof(*field)) + int64(cap(field.values)+cap(field.scratch))*8
+	for _, row := range field.Kernel {
+		bytes += int64(unsafe.Sizeof(row)) + int64(cap(row))*8
+	}
+	return bytes
+}
+
+/*
+MemoryUsage estimates the memory used by the AgentLayer's agents.
+*/
+func (layer *AgentLayer) MemoryUsage() int64 {
+	bytes := int64(unsafe.Sizeof(*layer)) + int64(cap(layer.agents))*pointerBytes
+	for _, agent := range layer.agents {
+		bytes += int64(unsafe.Sizeof(*agent)) + int64(len(agent.Kind)+len(agent.State))
+	}
+	return bytes
+}
+
+/*
+MemoryUsage estimates the memory used by every node the QuadUniverse has made, including the ones
+no longer reachable from the root.
+*/
+func (universe *QuadUniverse) MemoryUsage() int64 {
+	nodeBytes := int64(unsafe.Sizeof(QuadNode{}))
+	keyBytes := int64(unsafe.Sizeof(quadKey{}))
+	return int64(unsafe.Sizeof(*universe)) + int64(len(universe.nodes))*(nodeBytes+keyBytes+pointerBytes+mapEntryBytes) +
+		int64(len(universe.leaves)+len(universe.empty))*(nodeBytes+pointerBytes) +
+		int64(len(universe.counts))*(int64(unsafe.Sizeof(quadCountKey{}))+8+mapEntryBytes) +
+		int64(len(universe.hashes))*(pointerBytes+8+mapEntryBytes)
+}
@@ -0,0 +1,66 @@
+package cellaut
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulation_Memory(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	sim := NewSimulation()
+	assert.Nil(sim.Memory())
+
+	field, err := NewField(10, 10, NewDiffusionKernel(0.1), 0)
+	assert.Nil(err)
+	assert.Nil(sim.Configure(SimulationConfig{World: newGooRow(4), MaxTicks: 1, Layers: []Layer{field}}))
+	before := sim.Memory()
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	after := sim.Memory()
+
+	assert.Equal([]string{"cells", "engine", "layers", "errors"}, []string{
+		after[0].Subsystem, after[1].Subsystem, after[2].Subsystem, after[3].Subsystem,
+	})
+	// The cells are wired up before the simulation starts; the engine only exists after
+	assert.True(before[0].Bytes > 0)
+	assert.Equal(int64(0), before[1].Bytes)
+	assert.True(after[1].Bytes >= 4*goroutineStackBytes)
+	assert.True(after[2].Bytes >= 2*100*8)
+	assert.Equal(before[3].Bytes, after[3].Bytes)
+
+	var buf bytes.Buffer
+	assert.Nil(WriteMemory(&buf, after))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(lines, 5)
+	assert.True(strings.HasPrefix(lines[4], "total "))
+}
+
+func TestStateGrid_MemoryUsage(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	small := NewStateGrid(10, 10, NewStateTable("-", "X"))
+	big := NewStateGrid(100, 100, NewStateTable("-", "X"))
+	// 2 bytes per cell
+	assert.Equal(int64(2*(100*100-10*10)), big.MemoryUsage()-small.MemoryUsage())
+
+	table := NewStateTable("-")
+	before := table.MemoryUsage()
+	table.Intern("XYZ")
+	assert.Equal(int64(2*unsafe.Sizeof("")+3+2+mapEntryBytes), table.MemoryUsage()-before)
+}
+
+func TestFormatBytes(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal("512 B", formatBytes(512))
+	assert.Equal("1.5 KiB", formatBytes(1536))
+	assert.Equal("3.0 MiB", formatBytes(3<<20))
+}
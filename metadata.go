@@ -0,0 +1,178 @@
+package cellaut
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+Version is the version of cellaut, which is stamped into what it writes. Release builds set it
+with -ldflags "-X github.com/danslimmon/cellaut.Version=v1.2.3".
+*/
+var Version = "devel"
+
+/*
+RunMetadata says which run wrote a file, so that a PNG or an RLE file found lying around can be
+traced back to how it was made. It's stamped into PNG text chunks, GIF comments, RLE comment lines
+and .caz archives.
+*/
+type RunMetadata struct {
+	RunID string `json:"run_id"`
+	// ConfigHash is the SHA-256 of the run's configuration, as JSON
+	ConfigHash string `json:"config_hash,omitempty"`
+	Rule       string `json:"rule,omitempty"`
+	Seed       int64  `json:"seed"`
+	Version    string `json:"version"`
+}
+
+/*
+NewRunMetadata returns the *RunMetadata for a new run, with a random RunID. config is hashed as
+JSON, so two runs with the same config have the same ConfigHash; maps are fine, since their keys
+are sorted.
+*/
+func NewRunMetadata(rule string, seed int64, config interface{}) (*RunMetadata, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return &RunMetadata{
+		RunID:      hex.EncodeToString(id),
+		ConfigHash: hex.EncodeToString(sum[:]),
+		Rule:       rule,
+		Seed:       seed,
+		Version:    Version,
+	}, nil
+}
+
+/*
+fields returns the RunMetadata as names and values, in a fixed order, leaving out empty ones.
+*/
+func (meta *RunMetadata) fields() [][2]string {
+	var fields [][2]string
+	for _, field := range [][2]string{
+		{"run-id", meta.RunID},
+		{"config-hash", meta.ConfigHash},
+		{"rule", meta.Rule},
+		{"seed", strconv.FormatInt(meta.Seed, 10)},
+		{"version", meta.Version},
+	} {
+		if field[1] != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+/*
+String returns the RunMetadata as "name=value" pairs, like "run-id=8c1e… seed=1 version=devel".
+*/
+func (meta *RunMetadata) String() string {
+	var pairs []string
+	for _, field := range meta.fields() {
+		pairs = append(pairs, field[0]+"="+field[1])
+	}
+	return strings.Join(pairs, " ")
+}
+
+/*
+metadataOptions builds RunMetadata from a renderer's "run-id", "config-hash" and "seed" options,
+with the rule from "rule". It returns nil if there's no run-id.
+*/
+func metadataOptions(options Options) (*RunMetadata, error) {
+	if options["run-id"] == "" {
+		return nil, nil
+	}
+	seed, err := options.Int("seed", 0)
+	if err != nil {
+		return nil, err
+	}
+	return &RunMetadata{
+		RunID:      options["run-id"],
+		ConfigHash: options["config-hash"],
+		Rule:       options["rule"],
+		Seed:       int64(seed),
+		Version:    Version,
+	}, nil
+}
+
+/*
+EncodePNG writes img to w as a PNG, with meta, if it isn't nil, in tEXt chunks with keywords like
+"cellaut:run-id".
+*/
+func EncodePNG(w io.Writer, img image.Image, meta *RunMetadata) error {
+	if meta == nil {
+		return png.Encode(w, img)
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return err
+	}
+	// The 8 byte signature and the IHDR chunk, which must come first, are 33 bytes
+	data := b.Bytes()
+	var chunks bytes.Buffer
+	for _, field := range meta.fields() {
+		writePNGChunk(&chunks, "tEXt", []byte("cellaut:"+field[0]+"\x00"+field[1]))
+	}
+	if _, err := w.Write(data[:33]); err != nil {
+		return err
+	}
+	if _, err := w.Write(chunks.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.Write(data[33:])
+	return err
+}
+
+func writePNGChunk(b *bytes.Buffer, kind string, data []byte) {
+	binary.Write(b, binary.BigEndian, uint32(len(data)))
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(kind))
+	crc.Write(data)
+	b.WriteString(kind)
+	b.Write(data)
+	binary.Write(b, binary.BigEndian, crc.Sum32())
+}
+
+/*
+gifComment returns a GIF comment extension holding meta, which can go anywhere between a GIF's
+other blocks.
+*/
+func gifComment(meta *RunMetadata) []byte {
+	text := "cellaut " + meta.String()
+	b := []byte{0x21, 0xfe}
+	// The comment is split into sub-blocks of up to 255 bytes, and ends with an empty one
+	for len(text) > 0 {
+		n := minInt(len(text), 255)
+		b = append(b, byte(n))
+		b = append(b, text[:n]...)
+		text = text[n:]
+	}
+	return append(b, 0)
+}
+
+/*
+rleComments returns meta as RLE "#C" comment lines, which Golly and parseRLE skip.
+*/
+func rleComments(meta *RunMetadata) string {
+	var b strings.Builder
+	for _, field := range meta.fields() {
+		fmt.Fprintf(&b, "#C %s: %s\n", field[0], field[1])
+	}
+	return b.String()
+}
@@ -0,0 +1,88 @@
+package cellaut
+
+import (
+	"bytes"
+	"image/gif"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRunMetadata(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	config := map[string]string{"ticks": "10", "seed": "3"}
+	a, err := NewRunMetadata("B3/S23", 3, config)
+	assert.Nil(err)
+	b, err := NewRunMetadata("B3/S23", 3, map[string]string{"seed": "3", "ticks": "10"})
+	assert.Nil(err)
+	assert.NotEqual(a.RunID, b.RunID)
+	assert.Equal(a.ConfigHash, b.ConfigHash)
+	c, err := NewRunMetadata("B3/S23", 3, map[string]string{"ticks": "11", "seed": "3"})
+	assert.Nil(err)
+	assert.NotEqual(a.ConfigHash, c.ConfigHash)
+
+	meta := &RunMetadata{RunID: "abc", Seed: 3, Version: "v1.0.0"}
+	assert.Equal("run-id=abc seed=3 version=v1.0.0", meta.String())
+	_, err = NewRunMetadata("", 0, func() {})
+	assert.NotNil(err)
+}
+
+func TestRunMetadata_Stamped(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	meta := &RunMetadata{RunID: "0123abcd", ConfigHash: "feed", Rule: "B3/S23", Seed: 7, Version: "devel"}
+	grid := NewStateGrid(3, 3, NewStateTable("-"))
+	for _, cell := range gliderCells {
+		grid.Set(cell[0], cell[1], LifeAlive)
+	}
+
+	// PNG, in tEXt chunks that don't get in the way of decoding
+	var b bytes.Buffer
+	img, err := RenderGrid(grid, LifePalette, 2)
+	assert.Nil(err)
+	assert.Nil(EncodePNG(&b, img, meta))
+	assert.Contains(b.String(), "tEXtcellaut:run-id\x000123abcd")
+	assert.Contains(b.String(), "tEXtcellaut:seed\x007")
+	decoded, err := png.Decode(bytes.NewReader(b.Bytes()))
+	assert.Nil(err)
+	assert.Equal(6, decoded.Bounds().Dx())
+	render, err := LookupRenderer("png", Options{"run-id": "0123abcd", "seed": "7"})
+	assert.Nil(err)
+	b.Reset()
+	assert.Nil(render(&b, grid, LifePalette))
+	assert.Contains(b.String(), "cellaut:run-id\x000123abcd")
+	_, err = LookupRenderer("png", Options{"run-id": "0123abcd", "seed": "x"})
+	assert.NotNil(err)
+
+	// GIF, as a comment
+	recorder := NewGIFRecorder(LifePalette, 2)
+	recorder.Metadata = meta
+	recorder.AddGrid(grid)
+	b.Reset()
+	assert.Nil(recorder.WriteGIF(&b))
+	assert.Contains(b.String(), "cellaut run-id=0123abcd config-hash=feed rule=B3/S23 seed=7 version=devel")
+	anim, err := gif.DecodeAll(bytes.NewReader(b.Bytes()))
+	assert.Nil(err)
+	assert.Len(anim.Image, 1)
+
+	// RLE, as comment lines
+	b.Reset()
+	assert.Nil(writeRLE(&b, grid, "B3/S23", meta))
+	assert.True(strings.HasPrefix(b.String(), "#C run-id: 0123abcd\n#C config-hash: feed\n"))
+	pattern, rule, err := parseRLE(b.String())
+	assert.Nil(err)
+	assert.Equal("B3/S23", rule)
+	assert.True(pattern.StateGrid.Equal(grid))
+
+	// .caz archives, in config.json
+	b.Reset()
+	assert.Nil(WriteArchive(&b, &Experiment{Rule: "B3/S23", Initial: grid, Run: meta}))
+	exp, err := ReadArchive(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	assert.Nil(err)
+	assert.Equal(meta, exp.Run)
+}
@@ -0,0 +1,222 @@
+package cellaut
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+Montage lays out frames from many runs side by side, each under a label, the way the results of a
+parameter sweep are usually shown. Image draws the last frame of every run; WriteGIF animates them
+all together, with runs that finished early holding their last frame.
+
+Frames are added with Add, or by subscribing each run to a Sink. It's safe for concurrent use, so
+runs can go in parallel.
+*/
+type Montage struct {
+	Palette  Palette
+	CellSize int
+	// Columns is how many runs go across. Defaults to enough to make the montage roughly square.
+	Columns int
+	// LabelScale is how many pixels across each pixel of the labels' 3×5 font is. Defaults to 2.
+	LabelScale int
+	// Delay is how long each frame of the GIF is shown, as for GIFRecorder
+	Delay time.Duration
+	// Metadata, if it's set, is stamped into the PNG or GIF
+	Metadata *RunMetadata
+
+	mu     sync.Mutex
+	panels []*montagePanel
+}
+
+type montagePanel struct {
+	label  string
+	frames []*StateGrid
+}
+
+/*
+NewMontage returns an empty *Montage that draws with palette, cellSize pixels to a cell.
+*/
+func NewMontage(palette Palette, cellSize int) *Montage {
+	return &Montage{Palette: palette, CellSize: cellSize}
+}
+
+/*
+Add adds frames to the run with the given label, adding the run after the others if it's new.
+*/
+func (montage *Montage) Add(label string, frames ...*StateGrid) {
+	montage.mu.Lock()
+	defer montage.mu.Unlock()
+	montage.panel(label).frames = append(montage.panel(label).frames, frames...)
+}
+
+/*
+Sink returns a Sink that adds a frame of a Simulation's World to the run with the given label
+after every tick.
+*/
+func (montage *Montage) Sink(label string) Sink {
+	montage.mu.Lock()
+	montage.panel(label)
+	montage.mu.Unlock()
+	return func(event TickEvent) {
+		montage.Add(label, TakeSnapshot(event.World, nil))
+	}
+}
+
+/*
+panel returns the run with the given label, making it if there isn't one. The caller must hold
+montage.mu.
+*/
+func (montage *Montage) panel(label string) *montagePanel {
+	for _, panel := range montage.panels {
+		if panel.label == label {
+			return panel
+		}
+	}
+	panel := &montagePanel{label: label}
+	montage.panels = append(montage.panels, panel)
+	return panel
+}
+
+/*
+Image draws the last frame of every run. The labels need a color of their own, so it's an error
+for the Palette to have more than 254 States.
+*/
+func (montage *Montage) Image() (*image.Paletted, error) {
+	montage.mu.Lock()
+	defer montage.mu.Unlock()
+	return montage.draw(-1)
+}
+
+/*
+WritePNG writes Image to w as a PNG.
+*/
+func (montage *Montage) WritePNG(w io.Writer) error {
+	img, err := montage.Image()
+	if err != nil {
+		return err
+	}
+	return EncodePNG(w, img, montage.Metadata)
+}
+
+/*
+WriteGIF writes every run to w as one animated GIF, frame by frame, for as many frames as the
+longest run has.
+*/
+func (montage *Montage) WriteGIF(w io.Writer) error {
+	montage.mu.Lock()
+	recorder := NewGIFRecorder(montage.Palette, montage.CellSize)
+	recorder.Delay, recorder.Metadata = montage.Delay, montage.Metadata
+	frames := 0
+	for _, panel := range montage.panels {
+		frames = maxInt(frames, len(panel.frames))
+	}
+	for i := 0; i < frames; i++ {
+		if err := recorder.add(func() (*image.Paletted, error) { return montage.draw(i) }); err != nil {
+			montage.mu.Unlock()
+			return err
+		}
+	}
+	montage.mu.Unlock()
+	return recorder.WriteGIF(w)
+}
+
+// montageGap is how many pixels are left between runs, and around the labels
+const montageGap = 4
+
+/*
+draw draws frame i of every run, or the last frame if i is -1 or past the end. The caller must
+hold montage.mu.
+*/
+func (montage *Montage) draw(i int) (*image.Paletted, error) {
+	cellSize := maxInt(montage.CellSize, 1)
+	scale := montage.LabelScale
+	if scale < 1 {
+		scale = 2
+	}
+	columns := montage.Columns
+	if columns < 1 {
+		columns = int(math.Ceil(math.Sqrt(float64(len(montage.panels)))))
+	}
+	columns = maxInt(minInt(columns, len(montage.panels)), 1)
+	rows := (len(montage.panels) + columns - 1) / columns
+
+	// Every run gets a panel as big as the biggest frame
+	width, height := 0, 0
+	for _, panel := range montage.panels {
+		for _, frame := range panel.frames {
+			width, height = maxInt(width, frame.Width*cellSize), maxInt(height, frame.Height*cellSize)
+		}
+	}
+	labelHeight := 5*scale + montageGap
+	panelWidth, panelHeight := width+montageGap, height+labelHeight+montageGap
+
+	if err := montage.Palette.check(); err != nil {
+		return nil, err
+	}
+	if len(montage.Palette) == maxPaletteStates {
+		return nil, fmt.Errorf("a montage's palette can't have more than %d states, since the labels need a color", maxPaletteStates-1)
+	}
+	colors, indices := montage.Palette.colors()
+	// The background is black, which is always index 0; the labels are white
+	colors = append(colors, color.White)
+	text := uint8(len(colors) - 1)
+	img := image.NewPaletted(image.Rect(0, 0, columns*panelWidth+montageGap, rows*panelHeight+montageGap), colors)
+	for n, panel := range montage.panels {
+		left := montageGap + (n%columns)*panelWidth
+		top := montageGap + (n/columns)*panelHeight
+		drawLabel(img, panel.label, left, top, scale, text)
+		if len(panel.frames) == 0 {
+			continue
+		}
+		frame := panel.frames[len(panel.frames)-1]
+		if i >= 0 && i < len(panel.frames) {
+			frame = panel.frames[i]
+		}
+		top += labelHeight
+		for y := 0; y < frame.Height; y++ {
+			for x := 0; x < frame.Width; x++ {
+				// As for RenderImage, row 0 goes at the bottom
+				px, py := left+x*cellSize, top+(frame.Height-1-y)*cellSize
+				fillRect(img, image.Rect(px, py, px+cellSize, py+cellSize), indices[frame.At(x, y)])
+			}
+		}
+	}
+	return img, nil
+}
+
+func fillRect(img *image.Paletted, rect image.Rectangle, index uint8) {
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			img.SetColorIndex(x, y, index)
+		}
+	}
+}
+
+/*
+drawLabel writes label on img in bitmapFont, with its top left corner at (left, top). Letters
+are drawn in upper case, and characters the font doesn't have are drawn as "?".
+*/
+func drawLabel(img *image.Paletted, label string, left, top, scale int, index uint8) {
+	for n, r := range []rune(strings.ToUpper(label)) {
+		glyph, ok := bitmapFont[r]
+		if !ok {
+			glyph = bitmapFont['?']
+		}
+		for y, row := range glyph {
+			for x, c := range row {
+				if c != '#' {
+					continue
+				}
+				px, py := left+(n*4+x)*scale, top+y*scale
+				fillRect(img, image.Rect(px, py, px+scale, py+scale), index)
+			}
+		}
+	}
+}
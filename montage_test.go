@@ -0,0 +1,110 @@
+package cellaut
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMontage(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	palette := Palette{LifeAlive: color.RGBA{R: 255, A: 255}, LifeDead: color.Gray{Y: 64}}
+	montage := NewMontage(palette, 2)
+	montage.LabelScale = 1
+	montage.Delay = 20 * time.Millisecond
+	// A blinker that runs for 3 ticks, and an empty grid that only lasts 1
+	blinker := func(vertical bool) *StateGrid {
+		grid := NewStateGrid(3, 3, NewStateTable("-"))
+		for i := 0; i < 3; i++ {
+			if vertical {
+				grid.Set(1, i, LifeAlive)
+			} else {
+				grid.Set(i, 1, LifeAlive)
+			}
+		}
+		return grid
+	}
+	montage.Add("a", blinker(false), blinker(true))
+	montage.Add("empty", NewStateGrid(3, 3, NewStateTable("-")))
+	montage.Add("a", blinker(false))
+
+	// Two 3x3 runs of 2 pixel cells with 5 pixel labels, with 4 pixel gaps all round
+	img, err := montage.Image()
+	assert.Nil(err)
+	assert.Equal(24, img.Bounds().Dx())
+	assert.Equal(23, img.Bounds().Dy())
+	// The middle of the top of the "A"
+	assert.Equal(color.White, img.At(5, 4))
+	assert.Equal(img.Palette[0], img.At(4, 4))
+	// The last frame of "a" is horizontal, so its top row is dead and its middle row alive
+	assert.Equal(palette[LifeDead], img.At(6, 13))
+	assert.Equal(palette[LifeAlive], img.At(6, 15))
+	// "empty" is to the right
+	assert.Equal(palette[LifeDead], img.At(16, 15))
+
+	var b bytes.Buffer
+	montage.Metadata = &RunMetadata{RunID: "sweep-1"}
+	assert.Nil(montage.WritePNG(&b))
+	assert.Contains(b.String(), "cellaut:run-id\x00sweep-1")
+	_, err = png.Decode(&b)
+	assert.Nil(err)
+
+	// The animation is as long as the longest run, and the second frame has the vertical blinker
+	b.Reset()
+	assert.Nil(montage.WriteGIF(&b))
+	anim, err := gif.DecodeAll(&b)
+	assert.Nil(err)
+	assert.Len(anim.Image, 3)
+	assert.Equal([]int{2, 2, 2}, anim.Delay)
+	r, _, _, _ := anim.Image[1].At(6, 13).RGBA()
+	assert.Equal(uint32(0xffff), r)
+	r, _, _, _ = anim.Image[1].At(8, 13).RGBA()
+	assert.Equal(uint32(64*0x101), r)
+
+	// The labels take a color, so a Palette that a GIFRecorder could draw is one State too big
+	big := Palette{}
+	for i := 0; i < maxPaletteStates; i++ {
+		big[State(fmt.Sprintf("s%d", i))] = color.Gray{Y: uint8(i)}
+	}
+	montage = NewMontage(big, 1)
+	montage.Add("a", blinker(false))
+	_, err = montage.Image()
+	assert.NotNil(err)
+	assert.NotNil(montage.WritePNG(&b))
+	assert.NotNil(montage.WriteGIF(&b))
+}
+
+func TestMontage_Sink(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Runs of a sweep can go at once, each into its own panel
+	montage := NewMontage(LifePalette, 1)
+	montage.Columns = 1
+	done := make(chan error)
+	for _, label := range []string{"run 1", "run 2"} {
+		sim := NewSimulation()
+		assert.Nil(sim.Configure(SimulationConfig{World: startLifeGrid(t), MaxTicks: 4}))
+		sim.Subscribe(montage.Sink(label))
+		assert.Nil(sim.Start())
+		go func() { done <- sim.Wait() }()
+	}
+	assert.Nil(<-done)
+	assert.Nil(<-done)
+	for _, panel := range montage.panels {
+		assert.Len(panel.frames, 4)
+	}
+	// One column of two 6x5 runs, labelled with the default scale of 2
+	img, err := montage.Image()
+	assert.Nil(err)
+	assert.Equal(6+2*4, img.Bounds().Dx())
+	assert.Equal(2*(5+10+2*4)+4, img.Bounds().Dy())
+}
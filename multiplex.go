@@ -0,0 +1,428 @@
+package cellaut
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+/*
+MultiplexedCellAut is a CellAut that can be driven by a Multiplexer, without a goroutine of its own.
+
+Its inbox must be buffered, with room for a state from every neighbor, since a Multiplexer sends to
+it with nobody listening.
+*/
+type MultiplexedCellAut interface {
+	CellAut
+	// Commit makes the state set by SetState the current state, and returns whether it changed.
+	Commit(tickID int64) bool
+	// Broadcast sends the current state to every neighbor. It must not block.
+	Broadcast()
+	// Receive takes whatever states the neighbors have sent, without blocking, and reports any
+	// errors to supervisor.
+	Receive(supervisor *Supervisor)
+}
+
+/*
+muxCell is a MultiplexedCellAut plus the Multiplexer's bookkeeping for it.
+*/
+type muxCell struct {
+	aut    MultiplexedCellAut
+	asleep bool
+	// joined is the first tick the cell took part in, or -1 if it hasn't yet
+	joined int64
+	// changed is only touched by workers
+	changed bool
+	// ledger is where the cell's StateRecords go, if anywhere, and recorded is whether it's
+	// written one yet
+	ledger   chan StateRecord
+	recorded bool
+}
+
+/*
+Multiplexer runs any number of MultiplexedCellAuts on a fixed number of worker goroutines, so that a
+grid of millions of cells doesn't need millions of goroutines.
+
+Each tick goes in three phases, with every worker finishing a phase before any worker starts the
+next: every cell commits its new state, then every cell that changed broadcasts it, then every cell
+receives what its neighbors sent. That's the same tick protocol the Ticker follows, so cells end up
+in the same states either way.
+
+Within a phase, the workers take the cells BatchSize at a time, in the order they were added. A Grid
+adds them row by row, so each worker gets runs of cells that sit next to each other, and a worker
+whose batches were quick takes more, rather than waiting for the others.
+
+Cells are added and removed with Start and Remove, which, like the Ticker's, take effect at the
+start of the next tick.
+*/
+type Multiplexer struct {
+	// Workers is how many goroutines service the cells. Defaults to GOMAXPROCS.
+	Workers int
+	// BatchSize is how many cells a worker takes at a time. Defaults to DefaultBatchSize.
+	BatchSize int
+	// Supervisor handles the errors that cells report. If it's nil when the Multiplexer is first
+	// used, a Supervisor with the ErrorHalt policy is created.
+	Supervisor     *Supervisor
+	supervisorOnce sync.Once
+	// Tracer, if it's set, gets a span for every tick and its phases
+	Tracer Tracer
+
+	// tickID is only ever modified by Tick. Other goroutines must read it atomically.
+	tickID  int64
+	ticking int32
+	// mu guards everything below
+	mu sync.Mutex
+	// lastTick is the last tick that finished, or -1
+	lastTick int64
+	cells    []*muxCell
+	pending  []*muxCell
+	removals map[*muxCell]bool
+	byCell   map[CellAut]*muxCell
+	stopped  bool
+	done     chan struct{}
+	doneOnce sync.Once
+	// work has one channel per worker, on which it's told which phase to run
+	work      []chan func(cell *muxCell)
+	phaseDone sync.WaitGroup
+	workers   sync.WaitGroup
+	// phaseCells and phaseSkip are the cells the current phase runs on, and which of them to skip.
+	// They're set before the workers are told to start the phase, and only read by them after.
+	phaseCells []*muxCell
+	phaseSkip  []bool
+	// next is the index of the first of phaseCells that no worker has taken yet. The workers
+	// update it atomically.
+	next int64
+}
+
+// DefaultBatchSize is the BatchSize a Multiplexer uses if it isn't given one. It's big enough that
+// the workers don't spend their time fighting over the next batch, and small enough that there are
+// plenty of batches to share out on a grid of any size worth multiplexing.
+const DefaultBatchSize = 256
+
+func (mux *Multiplexer) supervisor() *Supervisor {
+	mux.supervisorOnce.Do(func() {
+		if mux.Supervisor == nil {
+			mux.Supervisor = NewSupervisor(ErrorHalt)
+		}
+	})
+	return mux.Supervisor
+}
+
+/*
+Err returns the error that halted the Multiplexer, if a cell reported one under the ErrorHalt
+policy. Once Err is non-nil, Tick does nothing.
+*/
+func (mux *Multiplexer) Err() error {
+	return mux.supervisor().Err()
+}
+
+/*
+Done returns a channel that Stop closes.
+*/
+func (mux *Multiplexer) Done() chan struct{} {
+	mux.doneOnce.Do(func() { mux.done = make(chan struct{}) })
+	return mux.done
+}
+
+/*
+Start registers aut with the Multiplexer. It doesn't actually start anything, since aut has no
+goroutine; it's called Start so that a Multiplexer can stand in for a Ticker. The Multiplexer writes
+aut's StateRecords to stateLedger for it.
+
+aut must be a MultiplexedCellAut. If it isn't, or the Multiplexer has been stopped, the error is
+logged and nothing else happens. Add is the same, but returns the error.
+*/
+func (mux *Multiplexer) Start(aut CellAut, stateLedger chan StateRecord) {
+	if err := mux.Add(aut, stateLedger); err != nil {
+		log.WithError(err).Error("couldn't start cell")
+	}
+}
+
+/*
+Add registers aut with the Multiplexer like Start, and returns an error if aut isn't a
+MultiplexedCellAut or the Multiplexer has been stopped.
+*/
+func (mux *Multiplexer) Add(aut CellAut, stateLedger chan StateRecord) error {
+	muxAut, ok := aut.(MultiplexedCellAut)
+	if !ok {
+		return fmt.Errorf("cell %s can't be multiplexed", cellName(aut))
+	}
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	if mux.stopped {
+		return fmt.Errorf("multiplexer has been stopped")
+	}
+	if mux.byCell == nil {
+		mux.byCell = make(map[CellAut]*muxCell)
+		mux.lastTick = -1
+	}
+	cell := &muxCell{aut: muxAut, joined: -1, ledger: stateLedger}
+	mux.byCell[aut] = cell
+	mux.pending = append(mux.pending, cell)
+	return nil
+}
+
+func (mux *Multiplexer) add(aut CellAut, stateLedger chan StateRecord) error {
+	return mux.Add(aut, stateLedger)
+}
+
+/*
+Remove stops ticking aut, starting with the next tick.
+*/
+func (mux *Multiplexer) Remove(aut CellAut) error {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	cell, ok := mux.byCell[aut]
+	if !ok {
+		return fmt.Errorf("cell %s isn't running on this Multiplexer", cellName(aut))
+	}
+	delete(mux.byCell, aut)
+	if mux.removals == nil {
+		mux.removals = make(map[*muxCell]bool)
+	}
+	mux.removals[cell] = true
+	return nil
+}
+
+/*
+SetAsleep puts aut to sleep or wakes it up, starting with the next tick. A sleeping cell doesn't
+commit, but it still receives its neighbors' states.
+*/
+func (mux *Multiplexer) SetAsleep(aut CellAut, asleep bool) error {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	cell, ok := mux.byCell[aut]
+	if !ok {
+		return fmt.Errorf("cell %s isn't running on this Multiplexer", cellName(aut))
+	}
+	cell.asleep = asleep
+	return nil
+}
+
+func (mux *Multiplexer) currentTick() int64 {
+	return atomic.LoadInt64(&mux.tickID)
+}
+
+/*
+Tick runs one tick.
+*/
+func (mux *Multiplexer) Tick() {
+	ctx, span := startSpan(context.Background(), mux.Tracer, "tick")
+	span.SetAttribute("tick.id", atomic.LoadInt64(&mux.tickID))
+	mux.TickContext(ctx)
+	span.End()
+}
+
+/*
+TickContext is like Tick, but the spans for the tick's phases are children of the span in ctx, if
+there's a Tracer.
+*/
+func (mux *Multiplexer) TickContext(ctx context.Context) {
+	mux.mu.Lock()
+	if mux.stopped || mux.Err() != nil {
+		mux.mu.Unlock()
+		return
+	}
+	mux.applyPending()
+	mux.startWorkers()
+	cells := mux.cells
+	asleep := make([]bool, len(cells))
+	for i, cell := range cells {
+		asleep[i] = cell.asleep
+	}
+	mux.mu.Unlock()
+
+	atomic.StoreInt32(&mux.ticking, 1)
+	tickID := mux.tickID
+	supervisor := mux.supervisor()
+	_, span := startSpan(ctx, mux.Tracer, "compute")
+	mux.runPhase(cells, asleep, func(cell *muxCell) {
+		cell.changed = cell.aut.Commit(tickID)
+		if cell.changed || !cell.recorded {
+			recordState(cell.ledger, mux.Done(), cellName(cell.aut), tickID, cell.aut.GetState())
+			cell.recorded = true
+		}
+	})
+	span.End()
+	_, span = startSpan(ctx, mux.Tracer, "exchange")
+	mux.runPhase(cells, nil, func(cell *muxCell) {
+		if cell.changed {
+			cell.aut.Broadcast()
+		}
+	})
+	mux.runPhase(cells, nil, func(cell *muxCell) {
+		cell.aut.Receive(supervisor)
+		cell.changed = false
+	})
+	span.End()
+	mux.mu.Lock()
+	mux.lastTick = tickID
+	mux.mu.Unlock()
+	atomic.AddInt64(&mux.tickID, 1)
+	atomic.StoreInt32(&mux.ticking, 0)
+}
+
+/*
+applyPending adds the pending cells and drops the removed ones. The caller must hold mux.mu.
+*/
+func (mux *Multiplexer) applyPending() {
+	if len(mux.pending) == 0 && len(mux.removals) == 0 {
+		return
+	}
+	for _, cell := range mux.pending {
+		cell.joined = mux.tickID
+	}
+	cells := make([]*muxCell, 0, len(mux.cells)+len(mux.pending))
+	for _, cell := range append(mux.cells, mux.pending...) {
+		if !mux.removals[cell] {
+			cells = append(cells, cell)
+		}
+	}
+	mux.cells = cells
+	mux.pending = nil
+	mux.removals = nil
+}
+
+/*
+startWorkers starts the worker goroutines, if they haven't been already. The caller must hold
+mux.mu.
+*/
+func (mux *Multiplexer) startWorkers() {
+	if mux.work != nil {
+		return
+	}
+	if mux.Workers < 1 {
+		mux.Workers = runtime.GOMAXPROCS(0)
+	}
+	if mux.BatchSize < 1 {
+		mux.BatchSize = DefaultBatchSize
+	}
+	mux.work = make([]chan func(cell *muxCell), mux.Workers)
+	for w := range mux.work {
+		mux.work[w] = make(chan func(cell *muxCell))
+		mux.workers.Add(1)
+		go mux.worker(w)
+	}
+}
+
+/*
+worker applies each phase it's given to batches of the cells, until there are none left.
+*/
+func (mux *Multiplexer) worker(w int) {
+	defer mux.workers.Done()
+	for phase := range mux.work[w] {
+		mux.phase(phase)
+		mux.phaseDone.Done()
+	}
+}
+
+func (mux *Multiplexer) phase(fn func(cell *muxCell)) {
+	batch := int64(mux.BatchSize)
+	for {
+		start := atomic.AddInt64(&mux.next, batch) - batch
+		if start >= int64(len(mux.phaseCells)) {
+			return
+		}
+		end := minInt(int(start+batch), len(mux.phaseCells))
+		for i := int(start); i < end; i++ {
+			if mux.phaseSkip != nil && mux.phaseSkip[i] {
+				continue
+			}
+			mux.runCell(mux.phaseCells[i], fn)
+		}
+	}
+}
+
+/*
+runCell runs fn on cell, turning a panic into an error reported to the Supervisor. Whatever the
+policy, the Multiplexer halts, since the cell may have been left half-updated.
+*/
+func (mux *Multiplexer) runCell(cell *muxCell, fn func(cell *muxCell)) {
+	defer func() {
+		if r := recover(); r != nil {
+			cellErr := &CellError{Cell: cell.aut, TickID: atomic.LoadInt64(&mux.tickID), Err: fmt.Errorf("panic: %v", r)}
+			mux.supervisor().Report(cellErr)
+			mux.supervisor().halt(cellErr)
+		}
+	}()
+	fn(cell)
+}
+
+/*
+runPhase runs fn on every cell that isn't skipped, spread across the workers, and waits for all of
+them to finish.
+*/
+func (mux *Multiplexer) runPhase(cells []*muxCell, skip []bool, fn func(cell *muxCell)) {
+	mux.phaseCells, mux.phaseSkip = cells, skip
+	atomic.StoreInt64(&mux.next, 0)
+	mux.phaseDone.Add(len(mux.work))
+	for _, work := range mux.work {
+		work <- fn
+	}
+	mux.phaseDone.Wait()
+}
+
+/*
+Stop stops the worker goroutines. It mustn't be called while Tick is running.
+
+timeout is only there so that a Multiplexer can stand in for a Ticker. Since cells have no
+goroutines of their own, there's nothing to wait for but the workers, which are never in the middle
+of anything when Tick isn't running.
+*/
+func (mux *Multiplexer) Stop(timeout time.Duration) error {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	if mux.stopped {
+		return nil
+	}
+	mux.stopped = true
+	close(mux.Done())
+	for _, work := range mux.work {
+		close(work)
+	}
+	mux.workers.Wait()
+	return nil
+}
+
+/*
+Health reports the state of the Multiplexer and its cells. Since cells have no goroutines of their
+own, they're alive until they're removed or the Multiplexer stops.
+*/
+func (mux *Multiplexer) Health() Health {
+	health := Health{
+		TickID:  atomic.LoadInt64(&mux.tickID),
+		Ticking: atomic.LoadInt32(&mux.ticking) == 1,
+	}
+	if err := mux.Err(); err != nil {
+		health.Err = err.Error()
+	}
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	for i, cell := range append(append([]*muxCell(nil), mux.cells...), mux.pending...) {
+		cellHealth := CellHealth{
+			Cell:          fmt.Sprintf("%d:%s", i, cellName(cell.aut)),
+			Alive:         !mux.stopped,
+			Phase:         CellIdle,
+			LastAckedTick: -1,
+		}
+		if cell.joined != -1 && cell.joined <= mux.lastTick {
+			cellHealth.LastAckedTick = mux.lastTick
+		}
+		switch {
+		case mux.stopped:
+			cellHealth.Phase = CellDead
+		case health.Ticking && cell.joined != -1:
+			// The workers don't keep track of where each cell is in the tick
+			cellHealth.Phase = CellComputing
+		case cell.asleep:
+			cellHealth.Phase = CellAsleep
+		}
+		health.Cells = append(health.Cells, cellHealth)
+	}
+	return health
+}
@@ -0,0 +1,217 @@
+package cellaut
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+Returns the states of cells as a string.
+*/
+func gooStates(cells []*GooCellAut) string {
+	auts := make([]CellAut, len(cells))
+	for i, aut := range cells {
+		auts[i] = aut
+	}
+	return concatStates(auts)
+}
+
+func TestMultiplexer(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// The same goo grid, on a Ticker and on a Multiplexer, must go through the same states. (Only
+	// as long as no cell hears "X" and "-" in the same tick, since then either could win.)
+	tickerCells := newGooGrid(7, 5, DefaultChannelBuffer)
+	muxCells := newGooGrid(7, 5, DefaultChannelBuffer)
+	ticker := &Ticker{}
+	// Small batches, so that every worker gets some
+	mux := &Multiplexer{Workers: 3, BatchSize: 4}
+	for i := range tickerCells {
+		ticker.Start(tickerCells[i], nil)
+		mux.Start(muxCells[i], nil)
+	}
+	for _, i := range []int{0, 20} {
+		tickerCells[i].SetState("X")
+		muxCells[i].SetState("X")
+	}
+	for i := 0; i < 20; i++ {
+		ticker.Tick()
+		mux.Tick()
+		assert.Equal(gooStates(tickerCells), gooStates(muxCells), "tick %d", i)
+	}
+	assert.Nil(ticker.Stop(time.Second))
+
+	health := mux.Health()
+	assert.Equal(int64(20), health.TickID)
+	assert.Len(health.Cells, 35)
+	assert.Equal(CellHealth{Cell: "4:goo#4", Alive: true, Phase: CellIdle, LastAckedTick: 19}, health.Cells[4])
+	assert.Len(mux.work, 3)
+	assert.Nil(mux.Stop(time.Second))
+	assert.Equal(CellDead, mux.Health().Cells[0].Phase)
+	// Ticking after Stop does nothing
+	mux.Tick()
+	assert.Equal(int64(20), mux.Health().TickID)
+}
+
+/*
+A GooCellAut that writes down which goroutine commits it, each tick.
+*/
+type committerCellAut struct {
+	*GooCellAut
+	mu         *sync.Mutex
+	committers map[int64][]uint64
+}
+
+func (aut *committerCellAut) Commit(tickID int64) bool {
+	aut.mu.Lock()
+	aut.committers[tickID] = append(aut.committers[tickID], goroutineID())
+	aut.mu.Unlock()
+	// Slow enough that every worker gets a share
+	time.Sleep(50 * time.Microsecond)
+	return aut.GooCellAut.Commit(tickID)
+}
+
+/*
+goroutineID returns the ID of the calling goroutine, which is how a test can tell a Multiplexer's
+workers apart.
+*/
+func goroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	// It starts "goroutine 123 ["
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	id, _ := strconv.ParseUint(string(buf[:bytes.IndexByte(buf, ' ')]), 10, 64)
+	return id
+}
+
+func TestMultiplexer_Batches(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	var mu sync.Mutex
+	cells := newGooGrid(7, 5, DefaultChannelBuffer)
+	recorders := make([]*committerCellAut, len(cells))
+	mux := &Multiplexer{Workers: 3, BatchSize: 4}
+	for i, cell := range cells {
+		recorders[i] = &committerCellAut{GooCellAut: cell, mu: &mu, committers: make(map[int64][]uint64)}
+		mux.Start(recorders[i], nil)
+	}
+	cells[0].SetState("X")
+	for i := 0; i < 10; i++ {
+		mux.Tick()
+	}
+	assert.Nil(mux.Stop(time.Second))
+
+	// Every cell is committed once a tick, and each run of 4 cells, the last 3 included, is
+	// committed by the same worker
+	workers := make(map[uint64]bool)
+	for tick := int64(0); tick < 10; tick++ {
+		for i, recorder := range recorders {
+			if !assert.Len(recorder.committers[tick], 1, "cell %d, tick %d", i, tick) {
+				return
+			}
+			committer := recorder.committers[tick][0]
+			workers[committer] = true
+			if i%4 > 0 {
+				assert.Equal(recorders[i-1].committers[tick][0], committer, "cell %d, tick %d", i, tick)
+			}
+		}
+	}
+	assert.True(len(workers) <= 3, "%d workers", len(workers))
+}
+
+/*
+A CellAut that only has the CellAut methods, so it can't be multiplexed.
+*/
+type plainCellAut struct {
+	CellAut
+}
+
+func TestSimulation_Multiplexed(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	world := newGooRow(12)
+	world.cells[0].SetState("X")
+	added := NewGooCellAut(12)
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{
+		World:            world,
+		Engine:           MultiplexedEngine,
+		Workers:          2,
+		MaxTicks:         16,
+		RegionOfInterest: &RegionOfInterest{TileSize: 2, QuietTicks: 2},
+	}))
+	var addErrs []error
+	sim.Edit(func(edit *TopologyEdit) {
+		addErrs = append(addErrs, edit.Add(added, map[NeighborIndex]CellAut{NeighborLf: world.cells[11]}))
+		addErrs = append(addErrs, edit.Add(&plainCellAut{NewGooCellAut(13)}, nil))
+	})
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+
+	assert.Nil(addErrs[0])
+	assert.NotNil(addErrs[1])
+	for _, aut := range world.cells {
+		assert.Equal(State("X"), aut.GetState())
+	}
+	assert.Equal(State("X"), added.GetState())
+
+	world.cells[3] = &plainCellAut{world.cells[3]}
+	assert.NotNil(NewSimulation().Configure(SimulationConfig{World: world, Engine: MultiplexedEngine}))
+}
+
+/*
+A GooCellAut that panics when it commits a given tick.
+*/
+type panickyMuxCellAut struct {
+	*GooCellAut
+	panicAt int64
+}
+
+func (aut *panickyMuxCellAut) Commit(tickID int64) bool {
+	if tickID == aut.panicAt {
+		panic("boom")
+	}
+	return aut.GooCellAut.Commit(tickID)
+}
+
+func TestMultiplexer_Panic(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	mux := &Multiplexer{Supervisor: NewSupervisor(ErrorRestart)}
+	mux.Start(&panickyMuxCellAut{GooCellAut: NewGooCellAut(0), panicAt: 1}, nil)
+	mux.Start(NewGooCellAut(1), nil)
+	mux.Tick()
+	mux.Tick()
+	// Even under ErrorRestart, a panic halts the Multiplexer
+	assert.NotNil(mux.Err())
+	mux.Tick()
+	assert.Equal(int64(2), mux.Health().TickID)
+	assert.Nil(mux.Stop(time.Second))
+}
+
+func TestMultiplexer_Add(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	mux := &Multiplexer{}
+	added := NewGooCellAut(0)
+	assert.Nil(mux.Add(added, nil))
+	assert.NotNil(mux.Add(&plainCellAut{NewGooCellAut(1)}, nil))
+	// Start only logs the error
+	plain := &plainCellAut{NewGooCellAut(2)}
+	mux.Start(plain, nil)
+	assert.NotNil(mux.Remove(plain))
+	assert.Nil(mux.Remove(added))
+	assert.Nil(mux.Stop(time.Second))
+	assert.NotNil(mux.Add(NewGooCellAut(3), nil))
+}
@@ -0,0 +1,52 @@
+package cellaut
+
+import (
+	"math/rand"
+)
+
+/*
+RandomLifeLike returns a random Life-like rulestring, with each birth and survival count in it with
+even odds. B0 is always left out: under a B0 rule, empty space comes alive all at once.
+*/
+func RandomLifeLike(rng *rand.Rand) string {
+	var conditions LifeLikeRule
+	for i := range conditions {
+		for n := range conditions[i] {
+			conditions[i][n] = rng.Intn(2) == 0
+		}
+	}
+	conditions[0][0] = false
+	return conditions.String()
+}
+
+/*
+MutateLifeLike returns rulestring with one birth or survival count, picked at random, flipped. As
+with RandomLifeLike, B0 is never added.
+*/
+func MutateLifeLike(rulestring string, rng *rand.Rand) (string, error) {
+	conditions, err := ParseRulestring(rulestring)
+	if err != nil {
+		return "", err
+	}
+	// 17 counts to pick from: B1 to B8, then S0 to S8
+	i := 1 + rng.Intn(len(conditions[0])+len(conditions[1])-1)
+	conditions[i/9][i%9] = !conditions[i/9][i%9]
+	return conditions.String(), nil
+}
+
+/*
+RandomLineRule returns a LineRule of the given radius with every table entry picked at random.
+*/
+func RandomLineRule(radius int, rng *rand.Rand) LineRule {
+	return NewLineRule(radius, func([]bool) bool { return rng.Intn(2) == 0 })
+}
+
+/*
+Mutate returns a copy of the rule with one table entry, picked at random, flipped.
+*/
+func (rule LineRule) Mutate(rng *rand.Rand) LineRule {
+	mutant := LineRule{Radius: rule.Radius, Table: append([]bool(nil), rule.Table...)}
+	i := rng.Intn(len(mutant.Table))
+	mutant.Table[i] = !mutant.Table[i]
+	return mutant
+}
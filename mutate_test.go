@@ -0,0 +1,65 @@
+package cellaut
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLifeLikeMutation(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	rng := rand.New(rand.NewSource(1))
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		rulestring := RandomLifeLike(rng)
+		seen[rulestring] = true
+		conditions, err := ParseRulestring(rulestring)
+		assert.Nil(err)
+		assert.False(conditions[0][0], rulestring)
+		assert.Equal(rulestring, conditions.String())
+	}
+	assert.True(len(seen) > 40)
+
+	// A mutant differs from its parent in exactly one count
+	for i := 0; i < 50; i++ {
+		mutant, err := MutateLifeLike("B3/S23", rng)
+		assert.Nil(err)
+		parent, _ := ParseRulestring("B3/S23")
+		child, _ := ParseRulestring(mutant)
+		assert.Equal(1, countDifferences(parent[0][:], child[0][:])+countDifferences(parent[1][:], child[1][:]), mutant)
+		assert.False(child[0][0])
+	}
+
+	_, err := MutateLifeLike("B3", rng)
+	assert.NotNil(err)
+}
+
+func TestLineRuleMutation(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	rng := rand.New(rand.NewSource(1))
+	rule := RandomLineRule(2, rng)
+	assert.Equal(32, len(rule.Table))
+	assert.True(countDifferences(rule.Table, make([]bool, 32)) > 0)
+
+	mutant := rule.Mutate(rng)
+	assert.Equal(1, countDifferences(rule.Table, mutant.Table))
+	assert.Equal(rule.Radius, mutant.Radius)
+}
+
+/*
+countDifferences returns how many places a and b differ in.
+*/
+func countDifferences(a, b []bool) int {
+	n := 0
+	for i := range a {
+		if a[i] != b[i] {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,251 @@
+package cellaut
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// neighborhoodSizes maps the neighborhoods new-rule knows about to how many neighbors a cell has.
+var neighborhoodSizes = map[string]int{
+	"moore":      8,
+	"vonneumann": 4,
+}
+
+/*
+newRuleSpec describes the rule that new-rule scaffolds.
+*/
+type newRuleSpec struct {
+	Name         string
+	Package      string
+	States       []State
+	Neighborhood string
+	// FileBase is the snake_case name that the generated files are named after
+	FileBase string
+}
+
+/*
+Q is what cellaut's names have to be qualified with in the generated files: nothing, if they're
+going in package cellaut, and "cellaut." anywhere else.
+*/
+func (spec *newRuleSpec) Q() string {
+	if spec.Package == "cellaut" {
+		return ""
+	}
+	return "cellaut."
+}
+
+/*
+newRuleCommand implements `cellaut new-rule <Name> --states X,-,O --neighborhood moore`.
+
+It writes a Rule skeleton, a test, and a golden fixture for the test, all of which the user is
+expected to edit. The skeleton registers the rule under its name, so it can be used from the REPL as
+soon as its package is imported.
+*/
+func newRuleCommand(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("usage: cellaut new-rule <Name> [--states X,-] [--neighborhood moore] [--package cellaut] [--dir .]")
+	}
+	spec := &newRuleSpec{Name: args[0]}
+	fs := flag.NewFlagSet("new-rule", flag.ContinueOnError)
+	states := fs.String("states", "X,-", "comma-separated states of the rule")
+	fs.StringVar(&spec.Neighborhood, "neighborhood", "moore", "moore or vonneumann")
+	fs.StringVar(&spec.Package, "package", "cellaut", "package of the generated files")
+	dir := fs.String("dir", ".", "directory to write the generated files into")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	for _, state := range strings.Split(*states, ",") {
+		if state == "" || strings.IndexFunc(state, unicode.IsSpace) != -1 {
+			return fmt.Errorf("states must be non-empty and contain no whitespace; got '%s'", state)
+		}
+		spec.States = append(spec.States, State(state))
+	}
+	if err := spec.validate(); err != nil {
+		return err
+	}
+	spec.FileBase = snakeCase(spec.Name)
+
+	files, err := spec.generate()
+	if err != nil {
+		return err
+	}
+	for name := range files {
+		if _, err := os.Stat(filepath.Join(*dir, name)); err == nil {
+			return fmt.Errorf("%s already exists", filepath.Join(*dir, name))
+		}
+	}
+	for name, contents := range files {
+		path := filepath.Join(*dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+			return err
+		}
+		fmt.Println("wrote", path)
+	}
+	return nil
+}
+
+func (spec *newRuleSpec) validate() error {
+	if spec.Name == "" || !unicode.IsUpper([]rune(spec.Name)[0]) {
+		return fmt.Errorf("rule name '%s' must be an exported Go identifier", spec.Name)
+	}
+	for _, r := range spec.Name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return fmt.Errorf("rule name '%s' must be an exported Go identifier", spec.Name)
+		}
+	}
+	if _, ok := neighborhoodSizes[spec.Neighborhood]; !ok {
+		return fmt.Errorf("unknown neighborhood '%s'", spec.Neighborhood)
+	}
+	return nil
+}
+
+/*
+generate returns the contents of the scaffolded files, keyed by their path relative to the output
+directory.
+*/
+func (spec *newRuleSpec) generate() (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	for name, tmpl := range map[string]*template.Template{
+		spec.FileBase + ".go":      newRuleTemplate,
+		spec.FileBase + "_test.go": newRuleTestTemplate,
+	} {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, spec); err != nil {
+			return nil, err
+		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		files[name] = src
+	}
+
+	// The skeleton rule leaves every cell alone, so the starting fixture says exactly that: one
+	// line per state, surrounded by neighbors in that same state.
+	var golden bytes.Buffer
+	fmt.Fprintf(&golden, "# %s transitions, one per line: <self> <neighbors...> -> <next>\n", spec.Name)
+	for _, state := range spec.States {
+		fields := []string{string(state)}
+		for i := 0; i < neighborhoodSizes[spec.Neighborhood]; i++ {
+			fields = append(fields, string(state))
+		}
+		fmt.Fprintf(&golden, "%s -> %s\n", strings.Join(fields, " "), state)
+	}
+	files[filepath.Join("testdata", spec.FileBase+".golden")] = golden.Bytes()
+	return files, nil
+}
+
+/*
+snakeCase turns a Go identifier like MyRule into my_rule.
+*/
+func snakeCase(name string) string {
+	var rslt []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				rslt = append(rslt, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		rslt = append(rslt, r)
+	}
+	return string(rslt)
+}
+
+var newRuleTemplate = template.Must(template.New("rule").Parse(`package {{.Package}}
+{{if .Q}}
+import "github.com/danslimmon/cellaut"
+{{end}}
+// {{.Name}}States are the states that {{.Name}} cells can be in.
+var {{.Name}}States = []{{.Q}}State{ {{- range .States}}{{printf "%q" .}}, {{end -}} }
+
+// {{.Name}} can be looked up by name, from the REPL or with LookupRule
+func init() {
+	{{.Q}}Register("{{.Name}}", func({{.Q}}Options) ({{.Q}}Rule, error) {
+		return {{.Name}}, nil
+	})
+}
+
+/*
+{{.Name}} is a Rule over a {{.Neighborhood}} neighborhood.
+
+TODO: describe what {{.Name}} does.
+*/
+func {{.Name}}(self {{.Q}}State, neighbors map[{{.Q}}NeighborIndex]{{.Q}}State) {{.Q}}State {
+	counts := make(map[{{.Q}}State]int)
+	for _, neighbor := range neighbors {
+		counts[neighbor]++
+	}
+	// TODO: compute the next state from self and counts (or from neighbors, if it matters which
+	// neighbor is in which state).
+	return self
+}
+`))
+
+var newRuleTestTemplate = template.Must(template.New("test").Parse(`package {{.Package}}
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+{{if .Q}}
+	"github.com/danslimmon/cellaut"
+{{- end}}
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+Checks {{.Name}} against the transitions listed in testdata/{{.FileBase}}.golden.
+*/
+func Test{{.Name}}_Golden(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	f, err := os.Open("testdata/{{.FileBase}}.golden")
+	if !assert.Nil(err) {
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Split(line, "->")
+		if !assert.Len(parts, 2, line) {
+			continue
+		}
+		fields := strings.Fields(parts[0])
+		neighbors := make(map[{{.Q}}NeighborIndex]{{.Q}}State)
+		for i, field := range fields[1:] {
+			neighbors[{{.Q}}NeighborIndex(i)] = {{.Q}}State(field)
+		}
+		assert.Equal({{.Q}}State(strings.TrimSpace(parts[1])), {{.Name}}({{.Q}}State(fields[0]), neighbors), line)
+	}
+	assert.Nil(scanner.Err())
+}
+
+func Test{{.Name}}_Registered(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	rule, err := {{.Q}}LookupRule("{{.Name}}", nil)
+	assert.Nil(err)
+	assert.NotNil(rule)
+}
+`))
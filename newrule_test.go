@@ -0,0 +1,110 @@
+package cellaut
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRuleCommand(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "cellaut-new-rule")
+	if !assert.Nil(err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	err = newRuleCommand([]string{"MyRule", "--states", "X,-,O", "--neighborhood", "vonneumann", "--dir", dir})
+	assert.Nil(err)
+
+	src, err := ioutil.ReadFile(filepath.Join(dir, "my_rule.go"))
+	assert.Nil(err)
+	assert.Contains(string(src), `var MyRuleStates = []State{"X", "-", "O"}`)
+	assert.Contains(string(src), "func MyRule(self State, neighbors map[NeighborIndex]State) State {")
+	assert.Contains(string(src), `Register("MyRule", func(Options) (Rule, error) {`)
+
+	src, err = ioutil.ReadFile(filepath.Join(dir, "my_rule_test.go"))
+	assert.Nil(err)
+	assert.Contains(string(src), "func TestMyRule_Golden(t *testing.T) {")
+	assert.Contains(string(src), `rule, err := LookupRule("MyRule", nil)`)
+	assert.Contains(string(src), `os.Open("testdata/my_rule.golden")`)
+
+	golden, err := ioutil.ReadFile(filepath.Join(dir, "testdata", "my_rule.golden"))
+	assert.Nil(err)
+	lines := strings.Split(strings.TrimSpace(string(golden)), "\n")
+	assert.Equal([]string{"X X X X X -> X", "- - - - - -> -", "O O O O O -> O"}, lines[1:])
+
+	// Existing files don't get clobbered
+	assert.NotNil(newRuleCommand([]string{"MyRule", "--dir", dir}))
+}
+
+func TestNewRuleCommand_OtherPackage(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "cellaut-new-rule")
+	if !assert.Nil(err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	err = newRuleCommand([]string{"MyRule", "--package", "mypkg", "--dir", dir})
+	assert.Nil(err)
+	src, err := ioutil.ReadFile(filepath.Join(dir, "my_rule.go"))
+	assert.Nil(err)
+	assert.Contains(string(src), "func MyRule(self cellaut.State, neighbors map[cellaut.NeighborIndex]cellaut.State) cellaut.State {")
+	assert.Contains(string(src), `cellaut.Register("MyRule", func(cellaut.Options) (cellaut.Rule, error) {`)
+
+	// Outside package cellaut, the rule and its test have to compile against cellaut's exported
+	// names alone
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range []string{"my_rule.go", "my_rule_test.go"} {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if !assert.Nil(err) {
+			return
+		}
+		files = append(files, f)
+	}
+	config := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = config.Check("mypkg", fset, files, nil)
+	assert.Nil(err)
+}
+
+func TestNewRuleCommand_Invalid(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	for _, args := range [][]string{
+		{},
+		{"--states", "X"},
+		{"myRule"},
+		{"My-Rule"},
+		{"MyRule", "--neighborhood", "hex"},
+		{"MyRule", "--states", "X,,O"},
+		{"MyRule", "--states", "X, O"},
+	} {
+		assert.NotNil(newRuleCommand(args), args)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal("my_rule", snakeCase("MyRule"))
+	assert.Equal("rule", snakeCase("Rule"))
+	assert.Equal("http_rule2", snakeCase("HTTPRule2"))
+	assert.Equal("brians_brain", snakeCase("BriansBrain"))
+}
@@ -0,0 +1,468 @@
+package cellaut
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Output is somewhere to write files, like checkpoints, ledgers and rendered frames. It's either a
+directory (DirOutput) or a bucket in S3-compatible object storage (S3Output), which is handy for
+sweeps run on a cluster with no shared disk.
+
+A file is only guaranteed to be there once the io.WriteCloser from Create has been closed without
+an error.
+*/
+type Output interface {
+	Create(name string) (io.WriteCloser, error)
+}
+
+/*
+OpenOutput returns the Output at location, which is either an "s3://bucket/prefix" URL, as for
+NewS3Output, or a directory.
+*/
+func OpenOutput(location string) (Output, error) {
+	if strings.HasPrefix(location, "s3://") {
+		return NewS3Output(location)
+	}
+	return DirOutput(location), nil
+}
+
+/*
+createOutput creates the file at path, which may be an s3:// URL.
+*/
+func createOutput(path string) (io.WriteCloser, error) {
+	if !strings.HasPrefix(path, "s3://") {
+		return os.Create(path)
+	}
+	bucket := strings.SplitN(strings.TrimPrefix(path, "s3://"), "/", 2)
+	if len(bucket) < 2 || bucket[1] == "" {
+		return nil, fmt.Errorf("'%s' has no object key", path)
+	}
+	out, err := NewS3Output("s3://" + bucket[0])
+	if err != nil {
+		return nil, err
+	}
+	return out.Create(bucket[1])
+}
+
+/*
+writeOutput writes data to the file at path, which may be an s3:// URL.
+*/
+func writeOutput(path string, data []byte) error {
+	f, err := createOutput(path)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+/*
+DirOutput writes files under a directory, making subdirectories as they're needed.
+*/
+type DirOutput string
+
+func (dir DirOutput) Create(name string) (io.WriteCloser, error) {
+	path := filepath.Join(string(dir), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+// s3MinPartSize is the smallest S3 allows any part of a multipart upload but the last to be
+const s3MinPartSize = 5 << 20
+
+/*
+S3Output writes files as objects in a bucket of S3 or something that speaks its API, like MinIO.
+Requests are signed with AWS Signature Version 4.
+
+Objects up to PartSize are written with a single PUT when they're closed. Bigger ones are written
+with a multipart upload as they go, so they never have to fit in memory; if one fails, the upload
+is aborted so that the parts don't linger.
+*/
+type S3Output struct {
+	Bucket string
+	// Prefix is put in front of every name, so "runs/7/" puts checkpoint.json at
+	// runs/7/checkpoint.json
+	Prefix string
+	// Endpoint is where requests go, using path-style URLs. Defaults to AWS's endpoint for Region.
+	Endpoint     string
+	Region       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	// PartSize is how big the parts of a multipart upload are. Defaults to, and can't be less than,
+	// 5 MiB.
+	PartSize int
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+
+	// now is stubbed out in tests
+	now func() time.Time
+}
+
+/*
+NewS3Output returns an *S3Output for an "s3://bucket/prefix" URL. Credentials come from the usual
+environment variables: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN. AWS_REGION
+defaults to us-east-1, and AWS_ENDPOINT_URL, if it's set, points somewhere other than AWS.
+*/
+func NewS3Output(location string) (*S3Output, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return nil, fmt.Errorf("expected s3://bucket/prefix; got '%s'", location)
+	}
+	out := &S3Output{
+		Bucket:       u.Host,
+		Prefix:       strings.TrimPrefix(u.Path, "/"),
+		Endpoint:     os.Getenv("AWS_ENDPOINT_URL"),
+		Region:       os.Getenv("AWS_REGION"),
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if out.Prefix != "" && !strings.HasSuffix(out.Prefix, "/") {
+		out.Prefix += "/"
+	}
+	if out.AccessKey == "" || out.SecretKey == "" {
+		return nil, fmt.Errorf("writing to %s needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY", location)
+	}
+	return out, nil
+}
+
+/*
+Create starts writing the object at Prefix + name.
+*/
+func (out *S3Output) Create(name string) (io.WriteCloser, error) {
+	if name == "" {
+		return nil, fmt.Errorf("empty object name")
+	}
+	partSize := out.PartSize
+	if partSize < s3MinPartSize {
+		partSize = s3MinPartSize
+	}
+	return &s3Object{out: out, key: out.Prefix + name, partSize: partSize}, nil
+}
+
+/*
+s3Object is an object being written to an S3Output.
+*/
+type s3Object struct {
+	out      *S3Output
+	key      string
+	partSize int
+	buf      []byte
+	// uploadID is set once a multipart upload has started
+	uploadID string
+	etags    []string
+	err      error
+	closed   bool
+}
+
+func (obj *s3Object) Write(p []byte) (int, error) {
+	if obj.err != nil {
+		return 0, obj.err
+	}
+	if obj.closed {
+		return 0, fmt.Errorf("write to closed object %s", obj.key)
+	}
+	obj.buf = append(obj.buf, p...)
+	for len(obj.buf) >= obj.partSize {
+		if err := obj.uploadPart(obj.buf[:obj.partSize]); err != nil {
+			obj.abort(err)
+			return 0, err
+		}
+		obj.buf = append([]byte(nil), obj.buf[obj.partSize:]...)
+	}
+	return len(p), nil
+}
+
+func (obj *s3Object) Close() error {
+	if obj.closed || obj.err != nil {
+		return obj.err
+	}
+	obj.closed = true
+	if obj.uploadID == "" {
+		_, err := obj.out.do("PUT", obj.key, nil, obj.buf)
+		obj.err = err
+		return err
+	}
+	if len(obj.buf) > 0 {
+		if err := obj.uploadPart(obj.buf); err != nil {
+			obj.abort(err)
+			return err
+		}
+	}
+	var complete struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []struct {
+			PartNumber int
+			ETag       string
+		} `xml:"Part"`
+	}
+	for i, etag := range obj.etags {
+		complete.Parts = append(complete.Parts, struct {
+			PartNumber int
+			ETag       string
+		}{i + 1, etag})
+	}
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		obj.abort(err)
+		return err
+	}
+	resp, err := obj.out.do("POST", obj.key, url.Values{"uploadId": {obj.uploadID}}, body)
+	if err == nil {
+		// S3 can report a failure with a 200, once it's started sending the response
+		var result struct {
+			XMLName xml.Name
+			Message string
+		}
+		if xml.Unmarshal(resp, &result) == nil && result.XMLName.Local == "Error" {
+			err = fmt.Errorf("completing upload of %s: %s", obj.key, result.Message)
+		}
+	}
+	if err != nil {
+		obj.abort(err)
+	}
+	return err
+}
+
+/*
+uploadPart uploads data as the next part, starting the multipart upload first if it hasn't been.
+*/
+func (obj *s3Object) uploadPart(data []byte) error {
+	if obj.uploadID == "" {
+		resp, err := obj.out.do("POST", obj.key, url.Values{"uploads": {""}}, nil)
+		if err != nil {
+			return err
+		}
+		var result struct {
+			UploadID string `xml:"UploadId"`
+		}
+		if err := xml.Unmarshal(resp, &result); err != nil || result.UploadID == "" {
+			return fmt.Errorf("starting upload of %s: no upload ID in response", obj.key)
+		}
+		obj.uploadID = result.UploadID
+	}
+	query := url.Values{
+		"partNumber": {strconv.Itoa(len(obj.etags) + 1)},
+		"uploadId":   {obj.uploadID},
+	}
+	req, err := obj.out.request("PUT", obj.key, query, data)
+	if err != nil {
+		return err
+	}
+	resp, err := obj.out.send(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	obj.etags = append(obj.etags, resp.Header.Get("ETag"))
+	return nil
+}
+
+/*
+abort gives up on the object, cancelling the multipart upload if there is one. The error from
+cancelling is dropped in favor of err, which is what went wrong in the first place.
+*/
+func (obj *s3Object) abort(err error) {
+	obj.err = err
+	obj.closed = true
+	if obj.uploadID != "" {
+		obj.out.do("DELETE", obj.key, url.Values{"uploadId": {obj.uploadID}}, nil)
+	}
+}
+
+/*
+do sends a signed request for the object at key and returns the response body.
+*/
+func (out *S3Output) do(method, key string, query url.Values, body []byte) ([]byte, error) {
+	req, err := out.request(method, key, query, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := out.send(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+/*
+request returns a signed request for the object at key.
+*/
+func (out *S3Output) request(method, key string, query url.Values, body []byte) (*http.Request, error) {
+	endpoint := out.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + out.region() + ".amazonaws.com"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + out.Bucket + "/" + key
+	// Escape the path the same way it's escaped for signing
+	u.RawPath = s3Escape(u.Path, false)
+	u.RawQuery = s3Query(query)
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if out.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", out.SessionToken)
+	}
+	now := time.Now
+	if out.now != nil {
+		now = out.now
+	}
+	signS3(req, body, out.AccessKey, out.SecretKey, out.region(), now())
+	return req, nil
+}
+
+/*
+send sends req, turning a response that isn't a 2xx into an error.
+*/
+func (out *S3Output) send(req *http.Request) (*http.Response, error) {
+	client := out.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		var result struct {
+			Code    string
+			Message string
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		xml.Unmarshal(data, &result)
+		return nil, fmt.Errorf("%s %s: %s %s %s", req.Method, req.URL.Path, resp.Status, result.Code, result.Message)
+	}
+	return resp, nil
+}
+
+func (out *S3Output) region() string {
+	if out.Region == "" {
+		return "us-east-1"
+	}
+	return out.Region
+}
+
+/*
+signS3 adds an AWS Signature Version 4 Authorization header to req, signing the host and every
+header req already has.
+*/
+func signS3(req *http.Request, body []byte, accessKey, secretKey, region string, now time.Time) {
+	now = now.UTC()
+	date := now.Format("20060102")
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3Escape(req.URL.Path, false),
+		s3Query(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+/*
+s3Query encodes query the way Signature Version 4 wants it: sorted by key, with everything but
+unreserved characters escaped, and "=" even when a value is empty.
+*/
+func s3Query(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(key, true)+"="+s3Escape(value, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+/*
+s3Escape percent-encodes everything in s but unreserved characters, and slashes unless
+escapeSlash is set.
+*/
+func s3Escape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
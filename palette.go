@@ -0,0 +1,115 @@
+package cellaut
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"sort"
+)
+
+/*
+Palette says what color each State should be drawn in.
+*/
+type Palette map[State]color.Color
+
+/*
+Color returns the color for the given State, or black if the Palette doesn't have one.
+*/
+func (palette Palette) Color(state State) color.Color {
+	if c, ok := palette[state]; ok {
+		return c
+	}
+	return color.Black
+}
+
+// maxPaletteStates is how many States a Palette can have colors for. A paletted image has room for
+// 256 colors, and one of them is the black of States missing from the Palette.
+const maxPaletteStates = 255
+
+/*
+check returns an error if the Palette has too many States to draw as a paletted image.
+*/
+func (palette Palette) check() error {
+	if len(palette) > maxPaletteStates {
+		return fmt.Errorf("a palette can't have more than %d states; this one has %d", maxPaletteStates, len(palette))
+	}
+	return nil
+}
+
+/*
+colors returns the Palette as a color.Palette along with the index of each State in it. The Palette
+must have passed check, or the indices wrap around and States end up sharing colors.
+
+The States are sorted so that the result doesn't depend on map iteration order. Black is always
+included as the color of States missing from the Palette.
+*/
+func (palette Palette) colors() (color.Palette, map[State]uint8) {
+	states := make([]State, 0, len(palette))
+	for state := range palette {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i] < states[j] })
+	colors := color.Palette{color.Black}
+	indices := make(map[State]uint8)
+	for _, state := range states {
+		indices[state] = uint8(len(colors))
+		colors = append(colors, palette[state])
+	}
+	return colors, indices
+}
+
+/*
+RenderImage draws the current states of the World, with each cell as a cellSize × cellSize square.
+
+The World's y axis points up, so row 0 of the World ends up at the bottom of the image. It's an error
+for palette to have more than 255 States, since black has to fit in too.
+*/
+func RenderImage(world World, palette Palette, cellSize int) (*image.Paletted, error) {
+	if err := palette.check(); err != nil {
+		return nil, err
+	}
+	width, height := world.Size()
+	return renderStates(width, height, func(x, y int) State { return world.At(x, y).GetState() }, palette, cellSize), nil
+}
+
+/*
+ExportPNG writes the current states of the World to w as a PNG, one pixel per cell. It's for grids
+too big to look at in a terminal; RenderImage draws bigger cells.
+*/
+func ExportPNG(w io.Writer, world World, palette Palette) error {
+	img, err := RenderImage(world, palette, 1)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+/*
+RenderGrid draws a StateGrid the same way RenderImage draws a World.
+*/
+func RenderGrid(grid *StateGrid, palette Palette, cellSize int) (*image.Paletted, error) {
+	if err := palette.check(); err != nil {
+		return nil, err
+	}
+	return renderStates(grid.Width, grid.Height, grid.At, palette, cellSize), nil
+}
+
+func renderStates(width, height int, at func(x, y int) State, palette Palette, cellSize int) *image.Paletted {
+	colors, indices := palette.colors()
+	img := image.NewPaletted(image.Rect(0, 0, width*cellSize, height*cellSize), colors)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// Unknown states get index 0, which is black
+			index := indices[at(x, y)]
+			top := (height - 1 - y) * cellSize
+			for py := top; py < top+cellSize; py++ {
+				for px := x * cellSize; px < (x+1)*cellSize; px++ {
+					img.SetColorIndex(px, py, index)
+				}
+			}
+		}
+	}
+	return img
+}
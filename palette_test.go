@@ -0,0 +1,90 @@
+package cellaut
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderImage(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	world, ticker := newSliceWorld(3, 2, LifeDead)
+	defer ticker.Stop(time.Second)
+	world.At(0, 0).(*GooCellAut).state = LifeRed
+	world.At(2, 1).(*GooCellAut).state = LifeBlue
+	world.At(1, 1).(*GooCellAut).state = "?"
+
+	img, err := RenderImage(world, ImmigrationPalette, 2)
+	assert.Nil(err)
+	assert.Equal(6, img.Bounds().Dx())
+	assert.Equal(4, img.Bounds().Dy())
+	// y=0 is the bottom row of the image
+	assert.Equal(ImmigrationPalette.Color(LifeRed), img.At(0, 3))
+	assert.Equal(ImmigrationPalette.Color(LifeRed), img.At(1, 2))
+	assert.Equal(ImmigrationPalette.Color(LifeBlue), img.At(5, 0))
+	assert.Equal(ImmigrationPalette.Color(LifeDead), img.At(2, 2))
+	// States that aren't in the palette are black
+	assert.Equal(color.Black, Palette{}.Color("?"))
+	assert.Equal(color.Palette{color.Black}.Convert(color.Black), img.At(2, 0))
+}
+
+func TestExportPNG(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A block in the bottom left corner, which stays put
+	grid := NewLifeGrid(40, 30)
+	for _, cell := range [][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+		grid.At(cell[0], cell[1]).SetState(LifeAlive)
+	}
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 2}))
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+
+	var b bytes.Buffer
+	assert.Nil(ExportPNG(&b, grid, LifePalette))
+	img, err := png.Decode(&b)
+	assert.Nil(err)
+	assert.Equal(image.Rect(0, 0, 40, 30), img.Bounds())
+	// Decoding gives RGBA colors, whatever kind the palette had
+	black, white := color.RGBAModel.Convert(color.Black), color.RGBAModel.Convert(color.White)
+	assert.Equal(black, color.RGBAModel.Convert(img.At(0, 29)))
+	assert.Equal(black, color.RGBAModel.Convert(img.At(1, 28)))
+	assert.Equal(white, color.RGBAModel.Convert(img.At(2, 29)))
+	assert.Equal(white, color.RGBAModel.Convert(img.At(39, 0)))
+}
+
+func TestPalette_TooBig(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// 255 States and black fill all 256 colors. One more would have to share an index with
+	// another State.
+	palette := Palette{}
+	for i := 0; i < 255; i++ {
+		palette[State(fmt.Sprintf("s%d", i))] = color.Gray{Y: uint8(i)}
+	}
+	assert.Nil(palette.check())
+	colors, indices := palette.colors()
+	assert.Len(colors, 256)
+	assert.Equal(uint8(255), indices["s99"])
+
+	palette["one too many"] = color.White
+	assert.NotNil(palette.check())
+	_, err := RenderGrid(NewStateGrid(2, 2, nil), palette, 1)
+	assert.NotNil(err)
+	world, ticker := newSliceWorld(2, 2, LifeDead)
+	defer ticker.Stop(time.Second)
+	_, err = RenderImage(world, palette, 1)
+	assert.NotNil(err)
+	assert.NotNil(ExportPNG(&bytes.Buffer{}, world, palette))
+}
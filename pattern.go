@@ -0,0 +1,216 @@
+package cellaut
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Pattern is a rectangular block of cells with a position, like a glider or a piece of a snapshot.
+
+(X, Y) is where the bottom left cell of the block goes. The transforms return new Patterns, and
+leave the original alone.
+*/
+type Pattern struct {
+	X, Y int
+	*StateGrid
+}
+
+/*
+NewPattern returns an empty width×height *Pattern at (0, 0). If table is nil, a new StateTable
+containing just the empty State is used.
+*/
+func NewPattern(width, height int, table *StateTable) *Pattern {
+	return &Pattern{StateGrid: NewStateGrid(width, height, table)}
+}
+
+/*
+ParsePattern makes a *Pattern out of rows of one-character States, top row first, like
+
+	.X.
+	..X
+	XXX
+
+Every row must be the same length. table must already have the empty State interned as StateID 0;
+if it's nil, a new table is made with "." as the empty State.
+*/
+func ParsePattern(text string, table *StateTable) (*Pattern, error) {
+	if table == nil {
+		table = NewStateTable(".")
+	}
+	rows := strings.Split(strings.TrimSpace(text), "\n")
+	width := len([]rune(strings.TrimSpace(rows[0])))
+	pattern := NewPattern(width, len(rows), table)
+	for i, row := range rows {
+		runes := []rune(strings.TrimSpace(row))
+		if len(runes) != width {
+			return nil, fmt.Errorf("row %d is %d cells wide; expected %d", i, len(runes), width)
+		}
+		for x, r := range runes {
+			pattern.Set(x, len(rows)-1-i, State(r))
+		}
+	}
+	return pattern, nil
+}
+
+/*
+String returns the Pattern's rows, top row first, with each State written out in full.
+*/
+func (pattern *Pattern) String() string {
+	var b strings.Builder
+	for y := pattern.Height - 1; y >= 0; y-- {
+		for x := 0; x < pattern.Width; x++ {
+			b.WriteString(string(pattern.At(x, y)))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+/*
+transform returns a new Pattern of the given size, with the cell at (x, y) in the old one moved to
+where(x, y) in the new one.
+*/
+func (pattern *Pattern) transform(width, height int, where func(x, y int) (int, int)) *Pattern {
+	rslt := NewPattern(width, height, pattern.Table)
+	rslt.X, rslt.Y = pattern.X, pattern.Y
+	for y := 0; y < pattern.Height; y++ {
+		for x := 0; x < pattern.Width; x++ {
+			nx, ny := where(x, y)
+			rslt.SetID(nx, ny, pattern.IDAt(x, y))
+		}
+	}
+	return rslt
+}
+
+/*
+Rotate90 returns the Pattern rotated a quarter turn counterclockwise, keeping its position.
+*/
+func (pattern *Pattern) Rotate90() *Pattern {
+	return pattern.transform(pattern.Height, pattern.Width, func(x, y int) (int, int) {
+		return pattern.Height - 1 - y, x
+	})
+}
+
+/*
+FlipH returns the Pattern mirrored left to right.
+*/
+func (pattern *Pattern) FlipH() *Pattern {
+	return pattern.transform(pattern.Width, pattern.Height, func(x, y int) (int, int) {
+		return pattern.Width - 1 - x, y
+	})
+}
+
+/*
+FlipV returns the Pattern mirrored top to bottom.
+*/
+func (pattern *Pattern) FlipV() *Pattern {
+	return pattern.transform(pattern.Width, pattern.Height, func(x, y int) (int, int) {
+		return x, pattern.Height - 1 - y
+	})
+}
+
+/*
+Translate returns the Pattern moved by (dx, dy).
+*/
+func (pattern *Pattern) Translate(dx, dy int) *Pattern {
+	rslt := pattern.transform(pattern.Width, pattern.Height, func(x, y int) (int, int) { return x, y })
+	rslt.X, rslt.Y = pattern.X+dx, pattern.Y+dy
+	return rslt
+}
+
+/*
+Orientation is one of the 8 ways to turn and flip a Pattern.
+*/
+type Orientation string
+
+const (
+	Identity  Orientation = "identity"
+	Rotate90  Orientation = "rotate 90"
+	Rotate180 Orientation = "rotate 180"
+	Rotate270 Orientation = "rotate 270"
+	FlipH     Orientation = "flip horizontal"
+	FlipV     Orientation = "flip vertical"
+	// FlipDiagonal mirrors across the line from bottom left to top right
+	FlipDiagonal Orientation = "flip diagonal"
+	// FlipAntiDiagonal mirrors across the line from top left to bottom right
+	FlipAntiDiagonal Orientation = "flip antidiagonal"
+)
+
+// Orientations are all the Orientations, Identity first.
+var Orientations = []Orientation{Identity, Rotate90, Rotate180, Rotate270, FlipH, FlipV, FlipDiagonal, FlipAntiDiagonal}
+
+/*
+Orient returns the Pattern turned and flipped according to orientation.
+*/
+func (pattern *Pattern) Orient(orientation Orientation) *Pattern {
+	switch orientation {
+	case Rotate90:
+		return pattern.Rotate90()
+	case Rotate180:
+		return pattern.Rotate90().Rotate90()
+	case Rotate270:
+		return pattern.Rotate90().Rotate90().Rotate90()
+	case FlipH:
+		return pattern.FlipH()
+	case FlipV:
+		return pattern.FlipV()
+	case FlipDiagonal:
+		return pattern.Rotate90().FlipH()
+	case FlipAntiDiagonal:
+		return pattern.Rotate90().FlipV()
+	}
+	return pattern.Translate(0, 0)
+}
+
+/*
+Symmetries returns the Orientations, other than Identity, that leave the Pattern looking the same.
+*/
+func (pattern *Pattern) Symmetries() []Orientation {
+	var symmetries []Orientation
+	for _, orientation := range Orientations[1:] {
+		if sameCells(pattern.StateGrid, pattern.Orient(orientation).StateGrid) {
+			symmetries = append(symmetries, orientation)
+		}
+	}
+	return symmetries
+}
+
+/*
+Canonical returns the Pattern in a standard orientation, so that two Patterns that are the same
+shape turned or flipped differently have the same canonical form. It also returns the Orientation
+that got it there.
+*/
+func (pattern *Pattern) Canonical() (*Pattern, Orientation) {
+	best, bestOrientation, bestKey := pattern, Identity, ""
+	for i, orientation := range Orientations {
+		oriented := pattern.Orient(orientation)
+		key := fmt.Sprintf("%dx%d\n%s", oriented.Width, oriented.Height, oriented.String())
+		if i == 0 || key < bestKey {
+			best, bestOrientation, bestKey = oriented, orientation, key
+		}
+	}
+	return best, bestOrientation
+}
+
+/*
+sameCells returns whether a and b are the same size and have the same States in every cell.
+*/
+func sameCells(a, b *StateGrid) bool {
+	if a.Width != b.Width || a.Height != b.Height {
+		return false
+	}
+	sameTable := a.Table == b.Table
+	for y := 0; y < a.Height; y++ {
+		for x := 0; x < a.Width; x++ {
+			if sameTable {
+				if a.IDAt(x, y) != b.IDAt(x, y) {
+					return false
+				}
+			} else if a.At(x, y) != b.At(x, y) {
+				return false
+			}
+		}
+	}
+	return true
+}
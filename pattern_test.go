@@ -0,0 +1,73 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+Parses a Pattern, failing the test if it can't.
+*/
+func mustParsePattern(t *testing.T, text string) *Pattern {
+	pattern, err := ParsePattern(text, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pattern
+}
+
+func TestPattern_Transforms(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// An L shape
+	pattern := mustParsePattern(t, `
+		X..
+		XXX
+	`)
+	pattern.X, pattern.Y = 5, 6
+	assert.Equal("X..\nXXX\n", pattern.String())
+	assert.Equal(State("X"), pattern.At(0, 1))
+
+	rotated := pattern.Rotate90()
+	assert.Equal(".X\n.X\nXX\n", rotated.String())
+	assert.Equal([2]int{5, 6}, [2]int{rotated.X, rotated.Y})
+	assert.Equal("..X\nXXX\n", pattern.FlipH().String())
+	assert.Equal("XXX\nX..\n", pattern.FlipV().String())
+	assert.Equal(pattern.String(), pattern.Rotate90().Rotate90().Rotate90().Rotate90().String())
+	// The original is left alone
+	assert.Equal("X..\nXXX\n", pattern.String())
+
+	moved := pattern.Translate(-2, 3)
+	assert.Equal([2]int{3, 9}, [2]int{moved.X, moved.Y})
+	assert.Equal(pattern.String(), moved.String())
+
+	assert.Equal("X.\nX.\nXX\n", pattern.Orient(FlipDiagonal).String())
+	assert.Equal("XX\n.X\n.X\n", pattern.Orient(FlipAntiDiagonal).String())
+
+	_, err := ParsePattern("XX\nX", nil)
+	assert.NotNil(err)
+}
+
+func TestPattern_Symmetries(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	block := mustParsePattern(t, "XX\nXX")
+	assert.Equal(Orientations[1:], block.Symmetries())
+	tromino := mustParsePattern(t, "X.\nXX")
+	assert.Equal([]Orientation{FlipDiagonal}, tromino.Symmetries())
+	glider := mustParsePattern(t, ".X.\n..X\nXXX")
+	assert.Nil(glider.Symmetries())
+	blinker := mustParsePattern(t, "XXX")
+	assert.Equal([]Orientation{Rotate180, FlipH, FlipV}, blinker.Symmetries())
+
+	// Every orientation of the glider has the same canonical form
+	canonical, _ := glider.Canonical()
+	for _, orientation := range Orientations {
+		other, how := glider.Orient(orientation).Canonical()
+		assert.Equal(canonical.String(), other.String())
+		assert.Equal(canonical.String(), glider.Orient(orientation).Orient(how).String())
+	}
+}
@@ -0,0 +1,230 @@
+package cellaut
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// States used by site percolation
+const (
+	SiteEmpty    State = "-"
+	SiteOccupied State = "X"
+)
+
+/*
+RandomSites returns a width×height grid where each site is SiteOccupied with probability p, and
+SiteEmpty otherwise.
+*/
+func RandomSites(width, height int, p float64, rng *rand.Rand) *StateGrid {
+	grid := NewStateGrid(width, height, NewStateTable(SiteEmpty, SiteOccupied))
+	occupied := grid.Table.Intern(SiteOccupied)
+	for i := range grid.cells {
+		if rng.Float64() < p {
+			grid.cells[i] = occupied
+		}
+	}
+	return grid
+}
+
+/*
+Clusters is the result of labeling the connected clusters of cells in one state. Cells are
+connected if they're up, down, left or right of each other.
+*/
+type Clusters struct {
+	Width, Height int
+	// Labels[y*Width+x] is the cluster the cell at (x, y) belongs to, numbered from 1, or 0 if the
+	// cell isn't in the state
+	Labels []int
+	// Sizes[label-1] is how many cells are in the cluster with that label
+	Sizes []int
+	// Spanning lists the clusters that touch both the top and bottom rows
+	Spanning []int
+}
+
+/*
+LabelClusters finds the connected clusters of cells in the given state, with the Hoshen-Kopelman
+algorithm: one pass over the grid, merging labels with a union-find as clusters turn out to meet.
+*/
+func LabelClusters(grid *StateGrid, state State) *Clusters {
+	clusters := &Clusters{Width: grid.Width, Height: grid.Height, Labels: make([]int, len(grid.cells))}
+	id, ok := grid.Table.ID(state)
+	if !ok {
+		return clusters
+	}
+
+	// parent[l] is the label that provisional label l was merged into; parent[l] == l for roots
+	parent := []int{0}
+	find := func(l int) int {
+		for parent[l] != l {
+			parent[l] = parent[parent[l]]
+			l = parent[l]
+		}
+		return l
+	}
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			i := y*grid.Width + x
+			if grid.cells[i] != id {
+				continue
+			}
+			var left, down int
+			if x > 0 {
+				left = clusters.Labels[i-1]
+			}
+			if y > 0 {
+				down = clusters.Labels[i-grid.Width]
+			}
+			switch {
+			case left == 0 && down == 0:
+				parent = append(parent, len(parent))
+				clusters.Labels[i] = len(parent) - 1
+			case left == 0 || down == 0:
+				clusters.Labels[i] = find(left + down)
+			default:
+				rootLeft, rootDown := find(left), find(down)
+				if rootLeft < rootDown {
+					rootLeft, rootDown = rootDown, rootLeft
+				}
+				parent[rootLeft] = rootDown
+				clusters.Labels[i] = rootDown
+			}
+		}
+	}
+
+	// Renumber the roots 1, 2, 3... in the order they're first seen
+	final := make([]int, len(parent))
+	for i, l := range clusters.Labels {
+		if l == 0 {
+			continue
+		}
+		root := find(l)
+		if final[root] == 0 {
+			clusters.Sizes = append(clusters.Sizes, 0)
+			final[root] = len(clusters.Sizes)
+		}
+		clusters.Labels[i] = final[root]
+		clusters.Sizes[final[root]-1]++
+	}
+
+	if grid.Height > 0 {
+		bottom := make(map[int]bool)
+		for _, l := range clusters.Labels[:grid.Width] {
+			if l != 0 {
+				bottom[l] = true
+			}
+		}
+		seen := make(map[int]bool)
+		for _, l := range clusters.Labels[(grid.Height-1)*grid.Width:] {
+			if bottom[l] && !seen[l] {
+				seen[l] = true
+				clusters.Spanning = append(clusters.Spanning, l)
+			}
+		}
+		sort.Ints(clusters.Spanning)
+	}
+	return clusters
+}
+
+/*
+Largest returns the size of the biggest cluster, or 0 if there aren't any.
+*/
+func (clusters *Clusters) Largest() int {
+	largest := 0
+	for _, size := range clusters.Sizes {
+		if size > largest {
+			largest = size
+		}
+	}
+	return largest
+}
+
+/*
+PercolationResult sums up a batch of random grids filled at the same probability.
+*/
+type PercolationResult struct {
+	P      float64
+	Trials int
+	// Spanning is the fraction of grids that had a spanning cluster
+	Spanning float64
+	// MeanLargest is the mean size of the largest cluster, as a fraction of the grid
+	MeanLargest float64
+	// MeanClusters is the mean number of clusters
+	MeanClusters float64
+}
+
+/*
+PercolationSweep fills trials random size×size grids at each probability in ps and labels their
+clusters. Around p = 0.593, the chance of a spanning cluster jumps from near 0 to near 1.
+*/
+func PercolationSweep(ps []float64, size, trials int, rng *rand.Rand) ([]PercolationResult, error) {
+	if size < 1 || trials < 1 {
+		return nil, fmt.Errorf("size and trials must be at least 1")
+	}
+	var results []PercolationResult
+	for _, p := range ps {
+		if p < 0 || p > 1 {
+			return nil, fmt.Errorf("probability %g isn't between 0 and 1", p)
+		}
+		result := PercolationResult{P: p, Trials: trials}
+		for i := 0; i < trials; i++ {
+			clusters := LabelClusters(RandomSites(size, size, p, rng), SiteOccupied)
+			if len(clusters.Spanning) > 0 {
+				result.Spanning++
+			}
+			result.MeanLargest += float64(clusters.Largest()) / float64(size*size)
+			result.MeanClusters += float64(len(clusters.Sizes))
+		}
+		result.Spanning /= float64(trials)
+		result.MeanLargest /= float64(trials)
+		result.MeanClusters /= float64(trials)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+/*
+WritePercolationResults writes results as a table.
+*/
+func WritePercolationResults(w io.Writer, results []PercolationResult) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "P\tSPANNING\tLARGEST\tCLUSTERS")
+	for _, result := range results {
+		fmt.Fprintf(tw, "%.3f\t%.2f\t%.3f\t%.1f\n", result.P, result.Spanning, result.MeanLargest, result.MeanClusters)
+	}
+	return tw.Flush()
+}
+
+/*
+percolationCommand implements `cellaut percolation --from 0.5 --to 0.7 --step 0.02 --size 100
+--trials 50`, which runs PercolationSweep and prints the results.
+*/
+func percolationCommand(args []string) error {
+	fs := flag.NewFlagSet("percolation", flag.ContinueOnError)
+	from := fs.Float64("from", 0.5, "lowest fill probability")
+	to := fs.Float64("to", 0.7, "highest fill probability")
+	step := fs.Float64("step", 0.02, "how much to raise the probability by each time")
+	size := fs.Int("size", 100, "width and height of the grids")
+	trials := fs.Int("trials", 50, "how many grids to try at each probability")
+	seed := fs.Int64("seed", 1, "random seed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *step <= 0 || *to < *from {
+		return fmt.Errorf("--step must be positive and --to must be at least --from")
+	}
+	var ps []float64
+	// Allow for rounding so that --to itself is included
+	for i := 0; *from+float64(i)**step <= *to+*step/1e6; i++ {
+		ps = append(ps, *from+float64(i)**step)
+	}
+	results, err := PercolationSweep(ps, *size, *trials, rand.New(rand.NewSource(*seed)))
+	if err != nil {
+		return err
+	}
+	return WritePercolationResults(os.Stdout, results)
+}
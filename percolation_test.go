@@ -0,0 +1,71 @@
+package cellaut
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelClusters(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// The U only turns out to be one cluster on its top row
+	pattern := mustParsePattern(t, `
+		XXXX.X
+		X..X.X
+		X..X..
+		...X.X
+		XX....
+	`)
+	clusters := LabelClusters(pattern.StateGrid, "X")
+	assert.Equal([]int{2, 9, 1, 2}, clusters.Sizes)
+	assert.Equal(9, clusters.Largest())
+	assert.Equal([]int(nil), clusters.Spanning)
+	label := func(x, y int) int { return clusters.Labels[y*clusters.Width+x] }
+	assert.Equal(label(0, 2), label(3, 1))
+	assert.NotEqual(label(5, 4), label(5, 1))
+	assert.Equal(0, label(1, 1))
+
+	spanning := mustParsePattern(t, `
+		.X.
+		.XX
+		..X
+	`)
+	clusters = LabelClusters(spanning.StateGrid, "X")
+	assert.Equal([]int{1}, clusters.Spanning)
+
+	// No cells in the state at all
+	clusters = LabelClusters(spanning.StateGrid, "O")
+	assert.Nil(clusters.Sizes)
+	assert.Equal(0, clusters.Largest())
+}
+
+func TestPercolationSweep(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	rng := rand.New(rand.NewSource(1))
+	results, err := PercolationSweep([]float64{0, 0.3, 0.9, 1}, 30, 10, rng)
+	assert.Nil(err)
+	assert.Equal(0.0, results[0].Spanning)
+	assert.Equal(0.0, results[0].MeanLargest)
+	// Well below and above the threshold
+	assert.True(results[1].Spanning < 0.2)
+	assert.True(results[2].Spanning > 0.8)
+	assert.Equal(1.0, results[3].Spanning)
+	assert.Equal(1.0, results[3].MeanLargest)
+	assert.Equal(1.0, results[3].MeanClusters)
+
+	_, err = PercolationSweep([]float64{1.5}, 10, 1, rng)
+	assert.NotNil(err)
+
+	var b bytes.Buffer
+	assert.Nil(WritePercolationResults(&b, results))
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Equal(5, len(lines))
+	assert.True(strings.HasPrefix(lines[4], "1.000  1.00"))
+}
@@ -0,0 +1,224 @@
+package cellaut
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+)
+
+/*
+NumericSeries turns a probe's States into numbers to plot. If every State is a number, that's what
+it's plotted as. Otherwise each State is numbered in the order it first turns up, starting from 0.
+*/
+func NumericSeries(states []State) []float64 {
+	series := make([]float64, len(states))
+	numeric := true
+	for i, state := range states {
+		f, err := strconv.ParseFloat(string(state), 64)
+		if err != nil {
+			numeric = false
+			break
+		}
+		series[i] = f
+	}
+	if numeric {
+		return series
+	}
+	seen := make(map[State]float64)
+	for i, state := range states {
+		if _, ok := seen[state]; !ok {
+			seen[state] = float64(len(seen))
+		}
+		series[i] = seen[state]
+	}
+	return series
+}
+
+/*
+Autocorrelation returns the autocorrelation of the series at lags 0 through maxLag. A periodic
+series comes back to nearly 1 at multiples of its period; a chaotic one falls off toward 0 and
+stays there. A series that never changes has no variance to correlate, so it's all 1s.
+*/
+func Autocorrelation(series []float64, maxLag int) []float64 {
+	if maxLag >= len(series) {
+		maxLag = len(series) - 1
+	}
+	if maxLag < 0 {
+		return nil
+	}
+	mean := 0.0
+	for _, x := range series {
+		mean += x
+	}
+	mean /= float64(len(series))
+	variance := 0.0
+	for _, x := range series {
+		variance += (x - mean) * (x - mean)
+	}
+	acf := make([]float64, maxLag+1)
+	for lag := range acf {
+		if variance == 0 {
+			acf[lag] = 1
+			continue
+		}
+		sum := 0.0
+		for t := 0; t+lag < len(series); t++ {
+			sum += (series[t] - mean) * (series[t+lag] - mean)
+		}
+		acf[lag] = sum / variance
+	}
+	return acf
+}
+
+/*
+SeriesPeriod returns the smallest period, up to maxPeriod, that the end of the series repeats with
+exactly for at least two full periods. ok is false if there isn't one.
+*/
+func SeriesPeriod(series []float64, maxPeriod int) (period int, ok bool) {
+	for period = 1; period <= maxPeriod && 2*period <= len(series); period++ {
+		repeats := true
+		for t := len(series) - period; t < len(series); t++ {
+			if series[t] != series[t-period] {
+				repeats = false
+				break
+			}
+		}
+		if repeats {
+			return period, true
+		}
+	}
+	return 0, false
+}
+
+// Colors used by the plots
+var (
+	plotBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	plotAxis       = color.RGBA{0xa0, 0xa0, 0xa0, 0xff}
+	plotInk        = color.RGBA{0x20, 0x40, 0xd0, 0xff}
+)
+
+// plotMargin is the space between the edge of a plot and its axes, in pixels
+const plotMargin = 4
+
+/*
+ReturnMap plots each value of the series against the one lag ticks later, in a size×size image. A
+fixed point shows up as one dot, a cycle as a few, quasi-periodic behavior as a closed curve and
+chaos as a cloud with structure to it. Where it has room, each point is joined to the next one with
+a faint line, so the order they come in can be followed.
+*/
+func ReturnMap(series []float64, lag, size int) *image.RGBA {
+	img := newPlot(size, size)
+	if lag < 1 || len(series) <= lag {
+		return img
+	}
+	lo, hi := seriesRange(series)
+	scale := func(v float64) int {
+		return plotMargin + int(math.Round((v-lo)/(hi-lo)*float64(size-1-2*plotMargin)))
+	}
+	faint := color.RGBA{0xc0, 0xc8, 0xf0, 0xff}
+	prevX, prevY := -1, -1
+	for t := 0; t+lag < len(series); t++ {
+		x, y := scale(series[t]), size-1-scale(series[t+lag])
+		if prevX >= 0 {
+			drawLine(img, prevX, prevY, x, y, faint)
+		}
+		prevX, prevY = x, y
+	}
+	for t := 0; t+lag < len(series); t++ {
+		x, y := scale(series[t]), size-1-scale(series[t+lag])
+		img.Set(x, y, plotInk)
+	}
+	return img
+}
+
+/*
+PlotAutocorrelation draws the output of Autocorrelation as a bar for each lag, going up from the
+middle of the image for positive correlation and down for negative.
+*/
+func PlotAutocorrelation(acf []float64, width, height int) *image.RGBA {
+	img := newPlot(width, height)
+	if len(acf) == 0 {
+		return img
+	}
+	zero := height / 2
+	barWidth := float64(width-2*plotMargin) / float64(len(acf))
+	for lag, r := range acf {
+		x0 := plotMargin + int(float64(lag)*barWidth)
+		x1 := plotMargin + int(float64(lag+1)*barWidth)
+		if x1 > x0+1 {
+			// Leave a gap between bars
+			x1--
+		}
+		top := zero - int(math.Round(r*float64(zero-plotMargin)))
+		y0, y1 := top, zero
+		if y0 > y1 {
+			y0, y1 = y1, y0
+		}
+		draw.Draw(img, image.Rect(x0, y0, x1, y1+1), image.NewUniform(plotInk), image.Point{}, draw.Src)
+	}
+	return img
+}
+
+/*
+newPlot returns a blank plot with a frame of axis lines.
+*/
+func newPlot(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(plotBackground), image.Point{}, draw.Src)
+	drawLine(img, plotMargin-1, height-plotMargin, width-plotMargin, height-plotMargin, plotAxis)
+	drawLine(img, plotMargin-1, plotMargin-1, plotMargin-1, height-plotMargin, plotAxis)
+	return img
+}
+
+/*
+seriesRange returns the smallest and largest values in the series, spread apart if they're the
+same so there's something to divide by.
+*/
+func seriesRange(series []float64) (lo, hi float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, v := range series {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	if lo == hi {
+		lo, hi = lo-1, hi+1
+	}
+	return lo, hi
+}
+
+/*
+drawLine draws a line from (x0, y0) to (x1, y1) with Bresenham's algorithm.
+*/
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+	dx, dy := x1-x0, y1-y0
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx - dy
+	for {
+		img.Set(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 > -dy {
+			err -= dy
+			x0 += sx
+		}
+		if e2 < dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
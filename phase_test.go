@@ -0,0 +1,83 @@
+package cellaut
+
+import (
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countInk returns how many pixels of the plot are in plotInk
+func countInk(img *image.RGBA) int {
+	n := 0
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			if img.RGBAAt(x, y) == plotInk {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestNumericSeries(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal([]float64{3, 1.5, -2}, NumericSeries([]State{"3", "1.5", "-2"}))
+	assert.Equal([]float64{0, 1, 0, 2}, NumericSeries([]State{"X", "-", "X", "3"}))
+	assert.Empty(NumericSeries(nil))
+}
+
+func TestAutocorrelation(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A blinker's population doesn't change; one end of it goes on and off
+	assert.Equal([]float64{1, 1, 1}, Autocorrelation([]float64{3, 3, 3, 3}, 2))
+	acf := Autocorrelation([]float64{1, 0, 1, 0, 1, 0, 1, 0}, 10)
+	assert.Len(acf, 8)
+	assert.InDelta(1, acf[0], 1e-9)
+	assert.True(acf[1] < -0.8)
+	assert.True(acf[2] > 0.7)
+	assert.Nil(Autocorrelation(nil, 3))
+
+	period, ok := SeriesPeriod([]float64{5, 9, 1, 2, 3, 1, 2, 3}, 4)
+	assert.True(ok)
+	assert.Equal(3, period)
+	_, ok = SeriesPeriod([]float64{1, 2, 3, 4, 5}, 4)
+	assert.False(ok)
+}
+
+func TestReturnMap(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A cycle of period 2 is two dots
+	img := ReturnMap([]float64{1, 5, 1, 5, 1, 5}, 1, 50)
+	assert.Equal(image.Rect(0, 0, 50, 50), img.Bounds())
+	assert.Equal(2, countInk(img))
+
+	// The logistic map at r=4 is chaotic, and fills in a parabola
+	series := []float64{0.3}
+	for len(series) < 500 {
+		x := series[len(series)-1]
+		series = append(series, 4*x*(1-x))
+	}
+	assert.True(countInk(ReturnMap(series, 1, 50)) > 40)
+
+	// Not enough to plot
+	assert.Equal(0, countInk(ReturnMap([]float64{1}, 1, 50)))
+}
+
+func TestPlotAutocorrelation(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	img := PlotAutocorrelation([]float64{1, -1}, 20, 41)
+	assert.Equal(image.Rect(0, 0, 20, 41), img.Bounds())
+	// Lag 0 goes all the way up, and lag 1 all the way down
+	assert.Equal(plotInk, img.RGBAAt(plotMargin, plotMargin))
+	assert.Equal(plotInk, img.RGBAAt(19-plotMargin-1, 40-plotMargin))
+	assert.Equal(plotBackground, img.RGBAAt(plotMargin, 40-plotMargin-1))
+}
@@ -0,0 +1,264 @@
+package cellaut
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+/*
+playgroundSession is what runs in the browser in `cellaut playground`: one of the demo presets,
+maybe with its rule swapped for one typed into the page.
+*/
+type playgroundSession struct {
+	preset  demoPreset
+	grid    *StateGrid
+	step    func(grid *StateGrid, tick int) *StateGrid
+	palette Palette
+	tick    int
+	// inspector backs the tooltip on the page
+	inspector *Inspector
+}
+
+// playgroundInspectDepth is how many ticks of history the playground's tooltip shows
+const playgroundInspectDepth = 8
+
+/*
+load starts the named demo preset over from the beginning.
+*/
+func (session *playgroundSession) load(name string, seed int64) error {
+	preset, ok := findPreset(name)
+	if !ok {
+		return fmt.Errorf("no demo named '%s'", name)
+	}
+	rng := rand.New(rand.NewSource(seed))
+	grid := NewStateGrid(preset.width, preset.height, NewStateTable("-"))
+	if err := preset.setup(grid, rng); err != nil {
+		return fmt.Errorf("setting up demo '%s': %s", preset.name, err)
+	}
+	step := preset.step
+	if step == nil {
+		rule := preset.rule(rng)
+		step = func(grid *StateGrid, tick int) *StateGrid { return stepGrid(grid, rule, preset.neighborhood) }
+	}
+	*session = playgroundSession{preset: preset, grid: grid, step: step, palette: preset.palette, inspector: NewInspector(playgroundInspectDepth)}
+	session.inspector.Record(0, grid)
+	return nil
+}
+
+/*
+setRule switches the grid over to the rule in text, which is one of:
+
+  - a whole rule table, starting with @RULE
+  - the name of a registered rule, followed by any options, like "forest-fire growth=0.05"
+  - a Life-like rulestring, like B36/S23
+*/
+func (session *playgroundSession) setRule(text string) error {
+	text = strings.TrimSpace(text)
+	neighborhood := session.preset.neighborhood
+	if neighborhood == nil {
+		neighborhood = mooreNeighborhood
+	}
+	var rule Rule
+	switch {
+	case text == "":
+		return fmt.Errorf("no rule given")
+	case strings.HasPrefix(text, "@RULE"):
+		table, err := ParseRuleTable(strings.NewReader(text))
+		if err != nil {
+			return err
+		}
+		rule, neighborhood = table.Rule(), table.neighborhood()
+		if table.Palette != nil {
+			session.palette = table.Palette
+		}
+	default:
+		var err error
+		if rule, err = lookupRuleSpec(text); err != nil {
+			return err
+		}
+	}
+	session.step = func(grid *StateGrid, tick int) *StateGrid { return stepGrid(grid, rule, neighborhood) }
+	return nil
+}
+
+/*
+advance runs n ticks.
+*/
+func (session *playgroundSession) advance(n int) {
+	for i := 0; i < n; i++ {
+		session.tick++
+		session.grid = session.step(session.grid, session.tick)
+		session.inspector.Record(int64(session.tick), session.grid)
+	}
+}
+
+/*
+frame draws the grid one pixel per cell, as RGBA bytes with the top row first, ready to put on a
+canvas. It returns an error if the palette has too many States to draw.
+*/
+func (session *playgroundSession) frame() ([]byte, error) {
+	paletted, err := RenderGrid(session.grid, session.palette, 1)
+	if err != nil {
+		return nil, err
+	}
+	rgba := image.NewRGBA(paletted.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), paletted, image.Point{}, draw.Src)
+	return rgba.Pix, nil
+}
+
+/*
+playgroundHandler serves the playground page, along with cellaut built for WASM and the
+wasm_exec.js that comes with Go to load it.
+*/
+func playgroundHandler(wasmPath, wasmExecPath string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, playgroundPage)
+	})
+	mux.HandleFunc("/cellaut.wasm", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/wasm")
+		http.ServeFile(w, r, wasmPath)
+	})
+	mux.HandleFunc("/wasm_exec.js", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, wasmExecPath)
+	})
+	return mux
+}
+
+/*
+playgroundCommand implements `cellaut playground --wasm cellaut.wasm [--addr localhost:8080]`.
+Everything runs in the browser, so the server only hands out files.
+*/
+func playgroundCommand(args []string) error {
+	fs := flag.NewFlagSet("playground", flag.ContinueOnError)
+	addr := fs.String("addr", "localhost:8080", "address to serve the playground on")
+	wasmPath := fs.String("wasm", "cellaut.wasm", "cmd/cellaut built with GOOS=js GOARCH=wasm")
+	wasmExecPath := fs.String("wasm-exec", filepath.Join(runtime.GOROOT(), "lib", "wasm", "wasm_exec.js"), "the wasm_exec.js that comes with the Go that built --wasm")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if _, err := os.Stat(*wasmPath); err != nil {
+		return fmt.Errorf("%s; build it with `GOOS=js GOARCH=wasm go build -o cellaut.wasm ./cmd/cellaut`", err)
+	}
+	if _, err := os.Stat(*wasmExecPath); err != nil {
+		return fmt.Errorf("%s; point --wasm-exec at the wasm_exec.js in $(go env GOROOT)/lib/wasm", err)
+	}
+	fmt.Printf("serving the playground on http://%s/\n", *addr)
+	return http.ListenAndServe(*addr, playgroundHandler(*wasmPath, *wasmExecPath))
+}
+
+// playgroundPage runs `cellaut wasm` in the browser, which leaves a cellaut object behind for the
+// page to drive
+const playgroundPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>cellaut playground</title>
+<style>
+  body { font-family: sans-serif; margin: 1em; }
+  #controls > * { margin-right: 0.5em; }
+  #rule { width: 40em; height: 6em; font-family: monospace; display: block; margin: 0.5em 0; }
+  canvas { image-rendering: pixelated; border: 1px solid #888; margin-top: 0.5em; }
+  #error { color: #c00; }
+  #inspect { font-family: monospace; margin-top: 0.5em; min-height: 1.2em; }
+</style>
+<script src="wasm_exec.js"></script>
+</head>
+<body>
+<div id="controls">
+  <select id="preset"></select>
+  <button id="play">play</button>
+  <button id="step">step</button>
+  <button id="reset">reset</button>
+  ticks per frame <input id="speed" type="number" value="1" min="1" max="100">
+  <span id="tick"></span>
+</div>
+<textarea id="rule" placeholder="B36/S23, a rule name like forest-fire growth=0.05, or an @RULE table"></textarea>
+<button id="apply">apply rule</button> <span id="error"></span>
+<br>
+<canvas id="grid"></canvas>
+<div id="inspect"></div>
+<script>
+const $ = (id) => document.getElementById(id);
+const canvas = $("grid"), ctx = canvas.getContext("2d");
+let playing = false;
+let hover = null;
+
+function draw() {
+  const f = cellaut.frame();
+  canvas.width = f.width;
+  canvas.height = f.height;
+  canvas.style.width = (f.width * 8) + "px";
+  canvas.style.height = (f.height * 8) + "px";
+  ctx.putImageData(new ImageData(new Uint8ClampedArray(f.pixels), f.width, f.height), 0, 0);
+  $("tick").textContent = "tick " + f.tick;
+  inspect();
+}
+
+// Cells are 8 pixels square on the page, and the grid's y axis points up
+function inspect() {
+  $("inspect").textContent = hover ? cellaut.inspect(hover.x, canvas.height - 1 - hover.y) : "";
+}
+
+function report(err) {
+  $("error").textContent = err || "";
+}
+
+function load() {
+  report(cellaut.load($("preset").value, 1));
+  draw();
+}
+
+function frame() {
+  if (!playing) return;
+  cellaut.step(parseInt($("speed").value, 10) || 1);
+  draw();
+  requestAnimationFrame(frame);
+}
+
+const go = new Go();
+go.argv = ["cellaut", "wasm"];
+WebAssembly.instantiateStreaming(fetch("cellaut.wasm"), go.importObject).then((result) => {
+  go.run(result.instance);
+  for (const name of cellaut.presets()) {
+    const option = document.createElement("option");
+    option.textContent = name;
+    $("preset").appendChild(option);
+  }
+  $("preset").onchange = load;
+  $("reset").onclick = load;
+  $("step").onclick = () => { cellaut.step(1); draw(); };
+  $("play").onclick = () => {
+    playing = !playing;
+    $("play").textContent = playing ? "pause" : "play";
+    requestAnimationFrame(frame);
+  };
+  $("apply").onclick = () => report(cellaut.setRule($("rule").value));
+  canvas.onmousemove = (e) => {
+    hover = { x: Math.floor(e.offsetX / 8), y: Math.floor(e.offsetY / 8) };
+    inspect();
+  };
+  canvas.onmouseleave = () => { hover = null; inspect(); };
+  load();
+});
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,69 @@
+//go:build js && wasm
+
+package cellaut
+
+import (
+	"sort"
+	"syscall/js"
+)
+
+func init() {
+	commands["wasm"] = wasmCommand
+}
+
+/*
+wasmCommand implements `cellaut wasm`, which only makes sense in a browser: it's what the
+playground page runs. It leaves a global cellaut object for the page to drive a playgroundSession
+with, and never returns.
+*/
+func wasmCommand(args []string) error {
+	session := &playgroundSession{}
+	errValue := func(err error) interface{} {
+		if err != nil {
+			return err.Error()
+		}
+		return nil
+	}
+	js.Global().Set("cellaut", js.ValueOf(map[string]interface{}{
+		"presets": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			names := make([]interface{}, len(demoPresets))
+			for i, preset := range demoPresets {
+				names[i] = preset.name
+			}
+			sort.Slice(names, func(i, j int) bool { return names[i].(string) < names[j].(string) })
+			return names
+		}),
+		"load": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return errValue(session.load(args[0].String(), int64(args[1].Int())))
+		}),
+		"setRule": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return errValue(session.setRule(args[0].String()))
+		}),
+		"step": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			session.advance(args[0].Int())
+			return nil
+		}),
+		"inspect": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			info, err := session.inspector.Inspect(args[0].Int(), args[1].Int())
+			if err != nil {
+				return err.Error()
+			}
+			return info.String()
+		}),
+		"frame": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			pix, err := session.frame()
+			if err != nil {
+				return err.Error()
+			}
+			pixels := js.Global().Get("Uint8Array").New(len(pix))
+			js.CopyBytesToJS(pixels, pix)
+			return map[string]interface{}{
+				"width":  session.grid.Width,
+				"height": session.grid.Height,
+				"tick":   session.tick,
+				"pixels": pixels,
+			}
+		}),
+	}))
+	select {}
+}
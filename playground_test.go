@@ -0,0 +1,105 @@
+package cellaut
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaygroundSession(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	session := &playgroundSession{}
+	assert.NotNil(session.load("bogus", 1))
+	assert.Nil(session.load("glider-gun", 1))
+	preset, _ := findPreset("glider-gun")
+	pix, err := session.frame()
+	assert.Nil(err)
+	assert.Equal(preset.width*preset.height*4, len(pix))
+
+	// Same as the demo, tick for tick
+	var want *StateGrid
+	assert.Nil(preset.run(30, 1, func(tick int, grid *StateGrid) { want = grid }))
+	session.advance(30)
+	assert.Equal(30, session.tick)
+	assert.Equal((&Pattern{StateGrid: want}).String(), (&Pattern{StateGrid: session.grid}).String())
+
+	info, err := session.inspector.Inspect(0, 0)
+	assert.Nil(err)
+	assert.Equal(int64(30), info.Age)
+	assert.Len(info.History, playgroundInspectDepth)
+
+	// The top left cell is empty, so it's drawn in white
+	pix, err = session.frame()
+	assert.Nil(err)
+	assert.Equal([]byte{0xff, 0xff, 0xff, 0xff}, pix[:4])
+
+	assert.NotNil(session.setRule(""))
+	assert.NotNil(session.setRule("B3/S23 extra"))
+	assert.NotNil(session.setRule("elementary number=lots"))
+	assert.NotNil(session.setRule("@RULE Broken\n@TABLE\nn_states:1\n"))
+
+	// B/S: everything dies
+	assert.Nil(session.setRule("B/S"))
+	session.advance(1)
+	assert.Equal(int64(0), session.grid.Population("X"))
+
+	assert.Nil(session.setRule(`@RULE Spread
+@TABLE
+n_states:2
+neighborhood:vonNeumann
+symmetries:permute
+0,1,0,0,0,1
+@COLORS
+1 255 0 0
+`))
+	session.grid.Set(0, 0, "1")
+	session.advance(2)
+	assert.Equal("1", string(session.grid.At(0, 2)))
+	// Two live neighbors isn't one
+	assert.Equal("-", string(session.grid.At(1, 1)))
+	pix, err = session.frame()
+	assert.Nil(err)
+	assert.Equal([]byte{0xff, 0, 0, 0xff}, pix[((preset.height-1)*preset.width)*4:][:4])
+
+	assert.Nil(session.setRule("wireworld"))
+}
+
+func TestPlaygroundHandler(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	wasmPath, wasmExecPath := filepath.Join(dir, "cellaut.wasm"), filepath.Join(dir, "wasm_exec.js")
+	assert.Nil(ioutil.WriteFile(wasmPath, []byte("\x00asm"), 0644))
+	assert.Nil(ioutil.WriteFile(wasmExecPath, []byte("// Go"), 0644))
+	server := httptest.NewServer(playgroundHandler(wasmPath, wasmExecPath))
+	defer server.Close()
+
+	get := func(path string) (*http.Response, string) {
+		resp, err := http.Get(server.URL + path)
+		assert.Nil(err)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.Nil(err)
+		return resp, string(body)
+	}
+	resp, body := get("/")
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Contains(body, `go.argv = ["cellaut", "wasm"]`)
+	resp, body = get("/cellaut.wasm")
+	assert.Equal("application/wasm", resp.Header.Get("Content-Type"))
+	assert.Equal("\x00asm", body)
+	_, body = get("/wasm_exec.js")
+	assert.Equal("// Go", body)
+	resp, _ = get("/nope")
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+
+	assert.NotNil(playgroundCommand([]string{"--wasm", filepath.Join(dir, "missing.wasm")}))
+	assert.NotNil(playgroundCommand([]string{"--wasm", wasmPath, "--wasm-exec", filepath.Join(dir, "missing.js")}))
+}
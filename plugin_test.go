@@ -0,0 +1,52 @@
+package cellaut
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterPluginRules(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// What Lookup would hand back for a plugin's CellautRules
+	name := uniqueName("test-plugin-count")
+	rules := map[string]PluginRule{
+		name: func(self string, neighbors map[uint8]string) string {
+			n := 0
+			for _, state := range neighbors {
+				if state == "X" {
+					n++
+				}
+			}
+			return string(rune('0' + n))
+		},
+	}
+	names, err := registerPluginRules(&rules)
+	assert.Nil(err)
+	assert.Equal([]string{name}, names)
+
+	rule, err := LookupRule(name, nil)
+	assert.Nil(err)
+	assert.Equal(State("2"), rule("-", map[NeighborIndex]State{NeighborUp: "X", NeighborLf: "X", NeighborDn: "-"}))
+
+	// Registering the same names again
+	_, err = registerPluginRules(&rules)
+	assert.NotNil(err)
+	_, err = registerPluginRules(rules)
+	assert.NotNil(err)
+}
+
+func TestLoadPlugin(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := LoadPlugin(filepath.Join(t.TempDir(), "missing.so"))
+	assert.NotNil(err)
+
+	t.Setenv(pluginsEnv, "")
+	assert.Nil(LoadPlugins())
+	t.Setenv(pluginsEnv, filepath.Join(t.TempDir(), "missing.so"))
+	assert.NotNil(LoadPlugins())
+}
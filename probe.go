@@ -0,0 +1,171 @@
+package cellaut
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+/*
+Probe watches a few cells: Region, or the single cell at its corner if Region has no width or
+height.
+*/
+type Probe struct {
+	Name   string
+	Region Rect
+}
+
+/*
+cells returns the coordinates the probe watches, bottom row first, along with what each one's
+column is called.
+*/
+func (probe Probe) cells() (coords [][2]int, columns []string) {
+	region := probe.Region
+	if region.Width <= 0 || region.Height <= 0 {
+		return [][2]int{{region.X, region.Y}}, []string{probe.Name}
+	}
+	for dy := 0; dy < region.Height; dy++ {
+		for dx := 0; dx < region.Width; dx++ {
+			coords = append(coords, [2]int{region.X + dx, region.Y + dy})
+			columns = append(columns, fmt.Sprintf("%s(%d,%d)", probe.Name, dx, dy))
+		}
+	}
+	return coords, columns
+}
+
+/*
+ProbeRecorder samples the cells under its Probes every tick, keeping a time series of their States.
+Looking at a handful of cells is a lot cheaper than keeping a copy of the whole grid every tick,
+when those cells are all you care about.
+
+It's safe for concurrent use.
+*/
+type ProbeRecorder struct {
+	mu      sync.Mutex
+	coords  [][2]int
+	columns []string
+	names   map[string]bool
+	ticks   []int64
+	// samples[i][j] is the State of cell j at ticks[i]
+	samples [][]State
+}
+
+/*
+NewProbeRecorder returns a ProbeRecorder watching the given probes, which must have different
+names.
+*/
+func NewProbeRecorder(probes ...Probe) (*ProbeRecorder, error) {
+	recorder := &ProbeRecorder{names: make(map[string]bool)}
+	for _, probe := range probes {
+		if err := recorder.Add(probe); err != nil {
+			return nil, err
+		}
+	}
+	return recorder, nil
+}
+
+/*
+Add starts watching another probe. It can't be added once sampling has started, since every row of
+the time series has to have the same columns.
+*/
+func (recorder *ProbeRecorder) Add(probe Probe) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if probe.Name == "" {
+		return fmt.Errorf("probe has no name")
+	}
+	if recorder.names[probe.Name] {
+		return fmt.Errorf("there's already a probe named '%s'", probe.Name)
+	}
+	if len(recorder.ticks) > 0 {
+		return fmt.Errorf("can't add probe '%s' after sampling has started", probe.Name)
+	}
+	coords, columns := probe.cells()
+	recorder.names[probe.Name] = true
+	recorder.coords = append(recorder.coords, coords...)
+	recorder.columns = append(recorder.columns, columns...)
+	return nil
+}
+
+/*
+Sample records the States of the probed cells at the given tick, reading them with at. Cells outside
+the width×height grid are recorded as "".
+*/
+func (recorder *ProbeRecorder) Sample(tick int64, width, height int, at func(x, y int) State) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	bounds := Rect{Width: width, Height: height}
+	row := make([]State, len(recorder.coords))
+	for i, xy := range recorder.coords {
+		if bounds.Contains(xy[0], xy[1]) {
+			row[i] = at(xy[0], xy[1])
+		}
+	}
+	recorder.ticks = append(recorder.ticks, tick)
+	recorder.samples = append(recorder.samples, row)
+}
+
+/*
+SampleGrid records the probed cells of grid at the given tick.
+*/
+func (recorder *ProbeRecorder) SampleGrid(tick int64, grid *StateGrid) {
+	recorder.Sample(tick, grid.Width, grid.Height, grid.At)
+}
+
+/*
+Sink returns a Sink that samples a Simulation's World after every tick.
+*/
+func (recorder *ProbeRecorder) Sink() Sink {
+	return func(event TickEvent) {
+		width, height := event.World.Size()
+		recorder.Sample(event.TickID, width, height, func(x, y int) State { return event.World.At(x, y).GetState() })
+	}
+}
+
+/*
+Series returns the time series of the probe's cell with the given column name: the probe's name for
+a single cell, or name(dx,dy) for a cell of a region, counting from its bottom left corner. It
+returns nil if there's no such column.
+*/
+func (recorder *ProbeRecorder) Series(column string) (ticks []int64, states []State) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	for j, name := range recorder.columns {
+		if name != column {
+			continue
+		}
+		states = make([]State, len(recorder.samples))
+		for i, row := range recorder.samples {
+			states[i] = row[j]
+		}
+		return append([]int64(nil), recorder.ticks...), states
+	}
+	return nil, nil
+}
+
+/*
+WriteCSV writes the time series as CSV: a header row, then a row per tick starting with the tick
+and followed by a column per probed cell.
+*/
+func (recorder *ProbeRecorder) WriteCSV(w io.Writer) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	out := csv.NewWriter(w)
+	if err := out.Write(append([]string{"tick"}, recorder.columns...)); err != nil {
+		return err
+	}
+	record := make([]string, len(recorder.columns)+1)
+	for i, row := range recorder.samples {
+		record[0] = strconv.FormatInt(recorder.ticks[i], 10)
+		for j, state := range row {
+			record[j+1] = string(state)
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
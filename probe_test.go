@@ -0,0 +1,73 @@
+package cellaut
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeRecorder(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	_, err := NewProbeRecorder(Probe{Name: "a"}, Probe{Name: "a"})
+	assert.NotNil(err)
+	_, err = NewProbeRecorder(Probe{})
+	assert.NotNil(err)
+
+	recorder, err := NewProbeRecorder(
+		Probe{Name: "middle", Region: Rect{X: 2, Y: 1}},
+		Probe{Name: "end", Region: Rect{X: 1, Y: 1, Width: 1, Height: 2}},
+		Probe{Name: "outside", Region: Rect{X: 9, Y: 9}},
+	)
+	assert.Nil(err)
+
+	// A blinker
+	pattern, err := ParsePattern("-----\n-XXX-\n-----\n", NewStateTable("-"))
+	assert.Nil(err)
+	grid := pattern.StateGrid
+	for tick := int64(0); tick < 3; tick++ {
+		recorder.SampleGrid(tick, grid)
+		grid = stepGrid(grid, Life, mooreNeighborhood)
+	}
+	assert.NotNil(recorder.Add(Probe{Name: "late"}))
+
+	ticks, states := recorder.Series("middle")
+	assert.Equal([]int64{0, 1, 2}, ticks)
+	assert.Equal([]State{"X", "X", "X"}, states)
+	_, states = recorder.Series("end(0,0)")
+	assert.Equal([]State{"X", "-", "X"}, states)
+	_, states = recorder.Series("bogus")
+	assert.Nil(states)
+
+	var b strings.Builder
+	assert.Nil(recorder.WriteCSV(&b))
+	assert.Equal(strings.Join([]string{
+		"tick,middle,\"end(0,0)\",\"end(0,1)\",outside",
+		"0,X,X,-,",
+		"1,X,-,-,",
+		"2,X,X,-,",
+		"",
+	}, "\n"), b.String())
+}
+
+func TestProbeRecorder_Sink(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	world := newGooRow(6)
+	world.cells[0].SetState("X")
+	recorder, err := NewProbeRecorder(Probe{Name: "far", Region: Rect{X: 5}})
+	assert.Nil(err)
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: world, MaxTicks: 8}))
+	sim.Subscribe(recorder.Sink())
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+
+	ticks, states := recorder.Series("far")
+	assert.Len(ticks, 8)
+	assert.NotEqual(State("X"), states[0])
+	assert.Equal(State("X"), states[7])
+}
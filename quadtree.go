@@ -0,0 +1,283 @@
+package cellaut
+
+/*
+QuadNode is a square block of cells, 2^Level on a side, in a QuadUniverse.
+
+Nodes are canonical: within a QuadUniverse, two blocks with the same contents are the same
+*QuadNode. That makes comparing blocks a pointer comparison, lets a pattern with a lot of repetition
+take up very little memory, and is what HashLife needs to memoize its results.
+
+Nodes are immutable once made.
+*/
+type QuadNode struct {
+	Level uint
+	// NW, NE, SW and SE are the four quadrants, each a level lower. They're nil for a leaf.
+	NW, NE, SW, SE *QuadNode
+	// ID is the StateID of a leaf's cell. It's 0 for anything that isn't a leaf.
+	ID StateID
+	// Population is how many cells in the block have a StateID other than 0
+	Population int64
+}
+
+/*
+quadKey identifies a non-leaf node by its quadrants, which are canonical already.
+*/
+type quadKey struct {
+	nw, ne, sw, se *QuadNode
+}
+
+/*
+QuadUniverse is an unbounded grid of cells stored as a canonical quadtree.
+
+It's the shared spatial index for anything that wants to skip over big empty or repetitive areas:
+HashLife, the macrocell format, and rendering a viewport onto a sparse universe. StateID 0, the
+first State in the Table, is the empty state that the universe is full of.
+
+Coordinates can be negative. The root is always centered on (0, 0) and grows as needed. As
+everywhere else, y increases going up.
+
+A QuadUniverse isn't safe for concurrent use.
+*/
+type QuadUniverse struct {
+	Table *StateTable
+	Root  *QuadNode
+	// nodes holds every non-leaf node ever made, so that they can be canonicalized
+	nodes map[quadKey]*QuadNode
+	// leaves[id] is the leaf for StateID id
+	leaves []*QuadNode
+	// empty[level] is the empty node of that level
+	empty []*QuadNode
+	// counts memoizes how many cells of each state a node has
+	counts map[quadCountKey]int64
+	// hashes memoizes each node's Hash, relative to its bottom left corner
+	hashes map[*QuadNode]uint64
+}
+
+/*
+NewQuadUniverse returns an empty *QuadUniverse. If table is nil, a new StateTable containing just
+the empty State is used.
+*/
+func NewQuadUniverse(table *StateTable) *QuadUniverse {
+	if table == nil {
+		table = NewStateTable("")
+	}
+	universe := &QuadUniverse{
+		Table: table,
+		nodes: make(map[quadKey]*QuadNode),
+	}
+	universe.Root = universe.Empty(1)
+	return universe
+}
+
+/*
+Leaf returns the canonical leaf node for a StateID.
+*/
+func (universe *QuadUniverse) Leaf(id StateID) *QuadNode {
+	for StateID(len(universe.leaves)) <= id {
+		leafID := StateID(len(universe.leaves))
+		leaf := &QuadNode{ID: leafID}
+		if leafID != 0 {
+			leaf.Population = 1
+		}
+		universe.leaves = append(universe.leaves, leaf)
+	}
+	return universe.leaves[id]
+}
+
+/*
+Node returns the canonical node with the given quadrants, which must all be the same level and come
+from this universe.
+*/
+func (universe *QuadUniverse) Node(nw, ne, sw, se *QuadNode) *QuadNode {
+	key := quadKey{nw, ne, sw, se}
+	if node, ok := universe.nodes[key]; ok {
+		return node
+	}
+	node := &QuadNode{
+		Level:      nw.Level + 1,
+		NW:         nw,
+		NE:         ne,
+		SW:         sw,
+		SE:         se,
+		Population: nw.Population + ne.Population + sw.Population + se.Population,
+	}
+	universe.nodes[key] = node
+	return node
+}
+
+/*
+Empty returns the canonical empty node of the given level.
+*/
+func (universe *QuadUniverse) Empty(level uint) *QuadNode {
+	if len(universe.empty) == 0 {
+		universe.empty = append(universe.empty, universe.Leaf(0))
+	}
+	for uint(len(universe.empty)) <= level {
+		e := universe.empty[len(universe.empty)-1]
+		universe.empty = append(universe.empty, universe.Node(e, e, e, e))
+	}
+	return universe.empty[level]
+}
+
+/*
+NodeCount returns how many distinct non-leaf nodes the universe has made. Since nodes are never
+thrown away, it only grows.
+*/
+func (universe *QuadUniverse) NodeCount() int {
+	return len(universe.nodes)
+}
+
+/*
+Bounds returns the square of coordinates the root currently covers: x and y both range from min
+(inclusive) to max (exclusive).
+*/
+func (universe *QuadUniverse) Bounds() (min, max int) {
+	half := 1 << (universe.Root.Level - 1)
+	return -half, half
+}
+
+/*
+contains returns whether the root covers (x, y).
+*/
+func (universe *QuadUniverse) contains(x, y int) bool {
+	min, max := universe.Bounds()
+	return x >= min && x < max && y >= min && y < max
+}
+
+/*
+expand doubles the size of the root, keeping its contents centered.
+*/
+func (universe *QuadUniverse) expand() {
+	root := universe.Root
+	e := universe.Empty(root.Level - 1)
+	universe.Root = universe.Node(
+		universe.Node(e, e, e, root.NW),
+		universe.Node(e, e, root.NE, e),
+		universe.Node(e, root.SW, e, e),
+		universe.Node(root.SE, e, e, e),
+	)
+}
+
+/*
+At returns the State of the cell at (x, y).
+*/
+func (universe *QuadUniverse) At(x, y int) State {
+	return universe.Table.State(universe.IDAt(x, y))
+}
+
+/*
+IDAt returns the StateID of the cell at (x, y).
+*/
+func (universe *QuadUniverse) IDAt(x, y int) StateID {
+	if !universe.contains(x, y) {
+		return 0
+	}
+	min, _ := universe.Bounds()
+	node, x0, y0 := universe.Root, min, min
+	for node.Level > 0 {
+		if node.Population == 0 {
+			return 0
+		}
+		half := 1 << (node.Level - 1)
+		switch {
+		case x < x0+half && y < y0+half:
+			node = node.SW
+		case y < y0+half:
+			node, x0 = node.SE, x0+half
+		case x < x0+half:
+			node, y0 = node.NW, y0+half
+		default:
+			node, x0, y0 = node.NE, x0+half, y0+half
+		}
+	}
+	return node.ID
+}
+
+/*
+Set sets the State of the cell at (x, y), growing the universe if needed.
+*/
+func (universe *QuadUniverse) Set(x, y int, state State) {
+	universe.SetID(x, y, universe.Table.Intern(state))
+}
+
+/*
+SetID sets the StateID of the cell at (x, y), growing the universe if needed. id must come from
+universe.Table.
+*/
+func (universe *QuadUniverse) SetID(x, y int, id StateID) {
+	for !universe.contains(x, y) {
+		universe.expand()
+	}
+	min, _ := universe.Bounds()
+	universe.Root = universe.set(universe.Root, min, min, x, y, id)
+}
+
+func (universe *QuadUniverse) set(node *QuadNode, x0, y0, x, y int, id StateID) *QuadNode {
+	if node.Level == 0 {
+		return universe.Leaf(id)
+	}
+	half := 1 << (node.Level - 1)
+	nw, ne, sw, se := node.NW, node.NE, node.SW, node.SE
+	switch {
+	case x < x0+half && y < y0+half:
+		sw = universe.set(sw, x0, y0, x, y, id)
+	case y < y0+half:
+		se = universe.set(se, x0+half, y0, x, y, id)
+	case x < x0+half:
+		nw = universe.set(nw, x0, y0+half, x, y, id)
+	default:
+		ne = universe.set(ne, x0+half, y0+half, x, y, id)
+	}
+	return universe.Node(nw, ne, sw, se)
+}
+
+/*
+Cells calls fn for every cell that isn't empty, skipping empty blocks without looking inside them.
+*/
+func (universe *QuadUniverse) Cells(fn func(x, y int, id StateID)) {
+	min, _ := universe.Bounds()
+	universe.cells(universe.Root, min, min, fn)
+}
+
+func (universe *QuadUniverse) cells(node *QuadNode, x0, y0 int, fn func(x, y int, id StateID)) {
+	if node.Population == 0 {
+		return
+	}
+	if node.Level == 0 {
+		fn(x0, y0, node.ID)
+		return
+	}
+	half := 1 << (node.Level - 1)
+	universe.cells(node.SW, x0, y0, fn)
+	universe.cells(node.SE, x0+half, y0, fn)
+	universe.cells(node.NW, x0, y0+half, fn)
+	universe.cells(node.NE, x0+half, y0+half, fn)
+}
+
+/*
+View copies the width×height block of cells whose bottom left corner is (x, y) into a StateGrid
+that shares the universe's StateTable. Empty blocks are skipped, so it's cheap to view a sparse
+universe, however big the viewport.
+*/
+func (universe *QuadUniverse) View(x, y, width, height int) *StateGrid {
+	grid := NewStateGrid(width, height, universe.Table)
+	min, _ := universe.Bounds()
+	universe.view(universe.Root, min, min, x, y, grid)
+	return grid
+}
+
+func (universe *QuadUniverse) view(node *QuadNode, x0, y0, x, y int, grid *StateGrid) {
+	size := 1 << node.Level
+	if node.Population == 0 || x0 >= x+grid.Width || y0 >= y+grid.Height || x0+size <= x || y0+size <= y {
+		return
+	}
+	if node.Level == 0 {
+		grid.SetID(x0-x, y0-y, node.ID)
+		return
+	}
+	half := size / 2
+	universe.view(node.SW, x0, y0, x, y, grid)
+	universe.view(node.SE, x0+half, y0, x, y, grid)
+	universe.view(node.NW, x0, y0+half, x, y, grid)
+	universe.view(node.NE, x0+half, y0+half, x, y, grid)
+}
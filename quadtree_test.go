@@ -0,0 +1,103 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuadUniverse(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	universe := NewQuadUniverse(NewStateTable("-", "X"))
+	assert.Equal(State("-"), universe.At(1000, -1000))
+	universe.Set(0, 0, "X")
+	universe.Set(-1, -1, "X")
+	universe.Set(100, -37, "O")
+	universe.Set(-1, -1, "-")
+
+	assert.Equal(State("X"), universe.At(0, 0))
+	assert.Equal(State("-"), universe.At(-1, -1))
+	assert.Equal(State("O"), universe.At(100, -37))
+	assert.Equal(int64(2), universe.Root.Population)
+	assert.True(universe.MemoryUsage() > 0)
+	min, max := universe.Bounds()
+	assert.True(min <= -37 && max > 100)
+
+	var visited [][3]int
+	universe.Cells(func(x, y int, id StateID) {
+		visited = append(visited, [3]int{x, y, int(id)})
+	})
+	assert.ElementsMatch([][3]int{{0, 0, 1}, {100, -37, 2}}, visited)
+}
+
+func TestQuadUniverse_Canonical(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	universe := NewQuadUniverse(nil)
+	x := universe.Leaf(universe.Table.Intern("X"))
+	e := universe.Leaf(0)
+	a := universe.Node(x, e, e, x)
+	b := universe.Node(x, e, e, x)
+	assert.True(a == b)
+	assert.Equal(int64(2), a.Population)
+	assert.Equal(uint(1), a.Level)
+
+	// A 64x64 checkerboard is the same 2x2 block over and over, so it only takes a couple of nodes
+	// per level
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			if (x+y)%2 == 0 {
+				universe.Set(x, y, "X")
+			}
+		}
+	}
+	assert.Equal(int64(64*32), universe.Root.Population)
+	board := universe.Root.NE
+	assert.Equal(uint(6), board.Level)
+	assert.True(board.NW == board.SE)
+	assert.True(len(reachable(universe.Root, nil)) <= 2*int(universe.Root.Level)+1)
+}
+
+/*
+Returns the distinct nodes that can be reached from node.
+*/
+func reachable(node *QuadNode, seen map[*QuadNode]bool) map[*QuadNode]bool {
+	if seen == nil {
+		seen = make(map[*QuadNode]bool)
+	}
+	if node == nil || seen[node] {
+		return seen
+	}
+	seen[node] = true
+	for _, child := range []*QuadNode{node.NW, node.NE, node.SW, node.SE} {
+		reachable(child, seen)
+	}
+	return seen
+}
+
+func TestQuadUniverse_View(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	universe := NewQuadUniverse(NewStateTable("-", "X"))
+	universe.Set(-2, 3, "X")
+	universe.Set(1, 1, "X")
+	universe.Set(50, 50, "X")
+
+	view := universe.View(-3, 0, 5, 4)
+	assert.True(view.Table == universe.Table)
+	assert.Equal(State("X"), view.At(1, 3))
+	assert.Equal(State("X"), view.At(4, 1))
+	var population int
+	for y := 0; y < view.Height; y++ {
+		for x := 0; x < view.Width; x++ {
+			if view.IDAt(x, y) != 0 {
+				population++
+			}
+		}
+	}
+	assert.Equal(2, population)
+}
@@ -0,0 +1,391 @@
+package cellaut
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+/*
+Options are the settings passed to a factory when something is looked up by name, like
+"growth=0.01" on the REPL's rule command. Each factory decides which keys it understands.
+*/
+type Options map[string]string
+
+/*
+Float returns the option with the given key as a number, or def if it isn't set.
+*/
+func (options Options) Float(key string, def float64) (float64, error) {
+	s, ok := options[key]
+	if !ok {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("option %s: '%s' isn't a number", key, s)
+	}
+	return f, nil
+}
+
+/*
+Int returns the option with the given key as an integer, or def if it isn't set.
+*/
+func (options Options) Int(key string, def int) (int, error) {
+	s, ok := options[key]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("option %s: '%s' isn't an integer", key, s)
+	}
+	return n, nil
+}
+
+/*
+ParseOptions reads options written as key=value, one per argument.
+*/
+func ParseOptions(args []string) (Options, error) {
+	options := make(Options)
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("option '%s' isn't key=value", arg)
+		}
+		options[parts[0]] = parts[1]
+	}
+	return options, nil
+}
+
+/*
+Renderer writes out a grid, drawn with the given Palette if it's the sort of output that has colors.
+*/
+type Renderer func(w io.Writer, grid *StateGrid, palette Palette) error
+
+/*
+Sink is somewhere for a Simulation to send its ticks, ready to pass to Subscribe.
+*/
+type Sink func(TickEvent)
+
+// The kinds of factory that can be passed to Register
+type (
+	RuleFactory     func(options Options) (Rule, error)
+	RendererFactory func(options Options) (Renderer, error)
+	SinkFactory     func(options Options) (Sink, error)
+)
+
+/*
+registry is where Register puts factories, keyed by lowercased name.
+*/
+var registry = struct {
+	sync.RWMutex
+	rules     map[string]RuleFactory
+	renderers map[string]RendererFactory
+	sinks     map[string]SinkFactory
+}{
+	rules:     make(map[string]RuleFactory),
+	renderers: make(map[string]RendererFactory),
+	sinks:     make(map[string]SinkFactory),
+}
+
+/*
+Register makes a rule, renderer or sink available by name, to the REPL and anything else that looks
+things up with LookupRule, LookupRenderer and LookupSink. factory must be a RuleFactory,
+RendererFactory or SinkFactory, or a function with the same signature as one.
+
+It's meant to be called from the init function of a package that adds to cellaut, the same way
+database/sql drivers register themselves. Names aren't case sensitive. Like sql.Register, it panics
+if the name is taken or the factory isn't one of the kinds above, since either is a programming
+mistake.
+*/
+func Register(name string, factory interface{}) {
+	key := strings.ToLower(name)
+	if key == "" {
+		panic("cellaut: Register called with an empty name")
+	}
+	// Plain functions with the right signature are as good as the named types
+	switch f := factory.(type) {
+	case func(Options) (Rule, error):
+		factory = RuleFactory(f)
+	case func(Options) (Renderer, error):
+		factory = RendererFactory(f)
+	case func(Options) (Sink, error):
+		factory = SinkFactory(f)
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	var kind string
+	taken := false
+	switch f := factory.(type) {
+	case RuleFactory:
+		kind = "rule"
+		_, taken = registry.rules[key]
+		if !taken {
+			registry.rules[key] = f
+		}
+	case RendererFactory:
+		kind = "renderer"
+		_, taken = registry.renderers[key]
+		if !taken {
+			registry.renderers[key] = f
+		}
+	case SinkFactory:
+		kind = "sink"
+		_, taken = registry.sinks[key]
+		if !taken {
+			registry.sinks[key] = f
+		}
+	default:
+		panic(fmt.Sprintf("cellaut: Register called with a %T for '%s', which isn't a factory", factory, name))
+	}
+	if taken {
+		panic(fmt.Sprintf("cellaut: Register called twice for %s '%s'", kind, key))
+	}
+}
+
+/*
+LookupRule builds the registered rule with the given name.
+*/
+func LookupRule(name string, options Options) (Rule, error) {
+	factory, ok := registeredRule(name)
+	if !ok {
+		return nil, fmt.Errorf("no rule named '%s'", name)
+	}
+	return factory(options)
+}
+
+func registeredRule(name string) (RuleFactory, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	factory, ok := registry.rules[strings.ToLower(name)]
+	return factory, ok
+}
+
+/*
+lookupRuleSpec builds a rule from a spec like "life-like rule=B36/S23": a registered rule's name
+and its options, or else a Life-like rulestring.
+*/
+func lookupRuleSpec(spec string) (Rule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no rule given")
+	}
+	if factory, ok := registeredRule(fields[0]); ok {
+		options, err := ParseOptions(fields[1:])
+		if err != nil {
+			return nil, err
+		}
+		return factory(options)
+	}
+	if len(fields) != 1 {
+		return nil, fmt.Errorf("no rule named '%s'", fields[0])
+	}
+	return lifeLikeRule(fields[0])
+}
+
+/*
+LookupRenderer builds the registered renderer with the given name.
+*/
+func LookupRenderer(name string, options Options) (Renderer, error) {
+	registry.RLock()
+	factory, ok := registry.renderers[strings.ToLower(name)]
+	registry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no renderer named '%s'", name)
+	}
+	return factory(options)
+}
+
+/*
+LookupSink builds the registered sink with the given name.
+*/
+func LookupSink(name string, options Options) (Sink, error) {
+	registry.RLock()
+	factory, ok := registry.sinks[strings.ToLower(name)]
+	registry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no sink named '%s'", name)
+	}
+	return factory(options)
+}
+
+/*
+RegisteredRules, RegisteredRenderers and RegisteredSinks return the names that have been
+registered, sorted.
+*/
+func RegisteredRules() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := make([]string, 0, len(registry.rules))
+	for name := range registry.rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func RegisteredRenderers() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := make([]string, 0, len(registry.renderers))
+	for name := range registry.renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func RegisteredSinks() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := make([]string, 0, len(registry.sinks))
+	for name := range registry.sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*
+fixedRule is a RuleFactory for a rule that doesn't take any options.
+*/
+func fixedRule(rule Rule) RuleFactory {
+	return func(Options) (Rule, error) { return rule, nil }
+}
+
+// Everything that comes with cellaut registers itself like anyone else would
+func init() {
+	Register("life", fixedRule(Life))
+	Register("highlife", fixedRule(HighLife))
+	Register("anneal", fixedRule(Anneal))
+	Register("wireworld", fixedRule(WireWorld))
+	Register("briansbrain", fixedRule(BriansBrain))
+	Register("immigration", fixedRule(Immigration))
+	Register("quadlife", fixedRule(QuadLife))
+	Register("life-like", RuleFactory(func(options Options) (Rule, error) {
+		rulestring, ok := options["rule"]
+		if !ok {
+			return nil, fmt.Errorf("life-like needs a rule option, like rule=B36/S23")
+		}
+		return lifeLikeRule(rulestring)
+	}))
+	Register("elementary", RuleFactory(func(options Options) (Rule, error) {
+		number, err := options.Int("number", 110)
+		if err != nil {
+			return nil, err
+		}
+		if number < 0 || number > 255 {
+			return nil, fmt.Errorf("elementary rule %d isn't between 0 and 255", number)
+		}
+		return Elementary(uint8(number)), nil
+	}))
+	Register("forest-fire", RuleFactory(func(options Options) (Rule, error) {
+		growth, err := options.Float("growth", 0.01)
+		if err != nil {
+			return nil, err
+		}
+		lightning, err := options.Float("lightning", 0.0001)
+		if err != nil {
+			return nil, err
+		}
+		seed, err := options.Int("seed", 0)
+		if err != nil {
+			return nil, err
+		}
+		if seed == 0 {
+			seed = int(time.Now().UnixNano())
+		}
+		return NewForestFire(growth, lightning, rand.New(rand.NewSource(int64(seed)))), nil
+	}))
+	for _, name := range BuiltinRuleTableNames() {
+		table, _ := BuiltinRuleTable(name)
+		Register(name, fixedRule(table.Rule()))
+	}
+	Register("von-neumann", fixedRule(VonNeumann().Rule()))
+
+	Register("text", RendererFactory(func(Options) (Renderer, error) {
+		return func(w io.Writer, grid *StateGrid, palette Palette) error {
+			_, err := io.WriteString(w, (&Pattern{StateGrid: grid}).String())
+			return err
+		}, nil
+	}))
+	Register("rle", RendererFactory(func(options Options) (Renderer, error) {
+		meta, err := metadataOptions(options)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer, grid *StateGrid, palette Palette) error {
+			return writeRLE(w, grid, options["rule"], meta)
+		}, nil
+	}))
+	Register("png", RendererFactory(func(options Options) (Renderer, error) {
+		cellSize, err := options.Int("cell-size", 4)
+		if err != nil {
+			return nil, err
+		}
+		if cellSize < 1 {
+			return nil, fmt.Errorf("cell size %d is less than 1", cellSize)
+		}
+		meta, err := metadataOptions(options)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer, grid *StateGrid, palette Palette) error {
+			img, err := RenderGrid(grid, palette, cellSize)
+			if err != nil {
+				return err
+			}
+			return EncodePNG(w, img, meta)
+		}, nil
+	}))
+
+	Register("log", SinkFactory(func(Options) (Sink, error) {
+		return func(event TickEvent) {
+			log.WithField("tick", event.TickID).Info("tick")
+		}, nil
+	}))
+	Register("boundary", SinkFactory(func(options Options) (Sink, error) {
+		margin, err := options.Int("margin", 4)
+		if err != nil {
+			return nil, err
+		}
+		checker := NewBoundaryChecker(margin, Plane)
+		checker.Empty = State(options["empty"])
+		return checker.Sink(), nil
+	}))
+	Register("webhook", SinkFactory(func(options Options) (Sink, error) {
+		url, ok := options["url"]
+		if !ok {
+			return nil, fmt.Errorf("webhook needs a url option")
+		}
+		var detectors []Detector
+		stable, err := options.Int("stable", 0)
+		if err != nil {
+			return nil, err
+		}
+		if stable < 0 {
+			return nil, fmt.Errorf("option stable must not be negative")
+		}
+		if stable > 0 {
+			detectors = append(detectors, DetectStabilized(stable))
+		}
+		if path, ok := options["pattern"]; ok {
+			grid, err := loadPatternFile(path, 0)
+			if err != nil {
+				return nil, err
+			}
+			detectors = append(detectors, DetectPattern(path, &Pattern{StateGrid: grid}))
+		}
+		if len(detectors) == 0 {
+			return nil, fmt.Errorf("webhook needs something to detect, like stable=1 or pattern=glider.rle")
+		}
+		return NewWebhook(url, detectors...).Sink(), nil
+	}))
+}
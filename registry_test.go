@@ -0,0 +1,147 @@
+package cellaut
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"image/png"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+registered counts the names that tests have registered, so that uniqueName never hands out the same
+one twice.
+*/
+var registered int64
+
+/*
+uniqueName returns base with a number on the end that no other call has used. Nothing can be
+unregistered, so a test that registers something has to use a new name every time it's run, or it
+would panic under -count.
+*/
+func uniqueName(base string) string {
+	return fmt.Sprintf("%s-%d", base, atomic.AddInt64(&registered, 1))
+}
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Every cell turns into the state given by the "to" option
+	paint := uniqueName("test-register-paint")
+	Register(strings.ToUpper(paint), func(options Options) (Rule, error) {
+		to := State(options["to"])
+		return func(State, map[NeighborIndex]State) State { return to }, nil
+	})
+	assert.Contains(RegisteredRules(), paint)
+
+	rule, err := LookupRule(strings.Replace(paint, "paint", "PAINT", 1), Options{"to": "Y"})
+	assert.Nil(err)
+	assert.Equal(State("Y"), rule("X", nil))
+
+	_, err = LookupRule("test-register-nonexistent", nil)
+	assert.NotNil(err)
+
+	assert.Panics(func() { Register(paint, fixedRule(Life)) })
+	assert.Panics(func() { Register(uniqueName("test-register-bogus"), Life) })
+	assert.Panics(func() { Register("", fixedRule(Life)) })
+	// Names only have to be unique within a kind
+	assert.NotPanics(func() {
+		Register(paint, SinkFactory(func(Options) (Sink, error) { return func(TickEvent) {}, nil }))
+	})
+	assert.Contains(RegisteredSinks(), paint)
+}
+
+func TestRegister_Builtins(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	rules := RegisteredRules()
+	for _, name := range []string{"life", "wireworld", "forest-fire", "elementary", "langtons-loops", "von-neumann"} {
+		assert.Contains(rules, name)
+	}
+	_, err := LookupRule("elementary", Options{"number": "300"})
+	assert.NotNil(err)
+	_, err = LookupRule("forest-fire", Options{"growth": "lots"})
+	assert.NotNil(err)
+	rule, err := LookupRule("life-like", Options{"rule": "B36/S23"})
+	assert.Nil(err)
+	assert.NotNil(rule)
+
+	pattern, err := ParsePattern("X.\n.X\n", nil)
+	assert.Nil(err)
+	text, err := LookupRenderer("text", nil)
+	assert.Nil(err)
+	var b bytes.Buffer
+	assert.Nil(text(&b, pattern.StateGrid, nil))
+	assert.Equal("X.\n.X\n", b.String())
+
+	draw, err := LookupRenderer("png", Options{"cell-size": "3"})
+	assert.Nil(err)
+	b.Reset()
+	assert.Nil(draw(&b, pattern.StateGrid, LifePalette))
+	img, err := png.Decode(&b)
+	assert.Nil(err)
+	assert.Equal(6, img.Bounds().Dx())
+	big := Palette{}
+	for i := 0; i <= maxPaletteStates; i++ {
+		big[State(fmt.Sprintf("s%d", i))] = color.Black
+	}
+	assert.NotNil(draw(&b, pattern.StateGrid, big))
+
+	_, err = LookupRenderer("png", Options{"cell-size": "0"})
+	assert.NotNil(err)
+	assert.Contains(RegisteredSinks(), "log")
+	assert.Contains(RegisteredSinks(), "boundary")
+}
+
+func TestParseOptions(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	options, err := ParseOptions([]string{"growth=0.5", "name=a=b"})
+	assert.Nil(err)
+	assert.Equal(Options{"growth": "0.5", "name": "a=b"}, options)
+	growth, err := options.Float("growth", 0)
+	assert.Nil(err)
+	assert.Equal(0.5, growth)
+	n, err := options.Int("missing", 7)
+	assert.Nil(err)
+	assert.Equal(7, n)
+	_, err = options.Int("growth", 0)
+	assert.NotNil(err)
+
+	_, err = ParseOptions([]string{"growth"})
+	assert.NotNil(err)
+}
+
+func TestREPL_RegisteredRules(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	script := strings.Join([]string{
+		"new 3 1",
+		"set 1 0 X",
+		"rule elementary number=254",
+		"step",
+		"show",
+		"rule elementary number=lots",
+		"rule B3/S23 extra",
+		"rules",
+	}, "\n")
+	var out bytes.Buffer
+	session := newREPLSession(3, 1, &out)
+	assert.Nil(runREPL(session, strings.NewReader(script), false))
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal([]string{
+		"tick 1, rule elementary",
+		"XXX",
+		"error: option number: 'lots' isn't an integer",
+		"error: usage: rule B3/S23, or rule name [key=value...]",
+	}, lines[:4])
+	assert.Contains(lines[4], "forest-fire")
+}
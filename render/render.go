@@ -0,0 +1,154 @@
+/*
+Package render draws cellaut grids as text, for tests and logs, or in color in a terminal, for
+watching a simulation live.
+*/
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/danslimmon/cellaut"
+)
+
+/*
+RenderText returns grid as text, top row first, one line per row and one rune per cell. Each cell
+is drawn as its State's glyph in stateGlyphs. States without one are drawn as their first rune, or a
+space if they're empty, so a nil stateGlyphs gives the same text as Pattern.String for one-letter
+States.
+*/
+func RenderText(grid *cellaut.StateGrid, stateGlyphs map[cellaut.State]rune) string {
+	var b strings.Builder
+	for y := grid.Height - 1; y >= 0; y-- {
+		for x := 0; x < grid.Width; x++ {
+			b.WriteRune(glyph(grid.At(x, y), stateGlyphs))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func glyph(state cellaut.State, stateGlyphs map[cellaut.State]rune) rune {
+	if r, ok := stateGlyphs[state]; ok {
+		return r
+	}
+	if state == "" {
+		return ' '
+	}
+	r, _ := utf8.DecodeRuneInString(string(state))
+	return r
+}
+
+const (
+	ansiClear      = "\x1b[2J"
+	ansiHome       = "\x1b[H"
+	ansiReset      = "\x1b[0m"
+	ansiClearLine  = "\x1b[K"
+	ansiHideCursor = "\x1b[?25l"
+	ansiShowCursor = "\x1b[?25h"
+)
+
+/*
+Terminal draws grids in color on an ANSI terminal, each one over the last, so that a running
+simulation can be watched in place. Each cell is its glyph, as for RenderText, on a background of
+its State's color in Palette; States the Palette doesn't have are drawn on the terminal's own
+background.
+
+The colors are 24-bit, which most terminals understand these days.
+*/
+type Terminal struct {
+	Palette cellaut.Palette
+	Glyphs  map[cellaut.State]rune
+
+	mu sync.Mutex
+	w  io.Writer
+	// drawn is whether anything's been drawn yet, and so whether the screen has been cleared
+	drawn bool
+	err   error
+}
+
+/*
+NewTerminal returns a *Terminal that draws to w.
+*/
+func NewTerminal(w io.Writer, palette cellaut.Palette, glyphs map[cellaut.State]rune) *Terminal {
+	return &Terminal{Palette: palette, Glyphs: glyphs, w: w}
+}
+
+/*
+Draw draws grid over whatever was drawn last, with title on the line above it.
+*/
+func (term *Terminal) Draw(title string, grid *cellaut.StateGrid) error {
+	term.mu.Lock()
+	defer term.mu.Unlock()
+	var b strings.Builder
+	if !term.drawn {
+		// Start from a blank screen, so the first frame doesn't get mixed up with whatever was
+		// there before
+		b.WriteString(ansiClear + ansiHideCursor)
+		term.drawn = true
+	}
+	b.WriteString(ansiHome)
+	b.WriteString(title)
+	b.WriteString(ansiClearLine + "\n")
+	for y := grid.Height - 1; y >= 0; y-- {
+		// Only change color when it's different from the cell before
+		current := ""
+		for x := 0; x < grid.Width; x++ {
+			state := grid.At(x, y)
+			code := ansiReset
+			if c, ok := term.Palette[state]; ok {
+				r, g, bl, _ := c.RGBA()
+				code = fmt.Sprintf("\x1b[48;2;%d;%d;%dm", r>>8, g>>8, bl>>8)
+			}
+			if code != current {
+				b.WriteString(code)
+				current = code
+			}
+			b.WriteRune(glyph(state, term.Glyphs))
+		}
+		b.WriteString(ansiReset + ansiClearLine + "\n")
+	}
+	if _, err := io.WriteString(term.w, b.String()); err != nil {
+		if term.err == nil {
+			term.err = err
+		}
+		return err
+	}
+	return nil
+}
+
+/*
+Subscriber returns a function to pass to Simulation.Subscribe, which draws the World after every
+tick. Write errors can't be returned from a subscriber, so they're kept for Err.
+*/
+func (term *Terminal) Subscriber() func(cellaut.TickEvent) {
+	return func(ev cellaut.TickEvent) {
+		term.Draw(fmt.Sprintf("tick %d", ev.TickID), cellaut.TakeSnapshot(ev.World, nil))
+	}
+}
+
+/*
+Err returns the first error from writing to the terminal, if there's been one.
+*/
+func (term *Terminal) Err() error {
+	term.mu.Lock()
+	defer term.mu.Unlock()
+	return term.err
+}
+
+/*
+Close puts the terminal's colors and cursor back the way they were. It doesn't close the
+io.Writer.
+*/
+func (term *Terminal) Close() error {
+	term.mu.Lock()
+	defer term.mu.Unlock()
+	if !term.drawn {
+		return nil
+	}
+	_, err := io.WriteString(term.w, ansiReset+ansiShowCursor)
+	return err
+}
@@ -0,0 +1,71 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/danslimmon/cellaut"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderText(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	pattern, err := cellaut.ParsePattern(`
+		-X-
+		--X
+		XXX
+	`, cellaut.NewStateTable("-"))
+	assert.Nil(err)
+	assert.Equal(pattern.String(), RenderText(pattern.StateGrid, nil))
+	assert.Equal("·█·\n··█\n███\n", RenderText(pattern.StateGrid, map[cellaut.State]rune{"-": '·', "X": '█'}))
+
+	// Empty cells are blank, and longer States get their first letter
+	grid := cellaut.NewStateGrid(3, 1, nil)
+	grid.Set(1, 0, "wire")
+	assert.Equal(" w \n", RenderText(grid, nil))
+}
+
+func TestTerminal(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	grid := cellaut.NewStateGrid(3, 2, cellaut.NewStateTable("-"))
+	grid.Set(0, 0, "X")
+	grid.Set(1, 0, "X")
+	var b bytes.Buffer
+	term := NewTerminal(&b, cellaut.Palette{"X": color.RGBA{255, 128, 0, 255}}, nil)
+	assert.Nil(term.Draw("tick 0", grid))
+	first := b.String()
+	// The screen is cleared only the first time, and each frame starts back at the top
+	assert.True(strings.HasPrefix(first, ansiClear))
+	assert.Equal(1, strings.Count(first, ansiHome))
+	assert.Contains(first, "tick 0")
+	// Two cells of the same color only need the one escape code
+	assert.Equal(1, strings.Count(first, "\x1b[48;2;255;128;0mXX"))
+	assert.Contains(first, ansiReset+"---")
+
+	b.Reset()
+	assert.Nil(term.Draw("tick 1", grid))
+	assert.True(strings.HasPrefix(b.String(), ansiHome))
+	assert.NotContains(b.String(), ansiClear)
+
+	b.Reset()
+	assert.Nil(term.Close())
+	assert.Equal(ansiReset+ansiShowCursor, b.String())
+	assert.Nil(term.Err())
+
+	broken := NewTerminal(failingWriter{}, nil, nil)
+	broken.Subscriber()(cellaut.TickEvent{World: cellaut.NewLifeGrid(2, 2)})
+	assert.NotNil(broken.Err())
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
@@ -0,0 +1,360 @@
+package cellaut
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/*
+replSession is the state of a `cellaut repl` session: a grid, the rule that steps it, and how far
+it's got.
+*/
+type replSession struct {
+	grid     *StateGrid
+	rule     Rule
+	ruleName string
+	tick     int
+	out      io.Writer
+	// clipboard is where copy and paste go
+	clipboard Clipboard
+	// inspector keeps the history that inspect shows
+	inspector *Inspector
+}
+
+func newREPLSession(width, height int, out io.Writer) *replSession {
+	return &replSession{
+		grid:      NewStateGrid(width, height, NewStateTable("-")),
+		rule:      Life,
+		ruleName:  "B3/S23",
+		out:       out,
+		clipboard: systemClipboard{},
+		inspector: NewInspector(replInspectDepth),
+	}
+}
+
+// replInspectDepth is how many ticks of history inspect shows
+const replInspectDepth = 8
+
+const replHelp = `commands:
+  step [n]         run n ticks (default 1)
+  set x y state    put a cell in the given state
+  clear            empty the grid and reset the tick count
+  new w h          start over with an empty w×h grid
+  show             print the grid
+  rule r [k=v...]  switch rules: a rulestring like B36/S23, or a registered rule like
+                   life, wireworld or forest-fire, with any options it takes
+  rules            list the registered rules
+  save file        write the grid to file, as RLE if it ends in .rle, in the plaintext format if
+                   it ends in .cells, and as text otherwise
+  copy             copy the grid to the clipboard as RLE, ready to paste into Golly
+  paste [x y]      paste an RLE pattern from the clipboard with its bottom left corner at
+                   (x, y), or (0, 0)
+  inspect x y      show a cell's state, how long it's been in it, and its recent history
+  help             print this
+  quit             leave
+`
+
+/*
+exec runs one line of input. It returns true if the session should end.
+*/
+func (session *replSession) exec(line string) (bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return false, nil
+	}
+	command, args := fields[0], fields[1:]
+	ints := func(want int) ([]int, error) {
+		if len(args) != want {
+			return nil, fmt.Errorf("%s takes %d arguments", command, want)
+		}
+		rslt := make([]int, want)
+		for i, arg := range args {
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				return nil, fmt.Errorf("'%s' isn't a number", arg)
+			}
+			rslt[i] = n
+		}
+		return rslt, nil
+	}
+
+	switch command {
+	case "step":
+		n := 1
+		if len(args) > 0 {
+			nums, err := ints(1)
+			if err != nil {
+				return false, err
+			}
+			n = nums[0]
+		}
+		// Whatever was set or pasted since the last step counts as part of this tick
+		session.inspector.Record(int64(session.tick), session.grid)
+		for i := 0; i < n; i++ {
+			session.grid = stepGrid(session.grid, session.rule, mooreNeighborhood)
+			session.tick++
+			session.inspector.Record(int64(session.tick), session.grid)
+		}
+	case "set":
+		if len(args) != 3 {
+			return false, fmt.Errorf("usage: set x y state")
+		}
+		state := State(args[2])
+		args = args[:2]
+		xy, err := ints(2)
+		if err != nil {
+			return false, err
+		}
+		if !(Rect{Width: session.grid.Width, Height: session.grid.Height}).Contains(xy[0], xy[1]) {
+			return false, fmt.Errorf("(%d, %d) is outside the %dx%d grid", xy[0], xy[1], session.grid.Width, session.grid.Height)
+		}
+		session.grid.Set(xy[0], xy[1], state)
+	case "clear":
+		session.grid = NewStateGrid(session.grid.Width, session.grid.Height, session.grid.Table)
+		session.tick = 0
+	case "new":
+		size, err := ints(2)
+		if err != nil {
+			return false, err
+		}
+		if size[0] <= 0 || size[1] <= 0 {
+			return false, fmt.Errorf("the grid must be at least 1x1")
+		}
+		session.grid = NewStateGrid(size[0], size[1], session.grid.Table)
+		session.tick = 0
+	case "show":
+		fmt.Fprintf(session.out, "tick %d, rule %s\n%s", session.tick, session.ruleName, &Pattern{StateGrid: session.grid})
+	case "rule":
+		if len(args) == 0 {
+			return false, fmt.Errorf("usage: rule B3/S23, or rule name [key=value...]")
+		}
+		if factory, ok := registeredRule(args[0]); ok {
+			options, err := ParseOptions(args[1:])
+			if err != nil {
+				return false, err
+			}
+			rule, err := factory(options)
+			if err != nil {
+				return false, err
+			}
+			session.rule, session.ruleName = rule, strings.ToLower(args[0])
+			return false, nil
+		}
+		if len(args) != 1 {
+			return false, fmt.Errorf("usage: rule B3/S23, or rule name [key=value...]")
+		}
+		rule, err := lifeLikeRule(args[0])
+		if err != nil {
+			return false, err
+		}
+		session.rule, session.ruleName = rule, strings.ToUpper(args[0])
+	case "rules":
+		fmt.Fprintln(session.out, strings.Join(RegisteredRules(), " "))
+	case "save":
+		if len(args) != 1 {
+			return false, fmt.Errorf("usage: save file")
+		}
+		return false, session.save(args[0])
+	case "copy":
+		var b strings.Builder
+		if err := writeRLE(&b, session.grid, session.ruleName, nil); err != nil {
+			return false, err
+		}
+		return false, session.clipboard.WriteText(b.String())
+	case "paste":
+		var xy []int
+		if len(args) > 0 {
+			var err error
+			if xy, err = ints(2); err != nil {
+				return false, err
+			}
+		} else {
+			xy = []int{0, 0}
+		}
+		return false, session.paste(xy[0], xy[1])
+	case "inspect":
+		xy, err := ints(2)
+		if err != nil {
+			return false, err
+		}
+		session.inspector.Record(int64(session.tick), session.grid)
+		info, err := session.inspector.Inspect(xy[0], xy[1])
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintln(session.out, info)
+	case "help":
+		fmt.Fprint(session.out, replHelp)
+	case "quit", "exit":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown command '%s'; try 'help'", command)
+	}
+	return false, nil
+}
+
+/*
+save writes the grid to path.
+*/
+func (session *replSession) save(path string) error {
+	var b strings.Builder
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".rle":
+		if err := writeRLE(&b, session.grid, session.ruleName, nil); err != nil {
+			return err
+		}
+	case ".cells":
+		if err := writeCells(&b, session.grid, ""); err != nil {
+			return err
+		}
+	default:
+		b.WriteString((&Pattern{StateGrid: session.grid}).String())
+	}
+	return ioutil.WriteFile(path, []byte(b.String()), 0644)
+}
+
+/*
+paste reads an RLE pattern from the clipboard and puts it on the grid with its bottom left corner at
+(x, y). Dead cells in the pattern are pasted too, like in Golly's copy mode.
+*/
+func (session *replSession) paste(x, y int) error {
+	text, err := session.clipboard.ReadText()
+	if err != nil {
+		return err
+	}
+	pattern, _, err := parseRLE(text)
+	if err != nil {
+		return fmt.Errorf("clipboard doesn't hold an RLE pattern: %s", err)
+	}
+	grid := Rect{Width: session.grid.Width, Height: session.grid.Height}
+	if !grid.Contains(x, y) || !grid.Contains(x+pattern.Width-1, y+pattern.Height-1) {
+		return fmt.Errorf("a %dx%d pattern at (%d, %d) doesn't fit in the %dx%d grid", pattern.Width, pattern.Height, x, y, grid.Width, grid.Height)
+	}
+	for py := 0; py < pattern.Height; py++ {
+		for px := 0; px < pattern.Width; px++ {
+			session.grid.Set(x+px, y+py, pattern.At(px, py))
+		}
+	}
+	return nil
+}
+
+/*
+writeRLE writes the live cells of a two-state grid in Golly's run length encoded format. Only "X"
+counts as alive; any other state besides "-" is an error.
+*/
+func writeRLE(w io.Writer, grid *StateGrid, rulestring string, meta *RunMetadata) error {
+	rect, ok := grid.boundingBox(func(id StateID) bool { return id != 0 })
+	if !ok {
+		rect = Rect{}
+	}
+	var body strings.Builder
+	run, runTag := 0, byte(0)
+	flush := func() {
+		if run > 1 {
+			body.WriteString(strconv.Itoa(run))
+		}
+		if run > 0 {
+			body.WriteByte(runTag)
+		}
+		run = 0
+	}
+	add := func(tag byte, n int) {
+		if tag != runTag {
+			flush()
+			runTag = tag
+		}
+		run += n
+	}
+	for y := rect.Y + rect.Height - 1; y >= rect.Y; y-- {
+		if y != rect.Y+rect.Height-1 {
+			add('$', 1)
+		}
+		dead := 0
+		for x := rect.X; x < rect.X+rect.Width; x++ {
+			switch state := grid.At(x, y); state {
+			case "X":
+				if dead > 0 {
+					add('b', dead)
+					dead = 0
+				}
+				add('o', 1)
+			case "-":
+				dead++
+			default:
+				return fmt.Errorf("RLE can only hold the states '-' and 'X', not '%s'", state)
+			}
+		}
+	}
+	flush()
+	body.WriteByte('!')
+
+	if meta != nil {
+		io.WriteString(w, rleComments(meta))
+	}
+	fmt.Fprintf(w, "x = %d, y = %d, rule = %s\n", rect.Width, rect.Height, rulestring)
+	// Lines of RLE shouldn't be longer than 70 characters
+	encoded := body.String()
+	for len(encoded) > 70 {
+		cut := 70
+		for cut > 0 && encoded[cut-1] >= '0' && encoded[cut-1] <= '9' {
+			// Don't split a run count from its tag
+			cut--
+		}
+		fmt.Fprintln(w, encoded[:cut])
+		encoded = encoded[cut:]
+	}
+	_, err := fmt.Fprintln(w, encoded)
+	return err
+}
+
+/*
+runREPL reads commands from in until it runs out or gets "quit". Errors are written to out and don't
+end the session. If prompt is true, a prompt is written before each command.
+*/
+func runREPL(session *replSession, in io.Reader, prompt bool) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		if prompt {
+			fmt.Fprint(session.out, "> ")
+		}
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+		quit, err := session.exec(scanner.Text())
+		if err != nil {
+			fmt.Fprintln(session.out, "error:", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+/*
+replCommand implements `cellaut repl [--width 40] [--height 20]`, an interactive session for
+poking at a grid. It reads commands from stdin, so it can be scripted by piping them in.
+*/
+func replCommand(args []string) error {
+	fs := flag.NewFlagSet("repl", flag.ContinueOnError)
+	width := fs.Int("width", 40, "width of the grid")
+	height := fs.Int("height", 20, "height of the grid")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *width <= 0 || *height <= 0 {
+		return fmt.Errorf("the grid must be at least 1x1")
+	}
+	// Only prompt when someone's typing
+	prompt := false
+	if info, err := os.Stdin.Stat(); err == nil {
+		prompt = info.Mode()&os.ModeCharDevice != 0
+	}
+	return runREPL(newREPLSession(*width, *height, os.Stdout), os.Stdin, prompt)
+}
@@ -0,0 +1,148 @@
+package cellaut
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestREPL(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	script := strings.Join([]string{
+		"# a blinker",
+		"new 5 3",
+		"set 1 1 X",
+		"set 2 1 X",
+		"set 3 1 X",
+		"show",
+		"step",
+		"show",
+		"step 2",
+		"rule b36/s23",
+		"show",
+		"save " + filepath.Join(dir, "blinker.rle"),
+		"save " + filepath.Join(dir, "blinker.txt"),
+		"set 9 9 X",
+		"bogus",
+		"quit",
+		"show",
+	}, "\n")
+	var out bytes.Buffer
+	session := newREPLSession(10, 10, &out)
+	assert.Nil(runREPL(session, strings.NewReader(script), false))
+
+	assert.Equal(strings.Join([]string{
+		"tick 0, rule B3/S23",
+		"-----",
+		"-XXX-",
+		"-----",
+		"tick 1, rule B3/S23",
+		"--X--",
+		"--X--",
+		"--X--",
+		"tick 3, rule B36/S23",
+		"--X--",
+		"--X--",
+		"--X--",
+		"error: (9, 9) is outside the 5x3 grid",
+		"error: unknown command 'bogus'; try 'help'",
+		"",
+	}, "\n"), out.String())
+
+	rle, err := ioutil.ReadFile(filepath.Join(dir, "blinker.rle"))
+	assert.Nil(err)
+	assert.Equal("x = 1, y = 3, rule = B36/S23\no$o$o!\n", string(rle))
+	text, err := ioutil.ReadFile(filepath.Join(dir, "blinker.txt"))
+	assert.Nil(err)
+	assert.Equal("--X--\n--X--\n--X--\n", string(text))
+}
+
+func TestWriteRLE(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	glider := mustParsePattern(t, `
+		.X...
+		..X..
+		XXX..
+		.....
+		.....
+		X....
+	`)
+	grid := NewStateGrid(glider.Width, glider.Height, NewStateTable("-"))
+	for y := 0; y < glider.Height; y++ {
+		for x := 0; x < glider.Width; x++ {
+			if glider.At(x, y) == "X" {
+				grid.Set(x, y, "X")
+			}
+		}
+	}
+	var b bytes.Buffer
+	assert.Nil(writeRLE(&b, grid, "B3/S23", nil))
+	assert.Equal("x = 3, y = 6, rule = B3/S23\nbo$2bo$3o3$o!\n", b.String())
+
+	// Long rows get wrapped without splitting a run
+	wide := NewStateGrid(100, 1, NewStateTable("-"))
+	for x := 0; x < 100; x += 2 {
+		wide.Set(x, 0, "X")
+	}
+	b.Reset()
+	assert.Nil(writeRLE(&b, wide, "B3/S23", nil))
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		assert.True(len(line) <= 70)
+	}
+
+	grid.Set(0, 0, "?")
+	assert.NotNil(writeRLE(&b, grid, "B3/S23", nil))
+}
+
+// fakeClipboard is a Clipboard that just holds on to the text
+type fakeClipboard struct {
+	text string
+}
+
+func (clipboard *fakeClipboard) ReadText() (string, error) { return clipboard.text, nil }
+
+func (clipboard *fakeClipboard) WriteText(text string) error {
+	clipboard.text = text
+	return nil
+}
+
+func TestREPL_Clipboard(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	var out bytes.Buffer
+	session := newREPLSession(6, 5, &out)
+	clipboard := &fakeClipboard{text: "#N Glider\nx = 3, y = 3, rule = B3/S23\nbo$2bo$3o!\n"}
+	session.clipboard = clipboard
+	script := strings.Join([]string{
+		"paste 1 1",
+		"show",
+		"copy",
+		"paste 5 5",
+	}, "\n")
+	assert.Nil(runREPL(session, strings.NewReader(script), false))
+	assert.Equal(strings.Join([]string{
+		"tick 0, rule B3/S23",
+		"------",
+		"--X---",
+		"---X--",
+		"-XXX--",
+		"------",
+		"error: a 3x3 pattern at (5, 5) doesn't fit in the 6x5 grid",
+		"",
+	}, "\n"), out.String())
+	assert.Equal("x = 3, y = 3, rule = B3/S23\nbo$2bo$3o!\n", clipboard.text)
+
+	clipboard.text = "not a pattern"
+	_, err := session.exec("paste")
+	assert.NotNil(err)
+}
@@ -0,0 +1,67 @@
+package cellaut
+
+/*
+Upscale returns the grid blown up n times, with each cell becoming an n×n block of the same State,
+for running a pattern at a coarser resolution than it was drawn at. An n below 1 counts as 1.
+*/
+func (grid *StateGrid) Upscale(n int) *StateGrid {
+	n = maxInt(n, 1)
+	rslt := NewStateGrid(grid.Width*n, grid.Height*n, grid.Table)
+	for y := 0; y < rslt.Height; y++ {
+		for x := 0; x < rslt.Width; x++ {
+			rslt.SetID(x, y, grid.IDAt(x/n, y/n))
+		}
+	}
+	return rslt
+}
+
+/*
+Downscale returns the grid shrunk n times, with each n×n block becoming one cell in whichever State
+most of the block is in, for coarse-graining a run down to its large-scale structure. Ties go to
+the State that's earliest in the grid's StateTable, which is usually the background. If the grid
+isn't a whole number of blocks across, the blocks on the top and right edges are cut short, and
+vote with the cells they have. An n below 1 counts as 1.
+*/
+func (grid *StateGrid) Downscale(n int) *StateGrid {
+	n = maxInt(n, 1)
+	rslt := NewStateGrid((grid.Width+n-1)/n, (grid.Height+n-1)/n, grid.Table)
+	votes := make([]int, grid.Table.Len())
+	for by := 0; by < rslt.Height; by++ {
+		for bx := 0; bx < rslt.Width; bx++ {
+			for i := range votes {
+				votes[i] = 0
+			}
+			for y := by * n; y < minInt((by+1)*n, grid.Height); y++ {
+				for x := bx * n; x < minInt((bx+1)*n, grid.Width); x++ {
+					votes[grid.IDAt(x, y)]++
+				}
+			}
+			winner := 0
+			for id, count := range votes {
+				if count > votes[winner] {
+					winner = id
+				}
+			}
+			rslt.SetID(bx, by, StateID(winner))
+		}
+	}
+	return rslt
+}
+
+/*
+Upscale returns the Pattern blown up n times, as StateGrid.Upscale does, with its position scaled up
+to match.
+*/
+func (pattern *Pattern) Upscale(n int) *Pattern {
+	n = maxInt(n, 1)
+	return &Pattern{X: pattern.X * n, Y: pattern.Y * n, StateGrid: pattern.StateGrid.Upscale(n)}
+}
+
+/*
+Downscale returns the Pattern shrunk n times, as StateGrid.Downscale does, with its position scaled
+down to match, rounding down.
+*/
+func (pattern *Pattern) Downscale(n int) *Pattern {
+	n = maxInt(n, 1)
+	return &Pattern{X: floorDiv(pattern.X, n), Y: floorDiv(pattern.Y, n), StateGrid: pattern.StateGrid.Downscale(n)}
+}
@@ -0,0 +1,44 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateGrid_Upscale(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	glider, err := ParsePattern(".X.\n..X\nXXX", nil)
+	assert.Nil(err)
+	glider.X, glider.Y = 1, -2
+	big := glider.Upscale(3)
+	assert.Equal([4]int{3, -6, 9, 9}, [4]int{big.X, big.Y, big.Width, big.Height})
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 9; x++ {
+			assert.Equal(glider.At(x/3, y/3), big.At(x, y))
+		}
+	}
+	// Scaling back down gets the original
+	assert.True(big.Downscale(3).Equal(glider))
+	assert.Equal([2]int{1, -2}, [2]int{big.Downscale(3).X, big.Downscale(3).Y})
+	assert.True(glider.StateGrid.Upscale(0).Equal(glider.StateGrid))
+}
+
+func TestStateGrid_Downscale(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	grid, err := ParsePattern("XX.XX\nX.X.X\n..OX.\nXXOO.", nil)
+	assert.Nil(err)
+	small := grid.StateGrid.Downscale(2)
+	// On top, 3 of 4 alive, a 2-2 tie that goes to the background, and a short block on the right
+	// edge that's all alive. Below, another tie, 3 of 4 "O", and a short block of background.
+	want, err := ParsePattern("X.X\n.O.", nil)
+	assert.Nil(err)
+	assert.True(small.Equal(want), (&Pattern{StateGrid: small}).String())
+	// Positions round down, so the blocks stay lined up
+	grid.X = -1
+	assert.Equal(-1, grid.Downscale(2).X)
+}
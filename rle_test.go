@@ -0,0 +1,84 @@
+package cellaut
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRLE(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// As Golly puts it on the clipboard
+	pattern, rule, err := parseRLE(`#N Glider
+#C Comments are skipped
+x = 3, y = 4, rule = B3/S23
+bo$2bo
+$3o!
+Anything after the end is ignored
+`)
+	assert.Nil(err)
+	assert.Equal("B3/S23", rule)
+	// The header's extra row is kept
+	assert.Equal("-X-\n--X\nXXX\n---\n", pattern.String())
+
+	// Blank lines in the middle of a run, and multi-state cells
+	pattern, rule, err = parseRLE("x = 4, y = 3, rule = WireWorld\n.A2C$\n\n2$pAB.!")
+	assert.Nil(err)
+	assert.Equal("WireWorld", rule)
+	assert.Equal(4, pattern.Height)
+	assert.Equal(State("1"), pattern.At(1, 3))
+	assert.Equal(State("3"), pattern.At(3, 3))
+	assert.Equal(TableState(25), pattern.At(0, 0))
+	assert.Equal(TableState(2), pattern.At(1, 0))
+
+	// No header
+	pattern, rule, err = parseRLE("3o!")
+	assert.Nil(err)
+	assert.Equal("", rule)
+	assert.Equal("XXX\n", pattern.String())
+
+	for _, bad := range []string{"3o", "x = 3, y = 1\n3z!", "pq!"} {
+		_, _, err := parseRLE(bad)
+		assert.NotNil(err, bad)
+	}
+}
+
+func TestLoadRLE(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	pattern, err := LoadRLE(strings.NewReader("#N Blinker\nx = 3, y = 1, rule = B3/S23\n3o!\n"))
+	assert.Nil(err)
+	assert.Equal("XXX\n", pattern.String())
+	_, err = LoadRLE(strings.NewReader("x = 3, y = 1\n3o\n"))
+	assert.NotNil(err)
+}
+
+func TestExportRLE(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	glider, err := LoadRLE(strings.NewReader("x = 3, y = 3\nbo$2bo$3o!"))
+	assert.Nil(err)
+	grid := NewLifeGrid(10, 10)
+	assert.Nil(grid.Place(glider, 4, 5))
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 1}))
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+
+	// Just the glider comes out, and reads back in as it went
+	var b strings.Builder
+	assert.Nil(ExportRLE(&b, grid, ""))
+	assert.Equal("x = 3, y = 3, rule = B3/S23\nbo$2bo$3o!\n", b.String())
+	exported, err := LoadRLE(strings.NewReader(b.String()))
+	assert.Nil(err)
+	assert.True(exported.StateGrid.Equal(glider.StateGrid))
+
+	b.Reset()
+	assert.Nil(ExportRLE(&b, NewLifeGrid(4, 4), "B36/S23"))
+	assert.Equal("x = 0, y = 0, rule = B36/S23\n!\n", b.String())
+}
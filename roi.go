@@ -0,0 +1,135 @@
+package cellaut
+
+import (
+	"fmt"
+)
+
+/*
+RegionOfInterest makes a Simulation stop ticking the parts of the World where nothing is happening.
+
+The World is split into square tiles. Once neither a tile nor any of the 8 tiles around it has had
+a cell change state for QuietTicks ticks in a row, the tile's cells are put to sleep, and they stay
+asleep until a cell in one of those tiles changes again. For a pattern that only lives in a small
+part of a big World, that skips most of the work.
+
+Only changes made by ticking are noticed. That's why a Simulation with a RegionOfInterest can't
+have Layers, which set cells' states from outside the tick.
+*/
+type RegionOfInterest struct {
+	// TileSize is the width and height of a tile, in cells
+	TileSize int
+	// QuietTicks is how many ticks a tile's neighborhood has to go without changing before the
+	// tile goes to sleep. It must be at least 1, since a state sent during one tick is only
+	// committed during the next.
+	QuietTicks int64
+}
+
+func (roi *RegionOfInterest) validate() error {
+	if roi.TileSize < 1 || roi.QuietTicks < 1 {
+		return fmt.Errorf("RegionOfInterest needs a TileSize and QuietTicks of at least 1")
+	}
+	return nil
+}
+
+/*
+regionTracker keeps track of which tiles have been changing, and puts the quiet ones to sleep.
+*/
+type regionTracker struct {
+	roi           RegionOfInterest
+	world         World
+	width, height int
+	// tilesX and tilesY are how many tiles there are across and down
+	tilesX, tilesY int
+	// states are the states of the cells as of the last tick, in row order
+	states []State
+	// quiet[i] is how many ticks tile i's neighborhood has gone without changing
+	quiet []int64
+	// asleep[i] is whether tile i's cells have been put to sleep
+	asleep []bool
+}
+
+func newRegionTracker(roi RegionOfInterest, world World) *regionTracker {
+	width, height := world.Size()
+	tracker := &regionTracker{
+		roi:    roi,
+		world:  world,
+		width:  width,
+		height: height,
+		tilesX: (width + roi.TileSize - 1) / roi.TileSize,
+		tilesY: (height + roi.TileSize - 1) / roi.TileSize,
+		states: make([]State, width*height),
+	}
+	tracker.quiet = make([]int64, tracker.tilesX*tracker.tilesY)
+	tracker.asleep = make([]bool, tracker.tilesX*tracker.tilesY)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			tracker.states[y*width+x] = world.At(x, y).GetState()
+		}
+	}
+	return tracker
+}
+
+/*
+update looks at which cells changed during the tick that just finished, and puts tiles to sleep or
+wakes them up for the next one. It must only be called between ticks.
+*/
+func (tracker *regionTracker) update(ticker engine) {
+	changed := make([]bool, len(tracker.quiet))
+	for y := 0; y < tracker.height; y++ {
+		for x := 0; x < tracker.width; x++ {
+			state := tracker.world.At(x, y).GetState()
+			if state != tracker.states[y*tracker.width+x] {
+				tracker.states[y*tracker.width+x] = state
+				changed[tracker.tile(x, y)] = true
+			}
+		}
+	}
+
+	for ty := 0; ty < tracker.tilesY; ty++ {
+		for tx := 0; tx < tracker.tilesX; tx++ {
+			i := ty*tracker.tilesX + tx
+			if tracker.neighborhoodChanged(changed, tx, ty) {
+				tracker.quiet[i] = 0
+			} else {
+				tracker.quiet[i]++
+			}
+			asleep := tracker.quiet[i] >= tracker.roi.QuietTicks
+			if asleep != tracker.asleep[i] {
+				tracker.asleep[i] = asleep
+				tracker.setAsleep(ticker, tx, ty, asleep)
+			}
+		}
+	}
+}
+
+func (tracker *regionTracker) tile(x, y int) int {
+	return (y/tracker.roi.TileSize)*tracker.tilesX + x/tracker.roi.TileSize
+}
+
+/*
+neighborhoodChanged returns whether any cell changed in tile (tx, ty) or the tiles around it.
+*/
+func (tracker *regionTracker) neighborhoodChanged(changed []bool, tx, ty int) bool {
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			nx, ny := tx+dx, ty+dy
+			if nx < 0 || ny < 0 || nx >= tracker.tilesX || ny >= tracker.tilesY {
+				continue
+			}
+			if changed[ny*tracker.tilesX+nx] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (tracker *regionTracker) setAsleep(ticker engine, tx, ty int, asleep bool) {
+	size := tracker.roi.TileSize
+	for y := ty * size; y < (ty+1)*size && y < tracker.height; y++ {
+		for x := tx * size; x < (tx+1)*size && x < tracker.width; x++ {
+			// Cells removed by Edit but still in the World are fine to skip
+			ticker.SetAsleep(tracker.world.At(x, y), asleep)
+		}
+	}
+}
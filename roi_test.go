@@ -0,0 +1,54 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulation_RegionOfInterest(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	world := newGooRow(12)
+	world.cells[0].SetState("X")
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{
+		World:            world,
+		MaxTicks:         16,
+		RegionOfInterest: &RegionOfInterest{TileSize: 2, QuietTicks: 2},
+	}))
+	asleep := make(map[int64]int)
+	sim.Subscribe(func(event TickEvent) {
+		for _, cell := range sim.Health().Cells {
+			if cell.Phase == CellAsleep {
+				asleep[event.TickID]++
+			}
+		}
+	})
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+
+	// The goo spreads exactly as it would if every cell were ticked
+	for _, aut := range world.cells {
+		assert.Equal(State("X"), aut.GetState())
+	}
+	// After tick 2, cells 6 and up have gone 2 ticks without goo next door
+	assert.Equal(0, asleep[0])
+	assert.Equal(6, asleep[2])
+	// Once the goo has filled the row, everything goes back to sleep
+	assert.Equal(12, asleep[15])
+}
+
+func TestSimulation_RegionOfInterestConfig(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	sim := NewSimulation()
+	assert.NotNil(sim.Configure(SimulationConfig{World: newGooRow(2), RegionOfInterest: &RegionOfInterest{TileSize: 2}}))
+	assert.NotNil(sim.Configure(SimulationConfig{
+		World:            newGooRow(2),
+		Layers:           []Layer{&countingLayer{}},
+		RegionOfInterest: &RegionOfInterest{TileSize: 2, QuietTicks: 1},
+	}))
+}
@@ -0,0 +1,107 @@
+package cellaut
+
+import (
+	"fmt"
+	"strings"
+)
+
+//go:generate go run ./cmd/rulegen -rule B36/S23 -name HighLife -o highlife_gen.go
+//go:generate go run ./cmd/rulegen -rule B3/S23 -name Life -o life_gen.go
+//go:generate go run ./cmd/rulegen -rule B4678/S35678 -name Anneal -o anneal_gen.go
+
+/*
+Rule is a transition function: given a cell's state and the states of its neighbors, it returns the
+cell's next state.
+
+neighbors is keyed by NeighborIndex so that rules which care about who their neighbors are (or where
+they are) can tell them apart. Rules that only care about counts can just range over it.
+*/
+type Rule func(self State, neighbors map[NeighborIndex]State) State
+
+/*
+lifeLikeRule interprets a Life-like rulestring like "B36/S23" into a Rule over the states "-" and
+"X", for rules that are only known at run time. Rules that are known ahead of time should be
+generated with rulegen instead.
+*/
+func lifeLikeRule(rulestring string) (Rule, error) {
+	rule, err := ParseRulestring(rulestring)
+	if err != nil {
+		return nil, err
+	}
+	return rule.Rule(), nil
+}
+
+/*
+LifeLikeRule is a birth/survival rule over the states "-" and "X": [0][n] says whether a dead cell
+with n live neighbors is born, and [1][n] whether a live one survives.
+*/
+type LifeLikeRule [2][9]bool
+
+/*
+ParseRulestring parses a rulestring like "B36/S23" into a LifeLikeRule, which can run as a Rule or
+drive a Grid of LifeCellAuts. That's HighLife, Day & Night ("B3678/S34678"), Seeds ("B2/S") and
+the rest without writing any new code.
+*/
+func ParseRulestring(rulestring string) (LifeLikeRule, error) {
+	var rule LifeLikeRule
+	parts := strings.Split(strings.ToUpper(rulestring), "/")
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "B") || !strings.HasPrefix(parts[1], "S") {
+		return rule, fmt.Errorf("rulestring '%s' is not of the form B<digits>/S<digits>", rulestring)
+	}
+	for i := range rule {
+		for _, c := range parts[i][1:] {
+			if c < '0' || c > '8' {
+				return rule, fmt.Errorf("invalid neighbor count '%c' in rulestring '%s'", c, rulestring)
+			}
+			rule[i][c-'0'] = true
+		}
+	}
+	return rule, nil
+}
+
+/*
+Rule returns the LifeLikeRule as a Rule.
+*/
+func (rule LifeLikeRule) Rule() Rule {
+	// table[0][n] is the next state of a dead cell with n live neighbors, and table[1][n] of a live one
+	var table [2][9]State
+	for i := range table {
+		for n := range table[i] {
+			table[i][n] = "-"
+			if rule[i][n] {
+				table[i][n] = "X"
+			}
+		}
+	}
+	return func(self State, neighbors map[NeighborIndex]State) State {
+		n := 0
+		for _, neighbor := range neighbors {
+			if neighbor == "X" {
+				n++
+			}
+		}
+		if n >= len(table[0]) {
+			return "-"
+		}
+		if self == "X" {
+			return table[1][n]
+		}
+		return table[0][n]
+	}
+}
+
+/*
+String returns the rule as a rulestring like "B36/S23".
+*/
+func (rule LifeLikeRule) String() string {
+	var b strings.Builder
+	for i, prefix := range []string{"B", "/S"} {
+		b.WriteString(prefix)
+		for n, ok := range rule[i] {
+			if ok {
+				b.WriteByte(byte('0' + n))
+			}
+		}
+	}
+	return b.String()
+}
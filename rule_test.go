@@ -0,0 +1,122 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+Tests the HighLife rule that rulegen generates into highlife_gen.go.
+*/
+func TestHighLife(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal(State("X"), HighLife("-", neighborMap("X", "X", "X")))
+	assert.Equal(State("X"), HighLife("-", neighborMap("X", "X", "X", "X", "X", "X", "-", "-")))
+	assert.Equal(State("-"), HighLife("-", neighborMap("X", "X", "X", "X")))
+	assert.Equal(State("X"), HighLife("X", neighborMap("X", "X", "-")))
+	assert.Equal(State("-"), HighLife("X", neighborMap("X", "X", "X", "X", "X", "X")))
+	assert.Equal(State("-"), HighLife("X", neighborMap("X")))
+	// Unknown states count as dead
+	assert.Equal(State("X"), HighLife("?", neighborMap("X", "X", "X", "?")))
+}
+
+/*
+Tests the Life rule that rulegen generates into life_gen.go.
+*/
+func TestLife(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal(State("X"), Life("-", neighborMap("X", "X", "X", "-")))
+	assert.Equal(State("-"), Life("-", neighborMap("X", "X", "X", "X", "X", "X")))
+	assert.Equal(State("X"), Life("X", neighborMap("X", "X")))
+	assert.Equal(State("-"), Life("X", neighborMap("X", "X", "X", "X")))
+}
+
+func TestLifeLikeRule(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// The interpreted rule agrees with the generated one everywhere
+	highLife, err := lifeLikeRule("b36/s23")
+	assert.Nil(err)
+	for n := 0; n <= 8; n++ {
+		states := make([]State, 8)
+		for i := range states {
+			states[i] = "-"
+			if i < n {
+				states[i] = "X"
+			}
+		}
+		for _, self := range []State{"-", "X"} {
+			assert.Equal(HighLife(self, neighborMap(states...)), highLife(self, neighborMap(states...)), "%s with %d", self, n)
+		}
+	}
+
+	for _, bad := range []string{"B3S23", "S23/B3", "B9/S23", "B3/S2x"} {
+		_, err := lifeLikeRule(bad)
+		assert.NotNil(err, bad)
+	}
+}
+
+func TestParseRulestring(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	seeds, err := ParseRulestring("b2/s")
+	assert.Nil(err)
+	assert.Equal(LifeLikeRule{{false, false, true}}, seeds)
+	assert.Equal("B2/S", seeds.String())
+
+	dayAndNight, err := ParseRulestring("B3678/S34678")
+	assert.Nil(err)
+	assert.Equal("B3678/S34678", dayAndNight.String())
+	// Day & Night treats live and dead cells the same way
+	rule := dayAndNight.Rule()
+	for n := 0; n <= 8; n++ {
+		states := make([]State, 8)
+		flipped := make([]State, 8)
+		for i := range states {
+			states[i], flipped[i] = "-", "X"
+			if i < n {
+				states[i], flipped[i] = "X", "-"
+			}
+		}
+		born := rule("-", neighborMap(states...)) == "X"
+		assert.Equal(born, rule("X", neighborMap(flipped...)) == "-", "%d", n)
+	}
+
+	_, err = ParseRulestring("B3/S23/C2")
+	assert.NotNil(err)
+}
+
+/*
+Tests the Anneal rule that rulegen generates into anneal_gen.go.
+*/
+func TestAnneal(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Counting the cell itself, 4 or 6-9 of the 9 cells alive means alive, and 0-3 or 5 means
+	// dead: a majority vote with the 4 and 5 outcomes swapped.
+	for n := 0; n <= 8; n++ {
+		states := make([]State, 8)
+		for i := range states {
+			states[i] = "-"
+			if i < n {
+				states[i] = "X"
+			}
+		}
+		for self, alive := range map[State]int{"-": 0, "X": 1} {
+			total := n + alive
+			expected := State("-")
+			if total == 4 || total >= 6 {
+				expected = "X"
+			}
+			assert.Equal(expected, Anneal(self, neighborMap(states...)), "%s with %d", self, n)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package cellaut
+
+import (
+	"fmt"
+	"time"
+)
+
+/*
+RuleCellAut is a CellAut that runs any Rule, so that a new automaton can be a transition function of
+a few lines rather than a whole CellAut with its own channel plumbing. The rule gets the cell's
+state and the states of whatever neighbors it's been wired to, keyed by direction, just as it would
+from stepGrid.
+
+Neighbors only send their state when it changes, so a RuleCellAut remembers the last one it heard
+from each of them. Every time it commits a state or hears from a neighbor, it works out its next
+state again, so by the end of the tick it's ready to commit.
+
+It runs under either engine: with a goroutine of its own under the Ticker, or on a Multiplexer.
+*/
+type RuleCellAut struct {
+	ID int
+	// ChannelBuffer is how many states the inbox can hold from each neighbor. As for GooCellAut,
+	// it has to be set before the cell is wired up, and it has to be at least 1.
+	ChannelBuffer int
+	tickID        int64
+	rule          Rule
+	newState      State
+	state         State
+	// announced is whether we've sent our state to the neighbors yet
+	announced bool
+	// frozen cells never evaluate the rule, so their state only changes if SetState changes it
+	frozen bool
+	// neighborStates is the last state heard from each neighbor. It's only touched by whoever is
+	// running the cell. It holds States rather than StateIDs, since it's handed to the rule as it is.
+	neighborStates map[NeighborIndex]State
+	// Our neighbors, and the inbox on which they send us their states
+	mailbox
+}
+
+/*
+NewRuleCellAut returns a *RuleCellAut in the given state that follows rule, ready to be wired up.
+*/
+func NewRuleCellAut(i int, rule Rule, state State) *RuleCellAut {
+	return &RuleCellAut{
+		ID:             i,
+		ChannelBuffer:  DefaultChannelBuffer,
+		rule:           rule,
+		newState:       state,
+		state:          state,
+		neighborStates: make(map[NeighborIndex]State),
+		mailbox:        mailbox{neighbors: make(map[NeighborIndex]CellAut)},
+	}
+}
+
+/*
+NewConstantCellAut returns a frozen *RuleCellAut that stays in state for good, for walls and sources.
+It can go anywhere in a Grid of rule cells, and its neighbors see it like any other cell.
+*/
+func NewConstantCellAut(i int, state State) *RuleCellAut {
+	aut := NewRuleCellAut(i, func(self State, neighbors map[NeighborIndex]State) State { return self }, state)
+	aut.frozen = true
+	return aut
+}
+
+/*
+FreezableCellAut is a CellAut that can be frozen, so that it keeps its state without evaluating its
+rule. RuleCellAut and everything built on it are FreezableCellAuts.
+*/
+type FreezableCellAut interface {
+	CellAut
+	SetFrozen(frozen bool)
+	Frozen() bool
+}
+
+/*
+NewRuleGrid builds a width×height Grid of RuleCellAuts that follow rule, all starting in state.
+Rules that need the diagonal neighbors should be given WithDiagonals.
+*/
+func NewRuleGrid(width, height int, rule Rule, state State, options ...GridOption) *Grid {
+	return NewGrid(width, height, func(x, y int) CellAut { return NewRuleCellAut(y*width+x, rule, state) }, options...)
+}
+
+/*
+NewLineGrid builds a one-dimensional Grid of width RuleCellAuts that follow rule, all starting in
+state, for elementary automata like Rule1D(30). Each cell is only wired to its left and right
+neighbors; WithTopology(Cylinder) joins the two ends into a ring.
+*/
+func NewLineGrid(width int, rule Rule, state State, options ...GridOption) *Grid {
+	return NewRuleGrid(width, 1, rule, state, options...)
+}
+
+/*
+AddNeighbor tells us "your neighbor to this direction is `neighbor`", as for GooCellAut. There's no
+limit on how many neighbors we can have, or which directions they're in.
+*/
+func (aut *RuleCellAut) AddNeighbor(i NeighborIndex, neighbor CellAut) {
+	aut.addNeighbor(i, neighbor, aut.ChannelBuffer)
+}
+
+/*
+RemoveNeighbor forgets our neighbor in direction i. The rule doesn't see it from then on, the same
+as a neighbor off the edge of the grid.
+*/
+func (aut *RuleCellAut) RemoveNeighbor(i NeighborIndex) {
+	aut.removeNeighbor(i)
+	delete(aut.neighborStates, i)
+}
+
+/*
+Inbox returns the channel on which all our neighbors send us their states.
+*/
+func (aut *RuleCellAut) Inbox() chan NeighborMessage {
+	return aut.inboxFor(aut.ChannelBuffer)
+}
+
+/*
+SetFrozen freezes the cell, or thaws it. A frozen cell skips the rule altogether and keeps its
+state, which makes it a wall or a source, but its neighbors still see it as usual. SetState still
+works on a frozen cell, so a source can be switched on and off.
+
+A cell frozen before its first tick still commits the state it was given with SetState. It should
+only be called between ticks.
+*/
+func (aut *RuleCellAut) SetFrozen(frozen bool) {
+	aut.frozen = frozen
+	if frozen && aut.announced {
+		// Forget whatever the rule came up with. Before the first tick, newState is the starting
+		// state, which still has to be committed.
+		aut.newState = aut.state
+	}
+}
+
+/*
+Frozen returns whether the cell is frozen.
+*/
+func (aut *RuleCellAut) Frozen() bool {
+	return aut.frozen
+}
+
+/*
+SetState sets the state the cell will have after the next tick, overriding the one the rule came
+up with.
+*/
+func (aut *RuleCellAut) SetState(newState State) {
+	aut.newState = newState
+}
+
+/*
+GetState returns the cell's current state.
+*/
+func (aut *RuleCellAut) GetState() State {
+	return aut.state
+}
+
+func (aut *RuleCellAut) Start(tick chan int64, done chan struct{}, stateLedger chan StateRecord, callbacks *CellAutCallbacks) {
+	for {
+		// The inbox can be swapped for a bigger one between ticks, so it's looked up every time
+		inbox := aut.currentInbox()
+		select {
+		case tickID, ok := <-tick:
+			if !aut.tick(tickID, ok, done, stateLedger, callbacks) {
+				return
+			}
+		case <-done:
+			return
+		case msg := <-inbox:
+			callbacks.handling()
+			aut.receive(msg.From, msg.State)
+			callbacks.MessageReceived(msg)
+		}
+	}
+}
+
+/*
+tick commits our state for tickID and sends it to the neighbors if it changed. It returns false if
+the tick channel was closed, and the cell should stop.
+*/
+func (aut *RuleCellAut) tick(tickID int64, ok bool, done chan struct{}, stateLedger chan StateRecord, callbacks *CellAutCallbacks) bool {
+	if !ok {
+		callbacks.ReportError(aut, aut.tickID, fmt.Errorf("tick channel closed unexpectedly"))
+		return false
+	}
+	changed := aut.Commit(tickID)
+	callbacks.StateCommitted()
+	if changed {
+		for _, to := range aut.mailingList() {
+			callbacks.sendState(to, aut.state)
+		}
+		recordState(stateLedger, done, callbacks.cellName(aut), tickID, aut.state)
+	}
+	callbacks.AllStatesSent()
+	return true
+}
+
+/*
+receive notes a neighbor's new state and works out our next state again.
+*/
+func (aut *RuleCellAut) receive(i NeighborIndex, neighborState State) {
+	aut.neighborStates[i] = neighborState
+	if !aut.frozen {
+		aut.newState = aut.rule(aut.state, aut.neighborStates)
+	}
+}
+
+/*
+Commit makes the next state our current state, and returns whether it changed. The first time, it
+always says it did, so that the neighbors hear about our state whatever it is. Then it works out the
+next state from the neighbors' states as we know them, in case none of them changes this tick,
+unless we're frozen.
+*/
+func (aut *RuleCellAut) Commit(tickID int64) bool {
+	aut.tickID = tickID
+	changed := aut.newState != aut.state || !aut.announced
+	aut.announced = true
+	aut.state = aut.newState
+	if !aut.frozen {
+		aut.newState = aut.rule(aut.state, aut.neighborStates)
+	}
+	return changed
+}
+
+/*
+Broadcast sends our state to all our neighbors, for the Multiplexer.
+*/
+func (aut *RuleCellAut) Broadcast() {
+	for _, to := range aut.mailingList() {
+		to.send(aut.state, time.Time{})
+	}
+}
+
+/*
+Receive takes whatever states our neighbors have sent us, without blocking, for the Multiplexer.
+RuleCellAuts never report errors: what a state means is up to the rule.
+*/
+func (aut *RuleCellAut) Receive(supervisor *Supervisor) {
+	inbox := aut.currentInbox()
+	for {
+		select {
+		case msg := <-inbox:
+			aut.receive(msg.From, msg.State)
+		default:
+			return
+		}
+	}
+}
+
+/*
+String identifies the RuleCellAut in logs and errors.
+*/
+func (aut *RuleCellAut) String() string {
+	return fmt.Sprintf("rule#%d", aut.ID)
+}
+
+/*
+ruleCellAut returns the RuleCellAut itself, so that cells built on one can be run by a SyncEngine.
+*/
+func (aut *RuleCellAut) ruleCellAut() *RuleCellAut {
+	return aut
+}
@@ -0,0 +1,120 @@
+package cellaut
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+runRuleGrid runs grid for the given number of ticks, and returns its States.
+*/
+func runRuleGrid(t *testing.T, grid *Grid, engine EngineKind, ticks int64) *StateGrid {
+	sim := NewSimulation()
+	assert.Nil(t, sim.Configure(SimulationConfig{World: grid, Engine: engine, MaxTicks: ticks}))
+	assert.Nil(t, sim.Start())
+	assert.Nil(t, sim.Wait())
+	return TakeSnapshot(grid, nil)
+}
+
+func TestRuleCellAut(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Every cell hears from every neighbor, even the ones that never change
+	count := func(self State, neighbors map[NeighborIndex]State) State {
+		return State(strconv.Itoa(len(neighbors)))
+	}
+	for _, engine := range []EngineKind{ChannelEngine, MultiplexedEngine} {
+		grid := runRuleGrid(t, NewRuleGrid(3, 3, count, "-", WithDiagonals()), engine, 2)
+		assert.Equal("353\n585\n353\n", (&Pattern{StateGrid: grid}).String(), "engine %s", engine)
+	}
+
+	// Neighbors are keyed by direction: this one moves everything one cell to the right, and
+	// wraps around
+	shift := func(self State, neighbors map[NeighborIndex]State) State {
+		return neighbors[NeighborLf]
+	}
+	world := NewRuleGrid(5, 1, shift, "-", WithTopology(Torus))
+	world.At(3, 0).SetState("X")
+	// The first tick commits the starting states, and each one after it moves them
+	grid := runRuleGrid(t, world, ChannelEngine, 4)
+	assert.Equal("-X---\n", (&Pattern{StateGrid: grid}).String())
+	assert.Equal("rule#4", world.At(4, 0).(*RuleCellAut).String())
+}
+
+func TestRuleCellAut_WireWorld(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A pulse runs along a WireWorld wire just as it does under stepGrid
+	start, err := ParsePattern(`
+		-CC----
+		CHTCCCC
+		-CC----
+	`, NewStateTable(WireEmpty))
+	assert.Nil(err)
+	want := start.StateGrid
+	for ticks := int64(1); ticks <= 8; ticks++ {
+		world := NewRuleGrid(7, 3, WireWorld, WireEmpty, WithDiagonals())
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 7; x++ {
+				world.At(x, y).SetState(start.At(x, y))
+			}
+		}
+		got := runRuleGrid(t, world, MultiplexedEngine, ticks)
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 7; x++ {
+				assert.Equal(want.At(x, y), got.At(x, y), "(%d, %d) after %d", x, y, ticks)
+			}
+		}
+		want = stepGrid(want, WireWorld, mooreNeighborhood)
+	}
+}
+
+func TestRuleCellAut_Frozen(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A source at the left end keeps spreading to the right, and its rule, which would panic,
+	// never runs
+	spread := func(self State, neighbors map[NeighborIndex]State) State {
+		if self == "S" {
+			panic("rule evaluated for a frozen cell")
+		}
+		if left := neighbors[NeighborLf]; left == "S" || left == "X" {
+			return "X"
+		}
+		return "-"
+	}
+	newWorld := func() *Grid {
+		return NewGrid(6, 1, func(x, y int) CellAut {
+			if x == 0 {
+				return NewConstantCellAut(x, "S")
+			}
+			return NewRuleCellAut(x, spread, "-")
+		})
+	}
+	for _, engine := range []EngineKind{ChannelEngine, MultiplexedEngine, SynchronousEngine} {
+		grid := runRuleGrid(t, newWorld(), engine, 4)
+		assert.Equal("SXXX--\n", (&Pattern{StateGrid: grid}).String(), "engine %s", engine)
+	}
+
+	// SetState still switches a frozen cell, and a thawed cell goes back to its rule
+	world := newWorld()
+	source := world.At(0, 0).(*RuleCellAut)
+	assert.True(source.Frozen())
+	engine, err := NewSyncEngine(world)
+	assert.Nil(err)
+	engine.Step()
+	engine.Step()
+	source.SetState("-")
+	engine.Step()
+	engine.Step()
+	assert.Equal("--XX--\n", (&Pattern{StateGrid: TakeSnapshot(world, nil)}).String())
+	// Frozen, the back of the pulse stays put while the front moves on
+	world.At(2, 0).(*RuleCellAut).SetFrozen(true)
+	engine.Step()
+	assert.Equal("--XXX-\n", (&Pattern{StateGrid: TakeSnapshot(world, nil)}).String())
+}
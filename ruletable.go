@@ -0,0 +1,527 @@
+package cellaut
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// tableDigits are the names TableState gives the first few states of a RuleTable
+const tableDigits = "-123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+/*
+TableState returns the cellaut State for state n of a RuleTable. State 0 is "-", the usual empty
+state, states 1 to 9 are their digits, and the states after that are letters, then decimal numbers
+once the letters run out.
+*/
+func TableState(n int) State {
+	if n >= 0 && n < len(tableDigits) {
+		return State(tableDigits[n])
+	}
+	return State(strconv.Itoa(n))
+}
+
+/*
+tableStateNumber is the reverse of TableState, for a table with the given number of states. Any
+State that isn't one of the table's is state 0.
+*/
+func tableStateNumber(state State, states int) int {
+	n := -1
+	if len(state) == 1 {
+		n = strings.IndexByte(tableDigits, state[0])
+	} else if number, err := strconv.Atoi(string(state)); err == nil && number >= len(tableDigits) {
+		n = number
+	}
+	if n < 0 || n >= states {
+		return 0
+	}
+	return n
+}
+
+// The orders Golly lists a cell's neighbors in. Both go clockwise, starting from the top.
+var (
+	vonNeumannOrder = []NeighborIndex{NeighborUp, NeighborRt, NeighborDn, NeighborLf}
+	mooreOrder      = []NeighborIndex{
+		NeighborUp, NeighborUpRt, NeighborRt, NeighborDnRt,
+		NeighborDn, NeighborDnLf, NeighborLf, NeighborUpLf,
+	}
+)
+
+/*
+RuleTable is a rule loaded from the @TABLE section of a Golly .rule file: a list of transitions, each
+of which gives a cell's next state when it and its neighbors are in states that match. It's how
+most of the multi-state rules in the literature are published.
+
+The first transition that matches wins. If none do, the cell stays as it is. States are numbered
+from 0 in the file, and named with TableState in cellaut; any State the table doesn't know, like
+"" or ".", is read as state 0.
+
+Rules with too many states to list every transition, like von Neumann's, can be written as a
+function of the neighborhood instead, and still get the RuleTable's caching and Step.
+*/
+type RuleTable struct {
+	Name   string
+	States int
+	// Neighborhood is "vonNeumann" or "Moore"
+	Neighborhood string
+	// Palette comes from the file's @COLORS section, if it has one
+	Palette Palette
+
+	transitions []tableTransition
+	// symmetries lists the orders a transition's neighbors can be matched in, as indexes into the
+	// real neighbors. The "permute" symmetry is too big to list, so it's handled by matchAny.
+	symmetries [][]int
+	permute    bool
+	// compute, if it's set, is used instead of transitions. It's given the same key as next.
+	compute func(key []byte) int
+	// cache maps a string of the cell's and its neighbors' states to the next state
+	cache sync.Map
+}
+
+/*
+tableTransition is one line of a rule table.
+*/
+type tableTransition struct {
+	// inputs[0] is the set of states the cell itself can be in, and inputs[1:] the sets for its
+	// neighbors, in Golly order. Each is indexed by state.
+	inputs [][]bool
+	// bound[i] is the first input that has to be in the same state as input i, because they use the
+	// same variable. It's i if there isn't one.
+	bound []int
+	// output is the next state, or if it's negative, the state of input -output-1
+	output int
+}
+
+/*
+ParseRuleTable reads a Golly .rule file. Only the @RULE, @TABLE and @COLORS sections are used;
+the others (like @ICONS) are skipped.
+*/
+func ParseRuleTable(r io.Reader) (*RuleTable, error) {
+	table := &RuleTable{Neighborhood: "Moore"}
+	vars := make(map[string][]int)
+	var section string
+	var symmetries string
+	var lines []tableLine
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "@") {
+			fields := strings.Fields(line)
+			section = fields[0]
+			if section == "@RULE" && len(fields) > 1 {
+				table.Name = fields[1]
+			}
+			continue
+		}
+		var err error
+		switch section {
+		case "@TABLE":
+			switch {
+			case strings.HasPrefix(line, "n_states:"):
+				table.States, err = strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "n_states:")))
+				if err == nil && (table.States < 2 || table.States > 256) {
+					err = fmt.Errorf("n_states must be between 2 and 256")
+				}
+			case strings.HasPrefix(line, "neighborhood:"):
+				table.Neighborhood = strings.TrimSpace(strings.TrimPrefix(line, "neighborhood:"))
+			case strings.HasPrefix(line, "symmetries:"):
+				symmetries = strings.TrimSpace(strings.TrimPrefix(line, "symmetries:"))
+			case strings.HasPrefix(line, "var "):
+				err = parseTableVar(line, table.States, vars)
+			default:
+				lines = append(lines, tableLine{lineno, line})
+			}
+		case "@COLORS":
+			err = table.parseColor(line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineno, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if table.States == 0 {
+		return nil, fmt.Errorf("no n_states in @TABLE section")
+	}
+
+	order := table.order()
+	if order == nil {
+		return nil, fmt.Errorf("unsupported neighborhood '%s'", table.Neighborhood)
+	}
+	var err error
+	table.symmetries, table.permute, err = tableSymmetries(symmetries, len(order))
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		transition, err := parseTableTransition(line.text, table.States, len(order), vars)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line.number, err)
+		}
+		table.transitions = append(table.transitions, transition)
+	}
+	return table, nil
+}
+
+/*
+tableLine is a transition waiting to be parsed, which can't happen until the whole @TABLE section
+has been read, since its header can come in any order.
+*/
+type tableLine struct {
+	number int
+	text   string
+}
+
+/*
+parseTableVar parses a line like `var a={0,1,2}` into vars. A variable's values can include
+variables defined before it.
+*/
+func parseTableVar(line string, states int, vars map[string][]int) error {
+	parts := strings.SplitN(strings.TrimPrefix(line, "var "), "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("variable definition has no '='")
+	}
+	name := strings.TrimSpace(parts[0])
+	body := strings.TrimSpace(parts[1])
+	if !strings.HasPrefix(body, "{") || !strings.HasSuffix(body, "}") {
+		return fmt.Errorf("values of variable '%s' aren't in braces", name)
+	}
+	var values []int
+	for _, token := range strings.Split(body[1:len(body)-1], ",") {
+		token = strings.TrimSpace(token)
+		if vs, ok := vars[token]; ok {
+			values = append(values, vs...)
+			continue
+		}
+		n, err := parseTableState(token, states)
+		if err != nil {
+			return err
+		}
+		values = append(values, n)
+	}
+	vars[name] = values
+	return nil
+}
+
+/*
+parseTableState parses a state number, checking that it's in range.
+*/
+func parseTableState(token string, states int) (int, error) {
+	n, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is neither a state nor a variable", token)
+	}
+	if n < 0 || n >= states {
+		return 0, fmt.Errorf("state %d is out of range for %d states", n, states)
+	}
+	return n, nil
+}
+
+/*
+parseTableTransition parses a transition: the cell's state, its neighbors' states, and its next
+state, either separated by commas or, if every one is a single digit, run together.
+*/
+func parseTableTransition(line string, states, neighbors int, vars map[string][]int) (tableTransition, error) {
+	var tokens []string
+	if strings.ContainsAny(line, ", ") {
+		tokens = strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+	} else {
+		for _, r := range line {
+			tokens = append(tokens, string(r))
+		}
+	}
+	if len(tokens) != neighbors+2 {
+		return tableTransition{}, fmt.Errorf("transition has %d states; expected %d", len(tokens), neighbors+2)
+	}
+	transition := tableTransition{bound: make([]int, neighbors+1)}
+	first := make(map[string]int)
+	for i, token := range tokens[:neighbors+1] {
+		set := make([]bool, states)
+		transition.bound[i] = i
+		if values, ok := vars[token]; ok {
+			for _, n := range values {
+				set[n] = true
+			}
+			if j, ok := first[token]; ok {
+				transition.bound[i] = j
+			} else {
+				first[token] = i
+			}
+		} else {
+			n, err := parseTableState(token, states)
+			if err != nil {
+				return tableTransition{}, err
+			}
+			set[n] = true
+		}
+		transition.inputs = append(transition.inputs, set)
+	}
+	output := tokens[neighbors+1]
+	if j, ok := first[output]; ok {
+		transition.output = -j - 1
+	} else if _, ok := vars[output]; ok {
+		return tableTransition{}, fmt.Errorf("output variable '%s' isn't bound by an input", output)
+	} else {
+		n, err := parseTableState(output, states)
+		if err != nil {
+			return tableTransition{}, err
+		}
+		transition.output = n
+	}
+	return transition, nil
+}
+
+/*
+tableSymmetries returns the neighbor orders for one of Golly's symmetries, for a neighborhood of n
+neighbors going round clockwise.
+*/
+func tableSymmetries(name string, n int) ([][]int, bool, error) {
+	rotate := func(step int) [][]int {
+		var orders [][]int
+		for k := 0; k < n; k += step {
+			order := make([]int, n)
+			for i := range order {
+				order[i] = (i + k) % n
+			}
+			orders = append(orders, order)
+		}
+		return orders
+	}
+	// reflect mirrors every order left to right
+	reflect := func(orders [][]int) [][]int {
+		for _, order := range orders {
+			mirrored := make([]int, n)
+			for i := range mirrored {
+				mirrored[i] = order[(n-i)%n]
+			}
+			orders = append(orders, mirrored)
+		}
+		return orders
+	}
+	switch name {
+	case "", "none":
+		return rotate(n), false, nil
+	case "rotate4":
+		return rotate(n / 4), false, nil
+	case "rotate4reflect":
+		return reflect(rotate(n / 4)), false, nil
+	case "reflect_horizontal":
+		return reflect(rotate(n)), false, nil
+	case "permute":
+		return nil, true, nil
+	}
+	if n == 8 {
+		switch name {
+		case "rotate8":
+			return rotate(1), false, nil
+		case "rotate8reflect":
+			return reflect(rotate(1)), false, nil
+		}
+	}
+	return nil, false, fmt.Errorf("unsupported symmetries '%s'", name)
+}
+
+/*
+parseColor parses a line of the @COLORS section, like `1 255 0 0`. Gradient lines are skipped.
+*/
+func (table *RuleTable) parseColor(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) != 4 {
+		return nil
+	}
+	var values [4]int
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return fmt.Errorf("invalid color line '%s'", line)
+		}
+		values[i] = n
+	}
+	if table.Palette == nil {
+		table.Palette = make(Palette)
+	}
+	table.Palette[TableState(values[0])] = color.RGBA{uint8(values[1]), uint8(values[2]), uint8(values[3]), 0xff}
+	return nil
+}
+
+/*
+order returns the NeighborIndexes of the table's neighborhood, in the order Golly lists them, or
+nil if the neighborhood isn't one cellaut supports.
+*/
+func (table *RuleTable) order() []NeighborIndex {
+	switch table.Neighborhood {
+	case "vonNeumann":
+		return vonNeumannOrder
+	case "Moore":
+		return mooreOrder
+	}
+	return nil
+}
+
+/*
+neighborhood returns where each of a cell's neighbors is, for stepping the table with stepGrid.
+*/
+func (table *RuleTable) neighborhood() map[NeighborIndex][2]int {
+	if table.Neighborhood == "vonNeumann" {
+		return vonNeumannNeighborhood
+	}
+	return mooreNeighborhood
+}
+
+/*
+Rule returns the table as a Rule. Results are cached, so it only has to search the table once for
+each neighborhood it sees.
+*/
+func (table *RuleTable) Rule() Rule {
+	order := table.order()
+	return func(self State, neighbors map[NeighborIndex]State) State {
+		key := make([]byte, len(order)+1)
+		key[0] = byte(tableStateNumber(self, table.States))
+		for i, index := range order {
+			// A missing neighbor is read as "", which is state 0
+			key[i+1] = byte(tableStateNumber(neighbors[index], table.States))
+		}
+		return TableState(table.lookup(key))
+	}
+}
+
+/*
+Step applies the table to every cell of grid at once, like stepGrid does with a Rule, but works on
+StateIDs the whole way through rather than building a map of neighbor States for every cell. It's
+the way to go for rules with a lot of states.
+
+Cells off the edge of the grid are read as state 0. grid's table should have TableState(0) as its
+StateID 0, so that the cells it adds are empty.
+*/
+func (table *RuleTable) Step(grid *StateGrid) *StateGrid {
+	// states[id] is the table state of each StateID, and ids[n] the StateID of each table state
+	states := make([]byte, grid.Table.Len())
+	for id, state := range grid.Table.States() {
+		states[id] = byte(tableStateNumber(state, table.States))
+	}
+	ids := make([]StateID, table.States)
+	for n := range ids {
+		ids[n] = grid.Table.Intern(TableState(n))
+	}
+
+	neighborhood := table.neighborhood()
+	offsets := make([][2]int, 0, len(neighborhood))
+	for _, index := range table.order() {
+		offsets = append(offsets, neighborhood[index])
+	}
+	next := NewStateGrid(grid.Width, grid.Height, grid.Table)
+	next.Groups = grid.Groups
+	key := make([]byte, len(offsets)+1)
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			key[0] = states[grid.cells[y*grid.Width+x]]
+			for i, offset := range offsets {
+				nx, ny := x+offset[0], y+offset[1]
+				key[i+1] = 0
+				if nx >= 0 && nx < grid.Width && ny >= 0 && ny < grid.Height {
+					key[i+1] = states[grid.cells[ny*grid.Width+nx]]
+				}
+			}
+			next.cells[y*grid.Width+x] = ids[table.lookup(key)]
+		}
+	}
+	return next
+}
+
+/*
+lookup returns the next state for a key of a cell's state followed by its neighbors', from the
+cache if it's there.
+*/
+func (table *RuleTable) lookup(key []byte) int {
+	if next, ok := table.cache.Load(string(key)); ok {
+		return next.(int)
+	}
+	next := table.next(key)
+	table.cache.Store(string(key), next)
+	return next
+}
+
+/*
+next returns the next state of a cell, given a key of its state followed by its neighbors'.
+*/
+func (table *RuleTable) next(key []byte) int {
+	if table.compute != nil {
+		return table.compute(key)
+	}
+	values := make([]int, len(key))
+	for _, transition := range table.transitions {
+		if !transition.inputs[0][key[0]] {
+			continue
+		}
+		values[0] = int(key[0])
+		matched := false
+		for _, order := range table.symmetries {
+			if matched = transition.match(key, order, values); matched {
+				break
+			}
+		}
+		if table.permute {
+			matched = transition.matchAny(key, 1, make([]bool, len(key)), values)
+		}
+		if !matched {
+			continue
+		}
+		if transition.output < 0 {
+			return values[-transition.output-1]
+		}
+		return transition.output
+	}
+	return int(key[0])
+}
+
+/*
+match returns whether the transition matches key when its neighbors are read in the given order,
+filling in values with the state matched by each input.
+*/
+func (transition tableTransition) match(key []byte, order []int, values []int) bool {
+	for i, set := range transition.inputs[1:] {
+		value := int(key[order[i]+1])
+		if !set[value] || (transition.bound[i+1] != i+1 && values[transition.bound[i+1]] != value) {
+			return false
+		}
+		values[i+1] = value
+	}
+	return true
+}
+
+/*
+matchAny returns whether the transition's inputs from i on can be matched to the neighbors in key
+that aren't used yet, in any order. It's how the "permute" symmetry is matched.
+*/
+func (transition tableTransition) matchAny(key []byte, i int, used []bool, values []int) bool {
+	if i == len(transition.inputs) {
+		return true
+	}
+	for j := 1; j < len(key); j++ {
+		value := int(key[j])
+		if used[j] || !transition.inputs[i][value] {
+			continue
+		}
+		if transition.bound[i] != i && values[transition.bound[i]] != value {
+			continue
+		}
+		used[j], values[i] = true, value
+		if transition.matchAny(key, i+1, used, values) {
+			return true
+		}
+		used[j] = false
+	}
+	return false
+}
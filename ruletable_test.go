@@ -0,0 +1,121 @@
+package cellaut
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRuleTable(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	table, err := ParseRuleTable(strings.NewReader(`
+		@RULE Test
+		Some prose about the rule, which is ignored.
+
+		@TABLE
+		n_states:4
+		neighborhood:vonNeumann
+		symmetries:rotate4
+		var a={0,1}
+		var b={a,2}
+		# A 1 with a 2 to its north (or east, south or west) becomes a 3
+		1,2,0,0,0,3
+		# A 0 between two neighbors in the same state copies them
+		0,b,0,b,0,b
+		230001
+
+		@COLORS
+		1 255 0 0
+		0 0 0 255 255 255
+	`))
+	assert.Nil(err)
+	assert.Equal("Test", table.Name)
+	assert.Equal(4, table.States)
+	assert.Equal(Palette{"1": color.RGBA{0xff, 0, 0, 0xff}}, table.Palette)
+	rule := table.Rule()
+
+	vn := func(up, rt, dn, lf State) map[NeighborIndex]State {
+		return map[NeighborIndex]State{NeighborUp: up, NeighborRt: rt, NeighborDn: dn, NeighborLf: lf}
+	}
+	assert.Equal(State("3"), rule("1", vn("2", "-", "-", "-")))
+	assert.Equal(State("3"), rule("1", vn("-", "-", "-", "2")))
+	assert.Equal(State("1"), rule("1", vn("2", "2", "-", "-")))
+	assert.Equal(State("2"), rule("-", vn("-", "2", "-", "2")))
+	assert.Equal(State("1"), rule("-", vn("1", "-", "1", "-")))
+	// b is bound, so both neighbors have to match
+	assert.Equal(State("-"), rule("-", vn("1", "-", "2", "-")))
+	// Cached the second time round
+	assert.Equal(State("-"), rule("-", vn("1", "-", "2", "-")))
+	// Unknown States and missing neighbors are state 0
+	assert.Equal(State("1"), rule("2", vn("3", "", ".", "-")))
+	assert.Equal(State("1"), rule("2", map[NeighborIndex]State{NeighborUp: "3"}))
+	// Nothing matches
+	assert.Equal(State("2"), rule("2", vn("1", "1", "1", "1")))
+
+	_, err = ParseRuleTable(strings.NewReader("@TABLE\nneighborhood:vonNeumann\n000000\n"))
+	assert.NotNil(err)
+	_, err = ParseRuleTable(strings.NewReader("@TABLE\nn_states:2\nneighborhood:vonNeumann\n0000\n"))
+	assert.NotNil(err)
+	_, err = ParseRuleTable(strings.NewReader("@TABLE\nn_states:2\nneighborhood:vonNeumann\n000002\n"))
+	assert.NotNil(err)
+	_, err = ParseRuleTable(strings.NewReader("@TABLE\nn_states:2\nneighborhood:hexagonal\n"))
+	assert.NotNil(err)
+}
+
+func TestRuleTable_Symmetries(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	moore := func(symmetries string) Rule {
+		table, err := ParseRuleTable(strings.NewReader(`
+			@TABLE
+			n_states:2
+			neighborhood:Moore
+			symmetries:` + symmetries + `
+			0,1,1,0,1,0,0,0,0,1
+		`))
+		assert.Nil(err)
+		return table.Rule()
+	}
+	neighbors := func(states ...NeighborIndex) map[NeighborIndex]State {
+		m := make(map[NeighborIndex]State)
+		for _, index := range states {
+			m[index] = "1"
+		}
+		return m
+	}
+	// North, northeast and southeast, which isn't its own mirror image
+	n := neighbors(NeighborUp, NeighborUpRt, NeighborDnRt)
+	// The same turned a quarter turn clockwise
+	e := neighbors(NeighborRt, NeighborDnRt, NeighborDnLf)
+	// The same turned an eighth of a turn
+	ne := neighbors(NeighborUpRt, NeighborRt, NeighborDn)
+	// The mirror image
+	nw := neighbors(NeighborUp, NeighborUpLf, NeighborDnLf)
+	// Three neighbors that only match when the order doesn't matter
+	ns := neighbors(NeighborUp, NeighborDn, NeighborLf)
+
+	for _, c := range []struct {
+		symmetries       string
+		n, e, ne, nw, ns State
+	}{
+		{"none", "1", "-", "-", "-", "-"},
+		{"rotate4", "1", "1", "-", "-", "-"},
+		{"rotate8", "1", "1", "1", "-", "-"},
+		{"reflect_horizontal", "1", "-", "-", "1", "-"},
+		{"rotate4reflect", "1", "1", "-", "1", "-"},
+		{"rotate8reflect", "1", "1", "1", "1", "-"},
+		{"permute", "1", "1", "1", "1", "1"},
+	} {
+		rule := moore(c.symmetries)
+		assert.Equal(c.n, rule("-", n), c.symmetries)
+		assert.Equal(c.e, rule("-", e), c.symmetries)
+		assert.Equal(c.ne, rule("-", ne), c.symmetries)
+		assert.Equal(c.nw, rule("-", nw), c.symmetries)
+		assert.Equal(c.ns, rule("-", ns), c.symmetries)
+	}
+}
@@ -0,0 +1,151 @@
+package cellaut
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+/*
+RunOption changes how Ticker.Run goes about ticking.
+*/
+type RunOption func(*runConfig)
+
+type runConfig struct {
+	interval time.Duration
+	maxTicks int64
+	stopWhen StopCondition
+	world    World
+}
+
+/*
+WithTicksPerSecond makes Run tick at most rate times a second. Without it, Run ticks as fast as the
+cells can keep up.
+*/
+func WithTicksPerSecond(rate float64) RunOption {
+	return func(config *runConfig) {
+		if rate > 0 {
+			config.interval = time.Duration(float64(time.Second) / rate)
+		}
+	}
+}
+
+/*
+WithMaxTicks makes Run return once the Ticker has run n ticks in all, counting any ticks from before
+Run was called.
+*/
+func WithMaxTicks(n int64) RunOption {
+	return func(config *runConfig) {
+		config.maxTicks = n
+	}
+}
+
+/*
+WithStopCondition makes Run check cond after every tick, and return once it says to stop.
+StopConditions that look at the World's States, like PopulationZero, also need WithWorld.
+*/
+func WithStopCondition(cond StopCondition) RunOption {
+	return func(config *runConfig) {
+		config.stopWhen = cond
+	}
+}
+
+/*
+WithWorld tells Run which World its Ticker's cells make up, for the StopCondition to look at.
+*/
+func WithWorld(world World) RunOption {
+	return func(config *runConfig) {
+		config.world = world
+	}
+}
+
+/*
+Run ticks over and over until ctx is done, the Ticker is stopped or halted, or one of the options
+says it's time to stop. It saves calling Tick in a loop and working out the timing by hand.
+
+It returns ctx's error if ctx is what stopped it, the Ticker's error if a cell halted it, and nil
+otherwise. Like Tick, it mustn't be called while the Ticker is already ticking.
+*/
+func (ticker *Ticker) Run(ctx context.Context, options ...RunOption) error {
+	var config runConfig
+	for _, option := range options {
+		option(&config)
+	}
+	var pace *time.Ticker
+	if config.interval > 0 {
+		pace = time.NewTicker(config.interval)
+		defer pace.Stop()
+	}
+	start := time.Now()
+	for first := true; ; first = false {
+		if config.maxTicks > 0 && ticker.currentTick() >= config.maxTicks {
+			return nil
+		}
+		// The first tick goes straight away, and the rest wait their turn
+		if pace != nil && !first {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.Done():
+				return ticker.Err()
+			case <-pace.C:
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.Done():
+			return ticker.Err()
+		default:
+		}
+
+		ticker.Tick()
+		if err := ticker.Err(); err != nil {
+			return err
+		}
+		if config.stopWhen != nil {
+			status := &StopStatus{Ticks: ticker.currentTick(), Elapsed: time.Since(start), world: config.world}
+			if config.world == nil {
+				// Without a World there's nothing to snapshot, so there's nothing to find in it
+				status.snapshot = NewStateGrid(0, 0, nil)
+			}
+			if config.stopWhen.ShouldStop(status) {
+				return nil
+			}
+		}
+	}
+}
+
+/*
+RunUntilStable ticks until a whole tick goes by without any cell changing state, and returns how
+many ticks it ran, counting that last, quiet one. From then on nothing will change unless something
+outside the rule does it, like SetState, so there's no need to guess how long a pattern takes to
+settle down.
+
+A cell's change is only noticed when it tells its neighbors about it, so a cell with no neighbors
+can change all it likes without RunUntilStable seeing. Oscillators never settle down; for them, see
+the Stabilized StopCondition.
+
+If the cells are still changing after maxTicks ticks, or the Ticker is stopped or halted first, it
+returns an error.
+*/
+func (ticker *Ticker) RunUntilStable(maxTicks int) (int, error) {
+	for ticks := 1; ticks <= maxTicks; ticks++ {
+		select {
+		case <-ticker.Done():
+			if err := ticker.Err(); err != nil {
+				return ticks - 1, err
+			}
+			return ticks - 1, fmt.Errorf("ticker stopped before the cells settled down")
+		default:
+		}
+		ticker.Tick()
+		if err := ticker.Err(); err != nil {
+			return ticks, err
+		}
+		if ticker.lastSends == 0 {
+			return ticks, nil
+		}
+	}
+	return maxTicks, fmt.Errorf("cells still changing after %d ticks", maxTicks)
+}
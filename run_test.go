@@ -0,0 +1,102 @@
+package cellaut
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+startLifeTicker starts every cell of a Life grid on a new Ticker, with the given cells alive.
+*/
+func startLifeTicker(grid *Grid, alive map[[2]int]bool) *Ticker {
+	for cell := range alive {
+		grid.At(cell[0], cell[1]).SetState(LifeAlive)
+	}
+	ticker := &Ticker{}
+	for _, aut := range grid.Cells() {
+		ticker.Start(aut, nil)
+	}
+	return ticker
+}
+
+func TestTicker_Run(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	grid := NewLifeGrid(10, 10)
+	ticker := startLifeTicker(grid, shifted(gliderCells, 2, 6))
+	defer ticker.Stop(time.Second)
+	assert.Nil(ticker.Run(context.Background(), WithMaxTicks(5)))
+	assert.Equal(int64(5), ticker.currentTick())
+
+	// Ticks already run count towards the maximum
+	assert.Nil(ticker.Run(context.Background(), WithMaxTicks(9), WithTicksPerSecond(100)))
+	assert.Equal(int64(9), ticker.currentTick())
+	assert.Equal(int64(len(gliderCells)), TakeSnapshot(grid, nil).Population(LifeAlive))
+
+	// Without a limit, it goes until ctx is done
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(context.DeadlineExceeded, ticker.Run(ctx, WithTicksPerSecond(1000)))
+	assert.True(ticker.currentTick() > 9)
+}
+
+func TestTicker_Run_Rate(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	ticker := startLifeTicker(NewLifeGrid(3, 3), nil)
+	defer ticker.Stop(time.Second)
+	start := time.Now()
+	assert.Nil(ticker.Run(context.Background(), WithMaxTicks(6), WithTicksPerSecond(50)))
+	// The first tick is straight away, and each of the other 5 waits 20ms
+	assert.True(time.Since(start) >= 100*time.Millisecond, "took %s", time.Since(start))
+}
+
+func TestTicker_Run_StopCondition(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A lone cell dies in the first generation, which is the second tick
+	grid := NewLifeGrid(5, 5)
+	ticker := startLifeTicker(grid, map[[2]int]bool{{2, 2}: true})
+	defer ticker.Stop(time.Second)
+	assert.Nil(ticker.Run(context.Background(), WithStopCondition(PopulationZero(LifeAlive)), WithWorld(grid)))
+	assert.Equal(int64(2), ticker.currentTick())
+
+	// Stopping the Ticker stops Run too
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		ticker.Stop(time.Second)
+	}()
+	assert.Nil(ticker.Run(context.Background(), WithStopCondition(AfterTicks(1<<40))))
+}
+
+func TestTicker_RunUntilStable(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Three cells of a block fill in the fourth in the first generation, and then nothing changes
+	grid := NewLifeGrid(6, 6)
+	ticker := startLifeTicker(grid, map[[2]int]bool{{2, 2}: true, {3, 2}: true, {2, 3}: true})
+	defer ticker.Stop(time.Second)
+	ticks, err := ticker.RunUntilStable(100)
+	assert.Nil(err)
+	// The starting states, the first generation, and the quiet tick that shows it's settled
+	assert.Equal(3, ticks)
+	assert.Equal(int64(4), TakeSnapshot(grid, nil).Population(LifeAlive))
+
+	// A blinker never settles
+	blinker := startLifeTicker(NewLifeGrid(5, 5), map[[2]int]bool{{1, 2}: true, {2, 2}: true, {3, 2}: true})
+	defer blinker.Stop(time.Second)
+	ticks, err = blinker.RunUntilStable(10)
+	assert.NotNil(err)
+	assert.Equal(10, ticks)
+
+	blinker.Stop(time.Second)
+	_, err = blinker.RunUntilStable(10)
+	assert.NotNil(err)
+}
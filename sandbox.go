@@ -0,0 +1,180 @@
+package cellaut
+
+import (
+	"fmt"
+	"time"
+)
+
+/*
+RuleSandbox steps a grid with a Rule that can't be trusted, like one submitted through a web form.
+Whatever the rule does wrong is reported as a RuleViolation against the cell it was working on, and
+that cell keeps its state; the rest of the grid carries on.
+
+All it enforces is this:
+
+  - A rule that panics is recovered from, and the cell keeps its state.
+  - A returned State longer than MaxStateLength is refused.
+  - A returned State that's new to the grid is refused once the rule has already added MaxStates of
+    them this Step.
+  - Once TickBudget has run out, no more cells are stepped. The rule runs on its own goroutine
+    against the clock, so even a call that never returns only holds the tick up until then.
+  - The rule is given its own copy of each cell's neighbors, so it can't change the grid through
+    them.
+
+It doesn't limit the CPU, memory or I/O a rule uses inside a call, or stop it from starting
+goroutines or touching globals. A call that runs past TickBudget is abandoned, not stopped: Go has no
+way to kill a goroutine, so a rule that loops forever keeps its goroutine busy for good. Those limits
+belong to whatever interprets the script, which should count instructions and memory and give
+scripts no I/O functions to call in the first place. RuleSandbox is the part that stops a
+misbehaving script from taking the engine down with it.
+
+If it has a Supervisor, every RuleViolation is reported to it too, as a CellError with no Cell.
+Under ErrorHalt, Step stops at the first one and leaves the rest of the cells as they were.
+*/
+type RuleSandbox struct {
+	// TickBudget is how long a whole tick may take. Cells that haven't been stepped when it runs
+	// out keep their states. Zero means no limit.
+	TickBudget time.Duration
+	// MaxStates is how many States a rule may add in one Step, on top of the ones in the grid's
+	// StateTable to start with. A rule that keeps making up new ones would otherwise grow the
+	// StateTable without end. Zero means no limit.
+	MaxStates int
+	// MaxStateLength is the longest State a rule may return, in bytes. Zero means no limit.
+	MaxStateLength int
+	// Supervisor, if it isn't nil, is told about every RuleViolation, with TickID as their tick
+	Supervisor *Supervisor
+	TickID     int64
+}
+
+/*
+RuleViolation is something a sandboxed rule did wrong at a cell.
+*/
+type RuleViolation struct {
+	X, Y int
+	Err  error
+}
+
+func (violation *RuleViolation) Error() string {
+	return fmt.Sprintf("cell (%d, %d): %s", violation.X, violation.Y, violation.Err)
+}
+
+/*
+Step is stepGrid, with the rule kept in the sandbox. It returns the next generation along with
+whatever the rule did wrong along the way.
+
+The next generation gets a copy of grid's StateTable, so the States the rule makes up never end up in
+grid's, which other grids may be sharing.
+*/
+func (sandbox RuleSandbox) Step(grid *StateGrid, rule Rule, neighborhood map[NeighborIndex][2]int) (*StateGrid, []*RuleViolation) {
+	// The copy gives every State the same ID, so the cells can be copied as they are
+	next := NewStateGrid(grid.Width, grid.Height, NewStateTable(grid.Table.States()...))
+	copy(next.cells, grid.cells)
+	base := next.Table.Len()
+	var violations []*RuleViolation
+	var deadline time.Time
+	if sandbox.TickBudget > 0 {
+		deadline = time.Now().Add(sandbox.TickBudget)
+	}
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			if !deadline.IsZero() && time.Now().After(deadline) {
+				violations = append(violations, sandbox.overBudget(x, y))
+				sandbox.report(violations[len(violations)-1])
+				return next, violations
+			}
+			// The rule gets its own copy of the neighbors, so it can't leave anything behind in them
+			neighbors := make(map[NeighborIndex]State, len(neighborhood))
+			for index, offset := range neighborhood {
+				nx, ny := x+offset[0], y+offset[1]
+				if nx >= 0 && nx < grid.Width && ny >= 0 && ny < grid.Height {
+					neighbors[index] = grid.At(nx, ny)
+				}
+			}
+			state, err := sandbox.applyBy(deadline, rule, grid.At(x, y), neighbors, next.Table, base)
+			if err == errOverBudget {
+				violations = append(violations, sandbox.overBudget(x, y))
+				sandbox.report(violations[len(violations)-1])
+				return next, violations
+			}
+			if err != nil {
+				violations = append(violations, &RuleViolation{X: x, Y: y, Err: err})
+				if sandbox.report(violations[len(violations)-1]) == ErrorHalt {
+					return next, violations
+				}
+				continue
+			}
+			next.Set(x, y, state)
+		}
+	}
+	return next, violations
+}
+
+// errOverBudget is what applyBy returns if the rule is still running when the tick's budget runs out
+var errOverBudget = fmt.Errorf("rule ran over the tick's budget")
+
+/*
+overBudget is the RuleViolation for running out of TickBudget at cell (x, y).
+*/
+func (sandbox RuleSandbox) overBudget(x, y int) *RuleViolation {
+	return &RuleViolation{X: x, Y: y, Err: fmt.Errorf("tick ran over its budget of %s; this cell and the rest were left as they were", sandbox.TickBudget)}
+}
+
+/*
+report tells the Supervisor about violation, if there is one, and returns its policy. With no
+Supervisor, violations never halt anything, which is the same as ErrorSkipCell.
+*/
+func (sandbox RuleSandbox) report(violation *RuleViolation) ErrorPolicy {
+	if sandbox.Supervisor == nil {
+		return ErrorSkipCell
+	}
+	return sandbox.Supervisor.Report(&CellError{TickID: sandbox.TickID, Err: violation})
+}
+
+/*
+applyBy is apply, run on a goroutine of its own so that the rule can be given up on if deadline
+passes before it returns. Then it returns errOverBudget. A zero deadline means there's no hurry, and
+apply is called as it is.
+*/
+func (sandbox RuleSandbox) applyBy(deadline time.Time, rule Rule, self State, neighbors map[NeighborIndex]State, table *StateTable, base int) (State, error) {
+	if deadline.IsZero() {
+		return sandbox.apply(rule, self, neighbors, table, base)
+	}
+	type result struct {
+		state State
+		err   error
+	}
+	// Buffered, so an abandoned rule that does return doesn't leave its goroutine stuck sending
+	done := make(chan result, 1)
+	go func() {
+		state, err := sandbox.apply(rule, self, neighbors, table, base)
+		done <- result{state, err}
+	}()
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.state, r.err
+	case <-timer.C:
+		return "", errOverBudget
+	}
+}
+
+/*
+apply runs the rule on one cell and checks what it comes back with. table had base States before
+the rule was let at it.
+*/
+func (sandbox RuleSandbox) apply(rule Rule, self State, neighbors map[NeighborIndex]State, table *StateTable, base int) (state State, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("rule panicked: %v", r)
+		}
+	}()
+	state = rule(self, neighbors)
+	if sandbox.MaxStateLength > 0 && len(state) > sandbox.MaxStateLength {
+		return "", fmt.Errorf("rule returned a %d-byte state, over the limit of %d", len(state), sandbox.MaxStateLength)
+	}
+	if _, ok := table.ID(state); !ok && sandbox.MaxStates > 0 && table.Len()-base >= sandbox.MaxStates {
+		return "", fmt.Errorf("rule returned new state '%s', but it has already added the limit of %d", state, sandbox.MaxStates)
+	}
+	return state, nil
+}
@@ -0,0 +1,132 @@
+package cellaut
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuleSandbox_Step(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	pattern, err := ParsePattern("-----\n--X--\n--X--\n--X--\n-----\n", NewStateTable("-"))
+	assert.Nil(err)
+	grid := pattern.StateGrid
+
+	// A well-behaved rule does the same as it would outside
+	next, violations := RuleSandbox{TickBudget: time.Minute, MaxStates: 1, MaxStateLength: 1}.Step(grid, Life, mooreNeighborhood)
+	assert.Empty(violations)
+	assert.Equal(stepGrid(grid, Life, mooreNeighborhood).cells, next.cells)
+
+	// One that blows up or misbehaves at some cells only costs those cells
+	misbehaving := func(self State, neighbors map[NeighborIndex]State) State {
+		if self == "X" {
+			panic("boom")
+		}
+		if _, ok := neighbors[NeighborUp]; !ok {
+			return State(strings.Repeat("Y", 10))
+		}
+		if _, ok := neighbors[NeighborLf]; !ok {
+			if _, ok := neighbors[NeighborDn]; !ok {
+				return "Z"
+			}
+			return "W"
+		}
+		return self
+	}
+	next, violations = RuleSandbox{MaxStates: 1, MaxStateLength: 5}.Step(grid, misbehaving, mooreNeighborhood)
+	// 3 panics, 5 cells along the top with states too long, and 3 more down the left with a second
+	// new state. The "-" and "X" that were already in the grid don't count toward MaxStates.
+	assert.Len(violations, 11)
+	assert.Equal(State("Z"), next.At(0, 0))
+	assert.Contains(violations[0].Error(), "cell (0, 1): rule returned new state 'W'")
+	assert.Contains(violations[10].Error(), "cell (4, 4): rule returned a 10-byte state")
+	// The new State went in a table of the next grid's own
+	assert.Equal([]State{"-", "X"}, grid.Table.States())
+	assert.Equal([]State{"-", "X", "Z"}, next.Table.States())
+
+	// With no limits, the long states are fine
+	_, violations = RuleSandbox{}.Step(grid, misbehaving, mooreNeighborhood)
+	assert.Len(violations, 3)
+	assert.Contains(violations[0].Error(), "rule panicked: boom")
+}
+
+func TestRuleSandbox_TickBudget(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	grid := NewStateGrid(10, 10, NewStateTable("-"))
+	slow := func(self State, neighbors map[NeighborIndex]State) State {
+		time.Sleep(time.Millisecond)
+		return "X"
+	}
+	next, violations := RuleSandbox{TickBudget: 10 * time.Millisecond}.Step(grid, slow, mooreNeighborhood)
+	assert.Len(violations, 1)
+	assert.Contains(violations[0].Error(), "ran over its budget")
+	// Some cells got stepped before time ran out, but not all of them
+	stepped := next.Population("X")
+	assert.True(stepped > 0 && stepped < 100, "%d cells stepped", stepped)
+}
+
+func TestRuleSandbox_StuckRule(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A rule that never returns at one cell only holds the tick up until the budget runs out
+	release := make(chan struct{})
+	defer close(release)
+	stuck := func(self State, neighbors map[NeighborIndex]State) State {
+		if self == "X" {
+			<-release
+		}
+		return "O"
+	}
+	pattern, err := ParsePattern("---\n-X-\n---\n", NewStateTable("-"))
+	assert.Nil(err)
+	start := time.Now()
+	next, violations := RuleSandbox{TickBudget: 50 * time.Millisecond}.Step(pattern.StateGrid, stuck, mooreNeighborhood)
+	assert.True(time.Since(start) < 5*time.Second)
+	assert.Len(violations, 1)
+	assert.Contains(violations[0].Error(), "cell (1, 1): tick ran over its budget")
+	// The cells before it were stepped, and it and the rest weren't
+	assert.Equal(int64(4), next.Population("O"))
+	assert.Equal(State("X"), next.At(1, 1))
+}
+
+func TestRuleSandbox_Supervisor(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	pattern, err := ParsePattern("-X-\n-X-\n", NewStateTable("-"))
+	assert.Nil(err)
+	grid := pattern.StateGrid
+	misbehaving := func(self State, neighbors map[NeighborIndex]State) State {
+		if self == "X" {
+			panic("boom")
+		}
+		return "O"
+	}
+
+	// Under ErrorSkipCell, every violation is reported and the rest of the grid is stepped
+	sup := NewSupervisor(ErrorSkipCell)
+	next, violations := RuleSandbox{Supervisor: sup, TickID: 7}.Step(grid, misbehaving, mooreNeighborhood)
+	assert.Len(violations, 2)
+	assert.Equal(int64(4), next.Population("O"))
+	if assert.Len(sup.Errors(), 2) {
+		cellErr := <-sup.Errors()
+		assert.Nil(cellErr.Cell)
+		assert.Equal(violations[0], cellErr.Err)
+		assert.Equal("at tick 7: cell (1, 0): rule panicked: boom", cellErr.Error())
+	}
+	assert.Nil(sup.Err())
+
+	// Under ErrorHalt, the first one stops the Step
+	sup = NewSupervisor(ErrorHalt)
+	next, violations = RuleSandbox{Supervisor: sup}.Step(grid, misbehaving, mooreNeighborhood)
+	assert.Len(violations, 1)
+	assert.Equal(int64(1), next.Population("O"))
+	assert.NotNil(sup.Err())
+}
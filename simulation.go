@@ -0,0 +1,617 @@
+package cellaut
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+/*
+Layer is anything that gets stepped once per tick, after the cells have updated, like an *AgentLayer
+or a *Field.
+*/
+type Layer interface {
+	Step()
+}
+
+/*
+EngineKind selects how a Simulation runs its cells.
+
+Whichever one is picked, a run of RuleCellAuts is deterministic: as long as the rule is, the States
+after every tick depend only on the States the World started in. They don't depend on the engine, on
+how many Workers there are, on GOMAXPROCS, or on what order the goroutines happen to get scheduled
+in. Runs can be compared across machines, and a run can be reproduced just by rerunning it. Rules
+that roll dice, like forest-fire's, are only as deterministic as their random number generator.
+
+That's because a RuleCellAut keeps its neighbors' States by direction. Cells that don't, like
+GooCellAut, which takes on whichever State it hears last, depend on the order the States arrive in,
+and that's up to the scheduler. They're only deterministic with OrderedDelivery.
+
+determinism_test.go holds the engines to this. It's worth running with -race and a high -count
+after changing anything about how ticks are scheduled.
+*/
+type EngineKind string
+
+const (
+	// ChannelEngine runs every CellAut in its own goroutine, talking to its neighbors over
+	// channels and driven by a Ticker. That's a goroutine per cell, so for big grids, the
+	// MultiplexedEngine's pool of workers goes a lot further.
+	ChannelEngine EngineKind = "channel"
+	// MultiplexedEngine runs the CellAuts on a fixed number of goroutines with a Multiplexer. Every
+	// CellAut must be a MultiplexedCellAut.
+	MultiplexedEngine EngineKind = "multiplexed"
+	// SynchronousEngine runs the CellAuts with a SyncEngine, on the goroutine that drives the ticks.
+	// The World must be a *Grid of RuleCellAuts, or of cells built on them like LifeCellAuts.
+	SynchronousEngine EngineKind = "sync"
+)
+
+/*
+engine is what a Simulation needs from whatever runs its cells. It's satisfied by *Ticker,
+*Multiplexer and *SyncEngine.
+*/
+type engine interface {
+	add(aut CellAut, stateLedger chan StateRecord) error
+	Remove(aut CellAut) error
+	SetAsleep(aut CellAut, asleep bool) error
+	TickContext(ctx context.Context)
+	Err() error
+	Done() chan struct{}
+	Stop(timeout time.Duration) error
+	Health() Health
+	currentTick() int64
+}
+
+/*
+SimulationConfig is everything a Simulation needs to know before it starts.
+*/
+type SimulationConfig struct {
+	// World holds the CellAuts to simulate. They must already be wired to their neighbors.
+	World World
+	// Engine picks how the cells are run. Defaults to ChannelEngine.
+	Engine EngineKind
+	// Layers are stepped after every tick, in order.
+	Layers []Layer
+	// TickInterval is how long to wait between ticks. Zero means tick as fast as possible.
+	TickInterval time.Duration
+	// MaxTicks is how many ticks to run before stopping on its own. Zero means run until Stop.
+	MaxTicks int64
+	// StopWhen, if it's set, is checked after every tick, and the Simulation stops once it says so.
+	StopWhen StopCondition
+	// Hooks are run at the ticks they ask for, after the subscribers or before the tick.
+	Hooks []Hook
+	// Input, if it's set, is read for InputEvents as an InputReader does, and each one is applied
+	// just before the tick it's for, after any hooks. The Simulation waits for the events it
+	// needs, so Input can be a pipe from something that's producing them as it goes.
+	Input io.Reader
+	// History, if it's set, is kept up to date with the World's states after every tick, before
+	// the subscribers are called, and lets RewindTo work. It should be made from World.
+	History *History
+	// StopTimeout is how long to wait for the cells to exit when stopping. Defaults to
+	// DefaultStopTimeout.
+	StopTimeout time.Duration
+	// OnError is what to do when a cell reports an error. Defaults to ErrorHalt.
+	OnError ErrorPolicy
+	// Tracer, if it's set, gets a span for every tick and its phases.
+	Tracer Tracer
+	// OrderedDelivery makes the cells that are OrderedCellAuts apply the states their neighbors
+	// send them in a fixed order of directions, so that cells whose next state depends on which
+	// neighbor they hear from last come out the same every run.
+	OrderedDelivery bool
+	// MeasureLatency makes the ChannelEngine count how long the states the cells send each other
+	// take to arrive, for Latency.
+	MeasureLatency bool
+	// RegionOfInterest, if it's set, stops ticking the parts of the World where nothing is
+	// happening.
+	RegionOfInterest *RegionOfInterest
+	// Workers is how many goroutines the MultiplexedEngine uses. Defaults to GOMAXPROCS.
+	Workers int
+	// BatchSize is how many cells the MultiplexedEngine's workers take at a time. Defaults to
+	// DefaultBatchSize.
+	BatchSize int
+	// Groups name sets of the World's cells, by their (x, y)
+	Groups CellGroups
+	// Frozen are the names of the Groups whose cells never change after the first tick. They
+	// keep telling their neighbors their state, so they make fixed walls and sources. Cells that
+	// are FreezableCellAuts are frozen, so their rule is skipped; others are put to sleep.
+	Frozen []string
+}
+
+// ledgerBuffer is how many StateRecords the state ledger holds, so that cells don't have to wait
+// their turn to write to it.
+const ledgerBuffer = 1024
+
+// DefaultStopTimeout is the StopTimeout used when a SimulationConfig doesn't set one.
+const DefaultStopTimeout = 5 * time.Second
+
+/*
+TickEvent is what Simulation subscribers receive after every tick.
+
+World may be read, but not modified, until the subscriber returns.
+*/
+type TickEvent struct {
+	TickID int64
+	World  World
+	// Topology lists the cells added and removed by Edit just before this tick
+	Topology []TopologyChange
+}
+
+/*
+Simulation wires up and drives everything needed to run a World of CellAuts: the Ticker, the cell
+goroutines, the layers, and whoever wants to watch.
+
+The lifecycle is Configure → Subscribe → Start → Stop (or Wait). A Simulation can only be started
+once.
+*/
+type Simulation struct {
+	mu          sync.Mutex
+	config      SimulationConfig
+	configured  bool
+	started     bool
+	subscribers []func(TickEvent)
+	// stop is closed to ask the run loop to stop
+	stop     chan struct{}
+	stopOnce sync.Once
+	// finished is closed once the run loop has exited and the Ticker has been stopped
+	finished chan struct{}
+	// stopErr is what stopping the Ticker returned
+	stopErr error
+	// ctxErr is the error of the context passed to StartCtx, if that's what stopped the Simulation
+	ctxErr     error
+	supervisor *Supervisor
+	engine     engine
+	// syncEngine is built by Configure, when the config asks for the SynchronousEngine, since
+	// that's where it's checked that the World can be run by one
+	syncEngine *SyncEngine
+	// stateLedger is passed to every cell, including the ones added by Edit
+	stateLedger chan StateRecord
+	// recorders are called with everything written to the ledger, and ledgerFlush is how the run
+	// loop waits for them to catch up
+	recorders   []func(StateRecord)
+	ledgerFlush chan chan struct{}
+	// edits are the functions queued by Edit
+	edits []func(*TopologyEdit)
+	// regions is only set if the config has a RegionOfInterest
+	regions *regionTracker
+	hooks   []compiledHook
+	input   *InputReader
+	// rewind is the grid that RewindTo asked for, if it's been called since the last tick
+	rewind *StateGrid
+}
+
+/*
+NewSimulation returns an unconfigured *Simulation.
+*/
+func NewSimulation() *Simulation {
+	return &Simulation{
+		stop:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+}
+
+/*
+Configure sets the Simulation's config. It can be called any number of times before Start.
+*/
+func (sim *Simulation) Configure(config SimulationConfig) error {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	if sim.started {
+		return fmt.Errorf("can't configure a simulation that has already started")
+	}
+	if config.World == nil {
+		return fmt.Errorf("simulation config has no World")
+	}
+	if config.Engine == "" {
+		config.Engine = ChannelEngine
+	}
+	if config.Engine != ChannelEngine && config.Engine != MultiplexedEngine && config.Engine != SynchronousEngine {
+		return fmt.Errorf("unknown engine '%s'", config.Engine)
+	}
+	var syncEngine *SyncEngine
+	if config.Engine == SynchronousEngine {
+		grid, ok := config.World.(*Grid)
+		if !ok {
+			return fmt.Errorf("the sync engine can only run a *Grid")
+		}
+		var err error
+		if syncEngine, err = NewSyncEngine(grid); err != nil {
+			return err
+		}
+	}
+	if config.Engine == MultiplexedEngine {
+		width, height := config.World.Size()
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				if _, ok := config.World.At(x, y).(MultiplexedCellAut); !ok {
+					return fmt.Errorf("cell at (%d, %d) can't be run by the multiplexed engine", x, y)
+				}
+			}
+		}
+	}
+	if config.TickInterval < 0 || config.MaxTicks < 0 || config.StopTimeout < 0 {
+		return fmt.Errorf("TickInterval, MaxTicks and StopTimeout must not be negative")
+	}
+	if config.RegionOfInterest != nil {
+		if err := config.RegionOfInterest.validate(); err != nil {
+			return err
+		}
+		if len(config.Layers) > 0 {
+			return fmt.Errorf("a simulation with a RegionOfInterest can't have Layers")
+		}
+		if !config.Groups.freezable(config.World, config.Frozen) {
+			// It would wake the sleeping cells up along with the rest of their tiles
+			return fmt.Errorf("a simulation with a RegionOfInterest can only freeze FreezableCellAuts")
+		}
+	}
+	if err := config.Groups.Check(config.Frozen...); err != nil {
+		return err
+	}
+	hooks, err := compileHooks(config.Hooks)
+	if err != nil {
+		return err
+	}
+	if config.StopTimeout == 0 {
+		config.StopTimeout = DefaultStopTimeout
+	}
+	sim.config = config
+	sim.syncEngine = syncEngine
+	sim.hooks = hooks
+	sim.input = nil
+	if config.Input != nil {
+		sim.input = NewInputReader(config.Input)
+	}
+	sim.supervisor = NewSupervisor(config.OnError)
+	sim.configured = true
+	return nil
+}
+
+/*
+Errors returns a channel on which the errors reported by cells can be read. It's only valid after
+Configure.
+*/
+func (sim *Simulation) Errors() <-chan *CellError {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	return sim.supervisor.Errors()
+}
+
+/*
+Subscribe registers fn to be called after every tick, once the cells and layers have all updated.
+
+Subscribers are called in the order they subscribed, from the goroutine that drives the ticks, so a
+slow subscriber slows the whole Simulation down.
+*/
+func (sim *Simulation) Subscribe(fn func(TickEvent)) {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	sim.subscribers = append(sim.subscribers, fn)
+}
+
+/*
+SubscribeStates registers fn to be called with the StateRecord for every change of state, starting
+with every cell's first state at tick 0.
+
+A tick's records all go to fn before that tick's TickEvent subscribers are called, but the order
+within a tick is up to the engine. fn is called from a goroutine of its own, so it can take its
+time, but the Simulation waits for it at the end of every tick.
+*/
+func (sim *Simulation) SubscribeStates(fn func(StateRecord)) {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	sim.recorders = append(sim.recorders, fn)
+}
+
+/*
+Start brings the Simulation's cells to life and starts ticking. It returns immediately, or with an
+error if the engine won't take one of the World's cells, in which case the engine is stopped again.
+*/
+func (sim *Simulation) Start() error {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	if !sim.configured {
+		return fmt.Errorf("can't start a simulation before configuring it")
+	}
+	if sim.started {
+		return fmt.Errorf("simulation has already started")
+	}
+	sim.started = true
+
+	channels := &Ticker{Supervisor: sim.supervisor, Tracer: sim.config.Tracer}
+	if sim.config.MeasureLatency {
+		channels.Latency = NewLatencyHistogram()
+	}
+	var ticker engine = channels
+	if sim.config.Engine == MultiplexedEngine {
+		ticker = &Multiplexer{Workers: sim.config.Workers, BatchSize: sim.config.BatchSize, Supervisor: sim.supervisor, Tracer: sim.config.Tracer}
+	}
+	if sim.config.Engine == SynchronousEngine {
+		syncEngine := sim.syncEngine
+		syncEngine.Supervisor, syncEngine.Tracer = sim.supervisor, sim.config.Tracer
+		ticker = syncEngine
+	}
+	sim.engine = ticker
+	stateLedger := make(chan StateRecord, ledgerBuffer)
+	sim.stateLedger = stateLedger
+	if sim.config.RegionOfInterest != nil {
+		sim.regions = newRegionTracker(*sim.config.RegionOfInterest, sim.config.World)
+	}
+	width, height := sim.config.World.Size()
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			aut := sim.config.World.At(x, y)
+			if ordered, ok := aut.(OrderedCellAut); ok && sim.config.OrderedDelivery {
+				ordered.SetOrderedDelivery(true)
+			}
+			if err := ticker.add(aut, stateLedger); err != nil {
+				// A World with cells missing isn't worth running. The Simulation is over before
+				// it's begun, and Wait says why.
+				ticker.Stop(sim.config.StopTimeout)
+				sim.stopErr = fmt.Errorf("cell at (%d, %d): %s", x, y, err)
+				close(sim.finished)
+				return sim.stopErr
+			}
+		}
+	}
+	if sim.config.History != nil {
+		// The History goes first, so it's up to date by the time the other recorders hear about
+		// a tick
+		sim.recorders = append([]func(StateRecord){sim.config.History.Record}, sim.recorders...)
+	}
+	sim.ledgerFlush = make(chan chan struct{})
+	go sim.readLedger(ticker)
+
+	go func() {
+		sim.run(ticker)
+		sim.stopErr = ticker.Stop(sim.config.StopTimeout)
+		close(sim.finished)
+	}()
+	return nil
+}
+
+/*
+StartCtx is like Start, but the Simulation also stops once ctx is done, and Wait then returns ctx's
+error. The tick that's underway is allowed to finish first, so the cells all shut down cleanly
+between ticks.
+*/
+func (sim *Simulation) StartCtx(ctx context.Context) error {
+	if err := sim.Start(); err != nil {
+		return err
+	}
+	if ctx.Done() == nil {
+		return nil
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			sim.mu.Lock()
+			sim.ctxErr = ctx.Err()
+			sim.mu.Unlock()
+			sim.Stop()
+		case <-sim.finished:
+		}
+	}()
+	return nil
+}
+
+/*
+run ticks until it's told to stop, reaches MaxTicks, meets its StopWhen condition, or a cell halts
+it with an error.
+*/
+func (sim *Simulation) run(ticker engine) {
+	start := time.Now()
+	for sim.config.MaxTicks == 0 || ticker.currentTick() < sim.config.MaxTicks {
+		select {
+		case <-sim.stop:
+			return
+		default:
+		}
+
+		if !sim.tick(ticker) {
+			return
+		}
+		if sim.config.StopWhen != nil {
+			status := &StopStatus{Ticks: ticker.currentTick(), Elapsed: time.Since(start), world: sim.config.World}
+			if sim.config.StopWhen.ShouldStop(status) {
+				return
+			}
+		}
+
+		if sim.config.TickInterval > 0 {
+			timer := time.NewTimer(sim.config.TickInterval)
+			select {
+			case <-sim.stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+/*
+tick runs one tick, then steps the layers and calls the subscribers. It returns false if the tick
+halted the Ticker.
+*/
+func (sim *Simulation) tick(ticker engine) bool {
+	tracer := sim.config.Tracer
+	tickID := ticker.currentTick()
+	ctx, tickSpan := startSpan(context.Background(), tracer, "tick")
+	defer tickSpan.End()
+	tickSpan.SetAttribute("tick.id", tickID)
+
+	sim.applyRewind()
+	runHooks(sim.hooks, sim.config.World, tickID, true)
+	sim.applyInput(tickID)
+	topology := sim.applyEdits(ticker)
+	if tickID == 0 {
+		sim.freeze(ticker, true)
+	}
+	ticker.TickContext(ctx)
+	if ticker.Err() != nil {
+		return false
+	}
+	sim.flushLedger(ticker)
+	if sim.config.History != nil {
+		sim.config.History.endTick(tickID)
+	}
+	if tickID == 0 {
+		sim.freeze(ticker, false)
+	}
+	if sim.regions != nil {
+		sim.regions.update(ticker)
+	}
+	_, span := startSpan(ctx, tracer, "layers")
+	for _, layer := range sim.config.Layers {
+		layer.Step()
+	}
+	span.End()
+	sim.mu.Lock()
+	subscribers := sim.subscribers
+	sim.mu.Unlock()
+	_, span = startSpan(ctx, tracer, "subscribers")
+	for _, fn := range subscribers {
+		fn(TickEvent{TickID: tickID, World: sim.config.World, Topology: topology})
+	}
+	span.End()
+	runHooks(sim.hooks, sim.config.World, tickID, false)
+	return true
+}
+
+/*
+applyInput sets the states of the cells that the Input has events for at tickID. Events for cells
+outside the World are logged and skipped. If the Input can't be read, that's logged too, and the
+rest of it is ignored.
+*/
+func (sim *Simulation) applyInput(tickID int64) {
+	if sim.input == nil {
+		return
+	}
+	events, err := sim.input.Due(tickID)
+	for _, event := range events {
+		if err := setHookCell(sim.config.World, event.X, event.Y, event.State); err != nil {
+			log.WithField("tick", tickID).Error(err.Error())
+		}
+	}
+	if err != nil {
+		log.WithField("tick", tickID).WithError(err).Error("can't read any more input")
+		sim.input = nil
+	}
+}
+
+/*
+readLedger hands everything written to the state ledger to the recorders, until the engine stops.
+*/
+func (sim *Simulation) readLedger(ticker engine) {
+	record := func(record StateRecord) {
+		sim.mu.Lock()
+		recorders := sim.recorders
+		sim.mu.Unlock()
+		for _, fn := range recorders {
+			fn(record)
+		}
+	}
+	for {
+		select {
+		case r := <-sim.stateLedger:
+			record(r)
+		case flushed := <-sim.ledgerFlush:
+			// The tick is over, so whatever's left in the buffer is all it's going to write
+			for len(sim.stateLedger) > 0 {
+				record(<-sim.stateLedger)
+			}
+			close(flushed)
+		case <-ticker.Done():
+			return
+		}
+	}
+}
+
+/*
+flushLedger waits until the recorders have been given every record from the tick that just ended.
+Every record is on the ledger by the time the tick ends, so once readLedger has emptied it, it's
+done with them.
+*/
+func (sim *Simulation) flushLedger(ticker engine) {
+	flushed := make(chan struct{})
+	select {
+	case sim.ledgerFlush <- flushed:
+		<-flushed
+	case <-ticker.Done():
+	}
+}
+
+/*
+freeze freezes the cells of the Frozen groups. FreezableCellAuts are frozen before the first tick,
+which they still commit their starting states in. Other cells are put to sleep after it, once
+they've told their neighbors about their starting states.
+*/
+func (sim *Simulation) freeze(ticker engine, beforeFirst bool) {
+	world := sim.config.World
+	width, height := world.Size()
+	sim.config.Groups.each(width, height, sim.config.Frozen, func(x, y int) {
+		aut := world.At(x, y)
+		if freezable, ok := aut.(FreezableCellAut); ok {
+			if beforeFirst {
+				freezable.SetFrozen(true)
+			}
+			return
+		}
+		if beforeFirst {
+			return
+		}
+		if err := ticker.SetAsleep(aut, true); err != nil {
+			log.WithError(err).Warn("couldn't freeze cell")
+		}
+	})
+}
+
+/*
+Stop stops ticking and waits for every cell goroutine to exit. It's safe to call more than once.
+
+Since Stop waits for the current tick's subscribers to return, a subscriber that wants to stop the
+Simulation has to call Stop in a new goroutine.
+
+The error is the same one Wait returns.
+*/
+func (sim *Simulation) Stop() error {
+	sim.halt()
+	sim.mu.Lock()
+	started := sim.started
+	sim.mu.Unlock()
+	if started {
+		return sim.Wait()
+	}
+	return nil
+}
+
+/*
+halt asks the run loop to stop before the next tick, without waiting for it to.
+*/
+func (sim *Simulation) halt() {
+	sim.stopOnce.Do(func() { close(sim.stop) })
+}
+
+/*
+Wait blocks until the Simulation has stopped, either because Stop was called, because it reached
+MaxTicks or its StopWhen condition, or because a cell reported an error under the ErrorHalt policy.
+
+It returns the error that halted the Simulation, if any, or else the error of the context it was
+started with, if that's what stopped it, or else an error if some cells didn't exit within the
+StopTimeout.
+*/
+func (sim *Simulation) Wait() error {
+	<-sim.finished
+	if err := sim.supervisor.Err(); err != nil {
+		return err
+	}
+	sim.mu.Lock()
+	ctxErr := sim.ctxErr
+	sim.mu.Unlock()
+	if ctxErr != nil {
+		return ctxErr
+	}
+	return sim.stopErr
+}
@@ -0,0 +1,207 @@
+package cellaut
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+Returns a sliceWorld holding a row of n GooCellAuts, each wired to its left and right neighbors.
+*/
+func newGooRow(n int) *sliceWorld {
+	world := &sliceWorld{width: n, height: 1, cells: make([]CellAut, n)}
+	for i := range world.cells {
+		world.cells[i] = NewGooCellAut(i)
+	}
+	for i := 0; i < n-1; i++ {
+		world.cells[i].AddNeighbor(NeighborRt, world.cells[i+1])
+		world.cells[i+1].AddNeighbor(NeighborLf, world.cells[i])
+	}
+	return world
+}
+
+/*
+A Layer that counts how many times it has been stepped.
+*/
+type countingLayer struct {
+	steps int
+}
+
+func (layer *countingLayer) Step() {
+	layer.steps++
+}
+
+func TestSimulation(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	world := newGooRow(5)
+	world.At(2, 0).SetState("X")
+	layer := &countingLayer{}
+	sim := NewSimulation()
+	assert.NotNil(sim.Start())
+	assert.Nil(sim.Configure(SimulationConfig{World: world, Layers: []Layer{layer}, MaxTicks: 4}))
+
+	var tickIDs []int64
+	var rows []string
+	sim.Subscribe(func(ev TickEvent) {
+		tickIDs = append(tickIDs, ev.TickID)
+		rows = append(rows, concatStates(ev.World.(*sliceWorld).cells))
+	})
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+
+	assert.Equal([]int64{0, 1, 2, 3}, tickIDs)
+	assert.Equal([]string{"--X--", "-XXX-", "XXXXX", "XXXXX"}, rows)
+	assert.Equal(4, layer.steps)
+
+	assert.NotNil(sim.Start())
+	assert.NotNil(sim.Configure(SimulationConfig{World: world}))
+	// Stopping a finished simulation is fine
+	assert.Nil(sim.Stop())
+}
+
+func TestSimulation_Stop(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: newGooRow(3), TickInterval: time.Millisecond}))
+	ticks := make(chan int64, 100)
+	sim.Subscribe(func(ev TickEvent) {
+		select {
+		case ticks <- ev.TickID:
+		default:
+		}
+	})
+	assert.Nil(sim.Start())
+	<-ticks
+	<-ticks
+	assert.Nil(sim.Stop())
+	assert.Nil(sim.Stop())
+	count := len(ticks)
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(count, len(ticks))
+
+	// Stopping a simulation that never started doesn't block
+	assert.Nil(NewSimulation().Stop())
+}
+
+func TestSimulation_SubscribeStates(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Goo cells only tell their neighbors about changes, but the ledger hears their first state too
+	world := newGooRow(5)
+	world.At(2, 0).SetState("X")
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: world, MaxTicks: 3}))
+	var records []StateRecord
+	sim.SubscribeStates(func(record StateRecord) { records = append(records, record) })
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	assert.Len(records, 9)
+	assert.Contains(records, StateRecord{TickID: 0, CellID: "goo#0", State: ""})
+	assert.Contains(records, StateRecord{TickID: 2, CellID: "goo#4", State: "X"})
+
+	// Replaying the records gives the same grid as the World after every tick, whatever the engine
+	for _, engine := range []EngineKind{ChannelEngine, MultiplexedEngine, SynchronousEngine} {
+		grid := NewLifeGrid(8, 8)
+		for _, cell := range gliderCells {
+			grid.At(cell[0], cell[1]).SetState(LifeAlive)
+		}
+		where := make(map[string][2]int)
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 8; x++ {
+				where[cellName(grid.At(x, y))] = [2]int{x, y}
+			}
+		}
+		replayed := NewStateGrid(8, 8, nil)
+		sim := NewSimulation()
+		assert.Nil(sim.Configure(SimulationConfig{World: grid, Engine: engine, MaxTicks: 6}))
+		sim.SubscribeStates(func(record StateRecord) {
+			cell := where[record.CellID]
+			replayed.Set(cell[0], cell[1], record.State)
+		})
+		sim.Subscribe(func(ev TickEvent) {
+			got := TakeSnapshot(ev.World, nil)
+			assert.True(got.Equal(replayed), "engine %s, tick %d\n%s\n%s", engine, ev.TickID, &Pattern{StateGrid: got}, &Pattern{StateGrid: replayed})
+		})
+		assert.Nil(sim.Start())
+		assert.Nil(sim.Wait())
+	}
+}
+
+func TestSimulation_StartCtx(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: newGooRow(5)}))
+	assert.Nil(sim.StartCtx(ctx))
+	assert.Equal(context.DeadlineExceeded, sim.Wait())
+
+	// A context that's never done changes nothing
+	sim = NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: newGooRow(5), MaxTicks: 3}))
+	assert.Nil(sim.StartCtx(context.Background()))
+	assert.Nil(sim.Wait())
+}
+
+func TestSimulation_Configure(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	sim := NewSimulation()
+	assert.NotNil(sim.Configure(SimulationConfig{}))
+	assert.NotNil(sim.Configure(SimulationConfig{World: newGooRow(1), Engine: "quantum"}))
+	assert.NotNil(sim.Configure(SimulationConfig{World: newGooRow(1), MaxTicks: -1}))
+	assert.Nil(sim.Configure(SimulationConfig{World: newGooRow(1)}))
+	assert.Equal(ChannelEngine, sim.config.Engine)
+	assert.Equal(DefaultStopTimeout, sim.config.StopTimeout)
+}
+
+func TestSimulation_OrderedDelivery(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// The middle cell hears "-" from its right and "X" from its left in the same tick. With
+	// ordered delivery, the left one always wins.
+	for _, engine := range []EngineKind{ChannelEngine, MultiplexedEngine} {
+		for i := 0; i < 10; i++ {
+			world := newGooRow(3)
+			world.cells[0].SetState("X")
+			world.cells[1].SetState("-")
+			world.cells[2].SetState("-")
+			sim := NewSimulation()
+			assert.Nil(sim.Configure(SimulationConfig{World: world, Engine: engine, MaxTicks: 2, OrderedDelivery: true}))
+			assert.Nil(sim.Start())
+			assert.Nil(sim.Wait())
+			assert.True(world.cells[1].(*GooCellAut).ordered)
+			assert.Equal("X", string(world.cells[1].GetState()), "engine %s", engine)
+		}
+	}
+}
+
+func TestSimulation_StartAddError(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// The cell is swapped for one the Multiplexer can't run after Configure has checked them
+	world := newGooRow(3)
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: world, Engine: MultiplexedEngine, MaxTicks: 2}))
+	world.cells[2] = &plainCellAut{world.cells[2]}
+	err := sim.Start()
+	if assert.NotNil(err) {
+		assert.Contains(err.Error(), "cell at (2, 0)")
+	}
+	assert.Equal(err, sim.Wait())
+	assert.Equal(err, sim.Stop())
+	assert.NotNil(sim.Start())
+}
@@ -0,0 +1,343 @@
+package cellaut
+
+/*
+Rect is a rectangle of cells. (X, Y) is its bottom left cell.
+*/
+type Rect struct {
+	X, Y          int
+	Width, Height int
+}
+
+/*
+Contains returns whether (x, y) is inside the Rect.
+*/
+func (rect Rect) Contains(x, y int) bool {
+	return x >= rect.X && x < rect.X+rect.Width && y >= rect.Y && y < rect.Y+rect.Height
+}
+
+/*
+Intersect returns the part of the Rect that's also in other. If they don't overlap, the result has
+zero Width or Height.
+*/
+func (rect Rect) Intersect(other Rect) Rect {
+	minX, minY := maxInt(rect.X, other.X), maxInt(rect.Y, other.Y)
+	maxX, maxY := minInt(rect.X+rect.Width, other.X+other.Width), minInt(rect.Y+rect.Height, other.Y+other.Height)
+	if maxX < minX {
+		maxX = minX
+	}
+	if maxY < minY {
+		maxY = minY
+	}
+	return Rect{X: minX, Y: minY, Width: maxX - minX, Height: maxY - minY}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+/*
+Snapshot is a frozen picture of cells, however it happens to be stored. *StateGrid is the dense
+backend, *QuadUniverse is the sparse one, and *Pattern is a StateGrid that has been positioned.
+
+BoundingBox and Population are implemented by each backend in whatever way is fastest for it, so
+that analyses and renderers don't each have to scan every cell.
+*/
+type Snapshot interface {
+	At(x, y int) State
+	// BoundingBox returns the smallest Rect holding every cell in the given state. ok is false if
+	// there are none.
+	BoundingBox(state State) (rect Rect, ok bool)
+	// Population returns how many cells are in the given state.
+	Population(state State) int64
+	// Extent returns the smallest Rect holding every cell that isn't in the empty state. ok is
+	// false if they're all empty.
+	Extent() (rect Rect, ok bool)
+	// Equal returns whether other has the same States in the same places. Empty cells are equal
+	// to each other even if the two Snapshots' StateTables call the empty state different
+	// things.
+	Equal(other Snapshot) bool
+	// Hash returns a hash of the non-empty cells and where they are. Snapshots that are Equal
+	// have the same Hash, whatever their backends.
+	Hash() uint64
+	// Crop copies the cells inside rect into a new Pattern positioned at (rect.X, rect.Y). Cells
+	// in rect that are outside the Snapshot come out empty. The Pattern shares the Snapshot's
+	// StateTable but none of its cells, so it can be kept after the Snapshot changes.
+	Crop(rect Rect) *Pattern
+}
+
+/*
+TakeSnapshot copies the States of every cell in world into a *StateGrid using table, which may be
+nil. Like anything else that reads the World, it must be called between ticks, e.g. from a
+Simulation subscriber.
+*/
+func TakeSnapshot(world World, table *StateTable) *StateGrid {
+	width, height := world.Size()
+	grid := NewStateGrid(width, height, table)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			grid.Set(x, y, world.At(x, y).GetState())
+		}
+	}
+	return grid
+}
+
+/*
+Population returns how many cells are in the given state.
+*/
+func (grid *StateGrid) Population(state State) int64 {
+	id, ok := grid.Table.ID(state)
+	if !ok {
+		return 0
+	}
+	var n int64
+	for _, cellID := range grid.cells {
+		if cellID == id {
+			n++
+		}
+	}
+	return n
+}
+
+/*
+BoundingBox returns the smallest Rect holding every cell in the given state.
+*/
+func (grid *StateGrid) BoundingBox(state State) (Rect, bool) {
+	id, ok := grid.Table.ID(state)
+	if !ok {
+		return Rect{}, false
+	}
+	return grid.boundingBox(func(cellID StateID) bool { return cellID == id })
+}
+
+/*
+boundingBox returns the smallest Rect holding every cell whose StateID matches.
+*/
+func (grid *StateGrid) boundingBox(match func(StateID) bool) (Rect, bool) {
+	rowHas := func(y int) bool {
+		for _, cellID := range grid.cells[y*grid.Width : (y+1)*grid.Width] {
+			if match(cellID) {
+				return true
+			}
+		}
+		return false
+	}
+	colHas := func(x, minY, maxY int) bool {
+		for y := minY; y <= maxY; y++ {
+			if match(grid.cells[y*grid.Width+x]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Work inward from each edge, so only the cells outside the box and one row or column inside
+	// each edge get looked at
+	minY := 0
+	for minY < grid.Height && !rowHas(minY) {
+		minY++
+	}
+	if minY == grid.Height {
+		return Rect{}, false
+	}
+	maxY := grid.Height - 1
+	for !rowHas(maxY) {
+		maxY--
+	}
+	minX := 0
+	for !colHas(minX, minY, maxY) {
+		minX++
+	}
+	maxX := grid.Width - 1
+	for !colHas(maxX, minY, maxY) {
+		maxX--
+	}
+	return Rect{X: minX, Y: minY, Width: maxX - minX + 1, Height: maxY - minY + 1}, true
+}
+
+/*
+Crop copies the cells inside rect into a new Pattern positioned at (rect.X, rect.Y).
+*/
+func (grid *StateGrid) Crop(rect Rect) *Pattern {
+	cropped := NewPattern(rect.Width, rect.Height, grid.Table)
+	cropped.X, cropped.Y = rect.X, rect.Y
+	inside := rect.Intersect(Rect{Width: grid.Width, Height: grid.Height})
+	for y := inside.Y; y < inside.Y+inside.Height; y++ {
+		src := grid.cells[y*grid.Width+inside.X : y*grid.Width+inside.X+inside.Width]
+		start := (y-rect.Y)*rect.Width + inside.X - rect.X
+		copy(cropped.cells[start:start+inside.Width], src)
+	}
+	return cropped
+}
+
+/*
+Crop copies the cells inside rect, which is in the coordinates the Pattern is positioned at, into a
+new Pattern.
+*/
+func (pattern *Pattern) Crop(rect Rect) *Pattern {
+	cropped := pattern.StateGrid.Crop(Rect{X: rect.X - pattern.X, Y: rect.Y - pattern.Y, Width: rect.Width, Height: rect.Height})
+	cropped.X, cropped.Y = rect.X, rect.Y
+	return cropped
+}
+
+/*
+BoundingBox returns the smallest Rect holding every cell in the given state, in the coordinates
+the Pattern is positioned at.
+*/
+func (pattern *Pattern) BoundingBox(state State) (Rect, bool) {
+	rect, ok := pattern.StateGrid.BoundingBox(state)
+	if ok {
+		rect.X += pattern.X
+		rect.Y += pattern.Y
+	}
+	return rect, ok
+}
+
+/*
+Population returns how many cells are in the given state.
+
+For the empty state, only the cells within Bounds are counted, since the rest of the universe is
+infinitely many more.
+*/
+func (universe *QuadUniverse) Population(state State) int64 {
+	id, ok := universe.Table.ID(state)
+	if !ok {
+		return 0
+	}
+	return universe.count(universe.Root, id)
+}
+
+/*
+Crop copies the cells inside rect into a new Pattern positioned at (rect.X, rect.Y). Like View, it
+only visits the blocks that overlap rect and aren't empty.
+*/
+func (universe *QuadUniverse) Crop(rect Rect) *Pattern {
+	return &Pattern{X: rect.X, Y: rect.Y, StateGrid: universe.View(rect.X, rect.Y, rect.Width, rect.Height)}
+}
+
+/*
+count returns how many cells in node have the given StateID. Counts are memoized per node, and since
+nodes are canonical, a pattern made of many copies of the same block only counts it once.
+*/
+func (universe *QuadUniverse) count(node *QuadNode, id StateID) int64 {
+	if id == 0 {
+		return int64(1)<<(2*node.Level) - node.Population
+	}
+	if node.Population == 0 {
+		return 0
+	}
+	if node.Level == 0 {
+		if node.ID == id {
+			return 1
+		}
+		return 0
+	}
+	key := quadCountKey{node, id}
+	if n, ok := universe.counts[key]; ok {
+		return n
+	}
+	n := universe.count(node.NW, id) + universe.count(node.NE, id) + universe.count(node.SW, id) + universe.count(node.SE, id)
+	if universe.counts == nil {
+		universe.counts = make(map[quadCountKey]int64)
+	}
+	universe.counts[key] = n
+	return n
+}
+
+// quadCountKey is what QuadUniverse.count memoizes on
+type quadCountKey struct {
+	node *QuadNode
+	id   StateID
+}
+
+/*
+BoundingBox returns the smallest Rect holding every cell in the given state. Blocks without any
+such cells are skipped without looking inside.
+
+For the empty state, only the cells within Bounds are considered.
+*/
+func (universe *QuadUniverse) BoundingBox(state State) (Rect, bool) {
+	id, ok := universe.Table.ID(state)
+	if !ok {
+		return Rect{}, false
+	}
+	return universe.boundingBox(func(node *QuadNode) int64 { return universe.count(node, id) })
+}
+
+/*
+boundingBox returns the smallest Rect holding every cell counted by count, which returns how many
+cells of interest a node has.
+*/
+func (universe *QuadUniverse) boundingBox(count func(*QuadNode) int64) (Rect, bool) {
+	min, _ := universe.Bounds()
+	box := boxBuilder{}
+	universe.growBox(universe.Root, min, min, count, &box)
+	return box.rect()
+}
+
+func (universe *QuadUniverse) growBox(node *QuadNode, x0, y0 int, count func(*QuadNode) int64, box *boxBuilder) {
+	n := count(node)
+	if n == 0 {
+		return
+	}
+	size := 1 << node.Level
+	if n == int64(size)*int64(size) {
+		// Completely full, so no need to look inside
+		box.add(x0, y0)
+		box.add(x0+size-1, y0+size-1)
+		return
+	}
+	if box.ok && box.minX <= x0 && box.minY <= y0 && box.maxX >= x0+size-1 && box.maxY >= y0+size-1 {
+		// Couldn't make the box any bigger
+		return
+	}
+	half := size / 2
+	universe.growBox(node.SW, x0, y0, count, box)
+	universe.growBox(node.SE, x0+half, y0, count, box)
+	universe.growBox(node.NW, x0, y0+half, count, box)
+	universe.growBox(node.NE, x0+half, y0+half, count, box)
+}
+
+/*
+boxBuilder grows a bounding box one point at a time.
+*/
+type boxBuilder struct {
+	ok                     bool
+	minX, minY, maxX, maxY int
+}
+
+func (box *boxBuilder) add(x, y int) {
+	if !box.ok {
+		box.ok = true
+		box.minX, box.minY, box.maxX, box.maxY = x, y, x, y
+		return
+	}
+	if x < box.minX {
+		box.minX = x
+	}
+	if x > box.maxX {
+		box.maxX = x
+	}
+	if y < box.minY {
+		box.minY = y
+	}
+	if y > box.maxY {
+		box.maxY = y
+	}
+}
+
+func (box *boxBuilder) rect() (Rect, bool) {
+	if !box.ok {
+		return Rect{}, false
+	}
+	return Rect{X: box.minX, Y: box.minY, Width: box.maxX - box.minX + 1, Height: box.maxY - box.minY + 1}, true
+}
@@ -0,0 +1,146 @@
+package cellaut
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot_BoundingBoxPopulation(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	pattern := mustParsePattern(t, `
+		......
+		..X...
+		...O..
+		.XX...
+		......
+	`)
+	pattern.X, pattern.Y = -3, 10
+	universe := NewQuadUniverse(pattern.Table)
+	for y := 0; y < pattern.Height; y++ {
+		for x := 0; x < pattern.Width; x++ {
+			universe.SetID(x, y, pattern.IDAt(x, y))
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		snapshot Snapshot
+		offset   [2]int
+	}{
+		{"grid", pattern.StateGrid, [2]int{0, 0}},
+		{"pattern", pattern, [2]int{-3, 10}},
+		{"quadtree", universe, [2]int{0, 0}},
+	} {
+		rect, ok := tc.snapshot.BoundingBox("X")
+		assert.True(ok, tc.name)
+		assert.Equal(Rect{X: 1 + tc.offset[0], Y: 1 + tc.offset[1], Width: 2, Height: 3}, rect, tc.name)
+		rect, ok = tc.snapshot.BoundingBox("O")
+		assert.True(ok, tc.name)
+		assert.Equal(Rect{X: 3 + tc.offset[0], Y: 2 + tc.offset[1], Width: 1, Height: 1}, rect, tc.name)
+		_, ok = tc.snapshot.BoundingBox("Q")
+		assert.False(ok, tc.name)
+
+		assert.Equal(int64(3), tc.snapshot.Population("X"), tc.name)
+		assert.Equal(int64(1), tc.snapshot.Population("O"), tc.name)
+		assert.Equal(int64(0), tc.snapshot.Population("Q"), tc.name)
+	}
+	assert.Equal(int64(26), pattern.Population("."))
+	min, max := universe.Bounds()
+	assert.Equal(int64((max-min)*(max-min)-4), universe.Population("."))
+}
+
+func TestQuadUniverse_BoundingBoxFull(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A big filled square is found without visiting every cell
+	universe := NewQuadUniverse(NewStateTable("-"))
+	for y := -64; y < 64; y++ {
+		for x := -64; x < 64; x++ {
+			universe.Set(x, y, "X")
+		}
+	}
+	universe.Set(70, -100, "X")
+	rect, ok := universe.BoundingBox("X")
+	assert.True(ok)
+	assert.Equal(Rect{X: -64, Y: -100, Width: 135, Height: 164}, rect)
+	assert.Equal(int64(128*128+1), universe.Population("X"))
+}
+
+func TestTakeSnapshot(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	world, ticker := newSliceWorld(3, 2, "-")
+	defer ticker.Stop(time.Second)
+	world.At(0, 1).SetState("X")
+	world.At(2, 1).SetState("X")
+	ticker.Tick()
+	snapshot := TakeSnapshot(world, NewStateTable(""))
+	assert.Equal(int64(2), snapshot.Population("X"))
+	rect, ok := snapshot.BoundingBox("X")
+	assert.True(ok)
+	assert.Equal(Rect{X: 0, Y: 1, Width: 3, Height: 1}, rect)
+	assert.True(Rect{X: 0, Y: 0, Width: 3, Height: 1}.Contains(2, 0))
+	assert.False(Rect{X: 0, Y: 0, Width: 3, Height: 1}.Contains(3, 0))
+}
+
+func TestSnapshot_Crop(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	pattern := mustParsePattern(t, `
+		.X..
+		..X.
+		XXX.
+	`)
+	pattern.X, pattern.Y = 10, -5
+	universe := NewQuadUniverse(pattern.Table)
+	for y := 0; y < pattern.Height; y++ {
+		for x := 0; x < pattern.Width; x++ {
+			universe.SetID(pattern.X+x, pattern.Y+y, pattern.IDAt(x, y))
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		snapshot Snapshot
+	}{
+		{"pattern", pattern},
+		{"quadtree", universe},
+	} {
+		// Just the top two rows, hanging off the right edge
+		cropped := tc.snapshot.Crop(Rect{X: 11, Y: -4, Width: 4, Height: 2})
+		assert.Equal(11, cropped.X, tc.name)
+		assert.Equal(-4, cropped.Y, tc.name)
+		assert.Equal("X...\n.X..\n", cropped.String(), tc.name)
+
+		// Cropping to the bounding box gets the pattern back
+		rect, _ := tc.snapshot.BoundingBox("X")
+		cropped = tc.snapshot.Crop(rect)
+		assert.Equal(".X.\n..X\nXXX\n", cropped.String(), tc.name)
+		assert.Equal(10, cropped.X, tc.name)
+
+		// Nowhere near
+		cropped = tc.snapshot.Crop(Rect{X: -50, Y: 50, Width: 2, Height: 1})
+		assert.Equal("..\n", cropped.String(), tc.name)
+	}
+
+	// The crop doesn't share cells with the original
+	cropped := pattern.Crop(Rect{X: 10, Y: -5, Width: 4, Height: 3})
+	cropped.Set(3, 0, "X")
+	assert.Equal(State("."), pattern.At(3, 0))
+}
+
+func TestRect_Intersect(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal(Rect{X: 2, Y: 1, Width: 3, Height: 2}, Rect{X: 0, Y: 0, Width: 5, Height: 3}.Intersect(Rect{X: 2, Y: 1, Width: 10, Height: 10}))
+	disjoint := Rect{X: 0, Y: 0, Width: 2, Height: 2}.Intersect(Rect{X: 5, Y: 0, Width: 2, Height: 2})
+	assert.Equal(0, disjoint.Width)
+}
@@ -0,0 +1,115 @@
+package cellaut
+
+import (
+	"flag"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// soakFor is how long TestSoak keeps going. By default it only does a few rounds, which is enough
+// to catch a leak that happens every time.
+var soakFor = flag.Duration("soak", 0, "how long TestSoak should run for, like -soak 10m")
+
+/*
+soakRound creates, runs and tears down a Simulation under every engine, and churns cells through a
+Ticker, checking that removed cells really are dropped.
+*/
+func soakRound(t *testing.T) {
+	for _, engine := range []EngineKind{ChannelEngine, MultiplexedEngine, SynchronousEngine} {
+		grid := NewLifeGrid(8, 8, WithTopology(Torus))
+		for _, cell := range gliderCells {
+			grid.At(cell[0], cell[1]).SetState(LifeAlive)
+		}
+		sim := NewSimulation()
+		assert.Nil(t, sim.Configure(SimulationConfig{World: grid, Engine: engine, MaxTicks: 5}))
+		assert.Nil(t, sim.Start())
+		assert.Nil(t, sim.Wait())
+	}
+
+	// Cells come and go on a Ticker that keeps running
+	ticker := &Ticker{}
+	var live []CellAut
+	for tick := 0; tick < 10; tick++ {
+		cell := NewRuleCellAut(tick, func(self State, neighbors map[NeighborIndex]State) State { return self }, "-")
+		ticker.Start(cell, nil)
+		live = append(live, cell)
+		if len(live) > 3 {
+			assert.Nil(t, ticker.Remove(live[0]))
+			live = live[1:]
+		}
+		ticker.Tick()
+	}
+	ticker.mu.Lock()
+	assert.Len(t, ticker.destinations, len(live), "removed cells are still getting ticks")
+	assert.Len(t, ticker.byCell, len(live), "removed cells are still registered")
+	ticker.mu.Unlock()
+	assert.Nil(t, ticker.Stop(time.Second))
+}
+
+/*
+settledGoroutines waits for the goroutine count to drop to at most want, and returns what it got
+to. Goroutines take a moment to exit after whatever stopped them returns.
+*/
+func settledGoroutines(want int) int {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		n := runtime.NumGoroutine()
+		if n <= want || time.Now().After(deadline) {
+			return n
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+/*
+heapInUse returns the bytes of heap in use after a full garbage collection.
+*/
+func heapInUse() uint64 {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
+
+/*
+TestSoak runs Simulations over and over, and fails if goroutines or heap pile up between them,
+which means something in the Ticker or cell lifecycle isn't being let go. For a proper soak, run
+
+	go test -run Soak -soak 10m
+
+It counts every goroutine in the process, so it mustn't run in parallel with anything.
+*/
+func TestSoak(t *testing.T) {
+	assert := assert.New(t)
+
+	// One round first, so that whatever gets set up once and kept, like logrus's, is in the
+	// baseline
+	soakRound(t)
+	time.Sleep(50 * time.Millisecond)
+	goroutines := runtime.NumGoroutine()
+	heap := heapInUse()
+
+	rounds := 0
+	for start := time.Now(); rounds < 20 || time.Since(start) < *soakFor; rounds++ {
+		soakRound(t)
+		if t.Failed() {
+			return
+		}
+		if rounds%10 != 9 {
+			continue
+		}
+		if n := settledGoroutines(goroutines); n > goroutines {
+			assert.Fail("goroutines leaked", "%d goroutines after %d rounds; started with %d", n, rounds+1, goroutines)
+			return
+		}
+		// The heap moves around, but a leak of anything at all per round soon passes this
+		if inUse := heapInUse(); inUse > 2*heap+(1<<20) {
+			assert.Fail("heap grew", "%d bytes in use after %d rounds; started with %d", inUse, rounds+1, heap)
+			return
+		}
+	}
+	t.Logf("%d rounds, %d goroutines, %d bytes of heap in use", rounds, runtime.NumGoroutine(), heapInUse())
+}
@@ -0,0 +1,114 @@
+package cellaut
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+/*
+SpaceTimeDiagram stacks the generations of a one-dimensional run into one grid, a row per
+generation with the first at the top, which is how elementary automata are usually drawn. The
+bottom row of each generation is used, so they can come straight from snapshots of a Grid from
+NewLineGrid. The diagram is as wide as the widest generation, and its empty State is "-".
+*/
+func SpaceTimeDiagram(generations []*StateGrid) *StateGrid {
+	width := 0
+	for _, generation := range generations {
+		width = maxInt(width, generation.Width)
+	}
+	diagram := NewStateGrid(width, len(generations), NewStateTable("-"))
+	for i, generation := range generations {
+		for x := 0; x < generation.Width && generation.Height > 0; x++ {
+			diagram.Set(x, len(generations)-1-i, generation.At(x, 0))
+		}
+	}
+	return diagram
+}
+
+/*
+SpaceTimeRecorder collects the generations of a one-dimensional run as it goes, and writes out the
+space-time diagram at the end, as a PNG or as text, so that what rule 30 or 110 did can be seen at
+a glance. Each cell is a CellSize × CellSize square in its State's color from Palette, as for
+RenderGrid.
+
+It's safe for concurrent use.
+*/
+type SpaceTimeRecorder struct {
+	Palette  Palette
+	CellSize int
+	// MaxRows, if it's set, is how many generations to keep. Any after that are ignored.
+	MaxRows int
+	// Metadata, if it's set, is stamped into the PNG
+	Metadata *RunMetadata
+
+	mu          sync.Mutex
+	generations []*StateGrid
+}
+
+/*
+NewSpaceTimeRecorder returns a *SpaceTimeRecorder that draws with palette, cellSize pixels to a
+cell.
+*/
+func NewSpaceTimeRecorder(palette Palette, cellSize int) *SpaceTimeRecorder {
+	return &SpaceTimeRecorder{Palette: palette, CellSize: cellSize}
+}
+
+/*
+Add adds the bottom row of grid as the next generation.
+*/
+func (recorder *SpaceTimeRecorder) Add(grid *StateGrid) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if recorder.MaxRows > 0 && len(recorder.generations) >= recorder.MaxRows {
+		return
+	}
+	recorder.generations = append(recorder.generations, grid)
+}
+
+/*
+Sink returns a Sink that adds the bottom row of a Simulation's World after every tick.
+*/
+func (recorder *SpaceTimeRecorder) Sink() Sink {
+	return func(event TickEvent) {
+		width, _ := event.World.Size()
+		row := NewStateGrid(width, 1, nil)
+		for x := 0; x < width; x++ {
+			row.Set(x, 0, event.World.At(x, 0).GetState())
+		}
+		recorder.Add(row)
+	}
+}
+
+/*
+Diagram returns the space-time diagram of the generations so far, as SpaceTimeDiagram does.
+*/
+func (recorder *SpaceTimeRecorder) Diagram() *StateGrid {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return SpaceTimeDiagram(recorder.generations)
+}
+
+/*
+WritePNG draws the diagram to w as a PNG, with the first generation at the top. It returns an error
+if the Palette has too many States to draw.
+*/
+func (recorder *SpaceTimeRecorder) WritePNG(w io.Writer) error {
+	diagram := recorder.Diagram()
+	if diagram.Width == 0 || diagram.Height == 0 {
+		return fmt.Errorf("no generations to draw")
+	}
+	img, err := RenderGrid(diagram, recorder.Palette, recorder.CellSize)
+	if err != nil {
+		return err
+	}
+	return EncodePNG(w, img, recorder.Metadata)
+}
+
+/*
+WriteText writes the diagram to w a generation per line, first to last, as for Pattern.String.
+*/
+func (recorder *SpaceTimeRecorder) WriteText(w io.Writer) error {
+	_, err := io.WriteString(w, (&Pattern{StateGrid: recorder.Diagram()}).String())
+	return err
+}
@@ -0,0 +1,95 @@
+package cellaut
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpaceTimeDiagram(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Rule 90 draws a Sierpinski triangle from a single cell
+	grid := NewLineGrid(9, Rule1D(90), "-")
+	grid.At(4, 0).SetState("X")
+	var generations []*StateGrid
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 4}))
+	sim.Subscribe(func(event TickEvent) { generations = append(generations, TakeSnapshot(event.World, nil)) })
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+
+	diagram := SpaceTimeDiagram(generations)
+	assert.Equal(""+
+		"----X----\n"+
+		"---X-X---\n"+
+		"--X---X--\n"+
+		"-X-X-X-X-\n", (&Pattern{StateGrid: diagram}).String())
+	assert.Equal(0, SpaceTimeDiagram(nil).Height)
+}
+
+func TestNewLineGrid(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// On a ring, the ends are each other's neighbors
+	grid := NewLineGrid(5, Rule1D(90), "-", WithTopology(Cylinder))
+	grid.At(0, 0).SetState("X")
+	var last *StateGrid
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 2}))
+	sim.Subscribe(func(event TickEvent) { last = TakeSnapshot(event.World, nil) })
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	assert.Equal("-X--X\n", (&Pattern{StateGrid: last}).String())
+}
+
+func TestSpaceTimeRecorder(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	recorder := NewSpaceTimeRecorder(LifePalette, 2)
+	var b bytes.Buffer
+	assert.NotNil(recorder.WritePNG(&b))
+
+	grid := NewLineGrid(7, Rule1D(30), "-")
+	grid.At(3, 0).SetState("X")
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 3}))
+	sim.Subscribe(recorder.Sink())
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+
+	assert.Nil(recorder.WriteText(&b))
+	assert.Equal("---X---\n--XXX--\n-XX--X-\n", b.String())
+	b.Reset()
+	recorder.Metadata = &RunMetadata{RunID: "rule-30"}
+	assert.Nil(recorder.WritePNG(&b))
+	assert.Contains(b.String(), "cellaut:run-id\x00rule-30")
+	img, err := png.Decode(&b)
+	assert.Nil(err)
+	assert.Equal(image.Rect(0, 0, 14, 6), img.Bounds())
+	// The first generation is at the top
+	r, _, _, _ := img.At(6, 0).RGBA()
+	assert.Equal(uint32(0), r)
+	r, _, _, _ = img.At(0, 0).RGBA()
+	assert.Equal(uint32(0xffff), r)
+
+	recorder = NewSpaceTimeRecorder(LifePalette, 1)
+	recorder.MaxRows = 2
+	for i := 0; i < 3; i++ {
+		recorder.Add(NewStateGrid(4, 1, NewStateTable("-")))
+	}
+	assert.Equal(2, recorder.Diagram().Height)
+	recorder.Palette = Palette{}
+	for i := 0; i <= maxPaletteStates; i++ {
+		recorder.Palette[State(fmt.Sprintf("s%d", i))] = color.Black
+	}
+	assert.NotNil(recorder.WritePNG(&b))
+}
@@ -0,0 +1,268 @@
+package cellaut
+
+import (
+	"fmt"
+	"time"
+)
+
+/*
+StopCondition decides when a Simulation has run long enough. It's checked after every tick, once
+the layers and subscribers are done.
+
+StopConditions can keep track of what they've seen from one tick to the next, so each one should
+only be given to one Simulation.
+*/
+type StopCondition interface {
+	ShouldStop(status *StopStatus) bool
+}
+
+/*
+StopStatus is what a StopCondition gets to look at.
+*/
+type StopStatus struct {
+	// Ticks is how many ticks have run so far
+	Ticks int64
+	// Elapsed is how long the Simulation has been running
+	Elapsed time.Duration
+	world   World
+	// snapshot is taken the first time a StopCondition asks for it, and shared by the rest
+	snapshot *StateGrid
+}
+
+/*
+Snapshot returns the States of the World's cells as of the tick that just finished.
+*/
+func (status *StopStatus) Snapshot() *StateGrid {
+	if status.snapshot == nil {
+		status.snapshot = TakeSnapshot(status.world, nil)
+	}
+	return status.snapshot
+}
+
+/*
+StopFunc lets an ordinary function be used as a StopCondition.
+*/
+type StopFunc func(status *StopStatus) bool
+
+func (fn StopFunc) ShouldStop(status *StopStatus) bool {
+	return fn(status)
+}
+
+/*
+AfterTicks stops once n ticks have run.
+*/
+func AfterTicks(n int64) StopCondition {
+	return StopFunc(func(status *StopStatus) bool { return status.Ticks >= n })
+}
+
+/*
+AfterElapsed stops once the Simulation has been running for at least d.
+*/
+func AfterElapsed(d time.Duration) StopCondition {
+	return StopFunc(func(status *StopStatus) bool { return status.Elapsed >= d })
+}
+
+/*
+PopulationZero stops once no cell is in the given state, e.g. when everything in a Game of Life
+has died.
+*/
+func PopulationZero(state State) StopCondition {
+	return StopFunc(func(status *StopStatus) bool { return status.Snapshot().Population(state) == 0 })
+}
+
+/*
+Stabilized stops once the World repeats a configuration it was in at most period ticks ago. A
+period of 1 catches still lifes; bigger periods also catch oscillators up to that period.
+*/
+func Stabilized(period int) StopCondition {
+	return &stabilized{period: period}
+}
+
+type stabilized struct {
+	period int
+	// recent holds the hashes of the last period ticks, oldest first
+	recent []uint64
+}
+
+func (cond *stabilized) ShouldStop(status *StopStatus) bool {
+	snapshot := status.Snapshot()
+	hash := snapshot.Hash()
+	for _, h := range cond.recent {
+		if h == hash {
+			// Snapshots this tick and an older tick aren't kept, so equal hashes are taken as equal
+			// configurations. A false positive is one chance in 2^61.
+			return true
+		}
+	}
+	cond.recent = append(cond.recent, hash)
+	if len(cond.recent) > cond.period {
+		cond.recent = cond.recent[1:]
+	}
+	return false
+}
+
+/*
+PatternMatched stops once pattern shows up anywhere in the World, in the orientation it's given in.
+The pattern's empty cells match anything, so only its non-empty cells have to be there.
+*/
+func PatternMatched(pattern *Pattern) StopCondition {
+	return StopFunc(func(status *StopStatus) bool {
+		_, _, ok := findPattern(status.Snapshot(), pattern)
+		return ok
+	})
+}
+
+/*
+findPattern returns where the bottom left cell of pattern is in grid, if the non-empty cells of
+pattern are all there.
+*/
+func findPattern(grid *StateGrid, pattern *Pattern) (x, y int, ok bool) {
+	type cell struct {
+		x, y  int
+		state State
+	}
+	var cells []cell
+	for py := 0; py < pattern.Height; py++ {
+		for px := 0; px < pattern.Width; px++ {
+			if pattern.IDAt(px, py) != 0 {
+				cells = append(cells, cell{px, py, pattern.At(px, py)})
+			}
+		}
+	}
+	if len(cells) == 0 {
+		return 0, 0, true
+	}
+	ids := make([]StateID, len(cells))
+	for i, c := range cells {
+		id, ok := grid.Table.ID(c.state)
+		if !ok {
+			return 0, 0, false
+		}
+		ids[i] = id
+	}
+	for y := 0; y+pattern.Height <= grid.Height; y++ {
+	offsets:
+		for x := 0; x+pattern.Width <= grid.Width; x++ {
+			for i, c := range cells {
+				if grid.IDAt(x+c.x, y+c.y) != ids[i] {
+					continue offsets
+				}
+			}
+			return x, y, true
+		}
+	}
+	return 0, 0, false
+}
+
+/*
+All stops once every one of conds would stop. Every condition is checked every tick, even once
+the answer is known, so that the ones keeping track of history don't miss any.
+*/
+func All(conds ...StopCondition) StopCondition {
+	return StopFunc(func(status *StopStatus) bool {
+		stop := true
+		for _, cond := range conds {
+			if !cond.ShouldStop(status) {
+				stop = false
+			}
+		}
+		return stop
+	})
+}
+
+/*
+Any stops as soon as one of conds would stop. Like All, it checks every condition every tick.
+*/
+func Any(conds ...StopCondition) StopCondition {
+	return StopFunc(func(status *StopStatus) bool {
+		stop := false
+		for _, cond := range conds {
+			if cond.ShouldStop(status) {
+				stop = true
+			}
+		}
+		return stop
+	})
+}
+
+/*
+StopSpec is the declarative form of a StopCondition, for config files. Exactly one field must be
+set:
+
+	any:
+	  - ticks: 1000
+	  - all:
+	      - stable: 2
+	      - elapsed: 30s
+*/
+type StopSpec struct {
+	// Ticks is AfterTicks
+	Ticks int64 `json:"ticks,omitempty" yaml:"ticks,omitempty"`
+	// Elapsed is AfterElapsed, as a duration like "1m30s"
+	Elapsed string `json:"elapsed,omitempty" yaml:"elapsed,omitempty"`
+	// Empty is PopulationZero: the state that must die out
+	Empty State `json:"empty,omitempty" yaml:"empty,omitempty"`
+	// Stable is Stabilized, with the given period
+	Stable int `json:"stable,omitempty" yaml:"stable,omitempty"`
+	// Pattern is PatternMatched, with the pattern written out as for ParsePattern
+	Pattern string     `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	All     []StopSpec `json:"all,omitempty" yaml:"all,omitempty"`
+	Any     []StopSpec `json:"any,omitempty" yaml:"any,omitempty"`
+}
+
+/*
+Build turns the StopSpec into a StopCondition.
+*/
+func (spec StopSpec) Build() (StopCondition, error) {
+	var conds []StopCondition
+	if spec.Ticks != 0 {
+		if spec.Ticks < 0 {
+			return nil, fmt.Errorf("stop condition 'ticks' must be positive")
+		}
+		conds = append(conds, AfterTicks(spec.Ticks))
+	}
+	if spec.Elapsed != "" {
+		d, err := time.ParseDuration(spec.Elapsed)
+		if err != nil {
+			return nil, fmt.Errorf("stop condition 'elapsed': %s", err)
+		}
+		conds = append(conds, AfterElapsed(d))
+	}
+	if spec.Empty != "" {
+		conds = append(conds, PopulationZero(spec.Empty))
+	}
+	if spec.Stable != 0 {
+		if spec.Stable < 0 {
+			return nil, fmt.Errorf("stop condition 'stable' must be positive")
+		}
+		conds = append(conds, Stabilized(spec.Stable))
+	}
+	if spec.Pattern != "" {
+		pattern, err := ParsePattern(spec.Pattern, nil)
+		if err != nil {
+			return nil, fmt.Errorf("stop condition 'pattern': %s", err)
+		}
+		conds = append(conds, PatternMatched(pattern))
+	}
+	for _, group := range []struct {
+		specs   []StopSpec
+		combine func(...StopCondition) StopCondition
+	}{{spec.All, All}, {spec.Any, Any}} {
+		if group.specs == nil {
+			continue
+		}
+		var children []StopCondition
+		for _, child := range group.specs {
+			cond, err := child.Build()
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, cond)
+		}
+		conds = append(conds, group.combine(children...))
+	}
+	if len(conds) != 1 {
+		return nil, fmt.Errorf("a stop condition must set exactly one field; this one sets %d", len(conds))
+	}
+	return conds[0], nil
+}
@@ -0,0 +1,95 @@
+package cellaut
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+Runs a Simulation of a 5-cell goo row seeded in the middle until cond stops it, and returns how
+many ticks it ran.
+*/
+func ticksUntil(t *testing.T, cond StopCondition) int64 {
+	world := newGooRow(5)
+	world.At(2, 0).SetState("X")
+	sim := NewSimulation()
+	assert.Nil(t, sim.Configure(SimulationConfig{World: world, StopWhen: cond, MaxTicks: 50}))
+	var ticks int64
+	sim.Subscribe(func(ev TickEvent) { ticks = ev.TickID + 1 })
+	assert.Nil(t, sim.Start())
+	assert.Nil(t, sim.Wait())
+	return ticks
+}
+
+func TestStopCondition(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// The row goes "--X--", "-XXX-", "XXXXX", and then stays put
+	assert.Equal(int64(3), ticksUntil(t, AfterTicks(3)))
+	assert.Equal(int64(4), ticksUntil(t, Stabilized(1)))
+	// Goo cells start out in the "" state until X reaches them
+	assert.Equal(int64(3), ticksUntil(t, PopulationZero("")))
+	assert.Equal(int64(2), ticksUntil(t, PatternMatched(mustParsePattern(t, "XXX"))))
+	assert.Equal(int64(3), ticksUntil(t, PatternMatched(mustParsePattern(t, "X.X.X"))))
+	assert.Equal(int64(50), ticksUntil(t, PatternMatched(mustParsePattern(t, "O"))))
+
+	assert.Equal(int64(2), ticksUntil(t, Any(AfterTicks(2), Stabilized(1))))
+	assert.Equal(int64(4), ticksUntil(t, All(AfterTicks(2), Stabilized(1))))
+	// Stabilized keeps its history even when All already knows the answer
+	assert.Equal(int64(4), ticksUntil(t, All(AfterTicks(4), Stabilized(1))))
+
+	start := time.Now()
+	world := newGooRow(3)
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: world, StopWhen: AfterElapsed(20 * time.Millisecond), TickInterval: time.Millisecond}))
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	assert.True(time.Since(start) >= 20*time.Millisecond)
+}
+
+func TestStopCondition_Stabilized(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	blinker := []string{"XXX", ".X.\n.X.\n.X."}
+	cond := Stabilized(2)
+	for i := 0; i < 4; i++ {
+		pattern := mustParsePattern(t, blinker[i%2])
+		grid := NewStateGrid(3, 3, pattern.Table)
+		copy(grid.cells, pattern.Crop(Rect{Width: 3, Height: 3}).cells)
+		stopped := cond.ShouldStop(&StopStatus{snapshot: grid})
+		// The first repeat is on the third tick
+		assert.Equal(i >= 2, stopped)
+	}
+	cond = Stabilized(1)
+	assert.False(cond.ShouldStop(&StopStatus{snapshot: mustParsePattern(t, "XXX").StateGrid}))
+	assert.False(cond.ShouldStop(&StopStatus{snapshot: mustParsePattern(t, "X\nX\nX").StateGrid}))
+	assert.False(cond.ShouldStop(&StopStatus{snapshot: mustParsePattern(t, "XXX").StateGrid}))
+}
+
+func TestStopSpec(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	var spec StopSpec
+	assert.Nil(json.Unmarshal([]byte(`{"any": [{"ticks": 2}, {"all": [{"stable": 1}, {"elapsed": "1h"}]}]}`), &spec))
+	cond, err := spec.Build()
+	assert.Nil(err)
+	assert.Equal(int64(2), ticksUntil(t, cond))
+
+	for _, bad := range []StopSpec{
+		{},
+		{Ticks: 3, Stable: 1},
+		{Ticks: -1},
+		{Elapsed: "soon"},
+		{Pattern: "XX\nX"},
+		{Any: []StopSpec{{Stable: 1}, {}}},
+	} {
+		_, err := bad.Build()
+		assert.NotNil(err, "%+v", bad)
+	}
+}
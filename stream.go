@@ -0,0 +1,192 @@
+package cellaut
+
+import (
+	"encoding/json"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+/*
+Publisher sends messages to a topic of a message broker. It's small enough that Kafka or NATS can be
+plugged in with an adapter a few lines long: with NATS, Publish each message to the topic as a
+subject, and with Kafka, write them all in one call as Messages for the topic.
+*/
+type Publisher interface {
+	Publish(topic string, messages [][]byte) error
+}
+
+/*
+TickSummary is the message a StreamSink publishes for every tick: how many cells changed state, how
+many cells are in each State afterwards, and how many went from each State to each other State, as
+in a TransitionMatrix. The first tick has no Transitions, since there's no tick before it.
+*/
+type TickSummary struct {
+	TickID      int64                     `json:"tick"`
+	Changes     int                       `json:"changes"`
+	Populations map[State]int64           `json:"populations"`
+	Transitions map[State]map[State]int64 `json:"transitions,omitempty"`
+}
+
+/*
+StreamSink publishes a Simulation's state ledger and a TickSummary of every tick to a message broker,
+for feeding simulations into a bigger data pipeline. Every StateRecord is published to StatesTopic
+as JSON, and every TickSummary to TicksTopic.
+
+Messages are published in batches of up to BatchSize, and whatever's waiting is published every
+FlushTicks ticks, so nothing waits long even when little is changing. Publishing happens while the
+Simulation waits, so a slow broker slows the Simulation down. A batch that can't be published is
+logged and dropped, and the error is kept for Err.
+
+It's hooked up to a Simulation with Attach.
+*/
+type StreamSink struct {
+	Publisher Publisher
+	// StatesTopic and TicksTopic default to "cellaut.states" and "cellaut.ticks"
+	StatesTopic string
+	TicksTopic  string
+	// BatchSize defaults to 100
+	BatchSize int
+	// FlushTicks defaults to 1, so each tick's messages are published by the end of it
+	FlushTicks int
+
+	mu      sync.Mutex
+	states  [][]byte
+	ticks   [][]byte
+	changes int
+	err     error
+	// transitions is only touched by Tick, which the Simulation never calls twice at once
+	transitions *TransitionRecorder
+}
+
+/*
+NewStreamSink returns a *StreamSink that publishes with publisher.
+*/
+func NewStreamSink(publisher Publisher) *StreamSink {
+	return &StreamSink{Publisher: publisher}
+}
+
+/*
+Attach subscribes the StreamSink to sim's ledger and ticks. It must be called before sim starts, so
+that nothing is missed.
+*/
+func (sink *StreamSink) Attach(sim *Simulation) {
+	sim.SubscribeStates(sink.Record)
+	sim.Subscribe(sink.Tick)
+}
+
+/*
+Record queues a StateRecord to be published.
+*/
+func (sink *StreamSink) Record(record StateRecord) {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.changes++
+	sink.states = sink.queue(sink.states, sink.topic(sink.StatesTopic, "cellaut.states"), record)
+}
+
+/*
+Tick queues the TickSummary for event, and publishes everything that's waiting if it's time to.
+*/
+func (sink *StreamSink) Tick(event TickEvent) {
+	summary := TickSummary{TickID: event.TickID, Populations: make(map[State]int64)}
+	grid := TakeSnapshot(event.World, nil)
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			summary.Populations[grid.At(x, y)]++
+		}
+	}
+	if sink.transitions == nil {
+		sink.transitions = NewTransitionRecorder()
+	}
+	if matrix, ok := sink.transitions.SampleGrid(event.TickID, grid); ok {
+		summary.Transitions = matrix.Counts
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	summary.Changes = sink.changes
+	sink.changes = 0
+	sink.ticks = sink.queue(sink.ticks, sink.topic(sink.TicksTopic, "cellaut.ticks"), summary)
+	flushTicks := sink.FlushTicks
+	if flushTicks <= 0 {
+		flushTicks = 1
+	}
+	if (event.TickID+1)%int64(flushTicks) == 0 {
+		sink.flush()
+	}
+}
+
+/*
+Flush publishes everything that's waiting, such as the messages from the last few ticks of a
+Simulation that's finished, and returns the first error there's been.
+*/
+func (sink *StreamSink) Flush() error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.flush()
+	return sink.err
+}
+
+/*
+Err returns the first error from publishing, if there's been one.
+*/
+func (sink *StreamSink) Err() error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	return sink.err
+}
+
+/*
+queue adds msg to batch as JSON, and publishes the batch to topic if it's full. It returns what's
+left of the batch. The caller must hold sink.mu.
+*/
+func (sink *StreamSink) queue(batch [][]byte, topic string, msg interface{}) [][]byte {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		sink.fail(topic, err)
+		return batch
+	}
+	batch = append(batch, data)
+	batchSize := sink.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	if len(batch) >= batchSize {
+		sink.publish(topic, batch)
+		return nil
+	}
+	return batch
+}
+
+/*
+flush publishes both batches. The caller must hold sink.mu.
+*/
+func (sink *StreamSink) flush() {
+	sink.publish(sink.topic(sink.StatesTopic, "cellaut.states"), sink.states)
+	sink.publish(sink.topic(sink.TicksTopic, "cellaut.ticks"), sink.ticks)
+	sink.states, sink.ticks = nil, nil
+}
+
+func (sink *StreamSink) publish(topic string, batch [][]byte) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := sink.Publisher.Publish(topic, batch); err != nil {
+		sink.fail(topic, err)
+	}
+}
+
+func (sink *StreamSink) fail(topic string, err error) {
+	log.WithField("topic", topic).WithError(err).Error("couldn't publish to stream")
+	if sink.err == nil {
+		sink.err = err
+	}
+}
+
+func (sink *StreamSink) topic(topic, def string) string {
+	if topic == "" {
+		return def
+	}
+	return topic
+}
@@ -0,0 +1,87 @@
+package cellaut
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+memoryPublisher keeps every batch published to it, by topic.
+*/
+type memoryPublisher struct {
+	mu      sync.Mutex
+	batches map[string][][][]byte
+	err     error
+}
+
+func (pub *memoryPublisher) Publish(topic string, messages [][]byte) error {
+	pub.mu.Lock()
+	defer pub.mu.Unlock()
+	if pub.err != nil {
+		return pub.err
+	}
+	if pub.batches == nil {
+		pub.batches = make(map[string][][][]byte)
+	}
+	pub.batches[topic] = append(pub.batches[topic], messages)
+	return nil
+}
+
+func TestStreamSink(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	pub := &memoryPublisher{}
+	sink := NewStreamSink(pub)
+	sink.BatchSize = 4
+	sink.FlushTicks = 2
+	sink.TicksTopic = "life.ticks"
+	sim := NewSimulation()
+	// A blinker: 3 alive and 6 dead cells at first, then 2 cells die and 2 are born every tick
+	grid := NewLifeGrid(3, 3)
+	for x := 0; x < 3; x++ {
+		grid.At(x, 1).SetState(LifeAlive)
+	}
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 5}))
+	sink.Attach(sim)
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	assert.Nil(sink.Flush())
+
+	var records []StateRecord
+	for _, batch := range pub.batches["cellaut.states"] {
+		assert.True(len(batch) <= 4)
+		for _, msg := range batch {
+			var record StateRecord
+			assert.Nil(json.Unmarshal(msg, &record))
+			records = append(records, record)
+		}
+	}
+	assert.Len(records, 9+4*4)
+	assert.Contains(records, StateRecord{TickID: 1, CellID: cellName(grid.At(1, 0)), State: LifeAlive})
+
+	// Tick summaries come in twos, except the last
+	var summaries []TickSummary
+	for _, batch := range pub.batches["life.ticks"] {
+		for _, msg := range batch {
+			var summary TickSummary
+			assert.Nil(json.Unmarshal(msg, &summary))
+			summaries = append(summaries, summary)
+		}
+	}
+	assert.Len(pub.batches["life.ticks"], 3)
+	assert.Len(summaries, 5)
+	assert.Equal(TickSummary{TickID: 0, Changes: 9, Populations: map[State]int64{LifeAlive: 3, LifeDead: 6}}, summaries[0])
+	assert.Equal(TickSummary{TickID: 4, Changes: 4, Populations: map[State]int64{LifeAlive: 3, LifeDead: 6},
+		Transitions: map[State]map[State]int64{LifeAlive: {LifeAlive: 1, LifeDead: 2}, LifeDead: {LifeAlive: 2, LifeDead: 4}}}, summaries[4])
+
+	// A broker that's down doesn't stop anything, but the error's kept
+	pub.err = errors.New("no brokers available")
+	sink.Tick(TickEvent{TickID: 5, World: grid})
+	assert.NotNil(sink.Flush())
+	assert.NotNil(sink.Err())
+}
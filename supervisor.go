@@ -0,0 +1,254 @@
+package cellaut
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+/*
+ErrorPolicy says what should happen when a CellAut reports an error.
+*/
+type ErrorPolicy int
+
+const (
+	// ErrorHalt finishes the current tick and then stops ticking for good.
+	ErrorHalt ErrorPolicy = iota
+	// ErrorSkipCell freezes the CellAut that reported the error: it keeps its current state and
+	// keeps taking part in ticks, but never updates again.
+	ErrorSkipCell
+	// ErrorRestart throws away whatever the CellAut was doing this tick and carries on from its
+	// last committed state.
+	ErrorRestart
+)
+
+func (policy ErrorPolicy) String() string {
+	switch policy {
+	case ErrorHalt:
+		return "halt"
+	case ErrorSkipCell:
+		return "skip cell"
+	case ErrorRestart:
+		return "restart"
+	}
+	return fmt.Sprintf("ErrorPolicy(%d)", int(policy))
+}
+
+/*
+CellError is an error reported by a CellAut.
+*/
+type CellError struct {
+	// Cell is nil if the error didn't come from a CellAut, like a RuleSandbox's RuleViolations. Err
+	// says where it happened instead.
+	Cell CellAut
+	// TickID is the last tick the CellAut saw before the error
+	TickID int64
+	Err    error
+}
+
+func (cellErr *CellError) Error() string {
+	if cellErr.Cell == nil {
+		return fmt.Sprintf("at tick %d: %s", cellErr.TickID, cellErr.Err)
+	}
+	return fmt.Sprintf("cell %s at tick %d: %s", cellName(cellErr.Cell), cellErr.TickID, cellErr.Err)
+}
+
+/*
+cellName returns a name for aut fit for logs and errors: its String() if it has one, and its
+address otherwise.
+*/
+func cellName(aut CellAut) string {
+	if stringer, ok := aut.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return fmt.Sprintf("%p", aut)
+}
+
+// supervisorErrorsBuffer is how many CellErrors a Supervisor holds on to for Errors() readers.
+const supervisorErrorsBuffer = 64
+
+/*
+Supervisor receives the errors reported by CellAuts, decides what to do about them, and remembers
+whether the simulation has to halt.
+*/
+type Supervisor struct {
+	Policy ErrorPolicy
+	// errors is where reported CellErrors go for anyone who wants to read them. When it's full,
+	// new errors are only logged.
+	errors chan *CellError
+	mu     sync.Mutex
+	// haltErr is the first error reported under ErrorHalt
+	haltErr error
+}
+
+/*
+NewSupervisor returns a *Supervisor that applies the given policy to every error.
+*/
+func NewSupervisor(policy ErrorPolicy) *Supervisor {
+	return &Supervisor{
+		Policy: policy,
+		errors: make(chan *CellError, supervisorErrorsBuffer),
+	}
+}
+
+/*
+Report records cellErr and returns the policy that the reporting CellAut should apply.
+*/
+func (sup *Supervisor) Report(cellErr *CellError) ErrorPolicy {
+	log.WithFields(log.Fields{
+		"tick":   cellErr.TickID,
+		"policy": sup.Policy.String(),
+	}).Error(cellErr.Error())
+
+	if sup.Policy == ErrorHalt {
+		sup.halt(cellErr)
+	}
+	select {
+	case sup.errors <- cellErr:
+	default:
+	}
+	return sup.Policy
+}
+
+/*
+halt makes the simulation halt because of cellErr, regardless of the policy.
+*/
+func (sup *Supervisor) halt(cellErr *CellError) {
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+	if sup.haltErr == nil {
+		sup.haltErr = cellErr
+	}
+}
+
+/*
+Errors returns a channel on which reported CellErrors can be read.
+*/
+func (sup *Supervisor) Errors() <-chan *CellError {
+	return sup.errors
+}
+
+/*
+Err returns the error that halted the simulation, or nil if it hasn't been halted.
+*/
+func (sup *Supervisor) Err() error {
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+	return sup.haltErr
+}
+
+/*
+runRecovered runs aut.Start, and returns whether aut should be restarted because it panicked.
+*/
+func runRecovered(aut CellAut, done chan struct{}, stateLedger chan StateRecord, callbacks *CellAutCallbacks) (restart bool) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		callbacks.mu.Lock()
+		tickID := callbacks.tickID
+		callbacks.mu.Unlock()
+		cellErr := &CellError{Cell: aut, TickID: tickID, Err: fmt.Errorf("panic: %v", r)}
+		log.WithField("stack", string(debug.Stack())).Debug("cell panicked")
+		restart = callbacks.Supervisor.Report(cellErr) == ErrorRestart
+		if !restart {
+			// Halt before settling, so that the Ticker doesn't start another tick that this
+			// CellAut would never take part in.
+			callbacks.Supervisor.halt(cellErr)
+		}
+		callbacks.recovered(restart)
+	}()
+	aut.Start(callbacks.tick, done, stateLedger, callbacks)
+	return false
+}
+
+/*
+deliver sends tick tickID to the CellAut that the callbacks belong to.
+
+If the CellAut is dead, it settles the tick on its behalf instead.
+*/
+func (callbacks *CellAutCallbacks) deliver(tickID int64) {
+	callbacks.mu.Lock()
+	defer callbacks.mu.Unlock()
+	callbacks.tickID = tickID
+	callbacks.committed = false
+	callbacks.sending = false
+	callbacks.allSent = false
+	callbacks.pending = true
+	if callbacks.dead {
+		callbacks.settle()
+		return
+	}
+	// The previous tick has always been taken by now, so this doesn't block
+	callbacks.tick <- tickID
+}
+
+/*
+recovered is called after the CellAut panicked, from its goroutine. It settles whatever the CellAut
+owed the current tick, and if the CellAut isn't going to be restarted, marks it dead.
+*/
+func (callbacks *CellAutCallbacks) recovered(restart bool) {
+	callbacks.mu.Lock()
+	defer callbacks.mu.Unlock()
+	if !restart {
+		callbacks.dead = true
+	}
+	// The message the CellAut was handling when it panicked, or the state it was sending, still
+	// has to be counted, whichever tick it belongs to
+	if callbacks.inFlight {
+		callbacks.StateReceived()
+		callbacks.inFlight = false
+	}
+	if callbacks.unsent {
+		callbacks.StateReceived()
+		callbacks.unsent = false
+	}
+	if !callbacks.pending {
+		return
+	}
+	if len(callbacks.tick) > 0 {
+		// The CellAut panicked before it took the current tick. If it's restarted, it'll take it
+		// and handle it like any other tick.
+		if restart {
+			return
+		}
+		<-callbacks.tick
+	}
+	callbacks.settle()
+}
+
+/*
+settle does whatever the CellAut didn't get around to doing for the current tick. The caller must
+hold callbacks.mu.
+*/
+func (callbacks *CellAutCallbacks) settle() {
+	if !callbacks.committed {
+		callbacks.CommitGroup.Done()
+		callbacks.committed = true
+	}
+	if !callbacks.allSent {
+		callbacks.WaitGroup.Done()
+		callbacks.allSent = true
+	}
+	callbacks.pending = false
+}
+
+/*
+drain counts every state sent to a dead CellAut as received, so that its neighbors' ticks can
+finish, until done is closed. A CellAut that's restarted doesn't need this: it reads its own inbox
+again, and throwing away what's in there would lose its neighbors' states.
+*/
+func (callbacks *CellAutCallbacks) drain(aut CellAut, done chan struct{}) {
+	for {
+		// The inbox can be swapped for a bigger one between ticks, so it's looked up every time
+		select {
+		case msg := <-aut.Inbox():
+			callbacks.MessageReceived(msg)
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package cellaut
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+Runs a row of 3 GooCellAuts whose middle cell is in an invalid state, then sets the middle cell to
+"X", and returns the states after each of the two ticks.
+*/
+func runInvalidGooRow(policy ErrorPolicy) (*Ticker, []string) {
+	world := newGooRow(3)
+	ticker := &Ticker{Supervisor: NewSupervisor(policy)}
+	for _, aut := range world.cells {
+		aut.SetState("-")
+		ticker.Start(aut, nil)
+	}
+	world.cells[1].SetState("?")
+	ticker.Tick()
+	rows := []string{concatStates(world.cells)}
+	world.cells[1].SetState("X")
+	ticker.Tick()
+	ticker.Tick()
+	rows = append(rows, concatStates(world.cells))
+	return ticker, rows
+}
+
+func TestSupervisor_Halt(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	ticker, rows := runInvalidGooRow(ErrorHalt)
+	defer ticker.Stop(time.Second)
+	// The tick that produced the error finishes, and then nothing else happens
+	assert.Equal([]string{"-?-", "-?-"}, rows)
+	assert.NotNil(ticker.Err())
+	assert.Contains(ticker.Err().Error(), "invalid neighbor state '?'")
+	assert.Equal(int64(1), ticker.tickID)
+
+	cellErr := <-ticker.Supervisor.Errors()
+	assert.Equal(int64(0), cellErr.TickID)
+	assert.Contains([]string{"goo#0", "goo#2"}, fmt.Sprint(cellErr.Cell))
+}
+
+func TestSupervisor_SkipCell(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	ticker, rows := runInvalidGooRow(ErrorSkipCell)
+	defer ticker.Stop(time.Second)
+	// The neighbors of the bad cell are frozen for good
+	assert.Equal([]string{"-?-", "-X-"}, rows)
+	assert.Nil(ticker.Err())
+	assert.Len(ticker.Supervisor.Errors(), 2)
+}
+
+func TestSupervisor_Restart(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	ticker, rows := runInvalidGooRow(ErrorRestart)
+	defer ticker.Stop(time.Second)
+	// The neighbors of the bad cell ignore the bad state but keep going
+	assert.Equal([]string{"-?-", "XXX"}, rows)
+	assert.Nil(ticker.Err())
+}
+
+func TestSupervisor_ErrorsBuffer(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	sup := NewSupervisor(ErrorRestart)
+	for i := 0; i < supervisorErrorsBuffer+10; i++ {
+		assert.Equal(ErrorRestart, sup.Report(&CellError{TickID: int64(i), Err: fmt.Errorf("oops")}))
+	}
+	assert.Len(sup.Errors(), supervisorErrorsBuffer)
+	assert.Nil(sup.Err())
+	assert.Equal("skip cell", ErrorSkipCell.String())
+}
+
+func TestSimulation_Halt(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	world := newGooRow(3)
+	world.cells[0].SetState("?")
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: world}))
+	ticks := 0
+	sim.Subscribe(func(TickEvent) { ticks++ })
+	assert.Nil(sim.Start())
+	err := sim.Wait()
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "goo#1 at tick 0")
+	assert.Equal(0, ticks)
+	assert.Len(sim.Errors(), 1)
+}
+
+/*
+A CellAut with no neighbors that panics when it gets tick panicAt, the first time only.
+*/
+type panickyCellAut struct {
+	*GooCellAut
+	panicAt  int64
+	panicked bool
+	starts   int
+}
+
+func (aut *panickyCellAut) Start(tick chan int64, done chan struct{}, stateLedger chan StateRecord, callbacks *CellAutCallbacks) {
+	aut.starts++
+	for {
+		select {
+		case tickID := <-tick:
+			if tickID == aut.panicAt && !aut.panicked {
+				aut.panicked = true
+				panic("boom")
+			}
+			callbacks.StateCommitted()
+			callbacks.AllStatesSent()
+		case <-done:
+			return
+		}
+	}
+}
+
+func TestTicker_PanicRestart(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	world := newGooRow(5)
+	ticker := &Ticker{Supervisor: NewSupervisor(ErrorRestart)}
+	panicky := &panickyCellAut{GooCellAut: NewGooCellAut(99), panicAt: 1}
+	ticker.Start(panicky, nil)
+	for _, aut := range world.cells {
+		ticker.Start(aut, nil)
+	}
+	world.cells[0].SetState("X")
+	for i := 0; i < 4; i++ {
+		ticker.Tick()
+	}
+	assert.Equal("XXXX-", concatStates(world.cells))
+	assert.Nil(ticker.Err())
+	assert.Nil(ticker.Stop(time.Second))
+	assert.Equal(2, panicky.starts)
+
+	cellErr := <-ticker.Supervisor.Errors()
+	assert.Equal("cell goo#99 at tick 1: panic: boom", cellErr.Error())
+}
+
+func TestTicker_PanicHalt(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	for _, policy := range []ErrorPolicy{ErrorHalt, ErrorSkipCell} {
+		world := newGooRow(5)
+		ticker := &Ticker{Supervisor: NewSupervisor(policy)}
+		for _, aut := range world.cells {
+			ticker.Start(aut, nil)
+		}
+		panicky := &panickyCellAut{GooCellAut: NewGooCellAut(99), panicAt: 1}
+		ticker.Start(panicky, nil)
+		world.cells[0].SetState("X")
+		for i := 0; i < 4; i++ {
+			ticker.Tick()
+		}
+		// The tick that panicked still finishes
+		assert.Equal("XX---", concatStates(world.cells))
+		assert.NotNil(ticker.Err())
+		assert.Contains(ticker.Err().Error(), "panic: boom")
+		assert.Nil(ticker.Stop(time.Second))
+		assert.Equal(1, panicky.starts)
+	}
+}
+
+/*
+panicOnceRule returns a Rule that panics the first time any cell sees a neighbor in "X", and
+otherwise spreads "X" like goo.
+*/
+func panicOnceRule() Rule {
+	var panicked int32
+	return func(self State, neighbors map[NeighborIndex]State) State {
+		for _, state := range neighbors {
+			if state != "X" {
+				continue
+			}
+			if atomic.CompareAndSwapInt32(&panicked, 0, 1) {
+				panic("boom")
+			}
+			return "X"
+		}
+		return self
+	}
+}
+
+func TestTicker_PanicInRule(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	for _, tc := range []struct {
+		policy ErrorPolicy
+		want   string
+	}{
+		// The middle cell panics on hearing from the left one, during tick 0. Restarted, it
+		// still knows its neighbor's state, so the X carries on spreading a tick late.
+		{ErrorRestart, "XXX"},
+		// Dead, it never changes again, and the tick it died in still finishes
+		{ErrorHalt, "X--"},
+		{ErrorSkipCell, "X--"},
+	} {
+		grid := NewLineGrid(3, panicOnceRule(), "-")
+		grid.At(0, 0).SetState("X")
+		ticker := &Ticker{Supervisor: NewSupervisor(tc.policy)}
+		for x := 0; x < 3; x++ {
+			ticker.Start(grid.At(x, 0), nil)
+		}
+		ticked := make(chan struct{})
+		go func() {
+			defer close(ticked)
+			for i := 0; i < 4; i++ {
+				ticker.Tick()
+			}
+		}()
+		select {
+		case <-ticked:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Tick hung after a panic under %s", tc.policy)
+		}
+		var states string
+		for x := 0; x < 3; x++ {
+			states += string(grid.At(x, 0).GetState())
+		}
+		assert.Equal(tc.want, states, tc.policy.String())
+		assert.Equal(tc.policy != ErrorRestart, ticker.Err() != nil, tc.policy.String())
+		assert.Nil(ticker.Stop(time.Second), tc.policy.String())
+	}
+}
+
+func TestSimulation_PanicInRule(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	grid := NewLineGrid(3, panicOnceRule(), "-")
+	grid.At(0, 0).SetState("X")
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 4}))
+	assert.Nil(sim.Start())
+	waited := make(chan error)
+	go func() { waited <- sim.Wait() }()
+	select {
+	case err := <-waited:
+		assert.NotNil(err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Wait hung after a panic")
+	}
+}
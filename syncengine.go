@@ -0,0 +1,335 @@
+package cellaut
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+ruleCell is a CellAut that's a RuleCellAut underneath, like a LifeCellAut, so that a SyncEngine can
+get at its rule and states without going through its channels.
+*/
+type ruleCell interface {
+	CellAut
+	ruleCellAut() *RuleCellAut
+}
+
+/*
+SyncEngine runs a Grid of RuleCellAuts (or cells built on them, like LifeCellAuts) on the calling
+goroutine, with no channels and no goroutines of its own. The States are interned and kept in one
+contiguous array of StateIDs, and each tick works out the next array from the current one and swaps
+them, which is about as cheap as a tick can get. A Grid of millions of cells is no trouble, but its
+rule can't come up with more different States over the run than a StateID can number.
+
+Ticks are the same as under a Ticker or a Multiplexer: the first commits the states the cells were
+given with SetState, and each one after that is a generation. SetState between ticks overrides what
+the rule came up with, as usual, and GetState is kept up to date, so anything that watches the Grid
+works just the same.
+
+The cells' rules and the Grid's wiring are read when the engine's first tick starts. Cells can't be
+added or removed after that.
+*/
+type SyncEngine struct {
+	// Supervisor handles rules that panic, which always halts the engine. If it's nil when the
+	// SyncEngine is first used, a Supervisor with the ErrorHalt policy is created.
+	Supervisor     *Supervisor
+	supervisorOnce sync.Once
+	// Tracer, if it's set, gets a span for every tick
+	Tracer Tracer
+
+	grid *Grid
+	// index is where each of the Grid's cells is in its order. It never changes, so it isn't
+	// guarded by mu.
+	index map[CellAut]int
+	// tickID is only ever modified by Step. Other goroutines must read it atomically.
+	tickID  int64
+	ticking int32
+	// mu guards everything below
+	mu       sync.Mutex
+	stopped  bool
+	done     chan struct{}
+	doneOnce sync.Once
+	// cells are the Grid's cells in its order, once the first tick has started
+	cells  []*RuleCellAut
+	asleep []bool
+	// states and next are the double buffer: the committed States, and where the next ones are
+	// worked out, interned in table. names is the table's States, so that looking one up doesn't
+	// take its lock.
+	states, next []StateID
+	table        *StateTable
+	names        []State
+	// directions are the directions each cell can have neighbors in, and neighbors[i*len(directions)+j]
+	// is the index of cell i's neighbor in direction j, or -1 if it has none
+	directions []NeighborIndex
+	neighbors  []int
+	// view is what the rule is given as the neighbors' States. It's reused for every cell.
+	view map[NeighborIndex]State
+	// ledger is where the cells' StateRecords go, if anywhere
+	ledger chan StateRecord
+}
+
+/*
+NewSyncEngine returns a *SyncEngine that runs grid. Every cell in grid must be a RuleCellAut, or
+built on one.
+*/
+func NewSyncEngine(grid *Grid) (*SyncEngine, error) {
+	index := make(map[CellAut]int, len(grid.cells))
+	for i, aut := range grid.cells {
+		if _, ok := aut.(ruleCell); !ok {
+			return nil, fmt.Errorf("cell at (%d, %d) can't be run by the sync engine", i%grid.width, i/grid.width)
+		}
+		index[aut] = i
+	}
+	return &SyncEngine{grid: grid, index: index}, nil
+}
+
+func (engine *SyncEngine) supervisor() *Supervisor {
+	engine.supervisorOnce.Do(func() {
+		if engine.Supervisor == nil {
+			engine.Supervisor = NewSupervisor(ErrorHalt)
+		}
+	})
+	return engine.Supervisor
+}
+
+/*
+Err returns the error that halted the SyncEngine, if a rule panicked. Once Err is non-nil, Step
+does nothing.
+*/
+func (engine *SyncEngine) Err() error {
+	return engine.supervisor().Err()
+}
+
+/*
+Done returns a channel that Stop closes.
+*/
+func (engine *SyncEngine) Done() chan struct{} {
+	engine.doneOnce.Do(func() { engine.done = make(chan struct{}) })
+	return engine.done
+}
+
+/*
+add checks that aut is one of the Grid's cells, so that a SyncEngine can stand in for a Ticker.
+The SyncEngine writes every cell's StateRecords to the last stateLedger it was given.
+*/
+func (engine *SyncEngine) add(aut CellAut, stateLedger chan StateRecord) error {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	if engine.stopped {
+		return fmt.Errorf("sync engine has been stopped")
+	}
+	if engine.cells != nil {
+		return fmt.Errorf("sync engine can't add cells once it's started")
+	}
+	if _, ok := engine.index[aut]; ok {
+		engine.ledger = stateLedger
+		return nil
+	}
+	return fmt.Errorf("cell %s isn't in the sync engine's grid", cellName(aut))
+}
+
+/*
+Remove always fails: a SyncEngine's cells are fixed.
+*/
+func (engine *SyncEngine) Remove(aut CellAut) error {
+	return fmt.Errorf("sync engine can't remove cell %s", cellName(aut))
+}
+
+/*
+SetAsleep puts aut to sleep or wakes it up, starting with the next tick. A sleeping cell doesn't
+commit, but its neighbors still see its state.
+*/
+func (engine *SyncEngine) SetAsleep(aut CellAut, asleep bool) error {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	engine.load()
+	if i, ok := engine.index[aut]; ok {
+		engine.asleep[i] = asleep
+		return nil
+	}
+	return fmt.Errorf("cell %s isn't in the sync engine's grid", cellName(aut))
+}
+
+func (engine *SyncEngine) currentTick() int64 {
+	return atomic.LoadInt64(&engine.tickID)
+}
+
+/*
+load reads the cells and works out who neighbors whom, if that hasn't been done yet. The caller must
+hold engine.mu.
+*/
+func (engine *SyncEngine) load() {
+	if engine.cells != nil {
+		return
+	}
+	grid := engine.grid
+	engine.cells = make([]*RuleCellAut, len(grid.cells))
+	for i, aut := range grid.cells {
+		engine.cells[i] = aut.(ruleCell).ruleCellAut()
+	}
+	engine.asleep = make([]bool, len(grid.cells))
+	engine.states = make([]StateID, len(grid.cells))
+	engine.next = make([]StateID, len(grid.cells))
+	engine.table = NewStateTable()
+	engine.directions = grid.directions()
+	engine.neighbors = make([]int, 0, len(grid.cells)*len(engine.directions))
+	for y := 0; y < grid.height; y++ {
+		for x := 0; x < grid.width; x++ {
+			for _, i := range engine.directions {
+				dx, dy := neighborOffset(i)
+				neighbor, ok := grid.wrapped(x+dx, y+dy)
+				if !ok {
+					engine.neighbors = append(engine.neighbors, -1)
+					continue
+				}
+				engine.neighbors = append(engine.neighbors, engine.index[neighbor])
+			}
+		}
+	}
+	engine.view = make(map[NeighborIndex]State, len(engine.directions))
+}
+
+/*
+intern returns the StateID for state in engine.table. The caller must hold engine.mu.
+*/
+func (engine *SyncEngine) intern(state State) StateID {
+	id := engine.table.Intern(state)
+	if int(id) == len(engine.names) {
+		engine.names = append(engine.names, state)
+	}
+	return id
+}
+
+/*
+Step runs one tick.
+*/
+func (engine *SyncEngine) Step() {
+	ctx, span := startSpan(context.Background(), engine.Tracer, "tick")
+	span.SetAttribute("tick.id", atomic.LoadInt64(&engine.tickID))
+	engine.TickContext(ctx)
+	span.End()
+}
+
+/*
+TickContext is like Step, but the span for the tick's work is a child of the span in ctx, if
+there's a Tracer.
+*/
+func (engine *SyncEngine) TickContext(ctx context.Context) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	if engine.stopped || engine.Err() != nil {
+		return
+	}
+	engine.load()
+	atomic.StoreInt32(&engine.ticking, 1)
+	defer atomic.StoreInt32(&engine.ticking, 0)
+	_, span := startSpan(ctx, engine.Tracer, "compute")
+	defer span.End()
+
+	// Commit whatever each cell is due to become, which is what the rule said last tick unless
+	// SetState has been called since
+	for i, cell := range engine.cells {
+		if !engine.asleep[i] {
+			changed := cell.newState != cell.state || !cell.announced
+			cell.state = cell.newState
+			cell.announced = true
+			if changed {
+				recordState(engine.ledger, engine.Done(), cellName(engine.grid.cells[i]), engine.tickID, cell.state)
+			}
+		}
+		engine.states[i] = engine.intern(cell.state)
+	}
+	if !engine.compute() {
+		return
+	}
+	for i, cell := range engine.cells {
+		cell.tickID = engine.tickID
+		cell.newState = engine.names[engine.next[i]]
+	}
+	engine.states, engine.next = engine.next, engine.states
+	atomic.AddInt64(&engine.tickID, 1)
+}
+
+/*
+compute works out every cell's next State into engine.next. It returns false if a rule panicked,
+which halts the engine.
+*/
+func (engine *SyncEngine) compute() (ok bool) {
+	i := 0
+	defer func() {
+		if r := recover(); r != nil {
+			cellErr := &CellError{Cell: engine.grid.cells[i], TickID: engine.tickID, Err: fmt.Errorf("panic: %v", r)}
+			engine.supervisor().Report(cellErr)
+			engine.supervisor().halt(cellErr)
+			ok = false
+		}
+	}()
+	k := len(engine.directions)
+	for ; i < len(engine.cells); i++ {
+		if engine.cells[i].frozen {
+			// Frozen cells don't need the rule, or their neighborhood
+			engine.next[i] = engine.states[i]
+			continue
+		}
+		for j, direction := range engine.directions {
+			if neighbor := engine.neighbors[i*k+j]; neighbor >= 0 {
+				engine.view[direction] = engine.names[engine.states[neighbor]]
+			} else {
+				delete(engine.view, direction)
+			}
+		}
+		engine.next[i] = engine.intern(engine.cells[i].rule(engine.names[engine.states[i]], engine.view))
+	}
+	return true
+}
+
+/*
+Stop stops the SyncEngine. There's nothing to wait for, so timeout is ignored.
+*/
+func (engine *SyncEngine) Stop(timeout time.Duration) error {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	if !engine.stopped {
+		engine.stopped = true
+		close(engine.Done())
+	}
+	return nil
+}
+
+/*
+Health reports the state of the SyncEngine and its cells, which are alive until it stops.
+*/
+func (engine *SyncEngine) Health() Health {
+	health := Health{
+		TickID:  atomic.LoadInt64(&engine.tickID),
+		Ticking: atomic.LoadInt32(&engine.ticking) == 1,
+	}
+	if err := engine.Err(); err != nil {
+		health.Err = err.Error()
+	}
+	if health.Ticking {
+		// The cells are all being worked on, and mu is held until the tick's over
+		return health
+	}
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	for i, aut := range engine.grid.cells {
+		cellHealth := CellHealth{
+			Cell:          fmt.Sprintf("%d:%s", i, cellName(aut)),
+			Alive:         !engine.stopped,
+			Phase:         CellIdle,
+			LastAckedTick: health.TickID - 1,
+		}
+		switch {
+		case engine.stopped:
+			cellHealth.Phase = CellDead
+		case engine.asleep != nil && engine.asleep[i]:
+			cellHealth.Phase = CellAsleep
+		}
+		health.Cells = append(health.Cells, cellHealth)
+	}
+	return health
+}
@@ -0,0 +1,118 @@
+package cellaut
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncEngine(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	soup := randomStateGrid(20, 20, []State{LifeDead, LifeAlive}, 0.4, rand.New(rand.NewSource(1)))
+	grid := NewLifeGrid(20, 20)
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			grid.At(x, y).SetState(soup.At(x, y))
+		}
+	}
+	engine, err := NewSyncEngine(grid)
+	assert.Nil(err)
+
+	// The first tick commits the starting states, like under the other engines
+	engine.Step()
+	assert.True(soup.Equal(TakeSnapshot(grid, NewStateTable(LifeDead))))
+	// Every cell is stored as a StateID, and there are only two States to intern
+	assert.Len(engine.states, 400)
+	assert.Equal(2, engine.table.Len())
+	want := soup
+	for tick := 0; tick < 10; tick++ {
+		engine.Step()
+		want = stepGrid(want, Life, mooreNeighborhood)
+		assert.True(want.Equal(TakeSnapshot(grid, NewStateTable(LifeDead))), "tick %d", tick)
+	}
+
+	// SetState between ticks overrides the rule
+	grid.At(0, 0).SetState(LifeAlive)
+	engine.Step()
+	assert.Equal(LifeAlive, grid.At(0, 0).GetState())
+	assert.Equal(int64(12), engine.Health().TickID)
+
+	// Cells can't come and go
+	assert.NotNil(engine.add(NewLifeCellAut(0), nil))
+	assert.NotNil(engine.Remove(grid.At(1, 1)))
+
+	// Only rule cells will do
+	_, err = NewSyncEngine(NewGrid(2, 2, func(x, y int) CellAut { return NewGooCellAut(y*2 + x) }))
+	assert.NotNil(err)
+}
+
+func TestSyncEngine_Asleep(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Everything shifts one cell to the right, around a torus, except for a sleeping cell
+	shift := func(self State, neighbors map[NeighborIndex]State) State {
+		return neighbors[NeighborLf]
+	}
+	grid := NewRuleGrid(5, 1, shift, "-", WithTopology(Torus))
+	grid.At(0, 0).SetState("X")
+	engine, err := NewSyncEngine(grid)
+	assert.Nil(err)
+	assert.Nil(engine.SetAsleep(grid.At(2, 0), true))
+	for tick := 0; tick < 4; tick++ {
+		engine.Step()
+	}
+	// The X got as far as the sleeping cell, which never took it
+	assert.Equal("-----\n", (&Pattern{StateGrid: TakeSnapshot(grid, nil)}).String())
+	assert.Equal(CellAsleep, engine.Health().Cells[2].Phase)
+}
+
+func TestSyncEngine_Panic(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	grid := NewRuleGrid(3, 3, func(self State, neighbors map[NeighborIndex]State) State {
+		if self == "X" {
+			panic("no X allowed")
+		}
+		return self
+	}, "-")
+	grid.At(1, 1).SetState("X")
+	engine, err := NewSyncEngine(grid)
+	assert.Nil(err)
+	engine.Step()
+	assert.EqualError(engine.Err(), "cell rule#4 at tick 0: panic: no X allowed")
+	engine.Step()
+	assert.Equal(int64(0), engine.Health().TickID)
+
+	// So does a rule that comes up with more States than a StateID can number
+	n := 0
+	grid = NewRuleGrid(256, 256, func(self State, neighbors map[NeighborIndex]State) State {
+		n++
+		return State(fmt.Sprint(n))
+	}, "-")
+	engine, err = NewSyncEngine(grid)
+	assert.Nil(err)
+	engine.Step()
+	if assert.NotNil(engine.Err()) {
+		assert.Contains(engine.Err().Error(), "StateTable is full")
+	}
+}
+
+func TestSimulation_SynchronousEngine(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	for _, topology := range []Topology{Plane, Torus} {
+		alive := runLifeGrid(t, NewLifeGrid(10, 10, WithTopology(topology)), SynchronousEngine, gliderCells, 2, 5, 9)
+		assert.Equal(shifted(gliderCells, 4, 3), alive, "topology %d", topology)
+	}
+
+	sim := NewSimulation()
+	world := NewGrid(2, 2, func(x, y int) CellAut { return NewGooCellAut(y*2 + x) })
+	assert.NotNil(sim.Configure(SimulationConfig{World: world, Engine: SynchronousEngine}))
+}
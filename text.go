@@ -0,0 +1,106 @@
+package cellaut
+
+import "strings"
+
+/*
+TextPattern draws text in live cells with a tiny built-in font, for seeding a simulation with words
+and watching them fall apart. Each character is 3 cells wide and 5 high, with a column between
+characters and a row between lines, and scale makes every cell of the font a scale×scale block.
+Letters come out in upper case, and anything the font doesn't have comes out as "?".
+
+Live cells are "X", and the rest are "-", the Pattern's empty State, so it's ready for Grid.Place.
+*/
+func TextPattern(text string, scale int) *Pattern {
+	if scale < 1 {
+		scale = 1
+	}
+	lines := strings.Split(strings.ToUpper(text), "\n")
+	width := 0
+	for _, line := range lines {
+		width = maxInt(width, len([]rune(line))*4-1)
+	}
+	height := len(lines)*6 - 1
+	pattern := NewPattern(maxInt(width, 0)*scale, height*scale, NewStateTable("-"))
+	for i, line := range lines {
+		for n, r := range []rune(line) {
+			glyph, ok := bitmapFont[r]
+			if !ok {
+				glyph = bitmapFont['?']
+			}
+			for gy, row := range glyph {
+				for gx, c := range row {
+					if c != '#' {
+						continue
+					}
+					// The first line goes at the top, and row 0 is at the bottom
+					x, y := n*4+gx, height-1-(i*6+gy)
+					for dy := 0; dy < scale; dy++ {
+						for dx := 0; dx < scale; dx++ {
+							pattern.Set(x*scale+dx, y*scale+dy, LifeAlive)
+						}
+					}
+				}
+			}
+		}
+	}
+	return pattern
+}
+
+/*
+PlaceText draws text on the Grid with TextPattern, with the bottom left corner of the text at
+(x, y). As with Place, nothing is drawn if it doesn't fit.
+*/
+func (grid *Grid) PlaceText(text string, x, y, scale int) error {
+	return grid.Place(TextPattern(text, scale), x, y)
+}
+
+// bitmapFont is a 3×5 font, for labelling montages and writing in cells. Letters are upper case.
+var bitmapFont = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'A': {".#.", "#.#", "###", "#.#", "#.#"},
+	'B': {"##.", "#.#", "##.", "#.#", "##."},
+	'C': {".##", "#..", "#..", "#..", ".##"},
+	'D': {"##.", "#.#", "#.#", "#.#", "##."},
+	'E': {"###", "#..", "##.", "#..", "###"},
+	'F': {"###", "#..", "##.", "#..", "#.."},
+	'G': {".##", "#..", "#.#", "#.#", ".##"},
+	'H': {"#.#", "#.#", "###", "#.#", "#.#"},
+	'I': {"###", ".#.", ".#.", ".#.", "###"},
+	'J': {"..#", "..#", "..#", "#.#", ".#."},
+	'K': {"#.#", "#.#", "##.", "#.#", "#.#"},
+	'L': {"#..", "#..", "#..", "#..", "###"},
+	'M': {"#.#", "###", "###", "#.#", "#.#"},
+	'N': {"##.", "#.#", "#.#", "#.#", "#.#"},
+	'O': {".#.", "#.#", "#.#", "#.#", ".#."},
+	'P': {"##.", "#.#", "##.", "#..", "#.."},
+	'Q': {".#.", "#.#", "#.#", "##.", ".##"},
+	'R': {"##.", "#.#", "##.", "#.#", "#.#"},
+	'S': {".##", "#..", ".#.", "..#", "##."},
+	'T': {"###", ".#.", ".#.", ".#.", ".#."},
+	'U': {"#.#", "#.#", "#.#", "#.#", "###"},
+	'V': {"#.#", "#.#", "#.#", "#.#", ".#."},
+	'W': {"#.#", "#.#", "###", "###", "#.#"},
+	'X': {"#.#", "#.#", ".#.", "#.#", "#.#"},
+	'Y': {"#.#", "#.#", ".#.", ".#.", ".#."},
+	'Z': {"###", "..#", ".#.", "#..", "###"},
+	' ': {"...", "...", "...", "...", "..."},
+	'.': {"...", "...", "...", "...", ".#."},
+	',': {"...", "...", "...", ".#.", "#.."},
+	':': {"...", ".#.", "...", ".#.", "..."},
+	'-': {"...", "...", "###", "...", "..."},
+	'+': {"...", ".#.", "###", ".#.", "..."},
+	'=': {"...", "###", "...", "###", "..."},
+	'/': {"..#", "..#", ".#.", "#..", "#.."},
+	'_': {"...", "...", "...", "...", "###"},
+	'%': {"#.#", "..#", ".#.", "#..", "#.#"},
+	'?': {"##.", "..#", ".#.", "...", ".#."},
+}
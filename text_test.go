@@ -0,0 +1,31 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextPattern(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal(""+
+		"X-X-XXX\n"+
+		"X-X--X-\n"+
+		"XXX--X-\n"+
+		"X-X--X-\n"+
+		"X-X-XXX\n", TextPattern("hi", 1).String())
+
+	// Lines are a row apart, and scale blows every cell up
+	pattern := TextPattern("ab\nc", 2)
+	assert.Equal([]int{14, 22}, []int{pattern.Width, pattern.Height})
+	assert.Equal(LifeAlive, pattern.At(2, 0))
+	assert.Equal(LifeDead, pattern.At(0, 10))
+	// Unknown characters are question marks
+	assert.Equal(TextPattern("?", 1).String(), TextPattern("é", 1).String())
+
+	grid := NewLifeGrid(20, 10)
+	assert.Nil(grid.PlaceText("OK", 2, 2, 1))
+	assert.NotNil(grid.PlaceText("TOO LONG", 2, 2, 1))
+}
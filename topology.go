@@ -0,0 +1,100 @@
+package cellaut
+
+/*
+TopologyKind says what a TopologyChange did.
+*/
+type TopologyKind string
+
+const (
+	CellAdded   TopologyKind = "added"
+	CellRemoved TopologyKind = "removed"
+)
+
+/*
+TopologyChange records one cell being added to or removed from a running Simulation.
+*/
+type TopologyChange struct {
+	Kind TopologyKind
+	Cell CellAut
+	// Neighbors are the cells it was wired to or unwired from
+	Neighbors map[NeighborIndex]CellAut
+}
+
+/*
+TopologyEdit is handed to the functions passed to Simulation.Edit, to add and remove cells with.
+*/
+type TopologyEdit struct {
+	ticker      engine
+	stateLedger chan StateRecord
+	changes     []TopologyChange
+}
+
+/*
+Add wires aut to its neighbors and brings it to life. It takes part in the tick that follows the
+edit.
+
+neighbors maps each direction, from aut's point of view, to the neighbor in that direction. If aut
+can't be run by the Simulation's engine, Add leaves everything alone and returns an error.
+*/
+func (edit *TopologyEdit) Add(aut CellAut, neighbors map[NeighborIndex]CellAut) error {
+	if err := edit.ticker.add(aut, edit.stateLedger); err != nil {
+		return err
+	}
+	for i, neighbor := range neighbors {
+		aut.AddNeighbor(i, neighbor)
+		neighbor.AddNeighbor(i.Recip(), aut)
+	}
+	edit.changes = append(edit.changes, TopologyChange{Kind: CellAdded, Cell: aut, Neighbors: neighbors})
+	return nil
+}
+
+/*
+Remove unwires aut from its neighbors and tells it to exit. It doesn't take part in the tick that
+follows the edit.
+
+neighbors maps each direction, from aut's point of view, to the neighbor in that direction. If aut
+isn't running in the Simulation, Remove leaves everything alone and returns an error.
+*/
+func (edit *TopologyEdit) Remove(aut CellAut, neighbors map[NeighborIndex]CellAut) error {
+	if err := edit.ticker.Remove(aut); err != nil {
+		return err
+	}
+	for i, neighbor := range neighbors {
+		aut.RemoveNeighbor(i)
+		neighbor.RemoveNeighbor(i.Recip())
+	}
+	edit.changes = append(edit.changes, TopologyChange{Kind: CellRemoved, Cell: aut, Neighbors: neighbors})
+	return nil
+}
+
+/*
+Edit queues fn to be called between ticks, when no cell is doing anything, so that it can add and
+remove cells. It returns immediately. Edits queued before Start are applied before the first tick.
+
+fn is called from the goroutine that drives the ticks, so it's also a safe place to update the
+World to match, since subscribers won't be reading it. The changes it makes are recorded in the
+TickEvent of the tick that follows.
+*/
+func (sim *Simulation) Edit(fn func(edit *TopologyEdit)) {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	sim.edits = append(sim.edits, fn)
+}
+
+/*
+applyEdits calls the queued edit functions and returns the changes they made.
+*/
+func (sim *Simulation) applyEdits(ticker engine) []TopologyChange {
+	sim.mu.Lock()
+	edits := sim.edits
+	sim.edits = nil
+	sim.mu.Unlock()
+
+	var changes []TopologyChange
+	for _, fn := range edits {
+		edit := &TopologyEdit{ticker: ticker, stateLedger: sim.stateLedger}
+		fn(edit)
+		changes = append(changes, edit.changes...)
+	}
+	return changes
+}
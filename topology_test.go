@@ -0,0 +1,64 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulation_EditAdd(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	world := newGooRow(3)
+	world.cells[0].SetState("X")
+	added := NewGooCellAut(3)
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: world, MaxTicks: 5}))
+	var topology [][]TopologyChange
+	sim.Subscribe(func(event TickEvent) {
+		topology = append(topology, event.Topology)
+		if event.TickID == 0 {
+			sim.Edit(func(edit *TopologyEdit) {
+				edit.Add(added, map[NeighborIndex]CellAut{NeighborLf: world.cells[2]})
+			})
+		}
+	})
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+
+	// The goo spreads into the new cell as if it had always been there
+	assert.Equal(State("X"), added.GetState())
+	assert.Len(sim.Health().Cells, 4)
+	assert.Nil(topology[0])
+	assert.Equal([]TopologyChange{{
+		Kind:      CellAdded,
+		Cell:      added,
+		Neighbors: map[NeighborIndex]CellAut{NeighborLf: world.cells[2]},
+	}}, topology[1])
+}
+
+func TestSimulation_EditRemove(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	world := newGooRow(3)
+	world.cells[0].SetState("X")
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: world, MaxTicks: 4}))
+	var removeErr error
+	sim.Edit(func(edit *TopologyEdit) {
+		edit.Remove(world.cells[1], map[NeighborIndex]CellAut{NeighborLf: world.cells[0], NeighborRt: world.cells[2]})
+		removeErr = edit.Remove(NewGooCellAut(99), nil)
+	})
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+
+	assert.NotNil(removeErr)
+	// With the middle cell gone, the goo has no way across
+	assert.Equal(State("X"), world.cells[0].GetState())
+	assert.NotEqual(State("X"), world.cells[2].GetState())
+	health := sim.Health()
+	assert.Len(health.Cells, 2)
+	assert.Equal("1:goo#2", health.Cells[1].Cell)
+}
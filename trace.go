@@ -0,0 +1,44 @@
+package cellaut
+
+import (
+	"context"
+)
+
+/*
+Tracer is how a Ticker or Simulation reports what it spends its time on. Every tick gets a "tick"
+span, with a child span for each phase:
+
+	compute      from sending the tick until every cell has committed its new state
+	exchange     from then until every cell has told its neighbors about its new state
+	layers       stepping the Simulation's Layers
+	subscribers  calling the Simulation's subscribers, which is usually where rendering happens
+
+The interface is a small subset of OpenTelemetry's trace.Tracer, so an OpenTelemetry tracer can be
+plugged in with an adapter a few lines long.
+*/
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+/*
+Span is a unit of work being traced. It ends when End is called.
+*/
+type Span interface {
+	SetAttribute(key string, value int64)
+	End()
+}
+
+/*
+startSpan starts a span with tracer, or does nothing if tracer is nil.
+*/
+func startSpan(ctx context.Context, tracer Tracer, name string) (context.Context, Span) {
+	if tracer == nil {
+		return ctx, nopSpan{}
+	}
+	return tracer.Start(ctx, name)
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(key string, value int64) {}
+func (nopSpan) End()                                 {}
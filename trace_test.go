@@ -0,0 +1,83 @@
+package cellaut
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type spanKey struct{}
+
+/*
+A Tracer that remembers every span that ended, as "parent/name".
+*/
+type recordingTracer struct {
+	mu    sync.Mutex
+	ended []string
+	attrs map[string]int64
+}
+
+type recordingSpan struct {
+	tracer *recordingTracer
+	path   string
+}
+
+func (tracer *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	path := name
+	if parent, ok := ctx.Value(spanKey{}).(*recordingSpan); ok {
+		path = parent.path + "/" + name
+	}
+	span := &recordingSpan{tracer: tracer, path: path}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (span *recordingSpan) SetAttribute(key string, value int64) {
+	span.tracer.mu.Lock()
+	defer span.tracer.mu.Unlock()
+	span.tracer.attrs[span.path+" "+key] = value
+}
+
+func (span *recordingSpan) End() {
+	span.tracer.mu.Lock()
+	defer span.tracer.mu.Unlock()
+	span.tracer.ended = append(span.tracer.ended, span.path)
+}
+
+func TestTicker_Tracer(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	tracer := &recordingTracer{attrs: make(map[string]int64)}
+	world := newGooRow(3)
+	ticker := &Ticker{Tracer: tracer}
+	for _, aut := range world.cells {
+		ticker.Start(aut, nil)
+	}
+	ticker.Tick()
+	ticker.Tick()
+	assert.Nil(ticker.Stop(time.Second))
+
+	assert.Equal([]string{
+		"tick/compute", "tick/exchange", "tick",
+		"tick/compute", "tick/exchange", "tick",
+	}, tracer.ended)
+	assert.Equal(int64(1), tracer.attrs["tick tick.id"])
+}
+
+func TestSimulation_Tracer(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	tracer := &recordingTracer{attrs: make(map[string]int64)}
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: newGooRow(3), MaxTicks: 1, Tracer: tracer}))
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+
+	assert.Equal([]string{
+		"tick/compute", "tick/exchange", "tick/layers", "tick/subscribers", "tick",
+	}, tracer.ended)
+}
@@ -0,0 +1,117 @@
+package cellaut
+
+import (
+	"image/color"
+	"math/rand"
+)
+
+// States used by the Biham-Middleton-Levine traffic model
+const (
+	TrafficEmpty State = "-"
+	TrafficEast  State = ">"
+	TrafficSouth State = "v"
+)
+
+// TrafficPalette draws the states used by the traffic model
+var TrafficPalette = Palette{
+	TrafficEmpty: color.White,
+	TrafficEast:  color.RGBA{0xd0, 0x20, 0x20, 0xff},
+	TrafficSouth: color.RGBA{0x20, 0x40, 0xd0, 0xff},
+}
+
+/*
+RandomTraffic returns a width×height grid where each cell holds a car with probability density,
+with the cars split evenly between east- and south-bound.
+*/
+func RandomTraffic(width, height int, density float64, rng *rand.Rand) *StateGrid {
+	grid := NewStateGrid(width, height, NewStateTable(TrafficEmpty, TrafficEast, TrafficSouth))
+	east, south := grid.Table.Intern(TrafficEast), grid.Table.Intern(TrafficSouth)
+	for i := range grid.cells {
+		if rng.Float64() < density {
+			grid.cells[i] = east
+			if rng.Intn(2) == 0 {
+				grid.cells[i] = south
+			}
+		}
+	}
+	return grid
+}
+
+/*
+TrafficStep runs one phase of the Biham-Middleton-Levine traffic model on a grid that wraps around
+at the edges. In the east phase, every east-bound car moves one cell right if that cell is empty;
+in the south phase, south-bound cars do the same going down. It returns the new grid and how many
+cars moved.
+*/
+func TrafficStep(grid *StateGrid, east bool) (*StateGrid, int) {
+	next := NewStateGrid(grid.Width, grid.Height, grid.Table)
+	copy(next.cells, grid.cells)
+	mover, dx, dy := grid.Table.Intern(TrafficSouth), 0, -1
+	if east {
+		mover, dx, dy = grid.Table.Intern(TrafficEast), 1, 0
+	}
+	empty := grid.Table.Intern(TrafficEmpty)
+	moved := 0
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			if grid.IDAt(x, y) != mover {
+				continue
+			}
+			nx, ny := (x+dx)%grid.Width, (y+dy+grid.Height)%grid.Height
+			// Every car looks at the grid as it was before anyone moved
+			if grid.IDAt(nx, ny) == empty {
+				next.SetID(x, y, empty)
+				next.SetID(nx, ny, mover)
+				moved++
+			}
+		}
+	}
+	return next, moved
+}
+
+/*
+TrafficStats sums up a run of the traffic model.
+*/
+type TrafficStats struct {
+	// Ticks is how many phases ran. East-bound cars move on even ticks and south-bound on odd ones.
+	Ticks int
+	// Velocity is the mean fraction of each phase's cars that moved
+	Velocity float64
+	// Jammed is true if the run stopped early because a whole east and south cycle went by
+	// without anyone moving, which means nobody ever will
+	Jammed bool
+}
+
+/*
+RunTraffic runs the traffic model for up to the given number of ticks, stopping early if it jams.
+*/
+func RunTraffic(grid *StateGrid, ticks int) (*StateGrid, TrafficStats) {
+	cars := map[bool]int{true: int(grid.Population(TrafficEast)), false: int(grid.Population(TrafficSouth))}
+	var stats TrafficStats
+	stuck := 0
+	for stats.Ticks < ticks {
+		east := stats.Ticks%2 == 0
+		var moved int
+		grid, moved = TrafficStep(grid, east)
+		stats.Ticks++
+		if cars[east] == 0 {
+			// Nobody to hold up
+			stats.Velocity++
+			continue
+		}
+		stats.Velocity += float64(moved) / float64(cars[east])
+		if moved == 0 {
+			stuck++
+		} else {
+			stuck = 0
+		}
+		if stuck >= 2 {
+			stats.Jammed = true
+			break
+		}
+	}
+	if stats.Ticks > 0 {
+		stats.Velocity /= float64(stats.Ticks)
+	}
+	return grid, stats
+}
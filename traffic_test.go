@@ -0,0 +1,55 @@
+package cellaut
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrafficStep(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	table := NewStateTable(TrafficEmpty, TrafficEast, TrafficSouth)
+	pattern, err := ParsePattern(`
+		--v
+		>v-
+		--v
+	`, table)
+	assert.Nil(err)
+
+	// The east car is blocked by the south car in front of it
+	grid, moved := TrafficStep(pattern.StateGrid, true)
+	assert.Equal(0, moved)
+	// The bottom car would wrap around to the top, but that cell was full when the phase started
+	grid, moved = TrafficStep(grid, false)
+	assert.Equal(2, moved)
+	assert.Equal("---\n>-v\n-vv\n", (&Pattern{StateGrid: grid}).String())
+	grid, moved = TrafficStep(grid, true)
+	assert.Equal(1, moved)
+	assert.Equal("---\n->v\n-vv\n", (&Pattern{StateGrid: grid}).String())
+}
+
+func TestRunTraffic(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Sparse traffic flows freely
+	_, stats := RunTraffic(RandomTraffic(32, 32, 0.15, rand.New(rand.NewSource(1))), 1000)
+	assert.False(stats.Jammed)
+	assert.Equal(1000, stats.Ticks)
+	assert.True(stats.Velocity > 0.9, "velocity %g", stats.Velocity)
+
+	// Dense traffic jams for good
+	grid, stats := RunTraffic(RandomTraffic(32, 32, 0.6, rand.New(rand.NewSource(1))), 5000)
+	assert.True(stats.Jammed)
+	assert.True(stats.Ticks < 5000)
+	_, moved := TrafficStep(grid, true)
+	assert.Equal(0, moved)
+
+	// An empty road isn't a jam
+	_, stats = RunTraffic(RandomTraffic(4, 4, 0, rand.New(rand.NewSource(1))), 10)
+	assert.False(stats.Jammed)
+	assert.Equal(1.0, stats.Velocity)
+}
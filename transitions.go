@@ -0,0 +1,185 @@
+package cellaut
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+/*
+TransitionMatrix is how many cells went from each State to each other State in one tick.
+Counts[from][to] is the number that were in from before the tick and in to after it, and cells that
+didn't change are counted too, in Counts[s][s]. In Life, Counts["-"]["X"] is the births,
+Counts["X"]["-"] the deaths and Counts["X"]["X"] the survivals, which says a lot more about what a
+rule is doing than the populations do.
+*/
+type TransitionMatrix struct {
+	TickID int64                     `json:"tick"`
+	Counts map[State]map[State]int64 `json:"counts"`
+}
+
+/*
+CountTransitions returns the TransitionMatrix from before to after, which must be the same size.
+Its TickID is left for the caller to fill in.
+*/
+func CountTransitions(before, after *StateGrid) TransitionMatrix {
+	matrix := TransitionMatrix{Counts: make(map[State]map[State]int64)}
+	for y := 0; y < after.Height; y++ {
+		for x := 0; x < after.Width; x++ {
+			matrix.add(before.At(x, y), after.At(x, y), 1)
+		}
+	}
+	return matrix
+}
+
+func (matrix TransitionMatrix) add(from, to State, n int64) {
+	if matrix.Counts[from] == nil {
+		matrix.Counts[from] = make(map[State]int64)
+	}
+	matrix.Counts[from][to] += n
+}
+
+/*
+Count returns how many cells went from from to to.
+*/
+func (matrix TransitionMatrix) Count(from, to State) int64 {
+	return matrix.Counts[from][to]
+}
+
+/*
+Population returns how many cells were in state after the tick: the column of the matrix for state.
+*/
+func (matrix TransitionMatrix) Population(state State) int64 {
+	var n int64
+	for _, row := range matrix.Counts {
+		n += row[state]
+	}
+	return n
+}
+
+/*
+States returns every State that cells went from or to, sorted.
+*/
+func (matrix TransitionMatrix) States() []State {
+	seen := make(map[State]bool)
+	for from, row := range matrix.Counts {
+		seen[from] = true
+		for to := range row {
+			seen[to] = true
+		}
+	}
+	states := make([]State, 0, len(seen))
+	for state := range seen {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i] < states[j] })
+	return states
+}
+
+/*
+TransitionRecorder keeps a TransitionMatrix for every tick of a run, by comparing each tick's States
+with the tick before's. The first tick it sees has nothing to compare with, so it's only the
+starting point, and so is any tick in which the World changes size.
+
+It's safe for concurrent use.
+*/
+type TransitionRecorder struct {
+	mu       sync.Mutex
+	previous *StateGrid
+	matrices []TransitionMatrix
+}
+
+/*
+NewTransitionRecorder returns an empty *TransitionRecorder.
+*/
+func NewTransitionRecorder() *TransitionRecorder {
+	return &TransitionRecorder{}
+}
+
+/*
+SampleGrid records the transitions from the last grid sampled to grid, which are the States as of
+the given tick. It returns the TransitionMatrix, and false if there was nothing to compare grid
+with.
+*/
+func (recorder *TransitionRecorder) SampleGrid(tick int64, grid *StateGrid) (TransitionMatrix, bool) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	previous := recorder.previous
+	recorder.previous = grid
+	if previous == nil || previous.Width != grid.Width || previous.Height != grid.Height {
+		return TransitionMatrix{}, false
+	}
+	matrix := CountTransitions(previous, grid)
+	matrix.TickID = tick
+	recorder.matrices = append(recorder.matrices, matrix)
+	return matrix, true
+}
+
+/*
+Sink returns a Sink that samples a Simulation's World after every tick.
+*/
+func (recorder *TransitionRecorder) Sink() Sink {
+	return func(event TickEvent) {
+		recorder.SampleGrid(event.TickID, TakeSnapshot(event.World, nil))
+	}
+}
+
+/*
+Matrices returns the TransitionMatrix of every tick recorded, in order.
+*/
+func (recorder *TransitionRecorder) Matrices() []TransitionMatrix {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return append([]TransitionMatrix(nil), recorder.matrices...)
+}
+
+/*
+Total returns the transitions of every tick recorded added together, with the TickID of the last.
+*/
+func (recorder *TransitionRecorder) Total() TransitionMatrix {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	total := TransitionMatrix{Counts: make(map[State]map[State]int64)}
+	for _, matrix := range recorder.matrices {
+		total.TickID = matrix.TickID
+		for from, row := range matrix.Counts {
+			for to, n := range row {
+				total.add(from, to, n)
+			}
+		}
+	}
+	return total
+}
+
+/*
+WriteCSV writes the transitions as CSV, for loading into a dataframe: a header row, then a row of
+tick, from, to and count for every pair of States that any cells went between that tick, with the
+States in order.
+*/
+func (recorder *TransitionRecorder) WriteCSV(w io.Writer) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"tick", "from", "to", "count"}); err != nil {
+		return err
+	}
+	for _, matrix := range recorder.matrices {
+		tick := strconv.FormatInt(matrix.TickID, 10)
+		states := matrix.States()
+		for _, from := range states {
+			for _, to := range states {
+				n, ok := matrix.Counts[from][to]
+				if !ok {
+					continue
+				}
+				if err := out.Write([]string{tick, string(from), string(to), strconv.FormatInt(n, 10)}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
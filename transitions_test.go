@@ -0,0 +1,70 @@
+package cellaut
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountTransitions(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	before, err := ParsePattern("X.X", nil)
+	assert.Nil(err)
+	after, err := ParsePattern("XX.", nil)
+	assert.Nil(err)
+	matrix := CountTransitions(before.StateGrid, after.StateGrid)
+	assert.Equal(int64(1), matrix.Count(".", "X"))
+	assert.Equal(int64(1), matrix.Count("X", "."))
+	assert.Equal(int64(1), matrix.Count("X", "X"))
+	assert.Equal(int64(0), matrix.Count(".", "."))
+	assert.Equal(int64(2), matrix.Population("X"))
+	assert.Equal([]State{".", "X"}, matrix.States())
+}
+
+func TestTransitionRecorder(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// A blinker: every tick, 2 of its 3 cells die, 2 are born, and the middle one survives
+	recorder := NewTransitionRecorder()
+	grid := NewLifeGrid(3, 3)
+	for x := 0; x < 3; x++ {
+		grid.At(x, 1).SetState(LifeAlive)
+	}
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{World: grid, MaxTicks: 4}))
+	sim.Subscribe(recorder.Sink())
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+
+	// Tick 0 has nothing to compare with
+	matrices := recorder.Matrices()
+	assert.Len(matrices, 3)
+	for i, matrix := range matrices {
+		assert.Equal(int64(i+1), matrix.TickID)
+		assert.Equal(int64(2), matrix.Count(LifeDead, LifeAlive))
+		assert.Equal(int64(2), matrix.Count(LifeAlive, LifeDead))
+		assert.Equal(int64(1), matrix.Count(LifeAlive, LifeAlive))
+		assert.Equal(int64(4), matrix.Count(LifeDead, LifeDead))
+		assert.Equal(int64(3), matrix.Population(LifeAlive))
+	}
+	total := recorder.Total()
+	assert.Equal(int64(3), total.TickID)
+	assert.Equal(int64(6), total.Count(LifeDead, LifeAlive))
+	assert.Equal(int64(27), total.Population(LifeAlive)+total.Population(LifeDead))
+
+	var out bytes.Buffer
+	assert.Nil(recorder.WriteCSV(&out))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(lines, 1+3*4)
+	assert.Equal([]string{"tick,from,to,count", "1,-,-,4", "1,-,X,2", "1,X,-,2", "1,X,X,1"}, lines[:5])
+
+	// A grid of a different size starts over
+	_, ok := recorder.SampleGrid(4, NewStateGrid(2, 2, nil))
+	assert.False(ok)
+	assert.Len(recorder.Matrices(), 3)
+}
@@ -0,0 +1,243 @@
+package cellaut
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+TuringAction is what a Turing machine does on one step: write a symbol, move the head, and switch
+to the next state.
+*/
+type TuringAction struct {
+	Write rune
+	// Move is -1 for left, 1 for right, or 0 to stay put
+	Move int
+	Next string
+}
+
+/*
+TuringMachine is a one-tape Turing machine. Compiled with Rule, it runs as a one-dimensional cellular
+automaton, one step per tick, which is the usual way of showing that cellular automata can compute
+anything a computer can.
+
+A machine halts when it's in a state with no action for the symbol under the head.
+*/
+type TuringMachine struct {
+	Start string
+	// Blank is the symbol the tape is filled with. Cells in any State that isn't a symbol, like the
+	// empty State, are read as blank too.
+	Blank rune
+	// Actions is keyed by state, then by the symbol under the head
+	Actions map[string]map[rune]TuringAction
+}
+
+/*
+ParseTuringMachine reads a Turing machine description like
+
+	start: A
+	blank: 0
+	# state, symbol read -> symbol written, move (L, R or N), next state
+	A 0 -> 1 R B
+	A 1 -> 1 L B
+	B 0 -> 1 L A
+	B 1 -> 1 R H
+
+Symbols are single characters. State names can't contain "@" or spaces.
+*/
+func ParseTuringMachine(text string) (*TuringMachine, error) {
+	tm := &TuringMachine{Blank: '0', Actions: make(map[string]map[rune]TuringAction)}
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "start:"):
+			tm.Start = strings.TrimSpace(strings.TrimPrefix(line, "start:"))
+			continue
+		case strings.HasPrefix(line, "blank:"):
+			blank := strings.TrimSpace(strings.TrimPrefix(line, "blank:"))
+			if utf8.RuneCountInString(blank) != 1 {
+				return nil, fmt.Errorf("line %d: blank symbol '%s' isn't a single character", lineno, blank)
+			}
+			tm.Blank, _ = utf8.DecodeRuneInString(blank)
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 6 || fields[2] != "->" {
+			return nil, fmt.Errorf("line %d: expected '<state> <symbol> -> <symbol> <L|R|N> <state>'", lineno)
+		}
+		for _, i := range []int{1, 3} {
+			if utf8.RuneCountInString(fields[i]) != 1 {
+				return nil, fmt.Errorf("line %d: symbol '%s' isn't a single character", lineno, fields[i])
+			}
+		}
+		for _, state := range []string{fields[0], fields[5]} {
+			if strings.Contains(state, "@") {
+				return nil, fmt.Errorf("line %d: state name '%s' contains '@'", lineno, state)
+			}
+		}
+		read, _ := utf8.DecodeRuneInString(fields[1])
+		write, _ := utf8.DecodeRuneInString(fields[3])
+		move, ok := map[string]int{"L": -1, "R": 1, "N": 0}[fields[4]]
+		if !ok {
+			return nil, fmt.Errorf("line %d: move '%s' isn't L, R or N", lineno, fields[4])
+		}
+		if tm.Actions[fields[0]] == nil {
+			tm.Actions[fields[0]] = make(map[rune]TuringAction)
+		}
+		if _, ok := tm.Actions[fields[0]][read]; ok {
+			return nil, fmt.Errorf("line %d: second action for state %s reading '%c'", lineno, fields[0], read)
+		}
+		tm.Actions[fields[0]][read] = TuringAction{Write: write, Move: move, Next: fields[5]}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if tm.Start == "" {
+		return nil, fmt.Errorf("no start state")
+	}
+	return tm, nil
+}
+
+/*
+TuringCell returns the State of a tape cell holding symbol. If head isn't empty, the head is on the
+cell, in that state.
+*/
+func TuringCell(symbol rune, head string) State {
+	if head == "" {
+		return State(string(symbol))
+	}
+	return State(string(symbol) + "@" + head)
+}
+
+/*
+cell picks apart the State of a tape cell.
+*/
+func (tm *TuringMachine) cell(state State) (symbol rune, head string) {
+	parts := strings.SplitN(string(state), "@", 2)
+	symbol, size := utf8.DecodeRuneInString(parts[0])
+	if size == 0 || size != len(parts[0]) {
+		symbol = tm.Blank
+	}
+	if len(parts) == 2 {
+		head = parts[1]
+	}
+	return symbol, head
+}
+
+/*
+Rule returns the machine as a one-dimensional Rule, to be run with a cell's NeighborLf and NeighborRt.
+The head moves one cell per tick. Off the ends of the grid there's no more tape, so a head that
+walks off is lost.
+*/
+func (tm *TuringMachine) Rule() Rule {
+	return func(self State, neighbors map[NeighborIndex]State) State {
+		symbol, head := tm.cell(self)
+		if head != "" {
+			action, ok := tm.Actions[head][symbol]
+			if !ok {
+				// Halted
+				return self
+			}
+			if action.Move == 0 {
+				return TuringCell(action.Write, action.Next)
+			}
+			return TuringCell(action.Write, "")
+		}
+		// Heads move in from the neighbors
+		for index, move := range map[NeighborIndex]int{NeighborLf: 1, NeighborRt: -1} {
+			neighbor, ok := neighbors[index]
+			if !ok {
+				continue
+			}
+			theirSymbol, theirHead := tm.cell(neighbor)
+			if action, ok := tm.Actions[theirHead][theirSymbol]; ok && theirHead != "" && action.Move == move {
+				return TuringCell(symbol, action.Next)
+			}
+		}
+		return self
+	}
+}
+
+/*
+Tape returns a width-cell grid for the machine to run on, with input written starting at cell
+offset and the head on its first symbol, in the start state. The rest of the tape is blank.
+*/
+func (tm *TuringMachine) Tape(input string, width, offset int) (*StateGrid, error) {
+	symbols := []rune(input)
+	if len(symbols) == 0 {
+		symbols = []rune{tm.Blank}
+	}
+	if offset < 0 || offset+len(symbols) > width {
+		return nil, fmt.Errorf("input of %d symbols at %d doesn't fit on a tape of %d cells", len(symbols), offset, width)
+	}
+	grid := NewStateGrid(width, 1, NewStateTable("-"))
+	for x := 0; x < width; x++ {
+		grid.Set(x, 0, TuringCell(tm.Blank, ""))
+	}
+	for i, symbol := range symbols {
+		grid.Set(offset+i, 0, TuringCell(symbol, ""))
+	}
+	grid.Set(offset, 0, TuringCell(symbols[0], tm.Start))
+	return grid, nil
+}
+
+/*
+ReadTape reads back the symbols on a tape, along with where the head is and what state it's in. head
+is -1 if the head has walked off the tape.
+*/
+func (tm *TuringMachine) ReadTape(grid *StateGrid) (tape string, head int, state string) {
+	var b strings.Builder
+	head = -1
+	for x := 0; x < grid.Width; x++ {
+		symbol, h := tm.cell(grid.At(x, 0))
+		b.WriteRune(symbol)
+		if h != "" {
+			head, state = x, h
+		}
+	}
+	return b.String(), head, state
+}
+
+/*
+Halted returns whether the machine on the tape has halted, or lost its head off the end.
+*/
+func (tm *TuringMachine) Halted(grid *StateGrid) bool {
+	tape, head, state := tm.ReadTape(grid)
+	if head < 0 {
+		return true
+	}
+	_, ok := tm.Actions[state][[]rune(tape)[head]]
+	return !ok
+}
+
+// BusyBeaver3 is the 3-state, 2-symbol busy beaver, which writes 6 1s and then halts
+const BusyBeaver3 = `
+start: A
+blank: 0
+A 0 -> 1 R B
+A 1 -> 1 R H
+B 0 -> 0 R C
+B 1 -> 1 R B
+C 0 -> 1 L C
+C 1 -> 1 L A
+`
+
+/*
+mustParseTuringMachine parses one of the machines built into cellaut, which had better be valid.
+*/
+func mustParseTuringMachine(text string) *TuringMachine {
+	tm, err := ParseTuringMachine(text)
+	if err != nil {
+		panic(err)
+	}
+	return tm
+}
@@ -0,0 +1,73 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTuringMachine(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	tm, err := ParseTuringMachine(BusyBeaver3)
+	assert.Nil(err)
+	grid, err := tm.Tape("", 12, 6)
+	assert.Nil(err)
+	rule := tm.Rule()
+	ticks := 0
+	for ; !tm.Halted(grid) && ticks < 100; ticks++ {
+		grid = stepGrid(grid, rule, lineNeighborhood)
+	}
+	tape, head, state := tm.ReadTape(grid)
+	// 13 steps to get to the halting transition, and one more to take it
+	assert.Equal(14, ticks)
+	assert.Equal("000001111110", tape)
+	assert.Equal("H", state)
+	assert.Equal(8, head)
+
+	// Binary increment: run right to the end of the number, then carry back left
+	tm, err = ParseTuringMachine(`
+		start: right
+		blank: _
+		right 0 -> 0 R right
+		right 1 -> 1 R right
+		right _ -> _ L carry
+		carry 1 -> 0 L carry
+		carry 0 -> 1 N done
+		carry _ -> 1 N done
+	`)
+	assert.Nil(err)
+	for input, want := range map[string]string{"1011": "_1100__", "111": "1000___", "0": "_1_____"} {
+		grid, err := tm.Tape(input, 7, 1)
+		assert.Nil(err)
+		for i := 0; i < 20; i++ {
+			grid = stepGrid(grid, tm.Rule(), lineNeighborhood)
+		}
+		tape, _, state := tm.ReadTape(grid)
+		assert.Equal(want, tape, input)
+		assert.Equal("done", state, input)
+		assert.True(tm.Halted(grid))
+	}
+
+	// Off the end of the tape
+	grid, _ = tm.Tape("11", 2, 0)
+	grid = stepGrid(stepGrid(grid, tm.Rule(), lineNeighborhood), tm.Rule(), lineNeighborhood)
+	_, head, _ = tm.ReadTape(grid)
+	assert.Equal(-1, head)
+	assert.True(tm.Halted(grid))
+
+	_, err = tm.Tape("111", 2, 0)
+	assert.NotNil(err)
+	for _, bad := range []string{
+		"A 0 -> 1 R B",
+		"start: A\nA 0 -> 1 X B",
+		"start: A\nA 00 -> 1 R B",
+		"start: A\nA 0 1 R B",
+		"start: A\nA@ 0 -> 1 R B",
+		"start: A\nA 0 -> 1 R B\nA 0 -> 0 L B",
+	} {
+		_, err := ParseTuringMachine(bad)
+		assert.NotNil(err, bad)
+	}
+}
@@ -0,0 +1,243 @@
+package cellaut
+
+import (
+	"fmt"
+	"image/color"
+	"math/rand"
+)
+
+// The states of von Neumann's 29-state automaton, numbered as RuleTable states
+const (
+	// jvnGround is the unexcitable ground state, U
+	jvnGround = iota
+	// The sensitized states, which a ground cell goes through while it's being built into something
+	// else. Each digit after the S is one bit received since.
+	jvnS
+	jvnS0
+	jvnS1
+	jvnS00
+	jvnS01
+	jvnS10
+	jvnS11
+	jvnS000
+	// The confluent states. The first digit is whether the cell is excited now, and the second
+	// whether it will be next tick.
+	jvnC00
+	jvnC01
+	jvnC10
+	jvnC11
+	// jvnOrdinary is the first of the 8 ordinary transmission states, and jvnSpecial the first of the
+	// 8 special ones. Each is followed by 2*direction + excited, where direction is an index into
+	// vonNeumannOrder.
+	jvnOrdinary
+	jvnSpecial = jvnOrdinary + 8
+	jvnStates  = jvnSpecial + 8
+)
+
+// jvnSensitized gives the next state of each sensitized state, if it receives a 0 and a 1
+var jvnSensitized = map[int][2]int{
+	jvnS:    {jvnS0, jvnS1},
+	jvnS0:   {jvnS00, jvnS01},
+	jvnS1:   {jvnS10, jvnS11},
+	jvnS00:  {jvnS000, jvnOrdinary + 2*3},
+	jvnS01:  {jvnOrdinary + 2*2, jvnSpecial + 2*1},
+	jvnS10:  {jvnSpecial + 2*0, jvnSpecial + 2*3},
+	jvnS11:  {jvnSpecial + 2*2, jvnC00},
+	jvnS000: {jvnOrdinary + 2*1, jvnOrdinary + 2*0},
+}
+
+/*
+VonNeumannTransmission returns the State of a transmission cell in von Neumann's automaton, which
+passes excitation on to the neighbor at direction a tick later. Special transmission cells carry the
+signals that tear down what ordinary ones build.
+*/
+func VonNeumannTransmission(direction NeighborIndex, special, excited bool) State {
+	n := jvnOrdinary
+	if special {
+		n = jvnSpecial
+	}
+	for i, index := range vonNeumannOrder {
+		if index == direction {
+			n += 2 * i
+		}
+	}
+	if excited {
+		n++
+	}
+	return TableState(n)
+}
+
+/*
+VonNeumannConfluent returns the State of a confluent cell in von Neumann's automaton, which is
+excited two ticks after all the ordinary transmission cells pointing at it are, and passes that on
+to every transmission cell next to it that isn't pointing at it.
+*/
+func VonNeumannConfluent(now, next bool) State {
+	n := jvnC00
+	if now {
+		n += 2
+	}
+	if next {
+		n++
+	}
+	return TableState(n)
+}
+
+/*
+VonNeumannPalette draws the states of von Neumann's automaton: ground in black, sensitized states in
+shades of purple, confluents in yellow, ordinary transmission in blue and special in red, brighter
+when excited.
+*/
+var VonNeumannPalette = func() Palette {
+	palette := Palette{TableState(jvnGround): color.Black}
+	for n := jvnS; n <= jvnS000; n++ {
+		palette[TableState(n)] = color.RGBA{uint8(0x60 + 0x10*n), 0x20, uint8(0x80 + 0x10*n), 0xff}
+	}
+	for n := jvnC00; n <= jvnC11; n++ {
+		palette[TableState(n)] = color.RGBA{0xff, uint8(0x90 + 0x18*(n-jvnC00)), 0x00, 0xff}
+	}
+	for i := 0; i < 4; i++ {
+		palette[TableState(jvnOrdinary+2*i)] = color.RGBA{0x20, 0x40, 0xa0, 0xff}
+		palette[TableState(jvnOrdinary+2*i+1)] = color.RGBA{0x80, 0xc0, 0xff, 0xff}
+		palette[TableState(jvnSpecial+2*i)] = color.RGBA{0xa0, 0x20, 0x20, 0xff}
+		palette[TableState(jvnSpecial+2*i+1)] = color.RGBA{0xff, 0x80, 0x80, 0xff}
+	}
+	return palette
+}()
+
+/*
+VonNeumann returns von Neumann's 29-state automaton, the one he designed his universal constructor
+in. Its states are named by TableState; VonNeumannTransmission and VonNeumannConfluent give the
+names of the ones that patterns are usually drawn with.
+
+There are too many neighborhoods to list as transitions, so the RuleTable computes each one the
+first time it comes up.
+*/
+func VonNeumann() *RuleTable {
+	return &RuleTable{
+		Name:         "JvN29",
+		States:       jvnStates,
+		Neighborhood: "vonNeumann",
+		Palette:      VonNeumannPalette,
+		compute:      vonNeumannNext,
+	}
+}
+
+/*
+jvnTransmission picks apart a transmission state. ok is false if n isn't one.
+*/
+func jvnTransmission(n int) (direction int, special, excited, ok bool) {
+	if n < jvnOrdinary || n >= jvnStates {
+		return 0, false, false, false
+	}
+	special = n >= jvnSpecial
+	n -= jvnOrdinary
+	if special {
+		n -= 8
+	}
+	return n / 2, special, n%2 == 1, true
+}
+
+/*
+vonNeumannNext returns the next state of a cell in von Neumann's automaton, given a key of its state
+followed by its neighbors' in vonNeumannOrder.
+*/
+func vonNeumannNext(key []byte) int {
+	self := int(key[0])
+	neighbors := key[1:]
+	// pointsHere says whether the neighbor at i is a transmission cell pointing at this one
+	pointsHere := func(i int) (special, excited, ok bool) {
+		direction, special, excited, ok := jvnTransmission(int(neighbors[i]))
+		return special, excited, ok && direction == (i+2)%4
+	}
+	var ordinaryIn, specialIn bool
+	for i := range neighbors {
+		if special, excited, ok := pointsHere(i); ok && excited {
+			if special {
+				specialIn = true
+			} else {
+				ordinaryIn = true
+			}
+		}
+	}
+
+	switch {
+	case self == jvnGround:
+		if ordinaryIn || specialIn {
+			return jvnS
+		}
+		return jvnGround
+
+	case self <= jvnS000:
+		if ordinaryIn || specialIn {
+			return jvnSensitized[self][1]
+		}
+		return jvnSensitized[self][0]
+
+	case self <= jvnC11:
+		if specialIn {
+			return jvnGround
+		}
+		// Excited next time round if every ordinary transmission cell pointing here is excited
+		fed, all := false, true
+		for i := range neighbors {
+			if special, excited, ok := pointsHere(i); ok && !special {
+				fed = true
+				all = all && excited
+			}
+		}
+		next := jvnC00 + 2*((self-jvnC00)%2)
+		if fed && all {
+			next++
+		}
+		return next
+	}
+
+	direction, special, _, _ := jvnTransmission(self)
+	if (special && ordinaryIn) || (!special && specialIn) {
+		return jvnGround
+	}
+	base := self - (self-jvnOrdinary)%2
+	for i, n := range neighbors {
+		if i == direction {
+			// Nothing comes in through the output side
+			continue
+		}
+		if fromSpecial, excited, ok := pointsHere(i); ok && excited && fromSpecial == special {
+			return base + 1
+		}
+		if int(n) == jvnC10 || int(n) == jvnC11 {
+			return base + 1
+		}
+	}
+	return base
+}
+
+/*
+vonNeumannPulser is a demo preset setup that builds a loop of ordinary transmission cells, carrying
+one pulse, with a confluent at (x+2, y+1) that sends a copy of the pulse down a construction arm
+going right every 9 ticks. Each pulse, along with the 0s that follow it, spells out 10000 to the
+ground cell at the end of the arm, which turns it into another piece of arm.
+*/
+func vonNeumannPulser(x, y int) func(*StateGrid, *rand.Rand) error {
+	return func(grid *StateGrid, rng *rand.Rand) error {
+		if x+4 > grid.Width || y+3 > grid.Height {
+			return fmt.Errorf("pulser doesn't fit in a %dx%d grid", grid.Width, grid.Height)
+		}
+		loop := []struct {
+			x, y      int
+			direction NeighborIndex
+		}{
+			{0, 0, NeighborRt}, {1, 0, NeighborRt}, {2, 0, NeighborUp},
+			{2, 2, NeighborLf}, {1, 2, NeighborLf}, {0, 2, NeighborDn}, {0, 1, NeighborDn},
+			// The start of the arm
+			{3, 1, NeighborRt},
+		}
+		for _, cell := range loop {
+			grid.Set(x+cell.x, y+cell.y, VonNeumannTransmission(cell.direction, false, false))
+		}
+		grid.Set(x+2, y+1, VonNeumannConfluent(false, false))
+		grid.Set(x, y, VonNeumannTransmission(NeighborRt, false, true))
+		return nil
+	}
+}
@@ -0,0 +1,109 @@
+package cellaut
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVonNeumann_Construction(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	table := VonNeumann()
+	for _, c := range []struct {
+		code string
+		want State
+	}{
+		{"10000", VonNeumannTransmission(NeighborRt, false, false)},
+		{"10001", VonNeumannTransmission(NeighborUp, false, false)},
+		{"1001", VonNeumannTransmission(NeighborLf, false, false)},
+		{"1010", VonNeumannTransmission(NeighborDn, false, false)},
+		{"1011", VonNeumannTransmission(NeighborRt, true, false)},
+		{"1100", VonNeumannTransmission(NeighborUp, true, false)},
+		{"1101", VonNeumannTransmission(NeighborLf, true, false)},
+		{"1110", VonNeumannTransmission(NeighborDn, true, false)},
+		{"1111", VonNeumannConfluent(false, false)},
+	} {
+		// Two cells of wire leading into a ground cell. The code is fed into the first one.
+		grid := NewStateGrid(3, 1, NewStateTable("-"))
+		grid.Set(1, 0, VonNeumannTransmission(NeighborRt, false, false))
+		for tick := 0; tick < len(c.code)+4; tick++ {
+			grid.Set(0, 0, VonNeumannTransmission(NeighborRt, false, tick < len(c.code) && c.code[tick] == '1'))
+			grid = table.Step(grid)
+		}
+		assert.Equal(c.want, grid.At(2, 0), c.code)
+	}
+}
+
+func TestVonNeumann_Signals(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	rule := VonNeumann().Rule()
+	east := func(special, excited bool) State { return VonNeumannTransmission(NeighborRt, special, excited) }
+	north := func(special, excited bool) State { return VonNeumannTransmission(NeighborUp, special, excited) }
+
+	// Excitation passes along a wire, but not in through its output side
+	assert.Equal(east(false, true), rule(east(false, false), map[NeighborIndex]State{NeighborLf: east(false, true)}))
+	assert.Equal(east(false, false), rule(east(false, true), map[NeighborIndex]State{}))
+	assert.Equal(east(false, false), rule(east(false, false), map[NeighborIndex]State{
+		NeighborRt: VonNeumannTransmission(NeighborLf, false, true),
+	}))
+	// Special signals tear down ordinary cells, and the other way round
+	assert.Equal(State("-"), rule(east(false, false), map[NeighborIndex]State{NeighborLf: east(true, true)}))
+	assert.Equal(State("-"), rule(east(true, false), map[NeighborIndex]State{NeighborLf: east(false, true)}))
+	assert.Equal(State("-"), rule(VonNeumannConfluent(true, false), map[NeighborIndex]State{NeighborLf: east(true, true)}))
+
+	// A confluent is an AND gate with a delay of 2
+	both := map[NeighborIndex]State{NeighborLf: east(false, true), NeighborDn: north(false, true)}
+	one := map[NeighborIndex]State{NeighborLf: east(false, true), NeighborDn: north(false, false)}
+	assert.Equal(VonNeumannConfluent(false, true), rule(VonNeumannConfluent(false, false), both))
+	assert.Equal(VonNeumannConfluent(false, false), rule(VonNeumannConfluent(false, false), one))
+	assert.Equal(VonNeumannConfluent(true, false), rule(VonNeumannConfluent(false, true), one))
+	// ...and it excites ordinary and special cells alike
+	assert.Equal(east(false, true), rule(east(false, false), map[NeighborIndex]State{NeighborLf: VonNeumannConfluent(true, false)}))
+	assert.Equal(east(true, true), rule(east(true, false), map[NeighborIndex]State{NeighborLf: VonNeumannConfluent(true, true)}))
+}
+
+func TestVonNeumann_Pulser(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	table := VonNeumann()
+	grid := NewStateGrid(30, 3, NewStateTable("-"))
+	assert.Nil(vonNeumannPulser(0, 0)(grid, nil))
+	slow := grid
+	rule := table.Rule()
+	for tick := 1; tick <= 100; tick++ {
+		grid = table.Step(grid)
+		slow = stepGrid(slow, rule, vonNeumannNeighborhood)
+	}
+	// Step and Rule agree
+	assert.True(grid.Equal(slow))
+	// The arm started out 1 cell long. Pulses leave the loop every 9 ticks, but each one has a cell
+	// further to go than the last, so the arm grows a cell every 10.
+	arm := 0
+	for x := 3; x < grid.Width && grid.At(x, 1) != "-"; x++ {
+		arm++
+	}
+	assert.Equal(11, arm)
+}
+
+func BenchmarkVonNeumann(b *testing.B) {
+	table := VonNeumann()
+	start := NewStateGrid(60, 5, NewStateTable("-"))
+	vonNeumannPulser(1, 1)(start, nil)
+	b.Run("Step", func(b *testing.B) {
+		grid := start
+		for i := 0; i < b.N; i++ {
+			grid = table.Step(grid)
+		}
+	})
+	b.Run("Rule", func(b *testing.B) {
+		grid, rule := start, table.Rule()
+		for i := 0; i < b.N; i++ {
+			grid = stepGrid(grid, rule, vonNeumannNeighborhood)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package cellaut
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+/*
+Detector watches a Simulation for something worth telling someone about. When is checked after every
+tick, and the Detector fires on each tick it says yes after a tick it said no, so a World that's
+settled down is only reported once rather than on every tick from then on.
+
+When is a StopCondition, so anything that can stop a Simulation can be detected too. Like a
+StopCondition, a Detector should only be used by one Simulation.
+*/
+type Detector struct {
+	// Kind is what sort of thing is detected, like "stabilized", "pattern" or "breakpoint"
+	Kind string
+	// Name tells Detectors of the same Kind apart, like the name of the pattern
+	Name string
+	When StopCondition
+}
+
+/*
+DetectStabilized fires once the World repeats itself within period ticks, as for Stabilized.
+*/
+func DetectStabilized(period int) Detector {
+	return Detector{Kind: "stabilized", Name: fmt.Sprintf("period %d", period), When: Stabilized(period)}
+}
+
+/*
+DetectPattern fires when pattern shows up in the World, as for PatternMatched.
+*/
+func DetectPattern(name string, pattern *Pattern) Detector {
+	return Detector{Kind: "pattern", Name: name, When: PatternMatched(pattern)}
+}
+
+/*
+DetectBreakpoint fires when fn returns true, for whatever condition is being debugged.
+*/
+func DetectBreakpoint(name string, fn func(status *StopStatus) bool) Detector {
+	return Detector{Kind: "breakpoint", Name: name, When: StopFunc(fn)}
+}
+
+/*
+Detection is the JSON body a Webhook POSTs. Text is a summary for chat services like Slack, whose
+incoming webhooks show it as the message.
+*/
+type Detection struct {
+	Kind string    `json:"kind"`
+	Name string    `json:"name"`
+	Tick int64     `json:"tick"`
+	Time time.Time `json:"time"`
+	Text string    `json:"text"`
+}
+
+/*
+Webhook POSTs a Detection to URL whenever one of its Detectors fires, so that a long run nobody's
+watching can let someone know how it's going.
+
+It's run as a Sink. Detections are POSTed from the subscriber, so a slow endpoint holds up the tick
+something was detected in, but not any other. Failed POSTs are logged and kept for Err, and never
+stop the Simulation.
+*/
+type Webhook struct {
+	URL       string
+	Detectors []Detector
+	// Client sends the requests. Defaults to a client with a 10-second timeout.
+	Client *http.Client
+
+	mu sync.Mutex
+	// fired is whether each Detector said yes last tick
+	fired []bool
+	start time.Time
+	err   error
+}
+
+/*
+NewWebhook returns a *Webhook that POSTs to url when any of detectors fires.
+*/
+func NewWebhook(url string, detectors ...Detector) *Webhook {
+	return &Webhook{
+		URL:       url,
+		Detectors: detectors,
+		Client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+/*
+Sink returns a Sink that checks the Detectors after every tick of a Simulation.
+*/
+func (hook *Webhook) Sink() Sink {
+	return func(event TickEvent) {
+		hook.mu.Lock()
+		defer hook.mu.Unlock()
+		if hook.fired == nil {
+			hook.fired = make([]bool, len(hook.Detectors))
+			hook.start = time.Now()
+		}
+		status := &StopStatus{Ticks: event.TickID + 1, Elapsed: time.Since(hook.start), world: event.World}
+		for i, detector := range hook.Detectors {
+			fire := detector.When.ShouldStop(status)
+			if fire && !hook.fired[i] {
+				hook.send(detector, event.TickID)
+			}
+			hook.fired[i] = fire
+		}
+	}
+}
+
+/*
+send POSTs the Detection for detector at tickID. The caller must hold hook.mu.
+*/
+func (hook *Webhook) send(detector Detector, tickID int64) {
+	detection := Detection{
+		Kind: detector.Kind,
+		Name: detector.Name,
+		Tick: tickID,
+		Time: time.Now().UTC(),
+		Text: fmt.Sprintf("cellaut: %s '%s' detected at tick %d", detector.Kind, detector.Name, tickID),
+	}
+	err := hook.post(detection)
+	if err != nil {
+		log.WithFields(log.Fields{"tick": tickID, "kind": detector.Kind, "name": detector.Name}).WithError(err).Error("couldn't send detection to webhook")
+		if hook.err == nil {
+			hook.err = err
+		}
+	}
+}
+
+func (hook *Webhook) post(detection Detection) error {
+	body, err := json.Marshal(detection)
+	if err != nil {
+		return err
+	}
+	client := hook.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(hook.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Read the body so the connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s returned %s", hook.URL, resp.Status)
+	}
+	return nil
+}
+
+/*
+Err returns the first error from POSTing to the webhook, if there's been one.
+*/
+func (hook *Webhook) Err() error {
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	return hook.err
+}
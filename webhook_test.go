@@ -0,0 +1,107 @@
+package cellaut
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+detectionServer records the Detections POSTed to it.
+*/
+type detectionServer struct {
+	*httptest.Server
+	mu         sync.Mutex
+	detections []Detection
+}
+
+func newDetectionServer(status int) *detectionServer {
+	server := &detectionServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var detection Detection
+		if r.Method == "POST" && r.Header.Get("Content-Type") == "application/json" && json.NewDecoder(r.Body).Decode(&detection) == nil {
+			server.mu.Lock()
+			server.detections = append(server.detections, detection)
+			server.mu.Unlock()
+		}
+		w.WriteHeader(status)
+	}))
+	return server
+}
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	server := newDetectionServer(http.StatusOK)
+	defer server.Close()
+
+	// A blinker is made into a block at tick 5, which is when the block and the crowd show up.
+	// The blinker never repeats itself from one tick to the next, but the block does.
+	grid := NewLifeGrid(8, 8)
+	for _, cell := range [][2]int{{2, 3}, {3, 3}, {4, 3}} {
+		grid.At(cell[0], cell[1]).SetState(LifeAlive)
+	}
+	block, err := ParsePattern("XX\nXX", NewStateTable("-"))
+	assert.Nil(err)
+	hook := NewWebhook(server.URL,
+		DetectStabilized(1),
+		DetectPattern("block", block),
+		DetectBreakpoint("crowded", func(status *StopStatus) bool { return status.Snapshot().Population(LifeAlive) > 3 }),
+	)
+	sim := NewSimulation()
+	assert.Nil(sim.Configure(SimulationConfig{
+		World:    grid,
+		MaxTicks: 10,
+		Input:    strings.NewReader("5,3,2,-\n5,2,3,X\n5,2,4,X\n"),
+	}))
+	sim.Subscribe(hook.Sink())
+	assert.Nil(sim.Start())
+	assert.Nil(sim.Wait())
+	assert.Nil(hook.Err())
+
+	// Each one fires once, even though they go on being true
+	var kinds []string
+	for _, detection := range server.detections {
+		kinds = append(kinds, detection.Kind+" "+detection.Name)
+		assert.Contains(detection.Text, detection.Name)
+	}
+	assert.ElementsMatch([]string{"stabilized period 1", "pattern block", "breakpoint crowded"}, kinds)
+	for _, detection := range server.detections {
+		switch detection.Kind {
+		case "stabilized":
+			// The block is the same at tick 6 as at tick 5
+			assert.Equal(int64(6), detection.Tick)
+		default:
+			assert.Equal(int64(5), detection.Tick)
+		}
+	}
+}
+
+func TestWebhook_Errors(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	server := newDetectionServer(http.StatusInternalServerError)
+	defer server.Close()
+	hook := NewWebhook(server.URL, DetectBreakpoint("always", func(*StopStatus) bool { return true }))
+	hook.Sink()(TickEvent{TickID: 0, World: NewLifeGrid(2, 2)})
+	assert.NotNil(hook.Err())
+	assert.Len(server.detections, 1)
+
+	_, err := LookupSink("webhook", Options{"stable": "1"})
+	assert.NotNil(err)
+	_, err = LookupSink("webhook", Options{"url": server.URL})
+	assert.NotNil(err)
+	path := filepath.Join(t.TempDir(), "block.txt")
+	assert.Nil(ioutil.WriteFile(path, []byte("XX\nXX\n"), 0644))
+	_, err = LookupSink("webhook", Options{"url": server.URL, "stable": "2", "pattern": path})
+	assert.Nil(err)
+}
@@ -0,0 +1,160 @@
+package cellaut
+
+import (
+	"image"
+	"image/color"
+)
+
+// zoomMinShade is how far from the background a block with anything at all in it is drawn, so that
+// a lone glider doesn't vanish when it's a millionth of its block
+const zoomMinShade = 0.25
+
+/*
+RenderZoomed draws the part of the universe inside view zoomed out, so that each pixel is a
+2^zoom × 2^zoom block of cells. Blocks are shaded from background to foreground by how many of
+their cells aren't empty. view is widened to whole blocks, and the image's top row is the top of the
+view.
+
+A node's Population is kept up to date as the node is made, so any node that fits inside a block
+adds to its pixel without being looked into, and empty nodes are skipped altogether. That makes
+drawing the whole of a huge universe about as cheap as drawing a small window onto it.
+*/
+func (universe *QuadUniverse) RenderZoomed(view Rect, zoom uint, background, foreground color.Color) *image.RGBA {
+	block := 1 << zoom
+	blocks := Rect{X: floorDiv(view.X, block), Y: floorDiv(view.Y, block)}
+	blocks.Width = floorDiv(view.X+view.Width+block-1, block) - blocks.X
+	blocks.Height = floorDiv(view.Y+view.Height+block-1, block) - blocks.Y
+
+	populations := make([]int64, blocks.Width*blocks.Height)
+	min, _ := universe.Bounds()
+	universe.zoomed(universe.Root, min, min, zoom, blocks, populations)
+
+	img := image.NewRGBA(image.Rect(0, 0, blocks.Width, blocks.Height))
+	area := float64(block) * float64(block)
+	for i, population := range populations {
+		shade := 0.0
+		if population > 0 {
+			shade = zoomMinShade + (1-zoomMinShade)*float64(population)/area
+		}
+		bx, by := i%blocks.Width, i/blocks.Width
+		img.Set(bx, blocks.Height-1-by, blend(background, foreground, shade))
+	}
+	return img
+}
+
+/*
+zoomed adds the population of node, whose bottom left corner is (x0, y0), to the blocks it
+overlaps.
+*/
+func (universe *QuadUniverse) zoomed(node *QuadNode, x0, y0 int, zoom uint, blocks Rect, populations []int64) {
+	size := 1 << node.Level
+	block := 1 << zoom
+	if node.Population == 0 ||
+		x0+size <= blocks.X*block || x0 >= (blocks.X+blocks.Width)*block ||
+		y0+size <= blocks.Y*block || y0 >= (blocks.Y+blocks.Height)*block {
+		return
+	}
+	if node.Level <= zoom {
+		// Nodes are aligned to their own size, so this one is inside a single block
+		bx, by := floorDiv(x0, block)-blocks.X, floorDiv(y0, block)-blocks.Y
+		populations[by*blocks.Width+bx] += node.Population
+		return
+	}
+	half := size / 2
+	universe.zoomed(node.SW, x0, y0, zoom, blocks, populations)
+	universe.zoomed(node.SE, x0+half, y0, zoom, blocks, populations)
+	universe.zoomed(node.NW, x0, y0+half, zoom, blocks, populations)
+	universe.zoomed(node.NE, x0+half, y0+half, zoom, blocks, populations)
+}
+
+/*
+FitZoom returns the smallest zoom at which RenderZoomed draws view in no more than width×height
+pixels.
+*/
+func FitZoom(view Rect, width, height int) uint {
+	var zoom uint
+	for {
+		block := 1 << zoom
+		if (view.Width+2*block-1)/block <= width && (view.Height+2*block-1)/block <= height {
+			// Widening to whole blocks costs at most one more block each way
+			return zoom
+		}
+		zoom++
+	}
+}
+
+/*
+blend mixes two colors, t of the way from a to b.
+*/
+func blend(a, b color.Color, t float64) color.RGBA {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	mix := func(x, y uint32) uint8 {
+		return uint8((float64(x)*(1-t) + float64(y)*t) / 0x101)
+	}
+	return color.RGBA{mix(ar, br), mix(ag, bg), mix(ab, bb), mix(aa, ba)}
+}
+
+/*
+floorDiv divides, rounding toward negative infinity rather than toward zero.
+*/
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+/*
+Minimap draws the whole universe, along with viewport, in no more than width×height pixels, with
+the viewport outlined in frame. It's for keeping track of where a viewer is looking in a universe
+much bigger than its window.
+*/
+func (universe *QuadUniverse) Minimap(viewport Rect, width, height int, background, foreground, frame color.Color) *image.RGBA {
+	area := viewport
+	if extent, ok := universe.Extent(); ok {
+		area = boundingRect(area, extent)
+	}
+	zoom := FitZoom(area, width, height)
+	img := universe.RenderZoomed(area, zoom, background, foreground)
+
+	// Where the viewport lands, in pixels, remembering that RenderZoomed widened area to whole
+	// blocks and put the top of it at row 0
+	block := 1 << zoom
+	left, top := floorDiv(area.X, block), floorDiv(area.Y+area.Height+block-1, block)
+	x0 := floorDiv(viewport.X, block) - left
+	x1 := floorDiv(viewport.X+viewport.Width-1, block) - left
+	y0 := top - 1 - floorDiv(viewport.Y+viewport.Height-1, block)
+	y1 := top - 1 - floorDiv(viewport.Y, block)
+	for x := x0; x <= x1; x++ {
+		img.Set(x, y0, frame)
+		img.Set(x, y1, frame)
+	}
+	for y := y0; y <= y1; y++ {
+		img.Set(x0, y, frame)
+		img.Set(x1, y, frame)
+	}
+	return img
+}
+
+/*
+boundingRect returns the smallest Rect holding both a and b.
+*/
+func boundingRect(a, b Rect) Rect {
+	x0, y0 := a.X, a.Y
+	if b.X < x0 {
+		x0 = b.X
+	}
+	if b.Y < y0 {
+		y0 = b.Y
+	}
+	x1, y1 := a.X+a.Width, a.Y+a.Height
+	if b.X+b.Width > x1 {
+		x1 = b.X + b.Width
+	}
+	if b.Y+b.Height > y1 {
+		y1 = b.Y + b.Height
+	}
+	return Rect{X: x0, Y: y0, Width: x1 - x0, Height: y1 - y0}
+}
@@ -0,0 +1,96 @@
+package cellaut
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuadUniverse_RenderZoomed(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	universe := NewQuadUniverse(NewStateTable("-"))
+	// A full 2x2 block, and one cell of the block to its right
+	for _, xy := range [][2]int{{-2, 0}, {-1, 0}, {-2, 1}, {-1, 1}, {1, 1}} {
+		universe.Set(xy[0], xy[1], "X")
+	}
+	white, black := color.RGBA{0xff, 0xff, 0xff, 0xff}, color.RGBA{0, 0, 0, 0xff}
+
+	// At zoom 0 it's just the cells
+	img := universe.RenderZoomed(Rect{X: -2, Y: 0, Width: 4, Height: 2}, 0, white, black)
+	assert.Equal(4, img.Bounds().Dx())
+	assert.Equal(2, img.Bounds().Dy())
+	assert.Equal(black, img.RGBAAt(0, 0))
+	assert.Equal(white, img.RGBAAt(2, 0))
+	assert.Equal(black, img.RGBAAt(3, 0))
+	assert.Equal(white, img.RGBAAt(3, 1))
+
+	// At zoom 1, each 2x2 block is a pixel. The view gets widened to whole blocks.
+	img = universe.RenderZoomed(Rect{X: -1, Y: 1, Width: 3, Height: 1}, 1, white, black)
+	assert.Equal(2, img.Bounds().Dx())
+	assert.Equal(1, img.Bounds().Dy())
+	assert.Equal(black, img.RGBAAt(0, 0))
+	// A quarter full, on top of the minimum shade
+	assert.Equal(blend(white, black, zoomMinShade+(1-zoomMinShade)/4), img.RGBAAt(1, 0))
+
+	// Nothing out here
+	img = universe.RenderZoomed(Rect{X: 100, Y: -100, Width: 8, Height: 8}, 2, white, black)
+	assert.Equal(white, img.RGBAAt(1, 1))
+}
+
+func TestQuadUniverse_RenderZoomed_Huge(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	universe := NewQuadUniverse(NewStateTable("-"))
+	universe.Set(-1000000, -1000000, "X")
+	universe.Set(1000000, 1000000, "X")
+	extent, ok := universe.Extent()
+	assert.True(ok)
+
+	zoom := FitZoom(extent, 64, 64)
+	img := universe.RenderZoomed(extent, zoom, color.White, color.Black)
+	assert.True(img.Bounds().Dx() <= 64 && img.Bounds().Dy() <= 64, "%v at zoom %d", img.Bounds(), zoom)
+	assert.True(zoom > 0 && FitZoom(extent, 64, 64) == zoom)
+	// The two cells are in opposite corners, and there's nothing in between
+	bounds := img.Bounds()
+	assert.NotEqual(uint8(0xff), img.RGBAAt(0, bounds.Dy()-1).R)
+	assert.NotEqual(uint8(0xff), img.RGBAAt(bounds.Dx()-1, 0).R)
+	assert.Equal(uint8(0xff), img.RGBAAt(bounds.Dx()/2, bounds.Dy()/2).R)
+}
+
+func TestFloorDiv(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal(1, floorDiv(5, 4))
+	assert.Equal(-2, floorDiv(-5, 4))
+	assert.Equal(-1, floorDiv(-4, 4))
+	assert.Equal(0, floorDiv(0, 4))
+}
+
+func TestQuadUniverse_Minimap(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	universe := NewQuadUniverse(NewStateTable("-"))
+	universe.Set(1000, 1000, "X")
+	red := color.RGBA{0xff, 0, 0, 0xff}
+
+	// Looking at a small empty window in the bottom left, far from the only cell
+	img := universe.Minimap(Rect{X: 0, Y: 0, Width: 10, Height: 10}, 32, 32, color.White, color.Black, red)
+	bounds := img.Bounds()
+	assert.True(bounds.Dx() <= 32 && bounds.Dy() <= 32, "%v", bounds)
+	assert.Equal(red, img.RGBAAt(0, bounds.Dy()-1))
+	assert.NotEqual(uint8(0xff), img.RGBAAt(bounds.Dx()-1, 0).G)
+	assert.Equal(color.RGBA{0xff, 0xff, 0xff, 0xff}, img.RGBAAt(bounds.Dx()/2, bounds.Dy()/2))
+
+	// With the viewport over the cell, and the whole thing at full size, the frame goes round it
+	img = universe.Minimap(Rect{X: 999, Y: 999, Width: 3, Height: 3}, 32, 32, color.White, color.Black, red)
+	assert.Equal(3, img.Bounds().Dx())
+	assert.Equal(red, img.RGBAAt(0, 0))
+	assert.Equal(red, img.RGBAAt(2, 2))
+	assert.Equal(color.RGBA{0, 0, 0, 0xff}, img.RGBAAt(1, 1))
+}